@@ -6,12 +6,14 @@ import (
 	campaignHandler "bingwa-service/internal/handlers/campaign"
 	configHandler "bingwa-service/internal/handlers/config"
 	customerHandler "bingwa-service/internal/handlers/customer"
+	healthHandler "bingwa-service/internal/handlers/health"
 	notifyHandler "bingwa-service/internal/handlers/notification"
 	offerHandler "bingwa-service/internal/handlers/offer"
 	scheduleHandler "bingwa-service/internal/handlers/schedule"
 	agentSubscriptionHandler "bingwa-service/internal/handlers/subscription"
 	planHandler "bingwa-service/internal/handlers/subscription_plans"
 	transactionHandler "bingwa-service/internal/handlers/transaction"
+	webhookHandler "bingwa-service/internal/handlers/webhook"
 	wsHandler "bingwa-service/internal/handlers/websocket"
 	"bingwa-service/internal/middleware"
 
@@ -31,6 +33,8 @@ type Handlers struct {
 	ScheduleHandler          *scheduleHandler.ScheduleHandler
 	AgentSubscriptionHandler *agentSubscriptionHandler.AgentSubscriptionHandler
 	WSHandler                *wsHandler.WebSocketHandler
+	HealthHandler            *healthHandler.HealthHandler
+	WebhookHandler           *webhookHandler.WebhookHandler
 	AuthMiddleware           *middleware.AuthMiddleware
 }
 
@@ -38,11 +42,11 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 	api := r.Group("/api/v1")
 
 	// ==================== Health Check ====================
-	api.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "version": "1.0.0"})
-	})
+	api.GET("/health", h.HealthHandler.Liveness)
+	api.GET("/health/ready", h.HealthHandler.Readiness)
 
 	// ==================== WebSocket ====================
+	// Token is validated in the handler before the upgrade (?token= or Authorization header)
 	r.GET("/ws", h.WSHandler.HandleConnection)
 
 	// ==================== Public Auth Routes ====================
@@ -53,6 +57,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		authPublic.POST("/forgot-password", h.AuthHandler.ForgotPassword)
 		authPublic.POST("/reset-password", h.AuthHandler.ResetPassword)
 		authPublic.GET("/verify-email", h.AuthHandler.VerifyEmail)
+		authPublic.GET("/confirm-email-change", h.AuthHandler.ConfirmEmailChange)
 	}
 
 	// ==================== Authenticated Auth Routes ====================
@@ -62,11 +67,15 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		authProtected.POST("/logout", h.AuthHandler.Logout)
 		authProtected.POST("/logout-all", h.AuthHandler.LogoutAll)
 		authProtected.PUT("/change-password", h.AuthHandler.ChangePassword)
+		authProtected.POST("/change-email", h.AuthHandler.RequestEmailChange)
 		authProtected.GET("/me", h.AuthHandler.GetMe)
 		authProtected.PUT("/profile", h.AuthHandler.UpdateProfile)
 		authProtected.POST("/resend-verification", h.AuthHandler.ResendVerificationEmail)
 		authProtected.GET("/sessions", h.AuthHandler.GetActiveSessions)
 		authProtected.DELETE("/sessions/:session_id", h.AuthHandler.RevokeSession)
+		authProtected.DELETE("/sessions/device/:device_id", h.AuthHandler.RevokeSessionsByDevice)
+		authProtected.GET("/export", h.AuthHandler.ExportUserData)
+		authProtected.DELETE("/account", h.AuthHandler.DeleteAccount)
 	}
 
 	// ==================== Notifications ====================
@@ -101,6 +110,9 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 	}
 
 	// ==================== Agent Customers ====================
+	// Customers confirm the code texted to them; no agent token. Body: {"phone_number": "...", "code": "123456"}
+	api.POST("/customers/verify", h.CustomerHandler.VerifyCustomer)
+
 	customers := api.Group("/customers")
 	customers.Use(h.AuthMiddleware.Auth())
 	{
@@ -108,6 +120,8 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		customers.GET("", h.CustomerHandler.ListCustomers)
 		customers.GET("/search", h.CustomerHandler.SearchCustomers)
 		customers.GET("/stats", h.CustomerHandler.GetCustomerStats)
+		customers.GET("/inactive", h.CustomerHandler.GetInactiveCustomers) // ?days=30
+		customers.GET("/export.csv", h.CustomerHandler.ExportCustomers)
 		
 		// Get by identifiers
 		customers.GET("/:id", h.CustomerHandler.GetCustomer)
@@ -122,14 +136,20 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		// Status management
 		customers.PUT("/:id/activate", h.CustomerHandler.ActivateCustomer)
 		customers.PUT("/:id/deactivate", h.CustomerHandler.DeactivateCustomer)
-		customers.PUT("/:id/verify", h.CustomerHandler.VerifyCustomer)
+		customers.POST("/:id/verification-code", h.CustomerHandler.SendVerificationCode)
 		
 		// Tag management
 		customers.POST("/:id/tags", h.CustomerHandler.AddTag)
 		customers.DELETE("/:id/tags", h.CustomerHandler.RemoveTag) // ?tag=xxx
+
+		// Notes
+		customers.GET("/:id/notes", h.CustomerHandler.ListNotes)
+		customers.POST("/:id/notes", h.CustomerHandler.AddNote)
+		customers.DELETE("/:id/notes/:note_id", h.CustomerHandler.DeleteNote)
 		
 		// Bulk operations
 		customers.POST("/bulk-import", h.CustomerHandler.BulkImportCustomers)
+		customers.POST("/import.csv", h.CustomerHandler.ImportCustomersCSV) // multipart, field "file"
 	}
 
 	// ==================== Agent Offers ====================
@@ -139,8 +159,22 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		// List and search
 		offers.GET("", h.OfferHandler.ListOffers)
 		offers.GET("/featured", h.OfferHandler.GetFeaturedOffers)
+		offers.GET("/available-at", h.OfferHandler.GetAvailableAt) // ?at=2026-01-02T15:04:05Z
+		offers.GET("/top", h.OfferHandler.GetTopOffers)
+		offers.GET("/recommendations", h.OfferHandler.RecommendForCustomer) // ?phone=xxx
 		offers.GET("/search", h.OfferHandler.SearchOffers)
+		offers.GET("/facets", h.OfferHandler.GetSearchFacets)
 		offers.GET("/stats", h.OfferHandler.GetOfferStats)
+		offers.GET("/stats/comparison", h.OfferHandler.GetStatsComparison) // ?period=30d
+		offers.GET("/templates", h.OfferHandler.ListTemplates)
+		offers.POST("/from-template", h.OfferHandler.CreateFromTemplate)
+		offers.POST("/availability/batch", h.OfferHandler.CheckAvailabilityBatch)
+		offers.PUT("/tags/rename", h.OfferHandler.RenameTag)
+		offers.POST("/qr-batch", h.OfferHandler.GenerateQRBatch)
+		offers.POST("/bulk-delete", h.OfferHandler.BulkDeleteOffers)
+		offers.POST("/bulk-adjust-price", h.OfferHandler.BulkAdjustPrice)
+		offers.POST("/bulk-tags", h.OfferHandler.BulkAddTag)
+		offers.DELETE("/bulk-tags", h.OfferHandler.BulkRemoveTag)
 		
 		// Get by identifiers
 		offers.GET("/:id", h.OfferHandler.GetOffer)
@@ -155,6 +189,8 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		
 		// Create, update, delete
 		offers.POST("", h.OfferHandler.CreateOffer)
+		offers.POST("/import", h.OfferHandler.ImportOffers)
+		offers.PUT("/ussd-codes/bulk-status", h.OfferHandler.BulkToggleUSSDStatus)
 		offers.PUT("/:id", h.OfferHandler.UpdateOffer)
 		offers.DELETE("/:id", h.OfferHandler.DeleteOffer)
 		
@@ -168,7 +204,10 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		offers.GET("/:id/ussd-code", h.OfferHandler.GenerateUSSDCode) // ?phone=xxx (deprecated, kept for backward compatibility)
 		offers.GET("/:id/ussd-code/execute", h.OfferHandler.GetUSSDCodeForExecution) // ?phone=xxx (new endpoint)
 		offers.GET("/:id/price", h.OfferHandler.CalculateOfferPrice)
+		offers.GET("/:id/price-history", h.OfferHandler.GetPriceHistory)
 		offers.GET("/:id/availability", h.OfferHandler.CheckOfferAvailability)
+		offers.POST("/:id/replenish", h.OfferHandler.ReplenishStock) // body: {"amount": 50}
+		offers.GET("/:id/sales-series", h.TransactionHandler.GetOfferSalesTimeSeries) // ?from=&to=&granularity=daily|weekly
 
 		ussdCodes := offers.Group("/:id/ussd-codes")
 		{
@@ -192,6 +231,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 			
 			// Usage tracking
 			ussdCodes.POST("/record-result", h.OfferHandler.RecordUSSDResult)
+			ussdCodes.POST("/:ussd_code_id/test", h.OfferHandler.TestUSSDCode)
 		}
 	}
 
@@ -204,7 +244,10 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		configs.GET("", h.ConfigHandler.ListConfigs)
 		configs.GET("/all", h.ConfigHandler.GetAllConfigs)
 		configs.GET("/global", h.ConfigHandler.GetGlobalConfigs)
+		configs.GET("/presets", h.ConfigHandler.ListPresets)
+		configs.POST("/apply-preset", h.ConfigHandler.ApplyPreset)
 		configs.GET("/:id", h.ConfigHandler.GetConfig)
+		configs.GET("/:id/history", h.ConfigHandler.GetConfigHistory)
 		configs.GET("/key/:key", h.ConfigHandler.GetConfigByKey) // ?device_id=xxx
 		configs.PUT("/:id", h.ConfigHandler.UpdateConfig)
 		configs.DELETE("/:id", h.ConfigHandler.DeleteConfig)
@@ -226,6 +269,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 			// Android device settings
 			configTypes.GET("/android/:device_id", h.ConfigHandler.GetAndroidDeviceConfig)
 			configTypes.PUT("/android/:device_id", h.ConfigHandler.SetAndroidDeviceConfig)
+			configTypes.POST("/android/:device_id/heartbeat", h.ConfigHandler.RecordDeviceHeartbeat)
 			
 			// Business settings
 			configTypes.GET("/business", h.ConfigHandler.GetBusinessConfig)
@@ -238,9 +282,24 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 			// Security settings
 			configTypes.GET("/security", h.ConfigHandler.GetSecurityConfig)
 			configTypes.PUT("/security", h.ConfigHandler.SetSecurityConfig)
+
+			// Webhook delivery
+			configTypes.GET("/webhook", h.ConfigHandler.GetWebhookConfig)
+			configTypes.PUT("/webhook", h.ConfigHandler.SetWebhookConfig)
+
+			// M-Pesa callbacks
+			configTypes.GET("/mpesa", h.ConfigHandler.GetMpesaConfig)
+			configTypes.PUT("/mpesa", h.ConfigHandler.SetMpesaConfig)
 		}
 	}
 
+	// ==================== Webhooks ====================
+	webhooks := api.Group("/webhooks")
+	webhooks.Use(h.AuthMiddleware.Auth())
+	{
+		webhooks.POST("/replay", h.WebhookHandler.ReplayFailed)
+	}
+
 	// ==================== Promotional Campaigns (Read Only for Users) ====================
 	campaigns := api.Group("/campaigns")
 	campaigns.Use(h.AuthMiddleware.Auth())
@@ -259,6 +318,9 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 	}
 
 	// ==================== Offer Requests & Redemptions ====================
+	// M-Pesa callbacks carry no agent token; the payload signature identifies the agent
+	api.POST("/transactions/mpesa/callback", h.TransactionHandler.IngestMpesaCallback)
+
 	transactions := api.Group("/transactions")
 	transactions.Use(h.AuthMiddleware.Auth())
 	{
@@ -269,21 +331,27 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 			requests.POST("", h.TransactionHandler.CreateOfferRequest)
 			requests.GET("", h.TransactionHandler.ListOfferRequests)
 			requests.GET("/:id", h.TransactionHandler.GetOfferRequest)
+			requests.GET("/:id/audit", h.TransactionHandler.GetTransactionAudit)
 			
 			// Status-based retrieval
 			requests.GET("/pending", h.TransactionHandler.GetPendingRequests)
 			requests.GET("/failed", h.TransactionHandler.GetFailedRequests)
 			requests.GET("/processing", h.TransactionHandler.GetProcessingRequests)
 			requests.GET("/by-status", h.TransactionHandler.GetRequestsByStatus)
+			requests.GET("/by-receipt", h.TransactionHandler.FindByMpesaReceipt) // ?receipt=
+			requests.GET("/held", h.TransactionHandler.GetHeldRequests)
 			
 			// Status updates
 			requests.PUT("/:id/status", h.TransactionHandler.UpdateOfferRequestStatus)
 			requests.PUT("/:id/complete", h.TransactionHandler.CompleteOfferRequest)
 			requests.PUT("/:id/processing", h.TransactionHandler.MarkAsProcessing)
 			requests.POST("/:id/retry", h.TransactionHandler.RetryFailedRequest)
+			requests.POST("/:id/review", h.TransactionHandler.ReviewHeldRequest)
+			requests.POST("/:id/confirm", h.TransactionHandler.ConfirmOfferRequest)
 			
 			// Batch operations
 			requests.GET("/batch/pending", h.TransactionHandler.GetBatchPendingForDevice)
+			requests.POST("/batch", h.TransactionHandler.BatchCreateRequests)
 			requests.PUT("/batch/update", h.TransactionHandler.BatchUpdateRequests)
 		}
 		
@@ -296,6 +364,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		
 		// Statistics
 		transactions.GET("/stats", h.TransactionHandler.GetTransactionStats)
+		transactions.GET("/stats/heatmap", h.TransactionHandler.GetSalesHeatmap)
 	}
 
 	// ==================== Scheduled Offers ====================
@@ -312,6 +381,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		schedules.PUT("/:id/pause", h.ScheduleHandler.PauseScheduledOffer)
 		schedules.PUT("/:id/resume", h.ScheduleHandler.ResumeScheduledOffer)
 		schedules.PUT("/:id/cancel", h.ScheduleHandler.CancelScheduledOffer)
+		schedules.POST("/:id/skip", h.ScheduleHandler.SkipNextRenewal)
 		
 		// Execution
 		schedules.POST("/:id/execute", h.ScheduleHandler.ExecuteScheduledOffer)
@@ -327,6 +397,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		// Batch operations (for mobile app)
 		schedules.GET("/batch/due", h.ScheduleHandler.GetBatchDueSchedules)
 		schedules.POST("/batch/execute", h.ScheduleHandler.BatchExecuteSchedules)
+		schedules.POST("/batch/process", h.ScheduleHandler.ProcessDueSchedules)
 	}
 
 	// ==================== Agent Subscriptions ====================
@@ -336,11 +407,23 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		// Create and renew (from mobile USSD payment)
 		subscriptions.POST("", h.AgentSubscriptionHandler.CreateSubscription)
 		subscriptions.POST("/renew", h.AgentSubscriptionHandler.RenewSubscription)
+		subscriptions.POST("/change-plan", h.AgentSubscriptionHandler.ChangePlan)
 		
 		// View subscriptions
 		subscriptions.GET("", h.AgentSubscriptionHandler.ListSubscriptions)
 		subscriptions.GET("/active", h.AgentSubscriptionHandler.GetActiveSubscription)
+		subscriptions.GET("/recommend-plan", h.AgentSubscriptionHandler.RecommendPlan)
+		subscriptions.GET("/preview-change", h.AgentSubscriptionHandler.PreviewPlanChange) // ?plan_id=
+
+		// Sub-agents sharing this agent's subscription
+		subscriptions.GET("/sub-agents", h.AgentSubscriptionHandler.ListSubAgents)
+		subscriptions.POST("/sub-agents", h.AgentSubscriptionHandler.AddSubAgent)
+		subscriptions.DELETE("/sub-agents/:child_id", h.AgentSubscriptionHandler.RemoveSubAgent)
+		subscriptions.GET("/parent", h.AgentSubscriptionHandler.GetParentLink)
+		subscriptions.POST("/parent/accept", h.AgentSubscriptionHandler.AcceptSubAgentInvite)
+		subscriptions.DELETE("/parent", h.AgentSubscriptionHandler.LeaveParent) // Decline an invite or leave
 		subscriptions.GET("/:id", h.AgentSubscriptionHandler.GetSubscription)
+		subscriptions.GET("/:id/billing-history", h.AgentSubscriptionHandler.GetBillingHistory)
 		
 		// Update and cancel
 		subscriptions.PUT("/:id", h.AgentSubscriptionHandler.UpdateSubscription)
@@ -353,6 +436,7 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 		// Statistics
 		subscriptions.GET("/stats/overview", h.AgentSubscriptionHandler.GetSubscriptionStats)
 		subscriptions.GET("/stats/by-status", h.AgentSubscriptionHandler.GetSubscriptionsByStatus)
+		subscriptions.GET("/stats/cancellation-reasons", h.AgentSubscriptionHandler.GetCancellationReasons)
 	}
 
 	// ==================== ADMIN ROUTES ====================
@@ -366,6 +450,25 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 			superAdmin.GET("/admins", h.AuthHandler.ListAdmins)
 			superAdmin.DELETE("/admins/:id", h.AuthHandler.DeactivateAdmin)
 			superAdmin.GET("/ws/stats", h.WSHandler.GetStats)
+
+			// Roles & permissions
+			roles := superAdmin.Group("/roles")
+			{
+				roles.POST("", h.AuthHandler.CreateRole)
+				roles.GET("", h.AuthHandler.ListRoles)
+				roles.DELETE("/:id", h.AuthHandler.DeleteRole)
+				roles.POST("/:id/permissions", h.AuthHandler.AssignPermissionToRole)
+			}
+
+			superAdmin.POST("/users/:id/roles", h.AuthHandler.AssignRole)
+
+			permissions := superAdmin.Group("/permissions")
+			{
+				permissions.GET("", h.AuthHandler.ListPermissions)
+				permissions.GET("/users/:identity_id", h.AuthHandler.GetUserPermissions)
+				permissions.POST("/users/:identity_id", h.AuthHandler.GrantPermissionToUser)
+				permissions.DELETE("/users/:identity_id/:permission_id", h.AuthHandler.RevokePermissionFromUser)
+			}
 		}
 
 		// Any Admin Routes
@@ -380,6 +483,9 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 				adminNotifications.POST("/broadcast", h.NotifHandler.BroadcastNotification)
 			}
 
+			// Email delivery history
+			adminAuth.GET("/email-logs", h.AuthHandler.ListEmailLogs) // ?recipient=&type=&status=
+
 			// Subscription Plans Management
 			adminPlans := adminAuth.Group("/plans")
 			{
@@ -417,6 +523,28 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 				
 				// Statistics
 				adminCampaigns.GET("/stats", h.CampaignHandler.GetCampaignStats)
+				adminCampaigns.GET("/:id/variant-stats", h.CampaignHandler.GetVariantStats)
+			}
+
+			// Offer Management
+			adminOffers := adminAuth.Group("/offers")
+			{
+				adminOffers.POST("/:id/transfer", h.OfferHandler.AdminTransferOffer)
+			}
+
+			// Offer Template Library
+			adminTemplates := adminAuth.Group("/offer-templates")
+			{
+				adminTemplates.GET("", h.OfferHandler.AdminListTemplates)
+				adminTemplates.POST("", h.OfferHandler.AdminCreateTemplate)
+				adminTemplates.PUT("/:id", h.OfferHandler.AdminUpdateTemplate)
+				adminTemplates.DELETE("/:id", h.OfferHandler.AdminDeleteTemplate)
+			}
+
+			// Transaction Support
+			adminTransactions := adminAuth.Group("/transactions")
+			{
+				adminTransactions.POST("/redemptions/:id/retry", h.TransactionHandler.AdminRetryRedemption)
 			}
 
 			// Agent Subscription Management
@@ -432,9 +560,13 @@ func SetupRouter(r *gin.Engine, logger *zap.Logger, h *Handlers) {
 				adminSubscriptions.PUT("/:id/suspend", h.AgentSubscriptionHandler.AdminSuspendSubscription)
 				adminSubscriptions.PUT("/:id/reactivate", h.AgentSubscriptionHandler.AdminReactivateSubscription)
 				adminSubscriptions.POST("/:id/cancel", h.AgentSubscriptionHandler.AdminCancelSubscription)
+				adminSubscriptions.PUT("/:id/custom-limit", h.AgentSubscriptionHandler.AdminSetCustomLimit)
+				adminSubscriptions.PUT("/:id/limit-behavior", h.AgentSubscriptionHandler.AdminSetLimitBehavior)
+				adminSubscriptions.POST("/sub-agents", h.AgentSubscriptionHandler.AdminLinkSubAgent)
 				
 				// Statistics
 				adminSubscriptions.GET("/stats", h.AgentSubscriptionHandler.AdminGetSubscriptionStats)
+				adminSubscriptions.GET("/stats/cancellation-reasons", h.AgentSubscriptionHandler.AdminGetCancellationReasons)
 			}
 		}
 	}
@@ -10,21 +10,26 @@ import (
 
 	"bingwa-service/internal/config"
 	"bingwa-service/internal/db"
+	offerDomain "bingwa-service/internal/domain/offer"
+	subscriptionDomain "bingwa-service/internal/domain/subscription"
 	authHandler "bingwa-service/internal/handlers/auth"
 	campaignHandler "bingwa-service/internal/handlers/campaign"
 	configHandler "bingwa-service/internal/handlers/config"
 	customerHandler "bingwa-service/internal/handlers/customer"
+	healthHandler "bingwa-service/internal/handlers/health"
 	notifyH "bingwa-service/internal/handlers/notification"
 	offerHandler "bingwa-service/internal/handlers/offer"
 	scheduleHandler "bingwa-service/internal/handlers/schedule"
 	subscriptionHandler "bingwa-service/internal/handlers/subscription"
 	subhandler "bingwa-service/internal/handlers/subscription_plans"
 	transactionHandler "bingwa-service/internal/handlers/transaction"
+	webhookHandler "bingwa-service/internal/handlers/webhook"
 	wsHandler "bingwa-service/internal/handlers/websocket"
 	"bingwa-service/internal/middleware"
 	"bingwa-service/internal/pkg/jwt"
 	"bingwa-service/internal/pkg/session"
 	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
 	authUsecase "bingwa-service/internal/service/auth"
 	campaignUsecase "bingwa-service/internal/service/campaign"
 	configUsecase "bingwa-service/internal/service/config"
@@ -33,9 +38,11 @@ import (
 	notifyUsecase "bingwa-service/internal/service/notification"
 	offerservice "bingwa-service/internal/service/offer"
 	scheduleUsecase "bingwa-service/internal/service/schedule"
+	smsUsecase "bingwa-service/internal/service/sms"
 	subscriptionUsecase "bingwa-service/internal/service/subscription"
 	subscription "bingwa-service/internal/service/subscription_plans"
 	transactionUsecase "bingwa-service/internal/service/transaction"
+	webhookUsecase "bingwa-service/internal/service/webhook"
 	"bingwa-service/internal/websocket"
 	wsHandlers "bingwa-service/internal/websocket/handler"
 
@@ -115,14 +122,21 @@ func (s *Server) Start() error {
 	notifyRepo := postgres.NewNotificationRepository(pool)
 	planRepo := postgres.NewSubscriptionPlanRepository(pool)
 	customerRepo := postgres.NewAgentCustomerRepository(pool)
+	customerNoteRepo := postgres.NewCustomerNoteRepository(pool)
 	offerRepo := postgres.NewAgentOfferRepository(pool, ussdCodeRepo, dbWrapper)
+	offerTemplateRepo := postgres.NewOfferTemplateRepository(pool)
 	configRepo := postgres.NewAgentConfigRepository(pool)
 	campaignRepo := postgres.NewPromotionalCampaignRepository(pool)
 	requestRepo := postgres.NewOfferRequestRepository(pool)
 	redemptionRepo := postgres.NewOfferRedemptionRepository(pool)
+	transactionAuditRepo := postgres.NewTransactionAuditRepository(pool)
 	scheduleRepo := postgres.NewScheduledOfferRepository(pool)
 	scheduleHistoryRepo := postgres.NewScheduledOfferHistoryRepository(pool)
 	agentSubscriptionRepo := postgres.NewAgentSubscriptionRepository(pool)
+	webhookDeliveryRepo := postgres.NewWebhookDeliveryRepository(pool)
+	outboxRepo := postgres.NewOutboxRepository(pool)
+	smsOutboxRepo := postgres.NewSMSOutboxRepository(pool)
+	emailLogRepo := postgres.NewEmailLogRepository(pool)
 
 	// Update session manager with auth repo
 	sessionManager = session.NewManager(redisClient, authRepo)
@@ -140,6 +154,11 @@ func (s *Server) Start() error {
 	// ----- Services (Usecases) -----
 	authService := authUsecase.NewAuthService(
 		authRepo,
+		offerRepo,
+		requestRepo,
+		redemptionRepo,
+		agentSubscriptionRepo,
+		emailLogRepo,
 		jwtManager,
 		sessionManager,
 		rateLimiter,
@@ -149,43 +168,169 @@ func (s *Server) Start() error {
 		logger,
 	)
 	s.authService = authService // Store authService in server
+	emailSender.OnResult(authService.RecordEmailDelivery)
 
 	notifService := notifyUsecase.NewNotificationService(notifyRepo, hub)
-	planService := subscription.NewPlanService(planRepo, logger)
-	customerService := customersvc.NewCustomerService(customerRepo, logger)
-	offerService := offerservice.NewOfferService(offerRepo, ussdCodeRepo, logger)
-	configService := configUsecase.NewConfigService(configRepo, logger)
+	planService := subscription.NewPlanService(planRepo, cache.NewPlanCache(redisClient), logger)
+	customerService := customersvc.NewCustomerService(customerRepo, customerNoteRepo, smsOutboxRepo, notifService, logger)
+	configService := configUsecase.NewConfigService(configRepo, cache.NewDevicePresence(redisClient), dbWrapper, logger)
+	authService.SetConfigService(configService)
+	offerService := offerservice.NewOfferService(offerservice.OfferRepositories{
+		Offers:        offerRepo,
+		USSDCodes:     ussdCodeRepo,
+		Templates:     offerTemplateRepo,
+		Customers:     customerRepo,
+		Auth:          authRepo,
+		Subscriptions: agentSubscriptionRepo,
+		Plans:         planRepo,
+	}, configService, dbWrapper, cache.NewOfferCache(redisClient), logger)
+	offerService.SetMinimumAmounts(offerMinimumAmounts(s.cfg))
+	offerService.SetMaxValidityDays(s.cfg.OfferMaxValidityDays)
+	offerService.SetNotificationService(notifService)
 	campaignService := campaignUsecase.NewCampaignService(campaignRepo, logger)
+	webhookService := webhookUsecase.NewWebhookService(webhookDeliveryRepo, configService, logger)
 	agentSubscriptionService := subscriptionUsecase.NewSubscriptionService(
 		agentSubscriptionRepo,
 		planRepo,
 		campaignRepo,
+		requestRepo,
+		configService,
+		notifService,
+		authRepo,
 		dbWrapper,
 		logger,
 	)
+	agentSubscriptionService.SetRenewalPricing(subscriptionDomain.RenewalPricing(s.cfg.PlanRenewalPricing))
+	agentSubscriptionService.SetUsagePolicy(subscriptionDomain.UsagePolicy(s.cfg.PlanChangeUsagePolicy))
+	scheduleService := scheduleUsecase.NewScheduleService(
+		scheduleRepo,
+		scheduleHistoryRepo,
+		redemptionRepo,
+		offerRepo,
+		customerRepo,
+		customerService,
+		dbWrapper,
+		offerService,
+		logger,
+	)
+	scheduleService.SetConfigService(configService)
+	deviceSlots := cache.NewDeviceSlots(redisClient, s.cfg.ProcessingTimeout)
 	transactionService := transactionUsecase.NewTransactionService(
 		requestRepo,
 		redemptionRepo,
 		offerRepo,
 		customerRepo,
+		transactionAuditRepo,
 		offerService,
 		customerService,
 		agentSubscriptionService,
+		scheduleService,
+		configService,
+		deviceSlots,
 		dbWrapper,
 		logger,
 	)
-	scheduleService := scheduleUsecase.NewScheduleService(
-		scheduleRepo,
-		scheduleHistoryRepo,
+	transactionService.SetPaymentAmountPolicy(s.cfg.PaymentAmountTolerance, s.cfg.RejectUnderpayments)
+	transactionService.SetRiskHoldThreshold(s.cfg.RiskHoldThreshold)
+	transactionService.SetConfirmationWindow(s.cfg.ConfirmationWindow)
+	transactionService.SetBatchLease(s.cfg.ProcessingTimeout)
+
+	// ----- Workers -----
+	renewalReminderWorker := subscriptionUsecase.NewRenewalReminderWorker(
+		agentSubscriptionService,
+		agentSubscriptionRepo,
+		planRepo,
+		authRepo,
+		emailSender,
+		s.cfg.RenewalReminderDays,
+		s.cfg.RenewalReminderInterval,
+		logger,
+	)
+	go renewalReminderWorker.Start(context.Background())
+
+	redemptionExpiryWorker := transactionUsecase.NewRedemptionExpiryWorker(
 		redemptionRepo,
 		offerRepo,
 		customerRepo,
-		customerService,
+		smsOutboxRepo,
+		notifService,
+		emailSender,
+		s.cfg.RedemptionExpiryNotice,
+		s.cfg.RedemptionExpiryInterval,
+		logger,
+	)
+	go redemptionExpiryWorker.Start(context.Background())
+
+	processingTimeoutWorker := transactionUsecase.NewProcessingTimeoutWorker(
+		requestRepo,
+		redemptionRepo,
+		transactionAuditRepo,
+		deviceSlots,
 		dbWrapper,
-		offerService,
+		s.cfg.ProcessingTimeout,
+		s.cfg.ProcessingTimeoutInterval,
+		logger,
+	)
+	go processingTimeoutWorker.Start(context.Background())
+
+	confirmationExpiryWorker := transactionUsecase.NewConfirmationExpiryWorker(
+		requestRepo,
+		redemptionRepo,
+		transactionAuditRepo,
+		dbWrapper,
+		s.cfg.ConfirmationWindow,
+		s.cfg.ConfirmationExpiryInterval,
 		logger,
 	)
-	
+	go confirmationExpiryWorker.Start(context.Background())
+
+	roleExpiryWorker := authUsecase.NewRoleExpiryWorker(
+		authService,
+		notifService,
+		s.cfg.RoleExpiryInterval,
+		logger,
+	)
+	go roleExpiryWorker.Start(context.Background())
+
+	outboxRelay := webhookUsecase.NewOutboxRelay(
+		outboxRepo,
+		webhookService,
+		s.cfg.OutboxRelayInterval,
+		logger,
+	)
+	go outboxRelay.Start(context.Background())
+
+	smsRelay := smsUsecase.NewRelay(
+		smsOutboxRepo,
+		smsUsecase.NewSender(s.cfg.SMSAPIURL, s.cfg.SMSAPIKey, s.cfg.SMSSenderID),
+		s.cfg.SMSRelayInterval,
+		logger,
+	)
+	go smsRelay.Start(context.Background())
+
+	dailySummaryWorker := transactionUsecase.NewDailySummaryWorker(
+		transactionService,
+		configService,
+		notifService,
+		authRepo,
+		emailSender,
+		cache.NewOnceMarker(redisClient, "daily_summary"),
+		s.cfg.DailySummaryHour,
+		s.cfg.DailySummaryInterval,
+		logger,
+	)
+	go dailySummaryWorker.Start(context.Background())
+
+	analyticsDigestWorker := webhookUsecase.NewAnalyticsDigestWorker(
+		requestRepo,
+		configService,
+		outboxRepo,
+		cache.NewOnceMarker(redisClient, "analytics_digest"),
+		s.cfg.AnalyticsDigestHour,
+		s.cfg.AnalyticsDigestInterval,
+		logger,
+	)
+	go analyticsDigestWorker.Start(context.Background())
 
 	// ----- Initialize Super Admin -----
 	if err := s.initializeSuperAdmin(); err != nil {
@@ -202,9 +347,16 @@ func (s *Server) Start() error {
 	configHandlerInst := configHandler.NewConfigHandler(configService)
 	campaignHandlerInst := campaignHandler.NewCampaignHandler(campaignService)
 	transactionHandlerInst := transactionHandler.NewTransactionHandler(transactionService)
-	wsHandlerInst := wsHandler.NewWebSocketHandler(hub, logger)
+	wsHandlerInst := wsHandler.NewWebSocketHandler(hub, authService, logger)
 	scheduleHandlerInst := scheduleHandler.NewScheduleHandler(scheduleService)
 	agentSubscriptionHandlerInst := subscriptionHandler.NewAgentSubscriptionHandler(agentSubscriptionService)
+	webhookHandlerInst := webhookHandler.NewWebhookHandler(webhookService)
+	healthHandlerInst := healthHandler.NewHealthHandler(map[string]healthHandler.Probe{
+		"postgres": pool.Ping,
+		"redis": func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		},
+	}, logger)
 
 	// ----- Middlewares -----
 	authMiddleware := middleware.NewAuthMiddleware(authService)
@@ -228,6 +380,8 @@ func (s *Server) Start() error {
 		ScheduleHandler:          scheduleHandlerInst,
 		AgentSubscriptionHandler: agentSubscriptionHandlerInst,
 		WSHandler:                wsHandlerInst,
+		HealthHandler:            healthHandlerInst,
+		WebhookHandler:           webhookHandlerInst,
 		AuthMiddleware:           authMiddleware,
 	}
 	SetupRouter(s.engine, logger, handlers)
@@ -237,6 +391,24 @@ func (s *Server) Start() error {
 	return s.engine.Run(s.cfg.HTTPAddr)
 }
 
+// offerMinimumAmounts builds per-type offer minimums from config, keeping defaults for unset values
+func offerMinimumAmounts(cfg config.AppConfig) offerDomain.MinimumAmounts {
+	minAmounts := offerDomain.DefaultMinimumAmounts
+	if cfg.OfferMinDataMB > 0 {
+		minAmounts.DataMB = float64(cfg.OfferMinDataMB)
+	}
+	if cfg.OfferMinSMS > 0 {
+		minAmounts.SMS = float64(cfg.OfferMinSMS)
+	}
+	if cfg.OfferMinVoiceMinutes > 0 {
+		minAmounts.VoiceMinutes = float64(cfg.OfferMinVoiceMinutes)
+	}
+	if cfg.OfferMinComboUnits > 0 {
+		minAmounts.ComboUnits = float64(cfg.OfferMinComboUnits)
+	}
+	return minAmounts
+}
+
 // initializeSuperAdmin creates super admin if it doesn't exist
 func (s *Server) initializeSuperAdmin() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
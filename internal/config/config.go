@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +26,48 @@ type AppConfig struct {
 	SMTPPass     string
 	SMTPFromName string
 	SMTPSecure   bool
+
+	// SMS gateway for messages sent straight to customers
+	SMSAPIURL   string
+	SMSAPIKey   string
+	SMSSenderID string
+
+	// Workers
+	RenewalReminderDays        int
+	RenewalReminderInterval    time.Duration
+	RedemptionExpiryNotice     time.Duration // How far ahead customers are warned
+	RedemptionExpiryInterval   time.Duration
+	ProcessingTimeout          time.Duration // How long a request may sit in processing before it is failed
+	ProcessingTimeoutInterval  time.Duration
+	ConfirmationWindow         time.Duration // How long a confirmation-required request may wait before it is cancelled
+	ConfirmationExpiryInterval time.Duration
+	RoleExpiryInterval         time.Duration
+	OutboxRelayInterval        time.Duration
+	SMSRelayInterval           time.Duration
+	DailySummaryHour           int // Local hour after which the day's summary is sent
+	DailySummaryInterval       time.Duration
+	AnalyticsDigestHour        int // Local hour after which the previous period's analytics digest is sent
+	AnalyticsDigestInterval    time.Duration
+
+	// Payment amount checks on offer requests
+	PaymentAmountTolerance float64 // Allowed gap between the amount paid and the offer's price
+	RejectUnderpayments    bool
+	RiskHoldThreshold      int // Fraud score (0-100) at which pending requests are held for review; 0 disables
+
+	// Renewal price once a plan's price changes: grandfathered or current
+	PlanRenewalPricing string
+
+	// Request usage on a mid-cycle plan change: keep (default) or reset
+	PlanChangeUsagePolicy string
+
+	// Offer minimum amounts per type
+	OfferMinDataMB       int
+	OfferMinSMS          int
+	OfferMinVoiceMinutes int
+	OfferMinComboUnits   int
+
+	// Longest validity period an offer may have, in days
+	OfferMaxValidityDays int
 }
 
 // Load loads environment variables into AppConfig.
@@ -41,6 +84,7 @@ func Load() AppConfig {
 			Issuer:   "diary-app",
 			Audience: "diary-users",
 			TTL:      720 * time.Hour,
+			AdminTTL: getEnvDuration("ADMIN_TOKEN_TTL", 8*time.Hour),
 			KID:      "diary-key",
 		},
 
@@ -50,6 +94,41 @@ func Load() AppConfig {
 		SMTPPass:     getEnv("SMTP_PASS", ""),
 		SMTPFromName: getEnv("SMTP_FROM_NAME", "Diary App"),
 		SMTPSecure:   strings.ToLower(getEnv("SMTP_SECURE", "true")) == "true",
+
+		SMSAPIURL:   getEnv("SMS_API_URL", ""),
+		SMSAPIKey:   getEnv("SMS_API_KEY", ""),
+		SMSSenderID: getEnv("SMS_SENDER_ID", "BINGWA"),
+
+		RenewalReminderDays:     getEnvInt("RENEWAL_REMINDER_DAYS", 3),
+		RenewalReminderInterval: getEnvDuration("RENEWAL_REMINDER_INTERVAL", time.Hour),
+
+		RedemptionExpiryNotice:   getEnvDuration("REDEMPTION_EXPIRY_NOTICE", 24*time.Hour),
+		RedemptionExpiryInterval: getEnvDuration("REDEMPTION_EXPIRY_INTERVAL", 15*time.Minute),
+
+		ProcessingTimeout:          getEnvDuration("PROCESSING_TIMEOUT", 10*time.Minute),
+		ProcessingTimeoutInterval:  getEnvDuration("PROCESSING_TIMEOUT_INTERVAL", time.Minute),
+		ConfirmationWindow:         getEnvDuration("CONFIRMATION_WINDOW", 15*time.Minute),
+		ConfirmationExpiryInterval: getEnvDuration("CONFIRMATION_EXPIRY_INTERVAL", time.Minute),
+		RoleExpiryInterval:         getEnvDuration("ROLE_EXPIRY_INTERVAL", 5*time.Minute),
+		OutboxRelayInterval:        getEnvDuration("OUTBOX_RELAY_INTERVAL", 10*time.Second),
+		SMSRelayInterval:           getEnvDuration("SMS_RELAY_INTERVAL", 10*time.Second),
+		DailySummaryHour:           getEnvInt("DAILY_SUMMARY_HOUR", 21),
+		DailySummaryInterval:       getEnvDuration("DAILY_SUMMARY_INTERVAL", 15*time.Minute),
+		AnalyticsDigestHour:        getEnvInt("ANALYTICS_DIGEST_HOUR", 6),
+		AnalyticsDigestInterval:    getEnvDuration("ANALYTICS_DIGEST_INTERVAL", 15*time.Minute),
+
+		PaymentAmountTolerance: getEnvFloat("PAYMENT_AMOUNT_TOLERANCE", 1),
+		RejectUnderpayments:    strings.ToLower(getEnv("REJECT_UNDERPAYMENTS", "false")) == "true",
+		RiskHoldThreshold:      getEnvInt("RISK_HOLD_THRESHOLD", 70),
+
+		PlanRenewalPricing:    strings.ToLower(getEnv("PLAN_RENEWAL_PRICING", "grandfathered")),
+		PlanChangeUsagePolicy: strings.ToLower(getEnv("PLAN_CHANGE_USAGE_POLICY", "keep")),
+
+		OfferMinDataMB:       getEnvInt("OFFER_MIN_DATA_MB", 1),
+		OfferMinSMS:          getEnvInt("OFFER_MIN_SMS", 1),
+		OfferMinVoiceMinutes: getEnvInt("OFFER_MIN_VOICE_MINUTES", 1),
+		OfferMinComboUnits:   getEnvInt("OFFER_MIN_COMBO_UNITS", 1),
+		OfferMaxValidityDays: getEnvInt("OFFER_MAX_VALIDITY_DAYS", 365),
 	}
 }
 
@@ -62,6 +141,35 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// getEnvDuration parses a positive duration; zero and negative values fall back, since they
+// would stop tickers and leases from working
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
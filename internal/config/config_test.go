@@ -0,0 +1,30 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"unset", "", time.Minute},
+		{"valid", "30s", 30 * time.Second},
+		{"malformed", "soon", time.Minute},
+		{"zero", "0s", time.Minute},
+		{"negative", "-5m", time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_INTERVAL", tt.value)
+			if got := getEnvDuration("TEST_INTERVAL", time.Minute); got != tt.want {
+				t.Errorf("getEnvDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,11 +8,14 @@ BEGIN;
 CREATE TYPE offer_type AS ENUM ('data', 'sms', 'voice', 'combo');
 CREATE TYPE offer_units AS ENUM ('GB', 'MB', 'KB', 'minutes', 'sms', 'units');
 CREATE TYPE offer_status AS ENUM ('active', 'inactive', 'paused', 'suspended', 'archived');
-CREATE TYPE transaction_status AS ENUM ('pending', 'processing', 'success', 'failed', 'cancelled', 'reversed');
+CREATE TYPE transaction_status AS ENUM ('pending', 'pending_confirmation', 'processing', 'success', 'failed', 'cancelled', 'reversed');
 CREATE TYPE subscription_status AS ENUM ('active', 'inactive', 'expired', 'cancelled', 'suspended');
 CREATE TYPE ussd_processing_type AS ENUM ('express', 'multistep', 'callback');
 CREATE TYPE renewal_period AS ENUM ('daily', 'weekly', 'monthly', 'quarterly', 'yearly');
 CREATE TYPE payment_method AS ENUM ('mpesa', 'airtel_money', 'tigopesa', 'card', 'bank', 'agent_balance');
+CREATE TYPE request_source AS ENUM ('ussd', 'app', 'web', 'unknown');
+CREATE TYPE purchase_limit_period AS ENUM ('lifetime', 'daily', 'weekly', 'monthly');
+CREATE TYPE failure_code AS ENUM ('insufficient_balance', 'ussd_timeout', 'invalid_code', 'network_error', 'invalid_number', 'service_unavailable', 'cancelled_by_user', 'unknown');
 
 -- ============================================
 -- AGENT CUSTOMERS (Non-login users)
@@ -39,6 +42,7 @@ CREATE TABLE IF NOT EXISTS agent_customers (
     metadata JSONB, -- Flexible field for additional data
     
     -- Timestamps
+    last_activity_at TIMESTAMPTZ, -- Last offer request made for this customer
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     deleted_at TIMESTAMPTZ,
@@ -53,6 +57,36 @@ CREATE TABLE IF NOT EXISTS agent_customers (
 CREATE INDEX idx_agent_customers_agent ON agent_customers(agent_identity_id) WHERE deleted_at IS NULL;
 CREATE INDEX idx_agent_customers_phone ON agent_customers(phone_number) WHERE deleted_at IS NULL;
 CREATE INDEX idx_agent_customers_active ON agent_customers(agent_identity_id, is_active) WHERE deleted_at IS NULL;
+CREATE INDEX idx_agent_customers_last_activity ON agent_customers(agent_identity_id, last_activity_at) WHERE deleted_at IS NULL;
+
+-- Freeform notes attached to customers
+CREATE TABLE IF NOT EXISTS customer_notes (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL, -- Agent who owns the customer
+    author_identity_id BIGINT NOT NULL, -- Identity that wrote the note (agent or admin)
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_customer_note_customer FOREIGN KEY (customer_id)
+        REFERENCES agent_customers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_customer_note_author FOREIGN KEY (author_identity_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_customer_notes_customer ON customer_notes(customer_id, created_at DESC);
+
+-- Pending SMS verification codes (one per customer; replaced on resend)
+CREATE TABLE IF NOT EXISTS customer_verification_codes (
+    customer_id BIGINT PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL, -- SHA-256 of the code
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_customer_verification_customer FOREIGN KEY (customer_id)
+        REFERENCES agent_customers(id) ON DELETE CASCADE
+);
 
 -- ============================================
 -- AGENT OFFERS
@@ -88,7 +122,14 @@ CREATE TABLE IF NOT EXISTS agent_offers (
     is_featured BOOLEAN DEFAULT FALSE,
     is_recurring BOOLEAN DEFAULT FALSE, -- Can be auto-renewed
     max_purchases_per_customer INT, -- Limit purchases per customer
+    purchase_limit_period purchase_limit_period NOT NULL DEFAULT 'lifetime', -- Window the purchase limit applies to
+    purchase_cooldown_seconds INT, -- Minimum gap between a customer's successful purchases
+    required_device_id VARCHAR(255), -- Android device that must be online to fulfil the offer
+    require_confirmation BOOLEAN NOT NULL DEFAULT FALSE, -- High-value offers: requests wait in pending_confirmation until confirmed
     
+    -- Stock
+    stock_limit INT CHECK (stock_limit >= 0), -- Units left to sell, taken as requests are created; NULL = unlimited
+
     -- Status
     status offer_status NOT NULL DEFAULT 'active',
     available_from TIMESTAMPTZ,
@@ -153,6 +194,64 @@ CREATE INDEX idx_offer_ussd_codes_priority ON offer_ussd_codes(offer_id, priorit
 CREATE INDEX idx_offer_ussd_codes_success ON offer_ussd_codes(offer_id, success_count DESC);
 COMMIT;
 
+-- Test executions of USSD codes (kept out of success/failure stats)
+CREATE TABLE IF NOT EXISTS offer_ussd_code_tests (
+    id BIGSERIAL PRIMARY KEY,
+    ussd_code_id BIGINT NOT NULL,
+    offer_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL,
+    test_phone VARCHAR(20) NOT NULL,
+    raw_response TEXT NOT NULL,
+    result VARCHAR(20) NOT NULL, -- success, failed, unknown
+    matched_pattern VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_ussd_test_code FOREIGN KEY (ussd_code_id)
+        REFERENCES offer_ussd_codes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_offer_ussd_code_tests_code ON offer_ussd_code_tests(ussd_code_id, created_at DESC);
+
+-- Admin-curated offer templates agents can instantiate as offers
+CREATE TABLE IF NOT EXISTS offer_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type offer_type NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    units offer_units NOT NULL,
+    price NUMERIC(10, 2) NOT NULL, -- Suggested price, overridable on instantiation
+    currency VARCHAR(3) DEFAULT 'KES',
+    validity_days INT NOT NULL,
+    validity_label VARCHAR(50),
+    ussd_code_template VARCHAR(255) NOT NULL,
+    ussd_processing_type ussd_processing_type NOT NULL DEFAULT 'express',
+    ussd_expected_response VARCHAR(255),
+    ussd_error_pattern VARCHAR(255),
+    tags VARCHAR(50)[],
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_offer_templates_active ON offer_templates(type, amount) WHERE is_active = TRUE;
+
+-- Offer price changes (manual edits and bulk adjustments)
+CREATE TABLE IF NOT EXISTS offer_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    offer_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL,
+    old_price NUMERIC(10, 2) NOT NULL,
+    new_price NUMERIC(10, 2) NOT NULL,
+    source VARCHAR(20) NOT NULL, -- manual, bulk_adjust
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_price_history_offer FOREIGN KEY (offer_id)
+        REFERENCES agent_offers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_offer_price_history_offer ON offer_price_history(offer_id, created_at DESC);
+
 -- Keep the old columns for backward compatibility, but they'll reference the primary USSD
 -- In migration, we can move existing data to the new table
 
@@ -185,9 +284,21 @@ CREATE TABLE IF NOT EXISTS offer_requests (
     -- Request details
     request_time TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     processed_at TIMESTAMPTZ,
+    processing_started_at TIMESTAMPTZ, -- When the request last entered processing; the processing timeout runs from here
     status transaction_status NOT NULL DEFAULT 'pending',
     failure_reason TEXT,
+    failure_code failure_code, -- Categorised failure for analytics
     retry_count INT DEFAULT 0,
+    last_retry_at TIMESTAMPTZ, -- Agents must wait the configured cooldown between retries
+    source request_source NOT NULL DEFAULT 'unknown', -- Channel the request came from
+    risk_score INT NOT NULL DEFAULT 0 CHECK (risk_score BETWEEN 0 AND 100), -- Fraud risk from velocity and amount anomalies
+    held_for_review BOOLEAN NOT NULL DEFAULT FALSE, -- High-risk pending requests wait for agent review before dispatch
+    leased_until TIMESTAMPTZ, -- Pending requests handed to a device are hidden from other batch fetches until this passes
+    leased_by VARCHAR(255), -- Device holding the lease
+    
+    -- Location (optional, for sales maps)
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
     
     -- Metadata
     device_info JSONB, -- Device that made the request
@@ -210,7 +321,15 @@ CREATE INDEX idx_offer_requests_agent ON offer_requests(agent_identity_id);
 CREATE INDEX idx_offer_requests_customer ON offer_requests(customer_id);
 CREATE INDEX idx_offer_requests_phone ON offer_requests(customer_phone);
 CREATE INDEX idx_offer_requests_status ON offer_requests(status);
+CREATE INDEX idx_offer_requests_source ON offer_requests(agent_identity_id, source);
+CREATE INDEX idx_offer_requests_failure_code ON offer_requests(agent_identity_id, failure_code) WHERE status = 'failed';
+CREATE INDEX idx_offer_requests_location ON offer_requests(agent_identity_id, latitude, longitude) WHERE latitude IS NOT NULL;
 CREATE INDEX idx_offer_requests_mpesa ON offer_requests(mpesa_transaction_id) WHERE mpesa_transaction_id IS NOT NULL;
+CREATE INDEX idx_offer_requests_held ON offer_requests(agent_identity_id) WHERE held_for_review;
+CREATE INDEX idx_offer_requests_dispatch ON offer_requests(agent_identity_id, created_at) WHERE status = 'pending' AND NOT held_for_review;
+CREATE INDEX idx_offer_requests_unconfirmed ON offer_requests(created_at) WHERE status = 'pending_confirmation';
+CREATE INDEX idx_offer_requests_phone_time ON offer_requests(agent_identity_id, customer_phone, request_time);
+CREATE UNIQUE INDEX idx_offer_requests_mpesa_receipt ON offer_requests(agent_identity_id, mpesa_receipt_number) WHERE mpesa_receipt_number IS NOT NULL;
 CREATE INDEX idx_offer_requests_created ON offer_requests(created_at DESC);
 
 -- ============================================
@@ -248,6 +367,7 @@ CREATE TABLE IF NOT EXISTS offer_redemptions (
     -- Validity
     valid_from TIMESTAMPTZ,
     valid_until TIMESTAMPTZ,
+    expiry_notified_for TIMESTAMPTZ, -- valid_until the expiry notice was sent for; a renewal moves valid_until on
     
     -- Metadata
     metadata JSONB,
@@ -273,6 +393,27 @@ CREATE INDEX idx_redemptions_customer ON offer_redemptions(customer_id) WHERE cu
 CREATE INDEX idx_redemptions_status ON offer_redemptions(status);
 CREATE INDEX idx_redemptions_created ON offer_redemptions(created_at DESC);
 
+-- ============================================
+-- TRANSACTION AUDIT (append-only, for disputes)
+-- ============================================
+CREATE TABLE IF NOT EXISTS transaction_audit (
+    id BIGSERIAL PRIMARY KEY,
+    offer_request_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL,
+    event VARCHAR(30) NOT NULL, -- request_created, status_updated, admin_retry, mpesa_payment, confirmed
+    status transaction_status NOT NULL, -- Request status after the event
+    payload JSONB, -- PII-masked request input or status update details
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    -- Audited requests and agents can't be deleted out from under their trail
+    CONSTRAINT fk_transaction_audit_request FOREIGN KEY (offer_request_id)
+        REFERENCES offer_requests(id) ON DELETE RESTRICT,
+    CONSTRAINT fk_transaction_audit_agent FOREIGN KEY (agent_identity_id)
+        REFERENCES auth_identities(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_transaction_audit_request ON transaction_audit(offer_request_id, id);
+
 -- ============================================
 -- SCHEDULED OFFERS (Auto-renewal)
 -- ============================================
@@ -294,6 +435,7 @@ CREATE TABLE IF NOT EXISTS scheduled_offers (
     -- Auto-renewal configuration
     auto_renew BOOLEAN DEFAULT FALSE,
     renewal_period renewal_period,
+    recurrence_pattern VARCHAR(100), -- e.g. 'every monday 08:00'; takes precedence over renewal_period
     renewal_count INT DEFAULT 0,
     renewal_limit INT, -- NULL = unlimited
     renew_until TIMESTAMPTZ, -- Stop renewals after this date
@@ -304,6 +446,10 @@ CREATE TABLE IF NOT EXISTS scheduled_offers (
     cancelled_at TIMESTAMPTZ,
     cancellation_reason TEXT,
     
+    -- Execution claim (lease held by a batch processor)
+    claimed_by VARCHAR(64),
+    claim_expires_at TIMESTAMPTZ,
+    
     -- Metadata
     metadata JSONB,
     
@@ -378,11 +524,14 @@ CREATE TABLE IF NOT EXISTS subscription_plans (
     price NUMERIC(10, 2) NOT NULL,
     currency VARCHAR(3) DEFAULT 'KES',
     setup_fee NUMERIC(10, 2) DEFAULT 0,
+    version INT NOT NULL DEFAULT 1, -- Bumped whenever the price changes
     
     -- Billing
     billing_usage INT NOT NULL, -- Number of requests/redemptions allowed
     billing_cycle renewal_period NOT NULL,
     overage_charge NUMERIC(10, 2), -- Charge per extra request
+    limit_behavior VARCHAR(20), -- block, warn, overage; NULL derives from overage_charge
+    allowed_transitions BIGINT[], -- Plan IDs subscribers may change to; NULL allows all
     
     -- Features (what agents get)
     max_offers INT, -- Max offers agent can create
@@ -430,6 +579,14 @@ CREATE TABLE IF NOT EXISTS promotional_campaigns (
     uses_per_user INT DEFAULT 1,
     current_uses INT DEFAULT 0,
     
+    -- Budget
+    total_budget NUMERIC(12, 2), -- Cap on cumulative discount given; NULL is unlimited
+    discount_given NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    deactivate_on_budget_spent BOOLEAN NOT NULL DEFAULT FALSE,
+    
+    -- A/B test
+    variants JSONB, -- Discount variants: [{name, discount_value, weight}]; NULL runs the campaign discount for everyone
+    
     -- Targeting
     applicable_plans BIGINT[], -- Array of plan IDs
     target_user_types VARCHAR(50)[], -- e.g., ['new_users', 'existing_users']
@@ -449,6 +606,26 @@ CREATE INDEX idx_campaigns_status ON promotional_campaigns(status);
 CREATE INDEX idx_campaigns_code ON promotional_campaigns(promotional_code);
 CREATE INDEX idx_campaigns_dates ON promotional_campaigns(start_date, end_date) WHERE status = 'active';
 
+-- ============================================
+-- CAMPAIGN VARIANT ASSIGNMENTS (A/B exposure and conversion per user)
+-- ============================================
+CREATE TABLE IF NOT EXISTS campaign_variant_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL,
+    identity_id BIGINT NOT NULL,
+    variant VARCHAR(50) NOT NULL,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    conversions INT NOT NULL DEFAULT 0, -- Times the user used the code
+    discount_given NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    last_converted_at TIMESTAMPTZ,
+    
+    CONSTRAINT fk_variant_assignment_campaign FOREIGN KEY (campaign_id) 
+        REFERENCES promotional_campaigns(id) ON DELETE CASCADE,
+    CONSTRAINT uq_variant_assignment UNIQUE (campaign_id, identity_id)
+);
+
+CREATE INDEX idx_variant_assignments_campaign ON campaign_variant_assignments(campaign_id, variant);
+
 -- ============================================
 -- AGENT SUBSCRIPTIONS
 -- ============================================
@@ -475,9 +652,12 @@ CREATE TABLE IF NOT EXISTS agent_subscriptions (
     -- Usage tracking
     requests_used INT DEFAULT 0,
     requests_limit INT,
+    usage_alert_level INT NOT NULL DEFAULT 0, -- Highest usage alert threshold (percent) sent this period
+    usage_settled_period_end TIMESTAMPTZ, -- Period end whose usage was last billed and reset
     
     -- Pricing (snapshot at subscription time)
     plan_price NUMERIC(10, 2) NOT NULL,
+    plan_version INT NOT NULL DEFAULT 1, -- Plan version plan_price was taken from
     discount_applied NUMERIC(10, 2) DEFAULT 0,
     amount_paid NUMERIC(10, 2) NOT NULL,
     currency VARCHAR(3) DEFAULT 'KES',
@@ -507,6 +687,88 @@ CREATE INDEX idx_subscriptions_plan ON agent_subscriptions(subscription_plan_id)
 CREATE INDEX idx_subscriptions_status ON agent_subscriptions(status);
 CREATE INDEX idx_subscriptions_next_billing ON agent_subscriptions(next_billing_date) WHERE status = 'active';
 
+-- ============================================
+-- SUBSCRIPTION BILLING RECORDS (one row per charge)
+-- ============================================
+CREATE TABLE IF NOT EXISTS subscription_billing_records (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL,
+    event VARCHAR(50) NOT NULL, -- subscribed, renewed, plan_changed, overage
+    subscription_plan_id BIGINT NOT NULL,
+    
+    -- Billed period
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    
+    -- Charges
+    plan_price NUMERIC(10, 2) NOT NULL,
+    setup_fee NUMERIC(10, 2) DEFAULT 0,
+    discount_applied NUMERIC(10, 2) DEFAULT 0,
+    amount_paid NUMERIC(10, 2) NOT NULL,
+    currency VARCHAR(3) DEFAULT 'KES',
+    promotional_campaign_id BIGINT,
+    payment_reference VARCHAR(255),
+    
+    -- Overage (requests past the limit, billed at period end)
+    overage_requests INT DEFAULT 0,
+    overage_rate NUMERIC(10, 2),
+    amount_due NUMERIC(10, 2) DEFAULT 0, -- Billed but not yet paid
+    
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    CONSTRAINT fk_billing_record_subscription FOREIGN KEY (subscription_id) 
+        REFERENCES agent_subscriptions(id) ON DELETE CASCADE,
+    CONSTRAINT fk_billing_record_campaign FOREIGN KEY (promotional_campaign_id) 
+        REFERENCES promotional_campaigns(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_billing_records_subscription ON subscription_billing_records(subscription_id, created_at);
+CREATE UNIQUE INDEX idx_billing_records_overage_period ON subscription_billing_records(subscription_id, period_end) WHERE event = 'overage';
+
+-- ============================================
+-- SUB-AGENTS (child agents sharing a parent agent's subscription)
+-- ============================================
+CREATE TABLE IF NOT EXISTS agent_sub_agents (
+    id BIGSERIAL PRIMARY KEY,
+    parent_agent_id BIGINT NOT NULL,
+    child_agent_id BIGINT NOT NULL UNIQUE, -- A child belongs to one parent
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending (awaiting the child's acceptance), active
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_sub_agent_not_self CHECK (parent_agent_id <> child_agent_id),
+    CONSTRAINT fk_sub_agent_parent FOREIGN KEY (parent_agent_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE,
+    CONSTRAINT fk_sub_agent_child FOREIGN KEY (child_agent_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sub_agents_parent ON agent_sub_agents(parent_agent_id);
+
+-- Sub-agents are one level deep: a child can't be a parent and a parent can't be a child
+CREATE OR REPLACE FUNCTION enforce_sub_agent_depth()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- Serialise links so two concurrent inserts can't build a chain between them
+    PERFORM pg_advisory_xact_lock(hashtext('agent_sub_agents'));
+
+    IF EXISTS (SELECT 1 FROM agent_sub_agents WHERE child_agent_id = NEW.parent_agent_id AND id <> NEW.id) THEN
+        RAISE EXCEPTION 'agent % is a sub-agent and cannot have sub-agents', NEW.parent_agent_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    IF EXISTS (SELECT 1 FROM agent_sub_agents WHERE parent_agent_id = NEW.child_agent_id AND id <> NEW.id) THEN
+        RAISE EXCEPTION 'agent % has sub-agents and cannot become one', NEW.child_agent_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER enforce_sub_agent_depth BEFORE INSERT OR UPDATE OF parent_agent_id, child_agent_id ON agent_sub_agents
+    FOR EACH ROW EXECUTE FUNCTION enforce_sub_agent_depth();
+
 -- ============================================
 -- AGENT CONFIGURATIONS
 -- ============================================
@@ -538,6 +800,134 @@ CREATE TABLE IF NOT EXISTS agent_configs (
 CREATE INDEX idx_agent_configs_agent ON agent_configs(agent_identity_id);
 CREATE INDEX idx_agent_configs_key ON agent_configs(config_key);
 
+-- ============================================
+-- AGENT CONFIGURATION HISTORY
+-- ============================================
+CREATE TABLE IF NOT EXISTS agent_config_history (
+    id BIGSERIAL PRIMARY KEY,
+    agent_config_id BIGINT NOT NULL,
+    agent_identity_id BIGINT NOT NULL,
+    
+    -- Snapshot of the value being replaced
+    config_key VARCHAR(255) NOT NULL,
+    previous_value JSONB NOT NULL,
+    new_value JSONB NOT NULL,
+    
+    -- Timestamps
+    changed_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    CONSTRAINT fk_config_history_config FOREIGN KEY (agent_config_id) 
+        REFERENCES agent_configs(id) ON DELETE CASCADE,
+    CONSTRAINT fk_config_history_agent FOREIGN KEY (agent_identity_id) 
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_agent_config_history_config ON agent_config_history(agent_config_id, changed_at DESC);
+
+-- ============================================
+-- WEBHOOK DELIVERIES
+-- ============================================
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    agent_identity_id BIGINT NOT NULL,
+
+    -- Event
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+
+    -- Delivery state
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, delivered, failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_status_code INT,
+    last_error TEXT,
+    last_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    outbox_event_id BIGINT UNIQUE, -- Outbox event relayed by this delivery; shared by every relay attempt
+
+    -- Timestamps
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_webhook_delivery_agent FOREIGN KEY (agent_identity_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_agent_status ON webhook_deliveries(agent_identity_id, status, created_at);
+
+-- ============================================
+-- OUTBOX (events written with the change that caused them, relayed to webhooks)
+-- ============================================
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    agent_identity_id BIGINT NOT NULL,
+
+    -- Event
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+
+    -- Relay state
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, sent, failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ, -- Lease held by the relay instance delivering the event
+    sent_at TIMESTAMPTZ,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_outbox_event_agent FOREIGN KEY (agent_identity_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_outbox_events_due ON outbox_events(next_attempt_at) WHERE status = 'pending';
+
+-- ============================================
+-- SMS OUTBOX (messages sent straight to customers' phones)
+-- ============================================
+CREATE TABLE IF NOT EXISTS sms_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    agent_identity_id BIGINT NOT NULL,
+
+    -- Message
+    phone_number VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL, -- Blanked once sent when sensitive
+    purpose VARCHAR(50) NOT NULL, -- verification_code, redemption_expiry, ...
+    sensitive BOOLEAN NOT NULL DEFAULT FALSE, -- Body holds a secret (e.g. a verification code)
+
+    -- Relay state
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, sent, failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ, -- Lease held by the relay instance sending the message
+    sent_at TIMESTAMPTZ,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT fk_sms_outbox_agent FOREIGN KEY (agent_identity_id)
+        REFERENCES auth_identities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sms_outbox_due ON sms_outbox(next_attempt_at) WHERE status = 'pending';
+
+-- ============================================
+-- EMAIL LOG (one row per send attempt)
+-- ============================================
+CREATE TABLE IF NOT EXISTS email_log (
+    id BIGSERIAL PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL, -- password_reset, welcome, renewal_reminder, ...
+    subject VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL, -- sent, failed
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_log_recipient ON email_log(LOWER(recipient), created_at DESC);
+CREATE INDEX idx_email_log_status ON email_log(status, created_at DESC);
+
 -- ============================================
 -- TRIGGERS FOR UPDATED_AT
 -- ============================================
@@ -549,6 +939,17 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Audit entries are immutable once written: no updates and no deletes
+CREATE OR REPLACE FUNCTION prevent_transaction_audit_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'transaction_audit entries are immutable (% rejected)', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_transaction_audit_change BEFORE UPDATE OR DELETE ON transaction_audit
+    FOR EACH ROW EXECUTE FUNCTION prevent_transaction_audit_change();
+
 CREATE TRIGGER update_agent_customers_updated_at BEFORE UPDATE ON agent_customers
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -570,4 +971,7 @@ CREATE TRIGGER update_agent_subscriptions_updated_at BEFORE UPDATE ON agent_subs
 CREATE TRIGGER update_agent_configs_updated_at BEFORE UPDATE ON agent_configs
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_webhook_deliveries_updated_at BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 COMMIT;
\ No newline at end of file
@@ -1,26 +1,37 @@
 // internal/domain/auth/dto.go
 package auth
 
-import "time"
+import (
+	"time"
+
+	"bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/subscription"
+	"bingwa-service/internal/domain/transaction"
+)
 
 // RegisterRequest for user registration
+// DeviceFingerprintHeader carries the client's device fingerprint on login and authenticated requests
+const DeviceFingerprintHeader = "X-Device-Fingerprint"
+
 type RegisterRequest struct {
-	Email     string `json:"email" binding:"required,email"`
-	Phone     string `json:"phone"`
-	Password  string `json:"password" binding:"required,min=8"`
-	FullName  string `json:"full_name" binding:"required"`
-	Device    string `json:"device"`
-	IPAddress string `json:"-"`
-	UserAgent string `json:"-"`
+	Email             string `json:"email" binding:"required,email"`
+	Phone             string `json:"phone"`
+	Password          string `json:"password" binding:"required,min=8"`
+	FullName          string `json:"full_name" binding:"required"`
+	Device            string `json:"device"`
+	DeviceFingerprint string `json:"-"`
+	IPAddress         string `json:"-"`
+	UserAgent         string `json:"-"`
 }
 
 // LoginRequest for user login
 type LoginRequest struct {
-	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required"`
-	Device    string `json:"device"`
-	IPAddress string `json:"-"`
-	UserAgent string `json:"-"`
+	Email             string `json:"email" binding:"required,email"`
+	Password          string `json:"password" binding:"required"`
+	Device            string `json:"device"`
+	DeviceFingerprint string `json:"-"`
+	IPAddress         string `json:"-"`
+	UserAgent         string `json:"-"`
 }
 
 // LoginResponse successful login response
@@ -41,6 +52,30 @@ type CreateAdminRequest struct {
 	Roles    []string `json:"roles" binding:"required"` // e.g., ["admin"] or ["super_admin"]
 }
 
+// CreateRoleRequest for creating a custom role
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	DisplayName string `json:"display_name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// AssignRoleRequest assigns a role to a user; without ExpiresAt the role is permanent
+type AssignRoleRequest struct {
+	RoleName  string     `json:"role_name" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RolePermissionRequest adds a permission to a role
+type RolePermissionRequest struct {
+	PermissionID int64 `json:"permission_id" binding:"required"`
+}
+
+// UserPermissionRequest grants a permission to a single user, optionally until ExpiresAt
+type UserPermissionRequest struct {
+	PermissionID int64      `json:"permission_id" binding:"required"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
 // UserInfo minimal user information
 type UserInfo struct {
 	IdentityID  int64    `json:"identity_id"`
@@ -56,6 +91,11 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
 
+// ChangeEmailRequest for starting an email change
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
 // ForgotPasswordRequest for password reset
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -73,4 +113,17 @@ type UpdateProfileRequest struct {
 	AvatarURL string                 `json:"avatar_url"`
 	Bio       string                 `json:"bio"`
 	Metadata  map[string]interface{} `json:"metadata"`
-}
\ No newline at end of file
+}
+
+// UserDataExport bundles everything held about a user for data portability requests
+type UserDataExport struct {
+	ExportedAt    time.Time                        `json:"exported_at"`
+	Identity      *Identity                        `json:"identity"`
+	Profile       *UserProfile                     `json:"profile,omitempty"`
+	Roles         []string                         `json:"roles"`
+	Sessions      []Session                        `json:"sessions"`
+	Offers        []offer.AgentOffer               `json:"offers"`
+	OfferRequests []transaction.OfferRequest       `json:"offer_requests"`
+	Redemptions   []transaction.OfferRedemption    `json:"redemptions"`
+	Subscriptions []subscription.AgentSubscription `json:"subscriptions"`
+}
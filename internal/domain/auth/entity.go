@@ -99,4 +99,24 @@ type Role struct {
 	IsActive    bool      `json:"is_active" db:"is_active"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ExpiredRoleAssignment is a temporary role assignment that has lapsed
+type ExpiredRoleAssignment struct {
+	IdentityID      int64     `json:"identity_id" db:"identity_id"`
+	RoleID          int64     `json:"role_id" db:"role_id"`
+	RoleName        string    `json:"role_name" db:"role_name"`
+	RoleDisplayName string    `json:"role_display_name" db:"role_display_name"`
+	ExpiresAt       time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type Permission struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	Description string    `json:"description" db:"description"`
+	Resource    string    `json:"resource" db:"resource"`
+	Action      string    `json:"action" db:"action"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
\ No newline at end of file
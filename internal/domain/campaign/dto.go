@@ -21,10 +21,17 @@ type CreateCampaignRequest struct {
 	MaxUses     *int32 `json:"max_uses" binding:"omitempty,min=1"`
 	UsesPerUser int    `json:"uses_per_user" binding:"min=1"`
 	
+	// Budget
+	TotalBudget             *float64 `json:"total_budget" binding:"omitempty,gt=0"`
+	DeactivateOnBudgetSpent bool     `json:"deactivate_on_budget_spent"`
+	
 	// Targeting
 	ApplicablePlans []int64  `json:"applicable_plans"`
 	TargetUserTypes []string `json:"target_user_types"`
 	
+	// A/B test
+	Variants []CampaignVariant `json:"variants" binding:"omitempty,dive"`
+	
 	// Metadata
 	Metadata map[string]interface{} `json:"metadata"`
 }
@@ -45,10 +52,17 @@ type UpdateCampaignRequest struct {
 	MaxUses     *int32 `json:"max_uses" binding:"omitempty,min=1"`
 	UsesPerUser *int   `json:"uses_per_user" binding:"omitempty,min=1"`
 	
+	// Budget
+	TotalBudget             *float64 `json:"total_budget" binding:"omitempty,min=0"` // 0 removes the budget
+	DeactivateOnBudgetSpent *bool    `json:"deactivate_on_budget_spent"`
+	
 	// Targeting
 	ApplicablePlans []int64  `json:"applicable_plans"`
 	TargetUserTypes []string `json:"target_user_types"`
 	
+	// A/B test; an empty list ends the test
+	Variants []CampaignVariant `json:"variants" binding:"omitempty,dive"`
+	
 	// Metadata
 	Metadata map[string]interface{} `json:"metadata"`
 }
@@ -81,7 +95,14 @@ type ValidateCampaignRequest struct {
 type ValidateCampaignResponse struct {
 	Valid             bool              `json:"valid"`
 	Campaign          *PromotionalCampaign `json:"campaign,omitempty"`
+	Variant           string            `json:"variant,omitempty"` // A/B variant the user was placed in
 	DiscountAmount    float64           `json:"discount_amount"`
 	FinalPrice        float64           `json:"final_price"`
 	Message           string            `json:"message"`
+}
+
+// CampaignVariantReport breaks a campaign's usage down by A/B variant
+type CampaignVariantReport struct {
+	CampaignID int64                  `json:"campaign_id"`
+	Variants   []CampaignVariantStats `json:"variants"`
 }
\ No newline at end of file
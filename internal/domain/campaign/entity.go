@@ -3,6 +3,8 @@ package campaign
 
 import (
 	"database/sql"
+	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/lib/pq"
@@ -47,6 +49,14 @@ type PromotionalCampaign struct {
 	UsesPerUser   int           `json:"uses_per_user" db:"uses_per_user"`
 	CurrentUses   int           `json:"current_uses" db:"current_uses"`
 
+	// Budget
+	TotalBudget             sql.NullFloat64 `json:"total_budget,omitempty" db:"total_budget"`
+	DiscountGiven           float64         `json:"discount_given" db:"discount_given"`
+	DeactivateOnBudgetSpent bool            `json:"deactivate_on_budget_spent" db:"deactivate_on_budget_spent"`
+
+	// A/B test; when set, each user gets one variant's discount value instead of DiscountValue
+	Variants []CampaignVariant `json:"variants,omitempty" db:"variants"`
+
 	// Targeting
 	ApplicablePlans  pq.Int64Array  `json:"applicable_plans,omitempty" db:"applicable_plans"`
 	TargetUserTypes  pq.StringArray `json:"target_user_types,omitempty" db:"target_user_types"`
@@ -62,10 +72,81 @@ type PromotionalCampaign struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// CampaignVariant is one arm of an A/B test on a campaign's discount
+type CampaignVariant struct {
+	Name          string  `json:"name" binding:"required,max=50"`
+	DiscountValue float64 `json:"discount_value" binding:"min=0"`
+	Weight        int     `json:"weight" binding:"omitempty,min=1"` // Relative share of users; defaults to 1
+}
+
+// CampaignVariantStats is usage of one variant, for conversion analysis
+type CampaignVariantStats struct {
+	Variant        string  `json:"variant"`
+	DiscountValue  float64 `json:"discount_value"`
+	Assigned       int64   `json:"assigned"`  // Users placed in the variant
+	Converted      int64   `json:"converted"` // Assigned users who used the code
+	Uses           int64   `json:"uses"`
+	DiscountGiven  float64 `json:"discount_given"`
+	ConversionRate float64 `json:"conversion_rate"` // Converted as a percentage of assigned
+}
+
 type CampaignStats struct {
 	TotalCampaigns   int64   `json:"total_campaigns"`
 	ActiveCampaigns  int64   `json:"active_campaigns"`
 	ExpiredCampaigns int64   `json:"expired_campaigns"`
 	TotalUses        int64   `json:"total_uses"`
 	TotalDiscount    float64 `json:"total_discount_given"`
-}
\ No newline at end of file
+}
+
+// VariantFor picks the user's variant. The pick hashes the campaign and user IDs, so a user
+// always gets the same variant while the variant list is unchanged. Nil without variants.
+func (c *PromotionalCampaign) VariantFor(identityID int64) *CampaignVariant {
+	if len(c.Variants) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, v := range c.Variants {
+		total += v.VariantWeight()
+	}
+
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", c.ID, identityID)
+	bucket := int(h.Sum32() % uint32(total))
+
+	for i := range c.Variants {
+		bucket -= c.Variants[i].VariantWeight()
+		if bucket < 0 {
+			return &c.Variants[i]
+		}
+	}
+	return &c.Variants[len(c.Variants)-1]
+}
+
+// VariantNamed returns the campaign's variant with the given name, or nil if there is none
+func (c *PromotionalCampaign) VariantNamed(name string) *CampaignVariant {
+	for i := range c.Variants {
+		if c.Variants[i].Name == name {
+			return &c.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantWeight is the variant's share of users, treating an unset weight as 1
+func (v *CampaignVariant) VariantWeight() int {
+	if v.Weight < 1 {
+		return 1
+	}
+	return v.Weight
+}
+
+// BudgetAllows reports whether giving discount more stays within the campaign's budget
+func (c *PromotionalCampaign) BudgetAllows(discount float64) bool {
+	return !c.TotalBudget.Valid || c.DiscountGiven+discount <= c.TotalBudget.Float64
+}
+
+// BudgetSpent reports whether the campaign has no budget left
+func (c *PromotionalCampaign) BudgetSpent() bool {
+	return c.TotalBudget.Valid && c.DiscountGiven >= c.TotalBudget.Float64
+}
@@ -0,0 +1,54 @@
+// internal/domain/campaign/entity_test.go
+package campaign
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPromotionalCampaignVariantFor(t *testing.T) {
+	const users = 10000
+
+	tests := []struct {
+		name      string
+		variants  []CampaignVariant
+		wantShare map[string]float64 // Expected share of users per variant; nil expects no variant
+	}{
+		{"no variants", nil, nil},
+		{"single variant", []CampaignVariant{{Name: "A", DiscountValue: 10}}, map[string]float64{"A": 1}},
+		{"even split", []CampaignVariant{{Name: "A", Weight: 1}, {Name: "B", Weight: 1}}, map[string]float64{"A": 0.5, "B": 0.5}},
+		{"weighted split", []CampaignVariant{{Name: "A", Weight: 3}, {Name: "B", Weight: 1}}, map[string]float64{"A": 0.75, "B": 0.25}},
+		{"unset weight counts as one", []CampaignVariant{{Name: "A"}, {Name: "B", Weight: 1}}, map[string]float64{"A": 0.5, "B": 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &PromotionalCampaign{ID: 42, Variants: tt.variants}
+
+			counts := map[string]int{}
+			for id := int64(1); id <= users; id++ {
+				v := c.VariantFor(id)
+				if tt.wantShare == nil {
+					if v != nil {
+						t.Fatalf("VariantFor(%d) = %q, want nil", id, v.Name)
+					}
+					continue
+				}
+				if v == nil {
+					t.Fatalf("VariantFor(%d) = nil", id)
+				}
+				if again := c.VariantFor(id); again.Name != v.Name {
+					t.Fatalf("VariantFor(%d) changed from %q to %q", id, v.Name, again.Name)
+				}
+				counts[v.Name]++
+			}
+
+			for name, want := range tt.wantShare {
+				got := float64(counts[name]) / users
+				if math.Abs(got-want) > 0.03 {
+					t.Errorf("variant %q got %.3f of users, want about %.2f", name, got, want)
+				}
+			}
+		})
+	}
+}
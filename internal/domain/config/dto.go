@@ -1,6 +1,12 @@
 // internal/domain/config/dto.go
 package config
 
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
 type CreateConfigRequest struct {
 	ConfigKey   string                 `json:"config_key" binding:"required,max=255"`
 	ConfigValue map[string]interface{} `json:"config_value" binding:"required"`
@@ -32,6 +38,17 @@ type ConfigListResponse struct {
 	TotalPages int           `json:"total_pages"`
 }
 
+type ApplyPresetRequest struct {
+	Preset    string `json:"preset" binding:"required"`
+	Overwrite bool   `json:"overwrite"` // Replace keys the agent has already set
+}
+
+type ApplyPresetResult struct {
+	Preset  string   `json:"preset"`
+	Applied []string `json:"applied"`
+	Skipped []string `json:"skipped"`
+}
+
 // Specific config value structures
 type NotificationConfig struct {
 	Enabled         bool   `json:"enabled"`
@@ -39,6 +56,33 @@ type NotificationConfig struct {
 	Vibration       bool   `json:"vibration"`
 	EmailAlerts     bool   `json:"email_alerts"`
 	PushEnabled     bool   `json:"push_enabled"`
+	DailySummary    bool   `json:"daily_summary"` // End-of-day transaction summary
+
+	UsageAlertThresholds []int `json:"usage_alert_thresholds" binding:"omitempty,dive,min=1,max=100"` // Subscription usage percentages that trigger an alert
+}
+
+// DefaultUsageAlertThresholds apply when an agent has not configured any
+var DefaultUsageAlertThresholds = []int{80, 100}
+
+// AlertThresholds returns the configured usage alert thresholds sorted and deduplicated,
+// ignoring values outside 1-100, or the defaults when none remain
+func (c *NotificationConfig) AlertThresholds() []int {
+	seen := make(map[int]bool, len(c.UsageAlertThresholds))
+	thresholds := make([]int, 0, len(c.UsageAlertThresholds))
+	for _, t := range c.UsageAlertThresholds {
+		if t < 1 || t > 100 || seen[t] {
+			continue
+		}
+		seen[t] = true
+		thresholds = append(thresholds, t)
+	}
+
+	if len(thresholds) == 0 {
+		return DefaultUsageAlertThresholds
+	}
+
+	sort.Ints(thresholds)
+	return thresholds
 }
 
 type USSDConfig struct {
@@ -61,6 +105,82 @@ type BusinessConfig struct {
 	DefaultOfferValidityDays int  `json:"default_offer_validity_days"`
 	MaxOffersPerCustomer     int  `json:"max_offers_per_customer"`
 	RequireCustomerVerification bool `json:"require_customer_verification"`
+	// Offers that may be featured at once; 0 turns featuring off, and leaving it unset applies
+	// DefaultMaxFeaturedOffers
+	MaxFeaturedOffers *int `json:"max_featured_offers,omitempty" binding:"omitempty,min=0"`
+	// Quiet hours ("HH:MM", agent's display timezone) during which scheduled top-ups are deferred;
+	// a window may wrap midnight, and leaving either end empty turns quiet hours off
+	QuietHoursStart string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   string `json:"quiet_hours_end,omitempty"`
+	// Minimum seconds between retries of the same failed request; 0 applies DefaultRetryCooldownSeconds
+	RetryCooldownSeconds int `json:"retry_cooldown_seconds" binding:"omitempty,min=0"`
+}
+
+// FeaturedLimit returns how many offers may be featured at once, defaulting when unset
+func (c *BusinessConfig) FeaturedLimit() int {
+	if c.MaxFeaturedOffers == nil {
+		return DefaultMaxFeaturedOffers
+	}
+	return *c.MaxFeaturedOffers
+}
+
+// QuietHoursEnabled reports whether the config defines a quiet-hours window
+func (c *BusinessConfig) QuietHoursEnabled() bool {
+	return c.QuietHoursStart != "" && c.QuietHoursEnd != "" && c.QuietHoursStart != c.QuietHoursEnd
+}
+
+// ValidateQuietHours checks that quiet hours are either both set to valid times or both empty
+func (c *BusinessConfig) ValidateQuietHours() error {
+	if (c.QuietHoursStart == "") != (c.QuietHoursEnd == "") {
+		return fmt.Errorf("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	for _, v := range []string{c.QuietHoursStart, c.QuietHoursEnd} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", v); err != nil {
+			return fmt.Errorf("invalid quiet hours time %q, expected HH:MM", v)
+		}
+	}
+	return nil
+}
+
+// QuietUntil returns when the quiet-hours window containing t ends, and false if t is
+// outside quiet hours. t is evaluated in loc.
+func (c *BusinessConfig) QuietUntil(t time.Time, loc *time.Location) (time.Time, bool) {
+	if !c.QuietHoursEnabled() {
+		return time.Time{}, false
+	}
+	start, err := time.Parse("15:04", c.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", c.QuietHoursEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if startMin < endMin {
+		quiet = minute >= startMin && minute < endMin
+	} else {
+		// Window wraps midnight, e.g. 22:00-06:00
+		quiet = minute >= startMin || minute < endMin
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
 }
 
 type DisplayConfig struct {
@@ -76,4 +196,29 @@ type SecurityConfig struct {
 	SessionTimeoutMinutes int    `json:"session_timeout_minutes"`
 	IPWhitelist         []string `json:"ip_whitelist"`
 	AllowedDevices      int      `json:"allowed_devices"`
-}
\ No newline at end of file
+	StrictDeviceBinding bool     `json:"strict_device_binding"` // Reject tokens used from a different device fingerprint
+}
+
+type WebhookConfig struct {
+	Enabled         bool   `json:"enabled"`
+	URL             string `json:"url" binding:"omitempty,url"`
+	Secret          string `json:"secret"`                                                  // Used to sign payloads (X-Bingwa-Signature)
+	MaxAttempts     int    `json:"max_attempts" binding:"omitempty,max=10"`                 // Delivery attempts before an event is marked failed; the outbox relay stops at 10
+	TimeoutSeconds  int    `json:"timeout_seconds" binding:"omitempty,min=1,max=30"`        // Per-attempt HTTP timeout, at most MaxWebhookTimeoutSeconds
+	AnalyticsDigest string `json:"analytics_digest" binding:"omitempty,oneof=daily weekly"` // Offer performance push (daily or weekly); empty turns it off
+}
+
+// MaxWebhookTimeoutSeconds caps the per-attempt timeout so a slow endpoint cannot stall deliveries and replays
+const MaxWebhookTimeoutSeconds = 30
+
+// Analytics digest frequencies
+const (
+	AnalyticsDigestDaily  = "daily"
+	AnalyticsDigestWeekly = "weekly"
+)
+
+// MpesaConfig links an agent's M-Pesa shortcode to callback ingestion
+type MpesaConfig struct {
+	ShortCode      string `json:"short_code" binding:"omitempty,numeric"`
+	CallbackSecret string `json:"callback_secret"` // Verifies the X-Bingwa-Signature on forwarded callbacks
+}
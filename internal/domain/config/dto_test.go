@@ -0,0 +1,42 @@
+// internal/domain/config/dto_test.go
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessConfigQuietUntil(t *testing.T) {
+	eat := time.FixedZone("EAT", 3*60*60)
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		t         time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"inside a daytime window", "12:00", "14:00", time.Date(2026, 10, 16, 13, 0, 0, 0, eat), true, time.Date(2026, 10, 16, 14, 0, 0, 0, eat)},
+		{"at the window end", "12:00", "14:00", time.Date(2026, 10, 16, 14, 0, 0, 0, eat), false, time.Time{}},
+		{"before a wrapping window", "22:00", "06:00", time.Date(2026, 10, 16, 21, 59, 0, 0, eat), false, time.Time{}},
+		{"late evening in a wrapping window", "22:00", "06:00", time.Date(2026, 10, 16, 23, 30, 0, 0, eat), true, time.Date(2026, 10, 17, 6, 0, 0, 0, eat)},
+		{"early morning in a wrapping window", "22:00", "06:00", time.Date(2026, 10, 17, 2, 0, 0, 0, eat), true, time.Date(2026, 10, 17, 6, 0, 0, 0, eat)},
+		{"evaluated in the agent's timezone", "22:00", "06:00", time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 17, 6, 0, 0, 0, eat)},
+		{"quiet hours off", "", "", time.Date(2026, 10, 16, 23, 0, 0, 0, eat), false, time.Time{}},
+		{"same start and end", "22:00", "22:00", time.Date(2026, 10, 16, 22, 0, 0, 0, eat), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BusinessConfig{QuietHoursStart: tt.start, QuietHoursEnd: tt.end}
+			until, quiet := c.QuietUntil(tt.t, eat)
+			if quiet != tt.wantQuiet {
+				t.Fatalf("QuietUntil(%v) quiet = %v, want %v", tt.t, quiet, tt.wantQuiet)
+			}
+			if !until.Equal(tt.wantUntil) {
+				t.Errorf("QuietUntil(%v) = %v, want %v", tt.t, until, tt.wantUntil)
+			}
+		})
+	}
+}
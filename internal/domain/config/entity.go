@@ -19,6 +19,16 @@ type AgentConfig struct {
 	UpdatedAt       time.Time              `json:"updated_at" db:"updated_at"`
 }
 
+type AgentConfigHistory struct {
+	ID              int64                  `json:"id" db:"id"`
+	AgentConfigID   int64                  `json:"agent_config_id" db:"agent_config_id"`
+	AgentIdentityID int64                  `json:"agent_identity_id" db:"agent_identity_id"`
+	ConfigKey       string                 `json:"config_key" db:"config_key"`
+	PreviousValue   map[string]interface{} `json:"previous_value" db:"previous_value"`
+	NewValue        map[string]interface{} `json:"new_value" db:"new_value"`
+	ChangedAt       time.Time              `json:"changed_at" db:"changed_at"`
+}
+
 // Predefined config keys
 const (
 	// Notification settings
@@ -51,4 +61,91 @@ const (
 	ConfigKey2FAEnabled              = "2fa_enabled"
 	ConfigKeySessionTimeout          = "session_timeout"
 	ConfigKeyIPWhitelist             = "ip_whitelist"
-)
\ No newline at end of file
+
+	// Webhook settings
+	ConfigKeyWebhook                 = "webhook"
+
+	// M-Pesa callback settings
+	ConfigKeyMpesa                   = "mpesa"
+)
+
+// DefaultMaxFeaturedOffers applies when an agent has not set max_featured_offers
+const DefaultMaxFeaturedOffers = 5
+
+// DefaultRetryCooldownSeconds applies when an agent has not set retry_cooldown_seconds
+const DefaultRetryCooldownSeconds = 60
+
+// ConfigPreset is a named bundle of config values that can be applied in one go
+type ConfigPreset struct {
+	Name        string                            `json:"name"`
+	Description string                            `json:"description"`
+	Configs     map[string]map[string]interface{} `json:"configs"`
+}
+
+// Built-in preset names
+const (
+	PresetHighVolume   = "high-volume"
+	PresetConservative = "conservative"
+)
+
+// Presets holds the built-in config presets keyed by name
+var Presets = map[string]ConfigPreset{
+	PresetHighVolume: {
+		Name:        PresetHighVolume,
+		Description: "Aggressive retries, auto-renewal and quiet alerts for busy agents",
+		Configs: map[string]map[string]interface{}{
+			ConfigKeyNotifications: {
+				"enabled":      true,
+				"sound":        false,
+				"vibration":    false,
+				"email_alerts": false,
+				"push_enabled": true,
+			},
+			ConfigKeyUSSDAutoRetry: {
+				"auto_retry":          true,
+				"retry_attempts":      5,
+				"timeout_seconds":     20,
+				"auto_dismiss_dialog": true,
+			},
+			ConfigKeyAutoRenewalEnabled: {
+				"auto_renewal_enabled":          true,
+				"default_offer_validity_days":   30,
+				"max_offers_per_customer":       50,
+				"require_customer_verification": false,
+				"max_featured_offers":           10,
+			},
+		},
+	},
+	PresetConservative: {
+		Name:        PresetConservative,
+		Description: "Fewer retries, customer verification and tighter security",
+		Configs: map[string]map[string]interface{}{
+			ConfigKeyNotifications: {
+				"enabled":      true,
+				"sound":        true,
+				"vibration":    true,
+				"email_alerts": true,
+				"push_enabled": true,
+			},
+			ConfigKeyUSSDAutoRetry: {
+				"auto_retry":          true,
+				"retry_attempts":      2,
+				"timeout_seconds":     45,
+				"auto_dismiss_dialog": false,
+			},
+			ConfigKeyAutoRenewalEnabled: {
+				"auto_renewal_enabled":          false,
+				"default_offer_validity_days":   7,
+				"max_offers_per_customer":       5,
+				"require_customer_verification": true,
+				"max_featured_offers":           3,
+			},
+			ConfigKey2FAEnabled: {
+				"2fa_enabled":             true,
+				"session_timeout_minutes": 15,
+				"ip_whitelist":            []string{},
+				"allowed_devices":         2,
+			},
+		},
+	},
+}
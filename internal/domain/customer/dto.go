@@ -1,6 +1,8 @@
 // internal/domain/customer/dto.go
 package customer
 
+import "time"
+
 type CreateCustomerRequest struct {
 	FullName       string                 `json:"full_name" binding:"max=255"`
 	PhoneNumber    string                 `json:"phone_number" binding:"required,max=20"`
@@ -32,10 +34,52 @@ type CustomerListFilters struct {
 	SortOrder  string `form:"sort_order" binding:"omitempty,oneof=asc desc"`
 }
 
+type AddNoteRequest struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+// VerifyCustomerRequest is submitted by the customer with the code texted to their phone
+type VerifyCustomerRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// VerificationCodeSent describes a verification SMS queued for a customer
+type VerificationCodeSent struct {
+	CustomerID  int64     `json:"customer_id"`
+	PhoneNumber string    `json:"phone_number"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
 type CustomerListResponse struct {
 	Customers  []AgentCustomer `json:"customers"`
 	Total      int64           `json:"total"`
 	Page       int             `json:"page"`
 	PageSize   int             `json:"page_size"`
 	TotalPages int             `json:"total_pages"`
-}
\ No newline at end of file
+}
+// CSVImportStatus is the outcome of one row in a CSV customer import
+type CSVImportStatus string
+
+const (
+	CSVImportStatusCreated   CSVImportStatus = "created"
+	CSVImportStatusInvalid   CSVImportStatus = "invalid"
+	CSVImportStatusDuplicate CSVImportStatus = "duplicate"
+	CSVImportStatusFailed    CSVImportStatus = "failed"
+)
+
+// CSVImportRowResult reports what happened to one CSV data row; Row is the 1-based line number in the file
+type CSVImportRowResult struct {
+	Row         int             `json:"row"`
+	PhoneNumber string          `json:"phone_number,omitempty"`
+	Status      CSVImportStatus `json:"status"`
+	CustomerID  int64           `json:"customer_id,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+type CSVImportResponse struct {
+	Total        int                  `json:"total"`
+	SuccessCount int                  `json:"success_count"`
+	FailureCount int                  `json:"failure_count"`
+	Results      []CSVImportRowResult `json:"results"`
+}
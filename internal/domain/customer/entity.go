@@ -28,11 +28,31 @@ type AgentCustomer struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
 	
 	// Timestamps
+	LastActivityAt sql.NullTime `json:"last_activity_at,omitempty" db:"last_activity_at"`
 	CreatedAt time.Time    `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
 	DeletedAt sql.NullTime `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
+// CustomerNote is a freeform note an agent attached to a customer
+type CustomerNote struct {
+	ID               int64     `json:"id" db:"id"`
+	CustomerID       int64     `json:"customer_id" db:"customer_id"`
+	AgentIdentityID  int64     `json:"agent_identity_id" db:"agent_identity_id"`
+	AuthorIdentityID int64     `json:"author_identity_id" db:"author_identity_id"`
+	Body             string    `json:"body" db:"body"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// VerificationCode is a pending SMS code a customer must confirm to be verified
+type VerificationCode struct {
+	CustomerID int64     `json:"customer_id" db:"customer_id"`
+	CodeHash   string    `json:"-" db:"code_hash"`
+	Attempts   int       `json:"attempts" db:"attempts"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
 type CustomerStats struct {
 	TotalCustomers    int64 `json:"total_customers"`
 	ActiveCustomers   int64 `json:"active_customers"`
@@ -0,0 +1,22 @@
+// internal/domain/emaillog/dto.go
+package emaillog
+
+import "time"
+
+type EmailLogListFilters struct {
+	Recipient string          `form:"recipient"`
+	Type      *EmailType      `form:"type"`
+	Status    *DeliveryStatus `form:"status" binding:"omitempty,oneof=sent failed"`
+	DateFrom  *time.Time      `form:"date_from"`
+	DateTo    *time.Time      `form:"date_to"`
+	Page      int             `form:"page" binding:"omitempty,min=1"`
+	PageSize  int             `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type EmailLogListResponse struct {
+	Logs       []EmailLog `json:"logs"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"page_size"`
+	TotalPages int        `json:"total_pages"`
+}
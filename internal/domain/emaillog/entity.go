@@ -0,0 +1,40 @@
+// internal/domain/emaillog/entity.go
+package emaillog
+
+import (
+	"database/sql"
+	"time"
+)
+
+type EmailType string
+
+const (
+	TypeGeneral           EmailType = "general"
+	TypePasswordReset     EmailType = "password_reset"
+	TypeEmailVerification EmailType = "email_verification"
+	TypeEmailChange       EmailType = "email_change"
+	TypeWelcome           EmailType = "welcome"
+	TypeAccountCreated    EmailType = "account_created"
+	TypePasswordChanged   EmailType = "password_changed"
+	TypeRenewalReminder   EmailType = "renewal_reminder"
+	TypeRedemptionExpiry  EmailType = "redemption_expiry"
+	TypeDailySummary      EmailType = "daily_summary"
+)
+
+type DeliveryStatus string
+
+const (
+	StatusSent   DeliveryStatus = "sent"
+	StatusFailed DeliveryStatus = "failed"
+)
+
+// EmailLog records the outcome of one send attempt
+type EmailLog struct {
+	ID        int64          `json:"id" db:"id"`
+	Recipient string         `json:"recipient" db:"recipient"`
+	Type      EmailType      `json:"type" db:"type"`
+	Subject   string         `json:"subject" db:"subject"`
+	Status    DeliveryStatus `json:"status" db:"status"`
+	Error     sql.NullString `json:"error,omitempty" db:"error"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
@@ -38,6 +38,18 @@ type CreateNotificationRequest struct {
 	ExpiresAt  *time.Time             `json:"expires_at,omitempty"`
 }
 
+// BroadcastTarget narrows a broadcast to matching identities; all set filters must match
+type BroadcastTarget struct {
+	Roles              []string `json:"roles,omitempty"`               // Any of these role names
+	PlanIDs            []int64  `json:"plan_ids,omitempty"`            // Active subscription on any of these plans
+	ActiveSubscription *bool    `json:"active_subscription,omitempty"` // Has (true) or lacks (false) an active subscription
+}
+
+// IsEmpty reports whether no filters are set
+func (t *BroadcastTarget) IsEmpty() bool {
+	return t == nil || (len(t.Roles) == 0 && len(t.PlanIDs) == 0 && t.ActiveSubscription == nil)
+}
+
 type NotificationListFilters struct {
 	IsRead    *bool            `form:"is_read"`
 	Type      *NotificationType `form:"type"`
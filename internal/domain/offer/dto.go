@@ -1,7 +1,10 @@
 // internal/domain/offer/dto.go
 package offer
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type CreateOfferRequest struct {
 	Name        string     `json:"name" binding:"required,max=255"`
@@ -12,7 +15,7 @@ type CreateOfferRequest struct {
 
 	// Pricing
 	Price              float64 `json:"price" binding:"required,min=0"`
-	Currency           string  `json:"currency" binding:"required,len=3"`
+	Currency           string  `json:"currency" binding:"omitempty,len=3"` // Defaults to the agent's display currency
 	DiscountPercentage float64 `json:"discount_percentage" binding:"min=0,max=100"`
 
 	// Validity
@@ -29,6 +32,13 @@ type CreateOfferRequest struct {
 	IsFeatured              bool  `json:"is_featured"`
 	IsRecurring             bool  `json:"is_recurring"`
 	MaxPurchasesPerCustomer *int32 `json:"max_purchases_per_customer"`
+	PurchaseLimitPeriod     PurchaseLimitPeriod `json:"purchase_limit_period" binding:"omitempty,oneof=lifetime daily weekly monthly"` // Defaults to lifetime
+	PurchaseCooldownSeconds *int32 `json:"purchase_cooldown_seconds" binding:"omitempty,min=0"`
+	RequiredDeviceID        string `json:"required_device_id"`
+	RequireConfirmation     bool   `json:"require_confirmation"` // Requests wait for agent confirmation before dispatch
+
+	// Stock
+	StockLimit *int32 `json:"stock_limit" binding:"omitempty,min=0"` // Units available to sell; omit for unlimited. Top up with /replenish
 
 	// Availability
 	AvailableFrom  *time.Time `json:"available_from"`
@@ -37,6 +47,8 @@ type CreateOfferRequest struct {
 	// Metadata
 	Tags     []string               `json:"tags"`
 	Metadata map[string]interface{} `json:"metadata"`
+
+	Confirm bool `json:"confirm"` // Acknowledges an unusual price, suppressing the anomaly warning
 }
 
 type UpdateOfferRequest struct {
@@ -64,6 +76,10 @@ type UpdateOfferRequest struct {
 	IsFeatured              *bool  `json:"is_featured"`
 	IsRecurring             *bool  `json:"is_recurring"`
 	MaxPurchasesPerCustomer *int32 `json:"max_purchases_per_customer"`
+	PurchaseLimitPeriod     *PurchaseLimitPeriod `json:"purchase_limit_period" binding:"omitempty,oneof=lifetime daily weekly monthly"`
+	PurchaseCooldownSeconds *int32 `json:"purchase_cooldown_seconds" binding:"omitempty,min=0"` // 0 removes the cooldown
+	RequiredDeviceID        *string `json:"required_device_id"` // Empty removes the device requirement
+	RequireConfirmation     *bool   `json:"require_confirmation"`
 
 	// Availability
 	AvailableFrom  *time.Time `json:"available_from"`
@@ -72,6 +88,8 @@ type UpdateOfferRequest struct {
 	// Metadata
 	Tags     []string               `json:"tags"`
 	Metadata map[string]interface{} `json:"metadata"`
+
+	Confirm bool `json:"confirm"` // Acknowledges an unusual price, suppressing the anomaly warning
 }
 
 type OfferListFilters struct {
@@ -86,6 +104,57 @@ type OfferListFilters struct {
 	PageSize   int         `form:"page_size" binding:"min=1,max=100"`
 	SortBy     string      `form:"sort_by"` // price, created_at, name
 	SortOrder  string      `form:"sort_order" binding:"omitempty,oneof=asc desc"`
+	Fields     OfferProjection `form:"fields" binding:"omitempty,oneof=full summary"`
+}
+
+// OfferProjection selects how much of each offer a list returns
+type OfferProjection string
+
+const (
+	OfferProjectionFull    OfferProjection = "full"    // Every field plus the primary USSD code
+	OfferProjectionSummary OfferProjection = "summary" // Catalogue fields only, for lightweight mobile lists
+)
+
+// OfferSummary is the lightweight projection of an offer; it leaves out metadata and USSD configuration
+type OfferSummary struct {
+	ID                 int64       `json:"id"`
+	OfferCode          string      `json:"offer_code"`
+	Name               string      `json:"name"`
+	Type               OfferType   `json:"type"`
+	Amount             float64     `json:"amount"`
+	Units              OfferUnits  `json:"units"`
+	Price              float64     `json:"price"`
+	Currency           string      `json:"currency"`
+	DiscountPercentage float64     `json:"discount_percentage"`
+	ValidityDays       int         `json:"validity_days"`
+	ValidityLabel      string      `json:"validity_label,omitempty"`
+	IsFeatured         bool        `json:"is_featured"`
+	Status             OfferStatus `json:"status"`
+	Tags               []string    `json:"tags,omitempty"`
+}
+
+// OfferFacetFilters are the list filters that apply to search facets (no paging or sorting)
+type OfferFacetFilters struct {
+	Type       *OfferType   `form:"type"`
+	Status     *OfferStatus `form:"status"`
+	IsFeatured *bool        `form:"is_featured"`
+	MinPrice   *float64     `form:"min_price"`
+	MaxPrice   *float64     `form:"max_price"`
+	Search     string       `form:"search"`
+	Tags       []string     `form:"tags"`
+}
+
+// ListFilters converts facet filters to list filters
+func (f *OfferFacetFilters) ListFilters() *OfferListFilters {
+	return &OfferListFilters{
+		Type:       f.Type,
+		Status:     f.Status,
+		IsFeatured: f.IsFeatured,
+		MinPrice:   f.MinPrice,
+		MaxPrice:   f.MaxPrice,
+		Search:     f.Search,
+		Tags:       f.Tags,
+	}
 }
 
 type OfferListResponse struct {
@@ -96,6 +165,15 @@ type OfferListResponse struct {
 	TotalPages int          `json:"total_pages"`
 }
 
+// OfferSummaryListResponse is a page of offers in the summary projection
+type OfferSummaryListResponse struct {
+	Offers     []OfferSummary `json:"offers"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+	TotalPages int            `json:"total_pages"`
+}
+
 type AddUSSDCodeRequest struct {
 	USSDCode         string             `json:"ussd_code" binding:"required"`
 	SignaturePattern string             `json:"signature_pattern"`
@@ -124,12 +202,176 @@ type ReorderUSSDCodesRequest struct {
 	} `json:"codes" binding:"required,min=1"`
 }
 
+// BulkToggleUSSDStatusRequest activates or deactivates several USSD codes, possibly across offers
+type BulkToggleUSSDStatusRequest struct {
+	USSDCodeIDs []int64 `json:"ussd_code_ids" binding:"required,min=1,max=100,dive,gt=0"`
+	IsActive    *bool   `json:"is_active" binding:"required"`
+}
+
+type BulkToggleUSSDStatusResponse struct {
+	Updated  int     `json:"updated"`
+	IsActive bool    `json:"is_active"`
+	OfferIDs []int64 `json:"offer_ids"` // Offers whose codes changed
+}
+
 type RecordUSSDResultRequest struct {
 	USSDCodeID int64  `json:"ussd_code_id" binding:"required"`
 	Success    bool   `json:"success"`
 	Response   string `json:"response"`
 }
 
+type TestUSSDCodeRequest struct {
+	TestPhone   string `json:"test_phone" binding:"required"`
+	RawResponse string `json:"raw_response" binding:"required"`
+}
+
+type TransferOfferRequest struct {
+	ToAgentID int64 `json:"to_agent_id" binding:"required,min=1"`
+}
+
+type RenameTagRequest struct {
+	OldTag string `json:"old_tag" binding:"required,max=50"`
+	NewTag string `json:"new_tag" binding:"required,max=50"`
+}
+
+type RenameTagResult struct {
+	OldTag           string `json:"old_tag"`
+	NewTag           string `json:"new_tag"`
+	OffersUpdated    int64  `json:"offers_updated"`
+	CustomersUpdated int64  `json:"customers_updated"`
+}
+
+type BulkTagRequest struct {
+	OfferIDs []int64 `json:"offer_ids" binding:"required,min=1,max=100"`
+	Tag      string  `json:"tag" binding:"required,max=50"`
+}
+
+// BulkTagResult reports which offers changed; offers that already had (or lacked) the tag,
+// or that the agent doesn't own, are left untouched
+type BulkTagResult struct {
+	Tag        string  `json:"tag"`
+	Updated    int     `json:"updated"`
+	UpdatedIDs []int64 `json:"updated_ids"`
+}
+
+type QRBatchRequest struct {
+	OfferIDs []int64 `json:"offer_ids" binding:"required,min=1,max=100"`
+}
+
+// QRCodeImage is a rendered offer QR code
+type QRCodeImage struct {
+	OfferID   int64  `json:"offer_id"`
+	OfferCode string `json:"offer_code"`
+	Filename  string `json:"filename"`
+	PNG       []byte `json:"-"`
+}
+
+// ImportOfferRow is one offer in an import, carrying the offer code from the source system
+type ImportOfferRow struct {
+	OfferCode string `json:"offer_code"`
+	CreateOfferRequest
+}
+
+type ImportOffersRequest struct {
+	Offers []ImportOfferRow `json:"offers" binding:"required,min=1,max=200,dive"`
+}
+
+// ImportRowStatus is the outcome of one row in an offer import
+type ImportRowStatus string
+
+const (
+	ImportRowCreated ImportRowStatus = "created"
+	ImportRowInvalid ImportRowStatus = "invalid"
+	ImportRowFailed  ImportRowStatus = "failed"
+)
+
+// ImportRowResult reports what happened to one imported offer; Row is its 1-based position in the request
+type ImportRowResult struct {
+	Row       int             `json:"row"`
+	OfferCode string          `json:"offer_code"`
+	Status    ImportRowStatus `json:"status"`
+	OfferID   int64           `json:"offer_id,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type ImportOffersResponse struct {
+	Total        int               `json:"total"`
+	SuccessCount int               `json:"success_count"`
+	FailureCount int               `json:"failure_count"`
+	Results      []ImportRowResult `json:"results"`
+}
+
+type BulkDeleteRequest struct {
+	OfferIDs []int64 `json:"offer_ids" binding:"required,min=1,max=100"`
+}
+
+// BulkDeleteResult reports how many offers were deleted and which IDs were skipped
+// (not owned by the agent, already deleted or nonexistent)
+type BulkDeleteResult struct {
+	Deleted    int     `json:"deleted"`
+	DeletedIDs []int64 `json:"deleted_ids"`
+	SkippedIDs []int64 `json:"skipped_ids"`
+}
+
+type PriceAdjustmentType string
+
+const (
+	PriceAdjustmentPercentage PriceAdjustmentType = "percentage" // Value is a percent change, e.g. 10 or -5
+	PriceAdjustmentFixed      PriceAdjustmentType = "fixed"      // Value is added to the price, e.g. 20 or -10
+)
+
+type PriceAdjustment struct {
+	Type  PriceAdjustmentType `json:"type" binding:"required,oneof=percentage fixed"`
+	Value float64             `json:"value" binding:"required"`
+}
+
+// BulkPriceFilter selects the offers a bulk price adjustment applies to; an empty filter matches all offers
+type BulkPriceFilter struct {
+	Type       *OfferType   `json:"type"`
+	Status     *OfferStatus `json:"status"`
+	IsFeatured *bool        `json:"is_featured"`
+	MinPrice   *float64     `json:"min_price"`
+	MaxPrice   *float64     `json:"max_price"`
+	Tags       []string     `json:"tags"`
+}
+
+// ListFilters converts a bulk price filter to list filters
+func (f *BulkPriceFilter) ListFilters() *OfferListFilters {
+	return &OfferListFilters{
+		Type:       f.Type,
+		Status:     f.Status,
+		IsFeatured: f.IsFeatured,
+		MinPrice:   f.MinPrice,
+		MaxPrice:   f.MaxPrice,
+		Tags:       f.Tags,
+	}
+}
+
+type BulkAdjustPriceRequest struct {
+	Filter     BulkPriceFilter `json:"filter"`
+	Adjustment PriceAdjustment `json:"adjustment" binding:"required"`
+}
+
+type BulkAdjustPriceResult struct {
+	Adjusted int                `json:"adjusted"`
+	Changes  []OfferPriceChange `json:"changes"`
+}
+
+type CheckAvailabilityBatchRequest struct {
+	OfferIDs []int64 `json:"offer_ids" binding:"required,min=1,max=100"`
+}
+
+// AvailabilityResult describes whether an offer can currently be purchased
+type AvailabilityResult struct {
+	OfferID        int64        `json:"offer_id"`
+	OfferCode      string       `json:"offer_code,omitempty"`
+	IsAvailable    bool         `json:"is_available"`
+	Reason         string       `json:"reason,omitempty"` // inactive, not_started, expired or not_found
+	Status         OfferStatus  `json:"status,omitempty"`
+	AvailableFrom  sql.NullTime `json:"available_from"`
+	AvailableUntil sql.NullTime `json:"available_until"`
+}
+
 // USSDCodeExecutionInfo contains information for executing a USSD code
 type USSDCodeExecutionInfo struct {
 	USSDCodeID       int64    `json:"ussd_code_id,omitempty"`
@@ -141,4 +383,79 @@ type USSDCodeExecutionInfo struct {
 	Priority         int      `json:"priority"`
 	FallbackCodes    []string `json:"fallback_codes,omitempty"`
 	IsFallback       bool     `json:"is_fallback"`
-}
\ No newline at end of file
+}
+
+// StatsFilters narrows offer statistics to a date range; both bounds are optional
+type StatsFilters struct {
+	From *time.Time `form:"from"`
+	To   *time.Time `form:"to"`
+}
+
+// CreateOfferTemplateRequest adds a template to the library (admin)
+type CreateOfferTemplateRequest struct {
+	Name                 string             `json:"name" binding:"required,max=255"`
+	Description          string             `json:"description"`
+	Type                 OfferType          `json:"type" binding:"required"`
+	Amount               float64            `json:"amount" binding:"required,min=0"`
+	Units                OfferUnits         `json:"units" binding:"required"`
+	Price                float64            `json:"price" binding:"required,min=0"`
+	Currency             string             `json:"currency" binding:"omitempty,len=3"` // Defaults to KES
+	ValidityDays         int                `json:"validity_days" binding:"required,min=1"`
+	ValidityLabel        string             `json:"validity_label"`
+	USSDCodeTemplate     string             `json:"ussd_code_template" binding:"required"`
+	USSDProcessingType   USSDProcessingType `json:"ussd_processing_type" binding:"required"`
+	USSDExpectedResponse string             `json:"ussd_expected_response"`
+	USSDErrorPattern     string             `json:"ussd_error_pattern"`
+	Tags                 []string           `json:"tags"`
+}
+
+// UpdateOfferTemplateRequest changes a template (admin); nil fields are left unchanged
+type UpdateOfferTemplateRequest struct {
+	Name                 *string             `json:"name" binding:"omitempty,max=255"`
+	Description          *string             `json:"description"`
+	Amount               *float64            `json:"amount" binding:"omitempty,min=0"`
+	Units                *OfferUnits         `json:"units"`
+	Price                *float64            `json:"price" binding:"omitempty,min=0"`
+	ValidityDays         *int                `json:"validity_days" binding:"omitempty,min=1"`
+	ValidityLabel        *string             `json:"validity_label"`
+	USSDCodeTemplate     *string             `json:"ussd_code_template"`
+	USSDProcessingType   *USSDProcessingType `json:"ussd_processing_type"`
+	USSDExpectedResponse *string             `json:"ussd_expected_response"`
+	USSDErrorPattern     *string             `json:"ussd_error_pattern"`
+	Tags                 []string            `json:"tags"`
+	IsActive             *bool               `json:"is_active"`
+}
+
+// OfferTemplateOverrides replaces template defaults when instantiating an offer; nil fields keep the template value
+type OfferTemplateOverrides struct {
+	Name               *string  `json:"name" binding:"omitempty,max=255"`
+	Description        *string  `json:"description"`
+	Price              *float64 `json:"price" binding:"omitempty,min=0"`
+	Currency           *string  `json:"currency" binding:"omitempty,len=3"`
+	DiscountPercentage *float64 `json:"discount_percentage" binding:"omitempty,min=0,max=100"`
+	ValidityDays       *int     `json:"validity_days" binding:"omitempty,min=1"`
+	ValidityLabel      *string  `json:"validity_label"`
+	USSDCodeTemplate   *string  `json:"ussd_code_template"`
+	IsFeatured         *bool    `json:"is_featured"`
+	Tags               []string `json:"tags"`
+	Confirm            bool     `json:"confirm"` // Acknowledges an unusual price, suppressing the anomaly warning
+}
+
+// CreateFromTemplateRequest instantiates an offer from a library template
+type CreateFromTemplateRequest struct {
+	TemplateID int64                  `json:"template_id" binding:"required"`
+	Overrides  OfferTemplateOverrides `json:"overrides"`
+}
+
+// ReplenishStockRequest adds units to a stock-limited offer
+type ReplenishStockRequest struct {
+	Amount int32 `json:"amount" binding:"required,min=1"`
+}
+
+// StockReplenished reports a replenishment and whether the agent was told the offer is back in stock
+type StockReplenished struct {
+	OfferID       int64 `json:"offer_id"`
+	PreviousStock int32 `json:"previous_stock"`
+	StockLimit    int32 `json:"stock_limit"`
+	BackInStock   bool  `json:"back_in_stock"`
+}
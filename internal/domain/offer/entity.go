@@ -26,6 +26,61 @@ const (
 	UnitsUnits   OfferUnits = "units"
 )
 
+// MinimumAmounts sets the smallest sellable amount per offer type
+type MinimumAmounts struct {
+	DataMB       float64 // Data offers are compared after converting to MB
+	SMS          float64
+	VoiceMinutes float64
+	ComboUnits   float64
+}
+
+// DefaultMinimumAmounts applies when no minimums are configured
+var DefaultMinimumAmounts = MinimumAmounts{
+	DataMB:       1,
+	SMS:          1,
+	VoiceMinutes: 1,
+	ComboUnits:   1,
+}
+
+// DefaultMaxValidityDays caps offer validity when no maximum is configured
+const DefaultMaxValidityDays = 365
+
+type PurchaseLimitPeriod string
+
+const (
+	PurchaseLimitLifetime PurchaseLimitPeriod = "lifetime"
+	PurchaseLimitDaily    PurchaseLimitPeriod = "daily"
+	PurchaseLimitWeekly   PurchaseLimitPeriod = "weekly"
+	PurchaseLimitMonthly  PurchaseLimitPeriod = "monthly"
+)
+
+// WindowStart returns the start of the current limit window, or nil for lifetime limits (weeks start on Monday)
+func (p PurchaseLimitPeriod) WindowStart(now time.Time) *time.Time {
+	year, month, day := now.Date()
+	var start time.Time
+
+	switch p {
+	case PurchaseLimitDaily:
+		start = time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	case PurchaseLimitWeekly:
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		start = time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, now.Location())
+	case PurchaseLimitMonthly:
+		start = time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+	default:
+		return nil
+	}
+
+	return &start
+}
+
+type PricingStrategy string
+
+const (
+	PricingStrategyFlat      PricingStrategy = "flat"      // Percentage off the set price
+	PricingStrategyComponent PricingStrategy = "component" // Percentage off the sum of combo component prices
+)
+
 type OfferStatus string
 
 const (
@@ -75,6 +130,13 @@ type AgentOffer struct {
 	IsFeatured              bool          `json:"is_featured" db:"is_featured"`
 	IsRecurring             bool          `json:"is_recurring" db:"is_recurring"`
 	MaxPurchasesPerCustomer sql.NullInt32 `json:"max_purchases_per_customer,omitempty" db:"max_purchases_per_customer"`
+	PurchaseLimitPeriod     PurchaseLimitPeriod `json:"purchase_limit_period" db:"purchase_limit_period"`
+	PurchaseCooldownSeconds sql.NullInt32 `json:"purchase_cooldown_seconds,omitempty" db:"purchase_cooldown_seconds"`
+	RequiredDeviceID        sql.NullString `json:"required_device_id,omitempty" db:"required_device_id"`
+	RequireConfirmation     bool           `json:"require_confirmation" db:"require_confirmation"` // Requests wait for agent confirmation before dispatch
+
+	// Stock
+	StockLimit sql.NullInt32 `json:"stock_limit,omitempty" db:"stock_limit"` // Units left to sell; null means unlimited
 
 	// Status
 	Status         OfferStatus  `json:"status" db:"status"`
@@ -92,6 +154,61 @@ type AgentOffer struct {
 
 	// Primary USSD Code (loaded separately)
 	PrimaryUSSDCode *OfferUSSDCode `json:"primary_ussd_code,omitempty" db:"-"`
+
+	// Non-blocking validation warnings (set on create/update responses)
+	Warnings []string `json:"warnings,omitempty" db:"-"`
+}
+
+// PriceBucket is a price range used for catalog facets; Max of 0 means unbounded
+type PriceBucket struct {
+	Label string  `json:"label"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max,omitempty"`
+}
+
+// OfferPriceBuckets are the price ranges reported by offer search facets
+var OfferPriceBuckets = []PriceBucket{
+	{Label: "0-49", Min: 0, Max: 50},
+	{Label: "50-99", Min: 50, Max: 100},
+	{Label: "100-249", Min: 100, Max: 250},
+	{Label: "250-499", Min: 250, Max: 500},
+	{Label: "500-999", Min: 500, Max: 1000},
+	{Label: "1000+", Min: 1000},
+}
+
+type PriceBucketCount struct {
+	PriceBucket
+	Count int64 `json:"count"`
+}
+
+// OfferSearchFacets holds offer counts per type, status and price bucket for a filter set
+type OfferSearchFacets struct {
+	Total        int64                 `json:"total"`
+	Types        map[OfferType]int64   `json:"types"`
+	Statuses     map[OfferStatus]int64 `json:"statuses"`
+	PriceBuckets []PriceBucketCount    `json:"price_buckets"`
+}
+
+// OfferTemplate is an admin-curated offer blueprint agents can instantiate
+type OfferTemplate struct {
+	ID                   int64              `json:"id" db:"id"`
+	Name                 string             `json:"name" db:"name"`
+	Description          sql.NullString     `json:"description,omitempty" db:"description"`
+	Type                 OfferType          `json:"type" db:"type"`
+	Amount               float64            `json:"amount" db:"amount"`
+	Units                OfferUnits         `json:"units" db:"units"`
+	Price                float64            `json:"price" db:"price"`
+	Currency             string             `json:"currency" db:"currency"`
+	ValidityDays         int                `json:"validity_days" db:"validity_days"`
+	ValidityLabel        sql.NullString     `json:"validity_label,omitempty" db:"validity_label"`
+	USSDCodeTemplate     string             `json:"ussd_code_template" db:"ussd_code_template"`
+	USSDProcessingType   USSDProcessingType `json:"ussd_processing_type" db:"ussd_processing_type"`
+	USSDExpectedResponse sql.NullString     `json:"ussd_expected_response,omitempty" db:"ussd_expected_response"`
+	USSDErrorPattern     sql.NullString     `json:"ussd_error_pattern,omitempty" db:"ussd_error_pattern"`
+	Tags                 []string           `json:"tags" db:"tags"`
+	IsActive             bool               `json:"is_active" db:"is_active"`
+	CreatedAt            time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at" db:"updated_at"`
 }
 
 type OfferStats struct {
@@ -104,6 +221,30 @@ type OfferStats struct {
 	MostPopularOfferName string `json:"most_popular_offer_name,omitempty"`
 }
 
+// PeriodStats is an agent's successful sales in [From, To)
+type PeriodStats struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Revenue      float64   `json:"revenue"`
+	Redemptions  int64     `json:"redemptions"`
+	ActiveOffers int64     `json:"active_offers"` // Offers with at least one successful redemption
+}
+
+// StatsChange holds percentage changes from the previous period; nil when the previous value was zero
+type StatsChange struct {
+	Revenue      *float64 `json:"revenue"`
+	Redemptions  *float64 `json:"redemptions"`
+	ActiveOffers *float64 `json:"active_offers"`
+}
+
+// OfferStatsComparison compares the latest period with the one before it
+type OfferStatsComparison struct {
+	Period   string      `json:"period"`
+	Current  PeriodStats `json:"current"`
+	Previous PeriodStats `json:"previous"`
+	Change   StatsChange `json:"change"`
+}
+
 type OfferUSSDCode struct {
 	ID               int64                  `json:"id" db:"id"`
 	OfferID          int64                  `json:"offer_id" db:"offer_id"`
@@ -134,6 +275,45 @@ type OfferUSSDCode struct {
 	UpdatedAt        time.Time              `json:"updated_at" db:"updated_at"`
 }
 
+type USSDTestResult string
+
+const (
+	USSDTestResultSuccess USSDTestResult = "success"
+	USSDTestResultFailed  USSDTestResult = "failed"
+	USSDTestResultUnknown USSDTestResult = "unknown" // Neither pattern matched
+)
+
+// USSDCodeTestRun is a recorded test execution of a USSD code; it never affects code stats
+type USSDCodeTestRun struct {
+	ID              int64          `json:"id" db:"id"`
+	USSDCodeID      int64          `json:"ussd_code_id" db:"ussd_code_id"`
+	OfferID         int64          `json:"offer_id" db:"offer_id"`
+	AgentIdentityID int64          `json:"agent_identity_id" db:"agent_identity_id"`
+	TestPhone       string         `json:"test_phone" db:"test_phone"`
+	RawResponse     string         `json:"raw_response" db:"raw_response"`
+	Result          USSDTestResult `json:"result" db:"result"`
+	MatchedPattern  sql.NullString `json:"matched_pattern,omitempty" db:"matched_pattern"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+}
+
+type PriceChangeSource string
+
+const (
+	PriceChangeManual     PriceChangeSource = "manual"
+	PriceChangeBulkAdjust PriceChangeSource = "bulk_adjust"
+)
+
+// OfferPriceChange is a recorded change to an offer's base price
+type OfferPriceChange struct {
+	ID              int64             `json:"id" db:"id"`
+	OfferID         int64             `json:"offer_id" db:"offer_id"`
+	AgentIdentityID int64             `json:"agent_identity_id" db:"agent_identity_id"`
+	OldPrice        float64           `json:"old_price" db:"old_price"`
+	NewPrice        float64           `json:"new_price" db:"new_price"`
+	Source          PriceChangeSource `json:"source" db:"source"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+}
+
 type USSDCodeStats struct {
 	TotalCodes    int     `json:"total_codes"`
 	ActiveCodes   int     `json:"active_codes"`
@@ -0,0 +1,40 @@
+// internal/domain/offer/entity_test.go
+package offer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurchaseLimitPeriodWindowStart(t *testing.T) {
+	eat := time.FixedZone("EAT", 3*60*60)
+	// A Friday evening
+	now := time.Date(2026, 10, 16, 21, 45, 0, 0, eat)
+
+	tests := []struct {
+		period PurchaseLimitPeriod
+		want   *time.Time
+	}{
+		{PurchaseLimitDaily, ptrTime(time.Date(2026, 10, 16, 0, 0, 0, 0, eat))},
+		{PurchaseLimitWeekly, ptrTime(time.Date(2026, 10, 12, 0, 0, 0, 0, eat))},
+		{PurchaseLimitMonthly, ptrTime(time.Date(2026, 10, 1, 0, 0, 0, 0, eat))},
+		{PurchaseLimitLifetime, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			got := tt.period.WindowStart(now)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("WindowStart = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A daily window resets at midnight: yesterday's purchase falls outside today's window
+	yesterday := time.Date(2026, 10, 15, 23, 59, 0, 0, eat)
+	if start := PurchaseLimitDaily.WindowStart(now); !yesterday.Before(*start) {
+		t.Errorf("yesterday %v is inside today's window starting %v", yesterday, start)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
@@ -13,8 +13,12 @@ type CreateScheduledOfferRequest struct {
 	// Auto-renewal
 	AutoRenew     bool          `json:"auto_renew"`
 	RenewalPeriod RenewalPeriod `json:"renewal_period"`
+	RecurrencePattern string    `json:"recurrence_pattern"` // e.g. "every friday 08:00"; overrides renewal_period
 	RenewalLimit  *int32        `json:"renewal_limit"`
 	RenewUntil    *time.Time    `json:"renew_until"`
+
+	// Create even if an active schedule for the same offer and customer runs near the same time
+	Force bool `json:"force"`
 	
 	// Metadata
 	Metadata map[string]interface{} `json:"metadata"`
@@ -24,6 +28,7 @@ type UpdateScheduledOfferRequest struct {
 	ScheduledTime *time.Time `json:"scheduled_time"`
 	AutoRenew     *bool      `json:"auto_renew"`
 	RenewalPeriod *RenewalPeriod `json:"renewal_period"`
+	RecurrencePattern *string `json:"recurrence_pattern"` // Empty string clears the pattern
 	RenewalLimit  *int32     `json:"renewal_limit"`
 	RenewUntil    *time.Time `json:"renew_until"`
 	Metadata      map[string]interface{} `json:"metadata"`
@@ -53,7 +58,7 @@ type ExecuteScheduledOfferInput struct {
 	USSDResponse       string `json:"ussd_response"`
 	USSDSessionID      string `json:"ussd_session_id"`
 	USSDProcessingTime int32  `json:"ussd_processing_time"`
-	Status             string `json:"status"` // success, failed
+	Status             string `json:"status"` // success, failed, pending (queued for device execution)
 	FailureReason      string `json:"failure_reason"`
 }
 
@@ -70,4 +75,20 @@ type ScheduleHistoryListResponse struct {
 	Page       int                     `json:"page"`
 	PageSize   int                     `json:"page_size"`
 	TotalPages int                     `json:"total_pages"`
-}
\ No newline at end of file
+}
+type ScheduleExecutionResult struct {
+	ScheduleID   int64  `json:"schedule_id"`
+	Success      bool   `json:"success"`
+	Skipped      bool   `json:"skipped,omitempty"` // Claimed by another processor
+	RedemptionID *int64 `json:"redemption_id,omitempty"`
+	HistoryID    *int64 `json:"history_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type ProcessDueSchedulesResult struct {
+	Total        int                       `json:"total"`
+	SuccessCount int                       `json:"success_count"`
+	FailureCount int                       `json:"failure_count"`
+	SkippedCount int                       `json:"skipped_count"`
+	Results      []ScheduleExecutionResult `json:"results"`
+}
@@ -45,6 +45,7 @@ type ScheduledOffer struct {
 	// Auto-renewal configuration
 	AutoRenew      bool           `json:"auto_renew" db:"auto_renew"`
 	RenewalPeriod  sql.NullString `json:"renewal_period,omitempty" db:"renewal_period"`
+	RecurrencePattern sql.NullString `json:"recurrence_pattern,omitempty" db:"recurrence_pattern"`
 	RenewalCount   int            `json:"renewal_count" db:"renewal_count"`
 	RenewalLimit   sql.NullInt32  `json:"renewal_limit,omitempty" db:"renewal_limit"`
 	RenewUntil     sql.NullTime   `json:"renew_until,omitempty" db:"renew_until"`
@@ -84,6 +85,9 @@ type ScheduledOfferHistory struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// HistoryMetadataSkipped marks a history entry recorded for a skipped renewal rather than an execution
+const HistoryMetadataSkipped = "skipped"
+
 type ScheduleStats struct {
 	TotalSchedules    int64 `json:"total_schedules"`
 	ActiveSchedules   int64 `json:"active_schedules"`
@@ -0,0 +1,96 @@
+// internal/domain/schedule/recurrence.go
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is a parsed recurrence pattern such as "every monday 08:00", "every weekday" or "every day 18:30"
+type Recurrence struct {
+	Days    []time.Weekday // Days the schedule runs on
+	HasTime bool           // When false, the previous run's time of day is kept
+	Hour    int
+	Minute  int
+}
+
+var recurrenceDays = map[string][]time.Weekday{
+	"day":       {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	"weekday":   {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend":   {time.Saturday, time.Sunday},
+	"sunday":    {time.Sunday},
+	"monday":    {time.Monday},
+	"tuesday":   {time.Tuesday},
+	"wednesday": {time.Wednesday},
+	"thursday":  {time.Thursday},
+	"friday":    {time.Friday},
+	"saturday":  {time.Saturday},
+	"sun":       {time.Sunday},
+	"mon":       {time.Monday},
+	"tue":       {time.Tuesday},
+	"wed":       {time.Wednesday},
+	"thu":       {time.Thursday},
+	"fri":       {time.Friday},
+	"sat":       {time.Saturday},
+}
+
+// ParseRecurrence parses "every <day|weekday|weekend|monday..sunday> [HH:MM]" (case-insensitive)
+func ParseRecurrence(pattern string) (*Recurrence, error) {
+	fields := strings.Fields(strings.ToLower(pattern))
+	if len(fields) < 2 || len(fields) > 3 || fields[0] != "every" {
+		return nil, fmt.Errorf("invalid recurrence pattern %q: expected \"every <day> [HH:MM]\"", pattern)
+	}
+
+	days, ok := recurrenceDays[fields[1]]
+	if !ok {
+		return nil, fmt.Errorf("invalid recurrence pattern %q: unknown day %q", pattern, fields[1])
+	}
+
+	r := &Recurrence{Days: days}
+
+	if len(fields) == 3 {
+		parts := strings.Split(fields[2], ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid recurrence pattern %q: time must be HH:MM", pattern)
+		}
+		hour, err := strconv.Atoi(parts[0])
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("invalid recurrence pattern %q: invalid hour", pattern)
+		}
+		minute, err := strconv.Atoi(parts[1])
+		if err != nil || minute < 0 || minute > 59 {
+			return nil, fmt.Errorf("invalid recurrence pattern %q: invalid minute", pattern)
+		}
+		r.HasTime, r.Hour, r.Minute = true, hour, minute
+	}
+
+	return r, nil
+}
+
+// Next returns the first occurrence strictly after from. Days and the time of day are read in loc
+// (the agent's timezone), so "every monday 08:00" means 08:00 Monday where the agent is.
+func (r *Recurrence) Next(from time.Time, loc *time.Location) time.Time {
+	from = from.In(loc)
+	hour, minute, sec := from.Clock()
+	if r.HasTime {
+		hour, minute, sec = r.Hour, r.Minute, 0
+	}
+
+	year, month, day := from.Date()
+	for i := 0; i <= 7; i++ {
+		candidate := time.Date(year, month, day+i, hour, minute, sec, 0, loc)
+		if !candidate.After(from) {
+			continue
+		}
+		for _, d := range r.Days {
+			if candidate.Weekday() == d {
+				return candidate
+			}
+		}
+	}
+
+	// Unreachable for a parsed recurrence: every day set is non-empty
+	return from.AddDate(0, 0, 7)
+}
@@ -0,0 +1,68 @@
+// internal/domain/schedule/recurrence_test.go
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	eat := time.FixedZone("EAT", 3*60*60)
+
+	tests := []struct {
+		name    string
+		pattern string
+		from    time.Time
+		want    time.Time
+	}{
+		{
+			"later the same day",
+			"every monday 08:00",
+			time.Date(2026, 10, 12, 6, 0, 0, 0, eat),
+			time.Date(2026, 10, 12, 8, 0, 0, 0, eat),
+		},
+		{
+			"exactly at the time moves to next week",
+			"every monday 08:00",
+			time.Date(2026, 10, 12, 8, 0, 0, 0, eat),
+			time.Date(2026, 10, 19, 8, 0, 0, 0, eat),
+		},
+		{
+			"weekday skips the weekend",
+			"every weekday 18:30",
+			time.Date(2026, 10, 16, 19, 0, 0, 0, eat),
+			time.Date(2026, 10, 19, 18, 30, 0, 0, eat),
+		},
+		{
+			"weekend from friday",
+			"every weekend",
+			time.Date(2026, 10, 16, 9, 15, 0, 0, eat),
+			time.Date(2026, 10, 17, 9, 15, 0, 0, eat),
+		},
+		{
+			"without a time keeps the time of day",
+			"every day",
+			time.Date(2026, 10, 16, 21, 45, 30, 0, eat),
+			time.Date(2026, 10, 17, 21, 45, 30, 0, eat),
+		},
+		{
+			"day is read in the agent's timezone",
+			"every saturday 08:00",
+			// 22:00 UTC on Friday is already 01:00 Saturday in EAT
+			time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 17, 8, 0, 0, 0, eat),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q): %v", tt.pattern, err)
+			}
+			if got := r.Next(tt.from, eat); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// internal/domain/sms/entity.go
+package sms
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	StatusFailed  Status = "failed"
+)
+
+type Purpose string
+
+const (
+	PurposeVerificationCode Purpose = "verification_code"
+	PurposeRedemptionExpiry Purpose = "redemption_expiry"
+)
+
+// Message is an SMS queued for delivery straight to a customer's phone.
+// Sensitive messages (e.g. verification codes) have their body blanked once sent.
+type Message struct {
+	ID              int64          `json:"id" db:"id"`
+	AgentIdentityID int64          `json:"agent_identity_id" db:"agent_identity_id"`
+	PhoneNumber     string         `json:"phone_number" db:"phone_number"`
+	Body            string         `json:"-" db:"body"`
+	Purpose         Purpose        `json:"purpose" db:"purpose"`
+	Sensitive       bool           `json:"sensitive" db:"sensitive"`
+	Status          Status         `json:"status" db:"status"`
+	Attempts        int            `json:"attempts" db:"attempts"`
+	LastError       sql.NullString `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt   time.Time      `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt          sql.NullTime   `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+}
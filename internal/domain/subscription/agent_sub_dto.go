@@ -1,6 +1,8 @@
 // internal/domain/subscription/dto.go
 package subscription
 
+import "time"
+
 type CreateSubscriptionRequest struct {
 	SubscriptionPlanID    int64                  `json:"subscription_plan_id" binding:"required"`
 	PromotionalCode       string                 `json:"promotional_code"`
@@ -8,7 +10,7 @@ type CreateSubscriptionRequest struct {
 	
 	// Payment details (from mobile)
 	AmountPaid            float64                `json:"amount_paid" binding:"required,min=0"`
-	Currency              string                 `json:"currency" binding:"required,len=3"`
+	Currency              string                 `json:"currency" binding:"omitempty,len=3"` // Defaults to the plan's currency
 	PaymentReference      string                 `json:"payment_reference"` // M-Pesa transaction ID
 	PaymentMethod         string                 `json:"payment_method"`
 	
@@ -21,6 +23,14 @@ type UpdateSubscriptionRequest struct {
 	Metadata              map[string]interface{} `json:"metadata"`
 }
 
+type SetCustomLimitRequest struct {
+	RequestsLimit *int `json:"requests_limit"` // null clears the override
+}
+
+type SetLimitBehaviorRequest struct {
+	LimitBehavior *LimitBehavior `json:"limit_behavior" binding:"omitempty,oneof=block warn overage"` // null clears the override
+}
+
 type SubscriptionListFilters struct {
 	Status                *SubscriptionStatus `form:"status"`
 	PlanID                *int64              `form:"plan_id"`
@@ -42,12 +52,16 @@ type SubscriptionListResponse struct {
 
 type RenewSubscriptionRequest struct {
 	AmountPaid            float64                `json:"amount_paid" binding:"required,min=0"`
-	Currency              string                 `json:"currency" binding:"required,len=3"`
+	Currency              string                 `json:"currency" binding:"omitempty,len=3"` // Defaults to the plan's currency
 	PaymentReference      string                 `json:"payment_reference"`
 	PaymentMethod         string                 `json:"payment_method"`
 	PromotionalCode       string                 `json:"promotional_code"`
 }
 
+type ChangePlanRequest struct {
+	NewPlanID             int64       `json:"new_plan_id" binding:"required"`
+}
+
 type CancelSubscriptionRequest struct {
 	Reason                string `json:"reason"`
 	CancelImmediately     bool   `json:"cancel_immediately"` // If false, cancel at period end
@@ -62,5 +76,74 @@ type SubscriptionUsageInfo struct {
 	DaysRemaining         int     `json:"days_remaining"`
 	IsExpiring            bool    `json:"is_expiring"`
 	CanMakeRequests       bool    `json:"can_make_requests"`
+	LimitBehavior         LimitBehavior `json:"limit_behavior"`
 	Metadata              map[string]interface{} `json:"metadata"`
-}
\ No newline at end of file
+}
+
+// PlanCostEstimate is a plan's projected cost for the agent's usage
+type PlanCostEstimate struct {
+	PlanID               int64   `json:"plan_id"`
+	PlanName             string  `json:"plan_name"`
+	BillingUsage         int     `json:"billing_usage"`
+	ProjectedRequests    int     `json:"projected_requests"` // Per billing cycle
+	OverageRequests      int     `json:"overage_requests"`
+	CoversUsage          bool    `json:"covers_usage"`
+	EstimatedCycleCost   float64 `json:"estimated_cycle_cost"`
+	EstimatedMonthlyCost float64 `json:"estimated_monthly_cost"`
+}
+
+type PlanRecommendation struct {
+	RecommendedPlan      *SubscriptionPlan  `json:"recommended_plan"`
+	CurrentPlanID        *int64             `json:"current_plan_id,omitempty"`
+	IsChange             bool               `json:"is_change"`
+	Reason               string             `json:"reason"`
+	AverageDailyRequests float64            `json:"average_daily_requests"`
+	HistoryDays          int                `json:"history_days"`
+	EstimatedMonthlyCost float64            `json:"estimated_monthly_cost"`
+	Candidates           []PlanCostEstimate `json:"candidates"`
+}
+
+// PlanChangePreview prices a mid-cycle plan change; the period end is unchanged by a plan change
+type PlanChangePreview struct {
+	CurrentPlanID   int64     `json:"current_plan_id"`
+	NewPlanID       int64     `json:"new_plan_id"`
+	NewPlanPrice    float64   `json:"new_plan_price"`
+	RemainingDays   int       `json:"remaining_days"`
+	ProrationCredit float64   `json:"proration_credit"` // Unused share of the amount paid for the current period
+	ProratedCharge  float64   `json:"prorated_charge"`  // New plan price for the rest of the period
+	NetCharge       float64   `json:"net_charge"`
+	Currency        string    `json:"currency"`
+	NewPeriodEnd    time.Time `json:"new_period_end"`
+}
+
+// BillingHistoryResponse lists a subscription's charges oldest first
+type BillingHistoryResponse struct {
+	SubscriptionID int64           `json:"subscription_id"`
+	Entries        []BillingRecord `json:"entries"`
+	TotalPaid      float64         `json:"total_paid"`
+	TotalDiscount  float64         `json:"total_discount"`
+	TotalDue       float64         `json:"total_due"`
+	Currency       string          `json:"currency"`
+}
+
+type AddSubAgentRequest struct {
+	ChildAgentID int64 `json:"child_agent_id" binding:"required,min=1"`
+}
+
+// AdminLinkSubAgentRequest links a child agent to a parent without the child's acceptance
+type AdminLinkSubAgentRequest struct {
+	ParentAgentID int64 `json:"parent_agent_id" binding:"required,min=1"`
+	ChildAgentID  int64 `json:"child_agent_id" binding:"required,min=1"`
+}
+
+type CancellationReasonFilters struct {
+	DateFrom              *time.Time `form:"date_from"`
+	DateTo                *time.Time `form:"date_to"`
+}
+
+type CancellationReasonsResponse struct {
+	Reasons               []CancellationReasonStat `json:"reasons"`
+	TotalCancellations    int64                    `json:"total_cancellations"`
+	DateFrom              *time.Time               `json:"date_from,omitempty"`
+	DateTo                *time.Time               `json:"date_to,omitempty"`
+}
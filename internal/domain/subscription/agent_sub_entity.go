@@ -17,6 +17,30 @@ const (
 	SubscriptionStatusPaused   SubscriptionStatus = "paused"
 )
 
+// UsagePolicy decides what happens to requests_used when a plan changes mid-cycle
+type UsagePolicy string
+
+const (
+	UsagePolicyKeep  UsagePolicy = "keep"
+	UsagePolicyReset UsagePolicy = "reset"
+)
+
+// RenewalPricing decides which price a renewal charges once the plan's price has changed
+type RenewalPricing string
+
+const (
+	RenewalPricingGrandfathered RenewalPricing = "grandfathered"
+	RenewalPricingCurrent       RenewalPricing = "current"
+)
+
+// DefaultRenewalPricing keeps subscribers on the price they bought until they change plan
+const DefaultRenewalPricing = RenewalPricingGrandfathered
+
+// DefaultUsagePolicy keeps usage on plan change so agents cannot refill their
+// quota by switching plans mid-cycle; the counter still resets on renewal.
+// Set PLAN_CHANGE_USAGE_POLICY=reset to start every plan change on a fresh counter.
+const DefaultUsagePolicy = UsagePolicyKeep
+
 type AgentSubscription struct {
 	ID                     int64              `json:"id" db:"id"`
 	SubscriptionReference  string             `json:"subscription_reference" db:"subscription_reference"`
@@ -43,6 +67,7 @@ type AgentSubscription struct {
 	
 	// Pricing
 	PlanPrice              float64            `json:"plan_price" db:"plan_price"`
+	PlanVersion            int                `json:"plan_version" db:"plan_version"`
 	DiscountApplied        float64            `json:"discount_applied" db:"discount_applied"`
 	AmountPaid             float64            `json:"amount_paid" db:"amount_paid"`
 	Currency               string             `json:"currency" db:"currency"`
@@ -60,6 +85,57 @@ type AgentSubscription struct {
 	UpdatedAt              time.Time          `json:"updated_at" db:"updated_at"`
 }
 
+// BillingEvent names what produced a subscription charge
+type BillingEvent string
+
+const (
+	BillingEventSubscribed  BillingEvent = "subscribed"
+	BillingEventRenewed     BillingEvent = "renewed"
+	BillingEventPlanChanged BillingEvent = "plan_changed"
+	BillingEventOverage     BillingEvent = "overage"
+)
+
+// BillingRecord is one charge against a subscription, written when it is created, renewed, changes plan
+// or ends a period over its request limit
+type BillingRecord struct {
+	ID                    int64           `json:"id" db:"id"`
+	SubscriptionID        int64           `json:"subscription_id" db:"subscription_id"`
+	AgentIdentityID       int64           `json:"agent_identity_id" db:"agent_identity_id"`
+	Event                 BillingEvent    `json:"event" db:"event"`
+	SubscriptionPlanID    int64           `json:"subscription_plan_id" db:"subscription_plan_id"`
+	PeriodStart           time.Time       `json:"period_start" db:"period_start"`
+	PeriodEnd             time.Time       `json:"period_end" db:"period_end"`
+	PlanPrice             float64         `json:"plan_price" db:"plan_price"`
+	SetupFee              float64         `json:"setup_fee" db:"setup_fee"`
+	DiscountApplied       float64         `json:"discount_applied" db:"discount_applied"`
+	AmountPaid            float64         `json:"amount_paid" db:"amount_paid"`
+	Currency              string          `json:"currency" db:"currency"`
+	PromotionalCampaignID sql.NullInt64   `json:"promotional_campaign_id,omitempty" db:"promotional_campaign_id"`
+	PaymentReference      sql.NullString  `json:"payment_reference,omitempty" db:"payment_reference"`
+	OverageRequests       int             `json:"overage_requests,omitempty" db:"overage_requests"`
+	OverageRate           sql.NullFloat64 `json:"overage_rate,omitempty" db:"overage_rate"`
+	AmountDue             float64         `json:"amount_due" db:"amount_due"`
+	CreatedAt             time.Time       `json:"created_at" db:"created_at"`
+}
+
+type SubAgentStatus string
+
+const (
+	SubAgentStatusPending SubAgentStatus = "pending" // Invited; waiting for the child to accept
+	SubAgentStatusActive  SubAgentStatus = "active"
+)
+
+// SubAgent links a child agent to the parent agent whose subscription covers its usage.
+// The link only takes effect once active: accepted by the child or made by an admin.
+type SubAgent struct {
+	ID            int64          `json:"id" db:"id"`
+	ParentAgentID int64          `json:"parent_agent_id" db:"parent_agent_id"`
+	ChildAgentID  int64          `json:"child_agent_id" db:"child_agent_id"`
+	Status        SubAgentStatus `json:"status" db:"status"`
+	AcceptedAt    sql.NullTime   `json:"accepted_at,omitempty" db:"accepted_at"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
 type SubscriptionStats struct {
 	TotalSubscriptions     int64   `json:"total_subscriptions"`
 	ActiveSubscriptions    int64   `json:"active_subscriptions"`
@@ -67,4 +143,9 @@ type SubscriptionStats struct {
 	CancelledSubscriptions int64   `json:"cancelled_subscriptions"`
 	TotalRevenue           float64 `json:"total_revenue"`
 	AverageSubscriptionValue float64 `json:"average_subscription_value"`
-}
\ No newline at end of file
+}
+type CancellationReasonStat struct {
+	Reason     string  `json:"reason"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
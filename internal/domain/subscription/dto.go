@@ -15,6 +15,10 @@ type CreatePlanRequest struct {
 	BillingUsage   int           `json:"billing_usage" binding:"required,min=1"`
 	BillingCycle   RenewalPeriod `json:"billing_cycle" binding:"required"`
 	OverageCharge  *float64      `json:"overage_charge" binding:"omitempty,min=0"`
+	LimitBehavior  LimitBehavior `json:"limit_behavior" binding:"omitempty,oneof=block warn overage"` // Defaults from overage_charge
+
+	// Plan changes
+	AllowedTransitions []int64 `json:"allowed_transitions"` // Omit to allow changing to any plan
 	
 	// Features
 	MaxOffers    *int32                 `json:"max_offers" binding:"omitempty,min=1"`
@@ -40,6 +44,11 @@ type UpdatePlanRequest struct {
 	BillingUsage   *int     `json:"billing_usage" binding:"omitempty,min=1"`
 	BillingCycle   *RenewalPeriod `json:"billing_cycle"`
 	OverageCharge  *float64 `json:"overage_charge" binding:"omitempty,min=0"`
+	LimitBehavior  *LimitBehavior `json:"limit_behavior" binding:"omitempty,oneof=block warn overage"`
+
+	// Plan changes
+	AllowedTransitions  []int64 `json:"allowed_transitions"`   // Replaces the allowed target plans when set
+	AllowAllTransitions bool    `json:"allow_all_transitions"` // Clears the restriction
 	
 	// Features
 	MaxOffers    *int32                 `json:"max_offers" binding:"omitempty,min=1"`
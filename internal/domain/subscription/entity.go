@@ -27,6 +27,24 @@ const (
 	StatusPaused	SubscriptionStatus = "paused"
 )
 
+// LimitBehavior controls what happens once a subscription reaches its request limit
+type LimitBehavior string
+
+const (
+	LimitBehaviorBlock   LimitBehavior = "block"   // Reject requests at the limit
+	LimitBehaviorWarn    LimitBehavior = "warn"    // Allow requests past the limit without charging, logging a warning
+	LimitBehaviorOverage LimitBehavior = "overage" // Allow requests past the limit, charged at OverageCharge
+)
+
+// IsValid reports whether b is a known limit behavior
+func (b LimitBehavior) IsValid() bool {
+	switch b {
+	case LimitBehaviorBlock, LimitBehaviorWarn, LimitBehaviorOverage:
+		return true
+	}
+	return false
+}
+
 type SubscriptionPlan struct {
 	ID          int64                  `json:"id" db:"id"`
 	PlanCode    string                 `json:"plan_code" db:"plan_code"`
@@ -37,11 +55,16 @@ type SubscriptionPlan struct {
 	Price       float64 `json:"price" db:"price"`
 	Currency    string  `json:"currency" db:"currency"`
 	SetupFee    float64 `json:"setup_fee" db:"setup_fee"`
+	Version     int     `json:"version" db:"version"` // Bumped whenever the price changes
 	
 	// Billing
 	BillingUsage   int           `json:"billing_usage" db:"billing_usage"`
 	BillingCycle   RenewalPeriod `json:"billing_cycle" db:"billing_cycle"`
 	OverageCharge  sql.NullFloat64 `json:"overage_charge,omitempty" db:"overage_charge"`
+	LimitBehavior  LimitBehavior   `json:"limit_behavior,omitempty" db:"limit_behavior"` // Empty derives from OverageCharge
+
+	// Plan changes
+	AllowedTransitions []int64 `json:"allowed_transitions,omitempty" db:"allowed_transitions"` // nil allows changing to any plan
 	
 	// Features
 	MaxOffers    sql.NullInt32          `json:"max_offers,omitempty" db:"max_offers"`
@@ -68,4 +91,29 @@ type SubscriptionPlanStats struct {
 	AveragePrice        float64 `json:"average_price"`
 	MostPopularPlanID   int64   `json:"most_popular_plan_id,omitempty"`
 	MostPopularPlanName string  `json:"most_popular_plan_name,omitempty"`
-}
\ No newline at end of file
+}
+
+// EffectiveLimitBehavior returns the plan's limit behavior; plans without one overage-charge when
+// OverageCharge is set and block otherwise
+func (p *SubscriptionPlan) EffectiveLimitBehavior() LimitBehavior {
+	if p.LimitBehavior.IsValid() {
+		return p.LimitBehavior
+	}
+	if p.OverageCharge.Valid {
+		return LimitBehaviorOverage
+	}
+	return LimitBehaviorBlock
+}
+
+// AllowsTransitionTo reports whether subscribers may change from this plan to planID
+func (p *SubscriptionPlan) AllowsTransitionTo(planID int64) bool {
+	if p.AllowedTransitions == nil {
+		return true
+	}
+	for _, id := range p.AllowedTransitions {
+		if id == planID {
+			return true
+		}
+	}
+	return false
+}
@@ -9,7 +9,8 @@ type CreateOfferRequestInput struct {
 	CustomerName  string        `json:"customer_name"`
 	PaymentMethod PaymentMethod `json:"payment_method" binding:"required"`
 	AmountPaid    float64       `json:"amount_paid" binding:"required,min=0"`
-	Currency      string        `json:"currency" binding:"required,len=3"`
+	Currency      string        `json:"currency" binding:"omitempty,len=3"` // Defaults to the agent's display currency
+	Source        RequestSource `json:"source" binding:"omitempty,oneof=ussd app web unknown"`
 	
 	// M-Pesa specific
 	MpesaTransactionID   string    `json:"mpesa_transaction_id"`
@@ -18,11 +19,62 @@ type CreateOfferRequestInput struct {
 	MpesaPhoneNumber     string    `json:"mpesa_phone_number"`
 	MpesaMessage         string    `json:"mpesa_message"`
 	
+	// Create a renewal schedule when a recurring offer purchase succeeds
+	AutoScheduleRenewal bool `json:"auto_schedule_renewal"`
+	
+	// Location (optional, both or neither)
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	
 	// Device info
 	DeviceInfo map[string]interface{} `json:"device_info"`
 	Metadata   map[string]interface{} `json:"metadata"`
 }
 
+// BatchCreateRequestsInput submits many offer requests at once, e.g. for a bulk top-up event
+type BatchCreateRequestsInput struct {
+	Requests []CreateOfferRequestInput `json:"requests" binding:"required,min=1,max=100,dive"`
+}
+
+// BatchItemStatus is the outcome of one item in a batch
+type BatchItemStatus string
+
+const (
+	BatchItemCreated   BatchItemStatus = "created"
+	BatchItemDuplicate BatchItemStatus = "duplicate" // M-Pesa receipt already recorded; the existing request is returned
+	BatchItemFailed    BatchItemStatus = "failed"
+)
+
+// BatchCreateResult reports one item of a batch, in input order
+type BatchCreateResult struct {
+	Index               int               `json:"index"`
+	Status              BatchItemStatus   `json:"status"`
+	RequestID           int64             `json:"request_id,omitempty"`
+	RequestReference    string            `json:"request_reference,omitempty"`
+	RedemptionReference string            `json:"redemption_reference,omitempty"`
+	RequestStatus       TransactionStatus `json:"request_status,omitempty"`
+	Error               string            `json:"error,omitempty"`
+}
+
+type BatchCreateResponse struct {
+	Results        []BatchCreateResult `json:"results"`
+	Total          int                 `json:"total"`
+	CreatedCount   int                 `json:"created_count"`
+	DuplicateCount int                 `json:"duplicate_count"`
+	FailedCount    int                 `json:"failed_count"`
+}
+
+// RiskAssessment is the fraud score for an incoming offer request, 0 (clean) to 100
+type RiskAssessment struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ReviewHeldRequestInput approves a request held for review or rejects it
+type ReviewHeldRequestInput struct {
+	Approve bool `json:"approve"`
+}
+
 type OfferRequestListFilters struct {
 	Status        *TransactionStatus `form:"status"`
 	OfferID       *int64             `form:"offer_id"`
@@ -31,6 +83,7 @@ type OfferRequestListFilters struct {
 	DateFrom      *time.Time         `form:"date_from"`
 	DateTo        *time.Time         `form:"date_to"`
 	Search        string             `form:"search"`
+	HeldForReview *bool              `form:"held_for_review"`
 	Page          int                `form:"page" binding:"min=1"`
 	PageSize      int                `form:"page_size" binding:"min=1,max=100"`
 	SortBy        string             `form:"sort_by"`
@@ -45,6 +98,50 @@ type OfferRequestListResponse struct {
 	TotalPages int            `json:"total_pages"`
 }
 
+type SalesHeatmapBounds struct {
+	MinLatitude  *float64   `form:"min_lat" binding:"omitempty,min=-90,max=90"`
+	MaxLatitude  *float64   `form:"max_lat" binding:"omitempty,min=-90,max=90"`
+	MinLongitude *float64   `form:"min_lng" binding:"omitempty,min=-180,max=180"`
+	MaxLongitude *float64   `form:"max_lng" binding:"omitempty,min=-180,max=180"`
+	CellSize     float64    `form:"cell_size" binding:"omitempty,gt=0,max=10"` // Degrees per cell
+	DateFrom     *time.Time `form:"date_from"`
+	DateTo       *time.Time `form:"date_to"`
+}
+
+type SalesHeatmapResponse struct {
+	CellSize   float64       `json:"cell_size"`
+	Cells      []HeatmapCell `json:"cells"`
+	TotalSales int64         `json:"total_sales"`
+}
+
+// RequestWithRedemption pairs an offer request with its redemption, if one was created
+type RequestWithRedemption struct {
+	Request    *OfferRequest    `json:"request"`
+	Redemption *OfferRedemption `json:"redemption,omitempty"`
+}
+
+type SalesSeriesFilters struct {
+	From        *time.Time             `form:"from"`
+	To          *time.Time             `form:"to"`
+	Granularity SalesSeriesGranularity `form:"granularity"`
+}
+
+type SalesSeriesResponse struct {
+	OfferID      int64                  `json:"offer_id"`
+	Granularity  SalesSeriesGranularity `json:"granularity"`
+	From         time.Time              `json:"from"`
+	To           time.Time              `json:"to"`
+	Points       []SalesSeriesPoint     `json:"points"`
+	TotalCount   int64                  `json:"total_count"`
+	TotalRevenue float64                `json:"total_revenue"`
+}
+
+// StatsFilters narrows transaction statistics to a date range; both bounds are optional
+type StatsFilters struct {
+	From *time.Time `form:"from"`
+	To   *time.Time `form:"to"`
+}
+
 type RedemptionListFilters struct {
 	Status         *TransactionStatus `form:"status"`
 	OfferID        *int64             `form:"offer_id"`
@@ -72,4 +169,68 @@ type UpdateUSSDResponseInput struct {
 	USSDProcessingTime int32  `json:"ussd_processing_time"`
 	Status             TransactionStatus `json:"status"`
 	FailureReason      string `json:"failure_reason"`
-}
\ No newline at end of file
+	FailureCode        FailureCode `json:"failure_code"`
+}
+// TransactionAudit is the full captured history of a request, oldest entry first
+type TransactionAudit struct {
+	RequestID        int64             `json:"request_id"`
+	RequestReference string            `json:"request_reference"`
+	Status           TransactionStatus `json:"status"`
+	Entries          []AuditEntry      `json:"entries"`
+}
+
+// MpesaCallback is an M-Pesa callback body: either an STK push result under Body.stkCallback
+// or a C2B confirmation with its fields at the top level
+type MpesaCallback struct {
+	Body *MpesaCallbackBody `json:"Body,omitempty"`
+
+	// C2B confirmation
+	TransactionType   string `json:"TransactionType"`
+	TransID           string `json:"TransID"`
+	TransTime         string `json:"TransTime"` // yyyyMMddHHmmss, Nairobi time
+	TransAmount       string `json:"TransAmount"`
+	BusinessShortCode string `json:"BusinessShortCode"`
+	BillRefNumber     string `json:"BillRefNumber"`
+	MSISDN            string `json:"MSISDN"`
+	FirstName         string `json:"FirstName"`
+	LastName          string `json:"LastName"`
+}
+
+type MpesaCallbackBody struct {
+	StkCallback *MpesaSTKCallback `json:"stkCallback"`
+}
+
+type MpesaSTKCallback struct {
+	MerchantRequestID string `json:"MerchantRequestID"`
+	CheckoutRequestID string `json:"CheckoutRequestID"`
+	ResultCode        int    `json:"ResultCode"`
+	ResultDesc        string `json:"ResultDesc"`
+	CallbackMetadata  struct {
+		Item []MpesaCallbackItem `json:"Item"`
+	} `json:"CallbackMetadata"`
+}
+
+type MpesaCallbackItem struct {
+	Name  string      `json:"Name"`
+	Value interface{} `json:"Value"`
+}
+
+// MpesaCallbackStatus is what ingesting a callback did
+type MpesaCallbackStatus string
+
+const (
+	MpesaCallbackMatched   MpesaCallbackStatus = "matched"   // Payment recorded on a pending request
+	MpesaCallbackHeld      MpesaCallbackStatus = "held"      // Payment recorded but the amount differs, so the request waits for review
+	MpesaCallbackCreated   MpesaCallbackStatus = "created"   // New request created for the offer code paid to
+	MpesaCallbackDuplicate MpesaCallbackStatus = "duplicate" // Receipt already recorded
+	MpesaCallbackUnmatched MpesaCallbackStatus = "unmatched"
+	MpesaCallbackFailed    MpesaCallbackStatus = "failed" // STK push not paid
+)
+
+type MpesaCallbackResult struct {
+	Status        MpesaCallbackStatus `json:"status"`
+	MatchedBy     string              `json:"matched_by,omitempty"` // checkout_request, reference, amount_phone, offer_code
+	ReceiptNumber string              `json:"receipt_number,omitempty"`
+	RequestID     int64               `json:"request_id,omitempty"`
+	RedemptionID  int64               `json:"redemption_id,omitempty"`
+}
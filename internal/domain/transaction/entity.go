@@ -22,14 +22,57 @@ const (
 type TransactionStatus string
 
 const (
-	TransactionStatusPending    TransactionStatus = "pending"
-	TransactionStatusProcessing TransactionStatus = "processing"
-	TransactionStatusSuccess    TransactionStatus = "success"
-	TransactionStatusFailed     TransactionStatus = "failed"
-	TransactionStatusCancelled  TransactionStatus = "cancelled"
-	TransactionStatusReversed   TransactionStatus = "reversed"
+	TransactionStatusPending             TransactionStatus = "pending"
+	TransactionStatusPendingConfirmation TransactionStatus = "pending_confirmation" // Waiting for the agent to confirm a high-value purchase
+	TransactionStatusProcessing          TransactionStatus = "processing"
+	TransactionStatusSuccess             TransactionStatus = "success"
+	TransactionStatusFailed              TransactionStatus = "failed"
+	TransactionStatusCancelled           TransactionStatus = "cancelled"
+	TransactionStatusReversed            TransactionStatus = "reversed"
 )
 
+type RequestSource string
+
+const (
+	RequestSourceUSSD    RequestSource = "ussd"
+	RequestSourceApp     RequestSource = "app"
+	RequestSourceWeb     RequestSource = "web"
+	RequestSourceUnknown RequestSource = "unknown"
+)
+
+// IsValid reports whether the source is one of the known channels
+func (rs RequestSource) IsValid() bool {
+	switch rs {
+	case RequestSourceUSSD, RequestSourceApp, RequestSourceWeb, RequestSourceUnknown:
+		return true
+	}
+	return false
+}
+
+type FailureCode string
+
+const (
+	FailureCodeInsufficientBalance FailureCode = "insufficient_balance"
+	FailureCodeUSSDTimeout         FailureCode = "ussd_timeout"
+	FailureCodeInvalidCode         FailureCode = "invalid_code"
+	FailureCodeNetworkError        FailureCode = "network_error"
+	FailureCodeInvalidNumber       FailureCode = "invalid_number"
+	FailureCodeServiceUnavailable  FailureCode = "service_unavailable"
+	FailureCodeCancelledByUser     FailureCode = "cancelled_by_user"
+	FailureCodeUnknown             FailureCode = "unknown"
+)
+
+// IsValid reports whether the code is one of the known failure categories
+func (fc FailureCode) IsValid() bool {
+	switch fc {
+	case FailureCodeInsufficientBalance, FailureCodeUSSDTimeout, FailureCodeInvalidCode,
+		FailureCodeNetworkError, FailureCodeInvalidNumber, FailureCodeServiceUnavailable,
+		FailureCodeCancelledByUser, FailureCodeUnknown:
+		return true
+	}
+	return false
+}
+
 type OfferRequest struct {
 	ID                 int64             `json:"id" db:"id"`
 	RequestReference   string            `json:"request_reference" db:"request_reference"`
@@ -58,7 +101,16 @@ type OfferRequest struct {
 	ProcessedAt   sql.NullTime      `json:"processed_at,omitempty" db:"processed_at"`
 	Status        TransactionStatus `json:"status" db:"status"`
 	FailureReason sql.NullString    `json:"failure_reason,omitempty" db:"failure_reason"`
+	FailureCode   sql.NullString    `json:"failure_code,omitempty" db:"failure_code"`
 	RetryCount    int               `json:"retry_count" db:"retry_count"`
+	LastRetryAt   sql.NullTime      `json:"last_retry_at,omitempty" db:"last_retry_at"`
+	Source        RequestSource     `json:"source" db:"source"`
+	RiskScore     int               `json:"risk_score" db:"risk_score"`
+	HeldForReview bool              `json:"held_for_review" db:"held_for_review"`
+	
+	// Location
+	Latitude  sql.NullFloat64 `json:"latitude,omitempty" db:"latitude"`
+	Longitude sql.NullFloat64 `json:"longitude,omitempty" db:"longitude"`
 	
 	// Metadata
 	DeviceInfo map[string]interface{} `json:"device_info,omitempty" db:"device_info"`
@@ -113,10 +165,93 @@ type OfferRedemption struct {
 }
 
 type TransactionStats struct {
-	TotalRequests       int64   `json:"total_requests"`
-	SuccessfulRequests  int64   `json:"successful_requests"`
-	PendingRequests     int64   `json:"pending_requests"`
-	FailedRequests      int64   `json:"failed_requests"`
-	TotalRevenue        float64 `json:"total_revenue"`
-	SuccessRate         float64 `json:"success_rate"`
-}
\ No newline at end of file
+	TotalRequests         int64              `json:"total_requests"`
+	SuccessfulRequests    int64              `json:"successful_requests"`
+	PendingRequests       int64              `json:"pending_requests"`
+	FailedRequests        int64              `json:"failed_requests"`
+	TotalRevenue          float64            `json:"total_revenue"`
+	SuccessRate           float64            `json:"success_rate"`
+	TotalRedemptions      int64              `json:"total_redemptions"`
+	SuccessfulRedemptions int64              `json:"successful_redemptions"`
+	BySource              []SourceStats      `json:"by_source"`
+	FailureBreakdown      []FailureCodeStats `json:"failure_breakdown"`
+}
+
+// OfferPerformance aggregates one offer's requests over a period
+type OfferPerformance struct {
+	OfferID         int64   `json:"offer_id"`
+	OfferCode       string  `json:"offer_code"`
+	OfferName       string  `json:"offer_name"`
+	Requests        int64   `json:"requests"`
+	Sales           int64   `json:"sales"`           // Successful requests
+	Revenue         float64 `json:"revenue"`
+	ConversionRate  float64 `json:"conversion_rate"` // Sales as a percentage of requests
+	USSDAttempts    int64   `json:"ussd_attempts"`   // Redemptions that finished, successfully or not
+	USSDSuccesses   int64   `json:"ussd_successes"`
+	USSDSuccessRate float64 `json:"ussd_success_rate"`
+}
+
+type SalesSeriesGranularity string
+
+const (
+	SalesSeriesDaily  SalesSeriesGranularity = "daily"
+	SalesSeriesWeekly SalesSeriesGranularity = "weekly"
+)
+
+// TruncUnit returns the date_trunc unit for the granularity
+func (g SalesSeriesGranularity) TruncUnit() (string, bool) {
+	switch g {
+	case SalesSeriesDaily:
+		return "day", true
+	case SalesSeriesWeekly:
+		return "week", true
+	}
+	return "", false
+}
+
+type SalesSeriesPoint struct {
+	BucketStart time.Time `json:"bucket_start"` // UTC
+	Count       int64     `json:"count"`
+	Revenue     float64   `json:"revenue"`
+}
+
+type FailureCodeStats struct {
+	FailureCode FailureCode `json:"failure_code"`
+	Count       int64       `json:"count"`
+}
+
+type SourceStats struct {
+	Source             RequestSource `json:"source"`
+	TotalRequests      int64         `json:"total_requests"`
+	SuccessfulRequests int64         `json:"successful_requests"`
+	TotalRevenue       float64       `json:"total_revenue"`
+}
+
+type HeatmapCell struct {
+	Latitude  float64 `json:"latitude"`  // Cell center
+	Longitude float64 `json:"longitude"` // Cell center
+	Count     int64   `json:"count"`
+	Revenue   float64 `json:"revenue"`
+}
+
+// AuditEvent identifies what a transaction audit entry captured
+type AuditEvent string
+
+const (
+	AuditEventRequestCreated AuditEvent = "request_created"
+	AuditEventStatusUpdated  AuditEvent = "status_updated"
+	AuditEventAdminRetry     AuditEvent = "admin_retry"
+	AuditEventMpesaPayment   AuditEvent = "mpesa_payment"
+	AuditEventConfirmed      AuditEvent = "confirmed"
+)
+
+// AuditEntry is an immutable snapshot of a request's input or of a status change
+type AuditEntry struct {
+	ID              int64                  `json:"id" db:"id"`
+	OfferRequestID  int64                  `json:"offer_request_id" db:"offer_request_id"`
+	AgentIdentityID int64                  `json:"agent_identity_id" db:"agent_identity_id"`
+	Event           AuditEvent             `json:"event" db:"event"`
+	Status          TransactionStatus      `json:"status" db:"status"`
+	Payload         map[string]interface{} `json:"payload,omitempty" db:"payload"`
+	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
+}
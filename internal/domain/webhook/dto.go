@@ -0,0 +1,25 @@
+// internal/domain/webhook/dto.go
+package webhook
+
+import "time"
+
+type ReplayRequest struct {
+	Since time.Time `json:"since" binding:"required"`
+}
+
+// ReplayResult reports the outcome of re-delivering failed events
+type ReplayResult struct {
+	Replayed     int     `json:"replayed"`
+	Delivered    int     `json:"delivered"`
+	Failed       int     `json:"failed"`
+	DeliveredIDs []int64 `json:"delivered_ids"`
+	FailedIDs    []int64 `json:"failed_ids"`
+}
+
+// Envelope is the JSON body posted to the agent's endpoint
+type Envelope struct {
+	ID        int64                  `json:"id"`
+	Event     string                 `json:"event"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
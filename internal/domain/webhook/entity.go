@@ -0,0 +1,60 @@
+// internal/domain/webhook/entity.go
+package webhook
+
+import (
+	"database/sql"
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusFailed    DeliveryStatus = "failed" // Retries exhausted
+)
+
+// EventRedemptionStatusChanged is emitted when a redemption moves to a new status
+const EventRedemptionStatusChanged = "redemption.status_changed"
+
+// EventOfferAnalyticsDigest carries the agent's periodic per-offer performance
+const EventOfferAnalyticsDigest = "offer.analytics_digest"
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusFailed  OutboxStatus = "failed" // Relay attempts exhausted or the payload is unreadable
+)
+
+// OutboxEvent is an event written in the same transaction as the change that caused it,
+// waiting for the relay to hand it to webhook delivery
+type OutboxEvent struct {
+	ID              int64                  `json:"id" db:"id"`
+	AgentIdentityID int64                  `json:"agent_identity_id" db:"agent_identity_id"`
+	EventType       string                 `json:"event_type" db:"event_type"`
+	Payload         map[string]interface{} `json:"payload" db:"payload"`
+	Status          OutboxStatus           `json:"status" db:"status"`
+	Attempts        int                    `json:"attempts" db:"attempts"`
+	LastError       sql.NullString         `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt   time.Time              `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt          sql.NullTime           `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
+}
+
+// Delivery is a webhook event and the state of its delivery to the agent's endpoint
+type Delivery struct {
+	ID              int64                  `json:"id" db:"id"`
+	AgentIdentityID int64                  `json:"agent_identity_id" db:"agent_identity_id"`
+	EventType       string                 `json:"event_type" db:"event_type"`
+	Payload         map[string]interface{} `json:"payload" db:"payload"`
+	Status          DeliveryStatus         `json:"status" db:"status"`
+	Attempts        int                    `json:"attempts" db:"attempts"`
+	LastStatusCode  sql.NullInt32          `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError       sql.NullString         `json:"last_error,omitempty" db:"last_error"`
+	LastAttemptAt   sql.NullTime           `json:"last_attempt_at,omitempty" db:"last_attempt_at"`
+	DeliveredAt     sql.NullTime           `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at" db:"updated_at"`
+}
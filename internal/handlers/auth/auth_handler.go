@@ -2,11 +2,17 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	//"strings"
 
 	"bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/domain/emaillog"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
 	"bingwa-service/internal/pkg/response"
 	authUsecase "bingwa-service/internal/service/auth"
 
@@ -39,13 +45,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Set IP and User-Agent
 	req.IPAddress = c.ClientIP()
 	req.UserAgent = c.GetHeader("User-Agent")
+	req.DeviceFingerprint = c.GetHeader(auth.DeviceFingerprintHeader)
 
 	loginResp, err := h.authService.Register(c.Request.Context(), &req)
 	if err != nil {
 		h.logger.Error("registration failed",
-			zap.String("email", req.Email),
+			zap.String("email", mask.Email(req.Email)),
 			zap.Error(err),
 		)
+		if errors.Is(err, xerrors.ErrRateLimited) {
+			response.Error(c, http.StatusTooManyRequests, "too many registration attempts", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "registration failed", err)
 		return
 	}
@@ -66,11 +77,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Set IP and User-Agent
 	req.IPAddress = c.ClientIP()
 	req.UserAgent = c.GetHeader("User-Agent")
+	req.DeviceFingerprint = c.GetHeader(auth.DeviceFingerprintHeader)
 
 	loginResp, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
 		h.logger.Error("login failed",
-			zap.String("email", req.Email),
+			zap.String("email", mask.Email(req.Email)),
 			zap.String("ip", req.IPAddress),
 			zap.Error(err),
 		)
@@ -80,7 +92,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	h.logger.Info("user logged in",
 		zap.Int64("identity_id", loginResp.User.IdentityID),
-		zap.String("email", loginResp.User.Email),
+		zap.String("email", mask.Email(loginResp.User.Email)),
 	)
 
 	response.Success(c, http.StatusOK, "login successful", loginResp)
@@ -147,7 +159,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 
 	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
 		h.logger.Error("forgot password failed",
-			zap.String("email", req.Email),
+			zap.String("email", mask.Email(req.Email)),
 			zap.Error(err),
 		)
 		// Don't reveal if email exists
@@ -249,6 +261,211 @@ func (h *AuthHandler) ListAdmins(c *gin.Context) {
 	response.Success(c, http.StatusOK, "admins retrieved", admins)
 }
 
+// ListEmailLogs retrieves email delivery history (admin only)
+func (h *AuthHandler) ListEmailLogs(c *gin.Context) {
+	var filters emaillog.EmailLogListFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	result, err := h.authService.ListEmailLogs(c.Request.Context(), &filters)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list email logs", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "email logs retrieved", result)
+}
+
+// ========== Roles & Permissions (super admin only) ==========
+
+// CreateRole creates a custom role
+func (h *AuthHandler) CreateRole(c *gin.Context) {
+	var req auth.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	role, err := h.authService.CreateRole(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to create role", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "role created", role)
+}
+
+// ListRoles lists all roles
+func (h *AuthHandler) ListRoles(c *gin.Context) {
+	roles, err := h.authService.ListRoles(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list roles", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "roles retrieved", roles)
+}
+
+// DeleteRole deletes a custom role
+func (h *AuthHandler) DeleteRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid role ID", err)
+		return
+	}
+
+	if err := h.authService.DeleteRole(c.Request.Context(), roleID); err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to delete role", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "role deleted", nil)
+}
+
+// AssignRole assigns a role to a user, optionally until an expiry time
+func (h *AuthHandler) AssignRole(c *gin.Context) {
+	identityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	var req auth.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	assignedBy := middleware.MustGetIdentityID(c)
+	if err := h.authService.AssignRole(c.Request.Context(), identityID, &req, assignedBy); err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to assign role", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "role assigned", gin.H{
+		"identity_id": identityID,
+		"role":        req.RoleName,
+		"expires_at":  req.ExpiresAt,
+	})
+}
+
+// AssignPermissionToRole adds a permission to a role
+func (h *AuthHandler) AssignPermissionToRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid role ID", err)
+		return
+	}
+
+	var req auth.RolePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	grantedBy := middleware.MustGetIdentityID(c)
+	if err := h.authService.AssignPermissionToRole(c.Request.Context(), roleID, req.PermissionID, grantedBy); err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to assign permission", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "permission assigned to role", nil)
+}
+
+// ListPermissions lists all permissions
+func (h *AuthHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.authService.ListPermissions(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list permissions", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "permissions retrieved", permissions)
+}
+
+// GetUserPermissions lists a user's effective permissions
+func (h *AuthHandler) GetUserPermissions(c *gin.Context) {
+	identityID, err := strconv.ParseInt(c.Param("identity_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	permissions, err := h.authService.GetUserPermissions(c.Request.Context(), identityID)
+	if err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to get user permissions", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "user permissions retrieved", gin.H{
+		"identity_id": identityID,
+		"permissions": permissions,
+	})
+}
+
+// GrantPermissionToUser grants a permission to a single user
+func (h *AuthHandler) GrantPermissionToUser(c *gin.Context) {
+	identityID, err := strconv.ParseInt(c.Param("identity_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	var req auth.UserPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	grantedBy := middleware.MustGetIdentityID(c)
+	if err := h.authService.GrantPermissionToUser(c.Request.Context(), identityID, req.PermissionID, grantedBy, req.ExpiresAt); err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to grant permission", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "permission granted", nil)
+}
+
+// RevokePermissionFromUser denies a permission to a single user
+func (h *AuthHandler) RevokePermissionFromUser(c *gin.Context) {
+	identityID, err := strconv.ParseInt(c.Param("identity_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+	permissionID, err := strconv.ParseInt(c.Param("permission_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid permission ID", err)
+		return
+	}
+
+	revokedBy := middleware.MustGetIdentityID(c)
+	if err := h.authService.RevokePermissionFromUser(c.Request.Context(), identityID, permissionID, revokedBy); err != nil {
+		response.Error(c, roleErrorStatus(err), "failed to revoke permission", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "permission revoked", nil)
+}
+
+// roleErrorStatus maps role and permission errors to HTTP status codes
+func roleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, xerrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, xerrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, xerrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, xerrors.ErrInvalidInput):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 // DeactivateAdmin deactivates an admin account (super admin only)
 func (h *AuthHandler) DeactivateAdmin(c *gin.Context) {
 	identityID := c.GetInt64("id")
@@ -295,6 +512,50 @@ func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
 	response.Success(c, http.StatusOK, "verification email sent", nil)
 }
 
+// ========== Email Change ==========
+
+// RequestEmailChange starts an email change (requires auth)
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	identityID := middleware.MustGetIdentityID(c)
+
+	var req auth.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(c.Request.Context(), identityID, req.NewEmail); err != nil {
+		if errors.Is(err, xerrors.ErrDuplicateEntry) {
+			response.Error(c, http.StatusConflict, "email already in use", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "email change request failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "confirmation email sent to new address", nil)
+}
+
+// ConfirmEmailChange completes an email change
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		response.Error(c, http.StatusBadRequest, "token is required", nil)
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(c.Request.Context(), token); err != nil {
+		if errors.Is(err, xerrors.ErrDuplicateEntry) {
+			response.Error(c, http.StatusConflict, "email already in use", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "email change failed", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "email changed successfully, please log in again", nil)
+}
+
 // ========== Session Management ==========
 
 // GetActiveSessions returns all active sessions for current user
@@ -322,3 +583,54 @@ func (h *AuthHandler) RevokeSession(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "session revoked", nil)
 }
+
+// RevokeSessionsByDevice revokes all sessions for a device, e.g. a lost phone
+func (h *AuthHandler) RevokeSessionsByDevice(c *gin.Context) {
+	identityID := middleware.MustGetIdentityID(c)
+	deviceID := c.Param("device_id")
+
+	revoked, err := h.authService.RevokeSessionsByDevice(c.Request.Context(), identityID, deviceID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "no active sessions for device", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to revoke device sessions", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "device sessions revoked", gin.H{
+		"device_id":     deviceID,
+		"revoked_count": revoked,
+	})
+}
+
+// ========== Account Data ==========
+
+// ExportUserData returns a JSON archive of all data held about the current user
+func (h *AuthHandler) ExportUserData(c *gin.Context) {
+	identityID := middleware.MustGetIdentityID(c)
+
+	export, err := h.authService.ExportUserData(c.Request.Context(), identityID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to export user data", err)
+		return
+	}
+
+	filename := fmt.Sprintf("user-data-%d-%s.json", identityID, export.ExportedAt.Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	response.Success(c, http.StatusOK, "user data exported", export)
+}
+
+// DeleteAccount soft-deletes the current user's account and anonymizes personal data
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	identityID := middleware.MustGetIdentityID(c)
+
+	if err := h.authService.DeleteAccount(c.Request.Context(), identityID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to delete account", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "account deleted", nil)
+}
@@ -2,11 +2,13 @@
 package campaign
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"bingwa-service/internal/domain/campaign"
-	//"bingwa-service/internal/middleware"
+	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
 	service "bingwa-service/internal/service/campaign"
 
@@ -155,6 +157,27 @@ func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "campaign stats retrieved", stats)
 }
 
+// GetVariantStats reports per-variant usage of a campaign's A/B test (admin only)
+func (h *CampaignHandler) GetVariantStats(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid campaign ID", err)
+		return
+	}
+
+	report, err := h.campaignService.GetVariantReport(c.Request.Context(), campaignID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "campaign not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to get variant stats", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "campaign variant stats retrieved", report)
+}
+
 // ========== Public/User Endpoints ==========
 
 // GetCampaign retrieves a campaign by ID
@@ -231,7 +254,9 @@ func (h *CampaignHandler) ValidateCampaign(c *gin.Context) {
 		return
 	}
 
-	result, err := h.campaignService.ValidateCampaign(c.Request.Context(), &req)
+	identityID := middleware.MustGetIdentityID(c)
+
+	result, err := h.campaignService.ValidateCampaign(c.Request.Context(), identityID, &req)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "failed to validate campaign", err)
 		return
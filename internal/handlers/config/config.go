@@ -2,12 +2,15 @@
 package config
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"bingwa-service/internal/domain/config"
 	"bingwa-service/internal/middleware"
+	"bingwa-service/internal/pkg/mask"
 	"bingwa-service/internal/pkg/response"
+	xerrors "bingwa-service/internal/pkg/errors"
 	service "bingwa-service/internal/service/config"
 
 	"github.com/gin-gonic/gin"
@@ -208,6 +211,29 @@ func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 	response.Success(c, http.StatusOK, "config deleted successfully", nil)
 }
 
+// GetConfigHistory retrieves the change history of a configuration
+func (h *ConfigHandler) GetConfigHistory(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	configIDStr := c.Param("id")
+	configID, err := strconv.ParseInt(configIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid config ID", err)
+		return
+	}
+
+	history, err := h.configService.GetConfigHistory(c.Request.Context(), agentID, configID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "config not found", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "config history retrieved", gin.H{
+		"history": history,
+		"count":   len(history),
+	})
+}
+
 // ========== Specific Config Type Endpoints ==========
 
 // GetNotificationConfig retrieves notification configuration
@@ -315,6 +341,24 @@ func (h *ConfigHandler) SetAndroidDeviceConfig(c *gin.Context) {
 	response.Success(c, http.StatusOK, "Android device config saved successfully", req)
 }
 
+// RecordDeviceHeartbeat marks an Android device as online
+func (h *ConfigHandler) RecordDeviceHeartbeat(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	deviceID := c.Param("device_id")
+	if deviceID == "" {
+		response.Error(c, http.StatusBadRequest, "device_id is required", nil)
+		return
+	}
+
+	if err := h.configService.RecordDeviceHeartbeat(c.Request.Context(), agentID, deviceID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to record device heartbeat", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "device heartbeat recorded", gin.H{"device_id": deviceID})
+}
+
 // GetBusinessConfig retrieves business configuration
 func (h *ConfigHandler) GetBusinessConfig(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -406,4 +450,107 @@ func (h *ConfigHandler) SetSecurityConfig(c *gin.Context) {
 	}
 
 	response.Success(c, http.StatusOK, "security config saved successfully", req)
-}
\ No newline at end of file
+}
+
+// GetWebhookConfig retrieves webhook delivery configuration
+func (h *ConfigHandler) GetWebhookConfig(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.configService.GetWebhookConfig(c.Request.Context(), agentID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get webhook config", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "webhook config retrieved", maskedWebhookConfig(result))
+}
+
+// SetWebhookConfig sets webhook delivery configuration
+func (h *ConfigHandler) SetWebhookConfig(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req config.WebhookConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	if err := h.configService.SetWebhookConfig(c.Request.Context(), agentID, &req); err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to set webhook config", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "webhook config saved successfully", maskedWebhookConfig(&req))
+}
+
+// maskedWebhookConfig copies a webhook config with its signing secret masked for responses
+func maskedWebhookConfig(cfg *config.WebhookConfig) *config.WebhookConfig {
+	masked := *cfg
+	masked.Secret = mask.Secret(cfg.Secret)
+	return &masked
+}
+
+// GetMpesaConfig retrieves M-Pesa callback configuration
+func (h *ConfigHandler) GetMpesaConfig(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.configService.GetMpesaConfig(c.Request.Context(), agentID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get mpesa config", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "mpesa config retrieved", result)
+}
+
+// SetMpesaConfig sets M-Pesa callback configuration
+func (h *ConfigHandler) SetMpesaConfig(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req config.MpesaConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	if err := h.configService.SetMpesaConfig(c.Request.Context(), agentID, &req); err != nil {
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "shortcode already registered", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to set mpesa config", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "mpesa config saved successfully", req)
+}
+
+// ========== Presets ==========
+
+// ListPresets lists the built-in config presets
+func (h *ConfigHandler) ListPresets(c *gin.Context) {
+	response.Success(c, http.StatusOK, "config presets retrieved", h.configService.ListPresets())
+}
+
+// ApplyPreset applies a built-in config preset
+func (h *ConfigHandler) ApplyPreset(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req config.ApplyPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.configService.ApplyPreset(c.Request.Context(), agentID, req.Preset, req.Overwrite)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "preset not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to apply preset", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "preset applied successfully", result)
+}
@@ -2,12 +2,16 @@
 package customer
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"bingwa-service/internal/domain/customer"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
 	service "bingwa-service/internal/service/customer"
 
@@ -219,23 +223,39 @@ func (h *CustomerHandler) DeactivateCustomer(c *gin.Context) {
 	response.Success(c, http.StatusOK, "customer deactivated successfully", nil)
 }
 
-// VerifyCustomer marks a customer as verified
-func (h *CustomerHandler) VerifyCustomer(c *gin.Context) {
+// SendVerificationCode texts a verification code straight to the customer's phone
+func (h *CustomerHandler) SendVerificationCode(c *gin.Context) {
 	agentID, err := h.getAgentID(c)
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
 		return
 	}
 
-	customerIDStr := c.Param("id")
-	customerID, err := strconv.ParseInt(customerIDStr, 10, 64)
+	customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "invalid customer ID", err)
 		return
 	}
 
-	if err := h.customerService.VerifyCustomer(c.Request.Context(), agentID, customerID); err != nil {
-		response.Error(c, http.StatusInternalServerError, "failed to verify customer", err)
+	result, err := h.customerService.SendVerificationCode(c.Request.Context(), agentID, customerID)
+	if err != nil {
+		h.verificationError(c, "failed to send verification code", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "verification code sent", result)
+}
+
+// VerifyCustomer is called by the customer with the code texted to their phone; it needs no agent session
+func (h *CustomerHandler) VerifyCustomer(c *gin.Context) {
+	var req customer.VerifyCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	if err := h.customerService.VerifyCustomerCode(c.Request.Context(), req.PhoneNumber, req.Code); err != nil {
+		h.verificationError(c, "failed to verify customer", err)
 		return
 	}
 
@@ -282,6 +302,36 @@ func (h *CustomerHandler) GetCustomerStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "customer stats retrieved", stats)
 }
 
+// GetInactiveCustomers lists customers with no recent activity
+func (h *CustomerHandler) GetInactiveCustomers(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			response.Error(c, http.StatusBadRequest, "invalid days", err)
+			return
+		}
+	}
+
+	customers, err := h.customerService.GetInactiveCustomers(c.Request.Context(), agentID, days)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get inactive customers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "inactive customers retrieved", gin.H{
+		"customers": customers,
+		"count":     len(customers),
+		"days":      days,
+	})
+}
+
 // AddTag adds a tag to a customer
 func (h *CustomerHandler) AddTag(c *gin.Context) {
 	agentID, err := h.getAgentID(c)
@@ -380,6 +430,76 @@ func (h *CustomerHandler) BulkImportCustomers(c *gin.Context) {
 	})
 }
 
+// maxCSVUploadBytes caps the size of an uploaded customer CSV
+const maxCSVUploadBytes = 2 << 20
+
+// ImportCustomersCSV imports customers from a multipart CSV upload (form field "file")
+func (h *CustomerHandler) ImportCustomersCSV(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "file is required", err)
+		return
+	}
+	if fileHeader.Size > maxCSVUploadBytes {
+		response.Error(c, http.StatusRequestEntityTooLarge, "file is too large", nil)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to read file", err)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.customerService.ImportCustomersCSV(c.Request.Context(), agentID, file)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusBadRequest, "invalid CSV file", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to import customers", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "CSV import completed", result)
+}
+
+// ExportCustomers streams the agent's customers as a CSV download
+func (h *CustomerHandler) ExportCustomers(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	csvReader, err := h.customerService.ExportCustomers(c.Request.Context(), agentID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to export customers", err)
+		return
+	}
+	if closer, ok := csvReader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	filename := fmt.Sprintf("customers-%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if _, err := io.Copy(c.Writer, csvReader); err != nil {
+		// Headers are already sent; abort the partial download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
 // SearchCustomers searches customers
 func (h *CustomerHandler) SearchCustomers(c *gin.Context) {
 	agentID, err := h.getAgentID(c)
@@ -408,6 +528,91 @@ func (h *CustomerHandler) SearchCustomers(c *gin.Context) {
 
 // ========== Helper Methods ==========
 
+// AddNote attaches a note to a customer
+func (h *CustomerHandler) AddNote(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	customerIDStr := c.Param("id")
+	customerID, err := strconv.ParseInt(customerIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid customer ID", err)
+		return
+	}
+
+	var req customer.AddNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	authorID := middleware.MustGetIdentityID(c)
+	note, err := h.customerService.AddNote(c.Request.Context(), agentID, authorID, customerID, req.Body)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to add note", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "note added successfully", note)
+}
+
+// ListNotes lists a customer's notes
+func (h *CustomerHandler) ListNotes(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	customerIDStr := c.Param("id")
+	customerID, err := strconv.ParseInt(customerIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid customer ID", err)
+		return
+	}
+
+	notes, err := h.customerService.ListNotes(c.Request.Context(), agentID, customerID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "customer not found", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "notes retrieved", notes)
+}
+
+// DeleteNote removes a note from a customer
+func (h *CustomerHandler) DeleteNote(c *gin.Context) {
+	agentID, err := h.getAgentID(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid agent ID", err)
+		return
+	}
+
+	customerIDStr := c.Param("id")
+	customerID, err := strconv.ParseInt(customerIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid customer ID", err)
+		return
+	}
+
+	noteIDStr := c.Param("note_id")
+	noteID, err := strconv.ParseInt(noteIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid note ID", err)
+		return
+	}
+
+	if err := h.customerService.DeleteNote(c.Request.Context(), agentID, customerID, noteID); err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to delete note", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "note deleted successfully", nil)
+}
+
 // getAgentID extracts agent ID from context or query params
 // For agents: uses authenticated identity_id
 // For admins: requires agent_id query parameter
@@ -437,4 +642,22 @@ func (h *CustomerHandler) getAgentID(c *gin.Context) (int64, error) {
 	}
 
 	return identityID, nil
-}
\ No newline at end of file
+}
+
+// verificationError maps customer verification errors to HTTP statuses
+func (h *CustomerHandler) verificationError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, xerrors.ErrNotFound):
+		response.Error(c, http.StatusNotFound, "customer not found", err)
+	case errors.Is(err, xerrors.ErrUnauthorized):
+		response.Error(c, http.StatusForbidden, "customer does not belong to agent", err)
+	case errors.Is(err, xerrors.ErrConflict):
+		response.Error(c, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, xerrors.ErrInvalidInput):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error(), err)
+	case errors.Is(err, xerrors.ErrRateLimited):
+		response.Error(c, http.StatusTooManyRequests, err.Error(), err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,65 @@
+// internal/handlers/health/health.go
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const defaultProbeTimeout = 2 * time.Second
+
+// Probe checks a single dependency and returns an error if it is unreachable
+type Probe func(ctx context.Context) error
+
+type HealthHandler struct {
+	probes  map[string]Probe
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func NewHealthHandler(probes map[string]Probe, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		probes:  probes,
+		timeout: defaultProbeTimeout,
+		logger:  logger,
+	}
+}
+
+// Liveness reports that the process is up without touching dependencies
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "version": "1.0.0"})
+}
+
+// Readiness pings every dependency and returns 503 naming the ones that failed
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	checks := make(map[string]string, len(h.probes))
+	failed := make([]string, 0)
+
+	for name, probe := range h.probes {
+		if err := probe(ctx); err != nil {
+			h.logger.Warn("readiness probe failed", zap.String("dependency", name), zap.Error(err))
+			checks[name] = err.Error()
+			failed = append(failed, name)
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	if len(failed) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"failed": failed,
+			"checks": checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
@@ -0,0 +1,61 @@
+// internal/handlers/health/health_test.go
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		postgres   Probe
+		wantStatus int
+		wantFailed []string
+	}{
+		{"all dependencies up", ok, http.StatusOK, nil},
+		{"database ping fails", func(ctx context.Context) error { return errors.New("connection refused") }, http.StatusServiceUnavailable, []string{"postgres"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(map[string]Probe{"postgres": tt.postgres, "redis": ok}, zap.NewNop())
+			r := gin.New()
+			r.GET("/ready", h.Readiness)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var body struct {
+				Failed []string          `json:"failed"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if !reflect.DeepEqual(body.Failed, tt.wantFailed) {
+				t.Errorf("failed = %v, want %v", body.Failed, tt.wantFailed)
+			}
+			if body.Checks["redis"] != "ok" {
+				t.Errorf("redis check = %q, want ok", body.Checks["redis"])
+			}
+			if tt.wantFailed != nil && body.Checks["postgres"] != "connection refused" {
+				t.Errorf("postgres check = %q, want the ping error", body.Checks["postgres"])
+			}
+		})
+	}
+}
@@ -189,13 +189,14 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 	response.Success(c, http.StatusCreated, "notification created", result)
 }
 
-// BroadcastNotification broadcasts a notification to all users (admin only)
+// BroadcastNotification broadcasts a notification to all users, or only those matching target (admin only)
 func (h *NotificationHandler) BroadcastNotification(c *gin.Context) {
 	var req struct {
 		Title    string                        `json:"title" binding:"required"`
 		Message  string                        `json:"message" binding:"required"`
 		Type     notification.NotificationType `json:"type"`
 		Metadata map[string]interface{}        `json:"metadata"`
+		Target   *notification.BroadcastTarget `json:"target"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -203,6 +204,26 @@ func (h *NotificationHandler) BroadcastNotification(c *gin.Context) {
 		return
 	}
 
+	if !req.Target.IsEmpty() {
+		recipients, err := h.notificationService.BroadcastTargetedNotification(
+			c.Request.Context(),
+			req.Target,
+			req.Title,
+			req.Message,
+			req.Type,
+			req.Metadata,
+		)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "failed to broadcast notification", err)
+			return
+		}
+
+		response.Success(c, http.StatusOK, "notification broadcasted", gin.H{
+			"recipients": recipients,
+		})
+		return
+	}
+
 	if err := h.notificationService.BroadcastSystemNotification(
 		c.Request.Context(),
 		req.Title,
@@ -2,8 +2,11 @@
 package offer
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"bingwa-service/internal/domain/offer"
 	"bingwa-service/internal/middleware"
@@ -38,6 +41,10 @@ func (h *OfferHandler) CreateOffer(c *gin.Context) {
 
 	result, err := h.offerService.CreateOffer(c.Request.Context(), agentID, &req)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrForbidden) {
+			response.Error(c, http.StatusForbidden, "offer limit reached", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to create offer", err)
 		return
 	}
@@ -45,6 +52,21 @@ func (h *OfferHandler) CreateOffer(c *gin.Context) {
 	response.Success(c, http.StatusCreated, "offer created successfully", result)
 }
 
+// ImportOffers creates offers under caller-supplied offer codes, reporting the outcome per row
+func (h *OfferHandler) ImportOffers(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.ImportOffersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result := h.offerService.ImportOffers(c.Request.Context(), agentID, req.Offers)
+
+	response.Success(c, http.StatusOK, "offer import processed", result)
+}
+
 // GetOffer retrieves an offer by ID
 func (h *OfferHandler) GetOffer(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -94,6 +116,17 @@ func (h *OfferHandler) ListOffers(c *gin.Context) {
 		return
 	}
 
+	// ?fields=summary returns the lightweight projection
+	if filters.Fields == offer.OfferProjectionSummary {
+		result, err := h.offerService.ListOfferSummaries(c.Request.Context(), agentID, &filters)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "failed to list offers", err)
+			return
+		}
+		response.Success(c, http.StatusOK, "offers retrieved", result)
+		return
+	}
+
 	result, err := h.offerService.ListOffers(c.Request.Context(), agentID, &filters)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "failed to list offers", err)
@@ -103,6 +136,25 @@ func (h *OfferHandler) ListOffers(c *gin.Context) {
 	response.Success(c, http.StatusOK, "offers retrieved", result)
 }
 
+// GetSearchFacets retrieves offer counts per type, status and price bucket for the current filters
+func (h *OfferHandler) GetSearchFacets(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var filters offer.OfferFacetFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	facets, err := h.offerService.GetSearchFacets(c.Request.Context(), agentID, filters.ListFilters())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get search facets", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "search facets retrieved", facets)
+}
+
 // GetFeaturedOffers retrieves featured offers
 func (h *OfferHandler) GetFeaturedOffers(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -125,6 +177,83 @@ func (h *OfferHandler) GetFeaturedOffers(c *gin.Context) {
 	})
 }
 
+// GetAvailableAt lists offers that will be available at a given time (?at=RFC3339)
+func (h *OfferHandler) GetAvailableAt(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	atStr := c.Query("at")
+	if atStr == "" {
+		response.Error(c, http.StatusBadRequest, "at is required", nil)
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, atStr)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid at, expected RFC3339", err)
+		return
+	}
+
+	offers, err := h.offerService.GetAvailableAt(c.Request.Context(), agentID, at)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get available offers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "available offers retrieved", gin.H{
+		"at":     at,
+		"offers": offers,
+		"count":  len(offers),
+	})
+}
+
+// GetTopOffers retrieves the agent's most redeemed offers
+func (h *OfferHandler) GetTopOffers(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offers, err := h.offerService.GetTopOffers(c.Request.Context(), agentID, limit)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get top offers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "top offers retrieved", gin.H{
+		"offers": offers,
+		"count":  len(offers),
+	})
+}
+
+// RecommendForCustomer recommends offers for a customer by phone
+func (h *OfferHandler) RecommendForCustomer(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	phone := c.Query("phone")
+	if phone == "" {
+		response.Error(c, http.StatusBadRequest, "phone is required", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	offers, err := h.offerService.RecommendForCustomer(c.Request.Context(), agentID, phone, limit)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get recommendations", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "recommendations retrieved", gin.H{
+		"offers": offers,
+		"count":  len(offers),
+	})
+}
+
 // UpdateOffer updates an offer
 func (h *OfferHandler) UpdateOffer(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -163,6 +292,10 @@ func (h *OfferHandler) ActivateOffer(c *gin.Context) {
 	}
 
 	if err := h.offerService.ActivateOffer(c.Request.Context(), agentID, offerID); err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusUnprocessableEntity, "offer cannot be activated", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "failed to activate offer", err)
 		return
 	}
@@ -227,12 +360,22 @@ func (h *OfferHandler) DeleteOffer(c *gin.Context) {
 	response.Success(c, http.StatusOK, "offer deleted successfully", nil)
 }
 
-// GetOfferStats retrieves offer statistics
+// GetOfferStats retrieves offer statistics (?from=&to=, RFC3339; lifetime when omitted)
 func (h *OfferHandler) GetOfferStats(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
 
-	stats, err := h.offerService.GetOfferStats(c.Request.Context(), agentID)
+	var filters offer.StatsFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	stats, err := h.offerService.GetOfferStats(c.Request.Context(), agentID, &filters)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "failed to get offer stats", err)
 		return
 	}
@@ -240,6 +383,23 @@ func (h *OfferHandler) GetOfferStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "offer stats retrieved", stats)
 }
 
+// GetStatsComparison compares offer sales with the previous period (?period=30d)
+func (h *OfferHandler) GetStatsComparison(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.offerService.GetStatsComparison(c.Request.Context(), agentID, c.DefaultQuery("period", "30d"))
+	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to get offer stats comparison", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer stats comparison retrieved", result)
+}
+
 // SearchOffers searches offers
 func (h *OfferHandler) SearchOffers(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -344,10 +504,11 @@ func (h *OfferHandler) CalculateOfferPrice(c *gin.Context) {
 	}
 
 	// Calculate discounted price
-	discountedPrice := h.offerService.CalculateDiscountedPrice(o)
+	discountedPrice, strategy := h.offerService.QuoteDiscountedPrice(o)
 
 	response.Success(c, http.StatusOK, "price calculated", gin.H{
 		"original_price":    o.Price,
+		"pricing_strategy":  strategy,
 		"discount_percent":  o.DiscountPercentage,
 		"discounted_price":  discountedPrice,
 		"savings":           o.Price - discountedPrice,
@@ -374,7 +535,7 @@ func (h *OfferHandler) CheckOfferAvailability(c *gin.Context) {
 	}
 
 	// Check availability
-	isAvailable := h.offerService.IsOfferAvailable(o)
+	isAvailable := h.offerService.IsOfferAvailable(c.Request.Context(), o)
 
 	response.Success(c, http.StatusOK, "availability checked", gin.H{
 		"offer_id":     offerID,
@@ -386,6 +547,125 @@ func (h *OfferHandler) CheckOfferAvailability(c *gin.Context) {
 	})
 }
 
+// CheckAvailabilityBatch checks availability for multiple offers at once
+func (h *OfferHandler) CheckAvailabilityBatch(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.CheckAvailabilityBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	results, err := h.offerService.CheckAvailabilityBatch(c.Request.Context(), agentID, req.OfferIDs)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to check availability", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "availability checked", results)
+}
+
+// BulkDeleteOffers soft deletes many offers, reporting skipped IDs
+func (h *OfferHandler) BulkDeleteOffers(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.BulkDelete(c.Request.Context(), agentID, req.OfferIDs)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to delete offers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offers deleted", result)
+}
+
+// BulkAddTag adds a tag to several offers at once
+func (h *OfferHandler) BulkAddTag(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.BulkAddTag(c.Request.Context(), agentID, req.OfferIDs, req.Tag)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to tag offers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offers tagged", result)
+}
+
+// BulkRemoveTag removes a tag from several offers at once
+func (h *OfferHandler) BulkRemoveTag(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.BulkRemoveTag(c.Request.Context(), agentID, req.OfferIDs, req.Tag)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to untag offers", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offers untagged", result)
+}
+
+// BulkAdjustPrice applies a percentage or fixed price change to all offers matching a filter
+func (h *OfferHandler) BulkAdjustPrice(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.BulkAdjustPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.BulkAdjustPrice(c.Request.Context(), agentID, &req.Filter, req.Adjustment)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusUnprocessableEntity, "price adjustment rejected", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to adjust prices", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer prices adjusted", result)
+}
+
+// GetPriceHistory retrieves an offer's recorded price changes
+func (h *OfferHandler) GetPriceHistory(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	offerIDStr := c.Param("id")
+	offerID, err := strconv.ParseInt(offerIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid offer ID", err)
+		return
+	}
+
+	history, err := h.offerService.GetPriceHistory(c.Request.Context(), agentID, offerID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "offer not found", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "price history retrieved", history)
+}
+
 // GetOffersByAmount retrieves offers by amount
 func (h *OfferHandler) GetOffersByAmount(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -529,4 +809,84 @@ func (h *OfferHandler) GetOfferByPriceAndType(c *gin.Context) {
 	}
 
 	response.Success(c, http.StatusOK, "offer retrieved", offer)
-}
\ No newline at end of file
+}
+
+// GenerateQRBatch streams a ZIP of QR code PNGs for the requested offers
+func (h *OfferHandler) GenerateQRBatch(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.QRBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	images, err := h.offerService.GenerateQRBatch(c.Request.Context(), agentID, req.OfferIDs)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to generate QR codes", err)
+		return
+	}
+
+	filename := fmt.Sprintf("offer-qr-codes-%s.zip", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if err := service.WriteQRZip(c.Writer, images); err != nil {
+		// Headers are already sent; abort the partial download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// RenameTag renames a tag across the agent's offers and customers
+func (h *OfferHandler) RenameTag(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.RenameTag(c.Request.Context(), agentID, req.OldTag, req.NewTag)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to rename tag", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "tag renamed successfully", result)
+}
+
+// ========== Admin Endpoints ==========
+
+// AdminTransferOffer transfers an offer to another agent (admin only)
+func (h *OfferHandler) AdminTransferOffer(c *gin.Context) {
+	offerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid offer ID", err)
+		return
+	}
+
+	var req offer.TransferOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.AdminTransferOffer(c.Request.Context(), offerID, req.ToAgentID)
+	if err != nil {
+		if errors.Is(err, service.ErrOfferNotFound) {
+			response.Error(c, http.StatusNotFound, "offer not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrAgentNotFound) {
+			response.Error(c, http.StatusNotFound, "agent not found", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to transfer offer", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer transferred successfully", result)
+}
@@ -0,0 +1,51 @@
+// internal/handlers/offer/stock.go
+package offer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplenishStock adds units to a stock-limited offer
+func (h *OfferHandler) ReplenishStock(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	offerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid offer ID", err)
+		return
+	}
+
+	var req offer.ReplenishStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.ReplenishStock(c.Request.Context(), agentID, offerID, req.Amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, xerrors.ErrNotFound):
+			response.Error(c, http.StatusNotFound, "offer not found", err)
+		case errors.Is(err, xerrors.ErrUnauthorized):
+			response.Error(c, http.StatusForbidden, "offer does not belong to agent", err)
+		case errors.Is(err, xerrors.ErrInvalidInput):
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, xerrors.ErrConflict):
+			response.Error(c, http.StatusConflict, err.Error(), err)
+		default:
+			response.Error(c, http.StatusInternalServerError, "failed to replenish stock", err)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer stock replenished", result)
+}
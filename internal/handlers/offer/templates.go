@@ -0,0 +1,128 @@
+// internal/handlers/offer/templates.go
+package offer
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTemplates lists the active offer templates agents can start from
+func (h *OfferHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.offerService.ListTemplates(c.Request.Context(), true)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list offer templates", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer templates retrieved", templates)
+}
+
+// CreateFromTemplate creates an offer from a library template
+func (h *OfferHandler) CreateFromTemplate(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.CreateFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.CreateFromTemplate(c.Request.Context(), agentID, req.TemplateID, &req.Overrides)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "offer template not found", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrForbidden) {
+			response.Error(c, http.StatusForbidden, "offer limit reached", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to create offer", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "offer created from template", result)
+}
+
+// AdminListTemplates lists all offer templates, including inactive ones (admin)
+func (h *OfferHandler) AdminListTemplates(c *gin.Context) {
+	templates, err := h.offerService.ListTemplates(c.Request.Context(), false)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list offer templates", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer templates retrieved", templates)
+}
+
+// AdminCreateTemplate adds an offer template to the library (admin)
+func (h *OfferHandler) AdminCreateTemplate(c *gin.Context) {
+	var req offer.CreateOfferTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.CreateTemplate(c.Request.Context(), &req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to create offer template", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "offer template created", result)
+}
+
+// AdminUpdateTemplate updates an offer template (admin)
+func (h *OfferHandler) AdminUpdateTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid template ID", err)
+		return
+	}
+
+	var req offer.UpdateOfferTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.UpdateTemplate(c.Request.Context(), templateID, &req)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "offer template not found", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to update offer template", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer template updated", result)
+}
+
+// AdminDeleteTemplate removes an offer template (admin)
+func (h *OfferHandler) AdminDeleteTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid template ID", err)
+		return
+	}
+
+	if err := h.offerService.DeleteTemplate(c.Request.Context(), templateID); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "offer template not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to delete offer template", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer template deleted", nil)
+}
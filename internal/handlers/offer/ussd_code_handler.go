@@ -2,12 +2,14 @@
 package offer
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"bingwa-service/internal/domain/offer"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -256,6 +258,34 @@ func (h *OfferHandler) ToggleUSSDCodeStatus(c *gin.Context) {
 	response.Success(c, http.StatusOK, fmt.Sprintf("USSD code %s successfully", status), nil)
 }
 
+// BulkToggleUSSDStatus activates or deactivates several USSD codes, refusing to leave an offer without an active code
+func (h *OfferHandler) BulkToggleUSSDStatus(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req offer.BulkToggleUSSDStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.offerService.BulkToggleUSSDStatus(c.Request.Context(), agentID, req.USSDCodeIDs, *req.IsActive)
+	if err != nil {
+		switch {
+		case errors.Is(err, xerrors.ErrNotFound):
+			response.Error(c, http.StatusNotFound, "USSD code not found", err)
+		case errors.Is(err, xerrors.ErrUnauthorized):
+			response.Error(c, http.StatusForbidden, "USSD code does not belong to agent", err)
+		case errors.Is(err, xerrors.ErrConflict):
+			response.Error(c, http.StatusConflict, "would leave an offer without an active USSD code", err)
+		default:
+			response.Error(c, http.StatusInternalServerError, "failed to toggle USSD codes", err)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "USSD codes updated", result)
+}
+
 // DeleteUSSDCode deletes a USSD code
 func (h *OfferHandler) DeleteUSSDCode(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -307,6 +337,39 @@ func (h *OfferHandler) RecordUSSDResult(c *gin.Context) {
 	response.Success(c, http.StatusOK, "USSD result recorded successfully", nil)
 }
 
+// TestUSSDCode records a test execution of a USSD code without affecting its stats
+func (h *OfferHandler) TestUSSDCode(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	offerIDStr := c.Param("id")
+	offerID, err := strconv.ParseInt(offerIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid offer ID", err)
+		return
+	}
+
+	ussdCodeIDStr := c.Param("ussd_code_id")
+	ussdCodeID, err := strconv.ParseInt(ussdCodeIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid USSD code ID", err)
+		return
+	}
+
+	var req offer.TestUSSDCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	run, err := h.offerService.TestUSSDCode(c.Request.Context(), agentID, offerID, ussdCodeID, req.TestPhone, req.RawResponse)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to test USSD code", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "USSD code test recorded", run)
+}
+
 // GetUSSDCodeStats gets statistics for USSD codes
 func (h *OfferHandler) GetUSSDCodeStats(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
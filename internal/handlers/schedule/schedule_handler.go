@@ -2,11 +2,13 @@
 package schedule
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"bingwa-service/internal/domain/schedule"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
 	service "bingwa-service/internal/service/schedule"
 
@@ -37,6 +39,10 @@ func (h *ScheduleHandler) CreateScheduledOffer(c *gin.Context) {
 
 	result, err := h.scheduleService.CreateScheduledOffer(c.Request.Context(), agentID, &req)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "conflicting scheduled offer exists", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to create scheduled offer", err)
 		return
 	}
@@ -163,6 +169,26 @@ func (h *ScheduleHandler) ResumeScheduledOffer(c *gin.Context) {
 	response.Success(c, http.StatusOK, "scheduled offer resumed successfully", nil)
 }
 
+// SkipNextRenewal skips the upcoming renewal without cancelling the schedule
+func (h *ScheduleHandler) SkipNextRenewal(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	scheduleIDStr := c.Param("id")
+	scheduleID, err := strconv.ParseInt(scheduleIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid schedule ID", err)
+		return
+	}
+
+	result, err := h.scheduleService.SkipNextRenewal(c.Request.Context(), agentID, scheduleID)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to skip renewal", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "next renewal skipped successfully", result)
+}
+
 // CancelScheduledOffer cancels a scheduled offer
 func (h *ScheduleHandler) CancelScheduledOffer(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -209,6 +235,10 @@ func (h *ScheduleHandler) ExecuteScheduledOffer(c *gin.Context) {
 
 	redemption, history, err := h.scheduleService.ExecuteScheduledOffer(c.Request.Context(), agentID, scheduleID, &req)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "scheduled offer is not due or is already being executed", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to execute scheduled offer", err)
 		return
 	}
@@ -367,6 +397,25 @@ func (h *ScheduleHandler) BatchExecuteSchedules(c *gin.Context) {
 	})
 }
 
+// ProcessDueSchedules claims and executes all due schedules concurrently
+func (h *ScheduleHandler) ProcessDueSchedules(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	concurrencyStr := c.DefaultQuery("max_concurrency", "5")
+	maxConcurrency, err := strconv.Atoi(concurrencyStr)
+	if err != nil || maxConcurrency < 1 || maxConcurrency > 20 {
+		maxConcurrency = 5
+	}
+
+	result, err := h.scheduleService.ProcessDueSchedules(c.Request.Context(), agentID, maxConcurrency)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to process due schedules", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "due schedules processed", result)
+}
+
 // GetSchedulesByStatus retrieves schedules grouped by status
 func (h *ScheduleHandler) GetSchedulesByStatus(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -2,11 +2,13 @@
 package subscription
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"bingwa-service/internal/domain/subscription"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
 	service "bingwa-service/internal/service/subscription"
 
@@ -56,6 +58,10 @@ func (h *AgentSubscriptionHandler) RenewSubscription(c *gin.Context) {
 
 	result, err := h.subscriptionService.RenewSubscription(c.Request.Context(), agentID, &req)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "subscription was already renewed", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to renew subscription", err)
 		return
 	}
@@ -63,6 +69,65 @@ func (h *AgentSubscriptionHandler) RenewSubscription(c *gin.Context) {
 	response.Success(c, http.StatusOK, "subscription renewed successfully", result)
 }
 
+// ChangePlan switches the active subscription to another plan
+func (h *AgentSubscriptionHandler) ChangePlan(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req subscription.ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.subscriptionService.ChangePlan(c.Request.Context(), agentID, &req)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrForbidden) {
+			response.Error(c, http.StatusForbidden, "plan change not allowed", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to change plan", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "subscription plan changed successfully", result)
+}
+
+// RecommendPlan recommends a plan based on the agent's recent usage
+func (h *AgentSubscriptionHandler) RecommendPlan(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.subscriptionService.RecommendPlan(c.Request.Context(), agentID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to recommend plan", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "plan recommendation retrieved", result)
+}
+
+// PreviewPlanChange prices a plan change without applying it
+func (h *AgentSubscriptionHandler) PreviewPlanChange(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	planID, err := strconv.ParseInt(c.Query("plan_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid plan ID", err)
+		return
+	}
+
+	result, err := h.subscriptionService.PreviewPlanChange(c.Request.Context(), agentID, planID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrForbidden) {
+			response.Error(c, http.StatusForbidden, "plan change not allowed", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to preview plan change", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "plan change preview retrieved", result)
+}
+
 // GetSubscription retrieves a subscription by ID
 func (h *AgentSubscriptionHandler) GetSubscription(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -83,6 +148,152 @@ func (h *AgentSubscriptionHandler) GetSubscription(c *gin.Context) {
 	response.Success(c, http.StatusOK, "subscription retrieved", result)
 }
 
+// GetBillingHistory lists the charges made against a subscription
+func (h *AgentSubscriptionHandler) GetBillingHistory(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid subscription ID", err)
+		return
+	}
+
+	result, err := h.subscriptionService.GetBillingHistory(c.Request.Context(), agentID, subscriptionID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "subscription not found", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "billing history retrieved", result)
+}
+
+// ListSubAgents lists the agents sharing the caller's subscription
+func (h *AgentSubscriptionHandler) ListSubAgents(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	subAgents, err := h.subscriptionService.ListSubAgents(c.Request.Context(), agentID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list sub-agents", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sub-agents retrieved", gin.H{
+		"sub_agents": subAgents,
+		"count":      len(subAgents),
+	})
+}
+
+// AddSubAgent invites a child agent to share the caller's subscription; it takes effect once accepted
+func (h *AgentSubscriptionHandler) AddSubAgent(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req subscription.AddSubAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.subscriptionService.AddSubAgent(c.Request.Context(), agentID, req.ChildAgentID)
+	if err != nil {
+		subAgentError(c, "failed to add sub-agent", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "sub-agent invited", result)
+}
+
+// GetParentLink retrieves the caller's link to a parent agent's subscription, pending or active
+func (h *AgentSubscriptionHandler) GetParentLink(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.subscriptionService.GetParentLink(c.Request.Context(), agentID)
+	if err != nil {
+		subAgentError(c, "failed to get parent link", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "parent link retrieved", result)
+}
+
+// AcceptSubAgentInvite accepts the caller's pending invite to share a parent agent's subscription
+func (h *AgentSubscriptionHandler) AcceptSubAgentInvite(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	result, err := h.subscriptionService.AcceptSubAgentInvite(c.Request.Context(), agentID)
+	if err != nil {
+		subAgentError(c, "failed to accept invite", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sub-agent invite accepted", result)
+}
+
+// LeaveParent declines the caller's invite or stops it sharing a parent agent's subscription
+func (h *AgentSubscriptionHandler) LeaveParent(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	if err := h.subscriptionService.LeaveParent(c.Request.Context(), agentID); err != nil {
+		subAgentError(c, "failed to leave parent", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "left parent subscription", nil)
+}
+
+// AdminLinkSubAgent links a child agent to a parent's subscription without the child's acceptance (admin only)
+func (h *AgentSubscriptionHandler) AdminLinkSubAgent(c *gin.Context) {
+	adminID := middleware.MustGetIdentityID(c)
+
+	var req subscription.AdminLinkSubAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.subscriptionService.AdminLinkSubAgent(c.Request.Context(), req.ParentAgentID, req.ChildAgentID, adminID)
+	if err != nil {
+		subAgentError(c, "failed to link sub-agent", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "sub-agent linked", result)
+}
+
+func subAgentError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, xerrors.ErrConflict):
+		response.Error(c, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, xerrors.ErrNotFound):
+		response.Error(c, http.StatusNotFound, "not found", err)
+	case errors.Is(err, xerrors.ErrInvalidInput):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error(), err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// RemoveSubAgent stops sharing the caller's subscription with a child agent
+func (h *AgentSubscriptionHandler) RemoveSubAgent(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	childID, err := strconv.ParseInt(c.Param("child_id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid child agent ID", err)
+		return
+	}
+
+	if err := h.subscriptionService.RemoveSubAgent(c.Request.Context(), agentID, childID); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "sub-agent not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to remove sub-agent", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sub-agent removed", nil)
+}
+
 // GetActiveSubscription retrieves the active subscription for the agent
 func (h *AgentSubscriptionHandler) GetActiveSubscription(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -207,6 +418,25 @@ func (h *AgentSubscriptionHandler) GetSubscriptionStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "subscription statistics retrieved", stats)
 }
 
+// GetCancellationReasons retrieves cancellation reason frequencies
+func (h *AgentSubscriptionHandler) GetCancellationReasons(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var filters subscription.CancellationReasonFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	result, err := h.subscriptionService.GetCancellationReasons(c.Request.Context(), agentID, &filters)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get cancellation reasons", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "cancellation reasons retrieved", result)
+}
+
 // ========== Admin Endpoints ==========
 
 // AdminGetSubscription retrieves any subscription by ID (admin only)
@@ -279,6 +509,58 @@ func (h *AgentSubscriptionHandler) AdminSuspendSubscription(c *gin.Context) {
 	response.Success(c, http.StatusOK, "subscription suspended successfully", nil)
 }
 
+// AdminSetCustomLimit overrides a subscription's request limit (admin only)
+func (h *AgentSubscriptionHandler) AdminSetCustomLimit(c *gin.Context) {
+	adminID := middleware.MustGetIdentityID(c)
+
+	subscriptionIDStr := c.Param("id")
+	subscriptionID, err := strconv.ParseInt(subscriptionIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid subscription ID", err)
+		return
+	}
+
+	var req subscription.SetCustomLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.subscriptionService.SetCustomLimit(c.Request.Context(), adminID, subscriptionID, req.RequestsLimit)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to set custom limit", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "custom limit updated successfully", result)
+}
+
+// AdminSetLimitBehavior overrides what happens when a subscription reaches its request limit (admin only)
+func (h *AgentSubscriptionHandler) AdminSetLimitBehavior(c *gin.Context) {
+	adminID := middleware.MustGetIdentityID(c)
+
+	subscriptionIDStr := c.Param("id")
+	subscriptionID, err := strconv.ParseInt(subscriptionIDStr, 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid subscription ID", err)
+		return
+	}
+
+	var req subscription.SetLimitBehaviorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.subscriptionService.SetLimitBehavior(c.Request.Context(), adminID, subscriptionID, req.LimitBehavior)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to set limit behavior", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "limit behavior updated successfully", result)
+}
+
 // AdminReactivateSubscription reactivates a subscription (admin only)
 func (h *AgentSubscriptionHandler) AdminReactivateSubscription(c *gin.Context) {
 	subscriptionIDStr := c.Param("id")
@@ -357,6 +639,23 @@ func (h *AgentSubscriptionHandler) AdminGetSubscriptionStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "subscription statistics retrieved", stats)
 }
 
+// AdminGetCancellationReasons retrieves cancellation reason frequencies across all agents (admin only)
+func (h *AgentSubscriptionHandler) AdminGetCancellationReasons(c *gin.Context) {
+	var filters subscription.CancellationReasonFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	result, err := h.subscriptionService.AdminGetCancellationReasons(c.Request.Context(), &filters)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get cancellation reasons", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "cancellation reasons retrieved", result)
+}
+
 // GetSubscriptionsByStatus retrieves subscriptions grouped by status
 func (h *AgentSubscriptionHandler) GetSubscriptionsByStatus(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
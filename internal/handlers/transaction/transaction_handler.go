@@ -2,13 +2,17 @@
 package transaction
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"bingwa-service/internal/domain/transaction"
 	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/response"
+	"bingwa-service/internal/pkg/webhook"
 	service "bingwa-service/internal/service/transaction"
 
 	"github.com/gin-gonic/gin"
@@ -43,6 +47,22 @@ func (h *TransactionHandler) CreateOfferRequest(c *gin.Context) {
 			response.Error(c, http.StatusPaymentRequired, "subscription required", err)
 			return
 		}
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "offer is sold out", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrDuplicateEntry) {
+			response.Error(c, http.StatusConflict, "mpesa receipt already recorded", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrRateLimited) {
+			response.Error(c, http.StatusTooManyRequests, "purchase limit reached", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusUnprocessableEntity, "invalid offer request", err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to create offer request", err)
 		return
 	}
@@ -73,6 +93,48 @@ func (h *TransactionHandler) GetOfferRequest(c *gin.Context) {
 	response.Success(c, http.StatusOK, "offer request retrieved", result)
 }
 
+// GetTransactionAudit retrieves the captured request input and status history for disputes
+func (h *TransactionHandler) GetTransactionAudit(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request ID", err)
+		return
+	}
+
+	result, err := h.transactionService.GetTransactionAudit(c.Request.Context(), agentID, requestID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, "offer request not found", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "transaction audit retrieved", result)
+}
+
+// FindByMpesaReceipt retrieves a request and its redemption by M-Pesa receipt
+func (h *TransactionHandler) FindByMpesaReceipt(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	receipt := c.Query("receipt")
+	if receipt == "" {
+		response.Error(c, http.StatusBadRequest, "receipt is required", nil)
+		return
+	}
+
+	result, err := h.transactionService.FindByMpesaReceipt(c.Request.Context(), agentID, receipt)
+	if err != nil {
+		if err == xerrors.ErrNotFound {
+			response.Error(c, http.StatusNotFound, "no request found for this receipt", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to find request", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer request retrieved", result)
+}
+
 // ListOfferRequests retrieves offer requests with filters
 func (h *TransactionHandler) ListOfferRequests(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -247,6 +309,10 @@ func (h *TransactionHandler) RetryFailedRequest(c *gin.Context) {
 	}
 
 	if err := h.transactionService.RetryFailedRequest(c.Request.Context(), agentID, requestID); err != nil {
+		if errors.Is(err, xerrors.ErrRateLimited) {
+			response.Error(c, http.StatusTooManyRequests, err.Error(), err)
+			return
+		}
 		response.Error(c, http.StatusBadRequest, "failed to retry request", err)
 		return
 	}
@@ -254,6 +320,141 @@ func (h *TransactionHandler) RetryFailedRequest(c *gin.Context) {
 	response.Success(c, http.StatusOK, "offer request queued for retry", nil)
 }
 
+// GetHeldRequests lists pending requests held for fraud review
+func (h *TransactionHandler) GetHeldRequests(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.transactionService.GetHeldRequests(c.Request.Context(), agentID, page, pageSize)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get held requests", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "held requests retrieved", result)
+}
+
+// ReviewHeldRequest approves a held request for dispatch or rejects it
+func (h *TransactionHandler) ReviewHeldRequest(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request ID", err)
+		return
+	}
+
+	var req transaction.ReviewHeldRequestInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	if err := h.transactionService.ReviewHeldRequest(c.Request.Context(), agentID, requestID, req.Approve); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "offer request not found", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, "offer request is not held for review", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to review offer request", err)
+		return
+	}
+
+	message := "offer request rejected"
+	if req.Approve {
+		message = "offer request approved"
+	}
+	response.Success(c, http.StatusOK, message, nil)
+}
+
+// ConfirmOfferRequest confirms a request for a confirmation-required offer so it can be dispatched
+func (h *TransactionHandler) ConfirmOfferRequest(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request ID", err)
+		return
+	}
+
+	request, err := h.transactionService.ConfirmOfferRequest(c.Request.Context(), agentID, requestID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "offer request not found", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, err.Error(), err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to confirm offer request", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "offer request confirmed", request)
+}
+
+// AdminRetryRedemption force-retries any agent's stuck or failed redemption (admin)
+func (h *TransactionHandler) AdminRetryRedemption(c *gin.Context) {
+	adminID := middleware.MustGetIdentityID(c)
+
+	redemptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid redemption ID", err)
+		return
+	}
+
+	result, err := h.transactionService.AdminRetryRedemption(c.Request.Context(), adminID, redemptionID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "redemption not found", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, err.Error(), err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to retry redemption", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "redemption queued for retry", result)
+}
+
+// IngestMpesaCallback records a signed M-Pesa STK or C2B callback (no agent auth; verified by signature)
+func (h *TransactionHandler) IngestMpesaCallback(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.transactionService.IngestMpesaCallback(c.Request.Context(), payload, c.GetHeader(webhook.SignatureHeader))
+	if err != nil {
+		if errors.Is(err, xerrors.ErrUnauthorized) {
+			response.Error(c, http.StatusUnauthorized, "callback rejected", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusBadRequest, "invalid mpesa callback", err)
+			return
+		}
+		if errors.Is(err, xerrors.ErrConflict) {
+			response.Error(c, http.StatusConflict, err.Error(), err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to ingest mpesa callback", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "mpesa callback accepted", result)
+}
+
 // ========== Redemption Endpoints ==========
 
 // GetOfferRedemption retrieves a redemption by ID
@@ -297,12 +498,22 @@ func (h *TransactionHandler) ListOfferRedemptions(c *gin.Context) {
 
 // ========== Statistics ==========
 
-// GetTransactionStats retrieves transaction statistics
+// GetTransactionStats retrieves transaction statistics (?from=&to=, RFC3339; lifetime when omitted)
 func (h *TransactionHandler) GetTransactionStats(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
 
-	stats, err := h.transactionService.GetTransactionStats(c.Request.Context(), agentID)
+	var filters transaction.StatsFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	stats, err := h.transactionService.GetTransactionStats(c.Request.Context(), agentID, &filters)
 	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "failed to get statistics", err)
 		return
 	}
@@ -310,6 +521,62 @@ func (h *TransactionHandler) GetTransactionStats(c *gin.Context) {
 	response.Success(c, http.StatusOK, "transaction statistics retrieved", stats)
 }
 
+// GetSalesHeatmap retrieves binned sale locations for a map view
+func (h *TransactionHandler) GetSalesHeatmap(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var bounds transaction.SalesHeatmapBounds
+	if err := c.ShouldBindQuery(&bounds); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	result, err := h.transactionService.GetSalesHeatmap(c.Request.Context(), agentID, &bounds)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "failed to get sales heatmap", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sales heatmap retrieved", result)
+}
+
+// GetOfferSalesTimeSeries retrieves a bucketed sales series for one offer
+func (h *TransactionHandler) GetOfferSalesTimeSeries(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	offerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid offer ID", err)
+		return
+	}
+
+	var filters transaction.SalesSeriesFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+
+	var from, to time.Time
+	if filters.From != nil {
+		from = *filters.From
+	}
+	if filters.To != nil {
+		to = *filters.To
+	}
+
+	result, err := h.transactionService.GetOfferSalesTimeSeries(c.Request.Context(), agentID, offerID, from, to, filters.Granularity)
+	if err != nil {
+		if err == xerrors.ErrNotFound {
+			response.Error(c, http.StatusNotFound, "offer not found", err)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "failed to get sales series", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "sales series retrieved", result)
+}
+
 // GetRequestsByStatus retrieves counts by status
 func (h *TransactionHandler) GetRequestsByStatus(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
@@ -333,7 +600,7 @@ func (h *TransactionHandler) GetRequestsByStatus(c *gin.Context) {
 	failedCount = len(failed)
 
 	// Get stats for success count
-	stats, _ := h.transactionService.GetTransactionStats(c.Request.Context(), agentID)
+	stats, _ := h.transactionService.GetTransactionStats(c.Request.Context(), agentID, nil)
 	if stats != nil {
 		successCount = int(stats.SuccessfulRequests)
 	}
@@ -349,17 +616,20 @@ func (h *TransactionHandler) GetRequestsByStatus(c *gin.Context) {
 
 // ========== Batch Operations ==========
 
-// GetBatchPendingForDevice retrieves pending requests for a specific device
+// GetBatchPendingForDevice leases pending requests to the caller; with a device_id the batch
+// is capped to that device's free concurrency slots
 func (h *TransactionHandler) GetBatchPendingForDevice(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
 
+	deviceID := c.Query("device_id")
+
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 || limit > 50 {
 		limit = 10
 	}
 
-	requests, err := h.transactionService.GetPendingRequests(c.Request.Context(), agentID, limit)
+	requests, err := h.transactionService.GetBatchPendingForDevice(c.Request.Context(), agentID, deviceID, limit)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "failed to get pending requests", err)
 		return
@@ -394,6 +664,21 @@ func (h *TransactionHandler) GetBatchPendingForDevice(c *gin.Context) {
 	})
 }
 
+// BatchCreateRequests creates offer requests for many customers at once
+func (h *TransactionHandler) BatchCreateRequests(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req transaction.BatchCreateRequestsInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result := h.transactionService.BatchCreateRequests(c.Request.Context(), agentID, req.Requests)
+
+	response.Success(c, http.StatusOK, "batch create completed", result)
+}
+
 // BatchUpdateRequests updates multiple requests at once
 func (h *TransactionHandler) BatchUpdateRequests(c *gin.Context) {
 	agentID := middleware.MustGetIdentityID(c)
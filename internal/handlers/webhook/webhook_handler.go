@@ -0,0 +1,48 @@
+// internal/handlers/webhook/webhook_handler.go
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"bingwa-service/internal/domain/webhook"
+	"bingwa-service/internal/middleware"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/response"
+	service "bingwa-service/internal/service/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ReplayFailed re-delivers webhook events since the given time that exhausted their retries
+func (h *WebhookHandler) ReplayFailed(c *gin.Context) {
+	agentID := middleware.MustGetIdentityID(c)
+
+	var req webhook.ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	result, err := h.webhookService.ReplayFailed(c.Request.Context(), agentID, req.Since)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrInvalidInput) {
+			response.Error(c, http.StatusUnprocessableEntity, "webhooks are not enabled", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to replay webhooks", err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "webhook events replayed", result)
+}
@@ -6,7 +6,10 @@ import (
 	"strings"
 	"time"
 
+	authDomain "bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/pkg/mask"
 	"bingwa-service/internal/pkg/response"
+	authUsecase "bingwa-service/internal/service/auth"
 	ws "bingwa-service/internal/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -25,14 +28,16 @@ var upgrader = websocket.Upgrader{
 }
 
 type WebSocketHandler struct {
-	hub    *ws.Hub
-	logger *zap.Logger
+	hub         *ws.Hub
+	authService *authUsecase.AuthService
+	logger      *zap.Logger
 }
 
-func NewWebSocketHandler(hub *ws.Hub, logger *zap.Logger) *WebSocketHandler {
+func NewWebSocketHandler(hub *ws.Hub, authService *authUsecase.AuthService, logger *zap.Logger) *WebSocketHandler {
 	return &WebSocketHandler{
-		hub:    hub,
-		logger: logger,
+		hub:         hub,
+		authService: authService,
+		logger:      logger,
 	}
 }
 
@@ -45,8 +50,19 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		return
 	}
 
-	// Authenticate the client
-	auth, err := h.hub.AuthenticateClient(c.Request.Context(), token)
+	// Validate the token before upgrading (signature, blacklist, session)
+	claims, err := h.authService.ValidateToken(c.Request.Context(), token, h.extractFingerprint(c))
+	if err != nil {
+		h.logger.Warn("WebSocket token rejected",
+			zap.Error(err),
+			zap.String("ip", c.ClientIP()),
+		)
+		response.Error(c, http.StatusUnauthorized, "invalid or expired token", err)
+		return
+	}
+
+	// Associate the connection with the token's identity and session
+	auth, err := h.hub.ClientAuthFromClaims(c.Request.Context(), claims)
 	if err != nil {
 		h.logger.Error("WebSocket authentication failed",
 			zap.Error(err),
@@ -75,7 +91,7 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 	h.logger.Info("WebSocket client connected",
 		zap.Int64("identity_id", auth.IdentityID),
 		zap.String("session_id", auth.SessionID),
-		zap.String("email", auth.Email),
+		zap.String("email", mask.Email(auth.Email)),
 		zap.Strings("roles", auth.Roles),
 	)
 
@@ -104,6 +120,15 @@ func (h *WebSocketHandler) extractToken(c *gin.Context) string {
 	return ""
 }
 
+// extractFingerprint reads the device fingerprint from the header, falling back to the query parameter
+// since browsers cannot set headers on WebSocket upgrades
+func (h *WebSocketHandler) extractFingerprint(c *gin.Context) string {
+	if fingerprint := c.GetHeader(authDomain.DeviceFingerprintHeader); fingerprint != "" {
+		return fingerprint
+	}
+	return c.Query("fingerprint")
+}
+
 // GetStats returns WebSocket connection statistics (admin only)
 func (h *WebSocketHandler) GetStats(c *gin.Context) {
 	// This would be called via REST API with admin auth middleware
@@ -0,0 +1,125 @@
+// internal/handlers/websocket/websocket_test.go
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	wstypes "bingwa-service/internal/domain/websocket"
+	"bingwa-service/internal/pkg/jwt"
+	"bingwa-service/internal/pkg/session"
+	authUsecase "bingwa-service/internal/service/auth"
+	ws "bingwa-service/internal/websocket"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestHandleConnectionRequiresValidToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	jwtManager := &jwt.Manager{
+		Generator: jwt.NewGenerator(key, "bingwa-test", "bingwa-test", "test", 15*time.Minute),
+		Verifier:  jwt.NewVerifier(&key.PublicKey, "bingwa-test", "bingwa-test"),
+	}
+	// Sessions live in Redis, so the handshake never reaches the database
+	sessionManager := session.NewManager(client, nil)
+	hub := ws.NewHub(jwtManager.Verifier, sessionManager)
+	go hub.Run(ctx)
+
+	authService := authUsecase.NewAuthService(
+		nil, nil, nil, nil, nil, nil,
+		jwtManager,
+		sessionManager,
+		session.NewRateLimiter(client),
+		nil,
+		hub,
+		client,
+		zap.NewNop(),
+	)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", NewWebSocketHandler(hub, authService, zap.NewNop()).HandleConnection)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	token, jti, err := jwtManager.Generator.GenerateAccessToken(7, []string{"agent"}, nil, "phone", nil)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	for name, dialURL := range map[string]string{
+		"missing token": url,
+		"invalid token": url + "?token=not-a-jwt",
+	} {
+		conn, resp, err := websocket.DefaultDialer.Dial(dialURL, nil)
+		if err == nil {
+			conn.Close()
+			t.Errorf("%s: upgrade succeeded, want it rejected", name)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: response = %v, want 401", name, resp)
+		}
+	}
+
+	if err := sessionManager.CreateSession(ctx, &session.SessionData{
+		JTI:        jti,
+		IdentityID: 7,
+		Email:      "agent@example.com",
+		ExpiresAt:  time.Now().Add(15 * time.Minute),
+		IsActive:   true,
+	}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	header := http.Header{"Authorization": []string{"Bearer " + token}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("authenticated upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	// The hub greets the connection as the token's identity
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var welcome wstypes.WSMessage
+	if err := conn.ReadJSON(&welcome); err != nil {
+		t.Fatalf("failed to read welcome message: %v", err)
+	}
+	if welcome.Type != wstypes.EventTypeConnected {
+		t.Errorf("first message = %s, want %s", welcome.Type, wstypes.EventTypeConnected)
+	}
+	if data, _ := welcome.Data.(map[string]interface{}); data["identity_id"] != float64(7) {
+		t.Errorf("welcome data = %v, want identity 7", welcome.Data)
+	}
+
+	// A revoked token no longer upgrades, even with its session still cached
+	if err := sessionManager.BlacklistToken(ctx, jti, time.Hour); err != nil {
+		t.Fatalf("BlacklistToken: %v", err)
+	}
+	if conn, resp, err := websocket.DefaultDialer.Dial(url, header); err == nil {
+		conn.Close()
+		t.Error("upgrade with a revoked token succeeded, want it rejected")
+	} else if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("revoked token response = %v, want 401", resp)
+	}
+}
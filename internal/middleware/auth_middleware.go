@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	authDomain "bingwa-service/internal/domain/auth"
 	"bingwa-service/internal/pkg/response"
 	"bingwa-service/internal/service/auth"
 
@@ -31,7 +32,7 @@ func (m *AuthMiddleware) Auth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := m.authService.ValidateToken(c.Request.Context(), token)
+		claims, err := m.authService.ValidateToken(c.Request.Context(), token, c.GetHeader(authDomain.DeviceFingerprintHeader))
 		if err != nil {
 			response.Error(c, http.StatusUnauthorized, "invalid or expired token", err)
 			return
@@ -255,7 +256,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := m.authService.ValidateToken(c.Request.Context(), token)
+		claims, err := m.authService.ValidateToken(c.Request.Context(), token, c.GetHeader(authDomain.DeviceFingerprintHeader))
 		if err != nil {
 			// Don't abort, just continue without setting user context
 			c.Next()
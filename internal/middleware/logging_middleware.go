@@ -4,6 +4,8 @@ package middleware
 import (
 	"time"
 
+	"bingwa-service/internal/pkg/mask"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -24,7 +26,7 @@ func LoggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		logger.Info("request",
 			zap.String("method", method),
 			zap.String("path", path),
-			zap.String("query", query),
+			zap.String("query", mask.Query(query)),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
 			zap.String("client_ip", clientIP),
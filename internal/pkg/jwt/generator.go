@@ -16,6 +16,7 @@ type Generator struct {
 	audience string
 	kid      string // key id for rotation
 	Ttl      time.Duration
+	AdminTtl time.Duration // Access token lifetime for admin roles; zero uses Ttl
 }
 
 func NewGenerator(priv *rsa.PrivateKey, issuer, audience, kid string, ttl time.Duration) *Generator {
@@ -71,6 +72,13 @@ func (g *Generator) Generate(identityID int64, roles []string, permissions []str
 	return signed, jti, err
 }
 
+// WithTTL returns a copy of the generator that issues tokens with the given lifetime
+func (g *Generator) WithTTL(ttl time.Duration) *Generator {
+	clone := *g
+	clone.Ttl = ttl
+	return &clone
+}
+
 // GenerateAccessToken generates a standard access token
 func (g *Generator) GenerateAccessToken(identityID int64, roles []string, permissions []string, device string, extraData map[string]interface{}) (string, string, error) {
 	return g.Generate(identityID, roles, permissions, device, "access", false, extraData)
@@ -12,6 +12,7 @@ type Config struct {
 	Issuer   string
 	Audience string
 	TTL      time.Duration
+	AdminTTL time.Duration // Access token lifetime for admin roles; zero uses TTL
 	KID      string
 }
 
@@ -34,6 +35,7 @@ func LoadAndBuild(cfg Config) (*Manager, error) {
 	}
 
 	gen := NewGenerator(priv, cfg.Issuer, cfg.Audience, cfg.KID, cfg.TTL)
+	gen.AdminTtl = cfg.AdminTTL
 	ver := NewVerifier(pub, cfg.Issuer, cfg.Audience)
 
 	return &Manager{
@@ -0,0 +1,101 @@
+// internal/pkg/mask/mask.go
+package mask
+
+import (
+	"net/url"
+	"strings"
+)
+
+// sensitiveQueryParams are masked by Query
+var sensitiveQueryParams = map[string]func(string) string{
+	"phone":          Phone,
+	"customer_phone": Phone,
+	"phone_number":   Phone,
+	"receipt":        Receipt,
+	"email":          Email,
+	"token":          func(string) string { return "****" },
+}
+
+// Phone hides the middle digits of a phone number, e.g. 254712345678 -> 2547****5678
+func Phone(phone string) string {
+	return middle(phone, 4, 4)
+}
+
+// Receipt hides the middle of an M-Pesa receipt number, e.g. QJK7ABC123 -> QJ*****123
+func Receipt(receipt string) string {
+	return middle(receipt, 2, 3)
+}
+
+// Email hides the local part of an email address except its first character, e.g. jane@example.com -> j***@example.com
+func Email(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return middle(email, 1, 0)
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Secret hides a secret except its last four characters behind a fixed-width prefix, e.g. whsec_abc123xyz9 -> ****xyz9.
+// Short secrets are hidden entirely so little of them leaks.
+func Secret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) == 0 {
+		return secret
+	}
+	if len(runes) <= 8 {
+		return "****"
+	}
+	return "****" + string(runes[len(runes)-4:])
+}
+
+// Name keeps the first letter of each word of a name, e.g. Jane Doe -> J*** D**
+func Name(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		words[i] = middle(w, 1, 0)
+	}
+	return strings.Join(words, " ")
+}
+
+// Query masks sensitive parameters in a raw URL query string
+func Query(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "****"
+	}
+
+	masked := false
+	for key, vals := range values {
+		maskFn, ok := sensitiveQueryParams[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+		for i, v := range vals {
+			vals[i] = maskFn(v)
+		}
+		masked = true
+	}
+
+	if !masked {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// middle keeps the first keepStart and last keepEnd characters and replaces the rest with asterisks.
+// Values too short to keep both ends are fully masked.
+func middle(value string, keepStart, keepEnd int) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return value
+	}
+	if len(runes) <= keepStart+keepEnd {
+		return strings.Repeat("*", len(runes))
+	}
+	hidden := len(runes) - keepStart - keepEnd
+	return string(runes[:keepStart]) + strings.Repeat("*", hidden) + string(runes[len(runes)-keepEnd:])
+}
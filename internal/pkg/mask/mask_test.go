@@ -0,0 +1,68 @@
+// internal/pkg/mask/mask_test.go
+package mask
+
+import "testing"
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+	}{
+		{"international format", "254712345678", "2547****5678"},
+		{"local format", "0712345678", "0712**5678"},
+		{"too short to keep both ends", "12345678", "********"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Phone(tt.phone); got != tt.want {
+				t.Errorf("Phone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	tests := []struct {
+		name    string
+		receipt string
+		want    string
+	}{
+		{"mpesa receipt", "QJK7ABC123", "QJ*****123"},
+		{"six characters", "AB1234", "AB*234"},
+		{"too short to keep both ends", "AB123", "*****"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Receipt(tt.receipt); got != tt.want {
+				t.Errorf("Receipt(%q) = %q, want %q", tt.receipt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+	}{
+		{"long secret keeps last four", "whsec_abc123xyz9", "****xyz9"},
+		{"nine characters", "abcdefghi", "****fghi"},
+		{"eight characters hidden entirely", "abcdefgh", "****"},
+		{"short secret hidden entirely", "abc", "****"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Secret(tt.secret); got != tt.want {
+				t.Errorf("Secret(%q) = %q, want %q", tt.secret, got, tt.want)
+			}
+		})
+	}
+}
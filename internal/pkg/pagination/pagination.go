@@ -0,0 +1,46 @@
+// internal/pkg/pagination/pagination.go
+package pagination
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageMeta describes a page of a paginated list
+type PageMeta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// Clamp normalizes page and pageSize to the supported range
+func Clamp(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
+
+// Compute builds page metadata for a total, clamping page and pageSize first
+func Compute(total int64, page, pageSize int) PageMeta {
+	page, pageSize = Clamp(page, pageSize)
+
+	totalPages := int(total / int64(pageSize))
+	if total%int64(pageSize) > 0 {
+		totalPages++
+	}
+
+	return PageMeta{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+}
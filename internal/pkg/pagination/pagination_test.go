@@ -0,0 +1,30 @@
+// internal/pkg/pagination/pagination_test.go
+package pagination
+
+import "testing"
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int64
+		page     int
+		pageSize int
+		want     PageMeta
+	}{
+		{"exact pages", 40, 2, 20, PageMeta{Page: 2, PageSize: 20, Total: 40, TotalPages: 2}},
+		{"partial last page", 41, 1, 20, PageMeta{Page: 1, PageSize: 20, Total: 41, TotalPages: 3}},
+		{"empty list", 0, 1, 20, PageMeta{Page: 1, PageSize: 20, Total: 0, TotalPages: 0}},
+		{"page below one", 5, 0, 10, PageMeta{Page: 1, PageSize: 10, Total: 5, TotalPages: 1}},
+		{"page size defaulted", 45, 1, 0, PageMeta{Page: 1, PageSize: DefaultPageSize, Total: 45, TotalPages: 3}},
+		{"page size capped", 250, 1, 500, PageMeta{Page: 1, PageSize: MaxPageSize, Total: 250, TotalPages: 3}},
+		{"page past the end kept", 10, 5, 10, PageMeta{Page: 5, PageSize: 10, Total: 10, TotalPages: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compute(tt.total, tt.page, tt.pageSize); got != tt.want {
+				t.Errorf("Compute(%d, %d, %d) = %+v, want %+v", tt.total, tt.page, tt.pageSize, got, tt.want)
+			}
+		})
+	}
+}
@@ -109,6 +109,10 @@ func (m *Manager) GetSession(ctx context.Context, identityID int64, jti string)
 		IsActive:       dbSession.Status == "active",
 		Metadata:       dbSession.Metadata,
 	}
+	sessionData.DeviceFingerprint = stringFromNull(dbSession.DeviceFingerprint)
+	if strict, ok := dbSession.Metadata[MetadataKeyStrictDevice].(bool); ok {
+		sessionData.StrictDevice = strict
+	}
 
 	// Get user identity and roles/permissions
 	identity, err := m.authRepo.FindIdentityByID(ctx, identityID)
@@ -258,6 +262,9 @@ func (m *Manager) GetUserActiveSessions(ctx context.Context, identityID int64) (
 	return sessions, iter.Err()
 }
 
+// MetadataKeyStrictDevice records strict device binding on the DB session so it survives Redis misses
+const MetadataKeyStrictDevice = "strict_device"
+
 // Helper functions
 func (m *Manager) sessionKey(identityID int64, jti string) string {
 	return fmt.Sprintf("session:%d:%s", identityID, jti)
@@ -85,6 +85,24 @@ func (r *RateLimiter) CheckPasswordResetAttempt(ctx context.Context, email strin
 	return count <= 3, nil
 }
 
+// CheckRegistrationAttempt checks registration rate limit per IP
+func (r *RateLimiter) CheckRegistrationAttempt(ctx context.Context, ip string) (bool, error) {
+	key := fmt.Sprintf("ratelimit:register:%s", ip)
+
+	count, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to increment registration attempt: %w", err)
+	}
+
+	// Set expiration on first attempt
+	if count == 1 {
+		r.client.Expire(ctx, key, 1*time.Hour)
+	}
+
+	// Allow up to 5 registrations per IP per hour
+	return count <= 5, nil
+}
+
 // CheckOTPAttempt checks OTP verification rate limit
 func (r *RateLimiter) CheckOTPAttempt(ctx context.Context, identityID int64) (bool, error) {
 	key := fmt.Sprintf("ratelimit:otp:%d", identityID)
@@ -0,0 +1,39 @@
+// internal/pkg/session/redis_store_test.go
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCheckRegistrationAttemptThrottlesPerIP(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	limiter := NewRateLimiter(client)
+
+	for i := 1; i <= 5; i++ {
+		if allowed, err := limiter.CheckRegistrationAttempt(ctx, "10.0.0.1"); err != nil || !allowed {
+			t.Fatalf("registration %d = %v, %v; want allowed", i, allowed, err)
+		}
+	}
+	if allowed, err := limiter.CheckRegistrationAttempt(ctx, "10.0.0.1"); err != nil || allowed {
+		t.Errorf("sixth registration = %v, %v; want throttled", allowed, err)
+	}
+
+	// Other addresses have their own allowance
+	if allowed, err := limiter.CheckRegistrationAttempt(ctx, "10.0.0.2"); err != nil || !allowed {
+		t.Errorf("registration from another IP = %v, %v; want allowed", allowed, err)
+	}
+
+	// The window resets after an hour
+	mr.FastForward(time.Hour + time.Second)
+	if allowed, err := limiter.CheckRegistrationAttempt(ctx, "10.0.0.1"); err != nil || !allowed {
+		t.Errorf("registration after the window = %v, %v; want allowed", allowed, err)
+	}
+}
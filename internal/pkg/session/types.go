@@ -20,6 +20,8 @@ type SessionData struct {
 	LastActivityAt    time.Time              `json:"last_activity_at"`
 	ExpiresAt         time.Time              `json:"expires_at"`
 	IsActive          bool                   `json:"is_active"`
+	DeviceFingerprint string                 `json:"device_fingerprint,omitempty"`
+	StrictDevice      bool                   `json:"strict_device,omitempty"` // Fingerprint must match on every request
 	Metadata          map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -29,4 +31,4 @@ type UserInfo struct {
 	Email      string   `json:"email"`
 	FullName   string   `json:"full_name"`
 	Roles      []string `json:"roles"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,80 @@
+// internal/pkg/webhook/address.go
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for webhook endpoints on loopback, link-local or private addresses
+var ErrPrivateAddress = errors.New("webhook endpoint must be a public address")
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), not covered by netip's IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddr reports whether addr is routable on the public internet
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// ValidateURL checks that a webhook URL is http(s) and that its host resolves only to public addresses
+func ValidateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhook url must use http or https")
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("webhook url has no host")
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddr(addr) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// DialControl refuses connections to non-public addresses. Set it on the delivery client's dialer so a
+// host that re-resolves to a private address after ValidateURL is still blocked.
+func DialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unexpected dial address %q: %w", address, err)
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// internal/pkg/webhook/signature.go
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// SignatureHeader carries the payload signature on outgoing webhooks
+	SignatureHeader = "X-Bingwa-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature header value for a payload: "sha256=" followed by the hex HMAC-SHA256 of body keyed by secret
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether header is a valid Sign output for body and secret, comparing in constant time.
+// An empty secret or a header without the "sha256=" prefix never verifies.
+func VerifySignature(body []byte, header, secret string) bool {
+	if secret == "" || !strings.HasPrefix(header, signaturePrefix) {
+		return false
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(header, signaturePrefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(received, mac.Sum(nil))
+}
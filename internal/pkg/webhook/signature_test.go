@@ -0,0 +1,34 @@
+// internal/pkg/webhook/signature_test.go
+package webhook
+
+import "testing"
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event":"redemption.success"}`)
+	secret := "whsec_test"
+	valid := Sign(body, secret)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header string
+		secret string
+		want   bool
+	}{
+		{"valid signature", body, valid, secret, true},
+		{"wrong secret", body, valid, "whsec_other", false},
+		{"tampered body", []byte(`{"event":"redemption.failed"}`), valid, secret, false},
+		{"empty secret", body, Sign(body, ""), "", false},
+		{"missing prefix", body, valid[len(signaturePrefix):], secret, false},
+		{"non-hex signature", body, signaturePrefix + "zz", secret, false},
+		{"empty header", body, "", secret, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.body, tt.header, tt.secret); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
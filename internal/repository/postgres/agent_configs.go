@@ -3,8 +3,8 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +12,7 @@ import (
 	"bingwa-service/internal/domain/config"
 	xerrors "bingwa-service/internal/pkg/errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -78,7 +79,7 @@ func (r *AgentConfigRepository) FindByID(ctx context.Context, id int64) (*config
 		&cfg.DeviceID, &cfg.IsGlobal, &metadataJSON, &cfg.CreatedAt, &cfg.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -122,7 +123,7 @@ func (r *AgentConfigRepository) FindByKey(ctx context.Context, agentID int64, co
 		&cfg.DeviceID, &cfg.IsGlobal, &metadataJSON, &cfg.CreatedAt, &cfg.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -182,6 +183,114 @@ func (r *AgentConfigRepository) Update(ctx context.Context, id int64, cfg *confi
 	return nil
 }
 
+// UpdateWithTx updates a config within a transaction
+func (r *AgentConfigRepository) UpdateWithTx(ctx context.Context, tx pgx.Tx, id int64, cfg *config.AgentConfig) error {
+	query := `
+		UPDATE agent_configs
+		SET config_value = $1, description = $2, metadata = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	var configValueJSON, metadataJSON []byte
+	var err error
+
+	configValueJSON, err = json.Marshal(cfg.ConfigValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config_value: %w", err)
+	}
+
+	if cfg.Metadata != nil {
+		metadataJSON, err = json.Marshal(cfg.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(
+		ctx, query,
+		configValueJSON, cfg.Description, metadataJSON, time.Now(), id,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// CreateHistoryWithTx records a config change within a transaction
+func (r *AgentConfigRepository) CreateHistoryWithTx(ctx context.Context, tx pgx.Tx, history *config.AgentConfigHistory) error {
+	query := `
+		INSERT INTO agent_config_history (
+			agent_config_id, agent_identity_id, config_key, previous_value, new_value
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, changed_at
+	`
+
+	previousValueJSON, err := json.Marshal(history.PreviousValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal previous_value: %w", err)
+	}
+
+	newValueJSON, err := json.Marshal(history.NewValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal new_value: %w", err)
+	}
+
+	err = tx.QueryRow(
+		ctx, query,
+		history.AgentConfigID, history.AgentIdentityID, history.ConfigKey, previousValueJSON, newValueJSON,
+	).Scan(&history.ID, &history.ChangedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create config history: %w", err)
+	}
+
+	return nil
+}
+
+// ListHistory retrieves the change history of a config, newest first
+func (r *AgentConfigRepository) ListHistory(ctx context.Context, configID int64) ([]config.AgentConfigHistory, error) {
+	query := `
+		SELECT id, agent_config_id, agent_identity_id, config_key,
+		       previous_value, new_value, changed_at
+		FROM agent_config_history
+		WHERE agent_config_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []config.AgentConfigHistory{}
+	for rows.Next() {
+		var entry config.AgentConfigHistory
+		var previousValueJSON, newValueJSON []byte
+
+		err := rows.Scan(
+			&entry.ID, &entry.AgentConfigID, &entry.AgentIdentityID, &entry.ConfigKey,
+			&previousValueJSON, &newValueJSON, &entry.ChangedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan config history: %w", err)
+		}
+
+		json.Unmarshal(previousValueJSON, &entry.PreviousValue)
+		json.Unmarshal(newValueJSON, &entry.NewValue)
+
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
 // Delete deletes a config
 func (r *AgentConfigRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM agent_configs WHERE id = $1`
@@ -331,6 +440,60 @@ func (r *AgentConfigRepository) GetAllByAgent(ctx context.Context, agentID int64
 	return configs, nil
 }
 
+// ListAgentsWithFlag returns the agents whose global config under configKey has a true boolean field named flag
+func (r *AgentConfigRepository) ListAgentsWithFlag(ctx context.Context, configKey, flag string) ([]int64, error) {
+	query := `
+		SELECT DISTINCT agent_identity_id
+		FROM agent_configs
+		WHERE config_key = $1 AND device_id IS NULL AND config_value->$2 = 'true'::jsonb
+		ORDER BY agent_identity_id
+	`
+
+	rows, err := r.db.Query(ctx, query, configKey, flag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents with %s: %w", flag, err)
+	}
+	defer rows.Close()
+
+	agentIDs := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan agent ID: %w", err)
+		}
+		agentIDs = append(agentIDs, id)
+	}
+
+	return agentIDs, rows.Err()
+}
+
+// ListAgentsWithValue returns the agents whose global config under configKey has a string field equal to value
+func (r *AgentConfigRepository) ListAgentsWithValue(ctx context.Context, configKey, field, value string) ([]int64, error) {
+	query := `
+		SELECT DISTINCT agent_identity_id
+		FROM agent_configs
+		WHERE config_key = $1 AND device_id IS NULL AND config_value->>$2 = $3
+		ORDER BY agent_identity_id
+	`
+
+	rows, err := r.db.Query(ctx, query, configKey, field, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents with %s: %w", field, err)
+	}
+	defer rows.Close()
+
+	agentIDs := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan agent ID: %w", err)
+		}
+		agentIDs = append(agentIDs, id)
+	}
+
+	return agentIDs, rows.Err()
+}
+
 // GetGlobalConfigs retrieves all global configs for an agent
 func (r *AgentConfigRepository) GetGlobalConfigs(ctx context.Context, agentID int64) ([]config.AgentConfig, error) {
 	query := `
@@ -431,4 +594,26 @@ func (r *AgentConfigRepository) ExistsByKey(ctx context.Context, agentID int64,
 	var exists bool
 	err := r.db.QueryRow(ctx, query, agentID, configKey, deviceIDParam).Scan(&exists)
 	return exists, err
-}
\ No newline at end of file
+}
+
+// FindAgentByValue returns the agent whose global config under configKey has a string field equal to value
+func (r *AgentConfigRepository) FindAgentByValue(ctx context.Context, configKey, field, value string) (int64, error) {
+	query := `
+		SELECT agent_identity_id
+		FROM agent_configs
+		WHERE config_key = $1 AND device_id IS NULL AND config_value->>$2 = $3
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var agentID int64
+	err := r.db.QueryRow(ctx, query, configKey, field, value).Scan(&agentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find agent by %s: %w", field, err)
+	}
+
+	return agentID, nil
+}
@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"bingwa-service/internal/domain/customer"
 	xerrors "bingwa-service/internal/pkg/errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lib/pq"
 )
@@ -33,7 +35,7 @@ func (r *AgentCustomerRepository) scanCustomerRow(scanner interface {
 	err := scanner.Scan(
 		&c.ID, &c.AgentIdentityID, &c.CustomerReference, &c.FullName, &c.PhoneNumber,
 		&c.AltPhoneNumber, &c.Email, &c.IsActive, &c.IsVerified, &c.VerifiedAt,
-		&c.Notes, &c.Tags, &metadataJSON, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt,
+		&c.Notes, &c.Tags, &metadataJSON, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.LastActivityAt,
 	)
 
 	if err != nil {
@@ -92,7 +94,7 @@ func (r *AgentCustomerRepository) FindByID(ctx context.Context, id int64) (*cust
 	query := `
 		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
 		       alt_phone_number, email, is_active, is_verified, verified_at,
-		       notes, tags, metadata, created_at, updated_at, deleted_at
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
 		FROM agent_customers
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -115,7 +117,7 @@ func (r *AgentCustomerRepository) FindByReference(ctx context.Context, reference
 	query := `
 		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
 		       alt_phone_number, email, is_active, is_verified, verified_at,
-		       notes, tags, metadata, created_at, updated_at, deleted_at
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
 		FROM agent_customers
 		WHERE customer_reference = $1 AND deleted_at IS NULL
 	`
@@ -138,7 +140,7 @@ func (r *AgentCustomerRepository) FindByAgentAndPhone(ctx context.Context, agent
 	query := `
 		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
 		       alt_phone_number, email, is_active, is_verified, verified_at,
-		       notes, tags, metadata, created_at, updated_at, deleted_at
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
 		FROM agent_customers
 		WHERE agent_identity_id = $1 AND phone_number = $2 AND deleted_at IS NULL
 	`
@@ -208,6 +210,92 @@ func (r *AgentCustomerRepository) UpdateStatus(ctx context.Context, id int64, is
 	return nil
 }
 
+// SaveVerificationCode stores a customer's verification code, replacing any pending one
+func (r *AgentCustomerRepository) SaveVerificationCode(ctx context.Context, code *customer.VerificationCode) error {
+	query := `
+		INSERT INTO customer_verification_codes (customer_id, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, 0, $3, NOW())
+		ON CONFLICT (customer_id) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash, attempts = 0, expires_at = EXCLUDED.expires_at, created_at = NOW()
+		RETURNING created_at
+	`
+
+	if err := r.db.QueryRow(ctx, query, code.CustomerID, code.CodeHash, code.ExpiresAt).Scan(&code.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save verification code: %w", err)
+	}
+
+	return nil
+}
+
+// FindVerificationCode retrieves a customer's pending verification code
+func (r *AgentCustomerRepository) FindVerificationCode(ctx context.Context, customerID int64) (*customer.VerificationCode, error) {
+	query := `
+		SELECT customer_id, code_hash, attempts, expires_at, created_at
+		FROM customer_verification_codes
+		WHERE customer_id = $1
+	`
+
+	var code customer.VerificationCode
+	err := r.db.QueryRow(ctx, query, customerID).Scan(&code.CustomerID, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, xerrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to find verification code: %w", err)
+	}
+
+	return &code, nil
+}
+
+// FindVerificationCodesByPhone retrieves the unexpired pending codes of unverified customers with any of the given numbers.
+// A phone may belong to customers of several agents, each with their own code.
+func (r *AgentCustomerRepository) FindVerificationCodesByPhone(ctx context.Context, phones []string) ([]customer.VerificationCode, error) {
+	query := `
+		SELECT vc.customer_id, vc.code_hash, vc.attempts, vc.expires_at, vc.created_at
+		FROM customer_verification_codes vc
+		JOIN agent_customers ac ON ac.id = vc.customer_id
+		WHERE ac.phone_number = ANY($1)
+		  AND ac.is_verified = FALSE
+		  AND ac.deleted_at IS NULL
+		  AND vc.expires_at > NOW()
+		ORDER BY vc.created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, phones)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find verification codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []customer.VerificationCode{}
+	for rows.Next() {
+		var code customer.VerificationCode
+		if err := rows.Scan(&code.CustomerID, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan verification code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// IncrementVerificationAttempts counts a wrong code against a customer's pending verification
+func (r *AgentCustomerRepository) IncrementVerificationAttempts(ctx context.Context, customerID int64) error {
+	query := `UPDATE customer_verification_codes SET attempts = attempts + 1 WHERE customer_id = $1`
+	if _, err := r.db.Exec(ctx, query, customerID); err != nil {
+		return fmt.Errorf("failed to record verification attempt: %w", err)
+	}
+	return nil
+}
+
+// DeleteVerificationCode removes a customer's pending verification code
+func (r *AgentCustomerRepository) DeleteVerificationCode(ctx context.Context, customerID int64) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM customer_verification_codes WHERE customer_id = $1`, customerID); err != nil {
+		return fmt.Errorf("failed to delete verification code: %w", err)
+	}
+	return nil
+}
+
 // MarkAsVerified marks a customer as verified
 func (r *AgentCustomerRepository) MarkAsVerified(ctx context.Context, id int64) error {
 	query := `
@@ -313,7 +401,7 @@ func (r *AgentCustomerRepository) List(ctx context.Context, agentID int64, filte
 	query := fmt.Sprintf(`
 		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
 		       alt_phone_number, email, is_active, is_verified, verified_at,
-		       notes, tags, metadata, created_at, updated_at, deleted_at
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
 		FROM agent_customers
 		WHERE %s
 		ORDER BY %s %s
@@ -340,6 +428,51 @@ func (r *AgentCustomerRepository) List(ctx context.Context, agentID int64, filte
 	return customers, total, nil
 }
 
+// TouchLastActivityWithTx records customer activity within a transaction
+func (r *AgentCustomerRepository) TouchLastActivityWithTx(ctx context.Context, tx pgx.Tx, id int64, at time.Time) error {
+	query := `
+		UPDATE agent_customers
+		SET last_activity_at = GREATEST(COALESCE(last_activity_at, $1), $1)
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	if _, err := tx.Exec(ctx, query, at, id); err != nil {
+		return fmt.Errorf("failed to update customer activity: %w", err)
+	}
+
+	return nil
+}
+
+// FindInactive retrieves an agent's customers with no activity since the given time (never-active customers count from creation)
+func (r *AgentCustomerRepository) FindInactive(ctx context.Context, agentID int64, since time.Time) ([]customer.AgentCustomer, error) {
+	query := `
+		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
+		       alt_phone_number, email, is_active, is_verified, verified_at,
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
+		FROM agent_customers
+		WHERE agent_identity_id = $1 AND deleted_at IS NULL AND is_active = TRUE
+		  AND COALESCE(last_activity_at, created_at) < $2
+		ORDER BY COALESCE(last_activity_at, created_at) ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find inactive customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []customer.AgentCustomer{}
+	for rows.Next() {
+		c, err := r.scanCustomerRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inactive customer: %w", err)
+		}
+		customers = append(customers, *c)
+	}
+
+	return customers, nil
+}
+
 // GetStats retrieves customer statistics for an agent
 func (r *AgentCustomerRepository) GetStats(ctx context.Context, agentID int64) (*customer.CustomerStats, error) {
 	query := `
@@ -367,6 +500,26 @@ func (r *AgentCustomerRepository) GetStats(ctx context.Context, agentID int64) (
 	return &stats, nil
 }
 
+// RenameTagWithTx replaces a tag on all of an agent's customers, dropping it where the new tag already exists
+func (r *AgentCustomerRepository) RenameTagWithTx(ctx context.Context, tx pgx.Tx, agentID int64, oldTag, newTag string) (int64, error) {
+	query := `
+		UPDATE agent_customers
+		SET tags = CASE
+		        WHEN $3 = ANY(tags) THEN array_remove(tags, $2)
+		        ELSE array_replace(tags, $2, $3)
+		    END,
+		    updated_at = $4
+		WHERE agent_identity_id = $1 AND $2 = ANY(tags) AND deleted_at IS NULL
+	`
+
+	result, err := tx.Exec(ctx, query, agentID, oldTag, newTag, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename customer tag: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // ExistsByAgentAndPhone checks if customer exists for agent with phone number
 func (r *AgentCustomerRepository) ExistsByAgentAndPhone(ctx context.Context, agentID int64, phone string) (bool, error) {
 	query := `
@@ -386,4 +539,34 @@ func (r *AgentCustomerRepository) ExistsByReference(ctx context.Context, referen
 	var exists bool
 	err := r.db.QueryRow(ctx, query, reference).Scan(&exists)
 	return exists, err
-}
\ No newline at end of file
+}
+// ForEachByAgent streams an agent's customers ordered by creation, calling fn for each row.
+// Iteration stops at the first error returned by fn.
+func (r *AgentCustomerRepository) ForEachByAgent(ctx context.Context, agentID int64, fn func(*customer.AgentCustomer) error) error {
+	query := `
+		SELECT id, agent_identity_id, customer_reference, full_name, phone_number,
+		       alt_phone_number, email, is_active, is_verified, verified_at,
+		       notes, tags, metadata, created_at, updated_at, deleted_at, last_activity_at
+		FROM agent_customers
+		WHERE agent_identity_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID)
+	if err != nil {
+		return fmt.Errorf("failed to query customers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := r.scanCustomerRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
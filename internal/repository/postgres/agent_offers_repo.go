@@ -3,8 +3,9 @@ package postgres
 
 import (
 	"context"
-	//"database/sql"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,7 +13,7 @@ import (
 	"bingwa-service/internal/domain/offer"
 	xerrors "bingwa-service/internal/pkg/errors"
 
-	//"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lib/pq"
 )
@@ -43,9 +44,9 @@ func (r *AgentOfferRepository) scanOfferRow(scanner interface {
 		&o.ID, &o.AgentIdentityID, &o.OfferCode, &o.Name, &o.Description, &o.Type, &o.Amount, &o.Units,
 		&o.Price, &o.Currency, &o.DiscountPercentage, &o.ValidityDays, &o.ValidityLabel,
 		&o.USSDCodeTemplate, &o.USSDProcessingType, &o.USSDExpectedResponse, &o.USSDErrorPattern,
-		&o.IsFeatured, &o.IsRecurring, &o.MaxPurchasesPerCustomer,
+		&o.IsFeatured, &o.IsRecurring, &o.MaxPurchasesPerCustomer, &o.PurchaseLimitPeriod, &o.PurchaseCooldownSeconds, &o.RequiredDeviceID, &o.RequireConfirmation,
 		&o.Status, &o.AvailableFrom, &o.AvailableUntil, &o.Tags, &metadataJSON,
-		&o.CreatedAt, &o.UpdatedAt, &o.DeletedAt,
+		&o.StockLimit, &o.CreatedAt, &o.UpdatedAt, &o.DeletedAt,
 	)
 
 	if err != nil {
@@ -90,14 +91,16 @@ func (r *AgentOfferRepository) Create(ctx context.Context, o *offer.AgentOffer)
 			agent_identity_id, offer_code, name, description, type, amount, units,
 			price, currency, discount_percentage, validity_days, validity_label,
 			ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-			is_featured, is_recurring, max_purchases_per_customer,
-			status, available_from, available_until, tags, metadata
+			is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
+			status, available_from, available_until, tags, metadata,
+			stock_limit
 		) VALUES (
 			$1,$2,$3,$4,$5,$6,$7,
 			$8,$9,$10,$11,$12,
 			$13,$14,$15,$16,
-			$17,$18,$19,
-			$20,$21,$22,$23,$24
+			$17,$18,$19,$20,$21,$22,$23,
+			$24,$25,$26,$27,$28,
+			$29
 		)
 		RETURNING id, created_at, updated_at
 	`
@@ -120,8 +123,9 @@ func (r *AgentOfferRepository) Create(ctx context.Context, o *offer.AgentOffer)
 		o.AgentIdentityID, o.OfferCode, o.Name, o.Description, o.Type, o.Amount, o.Units,
 		o.Price, o.Currency, o.DiscountPercentage, o.ValidityDays, o.ValidityLabel,
 		o.USSDCodeTemplate, o.USSDProcessingType, o.USSDExpectedResponse, o.USSDErrorPattern,
-		o.IsFeatured, o.IsRecurring, o.MaxPurchasesPerCustomer,
+		o.IsFeatured, o.IsRecurring, o.MaxPurchasesPerCustomer, o.PurchaseLimitPeriod, o.PurchaseCooldownSeconds, o.RequiredDeviceID, o.RequireConfirmation,
 		o.Status, o.AvailableFrom, o.AvailableUntil, o.Tags, metadataJSON, // ✅ no pq.Array
+		o.StockLimit,
 	).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
 
 	if err != nil {
@@ -156,9 +160,9 @@ func (r *AgentOfferRepository) FindByID(ctx context.Context, id int64) (*offer.A
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -182,15 +186,80 @@ func (r *AgentOfferRepository) FindByID(ctx context.Context, id int64) (*offer.A
 	return o, nil
 }
 
+// FindByIDs retrieves non-deleted offers by ID in a single query (USSD codes are not loaded)
+func (r *AgentOfferRepository) FindByIDs(ctx context.Context, ids []int64) ([]offer.AgentOffer, error) {
+	query := `
+		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
+		       price, currency, discount_percentage, validity_days, validity_label,
+		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
+		       status, available_from, available_until, tags, metadata,
+		       stock_limit, created_at, updated_at, deleted_at
+		FROM agent_offers
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find offers: %w", err)
+	}
+	defer rows.Close()
+
+	offers := []offer.AgentOffer{}
+	for rows.Next() {
+		o, err := r.scanOfferRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, *o)
+	}
+
+	return offers, nil
+}
+
+// TopOfferIDs returns the agent's active offer IDs ordered by non-failed redemption count,
+// restricted to a single customer when customerID is set
+func (r *AgentOfferRepository) TopOfferIDs(ctx context.Context, agentID int64, customerID *int64, limit int) ([]int64, error) {
+	query := `
+		SELECT r.offer_id
+		FROM offer_redemptions r
+		JOIN agent_offers o ON o.id = r.offer_id
+		WHERE r.agent_identity_id = $1
+		  AND ($2::bigint IS NULL OR r.customer_id = $2)
+		  AND r.status NOT IN ('failed', 'cancelled', 'reversed')
+		  AND o.status = 'active' AND o.deleted_at IS NULL
+		GROUP BY r.offer_id
+		ORDER BY COUNT(*) DESC, MAX(r.redemption_time) DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, customerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top offers: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan offer id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 // FindByOfferCode retrieves an offer by offer code (now loads primary USSD code)
 func (r *AgentOfferRepository) FindByOfferCode(ctx context.Context, offerCode string) (*offer.AgentOffer, error) {
 	query := `
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE offer_code = $1 AND deleted_at IS NULL
 	`
@@ -220,9 +289,10 @@ func (r *AgentOfferRepository) Update(ctx context.Context, id int64, o *offer.Ag
 		SET name = $1, description = $2, type = $3, amount = $4, units = $5,
 		    price = $6, discount_percentage = $7, validity_days = $8, validity_label = $9,
 		    ussd_code_template = $10, ussd_processing_type = $11, ussd_expected_response = $12, ussd_error_pattern = $13,
-		    is_featured = $14, is_recurring = $15, max_purchases_per_customer = $16,
-		    available_from = $17, available_until = $18, tags = $19, metadata = $20, updated_at = $21
-		WHERE id = $22 AND deleted_at IS NULL
+		    is_featured = $14, is_recurring = $15, max_purchases_per_customer = $16, purchase_limit_period = $17,
+		    available_from = $18, available_until = $19, tags = $20, metadata = $21, updated_at = $22,
+		    purchase_cooldown_seconds = $23, required_device_id = $24, require_confirmation = $25
+		WHERE id = $26 AND deleted_at IS NULL
 	`
 
 	var metadataJSON []byte
@@ -240,8 +310,8 @@ func (r *AgentOfferRepository) Update(ctx context.Context, id int64, o *offer.Ag
 		o.Name, o.Description, o.Type, o.Amount, o.Units,
 		o.Price, o.DiscountPercentage, o.ValidityDays, o.ValidityLabel,
 		o.USSDCodeTemplate, o.USSDProcessingType, o.USSDExpectedResponse, o.USSDErrorPattern,
-		o.IsFeatured, o.IsRecurring, o.MaxPurchasesPerCustomer,
-		o.AvailableFrom, o.AvailableUntil, o.Tags, metadataJSON, time.Now(), id,
+		o.IsFeatured, o.IsRecurring, o.MaxPurchasesPerCustomer, o.PurchaseLimitPeriod,
+		o.AvailableFrom, o.AvailableUntil, o.Tags, metadataJSON, time.Now(), o.PurchaseCooldownSeconds, o.RequiredDeviceID, o.RequireConfirmation, id,
 	)
 
 	if err != nil {
@@ -271,6 +341,135 @@ func (r *AgentOfferRepository) UpdateStatus(ctx context.Context, id int64, statu
 	return nil
 }
 
+// TakeStockWithTx takes one unit from a stock-limited offer within a transaction.
+// It returns ErrConflict when the offer is sold out; unlimited offers are left alone.
+func (r *AgentOfferRepository) TakeStockWithTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	query := `
+		UPDATE agent_offers
+		SET stock_limit = stock_limit - 1
+		WHERE id = $1 AND (stock_limit IS NULL OR stock_limit > 0)
+	`
+
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to take offer stock: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: offer is sold out", xerrors.ErrConflict)
+	}
+
+	return nil
+}
+
+// AddStock adds units to a stock-limited offer and returns the stock before and after.
+// It returns ErrNotFound when the offer is missing or has unlimited stock.
+func (r *AgentOfferRepository) AddStock(ctx context.Context, id int64, amount int32) (int32, int32, error) {
+	query := `
+		UPDATE agent_offers
+		SET stock_limit = stock_limit + $1, updated_at = NOW()
+		WHERE id = $2 AND stock_limit IS NOT NULL AND deleted_at IS NULL
+		RETURNING stock_limit - $1, stock_limit
+	`
+
+	var previous, current int32
+	if err := r.db.QueryRow(ctx, query, amount, id).Scan(&previous, &current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, xerrors.ErrNotFound
+		}
+		return 0, 0, fmt.Errorf("failed to add offer stock: %w", err)
+	}
+
+	return previous, current, nil
+}
+
+// TransferOwnership reassigns an offer to another agent under a new offer code.
+// USSD codes follow the offer through offer_id; redemptions keep their original agent.
+func (r *AgentOfferRepository) TransferOwnership(ctx context.Context, id, toAgentID int64, offerCode string) error {
+	query := `
+		UPDATE agent_offers
+		SET agent_identity_id = $1, offer_code = $2, updated_at = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, toAgentID, offerCode, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to transfer offer: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// RenameTagWithTx replaces a tag on all of an agent's offers, dropping it where the new tag already exists
+func (r *AgentOfferRepository) RenameTagWithTx(ctx context.Context, tx pgx.Tx, agentID int64, oldTag, newTag string) (int64, error) {
+	query := `
+		UPDATE agent_offers
+		SET tags = CASE
+		        WHEN $3 = ANY(tags) THEN array_remove(tags, $2)
+		        ELSE array_replace(tags, $2, $3)
+		    END,
+		    updated_at = $4
+		WHERE agent_identity_id = $1 AND $2 = ANY(tags) AND deleted_at IS NULL
+	`
+
+	result, err := tx.Exec(ctx, query, agentID, oldTag, newTag, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename offer tag: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// AddTagByIDs appends tag to the agent's offers among ids that don't already have it, returning the updated IDs
+func (r *AgentOfferRepository) AddTagByIDs(ctx context.Context, agentID int64, ids []int64, tag string) ([]int64, error) {
+	query := `
+		UPDATE agent_offers
+		SET tags = array_append(COALESCE(tags, '{}'), $3), updated_at = NOW()
+		WHERE agent_identity_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		  AND NOT ($3 = ANY(COALESCE(tags, '{}')))
+		RETURNING id
+	`
+
+	return r.updateTagsReturningIDs(ctx, query, agentID, ids, tag)
+}
+
+// RemoveTagByIDs removes tag from the agent's offers among ids that have it, returning the updated IDs
+func (r *AgentOfferRepository) RemoveTagByIDs(ctx context.Context, agentID int64, ids []int64, tag string) ([]int64, error) {
+	query := `
+		UPDATE agent_offers
+		SET tags = array_remove(tags, $3), updated_at = NOW()
+		WHERE agent_identity_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		  AND $3 = ANY(tags)
+		RETURNING id
+	`
+
+	return r.updateTagsReturningIDs(ctx, query, agentID, ids, tag)
+}
+
+// updateTagsReturningIDs runs a bulk tag update and collects the returned offer IDs
+func (r *AgentOfferRepository) updateTagsReturningIDs(ctx context.Context, query string, agentID int64, ids []int64, tag string) ([]int64, error) {
+	rows, err := r.db.Query(ctx, query, agentID, ids, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update offer tags: %w", err)
+	}
+	defer rows.Close()
+
+	updated := make([]int64, 0, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan updated offer id: %w", err)
+		}
+		updated = append(updated, id)
+	}
+
+	return updated, rows.Err()
+}
+
 // SoftDelete soft deletes an offer
 func (r *AgentOfferRepository) SoftDelete(ctx context.Context, id int64) error {
 	query := `UPDATE agent_offers SET deleted_at = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
@@ -287,8 +486,147 @@ func (r *AgentOfferRepository) SoftDelete(ctx context.Context, id int64) error {
 	return nil
 }
 
-// List retrieves offers with filters (now loads primary USSD codes)
-func (r *AgentOfferRepository) List(ctx context.Context, agentID int64, filters *offer.OfferListFilters) ([]offer.AgentOffer, int64, error) {
+// SoftDeleteByIDs soft deletes the agent's live offers among ids and returns the IDs actually deleted
+func (r *AgentOfferRepository) SoftDeleteByIDs(ctx context.Context, agentID int64, ids []int64) ([]int64, error) {
+	query := `
+		UPDATE agent_offers SET deleted_at = NOW(), updated_at = NOW()
+		WHERE agent_identity_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		RETURNING id
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete offers: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make([]int64, 0, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted offer id: %w", err)
+		}
+		deleted = append(deleted, id)
+	}
+
+	return deleted, rows.Err()
+}
+
+// FindMatchingForUpdateWithTx locks and returns all of an agent's offers matching the filters
+func (r *AgentOfferRepository) FindMatchingForUpdateWithTx(ctx context.Context, tx pgx.Tx, agentID int64, filters *offer.OfferListFilters) ([]offer.AgentOffer, error) {
+	whereClause, args, _ := offerFilterConditions(agentID, filters)
+
+	query := fmt.Sprintf(`
+		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
+		       price, currency, discount_percentage, validity_days, validity_label,
+		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
+		       status, available_from, available_until, tags, metadata,
+		       stock_limit, created_at, updated_at, deleted_at
+		FROM agent_offers
+		WHERE %s
+		ORDER BY id
+		FOR UPDATE
+	`, whereClause)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find matching offers: %w", err)
+	}
+	defer rows.Close()
+
+	offers := []offer.AgentOffer{}
+	for rows.Next() {
+		o, err := r.scanOfferRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, *o)
+	}
+
+	return offers, rows.Err()
+}
+
+// UpdatePriceWithTx sets an offer's base price within a transaction
+func (r *AgentOfferRepository) UpdatePriceWithTx(ctx context.Context, tx pgx.Tx, id int64, price float64) error {
+	query := `UPDATE agent_offers SET price = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := tx.Exec(ctx, query, price, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update offer price: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// CreatePriceHistory records a change to an offer's base price
+func (r *AgentOfferRepository) CreatePriceHistory(ctx context.Context, change *offer.OfferPriceChange) error {
+	query := `
+		INSERT INTO offer_price_history (offer_id, agent_identity_id, old_price, new_price, source)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, change.OfferID, change.AgentIdentityID, change.OldPrice, change.NewPrice, change.Source).
+		Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record price history: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePriceHistoryWithTx records a change to an offer's base price within a transaction
+func (r *AgentOfferRepository) CreatePriceHistoryWithTx(ctx context.Context, tx pgx.Tx, change *offer.OfferPriceChange) error {
+	query := `
+		INSERT INTO offer_price_history (offer_id, agent_identity_id, old_price, new_price, source)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(ctx, query, change.OfferID, change.AgentIdentityID, change.OldPrice, change.NewPrice, change.Source).
+		Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record price history: %w", err)
+	}
+
+	return nil
+}
+
+// ListPriceHistory retrieves an offer's price changes, newest first
+func (r *AgentOfferRepository) ListPriceHistory(ctx context.Context, offerID int64, limit int) ([]offer.OfferPriceChange, error) {
+	query := `
+		SELECT id, offer_id, agent_identity_id, old_price, new_price, source, created_at
+		FROM offer_price_history
+		WHERE offer_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, offerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list price history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []offer.OfferPriceChange{}
+	for rows.Next() {
+		var c offer.OfferPriceChange
+		if err := rows.Scan(&c.ID, &c.OfferID, &c.AgentIdentityID, &c.OldPrice, &c.NewPrice, &c.Source, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// offerFilterConditions builds the WHERE clause and args shared by offer listing and facets
+func offerFilterConditions(agentID int64, filters *offer.OfferListFilters) (string, []interface{}, int) {
 	// Build WHERE clause
 	conditions := []string{"agent_identity_id = $1", "deleted_at IS NULL"}
 	args := []interface{}{agentID}
@@ -339,45 +677,97 @@ func (r *AgentOfferRepository) List(ctx context.Context, agentID int64, filters
 		argPos++
 	}
 
-	whereClause := strings.Join(conditions, " AND ")
+	return strings.Join(conditions, " AND "), args, argPos
+}
 
-	// Count total
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM agent_offers WHERE %s", whereClause)
-	var total int64
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+// GetSearchFacets counts offers per type, status and price bucket for a filter set in one aggregation
+func (r *AgentOfferRepository) GetSearchFacets(ctx context.Context, agentID int64, filters *offer.OfferListFilters) (*offer.OfferSearchFacets, error) {
+	whereClause, args, _ := offerFilterConditions(agentID, filters)
+
+	bucketCases := make([]string, 0, len(offer.OfferPriceBuckets))
+	for i, bucket := range offer.OfferPriceBuckets {
+		if bucket.Max > 0 {
+			bucketCases = append(bucketCases, fmt.Sprintf("WHEN price >= %g AND price < %g THEN %d", bucket.Min, bucket.Max, i))
+		} else {
+			bucketCases = append(bucketCases, fmt.Sprintf("WHEN price >= %g THEN %d", bucket.Min, i))
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT GROUPING(type), GROUPING(status), GROUPING(bucket),
+		       type::text, status::text, bucket, COUNT(*)
+		FROM (
+			SELECT type, status, CASE %s END AS bucket
+			FROM agent_offers
+			WHERE %s
+		) filtered
+		GROUP BY GROUPING SETS ((type), (status), (bucket), ())
+	`, strings.Join(bucketCases, " "), whereClause)
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count offers: %w", err)
+		return nil, fmt.Errorf("failed to get offer facets: %w", err)
 	}
+	defer rows.Close()
 
-	// Pagination
-	if filters.Page < 1 {
-		filters.Page = 1
+	facets := &offer.OfferSearchFacets{
+		Types:        map[offer.OfferType]int64{},
+		Statuses:     map[offer.OfferStatus]int64{},
+		PriceBuckets: make([]offer.PriceBucketCount, len(offer.OfferPriceBuckets)),
 	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
+	for i, bucket := range offer.OfferPriceBuckets {
+		facets.PriceBuckets[i] = offer.PriceBucketCount{PriceBucket: bucket}
 	}
 
-	offset := (filters.Page - 1) * filters.PageSize
-	limit := filters.PageSize
+	for rows.Next() {
+		var groupedType, groupedStatus, groupedBucket int
+		var offerType, status sql.NullString
+		var bucket sql.NullInt32
+		var count int64
 
-	// Sorting
-	sortBy := "created_at"
-	if filters.SortBy != "" {
-		sortBy = filters.SortBy
+		if err := rows.Scan(&groupedType, &groupedStatus, &groupedBucket, &offerType, &status, &bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan offer facet: %w", err)
+		}
+
+		switch {
+		case groupedType == 0:
+			facets.Types[offer.OfferType(offerType.String)] = count
+		case groupedStatus == 0:
+			facets.Statuses[offer.OfferStatus(status.String)] = count
+		case groupedBucket == 0:
+			if bucket.Valid && int(bucket.Int32) < len(facets.PriceBuckets) {
+				facets.PriceBuckets[bucket.Int32].Count = count
+			}
+		default:
+			facets.Total = count
+		}
 	}
-	sortOrder := "DESC"
-	if filters.SortOrder != "" {
-		sortOrder = strings.ToUpper(filters.SortOrder)
+
+	return facets, rows.Err()
+}
+
+// List retrieves offers with filters (now loads primary USSD codes)
+func (r *AgentOfferRepository) List(ctx context.Context, agentID int64, filters *offer.OfferListFilters) ([]offer.AgentOffer, int64, error) {
+	whereClause, args, argPos := offerFilterConditions(agentID, filters)
+
+	// Count total
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM agent_offers WHERE %s", whereClause)
+	var total int64
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count offers: %w", err)
 	}
 
+	sortBy, sortOrder, limit, offset := offerListPaging(filters)
+
 	// Query offers
 	query := fmt.Sprintf(`
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE %s
 		ORDER BY %s %s
@@ -412,8 +802,80 @@ func (r *AgentOfferRepository) List(ctx context.Context, agentID int64, filters
 	return offers, total, nil
 }
 
-// GetStats retrieves offer statistics for an agent
-func (r *AgentOfferRepository) GetStats(ctx context.Context, agentID int64) (*offer.OfferStats, error) {
+// ListSummaries retrieves offers in the summary projection. Only catalogue columns are read and
+// USSD codes aren't loaded, so a page costs two queries regardless of its size.
+func (r *AgentOfferRepository) ListSummaries(ctx context.Context, agentID int64, filters *offer.OfferListFilters) ([]offer.OfferSummary, int64, error) {
+	whereClause, args, argPos := offerFilterConditions(agentID, filters)
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM agent_offers WHERE %s", whereClause)
+	var total int64
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count offers: %w", err)
+	}
+
+	sortBy, sortOrder, limit, offset := offerListPaging(filters)
+
+	query := fmt.Sprintf(`
+		SELECT id, offer_code, name, type, amount, units, price, currency, discount_percentage,
+		       validity_days, COALESCE(validity_label, ''), is_featured, status, tags
+		FROM agent_offers
+		WHERE %s
+		ORDER BY %s %s
+		LIMIT $%d OFFSET $%d
+	`, whereClause, sortBy, sortOrder, argPos, argPos+1)
+
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list offers: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []offer.OfferSummary{}
+	for rows.Next() {
+		var s offer.OfferSummary
+		if err := rows.Scan(
+			&s.ID, &s.OfferCode, &s.Name, &s.Type, &s.Amount, &s.Units, &s.Price, &s.Currency, &s.DiscountPercentage,
+			&s.ValidityDays, &s.ValidityLabel, &s.IsFeatured, &s.Status, &s.Tags,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan offer summary: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, total, rows.Err()
+}
+
+// offerListPaging resolves the sort column, direction, limit and offset for an offer list
+func offerListPaging(filters *offer.OfferListFilters) (string, string, int, int) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 {
+		filters.PageSize = 20
+	}
+
+	sortBy := "created_at"
+	if filters.SortBy != "" {
+		sortBy = filters.SortBy
+	}
+	sortOrder := "DESC"
+	if filters.SortOrder != "" {
+		sortOrder = strings.ToUpper(filters.SortOrder)
+	}
+
+	return sortBy, sortOrder, filters.PageSize, (filters.Page - 1) * filters.PageSize
+}
+
+// GetStats retrieves offer statistics for an agent. With a date range, offer counts cover
+// offers created in the range and revenue covers successful redemptions in the range.
+func (r *AgentOfferRepository) GetStats(ctx context.Context, agentID int64, filters *offer.StatsFilters) (*offer.OfferStats, error) {
+	var from, to *time.Time
+	if filters != nil {
+		from, to = filters.From, filters.To
+	}
+
 	query := `
 		SELECT 
 			COUNT(*) as total,
@@ -422,10 +884,12 @@ func (r *AgentOfferRepository) GetStats(ctx context.Context, agentID int64) (*of
 			COALESCE(AVG(price), 0) as avg_price
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND deleted_at IS NULL
+		  AND ($2::timestamptz IS NULL OR created_at >= $2)
+		  AND ($3::timestamptz IS NULL OR created_at <= $3)
 	`
 
 	var stats offer.OfferStats
-	err := r.db.QueryRow(ctx, query, agentID).Scan(
+	err := r.db.QueryRow(ctx, query, agentID, from, to).Scan(
 		&stats.TotalOffers,
 		&stats.ActiveOffers,
 		&stats.FeaturedOffers,
@@ -436,12 +900,85 @@ func (r *AgentOfferRepository) GetStats(ctx context.Context, agentID int64) (*of
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
 
-	// TODO: Calculate total revenue from offer_redemptions table when implemented
-	stats.TotalRevenue = 0
+	revenueQuery := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM offer_redemptions
+		WHERE agent_identity_id = $1 AND status = 'success'
+		  AND ($2::timestamptz IS NULL OR redemption_time >= $2)
+		  AND ($3::timestamptz IS NULL OR redemption_time <= $3)
+	`
+	if err := r.db.QueryRow(ctx, revenueQuery, agentID, from, to).Scan(&stats.TotalRevenue); err != nil {
+		return nil, fmt.Errorf("failed to get revenue: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// GetPeriodStats sums an agent's successful redemptions in [from, to)
+func (r *AgentOfferRepository) GetPeriodStats(ctx context.Context, agentID int64, from, to time.Time) (*offer.PeriodStats, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0), COUNT(*), COUNT(DISTINCT offer_id)
+		FROM offer_redemptions
+		WHERE agent_identity_id = $1 AND status = 'success'
+		  AND redemption_time >= $2 AND redemption_time < $3
+	`
+
+	stats := offer.PeriodStats{From: from, To: to}
+	if err := r.db.QueryRow(ctx, query, agentID, from, to).Scan(&stats.Revenue, &stats.Redemptions, &stats.ActiveOffers); err != nil {
+		return nil, fmt.Errorf("failed to get period stats: %w", err)
+	}
 
 	return &stats, nil
 }
 
+// CountByAgent counts an agent's offers, excluding deleted ones
+func (r *AgentOfferRepository) CountByAgent(ctx context.Context, agentID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM agent_offers WHERE agent_identity_id = $1 AND deleted_at IS NULL`
+	var count int
+	if err := r.db.QueryRow(ctx, query, agentID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count offers: %w", err)
+	}
+	return count, nil
+}
+
+// CountFeatured counts an agent's featured offers
+func (r *AgentOfferRepository) CountFeatured(ctx context.Context, agentID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM agent_offers WHERE agent_identity_id = $1 AND is_featured = TRUE AND deleted_at IS NULL`
+	var count int
+	if err := r.db.QueryRow(ctx, query, agentID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count featured offers: %w", err)
+	}
+	return count, nil
+}
+
+// CountCustomerPurchases counts a customer's non-failed redemptions of an offer, optionally since a given time
+func (r *AgentOfferRepository) CountCustomerPurchases(ctx context.Context, offerID, customerID int64, since *time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM offer_redemptions
+		WHERE offer_id = $1 AND customer_id = $2
+		  AND status NOT IN ('failed', 'cancelled', 'reversed')
+		  AND ($3::timestamptz IS NULL OR redemption_time >= $3)
+	`
+	var count int
+	if err := r.db.QueryRow(ctx, query, offerID, customerID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count customer purchases: %w", err)
+	}
+	return count, nil
+}
+
+// LastCustomerPurchaseAt returns when the customer last successfully redeemed the offer, or nil if never
+func (r *AgentOfferRepository) LastCustomerPurchaseAt(ctx context.Context, offerID, customerID int64) (*time.Time, error) {
+	query := `
+		SELECT MAX(redemption_time) FROM offer_redemptions
+		WHERE offer_id = $1 AND customer_id = $2 AND status = 'success'
+	`
+	var last *time.Time
+	if err := r.db.QueryRow(ctx, query, offerID, customerID).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to get last customer purchase: %w", err)
+	}
+	return last, nil
+}
+
 // ExistsByOfferCode checks if offer code exists
 func (r *AgentOfferRepository) ExistsByOfferCode(ctx context.Context, offerCode string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM agent_offers WHERE offer_code = $1 AND deleted_at IS NULL)`
@@ -456,9 +993,9 @@ func (r *AgentOfferRepository) GetFeaturedOffers(ctx context.Context, agentID in
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND is_featured = TRUE AND status = 'active' AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -491,15 +1028,55 @@ func (r *AgentOfferRepository) GetFeaturedOffers(ctx context.Context, agentID in
 	return offers, nil
 }
 
+// FindActiveByAgent retrieves all of an agent's active offers (with primary USSD codes)
+func (r *AgentOfferRepository) FindActiveByAgent(ctx context.Context, agentID int64) ([]offer.AgentOffer, error) {
+	query := `
+		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
+		       price, currency, discount_percentage, validity_days, validity_label,
+		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
+		       status, available_from, available_until, tags, metadata,
+		       stock_limit, created_at, updated_at, deleted_at
+		FROM agent_offers
+		WHERE agent_identity_id = $1 AND status = 'active' AND deleted_at IS NULL
+		ORDER BY price ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active offers: %w", err)
+	}
+	defer rows.Close()
+
+	offers := []offer.AgentOffer{}
+	for rows.Next() {
+		o, err := r.scanOfferRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan active offer: %w", err)
+		}
+		offers = append(offers, *o)
+	}
+
+	// Load primary USSD codes for all offers
+	for i := range offers {
+		if err := r.loadPrimaryUSSDCode(ctx, &offers[i]); err != nil {
+			// Log but don't fail
+			continue
+		}
+	}
+
+	return offers, nil
+}
+
 // FindByAmount retrieves offers by amount (exact match or range)
 func (r *AgentOfferRepository) FindByAmount(ctx context.Context, agentID int64, amount float64) ([]offer.AgentOffer, error) {
 	query := `
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND amount = $2 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -537,9 +1114,9 @@ func (r *AgentOfferRepository) FindByAmountRange(ctx context.Context, agentID in
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND amount >= $2 AND amount <= $3 AND deleted_at IS NULL
 		ORDER BY amount ASC, created_at DESC
@@ -577,9 +1154,9 @@ func (r *AgentOfferRepository) FindByTypeAndAmount(ctx context.Context, agentID
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND type = $2 AND amount = $3 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -620,9 +1197,9 @@ func (r *AgentOfferRepository) FindByPrice(ctx context.Context, agentID int64, p
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND price = $2 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -653,9 +1230,9 @@ func (r *AgentOfferRepository) FindByPriceAndType(ctx context.Context, agentID i
 		SELECT id, agent_identity_id, offer_code, name, description, type, amount, units,
 		       price, currency, discount_percentage, validity_days, validity_label,
 		       ussd_code_template, ussd_processing_type, ussd_expected_response, ussd_error_pattern,
-		       is_featured, is_recurring, max_purchases_per_customer,
+		       is_featured, is_recurring, max_purchases_per_customer, purchase_limit_period, purchase_cooldown_seconds, required_device_id, require_confirmation,
 		       status, available_from, available_until, tags, metadata,
-		       created_at, updated_at, deleted_at
+		       stock_limit, created_at, updated_at, deleted_at
 		FROM agent_offers
 		WHERE agent_identity_id = $1 AND price = $2 AND type = $3 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -17,6 +18,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// subscriptionReferenceKey keeps subscription references unique
+const subscriptionReferenceKey = "agent_subscriptions_subscription_reference_key"
+
 type AgentSubscriptionRepository struct {
 	db *pgxpool.Pool
 }
@@ -32,9 +36,9 @@ func (r *AgentSubscriptionRepository) CreateWithTx(ctx context.Context, tx pgx.T
 			subscription_reference, agent_identity_id, subscription_plan_id, promotional_campaign_id,
 			start_date, end_date, current_period_start, current_period_end,
 			auto_renew, next_billing_date, requests_limit,
-			plan_price, discount_applied, amount_paid, currency,
+			plan_price, plan_version, discount_applied, amount_paid, currency,
 			status, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -53,11 +57,15 @@ func (r *AgentSubscriptionRepository) CreateWithTx(ctx context.Context, tx pgx.T
 		sub.SubscriptionReference, sub.AgentIdentityID, sub.SubscriptionPlanID, sub.PromotionalCampaignID,
 		sub.StartDate, sub.EndDate, sub.CurrentPeriodStart, sub.CurrentPeriodEnd,
 		sub.AutoRenew, sub.NextBillingDate, sub.RequestsLimit,
-		sub.PlanPrice, sub.DiscountApplied, sub.AmountPaid, sub.Currency,
+		sub.PlanPrice, sub.PlanVersion, sub.DiscountApplied, sub.AmountPaid, sub.Currency,
 		sub.Status, metadataJSON,
 	).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
 
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == subscriptionReferenceKey {
+			return fmt.Errorf("%w: subscription reference %s", xerrors.ErrDuplicateEntry, sub.SubscriptionReference)
+		}
 		return fmt.Errorf("failed to create subscription: %w", err)
 	}
 
@@ -71,7 +79,7 @@ func (r *AgentSubscriptionRepository) FindByID(ctx context.Context, id int64) (*
 		       start_date, end_date, current_period_start, current_period_end,
 		       auto_renew, renewal_count, next_billing_date,
 		       requests_used, requests_limit,
-		       plan_price, discount_applied, amount_paid, currency,
+		       plan_price, plan_version, discount_applied, amount_paid, currency,
 		       status, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM agent_subscriptions
@@ -86,7 +94,7 @@ func (r *AgentSubscriptionRepository) FindByID(ctx context.Context, id int64) (*
 		&sub.StartDate, &sub.EndDate, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
 		&sub.AutoRenew, &sub.RenewalCount, &sub.NextBillingDate,
 		&sub.RequestsUsed, &sub.RequestsLimit,
-		&sub.PlanPrice, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
+		&sub.PlanPrice, &sub.PlanVersion, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
 		&sub.Status, &sub.CancelledAt, &sub.CancellationReason,
 		&metadataJSON, &sub.CreatedAt, &sub.UpdatedAt,
 	)
@@ -112,7 +120,7 @@ func (r *AgentSubscriptionRepository) FindActiveByAgent(ctx context.Context, age
 		       start_date, end_date, current_period_start, current_period_end,
 		       auto_renew, renewal_count, next_billing_date,
 		       requests_used, requests_limit,
-		       plan_price, discount_applied, amount_paid, currency,
+		       plan_price, plan_version, discount_applied, amount_paid, currency,
 		       status, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM agent_subscriptions
@@ -129,12 +137,12 @@ func (r *AgentSubscriptionRepository) FindActiveByAgent(ctx context.Context, age
 		&sub.StartDate, &sub.EndDate, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
 		&sub.AutoRenew, &sub.RenewalCount, &sub.NextBillingDate,
 		&sub.RequestsUsed, &sub.RequestsLimit,
-		&sub.PlanPrice, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
+		&sub.PlanPrice, &sub.PlanVersion, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
 		&sub.Status, &sub.CancelledAt, &sub.CancellationReason,
 		&metadataJSON, &sub.CreatedAt, &sub.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if err == sql.ErrNoRows || err == pgx.ErrNoRows {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -178,6 +186,31 @@ func (r *AgentSubscriptionRepository) Update(ctx context.Context, id int64, sub
 	return nil
 }
 
+// MergeMetadata merges the given keys into a subscription's metadata
+func (r *AgentSubscriptionRepository) MergeMetadata(ctx context.Context, id int64, values map[string]interface{}) error {
+	query := `
+		UPDATE agent_subscriptions
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || $1::jsonb, updated_at = $2
+		WHERE id = $3
+	`
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, query, valuesJSON, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to merge metadata: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
 // UpdateStatusWithTx updates status within a transaction
 func (r *AgentSubscriptionRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.Tx, id int64, status subscription.SubscriptionStatus) error {
 	query := `UPDATE agent_subscriptions SET status = $1, updated_at = $2 WHERE id = $3`
@@ -194,6 +227,26 @@ func (r *AgentSubscriptionRepository) UpdateStatusWithTx(ctx context.Context, tx
 	return nil
 }
 
+// LockForRenewalWithTx takes a transaction-scoped advisory lock on a subscription and returns its current renewal count.
+// The lock is keyed by a class for agent_subscriptions plus the ID, so it cannot collide with single-key locks
+// such as the sub-agent link lock; IDs past the int4 range wrap, which only ever over-serializes.
+func (r *AgentSubscriptionRepository) LockForRenewalWithTx(ctx context.Context, tx pgx.Tx, id int64) (int, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('agent_subscriptions'), mod($1::bigint, 2147483648)::int)`, id); err != nil {
+		return 0, fmt.Errorf("failed to lock subscription: %w", err)
+	}
+
+	var renewalCount int
+	err := tx.QueryRow(ctx, `SELECT renewal_count FROM agent_subscriptions WHERE id = $1`, id).Scan(&renewalCount)
+	if err == pgx.ErrNoRows {
+		return 0, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get renewal count: %w", err)
+	}
+
+	return renewalCount, nil
+}
+
 // UpdateRenewalInfoWithTx updates renewal information
 func (r *AgentSubscriptionRepository) UpdateRenewalInfoWithTx(ctx context.Context, tx pgx.Tx, id int64, periodStart, periodEnd, nextBilling time.Time, renewalCount int) error {
 	query := `
@@ -221,13 +274,41 @@ func (r *AgentSubscriptionRepository) UpdateRenewalInfoWithTx(ctx context.Contex
 	return nil
 }
 
-// IncrementRequestUsage increments request usage counter
-func (r *AgentSubscriptionRepository) IncrementRequestUsage(ctx context.Context, id int64) error {
-	query := `UPDATE agent_subscriptions SET requests_used = requests_used + 1, updated_at = $1 WHERE id = $2`
+// UpdatePlanPricingWithTx moves a subscription onto a newer version of its plan's price
+func (r *AgentSubscriptionRepository) UpdatePlanPricingWithTx(ctx context.Context, tx pgx.Tx, id int64, planPrice float64, planVersion int) error {
+	query := `UPDATE agent_subscriptions SET plan_price = $1, plan_version = $2, updated_at = $3 WHERE id = $4`
+
+	result, err := tx.Exec(ctx, query, planPrice, planVersion, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update plan pricing: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// ChangePlanWithTx switches a subscription to another plan, optionally resetting usage
+func (r *AgentSubscriptionRepository) ChangePlanWithTx(ctx context.Context, tx pgx.Tx, id, planID int64, planPrice float64, planVersion, requestsLimit int, resetUsage bool) error {
+	query := `
+		UPDATE agent_subscriptions
+		SET subscription_plan_id = $1, plan_price = $2, plan_version = $3, requests_limit = $4,
+		    requests_used = CASE WHEN $5 THEN 0 ELSE requests_used END,
+		    updated_at = $6
+		WHERE id = $7
+	`
+
+	result, err := tx.Exec(
+		ctx, query,
+		planID, planPrice, planVersion,
+		sql.NullInt32{Int32: int32(requestsLimit), Valid: true},
+		resetUsage, time.Now(), id,
+	)
 
-	result, err := r.db.Exec(ctx, query, time.Now(), id)
 	if err != nil {
-		return fmt.Errorf("failed to increment request usage: %w", err)
+		return fmt.Errorf("failed to change plan: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
@@ -237,8 +318,69 @@ func (r *AgentSubscriptionRepository) IncrementRequestUsage(ctx context.Context,
 	return nil
 }
 
-// CancelSubscription cancels a subscription
-func (r *AgentSubscriptionRepository) CancelSubscription(ctx context.Context, id int64, reason string, immediately bool) error {
+// IncrementRequestUsage increments request usage counter
+func (r *AgentSubscriptionRepository) IncrementRequestUsage(ctx context.Context, id int64) (int, error) {
+	query := `UPDATE agent_subscriptions SET requests_used = requests_used + 1, updated_at = $1 WHERE id = $2 RETURNING requests_used`
+
+	var used int
+	if err := r.db.QueryRow(ctx, query, time.Now(), id).Scan(&used); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, xerrors.ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to increment request usage: %w", err)
+	}
+
+	return used, nil
+}
+
+// ClaimRenewalReminder records that a renewal reminder is being sent for the period identified by
+// periodKey, reporting false when one was already claimed for that period
+func (r *AgentSubscriptionRepository) ClaimRenewalReminder(ctx context.Context, id int64, periodKey string) (bool, error) {
+	query := `
+		UPDATE agent_subscriptions
+		SET metadata = COALESCE(metadata, '{}'::jsonb)
+		        || jsonb_build_object('renewal_reminder_sent_for', $2::text, 'renewal_reminder_sent_at', $3::text)
+		WHERE id = $1 AND metadata->>'renewal_reminder_sent_for' IS DISTINCT FROM $2
+	`
+
+	result, err := r.db.Exec(ctx, query, id, periodKey, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim renewal reminder: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ReleaseRenewalReminder drops a renewal reminder claim for the period so a later run can retry it
+func (r *AgentSubscriptionRepository) ReleaseRenewalReminder(ctx context.Context, id int64, periodKey string) error {
+	query := `
+		UPDATE agent_subscriptions
+		SET metadata = metadata - 'renewal_reminder_sent_for' - 'renewal_reminder_sent_at'
+		WHERE id = $1 AND metadata->>'renewal_reminder_sent_for' = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, periodKey); err != nil {
+		return fmt.Errorf("failed to release renewal reminder: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimUsageAlert raises a subscription's usage alert level to level, reporting false when
+// an alert at that level or higher was already sent this period
+func (r *AgentSubscriptionRepository) ClaimUsageAlert(ctx context.Context, id int64, level int) (bool, error) {
+	query := `UPDATE agent_subscriptions SET usage_alert_level = $2 WHERE id = $1 AND usage_alert_level < $2`
+
+	result, err := r.db.Exec(ctx, query, id, level)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim usage alert: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CancelSubscriptionWithTx cancels a subscription within a transaction
+func (r *AgentSubscriptionRepository) CancelSubscriptionWithTx(ctx context.Context, tx pgx.Tx, id int64, reason string, immediately bool) error {
 	var query string
 
 	if immediately {
@@ -263,14 +405,14 @@ func (r *AgentSubscriptionRepository) CancelSubscription(ctx context.Context, id
 
 	var result pgconn.CommandTag
 	if immediately {
-		result, err = r.db.Exec(
+		result, err = tx.Exec(
 			ctx, query,
 			subscription.SubscriptionStatusCancelled, now,
 			sql.NullString{String: reason, Valid: reason != ""},
 			now, id,
 		)
 	} else {
-		result, err = r.db.Exec(
+		result, err = tx.Exec(
 			ctx, query,
 			now,
 			sql.NullString{String: reason, Valid: reason != ""},
@@ -358,7 +500,7 @@ func (r *AgentSubscriptionRepository) List(ctx context.Context, agentID int64, f
 		       start_date, end_date, current_period_start, current_period_end,
 		       auto_renew, renewal_count, next_billing_date,
 		       requests_used, requests_limit,
-		       plan_price, discount_applied, amount_paid, currency,
+		       plan_price, plan_version, discount_applied, amount_paid, currency,
 		       status, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM agent_subscriptions
@@ -385,7 +527,7 @@ func (r *AgentSubscriptionRepository) List(ctx context.Context, agentID int64, f
 			&sub.StartDate, &sub.EndDate, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
 			&sub.AutoRenew, &sub.RenewalCount, &sub.NextBillingDate,
 			&sub.RequestsUsed, &sub.RequestsLimit,
-			&sub.PlanPrice, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
+			&sub.PlanPrice, &sub.PlanVersion, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
 			&sub.Status, &sub.CancelledAt, &sub.CancellationReason,
 			&metadataJSON, &sub.CreatedAt, &sub.UpdatedAt,
 		)
@@ -434,6 +576,57 @@ func (r *AgentSubscriptionRepository) GetStats(ctx context.Context, agentID int6
 	return &stats, nil
 }
 
+// GetCancellationReasons aggregates cancellation reasons (agentID 0 aggregates across all agents)
+func (r *AgentSubscriptionRepository) GetCancellationReasons(ctx context.Context, agentID int64, dateFrom, dateTo *time.Time) ([]subscription.CancellationReasonStat, error) {
+	conditions := []string{"cancelled_at IS NOT NULL"}
+	args := []interface{}{}
+	argPos := 1
+
+	if agentID > 0 {
+		conditions = append(conditions, fmt.Sprintf("agent_identity_id = $%d", argPos))
+		args = append(args, agentID)
+		argPos++
+	}
+
+	if dateFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("cancelled_at >= $%d", argPos))
+		args = append(args, *dateFrom)
+		argPos++
+	}
+
+	if dateTo != nil {
+		conditions = append(conditions, fmt.Sprintf("cancelled_at <= $%d", argPos))
+		args = append(args, *dateTo)
+		argPos++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(LOWER(TRIM(cancellation_reason)), ''), 'unspecified') as reason,
+		       COUNT(*) as count
+		FROM agent_subscriptions
+		WHERE %s
+		GROUP BY 1
+		ORDER BY count DESC, reason ASC
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellation reasons: %w", err)
+	}
+	defer rows.Close()
+
+	reasons := []subscription.CancellationReasonStat{}
+	for rows.Next() {
+		var stat subscription.CancellationReasonStat
+		if err := rows.Scan(&stat.Reason, &stat.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan cancellation reason: %w", err)
+		}
+		reasons = append(reasons, stat)
+	}
+
+	return reasons, rows.Err()
+}
+
 // GetExpiringSubscriptions retrieves subscriptions expiring soon
 func (r *AgentSubscriptionRepository) GetExpiringSubscriptions(ctx context.Context, days int) ([]subscription.AgentSubscription, error) {
 	query := `
@@ -441,7 +634,7 @@ func (r *AgentSubscriptionRepository) GetExpiringSubscriptions(ctx context.Conte
 		       start_date, end_date, current_period_start, current_period_end,
 		       auto_renew, renewal_count, next_billing_date,
 		       requests_used, requests_limit,
-		       plan_price, discount_applied, amount_paid, currency,
+		       plan_price, plan_version, discount_applied, amount_paid, currency,
 		       status, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM agent_subscriptions
@@ -454,6 +647,37 @@ func (r *AgentSubscriptionRepository) GetExpiringSubscriptions(ctx context.Conte
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expiring subscriptions: %w", err)
 	}
+
+	return scanSubscriptionRows(rows)
+}
+
+// GetEndedPeriodSubscriptions retrieves active subscriptions whose current period has ended with usage
+// recorded and not yet settled
+func (r *AgentSubscriptionRepository) GetEndedPeriodSubscriptions(ctx context.Context) ([]subscription.AgentSubscription, error) {
+	query := `
+		SELECT id, subscription_reference, agent_identity_id, subscription_plan_id, promotional_campaign_id,
+		       start_date, end_date, current_period_start, current_period_end,
+		       auto_renew, renewal_count, next_billing_date,
+		       requests_used, requests_limit,
+		       plan_price, plan_version, discount_applied, amount_paid, currency,
+		       status, cancelled_at, cancellation_reason,
+		       metadata, created_at, updated_at
+		FROM agent_subscriptions
+		WHERE status = 'active' AND current_period_end <= NOW() AND requests_used > 0
+		  AND usage_settled_period_end IS DISTINCT FROM current_period_end
+		ORDER BY current_period_end ASC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ended period subscriptions: %w", err)
+	}
+
+	return scanSubscriptionRows(rows)
+}
+
+// scanSubscriptionRows scans and closes rows selected with the standard subscription column list
+func scanSubscriptionRows(rows pgx.Rows) ([]subscription.AgentSubscription, error) {
 	defer rows.Close()
 
 	subscriptions := []subscription.AgentSubscription{}
@@ -466,7 +690,7 @@ func (r *AgentSubscriptionRepository) GetExpiringSubscriptions(ctx context.Conte
 			&sub.StartDate, &sub.EndDate, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
 			&sub.AutoRenew, &sub.RenewalCount, &sub.NextBillingDate,
 			&sub.RequestsUsed, &sub.RequestsLimit,
-			&sub.PlanPrice, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
+			&sub.PlanPrice, &sub.PlanVersion, &sub.DiscountApplied, &sub.AmountPaid, &sub.Currency,
 			&sub.Status, &sub.CancelledAt, &sub.CancellationReason,
 			&metadataJSON, &sub.CreatedAt, &sub.UpdatedAt,
 		)
@@ -494,7 +718,7 @@ func (r *AgentSubscriptionRepository) ExistsBySubscriptionReference(ctx context.
 
 // ResetRequestUsage resets the request usage counter (for new billing cycle)
 func (r *AgentSubscriptionRepository) ResetRequestUsage(ctx context.Context, id int64) error {
-	query := `UPDATE agent_subscriptions SET requests_used = 0, updated_at = $1 WHERE id = $2`
+	query := `UPDATE agent_subscriptions SET requests_used = 0, usage_alert_level = 0, updated_at = $1 WHERE id = $2`
 
 	result, err := r.db.Exec(ctx, query, time.Now(), id)
 	if err != nil {
@@ -506,4 +730,281 @@ func (r *AgentSubscriptionRepository) ResetRequestUsage(ctx context.Context, id
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// CreateBillingRecordWithTx records a subscription charge within a transaction
+func (r *AgentSubscriptionRepository) CreateBillingRecordWithTx(ctx context.Context, tx pgx.Tx, rec *subscription.BillingRecord) error {
+	query := `
+		INSERT INTO subscription_billing_records (
+			subscription_id, agent_identity_id, event, subscription_plan_id,
+			period_start, period_end, plan_price, setup_fee, discount_applied, amount_paid,
+			currency, promotional_campaign_id, payment_reference,
+			overage_requests, overage_rate, amount_due
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx, query,
+		rec.SubscriptionID, rec.AgentIdentityID, rec.Event, rec.SubscriptionPlanID,
+		rec.PeriodStart, rec.PeriodEnd, rec.PlanPrice, rec.SetupFee, rec.DiscountApplied, rec.AmountPaid,
+		rec.Currency, rec.PromotionalCampaignID, rec.PaymentReference,
+		rec.OverageRequests, rec.OverageRate, rec.AmountDue,
+	).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create billing record: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOverageRecordWithTx records a period's overage charge within a transaction.
+// It returns false without writing if the period was already billed.
+func (r *AgentSubscriptionRepository) CreateOverageRecordWithTx(ctx context.Context, tx pgx.Tx, rec *subscription.BillingRecord) (bool, error) {
+	query := `
+		INSERT INTO subscription_billing_records (
+			subscription_id, agent_identity_id, event, subscription_plan_id,
+			period_start, period_end, plan_price, amount_paid, currency,
+			overage_requests, overage_rate, amount_due
+		) VALUES ($1, $2, 'overage', $3, $4, $5, 0, 0, $6, $7, $8, $9)
+		ON CONFLICT (subscription_id, period_end) WHERE event = 'overage' DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(
+		ctx, query,
+		rec.SubscriptionID, rec.AgentIdentityID, rec.SubscriptionPlanID,
+		rec.PeriodStart, rec.PeriodEnd, rec.Currency,
+		rec.OverageRequests, rec.OverageRate, rec.AmountDue,
+	).Scan(&rec.ID, &rec.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create overage record: %w", err)
+	}
+
+	rec.Event = subscription.BillingEventOverage
+	return true, nil
+}
+
+// GetPeriodUsageWithTx reads a subscription's request usage within a transaction and whether
+// its current period has already been settled
+func (r *AgentSubscriptionRepository) GetPeriodUsageWithTx(ctx context.Context, tx pgx.Tx, id int64) (int, bool, error) {
+	query := `
+		SELECT requests_used, usage_settled_period_end IS NOT DISTINCT FROM current_period_end
+		FROM agent_subscriptions
+		WHERE id = $1
+	`
+
+	var requestsUsed int
+	var settled bool
+	err := tx.QueryRow(ctx, query, id).Scan(&requestsUsed, &settled)
+	if err == pgx.ErrNoRows {
+		return 0, false, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get period usage: %w", err)
+	}
+
+	return requestsUsed, settled, nil
+}
+
+// ResetRequestUsageWithTx resets the request usage counter within a transaction and marks
+// the current period as settled
+func (r *AgentSubscriptionRepository) ResetRequestUsageWithTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	query := `
+		UPDATE agent_subscriptions
+		SET requests_used = 0, usage_alert_level = 0, usage_settled_period_end = current_period_end, updated_at = $1
+		WHERE id = $2
+	`
+
+	result, err := tx.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reset request usage: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListBillingRecords retrieves a subscription's charges in chronological order
+func (r *AgentSubscriptionRepository) ListBillingRecords(ctx context.Context, subscriptionID int64) ([]subscription.BillingRecord, error) {
+	query := `
+		SELECT id, subscription_id, agent_identity_id, event, subscription_plan_id,
+		       period_start, period_end, plan_price, setup_fee, discount_applied, amount_paid,
+		       currency, promotional_campaign_id, payment_reference,
+		       overage_requests, overage_rate, amount_due, created_at
+		FROM subscription_billing_records
+		WHERE subscription_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []subscription.BillingRecord{}
+	for rows.Next() {
+		var rec subscription.BillingRecord
+		if err := rows.Scan(
+			&rec.ID, &rec.SubscriptionID, &rec.AgentIdentityID, &rec.Event, &rec.SubscriptionPlanID,
+			&rec.PeriodStart, &rec.PeriodEnd, &rec.PlanPrice, &rec.SetupFee, &rec.DiscountApplied, &rec.AmountPaid,
+			&rec.Currency, &rec.PromotionalCampaignID, &rec.PaymentReference,
+			&rec.OverageRequests, &rec.OverageRate, &rec.AmountDue, &rec.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan billing record: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+const subAgentColumns = `id, parent_agent_id, child_agent_id, status, accepted_at, created_at`
+
+func scanSubAgent(row pgx.Row) (*subscription.SubAgent, error) {
+	var sa subscription.SubAgent
+	if err := row.Scan(&sa.ID, &sa.ParentAgentID, &sa.ChildAgentID, &sa.Status, &sa.AcceptedAt, &sa.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &sa, nil
+}
+
+// AddSubAgent links a child agent to a parent agent with the given status (active links are accepted immediately).
+// Returns ErrConflict if the child already has a parent, ErrNotFound if either agent doesn't exist and
+// ErrInvalidInput if the link would nest sub-agents more than one level deep.
+func (r *AgentSubscriptionRepository) AddSubAgent(ctx context.Context, parentID, childID int64, status subscription.SubAgentStatus) (*subscription.SubAgent, error) {
+	query := `
+		INSERT INTO agent_sub_agents (parent_agent_id, child_agent_id, status, accepted_at)
+		VALUES ($1, $2, $3, CASE WHEN $3 = 'active' THEN NOW() END)
+		RETURNING ` + subAgentColumns
+
+	sa, err := scanSubAgent(r.db.QueryRow(ctx, query, parentID, childID, status))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return nil, xerrors.ErrConflict
+			case "23503":
+				return nil, xerrors.ErrNotFound
+			case "23514":
+				return nil, fmt.Errorf("%s: %w", pgErr.Message, xerrors.ErrInvalidInput)
+			}
+		}
+		return nil, fmt.Errorf("failed to add sub-agent: %w", err)
+	}
+
+	return sa, nil
+}
+
+// AcceptSubAgent activates a child agent's pending link to its parent
+func (r *AgentSubscriptionRepository) AcceptSubAgent(ctx context.Context, childID int64) (*subscription.SubAgent, error) {
+	query := `
+		UPDATE agent_sub_agents
+		SET status = 'active', accepted_at = NOW()
+		WHERE child_agent_id = $1 AND status = 'pending'
+		RETURNING ` + subAgentColumns
+
+	sa, err := scanSubAgent(r.db.QueryRow(ctx, query, childID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept sub-agent link: %w", err)
+	}
+
+	return sa, nil
+}
+
+// RemoveSubAgent unlinks a child agent from its parent
+func (r *AgentSubscriptionRepository) RemoveSubAgent(ctx context.Context, parentID, childID int64) error {
+	query := `DELETE FROM agent_sub_agents WHERE parent_agent_id = $1 AND child_agent_id = $2`
+
+	result, err := r.db.Exec(ctx, query, parentID, childID)
+	if err != nil {
+		return fmt.Errorf("failed to remove sub-agent: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// RemoveParentLink removes a child agent's link to its parent, pending or active
+func (r *AgentSubscriptionRepository) RemoveParentLink(ctx context.Context, childID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM agent_sub_agents WHERE child_agent_id = $1`, childID)
+	if err != nil {
+		return fmt.Errorf("failed to remove parent link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// FindParentLink retrieves a child agent's link to its parent, pending or active
+func (r *AgentSubscriptionRepository) FindParentLink(ctx context.Context, childID int64) (*subscription.SubAgent, error) {
+	query := `SELECT ` + subAgentColumns + ` FROM agent_sub_agents WHERE child_agent_id = $1`
+
+	sa, err := scanSubAgent(r.db.QueryRow(ctx, query, childID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find parent link: %w", err)
+	}
+
+	return sa, nil
+}
+
+// FindParentAgent returns the parent of an active sub-agent, or ErrNotFound if the agent isn't one
+func (r *AgentSubscriptionRepository) FindParentAgent(ctx context.Context, childID int64) (int64, error) {
+	var parentID int64
+	err := r.db.QueryRow(ctx, `SELECT parent_agent_id FROM agent_sub_agents WHERE child_agent_id = $1 AND status = 'active'`, childID).Scan(&parentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find parent agent: %w", err)
+	}
+
+	return parentID, nil
+}
+
+// ListSubAgents retrieves a parent agent's sub-agents, pending and active, oldest first
+func (r *AgentSubscriptionRepository) ListSubAgents(ctx context.Context, parentID int64) ([]subscription.SubAgent, error) {
+	query := `
+		SELECT ` + subAgentColumns + `
+		FROM agent_sub_agents
+		WHERE parent_agent_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sub-agents: %w", err)
+	}
+	defer rows.Close()
+
+	subAgents := []subscription.SubAgent{}
+	for rows.Next() {
+		sa, err := scanSubAgent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sub-agent: %w", err)
+		}
+		subAgents = append(subAgents, *sa)
+	}
+
+	return subAgents, rows.Err()
+}
@@ -5,12 +5,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"bingwa-service/internal/domain/auth"
 	xerrors "bingwa-service/internal/pkg/errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -101,7 +103,7 @@ func (r *AuthRepository) FindIdentityByID(ctx context.Context, id int64) (*auth.
 		&identity.CreatedAt, &identity.UpdatedAt, &identity.DeletedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -159,6 +161,77 @@ func (r *AuthRepository) UpdateIdentityStatus(ctx context.Context, id int64, sta
 	return err
 }
 
+// UpdateIdentityEmail replaces the identity's email and marks it verified
+func (r *AuthRepository) UpdateIdentityEmail(ctx context.Context, id int64, email string) error {
+	query := `
+		UPDATE auth_identities
+		SET email = $1, email_verified = TRUE, email_verified_at = $2, updated_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, email, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+	return nil
+}
+
+// AnonymizeIdentity soft-deletes an identity and replaces its PII with hashed values in one transaction
+func (r *AuthRepository) AnonymizeIdentity(ctx context.Context, id int64, hashedEmail, hashedPhone sql.NullString) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE auth_identities
+		SET email = $1, phone = $2, status = 'inactive', deleted_at = $3, updated_at = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`, hashedEmail, hashedPhone, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize identity: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_profiles
+		SET full_name = NULL, avatar_url = NULL, bio = NULL, metadata = NULL, updated_at = $1
+		WHERE identity_id = $2
+	`, now, id); err != nil {
+		return fmt.Errorf("failed to anonymize profile: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE auth_providers
+		SET provider_email = NULL, provider_username = NULL, provider_data = NULL,
+		    access_token = NULL, refresh_token = NULL, token_expires_at = NULL, updated_at = $1
+		WHERE identity_id = $2
+	`, now, id); err != nil {
+		return fmt.Errorf("failed to anonymize providers: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE auth_sessions
+		SET status = 'revoked', logout_at = $1, ip_address = NULL, user_agent = NULL
+		WHERE identity_id = $2
+	`, now, id); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // ========== Provider Methods ==========
 
 // FindProviderByIdentityAndType finds a provider by identity ID and provider type
@@ -350,6 +423,49 @@ func (r *AuthRepository) InvalidateAllUserSessions(ctx context.Context, identity
 	return err
 }
 
+// ListSessionsByIdentity retrieves all sessions (any status) for a user, newest first
+func (r *AuthRepository) ListSessionsByIdentity(ctx context.Context, identityID int64) ([]auth.Session, error) {
+	query := `
+		SELECT id, identity_id, session_token, refresh_token, provider,
+		       ip_address, user_agent, device_id, device_name, device_fingerprint,
+		       status, login_at, last_activity_at, expires_at, logout_at, metadata
+		FROM auth_sessions
+		WHERE identity_id = $1
+		ORDER BY login_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, identityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []auth.Session{}
+	for rows.Next() {
+		var session auth.Session
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&session.ID, &session.IdentityID, &session.SessionToken, &session.RefreshToken,
+			&session.Provider, &session.IPAddress, &session.UserAgent, &session.DeviceID,
+			&session.DeviceName, &session.DeviceFingerprint, &session.Status,
+			&session.LoginAt, &session.LastActivityAt, &session.ExpiresAt,
+			&session.LogoutAt, &metadataJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		if len(metadataJSON) > 0 {
+			json.Unmarshal(metadataJSON, &session.Metadata)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
 // ========== User Profile Methods ==========
 
 // GetUserProfile retrieves user profile
@@ -570,30 +686,60 @@ func (r *AuthRepository) GetUserRoles(ctx context.Context, identityID int64) ([]
 	return roles, nil
 }
 
+// IsAgent reports whether an identity is an existing agent account: it holds the user role and no admin role
+func (r *AuthRepository) IsAgent(ctx context.Context, identityID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM auth_identities i
+			WHERE i.id = $1 AND i.deleted_at IS NULL
+			  AND EXISTS (
+				SELECT 1 FROM auth_identity_roles ir JOIN auth_roles r ON ir.role_id = r.id
+				WHERE ir.identity_id = i.id AND r.name = 'user' AND ir.is_active = TRUE
+				  AND (ir.expires_at IS NULL OR ir.expires_at > NOW())
+			  )
+			  AND NOT EXISTS (
+				SELECT 1 FROM auth_identity_roles ir JOIN auth_roles r ON ir.role_id = r.id
+				WHERE ir.identity_id = i.id AND r.name IN ('admin', 'super_admin') AND ir.is_active = TRUE
+				  AND (ir.expires_at IS NULL OR ir.expires_at > NOW())
+			  )
+		)
+	`
+
+	var isAgent bool
+	if err := r.db.QueryRow(ctx, query, identityID).Scan(&isAgent); err != nil {
+		return false, fmt.Errorf("failed to check agent: %w", err)
+	}
+
+	return isAgent, nil
+}
+
 // GetUserPermissions retrieves all permissions for a user
 func (r *AuthRepository) GetUserPermissions(ctx context.Context, identityID int64) ([]string, error) {
 	query := `
 		SELECT DISTINCT p.name
 		FROM auth_permissions p
 		WHERE 
-			-- From role permissions
-			p.id IN (
-				SELECT rp.permission_id
-				FROM auth_identity_roles ir
-				JOIN auth_role_permissions rp ON ir.role_id = rp.role_id
-				WHERE ir.identity_id = $1
-				  AND ir.is_active = TRUE
-				  AND (ir.expires_at IS NULL OR ir.expires_at > NOW())
-			)
-			-- Add user-specific grants
-			OR p.id IN (
-				SELECT ip.permission_id
-				FROM auth_identity_permissions ip
-				WHERE ip.identity_id = $1
-				  AND ip.is_granted = TRUE
-				  AND (ip.expires_at IS NULL OR ip.expires_at > NOW())
+			(
+				-- From role permissions
+				p.id IN (
+					SELECT rp.permission_id
+					FROM auth_identity_roles ir
+					JOIN auth_role_permissions rp ON ir.role_id = rp.role_id
+					WHERE ir.identity_id = $1
+					  AND ir.is_active = TRUE
+					  AND (ir.expires_at IS NULL OR ir.expires_at > NOW())
+				)
+				-- Add user-specific grants
+				OR p.id IN (
+					SELECT ip.permission_id
+					FROM auth_identity_permissions ip
+					WHERE ip.identity_id = $1
+					  AND ip.is_granted = TRUE
+					  AND (ip.expires_at IS NULL OR ip.expires_at > NOW())
+				)
 			)
-			-- Exclude user-specific revokes
+			-- Exclude user-specific revokes; this applies to role permissions and grants alike
 			AND p.id NOT IN (
 				SELECT ip.permission_id
 				FROM auth_identity_permissions ip
@@ -619,7 +765,7 @@ func (r *AuthRepository) GetUserPermissions(ctx context.Context, identityID int6
 		permissions = append(permissions, permission)
 	}
 
-	return permissions, nil
+	return permissions, rows.Err()
 }
 
 // AssignRole assigns a role to a user
@@ -635,10 +781,57 @@ func (r *AuthRepository) AssignRole(ctx context.Context, identityID, roleID, ass
 	return err
 }
 
+// AssignRoleWithExpiry assigns a role that lapses at expiresAt; a nil expiresAt makes it permanent
+func (r *AuthRepository) AssignRoleWithExpiry(ctx context.Context, identityID, roleID, assignedBy int64, expiresAt *time.Time) error {
+	query := `
+		INSERT INTO auth_identity_roles (identity_id, role_id, assigned_by, expires_at, is_active)
+		VALUES ($1, $2, $3, $4, TRUE)
+		ON CONFLICT (identity_id, role_id) DO UPDATE
+		SET is_active = TRUE, assigned_by = $3, expires_at = $4, assigned_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, identityID, roleID, assignedBy, expiresAt); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return nil
+}
+
+// DeactivateExpiredRoles deactivates role assignments past their expiry and returns them
+func (r *AuthRepository) DeactivateExpiredRoles(ctx context.Context) ([]auth.ExpiredRoleAssignment, error) {
+	query := `
+		UPDATE auth_identity_roles ir
+		SET is_active = FALSE
+		FROM auth_roles r
+		WHERE ir.role_id = r.id
+		  AND ir.is_active = TRUE
+		  AND ir.expires_at IS NOT NULL
+		  AND ir.expires_at <= NOW()
+		RETURNING ir.identity_id, ir.role_id, r.name, r.display_name, ir.expires_at
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deactivate expired roles: %w", err)
+	}
+	defer rows.Close()
+
+	var expired []auth.ExpiredRoleAssignment
+	for rows.Next() {
+		var a auth.ExpiredRoleAssignment
+		if err := rows.Scan(&a.IdentityID, &a.RoleID, &a.RoleName, &a.RoleDisplayName, &a.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan expired role: %w", err)
+		}
+		expired = append(expired, a)
+	}
+
+	return expired, rows.Err()
+}
+
 // GetRoleByName gets a role by its name
 func (r *AuthRepository) GetRoleByName(ctx context.Context, name string) (*auth.Role, error) {
 	query := `
-		SELECT id, name, display_name, description, is_system, is_active, created_at, updated_at
+		SELECT id, name, display_name, COALESCE(description, ''), is_system, is_active, created_at, updated_at
 		FROM auth_roles
 		WHERE name = $1
 	`
@@ -649,7 +842,7 @@ func (r *AuthRepository) GetRoleByName(ctx context.Context, name string) (*auth.
 		&role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -660,6 +853,180 @@ func (r *AuthRepository) GetRoleByName(ctx context.Context, name string) (*auth.
 }
 
 
+// CreateRole inserts a new non-system role
+func (r *AuthRepository) CreateRole(ctx context.Context, role *auth.Role) error {
+	query := `
+		INSERT INTO auth_roles (name, display_name, description, is_system, is_active)
+		VALUES ($1, $2, $3, FALSE, TRUE)
+		RETURNING id, is_system, is_active, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, role.Name, role.DisplayName, role.Description).
+		Scan(&role.ID, &role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return nil
+}
+
+// RoleExists checks if a role with the given name exists
+func (r *AuthRepository) RoleExists(ctx context.Context, name string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM auth_roles WHERE name = $1)`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, name).Scan(&exists)
+	return exists, err
+}
+
+// FindRoleByID gets a role by ID
+func (r *AuthRepository) FindRoleByID(ctx context.Context, id int64) (*auth.Role, error) {
+	query := `
+		SELECT id, name, display_name, COALESCE(description, ''), is_system, is_active, created_at, updated_at
+		FROM auth_roles
+		WHERE id = $1
+	`
+
+	var role auth.Role
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&role.ID, &role.Name, &role.DisplayName, &role.Description,
+		&role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	return &role, nil
+}
+
+// ListRoles retrieves all roles
+func (r *AuthRepository) ListRoles(ctx context.Context) ([]auth.Role, error) {
+	query := `
+		SELECT id, name, display_name, COALESCE(description, ''), is_system, is_active, created_at, updated_at
+		FROM auth_roles
+		ORDER BY is_system DESC, name ASC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []auth.Role{}
+	for rows.Next() {
+		var role auth.Role
+		if err := rows.Scan(
+			&role.ID, &role.Name, &role.DisplayName, &role.Description,
+			&role.IsSystem, &role.IsActive, &role.CreatedAt, &role.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// DeleteRole removes a non-system role; assignments and role permissions cascade
+func (r *AuthRepository) DeleteRole(ctx context.Context, id int64) error {
+	query := `DELETE FROM auth_roles WHERE id = $1 AND is_system = FALSE`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// FindPermissionByID gets a permission by ID
+func (r *AuthRepository) FindPermissionByID(ctx context.Context, id int64) (*auth.Permission, error) {
+	query := `
+		SELECT id, name, display_name, COALESCE(description, ''), COALESCE(resource, ''), COALESCE(action, ''), is_active, created_at
+		FROM auth_permissions
+		WHERE id = $1
+	`
+
+	var p auth.Permission
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.Name, &p.DisplayName, &p.Description, &p.Resource, &p.Action, &p.IsActive, &p.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+
+	return &p, nil
+}
+
+// ListPermissions retrieves all permissions, grouped by resource
+func (r *AuthRepository) ListPermissions(ctx context.Context) ([]auth.Permission, error) {
+	query := `
+		SELECT id, name, display_name, COALESCE(description, ''), COALESCE(resource, ''), COALESCE(action, ''), is_active, created_at
+		FROM auth_permissions
+		ORDER BY resource NULLS LAST, name ASC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []auth.Permission{}
+	for rows.Next() {
+		var p auth.Permission
+		if err := rows.Scan(
+			&p.ID, &p.Name, &p.DisplayName, &p.Description, &p.Resource, &p.Action, &p.IsActive, &p.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// AssignPermissionToRole adds a permission to a role; assigning it again is a no-op
+func (r *AuthRepository) AssignPermissionToRole(ctx context.Context, roleID, permissionID, grantedBy int64) error {
+	query := `
+		INSERT INTO auth_role_permissions (role_id, permission_id, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, roleID, permissionID, grantedBy); err != nil {
+		return fmt.Errorf("failed to assign permission to role: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserPermission records a user-specific grant or revoke, replacing any earlier override for the permission
+func (r *AuthRepository) SetUserPermission(ctx context.Context, identityID, permissionID int64, granted bool, grantedBy int64, expiresAt *time.Time) error {
+	query := `
+		INSERT INTO auth_identity_permissions (identity_id, permission_id, is_granted, granted_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (identity_id, permission_id) DO UPDATE
+		SET is_granted = $3, granted_by = $4, expires_at = $5, granted_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, identityID, permissionID, granted, grantedBy, expiresAt); err != nil {
+		return fmt.Errorf("failed to set user permission: %w", err)
+	}
+
+	return nil
+}
+
 // SuperAdminExists checks if any super admin exists
 func (r *AuthRepository) SuperAdminExists(ctx context.Context) (bool, error) {
 	query := `
@@ -0,0 +1,78 @@
+// internal/repository/postgres/customer_note_repo.go
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"bingwa-service/internal/domain/customer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CustomerNoteRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCustomerNoteRepository(db *pgxpool.Pool) *CustomerNoteRepository {
+	return &CustomerNoteRepository{db: db}
+}
+
+// Create stores a customer note
+func (r *CustomerNoteRepository) Create(ctx context.Context, note *customer.CustomerNote) error {
+	query := `
+		INSERT INTO customer_notes (customer_id, agent_identity_id, author_identity_id, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, note.CustomerID, note.AgentIdentityID, note.AuthorIdentityID, note.Body).
+		Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create customer note: %w", err)
+	}
+
+	return nil
+}
+
+// ListByCustomer retrieves a customer's notes, newest first
+func (r *CustomerNoteRepository) ListByCustomer(ctx context.Context, customerID int64) ([]customer.CustomerNote, error) {
+	query := `
+		SELECT id, customer_id, agent_identity_id, author_identity_id, body, created_at
+		FROM customer_notes
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []customer.CustomerNote{}
+	for rows.Next() {
+		var n customer.CustomerNote
+		if err := rows.Scan(&n.ID, &n.CustomerID, &n.AgentIdentityID, &n.AuthorIdentityID, &n.Body, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan customer note: %w", err)
+		}
+		notes = append(notes, n)
+	}
+
+	return notes, rows.Err()
+}
+
+// Delete removes a note from a customer
+func (r *CustomerNoteRepository) Delete(ctx context.Context, customerID, noteID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM customer_notes WHERE id = $1 AND customer_id = $2`, noteID, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete customer note: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// internal/repository/postgres/email_log_repo.go
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"bingwa-service/internal/domain/emaillog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EmailLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailLogRepository(db *pgxpool.Pool) *EmailLogRepository {
+	return &EmailLogRepository{db: db}
+}
+
+// Create stores the outcome of a send attempt
+func (r *EmailLogRepository) Create(ctx context.Context, entry *emaillog.EmailLog) error {
+	query := `
+		INSERT INTO email_log (recipient, type, subject, status, error)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, entry.Recipient, entry.Type, entry.Subject, entry.Status, entry.Error).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create email log: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves send attempts matching the filters, newest first
+func (r *EmailLogRepository) List(ctx context.Context, filters *emaillog.EmailLogListFilters) ([]emaillog.EmailLog, int64, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	argPos := 1
+
+	if filters.Recipient != "" {
+		conditions = append(conditions, fmt.Sprintf("LOWER(recipient) = LOWER($%d)", argPos))
+		args = append(args, filters.Recipient)
+		argPos++
+	}
+
+	if filters.Type != nil {
+		conditions = append(conditions, fmt.Sprintf("type = $%d", argPos))
+		args = append(args, *filters.Type)
+		argPos++
+	}
+
+	if filters.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argPos))
+		args = append(args, *filters.Status)
+		argPos++
+	}
+
+	if filters.DateFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argPos))
+		args = append(args, *filters.DateFrom)
+		argPos++
+	}
+
+	if filters.DateTo != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argPos))
+		args = append(args, *filters.DateTo)
+		argPos++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM email_log %s", whereClause)
+	var total int64
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count email logs: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+
+	query := fmt.Sprintf(`
+		SELECT id, recipient, type, subject, status, error, created_at
+		FROM email_log
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argPos, argPos+1)
+
+	args = append(args, filters.PageSize, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list email logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []emaillog.EmailLog{}
+	for rows.Next() {
+		var l emaillog.EmailLog
+		if err := rows.Scan(&l.ID, &l.Recipient, &l.Type, &l.Subject, &l.Status, &l.Error, &l.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan email log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+
+	return logs, total, rows.Err()
+}
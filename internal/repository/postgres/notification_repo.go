@@ -22,6 +22,68 @@ func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
 	return &NotificationRepository{db: db}
 }
 
+// FindBroadcastRecipients resolves the identity IDs matching a broadcast target
+func (r *NotificationRepository) FindBroadcastRecipients(ctx context.Context, target *notification.BroadcastTarget) ([]int64, error) {
+	activeSubscription := `
+		SELECT 1 FROM agent_subscriptions s
+		WHERE s.agent_identity_id = i.id AND s.status = 'active' AND s.current_period_end > NOW()`
+
+	conditions := []string{"i.deleted_at IS NULL"}
+	args := []interface{}{}
+	argPos := 1
+
+	if len(target.Roles) > 0 {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM auth_identity_roles ir
+			JOIN auth_roles ro ON ir.role_id = ro.id
+			WHERE ir.identity_id = i.id
+			  AND ir.is_active = TRUE
+			  AND (ir.expires_at IS NULL OR ir.expires_at > NOW())
+			  AND ro.is_active = TRUE
+			  AND ro.name = ANY($%d))`, argPos))
+		args = append(args, target.Roles)
+		argPos++
+	}
+
+	if len(target.PlanIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("EXISTS (%s AND s.subscription_plan_id = ANY($%d))", activeSubscription, argPos))
+		args = append(args, target.PlanIDs)
+		argPos++
+	}
+
+	if target.ActiveSubscription != nil {
+		if *target.ActiveSubscription {
+			conditions = append(conditions, fmt.Sprintf("EXISTS (%s)", activeSubscription))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("NOT EXISTS (%s)", activeSubscription))
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT i.id
+		FROM auth_identities i
+		WHERE %s
+		ORDER BY i.id
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan recipient: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 // Create creates a new notification
 func (r *NotificationRepository) Create(ctx context.Context, n *notification.Notification) error {
 	query := `
@@ -5,14 +5,17 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/domain/webhook"
 	xerrors "bingwa-service/internal/pkg/errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -82,7 +85,7 @@ func (r *OfferRedemptionRepository) FindByID(ctx context.Context, id int64) (*tr
 		&redemption.ValidFrom, &redemption.ValidUntil, &metadataJSON, &redemption.CreatedAt, &redemption.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, xerrors.ErrNotFound
 	}
 	if err != nil {
@@ -96,6 +99,45 @@ func (r *OfferRedemptionRepository) FindByID(ctx context.Context, id int64) (*tr
 	return &redemption, nil
 }
 
+// FindByOfferRequestID retrieves the latest redemption created for an offer request
+func (r *OfferRedemptionRepository) FindByOfferRequestID(ctx context.Context, requestID int64) (*transaction.OfferRedemption, error) {
+	query := `
+		SELECT id, redemption_reference, offer_id, offer_request_id, agent_identity_id,
+		       customer_id, customer_phone, amount, currency, ussd_code_used,
+		       ussd_response, ussd_session_id, ussd_processing_time,
+		       redemption_time, completed_at, status, failure_reason, retry_count, max_retries,
+		       valid_from, valid_until, metadata, created_at, updated_at
+		FROM offer_redemptions
+		WHERE offer_request_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var redemption transaction.OfferRedemption
+	var metadataJSON []byte
+
+	err := r.db.QueryRow(ctx, query, requestID).Scan(
+		&redemption.ID, &redemption.RedemptionReference, &redemption.OfferID, &redemption.OfferRequestID, &redemption.AgentIdentityID,
+		&redemption.CustomerID, &redemption.CustomerPhone, &redemption.Amount, &redemption.Currency, &redemption.USSDCodeUsed,
+		&redemption.USSDResponse, &redemption.USSDSessionID, &redemption.USSDProcessingTime,
+		&redemption.RedemptionTime, &redemption.CompletedAt, &redemption.Status, &redemption.FailureReason, &redemption.RetryCount, &redemption.MaxRetries,
+		&redemption.ValidFrom, &redemption.ValidUntil, &metadataJSON, &redemption.CreatedAt, &redemption.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find redemption by request: %w", err)
+	}
+
+	if len(metadataJSON) > 0 {
+		json.Unmarshal(metadataJSON, &redemption.Metadata)
+	}
+
+	return &redemption, nil
+}
+
 // UpdateStatusWithTx updates redemption status within a transaction
 func (r *OfferRedemptionRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.Tx, id int64, status transaction.TransactionStatus, failureReason string) error {
 	query := `
@@ -126,8 +168,116 @@ func (r *OfferRedemptionRepository) UpdateStatusWithTx(ctx context.Context, tx p
 	return nil
 }
 
+// ResetForRetryWithTx returns a redemption to pending with its retry budget restored
+func (r *OfferRedemptionRepository) ResetForRetryWithTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	query := `
+		UPDATE offer_redemptions
+		SET status = 'pending', failure_reason = NULL, completed_at = NULL, retry_count = 0, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to reset redemption: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// FailByRequestIDsWithTx fails the unfinished redemptions of the given requests and returns how many changed
+func (r *OfferRedemptionRepository) FailByRequestIDsWithTx(ctx context.Context, tx pgx.Tx, requestIDs []int64, failureReason string) (int64, error) {
+	query := `
+		UPDATE offer_redemptions
+		SET status = 'failed', failure_reason = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE offer_request_id = ANY($2) AND status IN ('pending', 'processing')
+	`
+
+	result, err := tx.Exec(ctx, query, failureReason, requestIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail redemptions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ConfirmByRequestIDWithTx moves the redemption of a confirmed request from pending_confirmation to pending
+func (r *OfferRedemptionRepository) ConfirmByRequestIDWithTx(ctx context.Context, tx pgx.Tx, requestID int64) error {
+	query := `
+		UPDATE offer_redemptions
+		SET status = 'pending', updated_at = NOW()
+		WHERE offer_request_id = $1 AND status = 'pending_confirmation'
+	`
+
+	if _, err := tx.Exec(ctx, query, requestID); err != nil {
+		return fmt.Errorf("failed to confirm redemption: %w", err)
+	}
+
+	return nil
+}
+
+// CancelUnconfirmedByRequestIDsWithTx cancels the unconfirmed redemptions of the given requests and returns how many changed
+func (r *OfferRedemptionRepository) CancelUnconfirmedByRequestIDsWithTx(ctx context.Context, tx pgx.Tx, requestIDs []int64, failureReason string) (int64, error) {
+	query := `
+		UPDATE offer_redemptions
+		SET status = 'cancelled', failure_reason = $1, completed_at = NOW(), updated_at = NOW()
+		WHERE offer_request_id = ANY($2) AND status = 'pending_confirmation'
+	`
+
+	result, err := tx.Exec(ctx, query, failureReason, requestIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel redemptions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// EnqueueStatusEventsWithTx writes a status-changed outbox event for each redemption of the given requests,
+// using the redemption's current status, so the event commits or rolls back with the change
+func (r *OfferRedemptionRepository) EnqueueStatusEventsWithTx(ctx context.Context, tx pgx.Tx, requestIDs []int64, previousStatus transaction.TransactionStatus) error {
+	query := `
+		INSERT INTO outbox_events (agent_identity_id, event_type, payload)
+		SELECT agent_identity_id, $1, jsonb_build_object(
+			'redemption_id', id,
+			'redemption_reference', redemption_reference,
+			'offer_request_id', offer_request_id,
+			'offer_id', offer_id,
+			'customer_phone', customer_phone,
+			'amount', amount,
+			'currency', currency,
+			'status', status,
+			'previous_status', $2::text,
+			'failure_reason', failure_reason,
+			'completed_at', completed_at,
+			'updated_at', updated_at
+		)
+		FROM offer_redemptions
+		WHERE offer_request_id = ANY($3)
+	`
+
+	if _, err := tx.Exec(ctx, query, webhook.EventRedemptionStatusChanged, string(previousStatus), requestIDs); err != nil {
+		return fmt.Errorf("failed to enqueue redemption status events: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateUSSDResponse updates USSD response details
 func (r *OfferRedemptionRepository) UpdateUSSDResponse(ctx context.Context, id int64, input *transaction.UpdateUSSDResponseInput) error {
+	return updateUSSDResponse(ctx, r.db, id, input)
+}
+
+// UpdateUSSDResponseWithTx updates USSD response details within a transaction
+func (r *OfferRedemptionRepository) UpdateUSSDResponseWithTx(ctx context.Context, tx pgx.Tx, id int64, input *transaction.UpdateUSSDResponseInput) error {
+	return updateUSSDResponse(ctx, tx, id, input)
+}
+
+func updateUSSDResponse(ctx context.Context, db interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}, id int64, input *transaction.UpdateUSSDResponseInput) error {
 	query := `
 		UPDATE offer_redemptions
 		SET ussd_response = $1, ussd_session_id = $2, ussd_processing_time = $3,
@@ -140,7 +290,7 @@ func (r *OfferRedemptionRepository) UpdateUSSDResponse(ctx context.Context, id i
 		completedAt = sql.NullTime{Time: time.Now(), Valid: true}
 	}
 
-	result, err := r.db.Exec(
+	result, err := db.Exec(
 		ctx, query,
 		sql.NullString{String: input.USSDResponse, Valid: input.USSDResponse != ""},
 		sql.NullString{String: input.USSDSessionID, Valid: input.USSDSessionID != ""},
@@ -283,6 +433,158 @@ func (r *OfferRedemptionRepository) List(ctx context.Context, agentID int64, fil
 	return redemptions, total, nil
 }
 
+// GetOfferSalesSeries buckets an offer's successful redemptions by the given date_trunc unit (UTC)
+func (r *OfferRedemptionRepository) GetOfferSalesSeries(ctx context.Context, agentID, offerID int64, from, to time.Time, unit string) ([]transaction.SalesSeriesPoint, error) {
+	query := `
+		SELECT 
+			date_trunc($1, redemption_time AT TIME ZONE 'UTC') as bucket,
+			COUNT(*) as total,
+			COALESCE(SUM(amount), 0) as revenue
+		FROM offer_redemptions
+		WHERE agent_identity_id = $2 AND offer_id = $3 AND status = 'success'
+		  AND redemption_time >= $4 AND redemption_time < $5
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := r.db.Query(ctx, query, unit, agentID, offerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offer sales series: %w", err)
+	}
+	defer rows.Close()
+
+	points := []transaction.SalesSeriesPoint{}
+	for rows.Next() {
+		var point transaction.SalesSeriesPoint
+		if err := rows.Scan(&point.BucketStart, &point.Count, &point.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan sales series point: %w", err)
+		}
+		point.BucketStart = point.BucketStart.UTC()
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// ClaimExpiringRedemptions claims successful redemptions whose validity ends within the given window and
+// haven't been noticed for that validity end yet. The claim is stored on the row, so restarts and other
+// instances skip them; ReleaseExpiryNotice hands one back if its notice couldn't be sent.
+func (r *OfferRedemptionRepository) ClaimExpiringRedemptions(ctx context.Context, within time.Duration) ([]transaction.OfferRedemption, error) {
+	query := `
+		UPDATE offer_redemptions
+		SET expiry_notified_for = valid_until
+		WHERE id IN (
+			SELECT id FROM offer_redemptions
+			WHERE status = 'success'
+			  AND valid_until IS NOT NULL
+			  AND valid_until > NOW()
+			  AND valid_until <= $1
+			  AND expiry_notified_for IS DISTINCT FROM valid_until
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, redemption_reference, offer_id, offer_request_id, agent_identity_id,
+		          customer_id, customer_phone, amount, currency, ussd_code_used,
+		          ussd_response, ussd_session_id, ussd_processing_time,
+		          redemption_time, completed_at, status, failure_reason, retry_count, max_retries,
+		          valid_from, valid_until, metadata, created_at, updated_at
+	`
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(within))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim expiring redemptions: %w", err)
+	}
+	defer rows.Close()
+
+	redemptions := []transaction.OfferRedemption{}
+	for rows.Next() {
+		var redemption transaction.OfferRedemption
+		var metadataJSON []byte
+
+		err := rows.Scan(
+			&redemption.ID, &redemption.RedemptionReference, &redemption.OfferID, &redemption.OfferRequestID, &redemption.AgentIdentityID,
+			&redemption.CustomerID, &redemption.CustomerPhone, &redemption.Amount, &redemption.Currency, &redemption.USSDCodeUsed,
+			&redemption.USSDResponse, &redemption.USSDSessionID, &redemption.USSDProcessingTime,
+			&redemption.RedemptionTime, &redemption.CompletedAt, &redemption.Status, &redemption.FailureReason, &redemption.RetryCount, &redemption.MaxRetries,
+			&redemption.ValidFrom, &redemption.ValidUntil, &metadataJSON, &redemption.CreatedAt, &redemption.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redemption: %w", err)
+		}
+
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &redemption.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode redemption %d metadata: %w", redemption.ID, err)
+			}
+		}
+
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// ReleaseExpiryNotice clears a redemption's expiry notice claim so the next run retries it
+func (r *OfferRedemptionRepository) ReleaseExpiryNotice(ctx context.Context, id int64) error {
+	if _, err := r.db.Exec(ctx, `UPDATE offer_redemptions SET expiry_notified_for = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to release expiry notice: %w", err)
+	}
+	return nil
+}
+
+// CountByStatus counts an agent's redemptions and how many succeeded, optionally limited to a date range
+func (r *OfferRedemptionRepository) CountByStatus(ctx context.Context, agentID int64, filters *transaction.StatsFilters) (int64, int64, error) {
+	conditions := []string{"agent_identity_id = $1"}
+	args := []interface{}{agentID}
+
+	if filters != nil && filters.From != nil {
+		args = append(args, *filters.From)
+		conditions = append(conditions, fmt.Sprintf("redemption_time >= $%d", len(args)))
+	}
+
+	if filters != nil && filters.To != nil {
+		args = append(args, *filters.To)
+		conditions = append(conditions, fmt.Sprintf("redemption_time <= $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), COUNT(CASE WHEN status = 'success' THEN 1 END)
+		FROM offer_redemptions
+		WHERE %s
+	`, strings.Join(conditions, " AND "))
+
+	var total, successful int64
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&total, &successful); err != nil {
+		return 0, 0, fmt.Errorf("failed to count redemptions: %w", err)
+	}
+
+	return total, successful, nil
+}
+
+// MergeMetadata merges the given keys into a redemption's metadata
+func (r *OfferRedemptionRepository) MergeMetadata(ctx context.Context, id int64, values map[string]interface{}) error {
+	query := `
+		UPDATE offer_redemptions
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || $1::jsonb, updated_at = $2
+		WHERE id = $3
+	`
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, query, valuesJSON, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to merge metadata: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
 // ExistsByRedemptionReference checks if redemption reference exists
 func (r *OfferRedemptionRepository) ExistsByRedemptionReference(ctx context.Context, reference string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM offer_redemptions WHERE redemption_reference = $1)`
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -13,9 +14,13 @@ import (
 	xerrors "bingwa-service/internal/pkg/errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// mpesaReceiptIndex holds an agent's M-Pesa receipts unique across requests
+const mpesaReceiptIndex = "idx_offer_requests_mpesa_receipt"
+
 type OfferRequestRepository struct {
 	db *pgxpool.Pool
 }
@@ -32,8 +37,8 @@ func (r *OfferRequestRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, re
 			customer_phone, customer_name, payment_method, amount_paid, currency,
 			mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
 			mpesa_phone_number, mpesa_message, request_time, status,
-			device_info, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+			source, latitude, longitude, device_info, metadata, risk_score, held_for_review
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -60,9 +65,12 @@ func (r *OfferRequestRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, re
 		req.CustomerPhone, req.CustomerName, req.PaymentMethod, req.AmountPaid, req.Currency,
 		req.MpesaTransactionID, req.MpesaReceiptNumber, req.MpesaTransactionDate,
 		req.MpesaPhoneNumber, req.MpesaMessage, req.RequestTime, req.Status,
-		deviceInfoJSON, metadataJSON,
+		req.Source, req.Latitude, req.Longitude, deviceInfoJSON, metadataJSON, req.RiskScore, req.HeldForReview,
 	).Scan(&req.ID, &req.CreatedAt, &req.UpdatedAt)
 
+	if isMpesaReceiptConflict(err) {
+		return fmt.Errorf("mpesa receipt already recorded: %w", xerrors.ErrDuplicateEntry)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create offer request: %w", err)
 	}
@@ -77,7 +85,8 @@ func (r *OfferRequestRepository) FindByID(ctx context.Context, id int64) (*trans
 		       customer_phone, customer_name, payment_method, amount_paid, currency,
 		       mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
 		       mpesa_phone_number, mpesa_message, request_time, processed_at,
-		       status, failure_reason, retry_count, device_info, metadata,
+		       status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		       risk_score, held_for_review, last_retry_at,
 		       created_at, updated_at
 		FROM offer_requests
 		WHERE id = $1
@@ -91,7 +100,8 @@ func (r *OfferRequestRepository) FindByID(ctx context.Context, id int64) (*trans
 		&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
 		&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
 		&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
-		&req.Status, &req.FailureReason, &req.RetryCount, &deviceInfoJSON, &metadataJSON,
+		&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+		&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
 		&req.CreatedAt, &req.UpdatedAt,
 	)
 
@@ -113,12 +123,203 @@ func (r *OfferRequestRepository) FindByID(ctx context.Context, id int64) (*trans
 	return &req, nil
 }
 
+// FindByMpesaReceipt retrieves an agent's most recent offer request with the given M-Pesa receipt number
+func (r *OfferRequestRepository) FindByMpesaReceipt(ctx context.Context, agentID int64, receipt string) (*transaction.OfferRequest, error) {
+	query := `
+		SELECT id, request_reference, offer_id, agent_identity_id, customer_id,
+		       customer_phone, customer_name, payment_method, amount_paid, currency,
+		       mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
+		       mpesa_phone_number, mpesa_message, request_time, processed_at,
+		       status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		       risk_score, held_for_review, last_retry_at,
+		       created_at, updated_at
+		FROM offer_requests
+		WHERE agent_identity_id = $1 AND mpesa_receipt_number = $2
+		ORDER BY request_time DESC
+		LIMIT 1
+	`
+
+	var req transaction.OfferRequest
+	var deviceInfoJSON, metadataJSON []byte
+
+	err := r.db.QueryRow(ctx, query, agentID, receipt).Scan(
+		&req.ID, &req.RequestReference, &req.OfferID, &req.AgentIdentityID, &req.CustomerID,
+		&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
+		&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
+		&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
+		&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+		&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
+		&req.CreatedAt, &req.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find offer request by receipt: %w", err)
+	}
+
+	if len(deviceInfoJSON) > 0 {
+		json.Unmarshal(deviceInfoJSON, &req.DeviceInfo)
+	}
+	if len(metadataJSON) > 0 {
+		json.Unmarshal(metadataJSON, &req.Metadata)
+	}
+
+	return &req, nil
+}
+
+// FindByMpesaTransactionID retrieves the request an STK push was started for, whatever its status
+func (r *OfferRequestRepository) FindByMpesaTransactionID(ctx context.Context, transactionID string) (*transaction.OfferRequest, error) {
+	query := `
+		SELECT id, request_reference, offer_id, agent_identity_id, customer_id,
+		       customer_phone, customer_name, payment_method, amount_paid, currency,
+		       mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
+		       mpesa_phone_number, mpesa_message, request_time, processed_at,
+		       status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		       risk_score, held_for_review, last_retry_at,
+		       created_at, updated_at
+		FROM offer_requests
+		WHERE mpesa_transaction_id = $1
+		ORDER BY request_time DESC
+		LIMIT 1
+	`
+
+	var req transaction.OfferRequest
+	var deviceInfoJSON, metadataJSON []byte
+
+	err := r.db.QueryRow(ctx, query, transactionID).Scan(
+		&req.ID, &req.RequestReference, &req.OfferID, &req.AgentIdentityID, &req.CustomerID,
+		&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
+		&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
+		&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
+		&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+		&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
+		&req.CreatedAt, &req.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find offer request by mpesa transaction: %w", err)
+	}
+
+	if len(deviceInfoJSON) > 0 {
+		if err := json.Unmarshal(deviceInfoJSON, &req.DeviceInfo); err != nil {
+			return nil, fmt.Errorf("failed to decode device info: %w", err)
+		}
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &req.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+	}
+
+	return &req, nil
+}
+
+// FindPendingByReference retrieves an agent's unpaid pending request by its request reference
+func (r *OfferRequestRepository) FindPendingByReference(ctx context.Context, agentID int64, reference string) (*transaction.OfferRequest, error) {
+	return r.findUnpaidPending(ctx, `agent_identity_id = $1 AND request_reference = $2`, agentID, reference)
+}
+
+// FindPendingByAmountAndPhone retrieves an agent's oldest unpaid pending request since the given time
+// for the amount, matching the customer phone on its last nine digits
+func (r *OfferRequestRepository) FindPendingByAmountAndPhone(ctx context.Context, agentID int64, amount float64, phone string, since time.Time) (*transaction.OfferRequest, error) {
+	return r.findUnpaidPending(ctx,
+		`agent_identity_id = $1 AND amount_paid = $2
+		 AND RIGHT(regexp_replace(customer_phone, '\D', '', 'g'), 9) = RIGHT(regexp_replace($3, '\D', '', 'g'), 9)
+		 AND request_time >= $4`,
+		agentID, amount, phone, since,
+	)
+}
+
+// findUnpaidPending retrieves the oldest pending request with no M-Pesa receipt that matches the condition
+func (r *OfferRequestRepository) findUnpaidPending(ctx context.Context, condition string, args ...interface{}) (*transaction.OfferRequest, error) {
+	query := `
+		SELECT id, request_reference, offer_id, agent_identity_id, customer_id,
+		       customer_phone, customer_name, payment_method, amount_paid, currency,
+		       mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
+		       mpesa_phone_number, mpesa_message, request_time, processed_at,
+		       status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		       risk_score, held_for_review, last_retry_at,
+		       created_at, updated_at
+		FROM offer_requests
+		WHERE status = 'pending' AND mpesa_receipt_number IS NULL AND ` + condition + `
+		ORDER BY request_time ASC
+		LIMIT 1
+	`
+
+	var req transaction.OfferRequest
+	var deviceInfoJSON, metadataJSON []byte
+
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&req.ID, &req.RequestReference, &req.OfferID, &req.AgentIdentityID, &req.CustomerID,
+		&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
+		&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
+		&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
+		&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+		&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
+		&req.CreatedAt, &req.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, xerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending offer request: %w", err)
+	}
+
+	if len(deviceInfoJSON) > 0 {
+		json.Unmarshal(deviceInfoJSON, &req.DeviceInfo)
+	}
+	if len(metadataJSON) > 0 {
+		json.Unmarshal(metadataJSON, &req.Metadata)
+	}
+
+	return &req, nil
+}
+
+// RecordMpesaPaymentWithTx stores a confirmed M-Pesa payment on a pending request that has none yet.
+// With hold set the request is also held for review so it is not dispatched. Returns ErrDuplicateEntry
+// if the agent already has a request with the receipt.
+func (r *OfferRequestRepository) RecordMpesaPaymentWithTx(ctx context.Context, tx pgx.Tx, id int64, transactionID, receipt, phone string, paidAt time.Time, hold bool) error {
+	query := `
+		UPDATE offer_requests
+		SET payment_method = 'mpesa',
+		    mpesa_transaction_id = COALESCE(mpesa_transaction_id, NULLIF($1, '')),
+		    mpesa_receipt_number = $2, mpesa_phone_number = NULLIF($3, ''), mpesa_transaction_date = $4,
+		    held_for_review = held_for_review OR $6,
+		    updated_at = NOW()
+		WHERE id = $5 AND status = 'pending' AND mpesa_receipt_number IS NULL
+	`
+
+	result, err := tx.Exec(ctx, query, transactionID, receipt, phone, paidAt, id, hold)
+	if isMpesaReceiptConflict(err) {
+		return fmt.Errorf("mpesa receipt already recorded: %w", xerrors.ErrDuplicateEntry)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record mpesa payment: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("request is no longer awaiting payment: %w", xerrors.ErrConflict)
+	}
+
+	return nil
+}
+
 // UpdateStatusWithTx updates offer request status within a transaction
-func (r *OfferRequestRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.Tx, id int64, status transaction.TransactionStatus, failureReason string) error {
+func (r *OfferRequestRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.Tx, id int64, status transaction.TransactionStatus, failureReason string, failureCode transaction.FailureCode) error {
 	query := `
 		UPDATE offer_requests
-		SET status = $1, failure_reason = $2, processed_at = $3, updated_at = $4
-		WHERE id = $5
+		SET status = $1, failure_reason = $2, failure_code = $3, processed_at = $4, updated_at = $5,
+		    processing_started_at = CASE
+		        WHEN $1 = 'processing' AND status <> 'processing' THEN $5
+		        ELSE processing_started_at
+		    END
+		WHERE id = $6
 	`
 
 	var processedAt sql.NullTime
@@ -131,7 +332,12 @@ func (r *OfferRequestRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.
 		failureReasonNull = sql.NullString{String: failureReason, Valid: true}
 	}
 
-	result, err := tx.Exec(ctx, query, status, failureReasonNull, processedAt, time.Now(), id)
+	var failureCodeNull sql.NullString
+	if failureCode != "" {
+		failureCodeNull = sql.NullString{String: string(failureCode), Valid: true}
+	}
+
+	result, err := tx.Exec(ctx, query, status, failureReasonNull, failureCodeNull, processedAt, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
@@ -143,6 +349,55 @@ func (r *OfferRequestRepository) UpdateStatusWithTx(ctx context.Context, tx pgx.
 	return nil
 }
 
+// FailStaleProcessingWithTx fails requests that entered processing before cutoff and returns their IDs.
+// Rows without processing_started_at fall back to updated_at.
+func (r *OfferRequestRepository) FailStaleProcessingWithTx(ctx context.Context, tx pgx.Tx, cutoff time.Time, failureReason string, failureCode transaction.FailureCode) ([]int64, error) {
+	query := `
+		UPDATE offer_requests
+		SET status = 'failed', failure_reason = $1, failure_code = $2, processed_at = NOW(), updated_at = NOW()
+		WHERE status = 'processing' AND COALESCE(processing_started_at, updated_at) < $3
+		RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query, failureReason, string(failureCode), cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail stale requests: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan request id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// RequeueWithTx returns a request to pending, clearing its failure and counting the retry
+func (r *OfferRequestRepository) RequeueWithTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	query := `
+		UPDATE offer_requests
+		SET status = 'pending', failure_reason = NULL, failure_code = NULL, processed_at = NULL,
+		    retry_count = retry_count + 1, leased_until = NULL, leased_by = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue request: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
 // IncrementRetryCount increments retry count
 func (r *OfferRequestRepository) IncrementRetryCount(ctx context.Context, id int64) error {
 	query := `UPDATE offer_requests SET retry_count = retry_count + 1, updated_at = $1 WHERE id = $2`
@@ -159,6 +414,192 @@ func (r *OfferRequestRepository) IncrementRetryCount(ctx context.Context, id int
 	return nil
 }
 
+// ClaimRetry counts a retry and stamps last_retry_at unless the previous retry was less than cooldown ago;
+// it reports whether the retry was claimed
+func (r *OfferRequestRepository) ClaimRetry(ctx context.Context, id int64, cooldown time.Duration) (bool, error) {
+	query := `
+		UPDATE offer_requests
+		SET retry_count = retry_count + 1, last_retry_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND (last_retry_at IS NULL OR last_retry_at <= NOW() - make_interval(secs => $2))
+	`
+
+	result, err := r.db.Exec(ctx, query, id, cooldown.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim retry: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CountRecentByPhone counts an agent's requests for a phone number made since the given time
+func (r *OfferRequestRepository) CountRecentByPhone(ctx context.Context, agentID int64, phone string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM offer_requests
+		WHERE agent_identity_id = $1 AND customer_phone = $2 AND request_time >= $3
+	`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, agentID, phone, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recent requests: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetRequestVolume counts an agent's offer requests since the given time and returns the earliest one in that window
+func (r *OfferRequestRepository) GetRequestVolume(ctx context.Context, agentID int64, since time.Time) (int64, sql.NullTime, error) {
+	query := `
+		SELECT COUNT(*), MIN(request_time)
+		FROM offer_requests
+		WHERE agent_identity_id = $1 AND request_time >= $2
+	`
+
+	var count int64
+	var first sql.NullTime
+	if err := r.db.QueryRow(ctx, query, agentID, since).Scan(&count, &first); err != nil {
+		return 0, sql.NullTime{}, fmt.Errorf("failed to get request volume: %w", err)
+	}
+
+	return count, first, nil
+}
+
+// ClearHold releases a request held for review
+func (r *OfferRequestRepository) ClearHold(ctx context.Context, id int64) error {
+	query := `UPDATE offer_requests SET held_for_review = FALSE, updated_at = $1 WHERE id = $2 AND held_for_review`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to clear hold: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// ConfirmWithTx moves a request awaiting confirmation to pending; ErrNotFound if it is no longer awaiting confirmation
+func (r *OfferRequestRepository) ConfirmWithTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	query := `UPDATE offer_requests SET status = 'pending', updated_at = NOW() WHERE id = $1 AND status = 'pending_confirmation'`
+
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to confirm request: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// CancelUnconfirmedWithTx cancels requests awaiting confirmation since before cutoff and returns their IDs
+func (r *OfferRequestRepository) CancelUnconfirmedWithTx(ctx context.Context, tx pgx.Tx, cutoff time.Time, failureReason string) ([]int64, error) {
+	query := `
+		UPDATE offer_requests
+		SET status = 'cancelled', failure_reason = $1, processed_at = NOW(), updated_at = NOW()
+		WHERE status = 'pending_confirmation' AND created_at < $2
+		RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query, failureReason, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel unconfirmed requests: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan request id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ClaimPendingForDevice leases up to limit of an agent's oldest dispatchable requests to a device.
+// Rows are locked with SKIP LOCKED and hidden from other claims until the lease passes,
+// so concurrent batch fetches never hand out the same request twice.
+func (r *OfferRequestRepository) ClaimPendingForDevice(ctx context.Context, agentID int64, deviceID string, limit int, lease time.Duration) ([]transaction.OfferRequest, error) {
+	query := `
+		UPDATE offer_requests
+		SET leased_until = NOW() + $4 * INTERVAL '1 second', leased_by = $2
+		WHERE id IN (
+			SELECT id FROM offer_requests
+			WHERE agent_identity_id = $1 AND status = 'pending' AND NOT held_for_review
+			  AND (leased_until IS NULL OR leased_until < NOW())
+			ORDER BY created_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, request_reference, offer_id, agent_identity_id, customer_id,
+		          customer_phone, customer_name, payment_method, amount_paid, currency,
+		          mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
+		          mpesa_phone_number, mpesa_message, request_time, processed_at,
+		          status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		          risk_score, held_for_review, last_retry_at,
+		          created_at, updated_at
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, deviceID, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []transaction.OfferRequest{}
+	for rows.Next() {
+		var req transaction.OfferRequest
+		var deviceInfoJSON, metadataJSON []byte
+
+		if err := rows.Scan(
+			&req.ID, &req.RequestReference, &req.OfferID, &req.AgentIdentityID, &req.CustomerID,
+			&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
+			&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
+			&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
+			&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+			&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
+			&req.CreatedAt, &req.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan claimed request: %w", err)
+		}
+
+		if len(deviceInfoJSON) > 0 {
+			if err := json.Unmarshal(deviceInfoJSON, &req.DeviceInfo); err != nil {
+				return nil, fmt.Errorf("failed to decode device info of request %d: %w", req.ID, err)
+			}
+		}
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &req.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode metadata of request %d: %w", req.ID, err)
+			}
+		}
+
+		requests = append(requests, req)
+	}
+
+	return requests, rows.Err()
+}
+
+// ReleaseLeases hands claimed requests back so the next batch fetch can pick them up
+func (r *OfferRequestRepository) ReleaseLeases(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE offer_requests SET leased_until = NULL, leased_by = NULL WHERE id = ANY($1)`
+
+	if _, err := r.db.Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("failed to release request leases: %w", err)
+	}
+	return nil
+}
+
 // List retrieves offer requests with filters
 func (r *OfferRequestRepository) List(ctx context.Context, agentID int64, filters *transaction.OfferRequestListFilters) ([]transaction.OfferRequest, int64, error) {
 	conditions := []string{"agent_identity_id = $1"}
@@ -201,6 +642,12 @@ func (r *OfferRequestRepository) List(ctx context.Context, agentID int64, filter
 		argPos++
 	}
 
+	if filters.HeldForReview != nil {
+		conditions = append(conditions, fmt.Sprintf("held_for_review = $%d", argPos))
+		args = append(args, *filters.HeldForReview)
+		argPos++
+	}
+
 	if filters.Search != "" {
 		conditions = append(conditions, fmt.Sprintf(
 			"(customer_phone ILIKE $%d OR customer_name ILIKE $%d OR mpesa_transaction_id ILIKE $%d)",
@@ -247,7 +694,8 @@ func (r *OfferRequestRepository) List(ctx context.Context, agentID int64, filter
 		       customer_phone, customer_name, payment_method, amount_paid, currency,
 		       mpesa_transaction_id, mpesa_receipt_number, mpesa_transaction_date,
 		       mpesa_phone_number, mpesa_message, request_time, processed_at,
-		       status, failure_reason, retry_count, device_info, metadata,
+		       status, failure_reason, failure_code, retry_count, source, latitude, longitude, device_info, metadata,
+		       risk_score, held_for_review, last_retry_at,
 		       created_at, updated_at
 		FROM offer_requests
 		WHERE %s
@@ -273,7 +721,8 @@ func (r *OfferRequestRepository) List(ctx context.Context, agentID int64, filter
 			&req.CustomerPhone, &req.CustomerName, &req.PaymentMethod, &req.AmountPaid, &req.Currency,
 			&req.MpesaTransactionID, &req.MpesaReceiptNumber, &req.MpesaTransactionDate,
 			&req.MpesaPhoneNumber, &req.MpesaMessage, &req.RequestTime, &req.ProcessedAt,
-			&req.Status, &req.FailureReason, &req.RetryCount, &deviceInfoJSON, &metadataJSON,
+			&req.Status, &req.FailureReason, &req.FailureCode, &req.RetryCount, &req.Source, &req.Latitude, &req.Longitude, &deviceInfoJSON, &metadataJSON,
+			&req.RiskScore, &req.HeldForReview, &req.LastRetryAt,
 			&req.CreatedAt, &req.UpdatedAt,
 		)
 		if err != nil {
@@ -293,9 +742,28 @@ func (r *OfferRequestRepository) List(ctx context.Context, agentID int64, filter
 	return requests, total, nil
 }
 
-// GetStats retrieves statistics
-func (r *OfferRequestRepository) GetStats(ctx context.Context, agentID int64) (*transaction.TransactionStats, error) {
-	query := `
+// statsConditions builds the WHERE clause shared by the statistics queries
+func statsConditions(agentID int64, filters *transaction.StatsFilters) (string, []interface{}) {
+	conditions := []string{"agent_identity_id = $1"}
+	args := []interface{}{agentID}
+
+	if filters != nil && filters.From != nil {
+		args = append(args, *filters.From)
+		conditions = append(conditions, fmt.Sprintf("request_time >= $%d", len(args)))
+	}
+
+	if filters != nil && filters.To != nil {
+		args = append(args, *filters.To)
+		conditions = append(conditions, fmt.Sprintf("request_time <= $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetStats retrieves statistics, optionally limited to a date range
+func (r *OfferRequestRepository) GetStats(ctx context.Context, agentID int64, filters *transaction.StatsFilters) (*transaction.TransactionStats, error) {
+	whereClause, args := statsConditions(agentID, filters)
+	query := fmt.Sprintf(`
 		SELECT 
 			COUNT(*) as total,
 			COUNT(CASE WHEN status = 'success' THEN 1 END) as successful,
@@ -303,11 +771,11 @@ func (r *OfferRequestRepository) GetStats(ctx context.Context, agentID int64) (*
 			COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed,
 			COALESCE(SUM(CASE WHEN status = 'success' THEN amount_paid ELSE 0 END), 0) as revenue
 		FROM offer_requests
-		WHERE agent_identity_id = $1
-	`
+		WHERE %s
+	`, whereClause)
 
 	var stats transaction.TransactionStats
-	err := r.db.QueryRow(ctx, query, agentID).Scan(
+	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&stats.TotalRequests,
 		&stats.SuccessfulRequests,
 		&stats.PendingRequests,
@@ -327,10 +795,200 @@ func (r *OfferRequestRepository) GetStats(ctx context.Context, agentID int64) (*
 	return &stats, nil
 }
 
+// GetOfferPerformance aggregates requests and their redemptions per offer for requests created in [from, to)
+func (r *OfferRequestRepository) GetOfferPerformance(ctx context.Context, agentID int64, from, to time.Time) ([]transaction.OfferPerformance, error) {
+	query := `
+		SELECT o.id, o.offer_code, o.name,
+		       COUNT(r.id) AS requests,
+		       COUNT(r.id) FILTER (WHERE r.status = 'success') AS sales,
+		       COALESCE(SUM(r.amount_paid) FILTER (WHERE r.status = 'success'), 0) AS revenue,
+		       COUNT(d.id) FILTER (WHERE d.status IN ('success', 'failed')) AS ussd_attempts,
+		       COUNT(d.id) FILTER (WHERE d.status = 'success') AS ussd_successes
+		FROM offer_requests r
+		JOIN agent_offers o ON o.id = r.offer_id
+		LEFT JOIN offer_redemptions d ON d.offer_request_id = r.id
+		WHERE r.agent_identity_id = $1 AND r.created_at >= $2 AND r.created_at < $3
+		GROUP BY o.id, o.offer_code, o.name
+		ORDER BY sales DESC, o.id
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offer performance: %w", err)
+	}
+	defer rows.Close()
+
+	results := []transaction.OfferPerformance{}
+	for rows.Next() {
+		var p transaction.OfferPerformance
+		if err := rows.Scan(&p.OfferID, &p.OfferCode, &p.OfferName, &p.Requests, &p.Sales, &p.Revenue, &p.USSDAttempts, &p.USSDSuccesses); err != nil {
+			return nil, fmt.Errorf("failed to scan offer performance: %w", err)
+		}
+		if p.Requests > 0 {
+			p.ConversionRate = float64(p.Sales) / float64(p.Requests) * 100
+		}
+		if p.USSDAttempts > 0 {
+			p.USSDSuccessRate = float64(p.USSDSuccesses) / float64(p.USSDAttempts) * 100
+		}
+		results = append(results, p)
+	}
+
+	return results, rows.Err()
+}
+
+// GetStatsBySource retrieves request counts and revenue per source channel
+func (r *OfferRequestRepository) GetStatsBySource(ctx context.Context, agentID int64, filters *transaction.StatsFilters) ([]transaction.SourceStats, error) {
+	whereClause, args := statsConditions(agentID, filters)
+	query := fmt.Sprintf(`
+		SELECT 
+			source,
+			COUNT(*) as total,
+			COUNT(CASE WHEN status = 'success' THEN 1 END) as successful,
+			COALESCE(SUM(CASE WHEN status = 'success' THEN amount_paid ELSE 0 END), 0) as revenue
+		FROM offer_requests
+		WHERE %s
+		GROUP BY source
+		ORDER BY total DESC
+	`, whereClause)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats by source: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []transaction.SourceStats{}
+	for rows.Next() {
+		var stat transaction.SourceStats
+		if err := rows.Scan(&stat.Source, &stat.TotalRequests, &stat.SuccessfulRequests, &stat.TotalRevenue); err != nil {
+			return nil, fmt.Errorf("failed to scan source stats: %w", err)
+		}
+		sources = append(sources, stat)
+	}
+
+	return sources, nil
+}
+
+// GetFailureBreakdown retrieves failed request counts per failure code
+func (r *OfferRequestRepository) GetFailureBreakdown(ctx context.Context, agentID int64, filters *transaction.StatsFilters) ([]transaction.FailureCodeStats, error) {
+	whereClause, args := statsConditions(agentID, filters)
+	query := fmt.Sprintf(`
+		SELECT 
+			COALESCE(failure_code::text, 'unknown') as code,
+			COUNT(*) as total
+		FROM offer_requests
+		WHERE %s AND status = 'failed'
+		GROUP BY code
+		ORDER BY total DESC
+	`, whereClause)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := []transaction.FailureCodeStats{}
+	for rows.Next() {
+		var stat transaction.FailureCodeStats
+		if err := rows.Scan(&stat.FailureCode, &stat.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan failure breakdown: %w", err)
+		}
+		breakdown = append(breakdown, stat)
+	}
+
+	return breakdown, nil
+}
+
+// GetSalesHeatmap bins an agent's geotagged successful requests into lat/lng cells
+func (r *OfferRequestRepository) GetSalesHeatmap(ctx context.Context, agentID int64, bounds *transaction.SalesHeatmapBounds, cellSize float64) ([]transaction.HeatmapCell, error) {
+	conditions := []string{
+		"agent_identity_id = $1",
+		"status = 'success'",
+		"latitude IS NOT NULL",
+		"longitude IS NOT NULL",
+	}
+	args := []interface{}{agentID, cellSize}
+	argPos := 3
+
+	if bounds.MinLatitude != nil {
+		conditions = append(conditions, fmt.Sprintf("latitude >= $%d", argPos))
+		args = append(args, *bounds.MinLatitude)
+		argPos++
+	}
+
+	if bounds.MaxLatitude != nil {
+		conditions = append(conditions, fmt.Sprintf("latitude <= $%d", argPos))
+		args = append(args, *bounds.MaxLatitude)
+		argPos++
+	}
+
+	if bounds.MinLongitude != nil {
+		conditions = append(conditions, fmt.Sprintf("longitude >= $%d", argPos))
+		args = append(args, *bounds.MinLongitude)
+		argPos++
+	}
+
+	if bounds.MaxLongitude != nil {
+		conditions = append(conditions, fmt.Sprintf("longitude <= $%d", argPos))
+		args = append(args, *bounds.MaxLongitude)
+		argPos++
+	}
+
+	if bounds.DateFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argPos))
+		args = append(args, *bounds.DateFrom)
+		argPos++
+	}
+
+	if bounds.DateTo != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argPos))
+		args = append(args, *bounds.DateTo)
+		argPos++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT 
+			FLOOR(latitude / $2) as lat_bin,
+			FLOOR(longitude / $2) as lng_bin,
+			COUNT(*) as count,
+			COALESCE(SUM(amount_paid), 0) as revenue
+		FROM offer_requests
+		WHERE %s
+		GROUP BY lat_bin, lng_bin
+		ORDER BY count DESC
+	`, strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales heatmap: %w", err)
+	}
+	defer rows.Close()
+
+	cells := []transaction.HeatmapCell{}
+	for rows.Next() {
+		var latBin, lngBin float64
+		var cell transaction.HeatmapCell
+		if err := rows.Scan(&latBin, &lngBin, &cell.Count, &cell.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan heatmap cell: %w", err)
+		}
+		cell.Latitude = (latBin + 0.5) * cellSize
+		cell.Longitude = (lngBin + 0.5) * cellSize
+		cells = append(cells, cell)
+	}
+
+	return cells, nil
+}
+
 // ExistsByRequestReference checks if request reference exists
 func (r *OfferRequestRepository) ExistsByRequestReference(ctx context.Context, reference string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM offer_requests WHERE request_reference = $1)`
 	var exists bool
 	err := r.db.QueryRow(ctx, query, reference).Scan(&exists)
 	return exists, err
-}
\ No newline at end of file
+}
+// isMpesaReceiptConflict reports whether err is a write rejected for reusing an agent's M-Pesa receipt
+func isMpesaReceiptConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == mpesaReceiptIndex
+}
@@ -0,0 +1,147 @@
+// internal/repository/postgres/offer_template_repo.go
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const offerTemplateColumns = `
+	id, name, description, type, amount, units, price, currency,
+	validity_days, validity_label, ussd_code_template, ussd_processing_type,
+	ussd_expected_response, ussd_error_pattern, tags, is_active, created_at, updated_at
+`
+
+type OfferTemplateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOfferTemplateRepository(db *pgxpool.Pool) *OfferTemplateRepository {
+	return &OfferTemplateRepository{db: db}
+}
+
+// Create stores a new offer template
+func (r *OfferTemplateRepository) Create(ctx context.Context, t *offer.OfferTemplate) error {
+	query := `
+		INSERT INTO offer_templates (
+			name, description, type, amount, units, price, currency,
+			validity_days, validity_label, ussd_code_template, ussd_processing_type,
+			ussd_expected_response, ussd_error_pattern, tags, is_active
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		t.Name, t.Description, t.Type, t.Amount, t.Units, t.Price, t.Currency,
+		t.ValidityDays, t.ValidityLabel, t.USSDCodeTemplate, t.USSDProcessingType,
+		t.USSDExpectedResponse, t.USSDErrorPattern, t.Tags, t.IsActive,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create offer template: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves an offer template by ID
+func (r *OfferTemplateRepository) FindByID(ctx context.Context, id int64) (*offer.OfferTemplate, error) {
+	query := `SELECT ` + offerTemplateColumns + ` FROM offer_templates WHERE id = $1`
+
+	t, err := scanOfferTemplate(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, xerrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to find offer template: %w", err)
+	}
+
+	return t, nil
+}
+
+// List retrieves offer templates ordered by type and amount, optionally only active ones
+func (r *OfferTemplateRepository) List(ctx context.Context, activeOnly bool) ([]offer.OfferTemplate, error) {
+	query := `
+		SELECT ` + offerTemplateColumns + `
+		FROM offer_templates
+		WHERE ($1 = FALSE OR is_active = TRUE)
+		ORDER BY type, amount, id
+	`
+
+	rows, err := r.db.Query(ctx, query, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offer templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []offer.OfferTemplate{}
+	for rows.Next() {
+		t, err := scanOfferTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan offer template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+
+	return templates, rows.Err()
+}
+
+// Update saves all editable fields of an offer template
+func (r *OfferTemplateRepository) Update(ctx context.Context, t *offer.OfferTemplate) error {
+	query := `
+		UPDATE offer_templates SET
+			name = $2, description = $3, amount = $4, units = $5, price = $6,
+			validity_days = $7, validity_label = $8, ussd_code_template = $9, ussd_processing_type = $10,
+			ussd_expected_response = $11, ussd_error_pattern = $12, tags = $13, is_active = $14,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		t.ID, t.Name, t.Description, t.Amount, t.Units, t.Price,
+		t.ValidityDays, t.ValidityLabel, t.USSDCodeTemplate, t.USSDProcessingType,
+		t.USSDExpectedResponse, t.USSDErrorPattern, t.Tags, t.IsActive,
+	).Scan(&t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return xerrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to update offer template: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an offer template; offers created from it are unaffected
+func (r *OfferTemplateRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM offer_templates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete offer template: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanOfferTemplate(row pgx.Row) (*offer.OfferTemplate, error) {
+	var t offer.OfferTemplate
+	err := row.Scan(
+		&t.ID, &t.Name, &t.Description, &t.Type, &t.Amount, &t.Units, &t.Price, &t.Currency,
+		&t.ValidityDays, &t.ValidityLabel, &t.USSDCodeTemplate, &t.USSDProcessingType,
+		&t.USSDExpectedResponse, &t.USSDErrorPattern, &t.Tags, &t.IsActive, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
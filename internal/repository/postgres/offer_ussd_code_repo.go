@@ -280,6 +280,40 @@ func (r *OfferUSSDCodeRepository) ToggleActive(ctx context.Context, id int64, is
 	return nil
 }
 
+// LockActiveIDsWithTx returns the IDs of an offer's active codes, locking them for the transaction
+func (r *OfferUSSDCodeRepository) LockActiveIDsWithTx(ctx context.Context, tx pgx.Tx, offerID int64) ([]int64, error) {
+	query := `SELECT id FROM offer_ussd_codes WHERE offer_id = $1 AND is_active = TRUE ORDER BY id FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, offerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock active codes: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan code id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// SetActiveWithTx sets the active status of several USSD codes within a transaction
+func (r *OfferUSSDCodeRepository) SetActiveWithTx(ctx context.Context, tx pgx.Tx, ids []int64, isActive bool) (int64, error) {
+	query := `UPDATE offer_ussd_codes SET is_active = $1, updated_at = $2 WHERE id = ANY($3)`
+
+	result, err := tx.Exec(ctx, query, isActive, time.Now(), ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set active status: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // RecordSuccess records a successful USSD execution
 func (r *OfferUSSDCodeRepository) RecordSuccess(ctx context.Context, id int64) error {
 	query := `
@@ -326,6 +360,28 @@ func (r *OfferUSSDCodeRepository) RecordFailure(ctx context.Context, id int64) e
 	return nil
 }
 
+// CreateTestRun stores a USSD code test execution
+func (r *OfferUSSDCodeRepository) CreateTestRun(ctx context.Context, run *offer.USSDCodeTestRun) error {
+	query := `
+		INSERT INTO offer_ussd_code_tests (
+			ussd_code_id, offer_id, agent_identity_id, test_phone, raw_response, result, matched_pattern
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		run.USSDCodeID, run.OfferID, run.AgentIdentityID, run.TestPhone,
+		run.RawResponse, run.Result, run.MatchedPattern,
+	).Scan(&run.ID, &run.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create USSD test run: %w", err)
+	}
+
+	return nil
+}
+
 // Delete deletes a USSD code
 func (r *OfferUSSDCodeRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM offer_ussd_codes WHERE id = $1`
@@ -0,0 +1,134 @@
+// internal/repository/postgres/outbox_repo.go
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/webhook"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue writes an event for the relay outside of any other change
+func (r *OutboxRepository) Enqueue(ctx context.Context, agentID int64, eventType string, payload map[string]interface{}) (int64, error) {
+	query := `
+		INSERT INTO outbox_events (agent_identity_id, event_type, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	var id int64
+	if err := r.db.QueryRow(ctx, query, agentID, eventType, payloadJSON).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+
+	return id, nil
+}
+
+// ClaimPending leases up to limit due pending events, oldest first, so concurrent relays don't deliver the same event.
+// A lease that runs out (e.g. the relay crashed) makes the event claimable again. Events whose payload
+// can't be decoded are marked failed and left out.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]webhook.OutboxEvent, error) {
+	query := `
+		UPDATE outbox_events
+		SET locked_until = $1
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, agent_identity_id, event_type, payload, status, attempts, last_error, next_attempt_at, sent_at, created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []webhook.OutboxEvent{}
+	undecodable := map[int64]string{}
+	for rows.Next() {
+		var e webhook.OutboxEvent
+		var payloadJSON []byte
+
+		err := rows.Scan(
+			&e.ID, &e.AgentIdentityID, &e.EventType, &payloadJSON, &e.Status, &e.Attempts,
+			&e.LastError, &e.NextAttemptAt, &e.SentAt, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+
+		if len(payloadJSON) > 0 {
+			if err := json.Unmarshal(payloadJSON, &e.Payload); err != nil {
+				undecodable[e.ID] = fmt.Sprintf("invalid payload: %v", err)
+				continue
+			}
+		}
+
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox events: %w", err)
+	}
+
+	for id, errMsg := range undecodable {
+		if err := r.RecordFailure(ctx, id, errMsg, time.Now(), true); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// MarkSent records that an event was handed to webhook delivery
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox_events
+		SET status = 'sent', attempts = attempts + 1, last_error = NULL, locked_until = NULL, sent_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event sent: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure releases an event's lease, stores why the relay couldn't hand it off and schedules
+// its next attempt at retryAt. When final is set the event is marked failed instead.
+func (r *OutboxRepository) RecordFailure(ctx context.Context, id int64, errMsg string, retryAt time.Time, final bool) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $1, locked_until = NULL, next_attempt_at = $2,
+		    status = CASE WHEN $3 THEN 'failed' ELSE status END
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, errMsg, retryAt, final, id); err != nil {
+		return fmt.Errorf("failed to record outbox failure: %w", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"bingwa-service/internal/domain/campaign"
 	xerrors "bingwa-service/internal/pkg/errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	//"github.com/lib/pq"
 )
@@ -31,8 +32,9 @@ func (r *PromotionalCampaignRepository) Create(ctx context.Context, c *campaign.
 			campaign_code, name, description, promotional_code,
 			discount_type, discount_value, max_discount_amount,
 			start_date, end_date, max_uses, uses_per_user,
-			applicable_plans, target_user_types, status, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			applicable_plans, target_user_types, status, metadata,
+			total_budget, deactivate_on_budget_spent, variants
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -46,12 +48,18 @@ func (r *PromotionalCampaignRepository) Create(ctx context.Context, c *campaign.
 		}
 	}
 
+	variantsJSON, err := marshalCampaignVariants(c.Variants)
+	if err != nil {
+		return err
+	}
+
 	err = r.db.QueryRow(
 		ctx, query,
 		c.CampaignCode, c.Name, c.Description, c.PromotionalCode,
 		c.DiscountType, c.DiscountValue, c.MaxDiscountAmount,
 		c.StartDate, c.EndDate, c.MaxUses, c.UsesPerUser,
 		c.ApplicablePlans, c.TargetUserTypes, c.Status, metadataJSON,
+		c.TotalBudget, c.DeactivateOnBudgetSpent, variantsJSON,
 	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
 
 	if err != nil {
@@ -67,6 +75,7 @@ func (r *PromotionalCampaignRepository) FindByID(ctx context.Context, id int64)
 		SELECT id, campaign_code, name, description, promotional_code,
 		       discount_type, discount_value, max_discount_amount,
 		       start_date, end_date, max_uses, uses_per_user, current_uses,
+		       total_budget, discount_given, deactivate_on_budget_spent, variants,
 		       applicable_plans, target_user_types, status, metadata,
 		       created_at, updated_at
 		FROM promotional_campaigns
@@ -74,12 +83,13 @@ func (r *PromotionalCampaignRepository) FindByID(ctx context.Context, id int64)
 	`
 
 	var c campaign.PromotionalCampaign
-	var metadataJSON []byte
+	var metadataJSON, variantsJSON []byte
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&c.ID, &c.CampaignCode, &c.Name, &c.Description, &c.PromotionalCode,
 		&c.DiscountType, &c.DiscountValue, &c.MaxDiscountAmount,
 		&c.StartDate, &c.EndDate, &c.MaxUses, &c.UsesPerUser, &c.CurrentUses,
+		&c.TotalBudget, &c.DiscountGiven, &c.DeactivateOnBudgetSpent, &variantsJSON,
 		&c.ApplicablePlans, &c.TargetUserTypes, &c.Status, &metadataJSON,
 		&c.CreatedAt, &c.UpdatedAt,
 	)
@@ -97,6 +107,11 @@ func (r *PromotionalCampaignRepository) FindByID(ctx context.Context, id int64)
 			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 		}
 	}
+	if len(variantsJSON) > 0 {
+		if err := json.Unmarshal(variantsJSON, &c.Variants); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal variants: %w", err)
+		}
+	}
 
 	return &c, nil
 }
@@ -107,6 +122,7 @@ func (r *PromotionalCampaignRepository) FindByPromotionalCode(ctx context.Contex
 		SELECT id, campaign_code, name, description, promotional_code,
 		       discount_type, discount_value, max_discount_amount,
 		       start_date, end_date, max_uses, uses_per_user, current_uses,
+		       total_budget, discount_given, deactivate_on_budget_spent, variants,
 		       applicable_plans, target_user_types, status, metadata,
 		       created_at, updated_at
 		FROM promotional_campaigns
@@ -114,12 +130,13 @@ func (r *PromotionalCampaignRepository) FindByPromotionalCode(ctx context.Contex
 	`
 
 	var c campaign.PromotionalCampaign
-	var metadataJSON []byte
+	var metadataJSON, variantsJSON []byte
 
 	err := r.db.QueryRow(ctx, query, promoCode).Scan(
 		&c.ID, &c.CampaignCode, &c.Name, &c.Description, &c.PromotionalCode,
 		&c.DiscountType, &c.DiscountValue, &c.MaxDiscountAmount,
 		&c.StartDate, &c.EndDate, &c.MaxUses, &c.UsesPerUser, &c.CurrentUses,
+		&c.TotalBudget, &c.DiscountGiven, &c.DeactivateOnBudgetSpent, &variantsJSON,
 		&c.ApplicablePlans, &c.TargetUserTypes, &c.Status, &metadataJSON,
 		&c.CreatedAt, &c.UpdatedAt,
 	)
@@ -137,6 +154,11 @@ func (r *PromotionalCampaignRepository) FindByPromotionalCode(ctx context.Contex
 			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 		}
 	}
+	if len(variantsJSON) > 0 {
+		if err := json.Unmarshal(variantsJSON, &c.Variants); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal variants: %w", err)
+		}
+	}
 
 	return &c, nil
 }
@@ -147,8 +169,9 @@ func (r *PromotionalCampaignRepository) Update(ctx context.Context, id int64, c
 		UPDATE promotional_campaigns
 		SET name = $1, description = $2, discount_value = $3, max_discount_amount = $4,
 		    start_date = $5, end_date = $6, max_uses = $7, uses_per_user = $8,
-		    applicable_plans = $9, target_user_types = $10, metadata = $11, updated_at = $12
-		WHERE id = $13
+		    applicable_plans = $9, target_user_types = $10, metadata = $11, updated_at = $12,
+		    total_budget = $13, deactivate_on_budget_spent = $14, variants = $15
+		WHERE id = $16
 	`
 
 	var metadataJSON []byte
@@ -161,11 +184,17 @@ func (r *PromotionalCampaignRepository) Update(ctx context.Context, id int64, c
 		}
 	}
 
+	variantsJSON, err := marshalCampaignVariants(c.Variants)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.Exec(
 		ctx, query,
 		c.Name, c.Description, c.DiscountValue, c.MaxDiscountAmount,
 		c.StartDate, c.EndDate, c.MaxUses, c.UsesPerUser,
-		c.ApplicablePlans, c.TargetUserTypes, metadataJSON, time.Now(), id,
+		c.ApplicablePlans, c.TargetUserTypes, metadataJSON, time.Now(),
+		c.TotalBudget, c.DeactivateOnBudgetSpent, variantsJSON, id,
 	)
 
 	if err != nil {
@@ -211,6 +240,137 @@ func (r *PromotionalCampaignRepository) IncrementUses(ctx context.Context, id in
 	return nil
 }
 
+// RecordUseWithTx counts one use and the discount it gave against the campaign's budget within a transaction.
+// It fails if the discount would exceed the budget, and deactivates the campaign when
+// the budget is spent and deactivate_on_budget_spent is set.
+func (r *PromotionalCampaignRepository) RecordUseWithTx(ctx context.Context, tx pgx.Tx, id int64, discount float64) (campaign.CampaignStatus, error) {
+	query := `
+		UPDATE promotional_campaigns
+		SET current_uses = current_uses + 1,
+		    discount_given = discount_given + $2,
+		    status = CASE
+		        WHEN deactivate_on_budget_spent AND total_budget IS NOT NULL AND discount_given + $2 >= total_budget
+		        THEN 'inactive' ELSE status
+		    END,
+		    updated_at = NOW()
+		WHERE id = $1 AND (total_budget IS NULL OR discount_given + $2 <= total_budget)
+		RETURNING status
+	`
+
+	var status campaign.CampaignStatus
+	err := tx.QueryRow(ctx, query, id, discount).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("campaign budget exhausted")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record campaign use: %w", err)
+	}
+
+	return status, nil
+}
+
+// DecrementUsesWithTx gives back one campaign use and the discount it gave within a transaction, never going below zero
+func (r *PromotionalCampaignRepository) DecrementUsesWithTx(ctx context.Context, tx pgx.Tx, id int64, discount float64) error {
+	query := `
+		UPDATE promotional_campaigns
+		SET current_uses = GREATEST(current_uses - 1, 0),
+		    discount_given = GREATEST(discount_given - $3, 0),
+		    updated_at = $1
+		WHERE id = $2
+	`
+
+	result, err := tx.Exec(ctx, query, time.Now(), id, discount)
+	if err != nil {
+		return fmt.Errorf("failed to decrement uses: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// marshalCampaignVariants encodes variants for the JSONB column; no variants is stored as NULL
+func marshalCampaignVariants(variants []campaign.CampaignVariant) ([]byte, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal variants: %w", err)
+	}
+	return data, nil
+}
+
+// AssignVariant records the variant a user was placed in and returns the user's stored variant;
+// an existing assignment is kept, so a user stays in their first variant even if the variant list changes
+func (r *PromotionalCampaignRepository) AssignVariant(ctx context.Context, campaignID, identityID int64, variant string) (string, error) {
+	query := `
+		INSERT INTO campaign_variant_assignments (campaign_id, identity_id, variant)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (campaign_id, identity_id) DO UPDATE SET variant = campaign_variant_assignments.variant
+		RETURNING variant
+	`
+
+	var stored string
+	if err := r.db.QueryRow(ctx, query, campaignID, identityID, variant).Scan(&stored); err != nil {
+		return "", fmt.Errorf("failed to assign variant: %w", err)
+	}
+
+	return stored, nil
+}
+
+// RecordVariantConversionWithTx counts a use of the code by a user within a transaction. The conversion is
+// credited to the user's stored variant; variant is only used when the user has no assignment yet.
+func (r *PromotionalCampaignRepository) RecordVariantConversionWithTx(ctx context.Context, tx pgx.Tx, campaignID, identityID int64, variant string, discount float64) error {
+	query := `
+		INSERT INTO campaign_variant_assignments (campaign_id, identity_id, variant, conversions, discount_given, last_converted_at)
+		VALUES ($1, $2, $3, 1, $4, NOW())
+		ON CONFLICT (campaign_id, identity_id) DO UPDATE
+		SET conversions = campaign_variant_assignments.conversions + 1,
+		    discount_given = campaign_variant_assignments.discount_given + EXCLUDED.discount_given,
+		    last_converted_at = EXCLUDED.last_converted_at
+	`
+
+	if _, err := tx.Exec(ctx, query, campaignID, identityID, variant, discount); err != nil {
+		return fmt.Errorf("failed to record variant conversion: %w", err)
+	}
+
+	return nil
+}
+
+// GetVariantStats aggregates assignments and conversions per variant of a campaign
+func (r *PromotionalCampaignRepository) GetVariantStats(ctx context.Context, campaignID int64) ([]campaign.CampaignVariantStats, error) {
+	query := `
+		SELECT variant,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE conversions > 0),
+		       COALESCE(SUM(conversions), 0),
+		       COALESCE(SUM(discount_given), 0)
+		FROM campaign_variant_assignments
+		WHERE campaign_id = $1
+		GROUP BY variant
+	`
+
+	rows, err := r.db.Query(ctx, query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []campaign.CampaignVariantStats{}
+	for rows.Next() {
+		var s campaign.CampaignVariantStats
+		if err := rows.Scan(&s.Variant, &s.Assigned, &s.Converted, &s.Uses, &s.DiscountGiven); err != nil {
+			return nil, fmt.Errorf("failed to scan variant stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
 // Delete deletes a campaign
 func (r *PromotionalCampaignRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM promotional_campaigns WHERE id = $1`
@@ -301,6 +461,7 @@ func (r *PromotionalCampaignRepository) List(ctx context.Context, filters *campa
 		SELECT id, campaign_code, name, description, promotional_code,
 		       discount_type, discount_value, max_discount_amount,
 		       start_date, end_date, max_uses, uses_per_user, current_uses,
+		       total_budget, discount_given, deactivate_on_budget_spent, variants,
 		       applicable_plans, target_user_types, status, metadata,
 		       created_at, updated_at
 		FROM promotional_campaigns
@@ -320,12 +481,13 @@ func (r *PromotionalCampaignRepository) List(ctx context.Context, filters *campa
 	campaigns := []campaign.PromotionalCampaign{}
 	for rows.Next() {
 		var c campaign.PromotionalCampaign
-		var metadataJSON []byte
+		var metadataJSON, variantsJSON []byte
 
 		err := rows.Scan(
 			&c.ID, &c.CampaignCode, &c.Name, &c.Description, &c.PromotionalCode,
 			&c.DiscountType, &c.DiscountValue, &c.MaxDiscountAmount,
 			&c.StartDate, &c.EndDate, &c.MaxUses, &c.UsesPerUser, &c.CurrentUses,
+			&c.TotalBudget, &c.DiscountGiven, &c.DeactivateOnBudgetSpent, &variantsJSON,
 			&c.ApplicablePlans, &c.TargetUserTypes, &c.Status, &metadataJSON,
 			&c.CreatedAt, &c.UpdatedAt,
 		)
@@ -337,6 +499,9 @@ func (r *PromotionalCampaignRepository) List(ctx context.Context, filters *campa
 		if len(metadataJSON) > 0 {
 			json.Unmarshal(metadataJSON, &c.Metadata)
 		}
+		if len(variantsJSON) > 0 {
+			json.Unmarshal(variantsJSON, &c.Variants)
+		}
 
 		campaigns = append(campaigns, c)
 	}
@@ -350,6 +515,7 @@ func (r *PromotionalCampaignRepository) GetActiveCampaigns(ctx context.Context)
 		SELECT id, campaign_code, name, description, promotional_code,
 		       discount_type, discount_value, max_discount_amount,
 		       start_date, end_date, max_uses, uses_per_user, current_uses,
+		       total_budget, discount_given, deactivate_on_budget_spent, variants,
 		       applicable_plans, target_user_types, status, metadata,
 		       created_at, updated_at
 		FROM promotional_campaigns
@@ -366,12 +532,13 @@ func (r *PromotionalCampaignRepository) GetActiveCampaigns(ctx context.Context)
 	campaigns := []campaign.PromotionalCampaign{}
 	for rows.Next() {
 		var c campaign.PromotionalCampaign
-		var metadataJSON []byte
+		var metadataJSON, variantsJSON []byte
 
 		err := rows.Scan(
 			&c.ID, &c.CampaignCode, &c.Name, &c.Description, &c.PromotionalCode,
 			&c.DiscountType, &c.DiscountValue, &c.MaxDiscountAmount,
 			&c.StartDate, &c.EndDate, &c.MaxUses, &c.UsesPerUser, &c.CurrentUses,
+			&c.TotalBudget, &c.DiscountGiven, &c.DeactivateOnBudgetSpent, &variantsJSON,
 			&c.ApplicablePlans, &c.TargetUserTypes, &c.Status, &metadataJSON,
 			&c.CreatedAt, &c.UpdatedAt,
 		)
@@ -382,6 +549,9 @@ func (r *PromotionalCampaignRepository) GetActiveCampaigns(ctx context.Context)
 		if len(metadataJSON) > 0 {
 			json.Unmarshal(metadataJSON, &c.Metadata)
 		}
+		if len(variantsJSON) > 0 {
+			json.Unmarshal(variantsJSON, &c.Variants)
+		}
 
 		campaigns = append(campaigns, c)
 	}
@@ -396,7 +566,8 @@ func (r *PromotionalCampaignRepository) GetStats(ctx context.Context) (*campaign
 			COUNT(*) as total,
 			COUNT(CASE WHEN status = 'active' AND start_date <= NOW() AND end_date >= NOW() THEN 1 END) as active,
 			COUNT(CASE WHEN status = 'expired' OR end_date < NOW() THEN 1 END) as expired,
-			COALESCE(SUM(current_uses), 0) as total_uses
+			COALESCE(SUM(current_uses), 0) as total_uses,
+			COALESCE(SUM(discount_given), 0) as total_discount
 		FROM promotional_campaigns
 	`
 
@@ -406,15 +577,13 @@ func (r *PromotionalCampaignRepository) GetStats(ctx context.Context) (*campaign
 		&stats.ActiveCampaigns,
 		&stats.ExpiredCampaigns,
 		&stats.TotalUses,
+		&stats.TotalDiscount,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
 
-	// TODO: Calculate total discount given from agent_subscriptions table when implemented
-	stats.TotalDiscount = 0
-
 	return &stats, nil
 }
 
@@ -30,8 +30,8 @@ func (r *ScheduledOfferRepository) CreateWithTx(ctx context.Context, tx pgx.Tx,
 		INSERT INTO scheduled_offers (
 			schedule_reference, offer_id, agent_identity_id, customer_id, customer_phone,
 			scheduled_time, next_renewal_date, auto_renew, renewal_period,
-			renewal_limit, renew_until, status, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			renewal_limit, renew_until, status, metadata, recurrence_pattern
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -49,7 +49,7 @@ func (r *ScheduledOfferRepository) CreateWithTx(ctx context.Context, tx pgx.Tx,
 		ctx, query,
 		schedule.ScheduleReference, schedule.OfferID, schedule.AgentIdentityID, schedule.CustomerID, schedule.CustomerPhone,
 		schedule.ScheduledTime, schedule.NextRenewalDate, schedule.AutoRenew, schedule.RenewalPeriod,
-		schedule.RenewalLimit, schedule.RenewUntil, schedule.Status, metadataJSON,
+		schedule.RenewalLimit, schedule.RenewUntil, schedule.Status, metadataJSON, schedule.RecurrencePattern,
 	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
 
 	if err != nil {
@@ -64,7 +64,7 @@ func (r *ScheduledOfferRepository) FindByID(ctx context.Context, id int64) (*sch
 	query := `
 		SELECT id, schedule_reference, offer_id, agent_identity_id, customer_id, customer_phone,
 		       scheduled_time, next_renewal_date, last_renewal_date,
-		       auto_renew, renewal_period, renewal_count, renewal_limit, renew_until,
+		       auto_renew, renewal_period, recurrence_pattern, renewal_count, renewal_limit, renew_until,
 		       status, paused_at, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM scheduled_offers
@@ -77,7 +77,7 @@ func (r *ScheduledOfferRepository) FindByID(ctx context.Context, id int64) (*sch
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&s.ID, &s.ScheduleReference, &s.OfferID, &s.AgentIdentityID, &s.CustomerID, &s.CustomerPhone,
 		&s.ScheduledTime, &s.NextRenewalDate, &s.LastRenewalDate,
-		&s.AutoRenew, &s.RenewalPeriod, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
+		&s.AutoRenew, &s.RenewalPeriod, &s.RecurrencePattern, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
 		&s.Status, &s.PausedAt, &s.CancelledAt, &s.CancellationReason,
 		&metadataJSON, &s.CreatedAt, &s.UpdatedAt,
 	)
@@ -101,8 +101,8 @@ func (r *ScheduledOfferRepository) Update(ctx context.Context, id int64, schedul
 	query := `
 		UPDATE scheduled_offers
 		SET scheduled_time = $1, next_renewal_date = $2, auto_renew = $3, renewal_period = $4,
-		    renewal_limit = $5, renew_until = $6, metadata = $7, updated_at = $8
-		WHERE id = $9
+		    renewal_limit = $5, renew_until = $6, metadata = $7, updated_at = $8, recurrence_pattern = $9
+		WHERE id = $10
 	`
 
 	var metadataJSON []byte
@@ -118,7 +118,7 @@ func (r *ScheduledOfferRepository) Update(ctx context.Context, id int64, schedul
 	result, err := r.db.Exec(
 		ctx, query,
 		schedule.ScheduledTime, schedule.NextRenewalDate, schedule.AutoRenew, schedule.RenewalPeriod,
-		schedule.RenewalLimit, schedule.RenewUntil, metadataJSON, time.Now(), id,
+		schedule.RenewalLimit, schedule.RenewUntil, metadataJSON, time.Now(), schedule.RecurrencePattern, id,
 	)
 
 	if err != nil {
@@ -148,6 +148,22 @@ func (r *ScheduledOfferRepository) UpdateStatusWithTx(ctx context.Context, tx pg
 	return nil
 }
 
+// UpdateNextRenewalWithTx moves the next renewal date without touching the renewal count
+func (r *ScheduledOfferRepository) UpdateNextRenewalWithTx(ctx context.Context, tx pgx.Tx, id int64, nextRenewal time.Time) error {
+	query := `UPDATE scheduled_offers SET next_renewal_date = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := tx.Exec(ctx, query, nextRenewal, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update next renewal: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return xerrors.ErrNotFound
+	}
+
+	return nil
+}
+
 // UpdateRenewalInfoWithTx updates renewal information within a transaction
 func (r *ScheduledOfferRepository) UpdateRenewalInfoWithTx(ctx context.Context, tx pgx.Tx, id int64, nextRenewal, lastRenewal time.Time, renewalCount int) error {
 	query := `
@@ -307,7 +323,7 @@ func (r *ScheduledOfferRepository) List(ctx context.Context, agentID int64, filt
 	query := fmt.Sprintf(`
 		SELECT id, schedule_reference, offer_id, agent_identity_id, customer_id, customer_phone,
 		       scheduled_time, next_renewal_date, last_renewal_date,
-		       auto_renew, renewal_period, renewal_count, renewal_limit, renew_until,
+		       auto_renew, renewal_period, recurrence_pattern, renewal_count, renewal_limit, renew_until,
 		       status, paused_at, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM scheduled_offers
@@ -332,7 +348,7 @@ func (r *ScheduledOfferRepository) List(ctx context.Context, agentID int64, filt
 		err := rows.Scan(
 			&s.ID, &s.ScheduleReference, &s.OfferID, &s.AgentIdentityID, &s.CustomerID, &s.CustomerPhone,
 			&s.ScheduledTime, &s.NextRenewalDate, &s.LastRenewalDate,
-			&s.AutoRenew, &s.RenewalPeriod, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
+			&s.AutoRenew, &s.RenewalPeriod, &s.RecurrencePattern, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
 			&s.Status, &s.PausedAt, &s.CancelledAt, &s.CancellationReason,
 			&metadataJSON, &s.CreatedAt, &s.UpdatedAt,
 		)
@@ -355,7 +371,7 @@ func (r *ScheduledOfferRepository) GetDueSchedules(ctx context.Context) ([]sched
 	query := `
 		SELECT id, schedule_reference, offer_id, agent_identity_id, customer_id, customer_phone,
 		       scheduled_time, next_renewal_date, last_renewal_date,
-		       auto_renew, renewal_period, renewal_count, renewal_limit, renew_until,
+		       auto_renew, renewal_period, recurrence_pattern, renewal_count, renewal_limit, renew_until,
 		       status, paused_at, cancelled_at, cancellation_reason,
 		       metadata, created_at, updated_at
 		FROM scheduled_offers
@@ -377,7 +393,7 @@ func (r *ScheduledOfferRepository) GetDueSchedules(ctx context.Context) ([]sched
 		err := rows.Scan(
 			&s.ID, &s.ScheduleReference, &s.OfferID, &s.AgentIdentityID, &s.CustomerID, &s.CustomerPhone,
 			&s.ScheduledTime, &s.NextRenewalDate, &s.LastRenewalDate,
-			&s.AutoRenew, &s.RenewalPeriod, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
+			&s.AutoRenew, &s.RenewalPeriod, &s.RecurrencePattern, &s.RenewalCount, &s.RenewalLimit, &s.RenewUntil,
 			&s.Status, &s.PausedAt, &s.CancelledAt, &s.CancellationReason,
 			&metadataJSON, &s.CreatedAt, &s.UpdatedAt,
 		)
@@ -395,6 +411,67 @@ func (r *ScheduledOfferRepository) GetDueSchedules(ctx context.Context) ([]sched
 	return schedules, nil
 }
 
+// ClaimDueSchedule takes an execution lease on a due schedule; returns false if it is no longer due or already claimed
+func (r *ScheduledOfferRepository) ClaimDueSchedule(ctx context.Context, id int64, claimToken string, lease time.Duration) (bool, error) {
+	query := `
+		UPDATE scheduled_offers
+		SET claimed_by = $1, claim_expires_at = $2
+		WHERE id = $3 AND status = 'active' AND next_renewal_date <= $4
+		  AND (claim_expires_at IS NULL OR claim_expires_at < $4)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(ctx, query, claimToken, now.Add(lease), id, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ReleaseClaim releases an execution lease held by the given claim token
+func (r *ScheduledOfferRepository) ReleaseClaim(ctx context.Context, id int64, claimToken string) error {
+	query := `
+		UPDATE scheduled_offers
+		SET claimed_by = NULL, claim_expires_at = NULL
+		WHERE id = $1 AND claimed_by = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, claimToken); err != nil {
+		return fmt.Errorf("failed to release schedule claim: %w", err)
+	}
+
+	return nil
+}
+
+// FindConflictingIDs returns the agent's active schedules for the offer and customer phone whose
+// scheduled time or next renewal falls within window of at
+func (r *ScheduledOfferRepository) FindConflictingIDs(ctx context.Context, agentID, offerID int64, customerPhone string, at time.Time, window time.Duration) ([]int64, error) {
+	query := `
+		SELECT id FROM scheduled_offers
+		WHERE agent_identity_id = $1 AND offer_id = $2 AND customer_phone = $3 AND status = 'active'
+		  AND (scheduled_time BETWEEN $4 AND $5 OR next_renewal_date BETWEEN $4 AND $5)
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, offerID, customerPhone, at.Add(-window), at.Add(window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conflicting schedules: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 // GetStats retrieves statistics
 func (r *ScheduledOfferRepository) GetStats(ctx context.Context, agentID int64) (*schedule.ScheduleStats, error) {
 	query := `
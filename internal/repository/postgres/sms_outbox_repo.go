@@ -0,0 +1,125 @@
+// internal/repository/postgres/sms_outbox_repo.go
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/sms"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SMSOutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSMSOutboxRepository(db *pgxpool.Pool) *SMSOutboxRepository {
+	return &SMSOutboxRepository{db: db}
+}
+
+const smsOutboxInsert = `
+	INSERT INTO sms_outbox (agent_identity_id, phone_number, body, purpose, sensitive)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, status, next_attempt_at, created_at
+`
+
+// Enqueue queues a message for the SMS relay
+func (r *SMSOutboxRepository) Enqueue(ctx context.Context, msg *sms.Message) error {
+	err := r.db.QueryRow(ctx, smsOutboxInsert,
+		msg.AgentIdentityID, msg.PhoneNumber, msg.Body, msg.Purpose, msg.Sensitive,
+	).Scan(&msg.ID, &msg.Status, &msg.NextAttemptAt, &msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue sms: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueWithTx queues a message within a transaction, so it is only sent if the change that caused it commits
+func (r *SMSOutboxRepository) EnqueueWithTx(ctx context.Context, tx pgx.Tx, msg *sms.Message) error {
+	err := tx.QueryRow(ctx, smsOutboxInsert,
+		msg.AgentIdentityID, msg.PhoneNumber, msg.Body, msg.Purpose, msg.Sensitive,
+	).Scan(&msg.ID, &msg.Status, &msg.NextAttemptAt, &msg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue sms: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDue leases up to limit pending messages whose next attempt is due, oldest first.
+// Each message gets its own lease, sized for a single send, so a crashed relay only delays it briefly.
+func (r *SMSOutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]sms.Message, error) {
+	query := `
+		UPDATE sms_outbox
+		SET locked_until = $1
+		WHERE id IN (
+			SELECT id FROM sms_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, agent_identity_id, phone_number, body, purpose, sensitive, status,
+		          attempts, last_error, next_attempt_at, sent_at, created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sms messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []sms.Message{}
+	for rows.Next() {
+		var m sms.Message
+		err := rows.Scan(
+			&m.ID, &m.AgentIdentityID, &m.PhoneNumber, &m.Body, &m.Purpose, &m.Sensitive, &m.Status,
+			&m.Attempts, &m.LastError, &m.NextAttemptAt, &m.SentAt, &m.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sms message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// MarkSent records a delivered message; the body of a sensitive message is blanked so the secret isn't kept
+func (r *SMSOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE sms_outbox
+		SET status = 'sent', attempts = attempts + 1, last_error = NULL, locked_until = NULL, sent_at = NOW(),
+		    body = CASE WHEN sensitive THEN '' ELSE body END
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark sms sent: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure releases a message's lease and schedules its next attempt at retryAt.
+// When final is set the message is marked failed instead; a sensitive body is blanked then too.
+func (r *SMSOutboxRepository) RecordFailure(ctx context.Context, id int64, errMsg string, retryAt time.Time, final bool) error {
+	query := `
+		UPDATE sms_outbox
+		SET attempts = attempts + 1, last_error = $1, locked_until = NULL, next_attempt_at = $2,
+		    status = CASE WHEN $3 THEN 'failed' ELSE status END,
+		    body = CASE WHEN $3 AND sensitive THEN '' ELSE body END
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, errMsg, retryAt, final, id); err != nil {
+		return fmt.Errorf("failed to record sms failure: %w", err)
+	}
+
+	return nil
+}
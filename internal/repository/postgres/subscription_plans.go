@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,17 +13,31 @@ import (
 	"bingwa-service/internal/domain/subscription"
 	xerrors "bingwa-service/internal/pkg/errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type SubscriptionPlanRepository struct {
-	db *pgxpool.Pool
+	db          *pgxpool.Pool
+	changeHooks []func(ctx context.Context)
 }
 
 func NewSubscriptionPlanRepository(db *pgxpool.Pool) *SubscriptionPlanRepository {
 	return &SubscriptionPlanRepository{db: db}
 }
 
+// OnChange registers a hook run after any plan is created, updated or deleted (e.g. cache busting)
+func (r *SubscriptionPlanRepository) OnChange(hook func(ctx context.Context)) {
+	r.changeHooks = append(r.changeHooks, hook)
+}
+
+// notifyChange runs registered change hooks
+func (r *SubscriptionPlanRepository) notifyChange(ctx context.Context) {
+	for _, hook := range r.changeHooks {
+		hook(ctx)
+	}
+}
+
 // Create creates a new subscription plan
 func (r *SubscriptionPlanRepository) Create(ctx context.Context, plan *subscription.SubscriptionPlan) error {
 	query := `
@@ -30,9 +45,9 @@ func (r *SubscriptionPlanRepository) Create(ctx context.Context, plan *subscript
 			plan_code, name, description, price, currency, setup_fee,
 			billing_usage, billing_cycle, overage_charge,
 			max_offers, max_customers, features,
-			status, is_public, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		RETURNING id, created_at, updated_at
+			status, is_public, metadata, limit_behavior, allowed_transitions
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, ''), $17)
+		RETURNING id, version, created_at, updated_at
 	`
 
 	var featuresJSON, metadataJSON []byte
@@ -57,13 +72,14 @@ func (r *SubscriptionPlanRepository) Create(ctx context.Context, plan *subscript
 		plan.PlanCode, plan.Name, plan.Description, plan.Price, plan.Currency, plan.SetupFee,
 		plan.BillingUsage, plan.BillingCycle, plan.OverageCharge,
 		plan.MaxOffers, plan.MaxCustomers, featuresJSON,
-		plan.Status, plan.IsPublic, metadataJSON,
-	).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+		plan.Status, plan.IsPublic, metadataJSON, plan.LimitBehavior, plan.AllowedTransitions,
+	).Scan(&plan.ID, &plan.Version, &plan.CreatedAt, &plan.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create subscription plan: %w", err)
 	}
 
+	r.notifyChange(ctx)
 	return nil
 }
 
@@ -71,9 +87,9 @@ func (r *SubscriptionPlanRepository) Create(ctx context.Context, plan *subscript
 func (r *SubscriptionPlanRepository) FindByID(ctx context.Context, id int64) (*subscription.SubscriptionPlan, error) {
 	query := `
 		SELECT id, plan_code, name, description, price, currency, setup_fee,
-		       billing_usage, billing_cycle, overage_charge,
+		       billing_usage, billing_cycle, overage_charge, COALESCE(limit_behavior, ''), allowed_transitions,
 		       max_offers, max_customers, features,
-		       status, is_public, metadata, created_at, updated_at
+		       status, is_public, metadata, version, created_at, updated_at
 		FROM subscription_plans
 		WHERE id = $1
 	`
@@ -83,9 +99,9 @@ func (r *SubscriptionPlanRepository) FindByID(ctx context.Context, id int64) (*s
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&plan.ID, &plan.PlanCode, &plan.Name, &plan.Description, &plan.Price, &plan.Currency, &plan.SetupFee,
-		&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge,
+		&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge, &plan.LimitBehavior, &plan.AllowedTransitions,
 		&plan.MaxOffers, &plan.MaxCustomers, &featuresJSON,
-		&plan.Status, &plan.IsPublic, &metadataJSON, &plan.CreatedAt, &plan.UpdatedAt,
+		&plan.Status, &plan.IsPublic, &metadataJSON, &plan.Version, &plan.CreatedAt, &plan.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -115,9 +131,9 @@ func (r *SubscriptionPlanRepository) FindByID(ctx context.Context, id int64) (*s
 func (r *SubscriptionPlanRepository) FindByPlanCode(ctx context.Context, planCode string) (*subscription.SubscriptionPlan, error) {
 	query := `
 		SELECT id, plan_code, name, description, price, currency, setup_fee,
-		       billing_usage, billing_cycle, overage_charge,
+		       billing_usage, billing_cycle, overage_charge, COALESCE(limit_behavior, ''), allowed_transitions,
 		       max_offers, max_customers, features,
-		       status, is_public, metadata, created_at, updated_at
+		       status, is_public, metadata, version, created_at, updated_at
 		FROM subscription_plans
 		WHERE plan_code = $1
 	`
@@ -127,9 +143,9 @@ func (r *SubscriptionPlanRepository) FindByPlanCode(ctx context.Context, planCod
 
 	err := r.db.QueryRow(ctx, query, planCode).Scan(
 		&plan.ID, &plan.PlanCode, &plan.Name, &plan.Description, &plan.Price, &plan.Currency, &plan.SetupFee,
-		&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge,
+		&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge, &plan.LimitBehavior, &plan.AllowedTransitions,
 		&plan.MaxOffers, &plan.MaxCustomers, &featuresJSON,
-		&plan.Status, &plan.IsPublic, &metadataJSON, &plan.CreatedAt, &plan.UpdatedAt,
+		&plan.Status, &plan.IsPublic, &metadataJSON, &plan.Version, &plan.CreatedAt, &plan.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -162,8 +178,11 @@ func (r *SubscriptionPlanRepository) Update(ctx context.Context, id int64, plan
 		SET name = $1, description = $2, price = $3, setup_fee = $4,
 		    billing_usage = $5, billing_cycle = $6, overage_charge = $7,
 		    max_offers = $8, max_customers = $9, features = $10,
-		    is_public = $11, metadata = $12, updated_at = $13
-		WHERE id = $14
+		    is_public = $11, metadata = $12, updated_at = $13, limit_behavior = NULLIF($14, ''),
+		    allowed_transitions = $15,
+		    version = version + CASE WHEN price IS DISTINCT FROM $3 THEN 1 ELSE 0 END
+		WHERE id = $16
+		RETURNING version
 	`
 
 	var featuresJSON, metadataJSON []byte
@@ -183,22 +202,22 @@ func (r *SubscriptionPlanRepository) Update(ctx context.Context, id int64, plan
 		}
 	}
 
-	result, err := r.db.Exec(
+	err = r.db.QueryRow(
 		ctx, query,
 		plan.Name, plan.Description, plan.Price, plan.SetupFee,
 		plan.BillingUsage, plan.BillingCycle, plan.OverageCharge,
 		plan.MaxOffers, plan.MaxCustomers, featuresJSON,
-		plan.IsPublic, metadataJSON, time.Now(), id,
-	)
+		plan.IsPublic, metadataJSON, time.Now(), plan.LimitBehavior, plan.AllowedTransitions, id,
+	).Scan(&plan.Version)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		return xerrors.ErrNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update subscription plan: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return xerrors.ErrNotFound
-	}
-
+	r.notifyChange(ctx)
 	return nil
 }
 
@@ -215,6 +234,7 @@ func (r *SubscriptionPlanRepository) UpdateStatus(ctx context.Context, id int64,
 		return xerrors.ErrNotFound
 	}
 
+	r.notifyChange(ctx)
 	return nil
 }
 
@@ -231,6 +251,7 @@ func (r *SubscriptionPlanRepository) Delete(ctx context.Context, id int64) error
 		return xerrors.ErrNotFound
 	}
 
+	r.notifyChange(ctx)
 	return nil
 }
 
@@ -314,9 +335,9 @@ func (r *SubscriptionPlanRepository) List(ctx context.Context, filters *subscrip
 	// Query plans
 	query := fmt.Sprintf(`
 		SELECT id, plan_code, name, description, price, currency, setup_fee,
-		       billing_usage, billing_cycle, overage_charge,
+		       billing_usage, billing_cycle, overage_charge, COALESCE(limit_behavior, ''), allowed_transitions,
 		       max_offers, max_customers, features,
-		       status, is_public, metadata, created_at, updated_at
+		       status, is_public, metadata, version, created_at, updated_at
 		FROM subscription_plans
 		%s
 		ORDER BY %s %s
@@ -338,9 +359,9 @@ func (r *SubscriptionPlanRepository) List(ctx context.Context, filters *subscrip
 
 		err := rows.Scan(
 			&plan.ID, &plan.PlanCode, &plan.Name, &plan.Description, &plan.Price, &plan.Currency, &plan.SetupFee,
-			&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge,
+			&plan.BillingUsage, &plan.BillingCycle, &plan.OverageCharge, &plan.LimitBehavior, &plan.AllowedTransitions,
 			&plan.MaxOffers, &plan.MaxCustomers, &featuresJSON,
-			&plan.Status, &plan.IsPublic, &metadataJSON, &plan.CreatedAt, &plan.UpdatedAt,
+			&plan.Status, &plan.IsPublic, &metadataJSON, &plan.Version, &plan.CreatedAt, &plan.UpdatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan plan: %w", err)
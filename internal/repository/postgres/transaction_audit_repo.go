@@ -0,0 +1,100 @@
+// internal/repository/postgres/transaction_audit_repo.go
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"bingwa-service/internal/domain/transaction"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TransactionAuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTransactionAuditRepository(db *pgxpool.Pool) *TransactionAuditRepository {
+	return &TransactionAuditRepository{db: db}
+}
+
+// CreateWithTx appends an audit entry within a transaction
+func (r *TransactionAuditRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, entry *transaction.AuditEntry) error {
+	query := `
+		INSERT INTO transaction_audit (offer_request_id, agent_identity_id, event, status, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	payloadJSON, err := json.Marshal(entry.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit payload: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, query, entry.OfferRequestID, entry.AgentIdentityID, entry.Event, entry.Status, payloadJSON).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// CreateForRequestsWithTx appends the same event for each request, taking the status from the request row
+func (r *TransactionAuditRepository) CreateForRequestsWithTx(ctx context.Context, tx pgx.Tx, requestIDs []int64, event transaction.AuditEvent, payload map[string]interface{}) error {
+	query := `
+		INSERT INTO transaction_audit (offer_request_id, agent_identity_id, event, status, payload)
+		SELECT id, agent_identity_id, $2, status, $3
+		FROM offer_requests
+		WHERE id = ANY($1)
+	`
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit payload: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, requestIDs, event, payloadJSON); err != nil {
+		return fmt.Errorf("failed to create transaction audit entries: %w", err)
+	}
+
+	return nil
+}
+
+// ListByRequest retrieves a request's audit entries in the order they were written
+func (r *TransactionAuditRepository) ListByRequest(ctx context.Context, requestID int64) ([]transaction.AuditEntry, error) {
+	query := `
+		SELECT id, offer_request_id, agent_identity_id, event, status, payload, created_at
+		FROM transaction_audit
+		WHERE offer_request_id = $1
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transaction audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []transaction.AuditEntry{}
+	for rows.Next() {
+		var e transaction.AuditEntry
+		var payloadJSON []byte
+
+		if err := rows.Scan(&e.ID, &e.OfferRequestID, &e.AgentIdentityID, &e.Event, &e.Status, &payloadJSON, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction audit entry: %w", err)
+		}
+
+		if len(payloadJSON) > 0 {
+			if err := json.Unmarshal(payloadJSON, &e.Payload); err != nil {
+				return nil, fmt.Errorf("failed to decode payload of audit entry %d: %w", e.ID, err)
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,156 @@
+// internal/repository/postgres/webhook_delivery_repo.go
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/webhook"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookDeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookDeliveryRepository(db *pgxpool.Pool) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Create stores a new pending webhook delivery
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, d *webhook.Delivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (agent_identity_id, event_type, payload, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, attempts, created_at, updated_at
+	`
+
+	payloadJSON, err := json.Marshal(d.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	if d.Status == "" {
+		d.Status = webhook.DeliveryStatusPending
+	}
+
+	err = r.db.QueryRow(ctx, query, d.AgentIdentityID, d.EventType, payloadJSON, d.Status).
+		Scan(&d.ID, &d.Attempts, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// CreateForOutboxEvent stores a pending delivery for an outbox event, or loads the one already
+// stored for it, so every relay attempt of the event shares one delivery
+func (r *WebhookDeliveryRepository) CreateForOutboxEvent(ctx context.Context, d *webhook.Delivery, eventID int64) error {
+	query := `
+		INSERT INTO webhook_deliveries (agent_identity_id, event_type, payload, status, outbox_event_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (outbox_event_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id, status, attempts, created_at, updated_at
+	`
+
+	payloadJSON, err := json.Marshal(d.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	if d.Status == "" {
+		d.Status = webhook.DeliveryStatusPending
+	}
+
+	err = r.db.QueryRow(ctx, query, d.AgentIdentityID, d.EventType, payloadJSON, d.Status, eventID).
+		Scan(&d.ID, &d.Status, &d.Attempts, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt and the resulting status
+func (r *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, id int64, status webhook.DeliveryStatus, statusCode int, errMsg string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1,
+		    attempts = attempts + 1,
+		    last_status_code = NULLIF($2, 0),
+		    last_error = NULLIF($3, ''),
+		    last_attempt_at = NOW(),
+		    delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, status, statusCode, errMsg, id); err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimFailed moves a failed delivery back to pending for a replay. It returns false when the delivery
+// is no longer failed, such as when another replay has already claimed it.
+func (r *WebhookDeliveryRepository) ClaimFailed(ctx context.Context, id int64) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending'
+		WHERE id = $1 AND status = 'failed'
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ListFailedSince retrieves an agent's failed deliveries created at or after since, oldest first
+func (r *WebhookDeliveryRepository) ListFailedSince(ctx context.Context, agentID int64, since time.Time, limit int) ([]webhook.Delivery, error) {
+	query := `
+		SELECT id, agent_identity_id, event_type, payload, status, attempts,
+		       last_status_code, last_error, last_attempt_at, delivered_at,
+		       created_at, updated_at
+		FROM webhook_deliveries
+		WHERE agent_identity_id = $1 AND status = 'failed' AND created_at >= $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, agentID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []webhook.Delivery{}
+	for rows.Next() {
+		var d webhook.Delivery
+		var payloadJSON []byte
+
+		err := rows.Scan(
+			&d.ID, &d.AgentIdentityID, &d.EventType, &payloadJSON, &d.Status, &d.Attempts,
+			&d.LastStatusCode, &d.LastError, &d.LastAttemptAt, &d.DeliveredAt,
+			&d.CreatedAt, &d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		if len(payloadJSON) > 0 {
+			if err := json.Unmarshal(payloadJSON, &d.Payload); err != nil {
+				return nil, fmt.Errorf("failed to decode payload of webhook delivery %d: %w", d.ID, err)
+			}
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,41 @@
+// internal/repository/redis/device_presence.go
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DevicePresence records Android device heartbeats.
+// A device is online while its heartbeat key exists; each heartbeat sets the key's TTL.
+type DevicePresence struct {
+	client *redis.Client
+}
+
+func NewDevicePresence(client *redis.Client) *DevicePresence {
+	return &DevicePresence{client: client}
+}
+
+// Heartbeat marks a device online for ttl
+func (p *DevicePresence) Heartbeat(ctx context.Context, agentID int64, deviceID string, ttl time.Duration) error {
+	if err := p.client.Set(ctx, p.key(agentID, deviceID), time.Now().Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to record device heartbeat: %w", err)
+	}
+	return nil
+}
+
+// IsOnline reports whether a device has sent a heartbeat within its TTL
+func (p *DevicePresence) IsOnline(ctx context.Context, agentID int64, deviceID string) (bool, error) {
+	n, err := p.client.Exists(ctx, p.key(agentID, deviceID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check device presence: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (p *DevicePresence) key(agentID int64, deviceID string) string {
+	return fmt.Sprintf("devices:heartbeat:%d:%s", agentID, deviceID)
+}
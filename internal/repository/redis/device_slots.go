@@ -0,0 +1,122 @@
+// internal/repository/redis/device_slots.go
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeviceSlots tracks the offer requests each device is processing.
+// Every device has a sorted set of request IDs scored by when the slot expires, so slots
+// leaked by a device that never reports back free themselves after the TTL.
+type DeviceSlots struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewDeviceSlots(client *redis.Client, ttl time.Duration) *DeviceSlots {
+	return &DeviceSlots{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// InFlight returns how many unexpired requests a device is processing
+func (s *DeviceSlots) InFlight(ctx context.Context, agentID int64, deviceID string) (int, error) {
+	key := s.deviceKey(agentID, deviceID)
+
+	if err := s.client.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Unix(), 10)).Err(); err != nil {
+		return 0, fmt.Errorf("failed to expire device slots: %w", err)
+	}
+
+	count, err := s.client.ZCard(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count device slots: %w", err)
+	}
+	return int(count), nil
+}
+
+// acquireScript expires stale slots, counts the rest and takes as many of the requested
+// slots as the device has free, all in one step so concurrent fetches cannot overshoot.
+// KEYS[1] is the device set and KEYS[2..] the request keys; ARGV holds now, the expiry score,
+// the device's limit, the TTL in seconds and then the request IDs in KEYS order.
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local free = tonumber(ARGV[3]) - redis.call('ZCARD', KEYS[1])
+local acquired = {}
+for i = 2, #KEYS do
+	if free <= 0 then break end
+	local id = ARGV[i + 3]
+	if redis.call('ZADD', KEYS[1], 'NX', ARGV[2], id) == 1 then
+		redis.call('SET', KEYS[i], KEYS[1], 'EX', ARGV[4])
+		table.insert(acquired, id)
+		free = free - 1
+	end
+end
+if #acquired > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[4])
+end
+return acquired
+`)
+
+// TryAcquire marks as many requests in flight on a device as it has free slots, up to max,
+// and returns the IDs it took in the order given
+func (s *DeviceSlots) TryAcquire(ctx context.Context, agentID int64, deviceID string, max int, requestIDs []int64) ([]int64, error) {
+	if len(requestIDs) == 0 {
+		return []int64{}, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(requestIDs)+1)
+	keys = append(keys, s.deviceKey(agentID, deviceID))
+	args := make([]interface{}, 0, len(requestIDs)+4)
+	args = append(args, now.Unix(), now.Add(s.ttl).Unix(), max, int64(s.ttl.Seconds()))
+	for _, id := range requestIDs {
+		keys = append(keys, s.requestKey(id))
+		args = append(args, id)
+	}
+
+	members, err := acquireScript.Run(ctx, s.client, keys, args...).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire device slots: %w", err)
+	}
+
+	acquired := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected device slot member %q: %w", member, err)
+		}
+		acquired = append(acquired, id)
+	}
+	return acquired, nil
+}
+
+// Release frees the slot held by a request, if any
+func (s *DeviceSlots) Release(ctx context.Context, requestID int64) error {
+	key, err := s.client.GetDel(ctx, s.requestKey(requestID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up device slot: %w", err)
+	}
+
+	if err := s.client.ZRem(ctx, key, requestID).Err(); err != nil {
+		return fmt.Errorf("failed to release device slot: %w", err)
+	}
+	return nil
+}
+
+func (s *DeviceSlots) deviceKey(agentID int64, deviceID string) string {
+	return fmt.Sprintf("devices:inflight:%d:%s", agentID, deviceID)
+}
+
+func (s *DeviceSlots) requestKey(requestID int64) string {
+	return fmt.Sprintf("devices:inflight:request:%d", requestID)
+}
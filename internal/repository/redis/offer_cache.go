@@ -0,0 +1,61 @@
+// internal/repository/redis/offer_cache.go
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const offerCacheTTL = 2 * time.Minute
+
+// OfferCache caches per-agent top offers and customer recommendations.
+// Keys embed a per-agent version number so Invalidate busts an agent's entries with a single INCR.
+type OfferCache struct {
+	cache versionedCache
+}
+
+func NewOfferCache(client *redis.Client) *OfferCache {
+	return &OfferCache{
+		cache: versionedCache{client: client, ttl: offerCacheTTL},
+	}
+}
+
+// TopOffers resolves the cache entry for an agent's top offers
+func (c *OfferCache) TopOffers(ctx context.Context, agentID int64, limit int) (*CacheEntry, error) {
+	return c.entry(ctx, agentID, fmt.Sprintf("top:%d", limit))
+}
+
+// Recommendations resolves the cache entry for a customer's recommendations
+func (c *OfferCache) Recommendations(ctx context.Context, agentID int64, phone string, limit int) (*CacheEntry, error) {
+	return c.entry(ctx, agentID, fmt.Sprintf("recommend:%s:%d", phoneDigest(phone), limit))
+}
+
+// Invalidate drops all cached entries for an agent by bumping its cache version
+func (c *OfferCache) Invalidate(ctx context.Context, agentID int64) error {
+	if err := c.cache.invalidate(ctx, c.versionKey(agentID)); err != nil {
+		return fmt.Errorf("failed to invalidate offer cache: %w", err)
+	}
+	return nil
+}
+
+// phoneDigest stands in for a customer phone in cache keys so numbers aren't stored in Redis in the clear
+func phoneDigest(phone string) string {
+	sum := sha256.Sum256([]byte(phone))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *OfferCache) versionKey(agentID int64) string {
+	return fmt.Sprintf("offers:cache:version:%d", agentID)
+}
+
+// entry pins a per-agent cache key to the agent's current version
+func (c *OfferCache) entry(ctx context.Context, agentID int64, suffix string) (*CacheEntry, error) {
+	return c.cache.entry(ctx, c.versionKey(agentID), func(version int64) string {
+		return fmt.Sprintf("offers:%d:v%d:%s", agentID, version, suffix)
+	})
+}
@@ -0,0 +1,88 @@
+// internal/repository/redis/offer_cache_test.go
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOfferCacheHitAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewOfferCache(newTestClient(t))
+
+	first, err := c.TopOffers(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("TopOffers: %v", err)
+	}
+	var got []int64
+	if found, err := first.Get(ctx, &got); err != nil || found {
+		t.Fatalf("first call = %v, %v; want a miss", found, err)
+	}
+	if err := first.Set(ctx, []int64{10, 11}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// The second call hits the cache
+	second, err := c.TopOffers(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("TopOffers: %v", err)
+	}
+	if found, err := second.Get(ctx, &got); err != nil || !found {
+		t.Fatalf("second call = %v, %v; want a hit", found, err)
+	}
+	if len(got) != 2 || got[0] != 10 || got[1] != 11 {
+		t.Fatalf("cached top offers = %v, want [10 11]", got)
+	}
+
+	// Another agent's entry is untouched by agent 1's invalidation
+	other, err := c.TopOffers(ctx, 2, 5)
+	if err != nil {
+		t.Fatalf("TopOffers: %v", err)
+	}
+	if err := other.Set(ctx, []int64{20}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A new redemption invalidates the agent's cache
+	if err := c.Invalidate(ctx, 1); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+
+	after, err := c.TopOffers(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("TopOffers: %v", err)
+	}
+	if found, err := after.Get(ctx, &got); err != nil || found {
+		t.Fatalf("call after invalidation = %v, %v; want a miss", found, err)
+	}
+
+	other, err = c.TopOffers(ctx, 2, 5)
+	if err != nil {
+		t.Fatalf("TopOffers: %v", err)
+	}
+	if found, err := other.Get(ctx, &got); err != nil || !found {
+		t.Fatalf("other agent after invalidation = %v, %v; want a hit", found, err)
+	}
+}
+
+func TestOfferCacheRecommendationsKeyedByPhone(t *testing.T) {
+	ctx := context.Background()
+	c := NewOfferCache(newTestClient(t))
+
+	entry, err := c.Recommendations(ctx, 1, "254700000001", 5)
+	if err != nil {
+		t.Fatalf("Recommendations: %v", err)
+	}
+	if err := entry.Set(ctx, []int64{10}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	otherPhone, err := c.Recommendations(ctx, 1, "254700000002", 5)
+	if err != nil {
+		t.Fatalf("Recommendations: %v", err)
+	}
+	var got []int64
+	if found, err := otherPhone.Get(ctx, &got); err != nil || found {
+		t.Fatalf("another customer's recommendations = %v, %v; want a miss", found, err)
+	}
+}
@@ -0,0 +1,38 @@
+// internal/repository/redis/once_marker.go
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OnceMarker records that a job ran for a key, so workers on several instances
+// (or after a restart) don't repeat it. Marks expire after their TTL.
+type OnceMarker struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewOnceMarker(client *redis.Client, prefix string) *OnceMarker {
+	return &OnceMarker{client: client, prefix: prefix}
+}
+
+// Mark claims key for ttl and reports whether this call claimed it (false means it was already marked)
+func (m *OnceMarker) Mark(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := m.client.SetNX(ctx, m.prefix+":"+key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to set once marker: %w", err)
+	}
+	return ok, nil
+}
+
+// Unmark releases a claimed key, e.g. when the job failed and should be retried
+func (m *OnceMarker) Unmark(ctx context.Context, key string) error {
+	if err := m.client.Del(ctx, m.prefix+":"+key).Err(); err != nil {
+		return fmt.Errorf("failed to clear once marker: %w", err)
+	}
+	return nil
+}
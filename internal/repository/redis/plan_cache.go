@@ -0,0 +1,52 @@
+// internal/repository/redis/plan_cache.go
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	planCacheVersionKey = "plans:cache:version"
+	planCacheTTL        = 10 * time.Minute
+)
+
+// PlanCache caches public plan listings and comparisons.
+// Keys embed a version number so Invalidate busts every entry with a single INCR.
+type PlanCache struct {
+	cache versionedCache
+}
+
+func NewPlanCache(client *redis.Client) *PlanCache {
+	return &PlanCache{
+		cache: versionedCache{client: client, ttl: planCacheTTL},
+	}
+}
+
+// PublicPlans resolves the cache entry for a public plan page
+func (c *PlanCache) PublicPlans(ctx context.Context, page, pageSize int) (*CacheEntry, error) {
+	return c.entry(ctx, fmt.Sprintf("public:%d:%d", page, pageSize))
+}
+
+// Comparison resolves the cache entry for a plan comparison
+func (c *PlanCache) Comparison(ctx context.Context, planID1, planID2 int64) (*CacheEntry, error) {
+	return c.entry(ctx, fmt.Sprintf("compare:%d:%d", planID1, planID2))
+}
+
+// Invalidate drops all cached plan entries by bumping the cache version
+func (c *PlanCache) Invalidate(ctx context.Context) error {
+	if err := c.cache.invalidate(ctx, planCacheVersionKey); err != nil {
+		return fmt.Errorf("failed to invalidate plan cache: %w", err)
+	}
+	return nil
+}
+
+// entry pins a plan cache key to the current version
+func (c *PlanCache) entry(ctx context.Context, suffix string) (*CacheEntry, error) {
+	return c.cache.entry(ctx, planCacheVersionKey, func(version int64) string {
+		return fmt.Sprintf("plans:v%d:%s", version, suffix)
+	})
+}
@@ -0,0 +1,74 @@
+// internal/repository/redis/plan_cache_test.go
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestPlanCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewPlanCache(newTestClient(t))
+
+	entry, err := c.PublicPlans(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("PublicPlans: %v", err)
+	}
+	if err := entry.Set(ctx, []string{"basic"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var got []string
+	if found, err := entry.Get(ctx, &got); err != nil || !found {
+		t.Fatalf("Get after Set = %v, %v; want a hit", found, err)
+	}
+
+	// A plan update invalidates the cache; the next read misses
+	if err := c.Invalidate(ctx); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	fresh, err := c.PublicPlans(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("PublicPlans: %v", err)
+	}
+	if found, err := fresh.Get(ctx, &got); err != nil || found {
+		t.Fatalf("Get after Invalidate = %v, %v; want a miss", found, err)
+	}
+}
+
+func TestPlanCacheWriteRacingInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewPlanCache(newTestClient(t))
+
+	// A reader resolves its entry and misses, then a plan update invalidates before it writes back
+	stale, err := c.PublicPlans(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("PublicPlans: %v", err)
+	}
+	if err := c.Invalidate(ctx); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	if err := stale.Set(ctx, []string{"old price"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	fresh, err := c.PublicPlans(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("PublicPlans: %v", err)
+	}
+	var got []string
+	if found, err := fresh.Get(ctx, &got); err != nil || found {
+		t.Fatalf("stale write is visible after Invalidate: found=%v err=%v value=%v", found, err, got)
+	}
+}
@@ -0,0 +1,77 @@
+// internal/repository/redis/versioned_cache.go
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// versionedCache stores JSON values under keys that embed a version number, so bumping the
+// version with a single INCR busts every entry behind it.
+type versionedCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// entry resolves a cache key against the version current now; keyFor builds the key from it
+func (c versionedCache) entry(ctx context.Context, versionKey string, keyFor func(version int64) string) (*CacheEntry, error) {
+	version, err := c.client.Get(ctx, versionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get cache version %s: %w", versionKey, err)
+	}
+	return &CacheEntry{
+		client: c.client,
+		key:    keyFor(version),
+		ttl:    c.ttl,
+	}, nil
+}
+
+// invalidate bumps a version so every entry resolved against the old one is skipped
+func (c versionedCache) invalidate(ctx context.Context, versionKey string) error {
+	if err := c.client.Incr(ctx, versionKey).Err(); err != nil {
+		return fmt.Errorf("failed to bump cache version %s: %w", versionKey, err)
+	}
+	return nil
+}
+
+// CacheEntry is a cache slot pinned to the version that was current when it was resolved.
+// A value computed from a read that raced an Invalidate is written under the old version, where nothing reads it.
+type CacheEntry struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+}
+
+// Get loads the cached value into dest; found is false on a miss
+func (e *CacheEntry) Get(ctx context.Context, dest interface{}) (bool, error) {
+	data, err := e.client.Get(ctx, e.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, fmt.Errorf("failed to unmarshal cache entry: %w", err)
+	}
+	return true, nil
+}
+
+// Set caches value under the entry's key
+func (e *CacheEntry) Set(ctx context.Context, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := e.client.Set(ctx, e.key, data, e.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// internal/usecase/auth/account.go
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/subscription"
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// exportPageSize is the page size used when walking paginated records for an export
+const exportPageSize = 100
+
+// phoneHashLength keeps hashed phone numbers within the phone column width
+const phoneHashLength = 20
+
+// ========== Data Export ==========
+
+// ExportUserData bundles the user's profile, sessions, offers, transactions and subscriptions
+func (s *AuthService) ExportUserData(ctx context.Context, identityID int64) (*auth.UserDataExport, error) {
+	identity, err := s.authRepo.FindIdentityByID(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &auth.UserDataExport{
+		ExportedAt: time.Now(),
+		Identity:   identity,
+	}
+
+	profile, err := s.authRepo.GetUserProfile(ctx, identityID)
+	if err != nil && err != xerrors.ErrNotFound {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	export.Profile = profile
+
+	if export.Roles, err = s.authRepo.GetUserRoles(ctx, identityID); err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	if export.Sessions, err = s.authRepo.ListSessionsByIdentity(ctx, identityID); err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	if export.Offers, err = s.exportOffers(ctx, identityID); err != nil {
+		return nil, err
+	}
+
+	if export.OfferRequests, err = s.exportOfferRequests(ctx, identityID); err != nil {
+		return nil, err
+	}
+
+	if export.Redemptions, err = s.exportRedemptions(ctx, identityID); err != nil {
+		return nil, err
+	}
+
+	if export.Subscriptions, err = s.exportSubscriptions(ctx, identityID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("user data exported", zap.Int64("identity_id", identityID))
+
+	return export, nil
+}
+
+// ========== Account Deletion ==========
+
+// DeleteAccount soft-deletes the identity, revokes all sessions and replaces PII with hashes.
+// Offers, transactions and subscriptions are kept for financial record keeping.
+func (s *AuthService) DeleteAccount(ctx context.Context, identityID int64) error {
+	identity, err := s.authRepo.FindIdentityByID(ctx, identityID)
+	if err != nil {
+		return err
+	}
+
+	var hashedEmail, hashedPhone sql.NullString
+	if identity.Email.Valid {
+		hashedEmail = sql.NullString{String: hashPII(identity.Email.String), Valid: true}
+	}
+	if identity.Phone.Valid {
+		hashedPhone = sql.NullString{String: hashPII(identity.Phone.String)[:phoneHashLength], Valid: true}
+	}
+
+	if err := s.authRepo.AnonymizeIdentity(ctx, identityID, hashedEmail, hashedPhone); err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	// Revoke cached sessions and drop live connections
+	if err := s.sessionManager.InvalidateAllUserSessions(ctx, identityID); err != nil {
+		s.logger.Warn("failed to invalidate cached sessions", zap.Int64("identity_id", identityID), zap.Error(err))
+	}
+	s.hub.ForceLogout(identityID, "", "Account deleted")
+	s.hub.DisconnectUser(identityID, "Account deleted")
+
+	s.logger.Info("account deleted", zap.Int64("identity_id", identityID))
+
+	return nil
+}
+
+// ========== Helper Functions ==========
+
+// hashPII returns a stable, irreversible hex digest of a normalized PII value
+func hashPII(value string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *AuthService) exportOffers(ctx context.Context, identityID int64) ([]offer.AgentOffer, error) {
+	all := []offer.AgentOffer{}
+	for page := 1; ; page++ {
+		filters := &offer.OfferListFilters{Page: page, PageSize: exportPageSize}
+		offers, total, err := s.offerRepo.List(ctx, identityID, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export offers: %w", err)
+		}
+		all = append(all, offers...)
+		if len(offers) == 0 || int64(len(all)) >= total {
+			return all, nil
+		}
+	}
+}
+
+func (s *AuthService) exportOfferRequests(ctx context.Context, identityID int64) ([]transaction.OfferRequest, error) {
+	all := []transaction.OfferRequest{}
+	for page := 1; ; page++ {
+		filters := &transaction.OfferRequestListFilters{Page: page, PageSize: exportPageSize}
+		requests, total, err := s.requestRepo.List(ctx, identityID, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export offer requests: %w", err)
+		}
+		all = append(all, requests...)
+		if len(requests) == 0 || int64(len(all)) >= total {
+			return all, nil
+		}
+	}
+}
+
+func (s *AuthService) exportRedemptions(ctx context.Context, identityID int64) ([]transaction.OfferRedemption, error) {
+	all := []transaction.OfferRedemption{}
+	for page := 1; ; page++ {
+		filters := &transaction.RedemptionListFilters{Page: page, PageSize: exportPageSize}
+		redemptions, total, err := s.redemptionRepo.List(ctx, identityID, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export redemptions: %w", err)
+		}
+		all = append(all, redemptions...)
+		if len(redemptions) == 0 || int64(len(all)) >= total {
+			return all, nil
+		}
+	}
+}
+
+func (s *AuthService) exportSubscriptions(ctx context.Context, identityID int64) ([]subscription.AgentSubscription, error) {
+	all := []subscription.AgentSubscription{}
+	for page := 1; ; page++ {
+		filters := &subscription.SubscriptionListFilters{Page: page, PageSize: exportPageSize}
+		subscriptions, total, err := s.subscriptionRepo.List(ctx, identityID, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export subscriptions: %w", err)
+		}
+		all = append(all, subscriptions...)
+		if len(subscriptions) == 0 || int64(len(all)) >= total {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// internal/service/auth/account_test.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/testutil"
+)
+
+func TestExportUserDataAndDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestAuthService(t)
+
+	agentID := testutil.Identity(t, pool, "Export@Example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	if _, err := pool.Exec(ctx, `UPDATE auth_identities SET phone = '254712345678' WHERE id = $1`, agentID); err != nil {
+		t.Fatalf("failed to set phone: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO user_profiles (identity_id, full_name, bio) VALUES ($1, 'Jane Wanjiku', 'Sells bundles')
+	`, agentID); err != nil {
+		t.Fatalf("failed to seed profile: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO auth_sessions (identity_id, session_token, provider, ip_address, user_agent, expires_at)
+		VALUES ($1, 'token-1', 'local', '10.0.0.1', 'okhttp', $2)
+	`, agentID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to seed session: %v", err)
+	}
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+	testutil.Offer(t, pool, otherID, "OTHER-1GB", 50)
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO offer_requests (request_reference, offer_id, agent_identity_id, customer_phone, payment_method, amount_paid, status)
+		VALUES ('REQ-EXPORT-1', $1, $2, '254700000001', 'mpesa', 50, 'pending')
+	`, offerID, agentID); err != nil {
+		t.Fatalf("failed to seed request: %v", err)
+	}
+
+	export, err := svc.ExportUserData(ctx, agentID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+	if export.Identity == nil || export.Identity.ID != agentID {
+		t.Fatalf("exported identity = %+v, want %d", export.Identity, agentID)
+	}
+	if export.Profile == nil || export.Profile.FullName.String != "Jane Wanjiku" {
+		t.Errorf("exported profile = %+v, want Jane Wanjiku's", export.Profile)
+	}
+	if len(export.Sessions) != 1 || len(export.Offers) != 2 || len(export.OfferRequests) != 1 {
+		t.Errorf("exported %d sessions, %d offers, %d requests; want 1, 2, 1",
+			len(export.Sessions), len(export.Offers), len(export.OfferRequests))
+	}
+	for _, o := range export.Offers {
+		if o.AgentIdentityID != agentID {
+			t.Errorf("export includes offer %d of agent %d", o.ID, o.AgentIdentityID)
+		}
+	}
+	if export.Redemptions == nil || export.Subscriptions == nil {
+		t.Error("empty sections are exported as null, want empty lists")
+	}
+
+	if err := svc.DeleteAccount(ctx, agentID); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+
+	var email, phone sql.NullString
+	var status string
+	var deletedAt sql.NullTime
+	if err := pool.QueryRow(ctx, `
+		SELECT email, phone, status::text, deleted_at FROM auth_identities WHERE id = $1
+	`, agentID).Scan(&email, &phone, &status, &deletedAt); err != nil {
+		t.Fatalf("failed to read identity: %v", err)
+	}
+	if email.String != hashPII("export@example.com") {
+		t.Errorf("email = %q, want the hash of the normalized address", email.String)
+	}
+	if phone.String != hashPII("254712345678")[:phoneHashLength] {
+		t.Errorf("phone = %q, want the truncated hash", phone.String)
+	}
+	if status != "inactive" || !deletedAt.Valid {
+		t.Errorf("status = %s, deleted_at = %v; want an inactive, deleted identity", status, deletedAt)
+	}
+
+	var fullName, bio sql.NullString
+	if err := pool.QueryRow(ctx, `SELECT full_name, bio FROM user_profiles WHERE identity_id = $1`, agentID).Scan(&fullName, &bio); err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	if fullName.Valid || bio.Valid {
+		t.Errorf("profile still holds %q / %q, want both cleared", fullName.String, bio.String)
+	}
+
+	var sessionStatus string
+	var ip sql.NullString
+	if err := pool.QueryRow(ctx, `SELECT status::text, ip_address::text FROM auth_sessions WHERE identity_id = $1`, agentID).Scan(&sessionStatus, &ip); err != nil {
+		t.Fatalf("failed to read session: %v", err)
+	}
+	if sessionStatus != "revoked" || ip.Valid {
+		t.Errorf("session = %s from %s, want revoked with no IP", sessionStatus, ip.String)
+	}
+
+	// Financial records stay for bookkeeping
+	var offers, requests int
+	if err := pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM agent_offers WHERE agent_identity_id = $1),
+		       (SELECT COUNT(*) FROM offer_requests WHERE agent_identity_id = $1)
+	`, agentID).Scan(&offers, &requests); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if offers != 2 || requests != 1 {
+		t.Errorf("%d offers and %d requests kept, want 2 and 1", offers, requests)
+	}
+}
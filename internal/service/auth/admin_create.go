@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/pkg/mask"
 	
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -28,7 +29,7 @@ func (s *AuthService) EnsureSuperAdminExists(ctx context.Context, email, passwor
 		return nil
 	}
 
-	s.logger.Info("creating super admin account", zap.String("email", email))
+	s.logger.Info("creating super admin account", zap.String("email", mask.Email(email)))
 
 	// Validate inputs
 	if email == "" || password == "" || fullName == "" {
@@ -91,7 +92,7 @@ func (s *AuthService) EnsureSuperAdminExists(ctx context.Context, email, passwor
 	}
 
 	s.logger.Info("super admin created successfully",
-		zap.String("email", email),
+		zap.String("email", mask.Email(email)),
 		zap.String("full_name", fullName),
 		zap.Int64("identity_id", identity.ID),
 	)
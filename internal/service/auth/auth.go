@@ -9,14 +9,19 @@ import (
 
 	//"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/domain/emaillog"
 	"bingwa-service/internal/domain/websocket"
 	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/pkg/jwt"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/pkg/pagination"
 	"bingwa-service/internal/pkg/session"
 	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
 	"bingwa-service/internal/service/email"
 	ws "bingwa-service/internal/websocket"
 
@@ -26,19 +31,35 @@ import (
 )
 
 type AuthService struct {
-	authRepo       *postgres.AuthRepository
-	jwtManager     *jwt.Manager
-	sessionManager *session.Manager
-	rateLimiter    *session.RateLimiter
-	emailSender    *email.EmailSender
-	emailHelper    *EmailHelper
-	hub            *ws.Hub
-	cache          *redis.Client
-	logger         *zap.Logger
+	authRepo         *postgres.AuthRepository
+	offerRepo        *postgres.AgentOfferRepository
+	requestRepo      *postgres.OfferRequestRepository
+	redemptionRepo   *postgres.OfferRedemptionRepository
+	subscriptionRepo *postgres.AgentSubscriptionRepository
+	emailLogRepo     *postgres.EmailLogRepository
+	jwtManager       *jwt.Manager
+	sessionManager   *session.Manager
+	rateLimiter      *session.RateLimiter
+	emailSender      *email.EmailSender
+	emailHelper      *EmailHelper
+	hub              *ws.Hub
+	cache            *redis.Client
+	configService    *configsvc.ConfigService
+	logger           *zap.Logger
+}
+
+// SetConfigService wires per-agent security settings (optional; strict device binding is off without it)
+func (s *AuthService) SetConfigService(configService *configsvc.ConfigService) {
+	s.configService = configService
 }
 
 func NewAuthService(
 	authRepo *postgres.AuthRepository,
+	offerRepo *postgres.AgentOfferRepository,
+	requestRepo *postgres.OfferRequestRepository,
+	redemptionRepo *postgres.OfferRedemptionRepository,
+	subscriptionRepo *postgres.AgentSubscriptionRepository,
+	emailLogRepo *postgres.EmailLogRepository,
 	jwtManager *jwt.Manager,
 	sessionManager *session.Manager,
 	rateLimiter *session.RateLimiter,
@@ -48,15 +69,20 @@ func NewAuthService(
 	logger *zap.Logger,
 ) *AuthService {
 	return &AuthService{
-		authRepo:       authRepo,
-		jwtManager:     jwtManager,
-		sessionManager: sessionManager,
-		rateLimiter:    rateLimiter,
-		emailSender:    emailSender,
-		emailHelper:    NewEmailHelper(emailSender, logger, "https://your-base-url.com"),
-		hub:            hub,
-		cache:          cache,
-		logger:         logger,
+		authRepo:         authRepo,
+		offerRepo:        offerRepo,
+		requestRepo:      requestRepo,
+		redemptionRepo:   redemptionRepo,
+		subscriptionRepo: subscriptionRepo,
+		emailLogRepo:     emailLogRepo,
+		jwtManager:       jwtManager,
+		sessionManager:   sessionManager,
+		rateLimiter:      rateLimiter,
+		emailSender:      emailSender,
+		emailHelper:      NewEmailHelper(emailSender, logger, "https://your-base-url.com"),
+		hub:              hub,
+		cache:            cache,
+		logger:           logger,
 	}
 }
 
@@ -64,6 +90,15 @@ func NewAuthService(
 
 // Register creates a new user account
 func (s *AuthService) Register(ctx context.Context, req *auth.RegisterRequest) (*auth.LoginResponse, error) {
+	// Rate limiting
+	allowed, err := s.rateLimiter.CheckRegistrationAttempt(ctx, req.IPAddress)
+	if err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("too many registration attempts, please try again later: %w", xerrors.ErrRateLimited)
+	}
+
 	// Check if email already exists
 	exists, err := s.authRepo.ExistsByEmail(ctx, req.Email)
 	if err != nil {
@@ -135,7 +170,7 @@ func (s *AuthService) Register(ctx context.Context, req *auth.RegisterRequest) (
 	}
 
 	// Auto-login after registration
-	return s.loginWithIdentity(ctx, identity, provider, req.Device, req.IPAddress, req.UserAgent)
+	return s.loginWithIdentity(ctx, identity, provider, req.Device, req.DeviceFingerprint, req.IPAddress, req.UserAgent)
 }
 
 // Update methods to use emailHelper
@@ -221,19 +256,27 @@ func (s *AuthService) Login(ctx context.Context, req *auth.LoginRequest) (*auth.
 	}
 	s.rateLimiter.ResetLoginAttempts(ctx, req.IPAddress, req.Email)
 
-	return s.loginWithIdentity(ctx, identity, provider, req.Device, req.IPAddress, req.UserAgent)
+	return s.loginWithIdentity(ctx, identity, provider, req.Device, req.DeviceFingerprint, req.IPAddress, req.UserAgent)
 }
 
 // loginWithIdentity is a helper that creates session and generates tokens
-func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Identity, provider *auth.Provider, device, ipAddress, userAgent string) (*auth.LoginResponse, error) {
+func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Identity, provider *auth.Provider, device, fingerprint, ipAddress, userAgent string) (*auth.LoginResponse, error) {
+	// Bind the session to the login device when strict mode is enabled; a session
+	// without a fingerprint could never be checked, so refuse it up front
+	strictDevice := s.strictDeviceBinding(ctx, identity.ID)
+	if strictDevice && strings.TrimSpace(fingerprint) == "" {
+		return nil, fmt.Errorf("%w: device fingerprint is required", xerrors.ErrUnauthorized)
+	}
+
 	// Get user roles and permissions
 	roles, permissions, err := s.getUserRolesAndPermissions(ctx, identity.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get roles: %w", err)
 	}
 
-	// Generate tokens
-	accessToken, accessJTI, err := s.jwtManager.Generator.GenerateAccessToken(
+	// Generate tokens; admin roles get shorter-lived access tokens
+	accessTTL := s.accessTokenTTL(roles)
+	accessToken, accessJTI, err := s.jwtManager.Generator.WithTTL(accessTTL).GenerateAccessToken(
 		identity.ID,
 		roles,
 		permissions,
@@ -249,20 +292,24 @@ func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Iden
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	expiresAt := time.Now().Add(s.jwtManager.Generator.Ttl)
+	expiresAt := time.Now().Add(accessTTL)
 	refreshExpiresAt := time.Now().Add(7 * 24 * time.Hour)
 	_ = refreshExpiresAt
 
 	// Create session in database
 	dbSession := &auth.Session{
-		IdentityID:   identity.ID,
-		SessionToken: accessJTI,
-		RefreshToken: sql.NullString{String: refreshJTI, Valid: true},
-		Provider:     provider.Provider,
-		IPAddress:    sql.NullString{String: ipAddress, Valid: ipAddress != ""},
-		UserAgent:    sql.NullString{String: userAgent, Valid: userAgent != ""},
-		DeviceID:     sql.NullString{String: device, Valid: device != ""},
-		ExpiresAt:    expiresAt,
+		IdentityID:        identity.ID,
+		SessionToken:      accessJTI,
+		RefreshToken:      sql.NullString{String: refreshJTI, Valid: true},
+		Provider:          provider.Provider,
+		IPAddress:         sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		UserAgent:         sql.NullString{String: userAgent, Valid: userAgent != ""},
+		DeviceID:          sql.NullString{String: device, Valid: device != ""},
+		DeviceFingerprint: sql.NullString{String: fingerprint, Valid: fingerprint != ""},
+		ExpiresAt:         expiresAt,
+	}
+	if strictDevice {
+		dbSession.Metadata = map[string]interface{}{session.MetadataKeyStrictDevice: true}
 	}
 
 	if err := s.authRepo.CreateSession(ctx, dbSession); err != nil {
@@ -271,21 +318,23 @@ func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Iden
 
 	// Create session in Redis
 	sessionData := &session.SessionData{
-		JTI:            accessJTI,
-		IdentityID:     identity.ID,
-		SessionID:      dbSession.ID,
-		Email:          identity.Email.String,
-		Roles:          roles,
-		Permissions:    permissions,
-		Device:         device,
-		DeviceID:       device,
-		IPAddress:      ipAddress,
-		UserAgent:      userAgent,
-		Provider:       provider.Provider,
-		LoginAt:        time.Now(),
-		LastActivityAt: time.Now(),
-		ExpiresAt:      expiresAt,
-		IsActive:       true,
+		JTI:               accessJTI,
+		IdentityID:        identity.ID,
+		SessionID:         dbSession.ID,
+		Email:             identity.Email.String,
+		Roles:             roles,
+		Permissions:       permissions,
+		Device:            device,
+		DeviceID:          device,
+		IPAddress:         ipAddress,
+		UserAgent:         userAgent,
+		Provider:          provider.Provider,
+		LoginAt:           time.Now(),
+		LastActivityAt:    time.Now(),
+		ExpiresAt:         expiresAt,
+		IsActive:          true,
+		DeviceFingerprint: fingerprint,
+		StrictDevice:      strictDevice,
 	}
 
 	if err := s.sessionManager.CreateSession(ctx, sessionData); err != nil {
@@ -299,7 +348,7 @@ func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Iden
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		TokenType:    "Bearer",
-		ExpiresIn:    int(s.jwtManager.Generator.Ttl.Seconds()),
+		ExpiresIn:    int(accessTTL.Seconds()),
 		ExpiresAt:    expiresAt,
 		User: auth.UserInfo{
 			IdentityID:  identity.ID,
@@ -311,17 +360,46 @@ func (s *AuthService) loginWithIdentity(ctx context.Context, identity *auth.Iden
 	}, nil
 }
 
+// accessTokenTTL picks the access token lifetime for the roles; admin roles use the admin TTL when it is shorter
+func (s *AuthService) accessTokenTTL(roles []string) time.Duration {
+	ttl := s.jwtManager.Generator.Ttl
+	adminTTL := s.jwtManager.Generator.AdminTtl
+	if adminTTL <= 0 || adminTTL >= ttl {
+		return ttl
+	}
+
+	for _, role := range roles {
+		if role == "admin" || role == "super_admin" {
+			return adminTTL
+		}
+	}
+	return ttl
+}
+
+// blacklistTTL returns how long a revoked token must stay blacklisted: the rest of its session's
+// lifetime, or the longest access token lifetime when the session is already gone
+func (s *AuthService) blacklistTTL(ctx context.Context, identityID int64, jti string) time.Duration {
+	if sess, err := s.sessionManager.GetSession(ctx, identityID, jti); err == nil {
+		if remaining := time.Until(sess.ExpiresAt); remaining > 0 {
+			return remaining
+		}
+	}
+	return s.jwtManager.Generator.Ttl
+}
+
 // ========== Logout ==========
 
 // Logout invalidates the current session
 func (s *AuthService) Logout(ctx context.Context, identityID int64, jti string) error {
+	// Read the token's lifetime before the session is gone
+	remainingTTL := s.blacklistTTL(ctx, identityID, jti)
+
 	// Invalidate session in Redis and DB
 	if err := s.sessionManager.InvalidateSession(ctx, identityID, jti); err != nil {
 		return fmt.Errorf("failed to invalidate session: %w", err)
 	}
 
 	// Blacklist the token
-	remainingTTL := time.Until(time.Now().Add(s.jwtManager.Generator.Ttl))
 	if err := s.sessionManager.BlacklistToken(ctx, jti, remainingTTL); err != nil {
 		return fmt.Errorf("failed to blacklist token: %w", err)
 	}
@@ -544,8 +622,8 @@ func (s *AuthService) getUserRolesAndPermissions(ctx context.Context, identityID
 	return roles, permissions, nil
 }
 
-// ValidateToken validates a JWT token and session
-func (s *AuthService) ValidateToken(ctx context.Context, token string) (*jwt.Claims, error) {
+// ValidateToken validates a JWT token and session; fingerprint is the requesting device's fingerprint, if sent
+func (s *AuthService) ValidateToken(ctx context.Context, token, fingerprint string) (*jwt.Claims, error) {
 	claims, err := s.jwtManager.Verifier.VerifyAccessToken(token)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -561,14 +639,68 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (*jwt.Cla
 	}
 
 	// Verify session
-	_, err = s.sessionManager.GetSession(ctx, claims.IdentityID, claims.ID)
+	sess, err := s.sessionManager.GetSession(ctx, claims.IdentityID, claims.ID)
 	if err != nil {
 		return nil, fmt.Errorf("session not found or expired: %w", err)
 	}
 
+	// Enforce device binding for strict sessions
+	if sess.StrictDevice && !fingerprintMatches(sess.DeviceFingerprint, fingerprint) {
+		s.revokeMismatchedSession(ctx, claims.IdentityID, claims.ID)
+		return nil, fmt.Errorf("device fingerprint mismatch: %w", xerrors.ErrUnauthorized)
+	}
+
 	return claims, nil
 }
 
+// fingerprintMatches reports whether a request comes from the device a session is bound to.
+// A blank fingerprint on either side never matches.
+func fingerprintMatches(bound, presented string) bool {
+	return bound != "" && presented != "" && bound == presented
+}
+
+// strictDeviceBinding reports whether the identity's security config requires device binding
+func (s *AuthService) strictDeviceBinding(ctx context.Context, identityID int64) bool {
+	if s.configService == nil {
+		return false
+	}
+
+	securityConfig, err := s.configService.GetSecurityConfig(ctx, identityID)
+	if err != nil {
+		s.logger.Warn("failed to load security config", zap.Int64("identity_id", identityID), zap.Error(err))
+		return false
+	}
+
+	return securityConfig.StrictDeviceBinding
+}
+
+// revokeMismatchedSession revokes a session used from another device and alerts the owner
+func (s *AuthService) revokeMismatchedSession(ctx context.Context, identityID int64, jti string) {
+	s.logger.Warn("session used from a different device, revoking",
+		zap.Int64("identity_id", identityID),
+		zap.String("jti", jti),
+	)
+
+	remainingTTL := s.blacklistTTL(ctx, identityID, jti)
+	if err := s.sessionManager.InvalidateSession(ctx, identityID, jti); err != nil {
+		s.logger.Error("failed to revoke session", zap.Error(err))
+	}
+	if err := s.sessionManager.BlacklistToken(ctx, jti, remainingTTL); err != nil {
+		s.logger.Error("failed to blacklist token", zap.Error(err))
+	}
+
+	msg := websocket.NewMessage("session:revoked", map[string]interface{}{
+		"identity_id": identityID,
+		"reason":      "device_mismatch",
+		"revoked_at":  time.Now(),
+	})
+	s.hub.BroadcastMessage(&ws.BroadcastMessage{
+		IdentityIDs: []int64{identityID},
+		Channel:     websocket.ChannelSystem,
+		Message:     msg,
+	})
+}
+
 // Add these methods to internal/service/auth/auth_service.go
 
 // ========== Profile Methods ==========
@@ -637,6 +769,92 @@ func (s *AuthService) ResendEmailVerification(ctx context.Context, identityID in
 	return s.SendEmailVerification(ctx, identityID, identity.Email.String)
 }
 
+// ========== Email Change ==========
+
+// RequestEmailChange emails a confirmation link to the new address; the email is not changed until confirmed
+func (s *AuthService) RequestEmailChange(ctx context.Context, identityID int64, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+
+	identity, err := s.authRepo.FindIdentityByID(ctx, identityID)
+	if err != nil {
+		return fmt.Errorf("identity not found: %w", err)
+	}
+
+	if identity.Email.Valid && strings.EqualFold(identity.Email.String, newEmail) {
+		return fmt.Errorf("new email is the same as the current email")
+	}
+
+	exists, err := s.authRepo.ExistsByEmail(ctx, newEmail)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if exists {
+		return xerrors.ErrDuplicateEntry
+	}
+
+	token := generateToken()
+	vToken := &auth.VerificationToken{
+		IdentityID: identityID,
+		TokenType:  "email_change",
+		Token:      token,
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+		Metadata: map[string]interface{}{
+			"new_email": newEmail,
+		},
+	}
+
+	if err := s.authRepo.CreateVerificationToken(ctx, vToken); err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	profile, _ := s.authRepo.GetUserProfile(ctx, identityID)
+	fullName := "User"
+	if profile != nil && profile.FullName.Valid {
+		fullName = profile.FullName.String
+	}
+
+	s.emailHelper.SendEmailChangeVerification(ctx, newEmail, fullName, token)
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending email and logs out all sessions
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) error {
+	vToken, err := s.authRepo.FindVerificationToken(ctx, "email_change", token)
+	if err != nil {
+		return fmt.Errorf("invalid or expired token")
+	}
+
+	newEmail, _ := vToken.Metadata["new_email"].(string)
+	if newEmail == "" {
+		return fmt.Errorf("invalid or expired token")
+	}
+
+	// The address may have been taken since the change was requested
+	exists, err := s.authRepo.ExistsByEmail(ctx, newEmail)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if exists {
+		return xerrors.ErrDuplicateEntry
+	}
+
+	if err := s.authRepo.UpdateIdentityEmail(ctx, vToken.IdentityID, newEmail); err != nil {
+		return err
+	}
+
+	// Mark token as used
+	if err := s.authRepo.MarkTokenAsUsed(ctx, vToken.ID); err != nil {
+		s.logger.Error("failed to mark token as used", zap.Error(err))
+	}
+
+	// Invalidate all sessions
+	if err := s.LogoutAllSessions(ctx, vToken.IdentityID); err != nil {
+		return fmt.Errorf("failed to invalidate sessions: %w", err)
+	}
+
+	return nil
+}
+
 // ========== Session Management ==========
 
 // GetActiveSessions returns all active sessions for a user
@@ -651,12 +869,13 @@ func (s *AuthService) GetActiveSessions(ctx context.Context, identityID int64) (
 
 // RevokeSession revokes a specific session
 func (s *AuthService) RevokeSession(ctx context.Context, identityID int64, sessionID string) error {
+	remainingTTL := s.blacklistTTL(ctx, identityID, sessionID)
+
 	if err := s.sessionManager.InvalidateSession(ctx, identityID, sessionID); err != nil {
 		return fmt.Errorf("failed to revoke session: %w", err)
 	}
 
 	// Blacklist the token
-	remainingTTL := time.Until(time.Now().Add(s.jwtManager.Generator.Ttl))
 	if err := s.sessionManager.BlacklistToken(ctx, sessionID, remainingTTL); err != nil {
 		return fmt.Errorf("failed to blacklist token: %w", err)
 	}
@@ -664,6 +883,48 @@ func (s *AuthService) RevokeSession(ctx context.Context, identityID int64, sessi
 	return nil
 }
 
+// RevokeSessionsByDevice revokes every active session signed in from a device, e.g. after it is lost,
+// and returns how many were revoked. ErrNotFound if no active session is on the device.
+func (s *AuthService) RevokeSessionsByDevice(ctx context.Context, identityID int64, deviceID string) (int, error) {
+	if strings.TrimSpace(deviceID) == "" {
+		return 0, xerrors.ErrNotFound
+	}
+
+	sessions, err := s.sessionManager.GetUserActiveSessions(ctx, identityID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	revoked := 0
+	for _, sess := range sessions {
+		if sess.DeviceID != deviceID {
+			continue
+		}
+
+		remainingTTL := s.blacklistTTL(ctx, identityID, sess.JTI)
+
+		if err := s.sessionManager.InvalidateSession(ctx, identityID, sess.JTI); err != nil {
+			return revoked, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := s.sessionManager.BlacklistToken(ctx, sess.JTI, remainingTTL); err != nil {
+			return revoked, fmt.Errorf("failed to blacklist token: %w", err)
+		}
+		revoked++
+	}
+
+	if revoked == 0 {
+		return 0, xerrors.ErrNotFound
+	}
+
+	s.logger.Info("device sessions revoked",
+		zap.Int64("identity_id", identityID),
+		zap.String("device_id", deviceID),
+		zap.Int("count", revoked),
+	)
+
+	return revoked, nil
+}
+
 // ========== Admin Management (Super Admin Only) ==========
 
 // CreateAdmin creates a new admin user
@@ -740,6 +1001,37 @@ func (s *AuthService) ListAdmins(ctx context.Context) ([]*auth.UserProfile, erro
 	return nil, fmt.Errorf("not implemented")
 }
 
+// RecordEmailDelivery stores the outcome of an email send attempt (registered as an email sender hook)
+func (s *AuthService) RecordEmailDelivery(ctx context.Context, entry *emaillog.EmailLog) {
+	if err := s.emailLogRepo.Create(ctx, entry); err != nil {
+		s.logger.Error("failed to record email delivery",
+			zap.String("email", mask.Email(entry.Recipient)),
+			zap.String("type", string(entry.Type)),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListEmailLogs retrieves email delivery history (admin only)
+func (s *AuthService) ListEmailLogs(ctx context.Context, filters *emaillog.EmailLogListFilters) (*emaillog.EmailLogListResponse, error) {
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
+
+	logs, total, err := s.emailLogRepo.List(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email logs: %w", err)
+	}
+
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
+
+	return &emaillog.EmailLogListResponse{
+		Logs:       logs,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
+	}, nil
+}
+
 // DeactivateUser deactivates a user account
 func (s *AuthService) DeactivateUser(ctx context.Context, identityID int64) error {
 	if err := s.authRepo.UpdateIdentityStatus(ctx, identityID, "inactive"); err != nil {
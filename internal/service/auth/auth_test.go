@@ -0,0 +1,214 @@
+// internal/service/auth/auth_test.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/auth"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/jwt"
+	"bingwa-service/internal/pkg/session"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+	ws "bingwa-service/internal/websocket"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newTestAuthService wires an AuthService against a test database and an in-memory Redis
+func newTestAuthService(t *testing.T) (*AuthService, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	authRepo := postgres.NewAuthRepository(pool)
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	jwtManager := &jwt.Manager{
+		Generator: jwt.NewGenerator(key, "bingwa-test", "bingwa-test", "test", 15*time.Minute),
+		Verifier:  jwt.NewVerifier(&key.PublicKey, "bingwa-test", "bingwa-test"),
+	}
+	sessionManager := session.NewManager(client, authRepo)
+
+	svc := NewAuthService(
+		authRepo,
+		postgres.NewAgentOfferRepository(pool, postgres.NewOfferUSSDCodeRepository(pool), db),
+		postgres.NewOfferRequestRepository(pool),
+		postgres.NewOfferRedemptionRepository(pool),
+		postgres.NewAgentSubscriptionRepository(pool),
+		postgres.NewEmailLogRepository(pool),
+		jwtManager,
+		sessionManager,
+		session.NewRateLimiter(client),
+		nil,
+		ws.NewHub(jwtManager.Verifier, sessionManager),
+		client,
+		zap.NewNop(),
+	)
+	return svc, pool
+}
+
+func TestFingerprintMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		bound     string
+		presented string
+		want      bool
+	}{
+		{"same device", "fp-1", "fp-1", true},
+		{"different device", "fp-1", "fp-2", false},
+		{"blank request", "fp-1", "", false},
+		{"blank session", "", "fp-1", false},
+		{"both blank", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprintMatches(tt.bound, tt.presented); got != tt.want {
+				t.Errorf("fingerprintMatches(%q, %q) = %v, want %v", tt.bound, tt.presented, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRevokesMismatchedStrictSession(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestAuthService(t)
+
+	for _, presented := range []string{"fp-2", ""} {
+		token, jti, err := svc.jwtManager.Generator.GenerateAccessToken(1, []string{"agent"}, nil, "phone", nil)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken: %v", err)
+		}
+		err = svc.sessionManager.CreateSession(ctx, &session.SessionData{
+			JTI:               jti,
+			IdentityID:        1,
+			ExpiresAt:         time.Now().Add(15 * time.Minute),
+			IsActive:          true,
+			DeviceFingerprint: "fp-1",
+			StrictDevice:      true,
+		})
+		if err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+
+		if _, err := svc.ValidateToken(ctx, token, presented); !errors.Is(err, xerrors.ErrUnauthorized) {
+			t.Fatalf("ValidateToken(%q) error = %v, want ErrUnauthorized", presented, err)
+		}
+
+		// The session is gone, so even the bound device is refused afterwards
+		if _, err := svc.ValidateToken(ctx, token, "fp-1"); err == nil {
+			t.Fatalf("ValidateToken after a %q mismatch succeeded, want the token revoked", presented)
+		}
+	}
+}
+
+func TestAdminAccessTokenExpiresSooner(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestAuthService(t)
+	svc.jwtManager.Generator.AdminTtl = 5 * time.Minute
+
+	login := func(email, role string) *auth.LoginResponse {
+		t.Helper()
+		id := testutil.Identity(t, pool, email)
+		testutil.Role(t, pool, id, role)
+		if _, err := pool.Exec(ctx, `INSERT INTO user_profiles (identity_id) VALUES ($1)`, id); err != nil {
+			t.Fatalf("failed to seed profile: %v", err)
+		}
+		identity, err := svc.authRepo.FindIdentityByID(ctx, id)
+		if err != nil {
+			t.Fatalf("FindIdentityByID: %v", err)
+		}
+		resp, err := svc.loginWithIdentity(ctx, identity, &auth.Provider{Provider: "local"}, "web", "", "10.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("loginWithIdentity(%s): %v", role, err)
+		}
+		return resp
+	}
+
+	agent := login("agent@example.com", "user")
+	admin := login("admin@example.com", "admin")
+
+	if agent.ExpiresIn != int((15 * time.Minute).Seconds()) {
+		t.Errorf("agent token expires in %ds, want the standard 900s", agent.ExpiresIn)
+	}
+	if admin.ExpiresIn != int((5 * time.Minute).Seconds()) {
+		t.Errorf("admin token expires in %ds, want the admin 300s", admin.ExpiresIn)
+	}
+
+	// The token itself carries the shorter expiry, not just the response
+	claims, err := svc.jwtManager.Verifier.VerifyAccessToken(admin.AccessToken)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got := claims.ExpiresAt.Time; got.Sub(admin.ExpiresAt).Abs() > time.Second {
+		t.Errorf("admin token expires at %v, want %v", got, admin.ExpiresAt)
+	}
+}
+
+func TestRevokeSessionsByDeviceRevokesEverySessionOnIt(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestAuthService(t)
+
+	sessions := map[string]string{} // jti -> device
+	for _, device := range []string{"pixel-7", "pixel-7", "laptop"} {
+		_, jti, err := svc.jwtManager.Generator.GenerateAccessToken(1, []string{"user"}, nil, device, nil)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken: %v", err)
+		}
+		if err := svc.sessionManager.CreateSession(ctx, &session.SessionData{
+			JTI:        jti,
+			IdentityID: 1,
+			DeviceID:   device,
+			ExpiresAt:  time.Now().Add(15 * time.Minute),
+			IsActive:   true,
+		}); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		sessions[jti] = device
+	}
+
+	revoked, err := svc.RevokeSessionsByDevice(ctx, 1, "pixel-7")
+	if err != nil {
+		t.Fatalf("RevokeSessionsByDevice: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("revoked %d sessions, want 2", revoked)
+	}
+
+	for jti, device := range sessions {
+		blacklisted, err := svc.sessionManager.IsTokenBlacklisted(ctx, jti)
+		if err != nil {
+			t.Fatalf("IsTokenBlacklisted: %v", err)
+		}
+		if want := device == "pixel-7"; blacklisted != want {
+			t.Errorf("%s session blacklisted = %v, want %v", device, blacklisted, want)
+		}
+	}
+	remaining, err := svc.sessionManager.GetUserActiveSessions(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUserActiveSessions: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].DeviceID != "laptop" {
+		t.Errorf("%d sessions left, want only the laptop's", len(remaining))
+	}
+
+	// Nothing is left on the device to revoke
+	if _, err := svc.RevokeSessionsByDevice(ctx, 1, "pixel-7"); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("second RevokeSessionsByDevice error = %v, want ErrNotFound", err)
+	}
+}
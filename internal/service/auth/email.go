@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strings"
 
+	"bingwa-service/internal/domain/emaillog"
+	"bingwa-service/internal/pkg/mask"
 	"bingwa-service/internal/service/email"
 
 	"go.uber.org/zap"
@@ -86,14 +88,14 @@ func (h *EmailHelper) PasswordResetEmail(fullName, token string) (string, string
 func (h *EmailHelper) SendPasswordResetEmail(ctx context.Context, email, fullName, token string) {
 	go func() {
 		subject, body := h.PasswordResetEmail(fullName, token)
-		if err := h.sender.Send(email, subject, body); err != nil {
+		if err := h.sender.SendAs(emaillog.TypePasswordReset, email, subject, body); err != nil {
 			h.logger.Error("failed to send password reset email",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 				zap.Error(err),
 			)
 		} else {
 			h.logger.Info("password reset email sent",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 			)
 		}
 	}()
@@ -151,14 +153,80 @@ func (h *EmailHelper) EmailVerificationEmail(fullName, token string) (string, st
 func (h *EmailHelper) SendEmailVerification(ctx context.Context, email, fullName, token string) {
 	go func() {
 		subject, body := h.EmailVerificationEmail(fullName, token)
-		if err := h.sender.Send(email, subject, body); err != nil {
+		if err := h.sender.SendAs(emaillog.TypeEmailVerification, email, subject, body); err != nil {
 			h.logger.Error("failed to send email verification",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 				zap.Error(err),
 			)
 		} else {
 			h.logger.Info("email verification sent",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
+			)
+		}
+	}()
+}
+
+// ========== Email Change ==========
+
+// EmailChangeVerificationEmail builds the confirmation email sent to a new address
+func (h *EmailHelper) EmailChangeVerificationEmail(fullName, newEmail, token string) (string, string) {
+	confirmURL := fmt.Sprintf("%s/auth/confirm-email-change?token=%s", h.baseURL, token)
+
+	subject := "Confirm Your New Email - TaskaApp"
+	body := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<style>
+				body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+				.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+				.button { 
+					display: inline-block; 
+					padding: 12px 24px; 
+					background-color: #2196F3; 
+					color: white; 
+					text-decoration: none; 
+					border-radius: 4px; 
+					margin: 20px 0;
+				}
+				.footer { margin-top: 30px; font-size: 12px; color: #666; }
+			</style>
+		</head>
+		<body>
+			<div class="container">
+				<h2>Confirm Your New Email</h2>
+				<p>Hello %s,</p>
+				<p>We received a request to change your account email to <strong>%s</strong>.</p>
+				<p>Click the button below to confirm the change:</p>
+				<a href="%s" class="button">Confirm Email</a>
+				<p>Or copy and paste this link into your browser:</p>
+				<p><a href="%s">%s</a></p>
+				<p><strong>This link will expire in 24 hours.</strong></p>
+				<p>Once confirmed, you will be logged out of all devices.</p>
+				<div class="footer">
+					<p>If you didn't request this change, you can safely ignore this email.</p>
+					<p>This is an automated email, please do not reply.</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, fullName, newEmail, confirmURL, confirmURL, confirmURL)
+
+	return subject, body
+}
+
+// SendEmailChangeVerification sends the email change confirmation asynchronously
+func (h *EmailHelper) SendEmailChangeVerification(ctx context.Context, email, fullName, token string) {
+	go func() {
+		subject, body := h.EmailChangeVerificationEmail(fullName, email, token)
+		if err := h.sender.SendAs(emaillog.TypeEmailChange, email, subject, body); err != nil {
+			h.logger.Error("failed to send email change verification",
+				zap.String("email", mask.Email(email)),
+				zap.Error(err),
+			)
+		} else {
+			h.logger.Info("email change verification sent",
+				zap.String("email", mask.Email(email)),
 			)
 		}
 	}()
@@ -223,14 +291,14 @@ func (h *EmailHelper) WelcomeEmail(fullName, email string) (string, string) {
 func (h *EmailHelper) SendWelcomeEmail(ctx context.Context, email, fullName string) {
 	go func() {
 		subject, body := h.WelcomeEmail(fullName, email)
-		if err := h.sender.Send(email, subject, body); err != nil {
+		if err := h.sender.SendAs(emaillog.TypeWelcome, email, subject, body); err != nil {
 			h.logger.Error("failed to send welcome email",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 				zap.Error(err),
 			)
 		} else {
 			h.logger.Info("welcome email sent",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 			)
 		}
 	}()
@@ -307,14 +375,14 @@ func (h *EmailHelper) AccountCreatedByAdminEmail(fullName, email, temporaryPassw
 func (h *EmailHelper) SendAccountCreatedByAdmin(ctx context.Context, email, fullName, temporaryPassword string, roles []string) {
 	go func() {
 		subject, body := h.AccountCreatedByAdminEmail(fullName, email, temporaryPassword, roles)
-		if err := h.sender.Send(email, subject, body); err != nil {
+		if err := h.sender.SendAs(emaillog.TypeAccountCreated, email, subject, body); err != nil {
 			h.logger.Error("failed to send account created email",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 				zap.Error(err),
 			)
 		} else {
 			h.logger.Info("account created email sent",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 			)
 		}
 	}()
@@ -377,14 +445,14 @@ func (h *EmailHelper) PasswordChangedEmail(fullName string) (string, string) {
 func (h *EmailHelper) SendPasswordChangedNotification(ctx context.Context, email, fullName string) {
 	go func() {
 		subject, body := h.PasswordChangedEmail(fullName)
-		if err := h.sender.Send(email, subject, body); err != nil {
+		if err := h.sender.SendAs(emaillog.TypePasswordChanged, email, subject, body); err != nil {
 			h.logger.Error("failed to send password changed notification",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 				zap.Error(err),
 			)
 		} else {
 			h.logger.Info("password changed notification sent",
-				zap.String("email", email),
+				zap.String("email", mask.Email(email)),
 			)
 		}
 	}()
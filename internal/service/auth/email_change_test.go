@@ -0,0 +1,92 @@
+// internal/service/auth/email_change_test.go
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/service/email"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestEmailChangeRequiresConfirmationAndUniqueAddress(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestAuthService(t)
+	smtp := testutil.SMTP(t)
+	svc.emailHelper = NewEmailHelper(
+		email.NewEmailSender(smtp.Host, smtp.Port, "noreply@example.com", "secret", "Bingwa", false),
+		zap.NewNop(), "https://bingwa.test",
+	)
+
+	agentID := testutil.Identity(t, pool, "change@example.com")
+	testutil.Identity(t, pool, "taken@example.com")
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO auth_sessions (identity_id, session_token, provider, expires_at)
+		VALUES ($1, 'token-1', 'local', $2)
+	`, agentID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to seed session: %v", err)
+	}
+
+	// Another identity's address is refused whatever its case
+	if err := svc.RequestEmailChange(ctx, agentID, "Taken@Example.com"); !errors.Is(err, xerrors.ErrDuplicateEntry) {
+		t.Fatalf("RequestEmailChange to a taken address error = %v, want ErrDuplicateEntry", err)
+	}
+
+	if err := svc.RequestEmailChange(ctx, agentID, " new@example.com "); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+
+	currentEmail := func() string {
+		t.Helper()
+		var address sql.NullString
+		if err := pool.QueryRow(ctx, `SELECT email FROM auth_identities WHERE id = $1`, agentID).Scan(&address); err != nil {
+			t.Fatalf("failed to read email: %v", err)
+		}
+		return address.String
+	}
+	if got := currentEmail(); got != "change@example.com" {
+		t.Errorf("email before confirmation = %q, want it unchanged", got)
+	}
+
+	var token string
+	if err := pool.QueryRow(ctx, `
+		SELECT token FROM auth_verification_tokens WHERE identity_id = $1 AND token_type = 'email_change'
+	`, agentID).Scan(&token); err != nil {
+		t.Fatalf("failed to read change token: %v", err)
+	}
+
+	if err := svc.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	if got := currentEmail(); got != "new@example.com" {
+		t.Errorf("email after confirmation = %q, want new@example.com", got)
+	}
+
+	var sessionStatus string
+	if err := pool.QueryRow(ctx, `SELECT status::text FROM auth_sessions WHERE identity_id = $1`, agentID).Scan(&sessionStatus); err != nil {
+		t.Fatalf("failed to read session: %v", err)
+	}
+	if sessionStatus != "revoked" {
+		t.Errorf("session = %s after the email changed, want revoked", sessionStatus)
+	}
+
+	// The link works once
+	if err := svc.ConfirmEmailChange(ctx, token); err == nil {
+		t.Error("ConfirmEmailChange accepted a used token")
+	}
+
+	// The confirmation goes to the new address
+	deadline := time.Now().Add(5 * time.Second)
+	for len(smtp.Delivered()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := smtp.Delivered(); len(got) != 1 || got[0] != "new@example.com" {
+		t.Errorf("delivered = %v, want one confirmation to new@example.com", got)
+	}
+}
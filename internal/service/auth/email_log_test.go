@@ -0,0 +1,59 @@
+// internal/service/auth/email_log_test.go
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"bingwa-service/internal/domain/emaillog"
+	"bingwa-service/internal/service/email"
+	"bingwa-service/internal/testutil"
+)
+
+func TestFailedEmailSendIsLogged(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestAuthService(t)
+
+	// A port nothing listens on, so every send fails to connect
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve a port: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	failing := email.NewEmailSender(host, port, "noreply@example.com", "secret", "Bingwa", false)
+	failing.OnResult(svc.RecordEmailDelivery)
+	if err := failing.SendAs(emaillog.TypeWelcome, "jane@example.com", "Welcome", "<p>Hi</p>"); err == nil {
+		t.Fatal("SendAs to a closed port succeeded")
+	}
+
+	smtp := testutil.SMTP(t)
+	working := email.NewEmailSender(smtp.Host, smtp.Port, "noreply@example.com", "secret", "Bingwa", false)
+	working.OnResult(svc.RecordEmailDelivery)
+	if err := working.SendAs(emaillog.TypeWelcome, "john@example.com", "Welcome", "<p>Hi</p>"); err != nil {
+		t.Fatalf("SendAs: %v", err)
+	}
+
+	failed := emaillog.StatusFailed
+	logs, err := svc.ListEmailLogs(ctx, &emaillog.EmailLogListFilters{Status: &failed})
+	if err != nil {
+		t.Fatalf("ListEmailLogs: %v", err)
+	}
+	if logs.Total != 1 || len(logs.Logs) != 1 {
+		t.Fatalf("failed logs = %d, want 1", logs.Total)
+	}
+	entry := logs.Logs[0]
+	if entry.Recipient != "jane@example.com" || entry.Type != emaillog.TypeWelcome || !entry.Error.Valid {
+		t.Errorf("failed entry = %+v, want jane's welcome email with the send error", entry)
+	}
+
+	all, err := svc.ListEmailLogs(ctx, &emaillog.EmailLogListFilters{})
+	if err != nil {
+		t.Fatalf("ListEmailLogs: %v", err)
+	}
+	if all.Total != 2 {
+		t.Errorf("all logs = %d, want the failed and the sent email", all.Total)
+	}
+}
@@ -0,0 +1,81 @@
+// internal/service/auth/role_expiry.go
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	notificationsvc "bingwa-service/internal/service/notification"
+
+	"go.uber.org/zap"
+)
+
+// RoleExpiryWorker deactivates temporary role assignments once they lapse and tells the user
+type RoleExpiryWorker struct {
+	authService  *AuthService
+	notifService *notificationsvc.NotificationService
+	interval     time.Duration
+	logger       *zap.Logger
+}
+
+func NewRoleExpiryWorker(
+	authService *AuthService,
+	notifService *notificationsvc.NotificationService,
+	interval time.Duration,
+	logger *zap.Logger,
+) *RoleExpiryWorker {
+	return &RoleExpiryWorker{
+		authService:  authService,
+		notifService: notifService,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *RoleExpiryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("role expiry run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce expires lapsed role assignments and returns how many were expired
+func (w *RoleExpiryWorker) RunOnce(ctx context.Context) (int, error) {
+	expired, err := w.authService.ExpireRoles(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range expired {
+		message := fmt.Sprintf("Your %s role expired on %s. Sign in again to continue.", a.RoleDisplayName, a.ExpiresAt.Format("02 Jan 2006 15:04"))
+		if err := w.notifService.SendInfoNotification(ctx, a.IdentityID, "Role expired", message, map[string]interface{}{
+			"role_id":    a.RoleID,
+			"role":       a.RoleName,
+			"expires_at": a.ExpiresAt,
+		}); err != nil {
+			w.logger.Warn("failed to notify user about expired role",
+				zap.Int64("identity_id", a.IdentityID),
+				zap.String("role", a.RoleName),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(expired) > 0 {
+		w.logger.Info("role assignments expired", zap.Int("count", len(expired)))
+	}
+
+	return len(expired), nil
+}
@@ -0,0 +1,205 @@
+// internal/service/auth/roles.go
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"bingwa-service/internal/domain/auth"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// CreateRole creates a custom (non-system) role
+func (s *AuthService) CreateRole(ctx context.Context, req *auth.CreateRoleRequest) (*auth.Role, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if name == "" {
+		return nil, fmt.Errorf("role name is required: %w", xerrors.ErrInvalidInput)
+	}
+
+	exists, err := s.authRepo.RoleExists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check role: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("role %q already exists: %w", name, xerrors.ErrConflict)
+	}
+
+	role := &auth.Role{
+		Name:        name,
+		DisplayName: strings.TrimSpace(req.DisplayName),
+		Description: req.Description,
+	}
+	if err := s.authRepo.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("role created", zap.Int64("role_id", role.ID), zap.String("name", role.Name))
+
+	return role, nil
+}
+
+// ListRoles lists all roles, system roles first
+func (s *AuthService) ListRoles(ctx context.Context) ([]auth.Role, error) {
+	return s.authRepo.ListRoles(ctx)
+}
+
+// DeleteRole deletes a custom role; system roles can't be deleted
+func (s *AuthService) DeleteRole(ctx context.Context, roleID int64) error {
+	role, err := s.authRepo.FindRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return fmt.Errorf("system role %q cannot be deleted: %w", role.Name, xerrors.ErrForbidden)
+	}
+
+	if err := s.authRepo.DeleteRole(ctx, roleID); err != nil {
+		return err
+	}
+
+	s.logger.Info("role deleted", zap.Int64("role_id", roleID), zap.String("name", role.Name))
+
+	return nil
+}
+
+// AssignRole assigns a role to a user, optionally until expiresAt (e.g. a trial admin).
+// The user's existing sessions keep their old roles until they sign in again.
+func (s *AuthService) AssignRole(ctx context.Context, identityID int64, req *auth.AssignRoleRequest, assignedBy int64) error {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future: %w", xerrors.ErrInvalidInput)
+	}
+
+	if _, err := s.authRepo.FindIdentityByID(ctx, identityID); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	role, err := s.authRepo.GetRoleByName(ctx, strings.ToLower(strings.TrimSpace(req.RoleName)))
+	if err != nil {
+		return err
+	}
+	if !role.IsActive {
+		return fmt.Errorf("role %q is inactive: %w", role.Name, xerrors.ErrInvalidInput)
+	}
+
+	if err := s.authRepo.AssignRoleWithExpiry(ctx, identityID, role.ID, assignedBy, req.ExpiresAt); err != nil {
+		return err
+	}
+
+	fields := []zap.Field{
+		zap.Int64("identity_id", identityID),
+		zap.String("role", role.Name),
+		zap.Int64("assigned_by", assignedBy),
+	}
+	if req.ExpiresAt != nil {
+		fields = append(fields, zap.Time("expires_at", *req.ExpiresAt))
+	}
+	s.logger.Info("role assigned", fields...)
+
+	return nil
+}
+
+// ExpireRoles deactivates lapsed role assignments and signs the affected users out so their tokens drop the role
+func (s *AuthService) ExpireRoles(ctx context.Context) ([]auth.ExpiredRoleAssignment, error) {
+	expired, err := s.authRepo.DeactivateExpiredRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signedOut := make(map[int64]bool, len(expired))
+	for _, a := range expired {
+		if signedOut[a.IdentityID] {
+			continue
+		}
+		signedOut[a.IdentityID] = true
+
+		if err := s.LogoutAllSessions(ctx, a.IdentityID); err != nil {
+			s.logger.Warn("failed to sign out user after role expiry", zap.Int64("identity_id", a.IdentityID), zap.Error(err))
+		}
+	}
+
+	return expired, nil
+}
+
+// ListPermissions lists all permissions
+func (s *AuthService) ListPermissions(ctx context.Context) ([]auth.Permission, error) {
+	return s.authRepo.ListPermissions(ctx)
+}
+
+// AssignPermissionToRole gives every holder of a role the permission
+func (s *AuthService) AssignPermissionToRole(ctx context.Context, roleID, permissionID, grantedBy int64) error {
+	if _, err := s.authRepo.FindRoleByID(ctx, roleID); err != nil {
+		return err
+	}
+	if _, err := s.authRepo.FindPermissionByID(ctx, permissionID); err != nil {
+		return err
+	}
+
+	if err := s.authRepo.AssignPermissionToRole(ctx, roleID, permissionID, grantedBy); err != nil {
+		return err
+	}
+
+	s.logger.Info("permission assigned to role",
+		zap.Int64("role_id", roleID),
+		zap.Int64("permission_id", permissionID),
+		zap.Int64("granted_by", grantedBy),
+	)
+
+	return nil
+}
+
+// GrantPermissionToUser grants a permission to one user on top of their roles
+func (s *AuthService) GrantPermissionToUser(ctx context.Context, identityID, permissionID, grantedBy int64, expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future: %w", xerrors.ErrInvalidInput)
+	}
+
+	return s.setUserPermission(ctx, identityID, permissionID, true, grantedBy, expiresAt)
+}
+
+// RevokePermissionFromUser denies a permission to one user, even if one of their roles grants it
+func (s *AuthService) RevokePermissionFromUser(ctx context.Context, identityID, permissionID, revokedBy int64) error {
+	return s.setUserPermission(ctx, identityID, permissionID, false, revokedBy, nil)
+}
+
+// GetUserPermissions lists a user's effective permissions
+func (s *AuthService) GetUserPermissions(ctx context.Context, identityID int64) ([]string, error) {
+	if _, err := s.authRepo.FindIdentityByID(ctx, identityID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	permissions, err := s.authRepo.GetUserPermissions(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return permissions, nil
+}
+
+func (s *AuthService) setUserPermission(ctx context.Context, identityID, permissionID int64, granted bool, changedBy int64, expiresAt *time.Time) error {
+	if _, err := s.authRepo.FindIdentityByID(ctx, identityID); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	if _, err := s.authRepo.FindPermissionByID(ctx, permissionID); err != nil {
+		return err
+	}
+
+	if err := s.authRepo.SetUserPermission(ctx, identityID, permissionID, granted, changedBy, expiresAt); err != nil {
+		return err
+	}
+
+	s.logger.Info("user permission changed",
+		zap.Int64("identity_id", identityID),
+		zap.Int64("permission_id", permissionID),
+		zap.Bool("granted", granted),
+		zap.Int64("changed_by", changedBy),
+	)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// internal/service/auth/roles_test.go
+package auth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/auth"
+	"bingwa-service/internal/testutil"
+)
+
+func TestGrantedPermissionIsInUserPermissions(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestAuthService(t)
+
+	adminID := testutil.Identity(t, pool, "root@example.com")
+	userID := testutil.Identity(t, pool, "grantee@example.com")
+	var permissionID int64
+	if err := pool.QueryRow(ctx, `SELECT id FROM auth_permissions WHERE name = 'reports.view'`).Scan(&permissionID); err != nil {
+		t.Fatalf("failed to look up permission: %v", err)
+	}
+
+	before, err := svc.GetUserPermissions(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserPermissions: %v", err)
+	}
+	if slices.Contains(before, "reports.view") {
+		t.Fatalf("permissions before the grant = %v, want no reports.view", before)
+	}
+
+	if err := svc.GrantPermissionToUser(ctx, userID, permissionID, adminID, nil); err != nil {
+		t.Fatalf("GrantPermissionToUser: %v", err)
+	}
+	after, err := svc.GetUserPermissions(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserPermissions: %v", err)
+	}
+	if !slices.Contains(after, "reports.view") {
+		t.Errorf("permissions after the grant = %v, want reports.view", after)
+	}
+
+	// Revoking the grant takes it back out
+	if err := svc.RevokePermissionFromUser(ctx, userID, permissionID, adminID); err != nil {
+		t.Fatalf("RevokePermissionFromUser: %v", err)
+	}
+	revoked, err := svc.GetUserPermissions(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserPermissions: %v", err)
+	}
+	if slices.Contains(revoked, "reports.view") {
+		t.Errorf("permissions after the revoke = %v, want no reports.view", revoked)
+	}
+}
+
+func TestExpiredRoleIsNotInUserRoles(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestAuthService(t)
+
+	adminID := testutil.Identity(t, pool, "root@example.com")
+	userID := testutil.Identity(t, pool, "trial@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+	if err := svc.AssignRole(ctx, userID, &auth.AssignRoleRequest{RoleName: "admin", ExpiresAt: &expiresAt}, adminID); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	roles, err := svc.authRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserRoles: %v", err)
+	}
+	if !slices.Contains(roles, "admin") {
+		t.Fatalf("roles before expiry = %v, want admin", roles)
+	}
+
+	// Let the assignment lapse without waiting for the worker
+	if _, err := pool.Exec(ctx, `
+		UPDATE auth_identity_roles SET expires_at = NOW() - INTERVAL '1 minute'
+		WHERE identity_id = $1 AND role_id = (SELECT id FROM auth_roles WHERE name = 'admin')
+	`, userID); err != nil {
+		t.Fatalf("failed to backdate role expiry: %v", err)
+	}
+	roles, err = svc.authRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserRoles: %v", err)
+	}
+	if slices.Contains(roles, "admin") {
+		t.Errorf("roles after expiry = %v, want no admin", roles)
+	}
+
+	// The worker's sweep picks up the lapsed assignment
+	expired, err := svc.ExpireRoles(ctx)
+	if err != nil {
+		t.Fatalf("ExpireRoles: %v", err)
+	}
+	if len(expired) != 1 || expired[0].IdentityID != userID || expired[0].RoleName != "admin" {
+		t.Errorf("expired assignments = %+v, want the user's admin role", expired)
+	}
+}
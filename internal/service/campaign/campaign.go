@@ -5,11 +5,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"bingwa-service/internal/domain/campaign"
 	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/pagination"
 	"bingwa-service/internal/repository/postgres"
 
 	"github.com/lib/pq"
@@ -47,6 +49,10 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *campaign.Crea
 		return nil, err
 	}
 
+	if err := s.validateVariants(req.DiscountType, req.Variants); err != nil {
+		return nil, err
+	}
+
 	// Check if promotional code already exists
 	exists, err := s.campaignRepo.ExistsByPromotionalCode(ctx, req.PromotionalCode)
 	if err != nil {
@@ -76,6 +82,7 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *campaign.Crea
 		CurrentUses:     0,
 		ApplicablePlans: pq.Int64Array(req.ApplicablePlans),
 		TargetUserTypes: pq.StringArray(req.TargetUserTypes),
+		Variants:        req.Variants,
 		Status:          campaign.CampaignStatusActive,
 		Metadata:        req.Metadata,
 	}
@@ -87,6 +94,10 @@ func (s *CampaignService) CreateCampaign(ctx context.Context, req *campaign.Crea
 	if req.MaxUses != nil {
 		c.MaxUses = sql.NullInt32{Int32: *req.MaxUses, Valid: true}
 	}
+	if req.TotalBudget != nil {
+		c.TotalBudget = sql.NullFloat64{Float64: *req.TotalBudget, Valid: true}
+	}
+	c.DeactivateOnBudgetSpent = req.DeactivateOnBudgetSpent
 
 	// Create in database
 	if err := s.campaignRepo.Create(ctx, c); err != nil {
@@ -140,12 +151,24 @@ func (s *CampaignService) UpdateCampaign(ctx context.Context, id int64, req *cam
 	if req.UsesPerUser != nil {
 		c.UsesPerUser = *req.UsesPerUser
 	}
+	if req.TotalBudget != nil {
+		c.TotalBudget = sql.NullFloat64{Float64: *req.TotalBudget, Valid: *req.TotalBudget > 0}
+	}
+	if req.DeactivateOnBudgetSpent != nil {
+		c.DeactivateOnBudgetSpent = *req.DeactivateOnBudgetSpent
+	}
 	if req.ApplicablePlans != nil {
 		c.ApplicablePlans = pq.Int64Array(req.ApplicablePlans)
 	}
 	if req.TargetUserTypes != nil {
 		c.TargetUserTypes = pq.StringArray(req.TargetUserTypes)
 	}
+	if req.Variants != nil {
+		if err := s.validateVariants(c.DiscountType, req.Variants); err != nil {
+			return nil, err
+		}
+		c.Variants = req.Variants
+	}
 	if req.Metadata != nil {
 		c.Metadata = req.Metadata
 	}
@@ -235,32 +258,21 @@ func (s *CampaignService) GetCampaignByCode(ctx context.Context, promoCode strin
 // ListCampaigns retrieves campaigns with filters
 func (s *CampaignService) ListCampaigns(ctx context.Context, filters *campaign.CampaignListFilters) (*campaign.CampaignListResponse, error) {
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
-	}
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	campaigns, total, err := s.campaignRepo.List(ctx, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list campaigns: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &campaign.CampaignListResponse{
 		Campaigns:  campaigns,
-		Total:      total,
-		Page:       filters.Page,
-		PageSize:   filters.PageSize,
-		TotalPages: totalPages,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
 	}, nil
 }
 
@@ -274,8 +286,9 @@ func (s *CampaignService) GetActiveCampaigns(ctx context.Context) ([]campaign.Pr
 	return campaigns, nil
 }
 
-// ValidateCampaign validates a promotional code and calculates discount
-func (s *CampaignService) ValidateCampaign(ctx context.Context, req *campaign.ValidateCampaignRequest) (*campaign.ValidateCampaignResponse, error) {
+// ValidateCampaign validates a promotional code for a user. Campaigns running an A/B test place
+// the user in a variant and report the campaign with that variant's discount.
+func (s *CampaignService) ValidateCampaign(ctx context.Context, identityID int64, req *campaign.ValidateCampaignRequest) (*campaign.ValidateCampaignResponse, error) {
 	// Get campaign by promotional code
 	c, err := s.campaignRepo.FindByPromotionalCode(ctx, strings.ToUpper(req.PromotionalCode))
 	if err != nil {
@@ -342,13 +355,36 @@ func (s *CampaignService) ValidateCampaign(ctx context.Context, req *campaign.Va
 		}, nil
 	}
 
+	// Check budget
+	if c.TotalBudget.Valid && c.DiscountGiven >= c.TotalBudget.Float64 {
+		return &campaign.ValidateCampaignResponse{
+			Valid:    false,
+			Campaign: c,
+			Message:  "This promotional code's budget has been used up",
+		}, nil
+	}
+
 	// TODO: Check uses per user when agent_subscriptions table is implemented
 
-	return &campaign.ValidateCampaignResponse{
+	result := &campaign.ValidateCampaignResponse{
 		Valid:    true,
 		Campaign: c,
 		Message:  "Promotional code is valid",
-	}, nil
+	}
+
+	if variant := c.VariantFor(identityID); variant != nil {
+		stored, err := s.campaignRepo.AssignVariant(ctx, c.ID, identityID, variant.Name)
+		if err != nil {
+			return nil, err
+		}
+		if v := c.VariantNamed(stored); v != nil {
+			variant = v
+		}
+		c.DiscountValue = variant.DiscountValue
+		result.Variant = variant.Name
+	}
+
+	return result, nil
 }
 
 // ApplyCampaign applies a promotional campaign to calculate discount
@@ -378,8 +414,69 @@ func (s *CampaignService) GetCampaignStats(ctx context.Context) (*campaign.Campa
 	return stats, nil
 }
 
+// GetVariantReport reports each A/B variant's assignments and conversions (admin only).
+// Variants without assignments yet are listed with zero counts.
+func (s *CampaignService) GetVariantReport(ctx context.Context, campaignID int64) (*campaign.CampaignVariantReport, error) {
+	c, err := s.campaignRepo.FindByID(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := s.campaignRepo.GetVariantStats(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]campaign.CampaignVariantStats, len(recorded))
+	for _, st := range recorded {
+		byName[st.Variant] = st
+	}
+
+	report := &campaign.CampaignVariantReport{
+		CampaignID: campaignID,
+		Variants:   make([]campaign.CampaignVariantStats, 0, len(c.Variants)),
+	}
+	for _, v := range c.Variants {
+		st := byName[v.Name]
+		st.Variant = v.Name
+		st.DiscountValue = v.DiscountValue
+		if st.Assigned > 0 {
+			st.ConversionRate = math.Round(float64(st.Converted)/float64(st.Assigned)*10000) / 100
+		}
+		report.Variants = append(report.Variants, st)
+	}
+
+	return report, nil
+}
+
 // ========== Helper Methods ==========
 
+// validateVariants checks an A/B test has at least two uniquely named variants with valid discounts
+func (s *CampaignService) validateVariants(discountType campaign.DiscountType, variants []campaign.CampaignVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < 2 {
+		return fmt.Errorf("%w: an A/B test needs at least two variants", xerrors.ErrInvalidInput)
+	}
+
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if strings.TrimSpace(v.Name) == "" {
+			return fmt.Errorf("%w: variant name is required", xerrors.ErrInvalidInput)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: duplicate variant %q", xerrors.ErrInvalidInput, v.Name)
+		}
+		seen[v.Name] = true
+
+		if err := s.validateDiscountTypeAndValue(discountType, v.DiscountValue); err != nil {
+			return fmt.Errorf("variant %q: %w", v.Name, err)
+		}
+	}
+
+	return nil
+}
+
 // validateDiscountTypeAndValue validates discount type and value
 func (s *CampaignService) validateDiscountTypeAndValue(discountType campaign.DiscountType, value float64) error {
 	switch discountType {
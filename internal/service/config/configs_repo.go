@@ -4,23 +4,35 @@ package config
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"bingwa-service/internal/domain/config"
 	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/pkg/pagination"
+	signing "bingwa-service/internal/pkg/webhook"
 	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
 
 	"go.uber.org/zap"
 )
 
 type ConfigService struct {
 	configRepo *postgres.AgentConfigRepository
+	presence   *cache.DevicePresence
+	db         *postgres.DB
 	logger     *zap.Logger
 }
 
-func NewConfigService(configRepo *postgres.AgentConfigRepository, logger *zap.Logger) *ConfigService {
+func NewConfigService(configRepo *postgres.AgentConfigRepository, presence *cache.DevicePresence, db *postgres.DB, logger *zap.Logger) *ConfigService {
 	return &ConfigService{
 		configRepo: configRepo,
+		presence:   presence,
+		db:         db,
 		logger:     logger,
 	}
 }
@@ -110,32 +122,21 @@ func (s *ConfigService) GetConfigByKey(ctx context.Context, agentID int64, confi
 // ListConfigs retrieves configurations with filters
 func (s *ConfigService) ListConfigs(ctx context.Context, agentID int64, filters *config.ConfigListFilters) (*config.ConfigListResponse, error) {
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
-	}
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	configs, total, err := s.configRepo.List(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list configs: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &config.ConfigListResponse{
 		Configs:    configs,
-		Total:      total,
-		Page:       filters.Page,
-		PageSize:   filters.PageSize,
-		TotalPages: totalPages,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
 	}, nil
 }
 
@@ -188,7 +189,6 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, agentID, configID int6
 	}
 
 	// Update fields
-	cfg.ConfigValue = req.ConfigValue
 	if req.Description != nil {
 		cfg.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
 	}
@@ -197,7 +197,7 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, agentID, configID int6
 	}
 
 	// Update in database
-	if err := s.configRepo.Update(ctx, configID, cfg); err != nil {
+	if err := s.updateConfigWithHistory(ctx, cfg, req.ConfigValue); err != nil {
 		s.logger.Error("failed to update config", zap.Error(err))
 		return nil, fmt.Errorf("failed to update config: %w", err)
 	}
@@ -211,6 +211,25 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, agentID, configID int6
 	return s.configRepo.FindByID(ctx, configID)
 }
 
+// GetConfigHistory retrieves the change history of a configuration
+func (s *ConfigService) GetConfigHistory(ctx context.Context, agentID, configID int64) ([]config.AgentConfigHistory, error) {
+	// Verify ownership
+	cfg, err := s.configRepo.FindByID(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+
+	history, err := s.configRepo.ListHistory(ctx, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config history: %w", err)
+	}
+
+	return history, nil
+}
+
 // DeleteConfig deletes a configuration
 func (s *ConfigService) DeleteConfig(ctx context.Context, agentID, configID int64) error {
 	// Verify ownership
@@ -259,16 +278,29 @@ func (s *ConfigService) GetNotificationConfig(ctx context.Context, agentID int64
 // SetNotificationConfig sets notification configuration
 func (s *ConfigService) SetNotificationConfig(ctx context.Context, agentID int64, notifConfig *config.NotificationConfig) error {
 	configValue := map[string]interface{}{
-		"enabled":      notifConfig.Enabled,
-		"sound":        notifConfig.Sound,
-		"vibration":    notifConfig.Vibration,
-		"email_alerts": notifConfig.EmailAlerts,
-		"push_enabled": notifConfig.PushEnabled,
+		"enabled":       notifConfig.Enabled,
+		"sound":         notifConfig.Sound,
+		"vibration":     notifConfig.Vibration,
+		"email_alerts":  notifConfig.EmailAlerts,
+		"push_enabled":  notifConfig.PushEnabled,
+		"daily_summary": notifConfig.DailySummary,
+
+		"usage_alert_thresholds": notifConfig.AlertThresholds(),
 	}
 
 	return s.setOrUpdateConfig(ctx, agentID, config.ConfigKeyNotifications, configValue, "Notification preferences")
 }
 
+// ListDailySummaryAgents returns the agents whose notification config turns on the daily summary
+func (s *ConfigService) ListDailySummaryAgents(ctx context.Context) ([]int64, error) {
+	return s.configRepo.ListAgentsWithFlag(ctx, config.ConfigKeyNotifications, "daily_summary")
+}
+
+// ListAnalyticsDigestAgents returns the agents whose webhook config asks for an analytics digest at the given frequency
+func (s *ConfigService) ListAnalyticsDigestAgents(ctx context.Context, frequency string) ([]int64, error) {
+	return s.configRepo.ListAgentsWithValue(ctx, config.ConfigKeyWebhook, "analytics_digest", frequency)
+}
+
 // GetUSSDConfig retrieves USSD configuration
 func (s *ConfigService) GetUSSDConfig(ctx context.Context, agentID int64) (*config.USSDConfig, error) {
 	cfg, err := s.configRepo.FindByKey(ctx, agentID, config.ConfigKeyUSSDAutoRetry, nil)
@@ -317,6 +349,26 @@ func (s *ConfigService) GetAndroidDeviceConfig(ctx context.Context, agentID int6
 	return &deviceConfig, nil
 }
 
+// RecordDeviceHeartbeat marks an Android device online for two of its health check intervals
+func (s *ConfigService) RecordDeviceHeartbeat(ctx context.Context, agentID int64, deviceID string) error {
+	deviceConfig, err := s.GetAndroidDeviceConfig(ctx, agentID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	interval := deviceConfig.HealthCheckInterval
+	if interval <= 0 {
+		interval = s.getDefaultAndroidDeviceConfig().HealthCheckInterval
+	}
+
+	return s.presence.Heartbeat(ctx, agentID, deviceID, 2*time.Duration(interval)*time.Second)
+}
+
+// IsDeviceOnline reports whether an Android device has sent a recent heartbeat
+func (s *ConfigService) IsDeviceOnline(ctx context.Context, agentID int64, deviceID string) (bool, error) {
+	return s.presence.IsOnline(ctx, agentID, deviceID)
+}
+
 // SetAndroidDeviceConfig sets Android device configuration
 func (s *ConfigService) SetAndroidDeviceConfig(ctx context.Context, agentID int64, deviceID string, deviceConfig *config.AndroidDeviceConfig) error {
 	configValue := map[string]interface{}{
@@ -341,8 +393,7 @@ func (s *ConfigService) SetAndroidDeviceConfig(ctx context.Context, agentID int6
 	if exists {
 		// Update
 		cfg, _ := s.configRepo.FindByKey(ctx, agentID, config.ConfigKeyAndroidDeviceEnabled, &deviceID)
-		cfg.ConfigValue = configValue
-		return s.configRepo.Update(ctx, cfg.ID, cfg)
+		return s.updateConfigWithHistory(ctx, cfg, configValue)
 	}
 
 	// Create
@@ -372,22 +423,71 @@ func (s *ConfigService) GetBusinessConfig(ctx context.Context, agentID int64) (*
 	if err := s.mapConfigValue(cfg.ConfigValue, &businessConfig); err != nil {
 		return nil, err
 	}
+	if businessConfig.MaxFeaturedOffers == nil {
+		limit := config.DefaultMaxFeaturedOffers
+		businessConfig.MaxFeaturedOffers = &limit
+	}
+	if businessConfig.RetryCooldownSeconds <= 0 {
+		businessConfig.RetryCooldownSeconds = config.DefaultRetryCooldownSeconds
+	}
 
 	return &businessConfig, nil
 }
 
-// SetBusinessConfig sets business configuration
+// SetBusinessConfig replaces the business configuration as a whole, like the other Set*Config calls.
+// Fields left out take their defaults rather than the stored values: max_featured_offers becomes
+// DefaultMaxFeaturedOffers, quiet hours turn off and retry_cooldown_seconds becomes DefaultRetryCooldownSeconds.
 func (s *ConfigService) SetBusinessConfig(ctx context.Context, agentID int64, businessConfig *config.BusinessConfig) error {
+	if err := businessConfig.ValidateQuietHours(); err != nil {
+		return fmt.Errorf("%w: %v", xerrors.ErrInvalidInput, err)
+	}
+	if businessConfig.MaxFeaturedOffers != nil && *businessConfig.MaxFeaturedOffers < 0 {
+		return fmt.Errorf("%w: max_featured_offers must not be negative", xerrors.ErrInvalidInput)
+	}
+	if businessConfig.RetryCooldownSeconds < 0 {
+		return fmt.Errorf("%w: retry_cooldown_seconds must not be negative", xerrors.ErrInvalidInput)
+	}
+
+	if businessConfig.MaxFeaturedOffers == nil {
+		limit := config.DefaultMaxFeaturedOffers
+		businessConfig.MaxFeaturedOffers = &limit
+	}
+	if businessConfig.RetryCooldownSeconds == 0 {
+		businessConfig.RetryCooldownSeconds = config.DefaultRetryCooldownSeconds
+	}
+
 	configValue := map[string]interface{}{
 		"auto_renewal_enabled":           businessConfig.AutoRenewalEnabled,
 		"default_offer_validity_days":    businessConfig.DefaultOfferValidityDays,
 		"max_offers_per_customer":        businessConfig.MaxOffersPerCustomer,
 		"require_customer_verification":  businessConfig.RequireCustomerVerification,
+		"max_featured_offers":            *businessConfig.MaxFeaturedOffers,
+		"quiet_hours_start":              businessConfig.QuietHoursStart,
+		"quiet_hours_end":                businessConfig.QuietHoursEnd,
+		"retry_cooldown_seconds":         businessConfig.RetryCooldownSeconds,
 	}
 
 	return s.setOrUpdateConfig(ctx, agentID, config.ConfigKeyAutoRenewalEnabled, configValue, "Business settings")
 }
 
+// defaultAgentTimezone applies when the agent's display timezone is missing or unknown
+const defaultAgentTimezone = "Africa/Nairobi"
+
+// AgentLocation resolves the agent's display timezone, used for quiet hours, recurrences and day boundaries
+func (s *ConfigService) AgentLocation(ctx context.Context, agentID int64) *time.Location {
+	name := defaultAgentTimezone
+	if displayConfig, err := s.GetDisplayConfig(ctx, agentID); err == nil && displayConfig.Timezone != "" {
+		name = displayConfig.Timezone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// East Africa Time has no DST, so a fixed zone is a safe fallback when tzdata is missing
+		return time.FixedZone("EAT", 3*60*60)
+	}
+	return loc
+}
+
 // GetDisplayConfig retrieves display configuration
 func (s *ConfigService) GetDisplayConfig(ctx context.Context, agentID int64) (*config.DisplayConfig, error) {
 	cfg, err := s.configRepo.FindByKey(ctx, agentID, config.ConfigKeyTheme, nil)
@@ -406,6 +506,26 @@ func (s *ConfigService) GetDisplayConfig(ctx context.Context, agentID int64) (*c
 	return &displayConfig, nil
 }
 
+// DefaultCurrency returns the agent's display currency, used when a request omits one (KES if unset or unreadable)
+func (s *ConfigService) DefaultCurrency(ctx context.Context, agentID int64) string {
+	fallback := s.getDefaultDisplayConfig().Currency
+
+	displayConfig, err := s.GetDisplayConfig(ctx, agentID)
+	if err != nil {
+		s.logger.Warn("failed to get display config, using default currency",
+			zap.Int64("agent_id", agentID),
+			zap.Error(err),
+		)
+		return fallback
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(displayConfig.Currency))
+	if len(currency) != 3 {
+		return fallback
+	}
+	return currency
+}
+
 // SetDisplayConfig sets display configuration
 func (s *ConfigService) SetDisplayConfig(ctx context.Context, agentID int64, displayConfig *config.DisplayConfig) error {
 	configValue := map[string]interface{}{
@@ -444,11 +564,182 @@ func (s *ConfigService) SetSecurityConfig(ctx context.Context, agentID int64, se
 		"session_timeout_minutes": securityConfig.SessionTimeoutMinutes,
 		"ip_whitelist":           securityConfig.IPWhitelist,
 		"allowed_devices":        securityConfig.AllowedDevices,
+		"strict_device_binding":  securityConfig.StrictDeviceBinding,
 	}
 
 	return s.setOrUpdateConfig(ctx, agentID, config.ConfigKey2FAEnabled, configValue, "Security settings")
 }
 
+// GetWebhookConfig retrieves webhook delivery configuration
+func (s *ConfigService) GetWebhookConfig(ctx context.Context, agentID int64) (*config.WebhookConfig, error) {
+	cfg, err := s.configRepo.FindByKey(ctx, agentID, config.ConfigKeyWebhook, nil)
+	if err != nil {
+		if err == xerrors.ErrNotFound {
+			return s.getDefaultWebhookConfig(), nil
+		}
+		return nil, err
+	}
+
+	webhookConfig := s.getDefaultWebhookConfig()
+	if err := s.mapConfigValue(cfg.ConfigValue, webhookConfig); err != nil {
+		return nil, err
+	}
+
+	return webhookConfig, nil
+}
+
+// SetWebhookConfig sets webhook delivery configuration. The URL must resolve to a public address.
+// A blank secret, or the masked one GetWebhookConfig responses carry, keeps the stored secret.
+func (s *ConfigService) SetWebhookConfig(ctx context.Context, agentID int64, webhookConfig *config.WebhookConfig) error {
+	if webhookConfig.Enabled && webhookConfig.URL == "" {
+		return fmt.Errorf("webhook url is required when webhooks are enabled: %w", xerrors.ErrInvalidInput)
+	}
+	if webhookConfig.TimeoutSeconds < 0 || webhookConfig.TimeoutSeconds > config.MaxWebhookTimeoutSeconds {
+		return fmt.Errorf("webhook timeout must be between 1 and %d seconds: %w", config.MaxWebhookTimeoutSeconds, xerrors.ErrInvalidInput)
+	}
+	if webhookConfig.URL != "" {
+		if err := signing.ValidateURL(ctx, webhookConfig.URL); err != nil {
+			return fmt.Errorf("%v: %w", err, xerrors.ErrInvalidInput)
+		}
+	}
+
+	if webhookConfig.Secret == "" || strings.HasPrefix(webhookConfig.Secret, "****") {
+		current, err := s.GetWebhookConfig(ctx, agentID)
+		if err != nil {
+			return err
+		}
+		if webhookConfig.Secret == "" || webhookConfig.Secret == mask.Secret(current.Secret) {
+			webhookConfig.Secret = current.Secret
+		}
+	}
+	switch webhookConfig.AnalyticsDigest {
+	case "", config.AnalyticsDigestDaily, config.AnalyticsDigestWeekly:
+	default:
+		return fmt.Errorf("analytics digest must be daily or weekly: %w", xerrors.ErrInvalidInput)
+	}
+
+	configValue := map[string]interface{}{
+		"enabled":          webhookConfig.Enabled,
+		"url":              webhookConfig.URL,
+		"secret":           webhookConfig.Secret,
+		"max_attempts":     webhookConfig.MaxAttempts,
+		"timeout_seconds":  webhookConfig.TimeoutSeconds,
+		"analytics_digest": webhookConfig.AnalyticsDigest,
+	}
+
+	return s.setOrUpdateConfig(ctx, agentID, config.ConfigKeyWebhook, configValue, "Webhook delivery settings")
+}
+
+// GetMpesaConfig retrieves M-Pesa callback configuration
+func (s *ConfigService) GetMpesaConfig(ctx context.Context, agentID int64) (*config.MpesaConfig, error) {
+	cfg, err := s.configRepo.FindByKey(ctx, agentID, config.ConfigKeyMpesa, nil)
+	if err != nil {
+		if err == xerrors.ErrNotFound {
+			return &config.MpesaConfig{}, nil
+		}
+		return nil, err
+	}
+
+	var mpesaConfig config.MpesaConfig
+	if err := s.mapConfigValue(cfg.ConfigValue, &mpesaConfig); err != nil {
+		return nil, err
+	}
+
+	return &mpesaConfig, nil
+}
+
+// SetMpesaConfig sets M-Pesa callback configuration; a shortcode can belong to only one agent
+func (s *ConfigService) SetMpesaConfig(ctx context.Context, agentID int64, mpesaConfig *config.MpesaConfig) error {
+	if mpesaConfig.ShortCode != "" {
+		if mpesaConfig.CallbackSecret == "" {
+			return fmt.Errorf("callback secret is required with a shortcode: %w", xerrors.ErrInvalidInput)
+		}
+
+		ownerID, err := s.FindAgentByMpesaShortCode(ctx, mpesaConfig.ShortCode)
+		if err != nil && err != xerrors.ErrNotFound {
+			return err
+		}
+		if err == nil && ownerID != agentID {
+			return fmt.Errorf("shortcode is registered to another agent: %w", xerrors.ErrConflict)
+		}
+	}
+
+	configValue := map[string]interface{}{
+		"short_code":      mpesaConfig.ShortCode,
+		"callback_secret": mpesaConfig.CallbackSecret,
+	}
+
+	return s.setOrUpdateConfig(ctx, agentID, config.ConfigKeyMpesa, configValue, "M-Pesa callback settings")
+}
+
+// FindAgentByMpesaShortCode returns the agent whose M-Pesa config registers the shortcode
+func (s *ConfigService) FindAgentByMpesaShortCode(ctx context.Context, shortCode string) (int64, error) {
+	return s.configRepo.FindAgentByValue(ctx, config.ConfigKeyMpesa, "short_code", shortCode)
+}
+
+// ========== Presets ==========
+
+// ListPresets returns the built-in config presets
+func (s *ConfigService) ListPresets() []config.ConfigPreset {
+	names := make([]string, 0, len(config.Presets))
+	for name := range config.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	presets := make([]config.ConfigPreset, 0, len(names))
+	for _, name := range names {
+		presets = append(presets, config.Presets[name])
+	}
+	return presets
+}
+
+// ApplyPreset writes a preset's configs, skipping keys the agent already has unless overwrite is set
+func (s *ConfigService) ApplyPreset(ctx context.Context, agentID int64, presetName string, overwrite bool) (*config.ApplyPresetResult, error) {
+	preset, ok := config.Presets[presetName]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset '%s': %w", presetName, xerrors.ErrNotFound)
+	}
+
+	keys := make([]string, 0, len(preset.Configs))
+	for key := range preset.Configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := &config.ApplyPresetResult{
+		Preset:  preset.Name,
+		Applied: []string{},
+		Skipped: []string{},
+	}
+
+	for _, key := range keys {
+		exists, err := s.configRepo.ExistsByKey(ctx, agentID, key, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check config existence: %w", err)
+		}
+		if exists && !overwrite {
+			result.Skipped = append(result.Skipped, key)
+			continue
+		}
+
+		description := fmt.Sprintf("Applied from %s preset", preset.Name)
+		if err := s.setOrUpdateConfig(ctx, agentID, key, preset.Configs[key], description); err != nil {
+			return nil, fmt.Errorf("failed to apply config '%s': %w", key, err)
+		}
+		result.Applied = append(result.Applied, key)
+	}
+
+	s.logger.Info("config preset applied",
+		zap.Int64("agent_id", agentID),
+		zap.String("preset", preset.Name),
+		zap.Int("applied", len(result.Applied)),
+		zap.Int("skipped", len(result.Skipped)),
+	)
+
+	return result, nil
+}
+
 // ========== Helper Methods ==========
 
 // validateConfigKey validates config key format
@@ -494,8 +785,7 @@ func (s *ConfigService) setOrUpdateConfig(ctx context.Context, agentID int64, ke
 	if exists {
 		// Update
 		cfg, _ := s.configRepo.FindByKey(ctx, agentID, key, nil)
-		cfg.ConfigValue = value
-		return s.configRepo.Update(ctx, cfg.ID, cfg)
+		return s.updateConfigWithHistory(ctx, cfg, value)
 	}
 
 	// Create
@@ -510,10 +800,48 @@ func (s *ConfigService) setOrUpdateConfig(ctx context.Context, agentID int64, ke
 	return err
 }
 
-// mapConfigValue maps config value to struct
+// updateConfigWithHistory updates a config value and records the previous value in the same transaction
+func (s *ConfigService) updateConfigWithHistory(ctx context.Context, cfg *config.AgentConfig, value map[string]interface{}) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	history := &config.AgentConfigHistory{
+		AgentConfigID:   cfg.ID,
+		AgentIdentityID: cfg.AgentIdentityID,
+		ConfigKey:       cfg.ConfigKey,
+		PreviousValue:   cfg.ConfigValue,
+		NewValue:        value,
+	}
+
+	cfg.ConfigValue = value
+	if err := s.configRepo.UpdateWithTx(ctx, tx, cfg.ID, cfg); err != nil {
+		return err
+	}
+
+	if err := s.configRepo.CreateHistoryWithTx(ctx, tx, history); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// mapConfigValue decodes a stored config value onto target through its json tags.
+// Keys missing from value keep target's current fields, and unknown keys are ignored.
 func (s *ConfigService) mapConfigValue(value map[string]interface{}, target interface{}) error {
-	// Simple type assertion mapping
-	// In production, use a proper mapper like mapstructure
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config value: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to map config value: %w", err)
+	}
 	return nil
 }
 
@@ -546,11 +874,14 @@ func (s *ConfigService) getDefaultAndroidDeviceConfig() *config.AndroidDeviceCon
 }
 
 func (s *ConfigService) getDefaultBusinessConfig() *config.BusinessConfig {
+	maxFeatured := config.DefaultMaxFeaturedOffers
 	return &config.BusinessConfig{
 		AutoRenewalEnabled:           false,
 		DefaultOfferValidityDays:     30,
 		MaxOffersPerCustomer:         10,
 		RequireCustomerVerification:  false,
+		MaxFeaturedOffers:            &maxFeatured,
+		RetryCooldownSeconds:         config.DefaultRetryCooldownSeconds,
 	}
 }
 
@@ -564,11 +895,20 @@ func (s *ConfigService) getDefaultDisplayConfig() *config.DisplayConfig {
 	}
 }
 
+func (s *ConfigService) getDefaultWebhookConfig() *config.WebhookConfig {
+	return &config.WebhookConfig{
+		Enabled:        false,
+		MaxAttempts:    3,
+		TimeoutSeconds: 10,
+	}
+}
+
 func (s *ConfigService) getDefaultSecurityConfig() *config.SecurityConfig {
 	return &config.SecurityConfig{
 		TwoFactorEnabled:      false,
 		SessionTimeoutMinutes: 30,
 		IPWhitelist:           []string{},
 		AllowedDevices:        5,
+		StrictDeviceBinding:   false,
 	}
 }
\ No newline at end of file
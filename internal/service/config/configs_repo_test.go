@@ -0,0 +1,280 @@
+// internal/service/config/configs_repo_test.go
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"bingwa-service/internal/domain/config"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestMapConfigValue(t *testing.T) {
+	svc := NewConfigService(nil, nil, nil, zap.NewNop())
+	featured := 5
+
+	tests := []struct {
+		name   string
+		stored string // config_value as stored, decoded the way the repository decodes it
+		target interface{}
+		want   interface{}
+	}{
+		{
+			"display",
+			`{"theme": "dark", "language": "sw", "timezone": "Africa/Nairobi", "currency": "UGX"}`,
+			&config.DisplayConfig{},
+			&config.DisplayConfig{Theme: "dark", Language: "sw", Timezone: "Africa/Nairobi", Currency: "UGX"},
+		},
+		{
+			"notification",
+			`{"enabled": true, "email_alerts": true, "daily_summary": true}`,
+			&config.NotificationConfig{},
+			&config.NotificationConfig{Enabled: true, EmailAlerts: true, DailySummary: true},
+		},
+		{
+			"security tags and lists",
+			`{"2fa_enabled": true, "ip_whitelist": ["10.0.0.1"], "allowed_devices": 2}`,
+			&config.SecurityConfig{},
+			&config.SecurityConfig{TwoFactorEnabled: true, IPWhitelist: []string{"10.0.0.1"}, AllowedDevices: 2},
+		},
+		{
+			"business numbers into ints and pointers",
+			`{"max_featured_offers": 5, "retry_cooldown_seconds": 120, "quiet_hours_start": "22:00"}`,
+			&config.BusinessConfig{},
+			&config.BusinessConfig{MaxFeaturedOffers: &featured, RetryCooldownSeconds: 120, QuietHoursStart: "22:00"},
+		},
+		{
+			"missing keys keep the target's defaults",
+			`{"enabled": true, "url": "https://example.com/hook"}`,
+			&config.WebhookConfig{MaxAttempts: 3, TimeoutSeconds: 10},
+			&config.WebhookConfig{Enabled: true, URL: "https://example.com/hook", MaxAttempts: 3, TimeoutSeconds: 10},
+		},
+		{
+			"unknown keys are ignored",
+			`{"auto_retry": true, "legacy_flag": "x"}`,
+			&config.USSDConfig{},
+			&config.USSDConfig{AutoRetry: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.stored), &stored); err != nil {
+				t.Fatalf("bad fixture: %v", err)
+			}
+			if err := svc.mapConfigValue(stored, tt.target); err != nil {
+				t.Fatalf("mapConfigValue: %v", err)
+			}
+			if !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("mapped %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+
+	// A stored value of the wrong type is reported rather than silently dropped
+	err := svc.mapConfigValue(map[string]interface{}{"timeout_seconds": "soon"}, &config.USSDConfig{})
+	if err == nil {
+		t.Error("mapConfigValue accepted a string for an int field")
+	}
+}
+
+func TestConfigReadersReturnStoredValues(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewConfigService(postgres.NewAgentConfigRepository(pool), nil, postgres.NewDB(pool), zap.NewNop())
+	agentID := testutil.Identity(t, pool, "configs@example.com")
+
+	// Nothing stored yet: readers fall back to defaults
+	display, err := svc.GetDisplayConfig(ctx, agentID)
+	if err != nil {
+		t.Fatalf("GetDisplayConfig before set: %v", err)
+	}
+	if !reflect.DeepEqual(display, svc.getDefaultDisplayConfig()) {
+		t.Errorf("display before set = %+v, want defaults", display)
+	}
+	if currency := svc.DefaultCurrency(ctx, agentID); currency != "KES" {
+		t.Errorf("DefaultCurrency before set = %s, want KES", currency)
+	}
+
+	wantDisplay := &config.DisplayConfig{Theme: "dark", Language: "sw", Timezone: "Africa/Kampala", DateFormat: "YYYY-MM-DD", Currency: "UGX"}
+	if err := svc.SetDisplayConfig(ctx, agentID, wantDisplay); err != nil {
+		t.Fatalf("SetDisplayConfig: %v", err)
+	}
+	if display, err = svc.GetDisplayConfig(ctx, agentID); err != nil || !reflect.DeepEqual(display, wantDisplay) {
+		t.Errorf("GetDisplayConfig = %+v, %v; want %+v", display, err, wantDisplay)
+	}
+	if currency := svc.DefaultCurrency(ctx, agentID); currency != "UGX" {
+		t.Errorf("DefaultCurrency = %s, want UGX", currency)
+	}
+
+	wantSecurity := &config.SecurityConfig{TwoFactorEnabled: true, SessionTimeoutMinutes: 15, IPWhitelist: []string{"10.0.0.1"}, AllowedDevices: 2, StrictDeviceBinding: true}
+	if err := svc.SetSecurityConfig(ctx, agentID, wantSecurity); err != nil {
+		t.Fatalf("SetSecurityConfig: %v", err)
+	}
+	if security, err := svc.GetSecurityConfig(ctx, agentID); err != nil || !reflect.DeepEqual(security, wantSecurity) {
+		t.Errorf("GetSecurityConfig = %+v, %v; want %+v", security, err, wantSecurity)
+	}
+
+	wantUSSD := &config.USSDConfig{AutoRetry: true, RetryAttempts: 4, TimeoutSeconds: 45, AutoDismissDialog: true}
+	if err := svc.SetUSSDConfig(ctx, agentID, wantUSSD); err != nil {
+		t.Fatalf("SetUSSDConfig: %v", err)
+	}
+	if ussd, err := svc.GetUSSDConfig(ctx, agentID); err != nil || !reflect.DeepEqual(ussd, wantUSSD) {
+		t.Errorf("GetUSSDConfig = %+v, %v; want %+v", ussd, err, wantUSSD)
+	}
+}
+
+func TestSetBusinessConfigReplacesWholeConfig(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewConfigService(postgres.NewAgentConfigRepository(pool), nil, postgres.NewDB(pool), zap.NewNop())
+	agentID := testutil.Identity(t, pool, "business@example.com")
+
+	featured := 2
+	if err := svc.SetBusinessConfig(ctx, agentID, &config.BusinessConfig{
+		MaxFeaturedOffers:    &featured,
+		QuietHoursStart:      "22:00",
+		QuietHoursEnd:        "06:00",
+		RetryCooldownSeconds: 300,
+	}); err != nil {
+		t.Fatalf("SetBusinessConfig: %v", err)
+	}
+
+	// A later update that leaves fields out resets them to their defaults
+	if err := svc.SetBusinessConfig(ctx, agentID, &config.BusinessConfig{AutoRenewalEnabled: true}); err != nil {
+		t.Fatalf("SetBusinessConfig update: %v", err)
+	}
+	got, err := svc.GetBusinessConfig(ctx, agentID)
+	if err != nil {
+		t.Fatalf("GetBusinessConfig: %v", err)
+	}
+	if !got.AutoRenewalEnabled {
+		t.Error("auto_renewal_enabled was not stored")
+	}
+	if got.FeaturedLimit() != config.DefaultMaxFeaturedOffers {
+		t.Errorf("featured limit = %d, want default %d", got.FeaturedLimit(), config.DefaultMaxFeaturedOffers)
+	}
+	if got.QuietHoursEnabled() {
+		t.Errorf("quiet hours %s-%s still on, want off", got.QuietHoursStart, got.QuietHoursEnd)
+	}
+	if got.RetryCooldownSeconds != config.DefaultRetryCooldownSeconds {
+		t.Errorf("retry cooldown = %d, want default %d", got.RetryCooldownSeconds, config.DefaultRetryCooldownSeconds)
+	}
+
+	if err := svc.SetBusinessConfig(ctx, agentID, &config.BusinessConfig{RetryCooldownSeconds: -1}); !errors.Is(err, xerrors.ErrInvalidInput) {
+		t.Errorf("negative retry cooldown error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSetWebhookConfigRejectsOutOfRangeTimeout(t *testing.T) {
+	// Validation fails before anything is stored, so no repository is needed
+	svc := NewConfigService(nil, nil, nil, zap.NewNop())
+
+	for _, timeout := range []int{-1, config.MaxWebhookTimeoutSeconds + 1, 300} {
+		err := svc.SetWebhookConfig(context.Background(), 1, &config.WebhookConfig{TimeoutSeconds: timeout})
+		if !errors.Is(err, xerrors.ErrInvalidInput) {
+			t.Errorf("SetWebhookConfig(timeout %d) error = %v, want ErrInvalidInput", timeout, err)
+		}
+	}
+}
+
+func TestUpdateConfigRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewConfigService(postgres.NewAgentConfigRepository(pool), nil, postgres.NewDB(pool), zap.NewNop())
+	agentID := testutil.Identity(t, pool, "history@example.com")
+
+	cfg, err := svc.CreateConfig(ctx, agentID, &config.CreateConfigRequest{
+		ConfigKey:   config.ConfigKeyTheme,
+		ConfigValue: map[string]interface{}{"theme": "light"},
+		IsGlobal:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+
+	for _, theme := range []string{"dark", "auto"} {
+		if _, err := svc.UpdateConfig(ctx, agentID, cfg.ID, &config.UpdateConfigRequest{
+			ConfigValue: map[string]interface{}{"theme": theme},
+		}); err != nil {
+			t.Fatalf("UpdateConfig to %s: %v", theme, err)
+		}
+	}
+
+	history, err := svc.GetConfigHistory(ctx, agentID, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetConfigHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d history rows, want 2", len(history))
+	}
+
+	// Newest change first, each recording the value it replaced
+	for i, want := range [][2]string{{"dark", "auto"}, {"light", "dark"}} {
+		h := history[i]
+		if h.PreviousValue["theme"] != want[0] || h.NewValue["theme"] != want[1] {
+			t.Errorf("history[%d] = %v -> %v, want theme %s -> %s", i, h.PreviousValue, h.NewValue, want[0], want[1])
+		}
+		if h.ConfigKey != config.ConfigKeyTheme || h.AgentIdentityID != agentID {
+			t.Errorf("history[%d] = %s for agent %d, want %s for agent %d", i, h.ConfigKey, h.AgentIdentityID, config.ConfigKeyTheme, agentID)
+		}
+	}
+
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	if _, err := svc.GetConfigHistory(ctx, otherID, cfg.ID); !errors.Is(err, xerrors.ErrUnauthorized) {
+		t.Errorf("another agent's history error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestApplyPresetCreatesItsKeys(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewConfigService(postgres.NewAgentConfigRepository(pool), nil, postgres.NewDB(pool), zap.NewNop())
+	agentID := testutil.Identity(t, pool, "presets@example.com")
+
+	result, err := svc.ApplyPreset(ctx, agentID, config.PresetConservative, false)
+	if err != nil {
+		t.Fatalf("ApplyPreset: %v", err)
+	}
+	wantKeys := []string{
+		config.ConfigKey2FAEnabled,
+		config.ConfigKeyAutoRenewalEnabled,
+		config.ConfigKeyNotifications,
+		config.ConfigKeyUSSDAutoRetry,
+	}
+	if !reflect.DeepEqual(result.Applied, wantKeys) || len(result.Skipped) != 0 {
+		t.Errorf("applied %v, skipped %v; want %v applied", result.Applied, result.Skipped, wantKeys)
+	}
+
+	// The stored values read back through the typed readers
+	if ussd, err := svc.GetUSSDConfig(ctx, agentID); err != nil || ussd.RetryAttempts != 2 || ussd.TimeoutSeconds != 45 {
+		t.Errorf("GetUSSDConfig = %+v, %v; want 2 retries with a 45s timeout", ussd, err)
+	}
+	if security, err := svc.GetSecurityConfig(ctx, agentID); err != nil || !security.TwoFactorEnabled {
+		t.Errorf("GetSecurityConfig = %+v, %v; want 2FA enabled", security, err)
+	}
+
+	// Keys the agent already has are left alone unless overwriting
+	result, err = svc.ApplyPreset(ctx, agentID, config.PresetHighVolume, false)
+	if err != nil {
+		t.Fatalf("ApplyPreset over existing keys: %v", err)
+	}
+	if len(result.Applied) != 0 || len(result.Skipped) != 3 {
+		t.Errorf("applied %v, skipped %v; want all three keys skipped", result.Applied, result.Skipped)
+	}
+	if ussd, err := svc.GetUSSDConfig(ctx, agentID); err != nil || ussd.RetryAttempts != 2 {
+		t.Errorf("GetUSSDConfig after skipped preset = %+v, %v; want 2 retries kept", ussd, err)
+	}
+
+	if _, err := svc.ApplyPreset(ctx, agentID, "reckless", false); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("unknown preset error = %v, want ErrNotFound", err)
+	}
+}
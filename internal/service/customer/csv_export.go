@@ -0,0 +1,76 @@
+// internal/service/customer/csv_export.go
+package customer
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"bingwa-service/internal/domain/customer"
+
+	"go.uber.org/zap"
+)
+
+// csvExportHeader lists the exported columns; names match the import aliases so an export can be re-imported
+var csvExportHeader = []string{"phone_number", "full_name", "tags", "verification_status", "last_activity_at"}
+
+// ExportCustomers streams the agent's customers as CSV. Rows are written as they are read from the
+// database; read errors surface from the returned reader. Callers should close it if they stop early.
+func (s *CustomerService) ExportCustomers(ctx context.Context, agentID int64) (io.Reader, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		w := csv.NewWriter(pw)
+		err := w.Write(csvExportHeader)
+		if err == nil {
+			err = s.customerRepo.ForEachByAgent(ctx, agentID, func(c *customer.AgentCustomer) error {
+				return w.Write(customerCSVRecord(c))
+			})
+		}
+		if err == nil {
+			w.Flush()
+			err = w.Error()
+		}
+		if err != nil {
+			s.logger.Warn("customer export aborted", zap.Int64("agent_id", agentID), zap.Error(err))
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// csvFormulaPrefixes start cells that spreadsheets evaluate as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvSafeCell defuses a cell that a spreadsheet would run as a formula by prefixing it with '.
+// Import strips the prefix again, so exported files still round-trip.
+func csvSafeCell(v string) string {
+	if v != "" && strings.ContainsRune(csvFormulaPrefixes, rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// customerCSVRecord renders one customer in csvExportHeader order
+func customerCSVRecord(c *customer.AgentCustomer) []string {
+	status := "unverified"
+	if c.IsVerified {
+		status = "verified"
+	}
+
+	lastActivity := ""
+	if c.LastActivityAt.Valid {
+		lastActivity = c.LastActivityAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		csvSafeCell(c.PhoneNumber),
+		csvSafeCell(c.FullName.String),
+		csvSafeCell(strings.Join(c.Tags, ";")),
+		status,
+		lastActivity,
+	}
+}
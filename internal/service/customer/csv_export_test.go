@@ -0,0 +1,139 @@
+// internal/service/customer/csv_export_test.go
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/customer"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestCustomerCSVRecord(t *testing.T) {
+	activity := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("EAT", 3*60*60))
+
+	tests := []struct {
+		name     string
+		customer customer.AgentCustomer
+		want     []string
+	}{
+		{
+			"verified customer",
+			customer.AgentCustomer{
+				PhoneNumber:    "254712345678",
+				FullName:       sql.NullString{String: "Jane Wanjiku", Valid: true},
+				Tags:           []string{"vip", "nairobi"},
+				IsVerified:     true,
+				LastActivityAt: sql.NullTime{Time: activity, Valid: true},
+			},
+			[]string{"254712345678", "Jane Wanjiku", "vip;nairobi", "verified", "2026-03-01T06:30:00Z"},
+		},
+		{
+			"unverified customer without optional fields",
+			customer.AgentCustomer{PhoneNumber: "254700000001"},
+			[]string{"254700000001", "", "", "unverified", ""},
+		},
+		{
+			"formula-like cells are prefixed",
+			customer.AgentCustomer{
+				PhoneNumber: "+254712345678",
+				FullName:    sql.NullString{String: `=HYPERLINK("http://evil.example","x")`, Valid: true},
+				Tags:        []string{"@import", "ok"},
+			},
+			[]string{"'+254712345678", `'=HYPERLINK("http://evil.example","x")`, "'@import;ok", "unverified", ""},
+		},
+		{
+			"minus and tab prefixes",
+			customer.AgentCustomer{
+				PhoneNumber: "254712345678",
+				FullName:    sql.NullString{String: "-2+3", Valid: true},
+				Tags:        []string{"\tcmd"},
+			},
+			[]string{"254712345678", "'-2+3", "'\tcmd", "unverified", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := customerCSVRecord(&tt.customer)
+			if len(got) != len(csvExportHeader) {
+				t.Fatalf("record has %d columns, header has %d", len(got), len(csvExportHeader))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("customerCSVRecord = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportedCSVReimports(t *testing.T) {
+	svc := &CustomerService{}
+	columns, err := mapCSVHeader(csvExportHeader)
+	if err != nil {
+		t.Fatalf("mapCSVHeader: %v", err)
+	}
+
+	c := &customer.AgentCustomer{
+		PhoneNumber: "+254712345678",
+		FullName:    sql.NullString{String: "=Jane", Valid: true},
+		Tags:        []string{"-vip", "nairobi"},
+	}
+	req, err := svc.parseCSVRecord(columns, customerCSVRecord(c))
+	if err != nil {
+		t.Fatalf("parseCSVRecord: %v", err)
+	}
+	if req.PhoneNumber != "254712345678" || req.FullName != "=Jane" || !reflect.DeepEqual(req.Tags, []string{"-vip", "nairobi"}) {
+		t.Errorf("re-imported %+v, want the original phone, name and tags", req)
+	}
+}
+
+func TestExportCustomersWritesHeaderAndOneRowPerCustomer(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewCustomerService(postgres.NewAgentCustomerRepository(pool), nil, nil, nil, zap.NewNop())
+
+	agentID := testutil.Identity(t, pool, "export@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	for _, c := range []struct {
+		agentID int64
+		phone   string
+		name    string
+	}{
+		{agentID, "254700000001", "First"},
+		{agentID, "254700000002", "=SUM(A1:A2)"},
+		{otherID, "254700000003", "Not exported"},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO agent_customers (agent_identity_id, customer_reference, phone_number, full_name)
+			VALUES ($1, $2, $3, $4)
+		`, c.agentID, "CUST-"+c.phone, c.phone, c.name); err != nil {
+			t.Fatalf("failed to seed customer: %v", err)
+		}
+	}
+
+	r, err := svc.ExportCustomers(ctx, agentID)
+	if err != nil {
+		t.Fatalf("ExportCustomers: %v", err)
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil && err != io.EOF {
+		t.Fatalf("failed to read export: %v", err)
+	}
+
+	want := [][]string{
+		csvExportHeader,
+		{"254700000001", "First", "", "unverified", ""},
+		{"254700000002", "'=SUM(A1:A2)", "", "unverified", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("export = %q, want %q", records, want)
+	}
+}
@@ -0,0 +1,222 @@
+// internal/service/customer/csv_import.go
+package customer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+
+	"bingwa-service/internal/domain/customer"
+	xerrors "bingwa-service/internal/pkg/errors"
+)
+
+// maxCSVImportRows caps how many data rows one CSV upload may contain
+const maxCSVImportRows = 1000
+
+// csvHeaderAliases maps accepted CSV header names to customer fields
+var csvHeaderAliases = map[string]string{
+	"full_name":        "full_name",
+	"name":             "full_name",
+	"phone_number":     "phone_number",
+	"phone":            "phone_number",
+	"msisdn":           "phone_number",
+	"alt_phone_number": "alt_phone_number",
+	"alt_phone":        "alt_phone_number",
+	"email":            "email",
+	"notes":            "notes",
+	"tags":             "tags",
+}
+
+// ImportCustomersCSV parses a CSV of customers, validates each row and imports the valid ones.
+// The header row is required and must include a phone column; tags are separated by ';'.
+func (s *CustomerService) ImportCustomersCSV(ctx context.Context, agentID int64, r io.Reader) (*customer.CSVImportResponse, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty: %w", xerrors.ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", xerrors.ErrInvalidInput)
+	}
+
+	columns, err := mapCSVHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &customer.CSVImportResponse{Results: []customer.CSVImportRowResult{}}
+	requests := []customer.CreateCustomerRequest{}
+	requestRows := []int{} // index into resp.Results for each request
+	seenPhones := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		resp.Total++
+		if resp.Total > maxCSVImportRows {
+			return nil, fmt.Errorf("CSV has more than %d rows: %w", maxCSVImportRows, xerrors.ErrInvalidInput)
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			resp.Results = append(resp.Results, customer.CSVImportRowResult{
+				Row:    parseErr.StartLine,
+				Status: customer.CSVImportStatusInvalid,
+				Error:  "malformed CSV row",
+			})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		req, err := s.parseCSVRecord(columns, record)
+		if err != nil {
+			resp.Results = append(resp.Results, customer.CSVImportRowResult{
+				Row:         line,
+				PhoneNumber: req.PhoneNumber,
+				Status:      customer.CSVImportStatusInvalid,
+				Error:       err.Error(),
+			})
+			continue
+		}
+
+		if firstRow, ok := seenPhones[req.PhoneNumber]; ok {
+			resp.Results = append(resp.Results, customer.CSVImportRowResult{
+				Row:         line,
+				PhoneNumber: req.PhoneNumber,
+				Status:      customer.CSVImportStatusDuplicate,
+				Error:       fmt.Sprintf("phone number already appears on row %d", firstRow),
+			})
+			continue
+		}
+		seenPhones[req.PhoneNumber] = line
+
+		resp.Results = append(resp.Results, customer.CSVImportRowResult{Row: line, PhoneNumber: req.PhoneNumber})
+		requests = append(requests, req)
+		requestRows = append(requestRows, len(resp.Results)-1)
+	}
+
+	createdIDs, importErrs := s.BulkImportCustomers(ctx, agentID, requests)
+	for i, idx := range requestRows {
+		if importErrs[i] != nil {
+			resp.Results[idx].Status = customer.CSVImportStatusFailed
+			resp.Results[idx].Error = importErrs[i].Error()
+			continue
+		}
+		resp.Results[idx].Status = customer.CSVImportStatusCreated
+		resp.Results[idx].CustomerID = createdIDs[i]
+	}
+
+	for _, result := range resp.Results {
+		if result.Status == customer.CSVImportStatusCreated {
+			resp.SuccessCount++
+		} else {
+			resp.FailureCount++
+		}
+	}
+
+	return resp, nil
+}
+
+// mapCSVHeader returns the column index of each recognised customer field
+func mapCSVHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		field, ok := csvHeaderAliases[key]
+		if !ok {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("CSV header maps %s more than once: %w", field, xerrors.ErrInvalidInput)
+		}
+		columns[field] = i
+	}
+
+	if _, ok := columns["phone_number"]; !ok {
+		return nil, fmt.Errorf("CSV header must include a phone_number column: %w", xerrors.ErrInvalidInput)
+	}
+
+	return columns, nil
+}
+
+// parseCSVRecord builds a create request from a CSV record, normalizing and validating phone numbers
+func (s *CustomerService) parseCSVRecord(columns map[string]int, record []string) (customer.CreateCustomerRequest, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(csvUnescapeCell(record[i]))
+	}
+
+	req := customer.CreateCustomerRequest{
+		FullName:       field("full_name"),
+		PhoneNumber:    normalizePhoneNumber(field("phone_number")),
+		AltPhoneNumber: normalizePhoneNumber(field("alt_phone_number")),
+		Email:          field("email"),
+		Notes:          field("notes"),
+	}
+
+	if req.PhoneNumber == "" {
+		return req, fmt.Errorf("phone number is required")
+	}
+	if err := s.validatePhoneNumber(req.PhoneNumber); err != nil {
+		return req, err
+	}
+	if req.AltPhoneNumber != "" {
+		if err := s.validatePhoneNumber(req.AltPhoneNumber); err != nil {
+			return req, fmt.Errorf("alt phone: %w", err)
+		}
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return req, fmt.Errorf("invalid email address")
+		}
+	}
+	if len(req.FullName) > 255 {
+		return req, fmt.Errorf("full name is too long")
+	}
+
+	for _, tag := range strings.Split(field("tags"), ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			req.Tags = append(req.Tags, tag)
+		}
+	}
+
+	return req, nil
+}
+
+// csvUnescapeCell undoes csvSafeCell, dropping the ' that export puts before formula-like cells
+func csvUnescapeCell(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+// normalizePhoneNumber strips formatting and converts Kenyan numbers to the 254XXXXXXXXX form
+func normalizePhoneNumber(phone string) string {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(phone)
+	phone = strings.TrimPrefix(phone, "+")
+
+	switch {
+	case len(phone) == 10 && strings.HasPrefix(phone, "0"):
+		return "254" + phone[1:]
+	case len(phone) == 9 && (strings.HasPrefix(phone, "7") || strings.HasPrefix(phone, "1")):
+		return "254" + phone
+	}
+	return phone
+}
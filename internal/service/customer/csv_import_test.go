@@ -0,0 +1,53 @@
+// internal/service/customer/csv_import_test.go
+package customer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"bingwa-service/internal/domain/customer"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestImportCustomersCSVReportsMalformedRow(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewCustomerService(postgres.NewAgentCustomerRepository(pool), nil, nil, nil, zap.NewNop())
+	agentID := testutil.Identity(t, pool, "import@example.com")
+
+	file := strings.Join([]string{
+		"phone,name",
+		"0712345678,Jane",
+		`0712345679,Ja"ne`, // Bare quote inside an unquoted field
+		"0712345680,John",
+	}, "\n")
+
+	resp, err := svc.ImportCustomersCSV(ctx, agentID, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ImportCustomersCSV: %v", err)
+	}
+	if resp.Total != 3 || resp.SuccessCount != 2 || resp.FailureCount != 1 {
+		t.Fatalf("import = %d created, %d failed of %d; want 2, 1 of 3", resp.SuccessCount, resp.FailureCount, resp.Total)
+	}
+
+	want := []struct {
+		row    int
+		status customer.CSVImportStatus
+	}{
+		{2, customer.CSVImportStatusCreated},
+		{3, customer.CSVImportStatusInvalid},
+		{4, customer.CSVImportStatusCreated},
+	}
+	for i, w := range want {
+		if got := resp.Results[i]; got.Row != w.row || got.Status != w.status {
+			t.Errorf("result %d = row %d, %s; want row %d, %s", i, got.Row, got.Status, w.row, w.status)
+		}
+	}
+	if got := resp.Results[1].Error; got != "malformed CSV row" {
+		t.Errorf("malformed row error = %q, want %q", got, "malformed CSV row")
+	}
+}
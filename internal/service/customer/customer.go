@@ -11,6 +11,7 @@ import (
 	"bingwa-service/internal/domain/customer"
 	xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/repository/postgres"
+	notificationsvc "bingwa-service/internal/service/notification"
 
 	"github.com/lib/pq"
 	"go.uber.org/zap"
@@ -18,12 +19,18 @@ import (
 
 type CustomerService struct {
 	customerRepo *postgres.AgentCustomerRepository
+	noteRepo     *postgres.CustomerNoteRepository
+	smsRepo      *postgres.SMSOutboxRepository
+	notifService *notificationsvc.NotificationService
 	logger       *zap.Logger
 }
 
-func NewCustomerService(customerRepo *postgres.AgentCustomerRepository, logger *zap.Logger) *CustomerService {
+func NewCustomerService(customerRepo *postgres.AgentCustomerRepository, noteRepo *postgres.CustomerNoteRepository, smsRepo *postgres.SMSOutboxRepository, notifService *notificationsvc.NotificationService, logger *zap.Logger) *CustomerService {
 	return &CustomerService{
 		customerRepo: customerRepo,
+		noteRepo:     noteRepo,
+		smsRepo:      smsRepo,
+		notifService: notifService,
 		logger:       logger,
 	}
 }
@@ -59,6 +66,21 @@ func (s *CustomerService) GetOrCreateCustomer(ctx context.Context, agentID int64
 }
 
 
+// GetInactiveCustomers lists customers with no activity in the last sinceDays days
+func (s *CustomerService) GetInactiveCustomers(ctx context.Context, agentID int64, sinceDays int) ([]customer.AgentCustomer, error) {
+	if sinceDays <= 0 {
+		return nil, fmt.Errorf("days must be greater than zero")
+	}
+
+	since := time.Now().AddDate(0, 0, -sinceDays)
+	customers, err := s.customerRepo.FindInactive(ctx, agentID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inactive customers: %w", err)
+	}
+
+	return customers, nil
+}
+
 // CreateCustomer creates a new customer for an agent
 func (s *CustomerService) CreateCustomer(ctx context.Context, agentID int64, req *customer.CreateCustomerRequest) (*customer.AgentCustomer, error) {
 	// Validate phone number format
@@ -296,33 +318,6 @@ func (s *CustomerService) DeactivateCustomer(ctx context.Context, agentID, custo
 	return nil
 }
 
-// VerifyCustomer marks a customer as verified
-func (s *CustomerService) VerifyCustomer(ctx context.Context, agentID, customerID int64) error {
-	// Verify ownership
-	c, err := s.customerRepo.FindByID(ctx, customerID)
-	if err != nil {
-		return err
-	}
-	if c.AgentIdentityID != agentID {
-		return xerrors.ErrUnauthorized
-	}
-
-	if c.IsVerified {
-		return fmt.Errorf("customer already verified")
-	}
-
-	if err := s.customerRepo.MarkAsVerified(ctx, customerID); err != nil {
-		return fmt.Errorf("failed to verify customer: %w", err)
-	}
-
-	s.logger.Info("customer verified",
-		zap.Int64("customer_id", customerID),
-		zap.Int64("agent_id", agentID),
-	)
-
-	return nil
-}
-
 // DeleteCustomer soft deletes a customer
 func (s *CustomerService) DeleteCustomer(ctx context.Context, agentID, customerID int64) error {
 	// Verify ownership
@@ -475,6 +470,48 @@ func (s *CustomerService) RemoveTag(ctx context.Context, agentID, customerID int
 	return s.customerRepo.Update(ctx, customerID, c)
 }
 
+// AddNote attaches a note to one of the agent's customers; authorID is the identity writing it
+func (s *CustomerService) AddNote(ctx context.Context, agentID, authorID, customerID int64, body string) (*customer.CustomerNote, error) {
+	if _, err := s.GetCustomer(ctx, agentID, customerID); err != nil {
+		return nil, err
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("note cannot be empty")
+	}
+
+	note := &customer.CustomerNote{
+		CustomerID:       customerID,
+		AgentIdentityID:  agentID,
+		AuthorIdentityID: authorID,
+		Body:             body,
+	}
+	if err := s.noteRepo.Create(ctx, note); err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// ListNotes retrieves the notes on one of the agent's customers, newest first
+func (s *CustomerService) ListNotes(ctx context.Context, agentID, customerID int64) ([]customer.CustomerNote, error) {
+	if _, err := s.GetCustomer(ctx, agentID, customerID); err != nil {
+		return nil, err
+	}
+
+	return s.noteRepo.ListByCustomer(ctx, customerID)
+}
+
+// DeleteNote removes a note from one of the agent's customers
+func (s *CustomerService) DeleteNote(ctx context.Context, agentID, customerID, noteID int64) error {
+	if _, err := s.GetCustomer(ctx, agentID, customerID); err != nil {
+		return err
+	}
+
+	return s.noteRepo.Delete(ctx, customerID, noteID)
+}
+
 // BulkImportCustomers imports multiple customers at once
 func (s *CustomerService) BulkImportCustomers(ctx context.Context, agentID int64, customers []customer.CreateCustomerRequest) ([]int64, []error) {
 	createdIDs := []int64{}
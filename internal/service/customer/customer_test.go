@@ -0,0 +1,114 @@
+// internal/service/customer/customer_test.go
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestGetInactiveCustomersListsThoseBeyondThreshold(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewCustomerService(postgres.NewAgentCustomerRepository(pool), nil, nil, nil, zap.NewNop())
+
+	agentID := testutil.Identity(t, pool, "inactive@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	now := time.Now()
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	activeAt := func(days int) sql.NullTime { return sql.NullTime{Time: daysAgo(days), Valid: true} }
+
+	for _, c := range []struct {
+		agentID      int64
+		phone        string
+		createdAt    time.Time
+		lastActivity sql.NullTime
+		active       bool
+	}{
+		{agentID, "254700000001", daysAgo(120), activeAt(60), true},  // dormant
+		{agentID, "254700000002", daysAgo(45), sql.NullTime{}, true}, // never bought
+		{agentID, "254700000003", daysAgo(120), activeAt(2), true},   // recent
+		{agentID, "254700000004", now, sql.NullTime{}, true},         // just added
+		{agentID, "254700000005", daysAgo(120), activeAt(90), false},
+		{otherID, "254700000006", daysAgo(120), activeAt(90), true},
+	} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO agent_customers (agent_identity_id, customer_reference, phone_number, created_at, last_activity_at, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, c.agentID, "CUST-"+c.phone, c.phone, c.createdAt, c.lastActivity, c.active); err != nil {
+			t.Fatalf("failed to seed customer: %v", err)
+		}
+	}
+
+	inactive, err := svc.GetInactiveCustomers(ctx, agentID, 30)
+	if err != nil {
+		t.Fatalf("GetInactiveCustomers: %v", err)
+	}
+	phones := make([]string, 0, len(inactive))
+	for _, c := range inactive {
+		phones = append(phones, c.PhoneNumber)
+	}
+	// Longest inactive first
+	if len(phones) != 2 || phones[0] != "254700000001" || phones[1] != "254700000002" {
+		t.Errorf("inactive customers = %v, want the dormant then the never-active customer", phones)
+	}
+
+	if _, err := svc.GetInactiveCustomers(ctx, agentID, 0); err == nil {
+		t.Error("GetInactiveCustomers accepted a zero-day threshold")
+	}
+}
+
+func TestAddAndListCustomerNotes(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewCustomerService(postgres.NewAgentCustomerRepository(pool), postgres.NewCustomerNoteRepository(pool), nil, nil, zap.NewNop())
+
+	agentID := testutil.Identity(t, pool, "notes@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	var customerID int64
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO agent_customers (agent_identity_id, customer_reference, phone_number)
+		VALUES ($1, 'CUST-NOTES', '254712345678')
+		RETURNING id
+	`, agentID).Scan(&customerID); err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+
+	for _, body := range []string{"Prefers weekly bundles", "  Asked about Okoa Jahazi  "} {
+		if _, err := svc.AddNote(ctx, agentID, agentID, customerID, body); err != nil {
+			t.Fatalf("AddNote(%q): %v", body, err)
+		}
+	}
+
+	notes, err := svc.ListNotes(ctx, agentID, customerID)
+	if err != nil {
+		t.Fatalf("ListNotes: %v", err)
+	}
+	// Newest first, trimmed
+	if len(notes) != 2 || notes[0].Body != "Asked about Okoa Jahazi" || notes[1].Body != "Prefers weekly bundles" {
+		t.Fatalf("notes = %+v, want both notes newest first", notes)
+	}
+	if notes[0].AuthorIdentityID != agentID || notes[0].CustomerID != customerID {
+		t.Errorf("note = %+v, want it written by the agent on their customer", notes[0])
+	}
+
+	if _, err := svc.AddNote(ctx, agentID, agentID, customerID, "   "); err == nil {
+		t.Error("AddNote accepted a blank note")
+	}
+
+	// Another agent can neither read nor add notes on the customer
+	if _, err := svc.ListNotes(ctx, otherID, customerID); !errors.Is(err, xerrors.ErrUnauthorized) {
+		t.Errorf("ListNotes by another agent = %v, want ErrUnauthorized", err)
+	}
+	if _, err := svc.AddNote(ctx, otherID, otherID, customerID, "Not mine"); !errors.Is(err, xerrors.ErrUnauthorized) {
+		t.Errorf("AddNote by another agent = %v, want ErrUnauthorized", err)
+	}
+}
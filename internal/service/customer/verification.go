@@ -0,0 +1,157 @@
+// internal/service/customer/verification.go
+package customer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"bingwa-service/internal/domain/customer"
+	"bingwa-service/internal/domain/sms"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+const (
+	verificationCodeTTL        = 10 * time.Minute
+	verificationResendInterval = time.Minute
+	verificationMaxAttempts    = 5
+	verificationCodeDigits     = 6
+)
+
+// SendVerificationCode issues a fresh verification code for a customer and texts it straight to
+// the customer's phone. The code never reaches the agent; any previously sent code stops working.
+func (s *CustomerService) SendVerificationCode(ctx context.Context, agentID, customerID int64) (*customer.VerificationCodeSent, error) {
+	c, err := s.customerRepo.FindByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if c.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+	if c.IsVerified {
+		return nil, fmt.Errorf("%w: customer already verified", xerrors.ErrConflict)
+	}
+
+	// Throttle resends so a customer isn't flooded with codes
+	if pending, err := s.customerRepo.FindVerificationCode(ctx, customerID); err == nil {
+		if wait := time.Until(pending.CreatedAt.Add(verificationResendInterval)); wait > 0 {
+			return nil, fmt.Errorf("a code was just sent, try again in %s: %w", wait.Round(time.Second), xerrors.ErrRateLimited)
+		}
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := &customer.VerificationCode{
+		CustomerID: customerID,
+		CodeHash:   hashVerificationCode(code),
+		ExpiresAt:  time.Now().Add(verificationCodeTTL),
+	}
+	if err := s.customerRepo.SaveVerificationCode(ctx, pending); err != nil {
+		return nil, err
+	}
+
+	// Sensitive: the relay blanks the body once it has been sent
+	if err := s.smsRepo.Enqueue(ctx, &sms.Message{
+		AgentIdentityID: agentID,
+		PhoneNumber:     c.PhoneNumber,
+		Body:            fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(verificationCodeTTL.Minutes())),
+		Purpose:         sms.PurposeVerificationCode,
+		Sensitive:       true,
+	}); err != nil {
+		if err := s.customerRepo.DeleteVerificationCode(ctx, customerID); err != nil {
+			s.logger.Warn("failed to clear unsent verification code", zap.Int64("customer_id", customerID), zap.Error(err))
+		}
+		return nil, fmt.Errorf("failed to queue verification SMS: %w", err)
+	}
+
+	s.logger.Info("customer verification code sent",
+		zap.Int64("customer_id", customerID),
+		zap.Int64("agent_id", agentID),
+	)
+
+	return &customer.VerificationCodeSent{
+		CustomerID:  customerID,
+		PhoneNumber: c.PhoneNumber,
+		ExpiresAt:   pending.ExpiresAt,
+	}, nil
+}
+
+// VerifyCustomerCode verifies the customer a code was texted to. It is called by the customer,
+// not the agent, so it is keyed on the phone number the code was sent to.
+func (s *CustomerService) VerifyCustomerCode(ctx context.Context, phone, code string) error {
+	phones := []string{phone}
+	if normalized := normalizePhoneNumber(phone); normalized != phone {
+		phones = append(phones, normalized)
+	}
+
+	pending, err := s.customerRepo.FindVerificationCodesByPhone(ctx, phones)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return fmt.Errorf("%w: no verification code is pending for this number", xerrors.ErrInvalidInput)
+	}
+
+	hash := hashVerificationCode(code)
+	open := 0
+	for _, p := range pending {
+		if p.Attempts >= verificationMaxAttempts {
+			continue
+		}
+		open++
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(p.CodeHash)) != 1 {
+			continue
+		}
+
+		if err := s.customerRepo.MarkAsVerified(ctx, p.CustomerID); err != nil {
+			return fmt.Errorf("failed to verify customer: %w", err)
+		}
+		if err := s.customerRepo.DeleteVerificationCode(ctx, p.CustomerID); err != nil {
+			s.logger.Warn("failed to clear verification code", zap.Int64("customer_id", p.CustomerID), zap.Error(err))
+		}
+
+		s.logger.Info("customer verified", zap.Int64("customer_id", p.CustomerID))
+		return nil
+	}
+
+	if open == 0 {
+		return fmt.Errorf("too many wrong codes, ask for a new one: %w", xerrors.ErrRateLimited)
+	}
+
+	// A wrong guess counts against every code pending for the number
+	for _, p := range pending {
+		if p.Attempts >= verificationMaxAttempts {
+			continue
+		}
+		if err := s.customerRepo.IncrementVerificationAttempts(ctx, p.CustomerID); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: invalid verification code", xerrors.ErrInvalidInput)
+}
+
+// generateVerificationCode returns a random zero-padded numeric code
+func generateVerificationCode() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(verificationCodeDigits), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n.Int64()), nil
+}
+
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
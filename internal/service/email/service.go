@@ -2,10 +2,14 @@
 package email
 
 import (
+	"context"
 	"crypto/tls"
+	"database/sql"
 	"fmt"
 	"net/smtp"
 	"strings"
+
+	"bingwa-service/internal/domain/emaillog"
 )
 
 // EmailSender handles outgoing emails via SMTP.
@@ -16,6 +20,8 @@ type EmailSender struct {
 	password string
 	fromName string
 	secure   bool
+
+	resultHooks []func(ctx context.Context, entry *emaillog.EmailLog)
 }
 
 // NewEmailSender creates a new SMTP email sender.
@@ -30,8 +36,39 @@ func NewEmailSender(host, port, user, pass, fromName string, secure bool) *Email
 	}
 }
 
+// OnResult registers a hook run after every send attempt (e.g. delivery logging).
+func (e *EmailSender) OnResult(hook func(ctx context.Context, entry *emaillog.EmailLog)) {
+	e.resultHooks = append(e.resultHooks, hook)
+}
+
 // Send sends an email with a subject and body (HTML supported).
 func (e *EmailSender) Send(to, subject, bodyHTML string) error {
+	return e.SendAs(emaillog.TypeGeneral, to, subject, bodyHTML)
+}
+
+// SendAs sends an email and reports the outcome, tagged with its type, to the result hooks.
+func (e *EmailSender) SendAs(emailType emaillog.EmailType, to, subject, bodyHTML string) error {
+	err := e.send(to, subject, bodyHTML)
+
+	entry := &emaillog.EmailLog{
+		Recipient: to,
+		Type:      emailType,
+		Subject:   subject,
+		Status:    emaillog.StatusSent,
+	}
+	if err != nil {
+		entry.Status = emaillog.StatusFailed
+		entry.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+	for _, hook := range e.resultHooks {
+		hook(context.Background(), entry)
+	}
+
+	return err
+}
+
+// send delivers the message over SMTP.
+func (e *EmailSender) send(to, subject, bodyHTML string) error {
 	from := fmt.Sprintf("%s <%s>", e.fromName, e.username)
 	msg := []byte(
 		fmt.Sprintf("From: %s\r\n", from) +
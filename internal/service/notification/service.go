@@ -248,6 +248,36 @@ func (s *NotificationService) BroadcastSystemNotification(ctx context.Context, t
 	return nil
 }
 
+// BroadcastTargetedNotification creates and pushes a notification to every identity matching the target and returns the recipient count
+func (s *NotificationService) BroadcastTargetedNotification(ctx context.Context, target *notification.BroadcastTarget, title, message string, notifType notification.NotificationType, metadata map[string]interface{}) (int, error) {
+	recipients, err := s.repo.FindBroadcastRecipients(ctx, target)
+	if err != nil {
+		return 0, err
+	}
+
+	if notifType == "" {
+		notifType = notification.TypeSystem
+	}
+
+	requests := make([]*notification.CreateNotificationRequest, 0, len(recipients))
+	for _, identityID := range recipients {
+		requests = append(requests, &notification.CreateNotificationRequest{
+			IdentityID: identityID,
+			Title:      title,
+			Message:    message,
+			Type:       notifType,
+			Metadata:   metadata,
+		})
+	}
+
+	sent, err := s.CreateBulkAndPush(ctx, requests)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(sent), nil
+}
+
 // SendAlertNotification sends an alert notification
 func (s *NotificationService) SendAlertNotification(ctx context.Context, identityID int64, title, message string, metadata map[string]interface{}) error {
 	req := &notification.CreateNotificationRequest{
@@ -0,0 +1,58 @@
+// internal/service/notification/service_test.go
+package notification
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"bingwa-service/internal/domain/notification"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+)
+
+func TestBroadcastTargetedNotificationReachesOnlyAgents(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	svc := NewNotificationService(postgres.NewNotificationRepository(pool), nil)
+
+	// Agents hold the "user" role
+	agentID := testutil.Identity(t, pool, "agent@example.com")
+	testutil.Role(t, pool, agentID, "user")
+	secondAgentID := testutil.Identity(t, pool, "second@example.com")
+	testutil.Role(t, pool, secondAgentID, "user")
+	adminID := testutil.Identity(t, pool, "admin@example.com")
+	testutil.Role(t, pool, adminID, "admin")
+	testutil.Identity(t, pool, "norole@example.com")
+	lapsedID := testutil.Identity(t, pool, "lapsed@example.com")
+	testutil.Role(t, pool, lapsedID, "user")
+	if _, err := pool.Exec(ctx, `UPDATE auth_identity_roles SET is_active = FALSE WHERE identity_id = $1`, lapsedID); err != nil {
+		t.Fatalf("failed to deactivate role: %v", err)
+	}
+
+	sent, err := svc.BroadcastTargetedNotification(ctx, &notification.BroadcastTarget{Roles: []string{"user"}},
+		"Maintenance", "USSD gateway down at midnight", "", nil)
+	if err != nil {
+		t.Fatalf("BroadcastTargetedNotification: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("sent %d notifications, want 2", sent)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT identity_id FROM notifications WHERE title = 'Maintenance' ORDER BY identity_id`)
+	if err != nil {
+		t.Fatalf("failed to read notifications: %v", err)
+	}
+	recipients := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan recipient: %v", err)
+		}
+		recipients = append(recipients, id)
+	}
+	rows.Close()
+	if want := []int64{agentID, secondAgentID}; !reflect.DeepEqual(recipients, want) {
+		t.Errorf("notified %v, want the agents %v", recipients, want)
+	}
+}
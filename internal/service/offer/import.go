@@ -0,0 +1,105 @@
+// internal/service/offer/import.go
+package offer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// maxOfferCodeLength matches the agent_offers.offer_code column
+const maxOfferCodeLength = 50
+
+// offerCodePattern is the shape of offer codes: uppercase alphanumeric segments joined by hyphens (e.g. DATA-5GB-30D-1)
+var offerCodePattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
+// ValidateOfferCode checks that an offer code follows the offer code format
+func ValidateOfferCode(code string) error {
+	if code == "" {
+		return fmt.Errorf("%w: offer code is required", xerrors.ErrInvalidInput)
+	}
+	if len(code) > maxOfferCodeLength {
+		return fmt.Errorf("%w: offer code exceeds %d characters", xerrors.ErrInvalidInput, maxOfferCodeLength)
+	}
+	if !offerCodePattern.MatchString(code) {
+		return fmt.Errorf("%w: offer code %q must be uppercase letters and digits separated by single hyphens", xerrors.ErrInvalidInput, code)
+	}
+	return nil
+}
+
+// ImportOffers creates offers under the codes supplied by the caller. Each row is validated and
+// created on its own, so one bad row doesn't stop the rest; codes that are malformed, repeated
+// in the import or already taken are rejected.
+func (s *OfferService) ImportOffers(ctx context.Context, agentID int64, rows []offer.ImportOfferRow) *offer.ImportOffersResponse {
+	result := &offer.ImportOffersResponse{
+		Total:   len(rows),
+		Results: make([]offer.ImportRowResult, 0, len(rows)),
+	}
+	seen := make(map[string]int, len(rows))
+
+	for i := range rows {
+		row := &rows[i]
+		code := strings.TrimSpace(row.OfferCode)
+		rowResult := offer.ImportRowResult{Row: i + 1, OfferCode: code}
+
+		if err := s.checkImportCode(ctx, code, seen); err != nil {
+			rowResult.Status = offer.ImportRowInvalid
+			rowResult.Error = err.Error()
+		} else if created, err := s.createOffer(ctx, agentID, &row.CreateOfferRequest, code); err != nil {
+			rowResult.Status = offer.ImportRowFailed
+			rowResult.Error = err.Error()
+		} else {
+			rowResult.Status = offer.ImportRowCreated
+			rowResult.OfferID = created.ID
+		}
+
+		if code != "" {
+			if _, ok := seen[code]; !ok {
+				seen[code] = rowResult.Row
+			}
+		}
+
+		if rowResult.Status == offer.ImportRowCreated {
+			result.SuccessCount++
+		} else {
+			result.FailureCount++
+		}
+		result.Results = append(result.Results, rowResult)
+	}
+
+	s.logger.Info("offers imported",
+		zap.Int64("agent_id", agentID),
+		zap.Int("total", result.Total),
+		zap.Int("created", result.SuccessCount),
+	)
+
+	return result
+}
+
+// checkImportCode validates an imported code's format and that it collides with neither an
+// earlier row nor an existing offer
+func (s *OfferService) checkImportCode(ctx context.Context, code string, seen map[string]int) error {
+	if err := ValidateOfferCode(code); err != nil {
+		return err
+	}
+
+	if row, ok := seen[code]; ok {
+		return fmt.Errorf("%w: offer code %s repeats row %d", xerrors.ErrConflict, code, row)
+	}
+
+	exists, err := s.offerRepo.ExistsByOfferCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to check offer code: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("%w: offer code %s is already in use", xerrors.ErrConflict, code)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// internal/service/offer/import_test.go
+package offer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	xerrors "bingwa-service/internal/pkg/errors"
+)
+
+func TestValidateOfferCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{"generated format", "DATA-5GB-30D-1", false},
+		{"single segment", "PROMO2024", false},
+		{"longest allowed", strings.Repeat("A", maxOfferCodeLength), false},
+		{"too long", strings.Repeat("A", maxOfferCodeLength+1), true},
+		{"empty", "", true},
+		{"lowercase", "data-5gb", true},
+		{"double hyphen", "DATA--5GB", true},
+		{"leading hyphen", "-DATA", true},
+		{"trailing hyphen", "DATA-", true},
+		{"space", "DATA 5GB", true},
+		{"underscore", "DATA_5GB", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOfferCode(tt.code)
+			if tt.wantErr {
+				if !errors.Is(err, xerrors.ErrInvalidInput) {
+					t.Errorf("ValidateOfferCode(%q) error = %v, want ErrInvalidInput", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateOfferCode(%q) unexpected error: %v", tt.code, err)
+			}
+		})
+	}
+}
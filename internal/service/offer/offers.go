@@ -2,58 +2,172 @@
 package offer
 
 import (
+	"archive/zip"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"bingwa-service/internal/domain/offer"
 	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/pagination"
 	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
+	notificationsvc "bingwa-service/internal/service/notification"
 
+	qrcode "github.com/skip2/go-qrcode"
 	"go.uber.org/zap"
 )
 
+// qrCodeSize is the width and height of generated QR PNGs in pixels
+const qrCodeSize = 512
+
+// discountWarningThreshold is the discount percentage above which create/update responses carry a warning
+const discountWarningThreshold = 50.0
+
+// Offer metadata keys that set a floor for the discounted price
+const (
+	metadataKeyMinPrice  = "min_price"
+	metadataKeyCostPrice = "cost_price"
+)
+
 type OfferService struct {
-	offerRepo    *postgres.AgentOfferRepository
-	ussdCodeRepo *postgres.OfferUSSDCodeRepository
-	logger       *zap.Logger
+	offerRepo        *postgres.AgentOfferRepository
+	ussdCodeRepo     *postgres.OfferUSSDCodeRepository
+	templateRepo     *postgres.OfferTemplateRepository
+	customerRepo     *postgres.AgentCustomerRepository
+	authRepo         *postgres.AuthRepository
+	subscriptionRepo *postgres.AgentSubscriptionRepository
+	planRepo         *postgres.SubscriptionPlanRepository
+	configService    *configsvc.ConfigService
+	notifService     *notificationsvc.NotificationService
+	db               *postgres.DB
+	minAmounts       offer.MinimumAmounts
+	maxValidityDays  int
+	offerCache       *cache.OfferCache
+	logger           *zap.Logger
 }
 
-func NewOfferService(offerRepo *postgres.AgentOfferRepository, ussdCodeRepo *postgres.OfferUSSDCodeRepository, logger *zap.Logger) *OfferService {
+// OfferRepositories are the stores the offer service reads and writes
+type OfferRepositories struct {
+	Offers        *postgres.AgentOfferRepository
+	USSDCodes     *postgres.OfferUSSDCodeRepository
+	Templates     *postgres.OfferTemplateRepository
+	Customers     *postgres.AgentCustomerRepository
+	Auth          *postgres.AuthRepository
+	Subscriptions *postgres.AgentSubscriptionRepository
+	Plans         *postgres.SubscriptionPlanRepository
+}
+
+// Errors AdminTransferOffer returns for a missing offer or target agent. Both wrap xerrors.ErrNotFound.
+var (
+	ErrOfferNotFound = fmt.Errorf("%w: offer", xerrors.ErrNotFound)
+	ErrAgentNotFound = fmt.Errorf("%w: agent", xerrors.ErrNotFound)
+)
+
+func NewOfferService(repos OfferRepositories, configService *configsvc.ConfigService, db *postgres.DB, offerCache *cache.OfferCache, logger *zap.Logger) *OfferService {
 	return &OfferService{
-		offerRepo:    offerRepo,
-		ussdCodeRepo: ussdCodeRepo,
-		logger:       logger,
+		offerRepo:        repos.Offers,
+		ussdCodeRepo:     repos.USSDCodes,
+		templateRepo:     repos.Templates,
+		customerRepo:     repos.Customers,
+		authRepo:         repos.Auth,
+		subscriptionRepo: repos.Subscriptions,
+		planRepo:         repos.Plans,
+		configService:    configService,
+		db:               db,
+		minAmounts:       offer.DefaultMinimumAmounts,
+		maxValidityDays:  offer.DefaultMaxValidityDays,
+		offerCache:       offerCache,
+		logger:           logger,
+	}
+}
+
+// SetNotificationService configures where back-in-stock notices are sent
+func (s *OfferService) SetNotificationService(notifService *notificationsvc.NotificationService) {
+	s.notifService = notifService
+}
+
+// SetMinimumAmounts sets the smallest sellable amount per offer type
+func (s *OfferService) SetMinimumAmounts(amounts offer.MinimumAmounts) {
+	s.minAmounts = amounts
+}
+
+// SetMaxValidityDays sets the longest validity an offer or template may have; values below 1 keep the default
+func (s *OfferService) SetMaxValidityDays(days int) {
+	if days < 1 {
+		days = offer.DefaultMaxValidityDays
 	}
+	s.maxValidityDays = days
 }
 
 // ========== Offer CRUD Operations ==========
 
 // CreateOffer creates a new offer for an agent (with initial USSD code in transaction)
 func (s *OfferService) CreateOffer(ctx context.Context, agentID int64, req *offer.CreateOfferRequest) (*offer.AgentOffer, error) {
+	return s.createOffer(ctx, agentID, req, "")
+}
+
+// createOffer creates an offer under offerCode, generating a code when it is empty
+func (s *OfferService) createOffer(ctx context.Context, agentID int64, req *offer.CreateOfferRequest, offerCode string) (*offer.AgentOffer, error) {
 	// Validate offer type and units
 	if err := s.validateOfferTypeAndUnits(req.Type, req.Units); err != nil {
 		return nil, err
 	}
 
+	// Validate amount against the per-type minimum
+	if err := s.validateOfferAmount(req.Type, req.Units, req.Amount); err != nil {
+		return nil, err
+	}
+
+	// Validate validity period
+	if err := s.validateValidityDays(req.ValidityDays); err != nil {
+		return nil, err
+	}
+
+	// Fall back to the agent's display currency
+	if strings.TrimSpace(req.Currency) == "" {
+		req.Currency = s.configService.DefaultCurrency(ctx, agentID)
+	}
+
 	// Validate USSD code template
 	if err := s.validateUSSDCodeTemplate(req.USSDCodeTemplate); err != nil {
 		return nil, err
 	}
 
+	// Enforce the subscription tier's offer cap
+	if err := s.checkOfferLimit(ctx, agentID); err != nil {
+		return nil, err
+	}
+
+	// Enforce featured offer limit
+	if req.IsFeatured {
+		if err := s.checkFeaturedLimit(ctx, agentID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate unique offer code
-	offerCode, err := s.generateOfferCode(ctx, agentID, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate offer code: %w", err)
+	if offerCode == "" {
+		generated, err := s.generateOfferCode(ctx, agentID, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate offer code: %w", err)
+		}
+		offerCode = generated
 	}
 
 	// Generate validity label if not provided
 	validityLabel := req.ValidityLabel
 	if validityLabel == "" {
-		validityLabel = s.generateValidityLabel(req.ValidityDays)
+		validityLabel = s.generateValidityLabel(req.ValidityDays, s.labelLanguage(ctx, agentID))
 	}
 
 	// Create offer entity
@@ -85,6 +199,20 @@ func (s *OfferService) CreateOffer(ctx context.Context, agentID int64, req *offe
 	if req.MaxPurchasesPerCustomer != nil {
 		o.MaxPurchasesPerCustomer = sql.NullInt32{Int32: *req.MaxPurchasesPerCustomer, Valid: true}
 	}
+	if req.StockLimit != nil {
+		o.StockLimit = sql.NullInt32{Int32: *req.StockLimit, Valid: true}
+	}
+	o.PurchaseLimitPeriod = offer.PurchaseLimitLifetime
+	if req.PurchaseLimitPeriod != "" {
+		o.PurchaseLimitPeriod = req.PurchaseLimitPeriod
+	}
+	if req.PurchaseCooldownSeconds != nil && *req.PurchaseCooldownSeconds > 0 {
+		o.PurchaseCooldownSeconds = sql.NullInt32{Int32: *req.PurchaseCooldownSeconds, Valid: true}
+	}
+	o.RequireConfirmation = req.RequireConfirmation
+	if deviceID := strings.TrimSpace(req.RequiredDeviceID); deviceID != "" {
+		o.RequiredDeviceID = sql.NullString{String: deviceID, Valid: true}
+	}
 	if req.AvailableFrom != nil {
 		o.AvailableFrom = sql.NullTime{Time: *req.AvailableFrom, Valid: true}
 	}
@@ -92,6 +220,19 @@ func (s *OfferService) CreateOffer(ctx context.Context, agentID int64, req *offe
 		o.AvailableUntil = sql.NullTime{Time: *req.AvailableUntil, Valid: true}
 	}
 
+	// Validate discounted price against floor
+	warnings, err := s.validateDiscountFloor(o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flag likely typos in the price unless the agent confirmed it
+	if !req.Confirm {
+		if warning := s.priceAnomalyWarning(ctx, o); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
 	// Create in database (repo handles USSD code creation in transaction)
 	if err := s.offerRepo.Create(ctx, o); err != nil {
 		s.logger.Error("failed to create offer", zap.Error(err))
@@ -105,7 +246,13 @@ func (s *OfferService) CreateOffer(ctx context.Context, agentID int64, req *offe
 	)
 
 	// Return with primary USSD code loaded
-	return s.offerRepo.FindByID(ctx, o.ID)
+	created, err := s.offerRepo.FindByID(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.Warnings = warnings
+
+	return created, nil
 }
 
 // GetOffer retrieves an offer by ID (with primary USSD code)
@@ -141,32 +288,41 @@ func (s *OfferService) GetOfferByCode(ctx context.Context, agentID int64, offerC
 // ListOffers retrieves offers for an agent with filters (with primary USSD codes)
 func (s *OfferService) ListOffers(ctx context.Context, agentID int64, filters *offer.OfferListFilters) (*offer.OfferListResponse, error) {
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
-	}
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	offers, total, err := s.offerRepo.List(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list offers: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &offer.OfferListResponse{
 		Offers:     offers,
-		Total:      total,
-		Page:       filters.Page,
-		PageSize:   filters.PageSize,
-		TotalPages: totalPages,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
+	}, nil
+}
+
+// ListOfferSummaries lists offers in the lightweight summary projection, without USSD codes
+func (s *OfferService) ListOfferSummaries(ctx context.Context, agentID int64, filters *offer.OfferListFilters) (*offer.OfferSummaryListResponse, error) {
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
+
+	summaries, total, err := s.offerRepo.ListSummaries(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offers: %w", err)
+	}
+
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
+
+	return &offer.OfferSummaryListResponse{
+		Offers:     summaries,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
 	}, nil
 }
 
@@ -187,6 +343,24 @@ func (s *OfferService) GetFeaturedOffers(ctx context.Context, agentID int64, lim
 	return offers, nil
 }
 
+// GetAvailableAt lists the agent's offers whose availability window includes the given time.
+// Device presence can't be known ahead of time, so required devices aren't checked.
+func (s *OfferService) GetAvailableAt(ctx context.Context, agentID int64, at time.Time) ([]offer.AgentOffer, error) {
+	offers, err := s.offerRepo.FindActiveByAgent(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+
+	available := []offer.AgentOffer{}
+	for i := range offers {
+		if unavailableReason(&offers[i], at) == "" {
+			available = append(available, offers[i])
+		}
+	}
+
+	return available, nil
+}
+
 // UpdateOffer updates an offer
 func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64, req *offer.UpdateOfferRequest) (*offer.AgentOffer, error) {
 	// Get existing offer
@@ -199,6 +373,7 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 	if o.AgentIdentityID != agentID {
 		return nil, xerrors.ErrUnauthorized
 	}
+	oldPrice := o.Price
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -223,9 +398,12 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 		o.DiscountPercentage = *req.DiscountPercentage
 	}
 	if req.ValidityDays != nil {
+		if err := s.validateValidityDays(*req.ValidityDays); err != nil {
+			return nil, err
+		}
 		o.ValidityDays = *req.ValidityDays
 		// Regenerate validity label
-		o.ValidityLabel = sql.NullString{String: s.generateValidityLabel(*req.ValidityDays), Valid: true}
+		o.ValidityLabel = sql.NullString{String: s.generateValidityLabel(*req.ValidityDays, s.labelLanguage(ctx, agentID)), Valid: true}
 	}
 	if req.ValidityLabel != nil {
 		o.ValidityLabel = sql.NullString{String: *req.ValidityLabel, Valid: *req.ValidityLabel != ""}
@@ -246,6 +424,12 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 		o.USSDErrorPattern = sql.NullString{String: *req.USSDErrorPattern, Valid: *req.USSDErrorPattern != ""}
 	}
 	if req.IsFeatured != nil {
+		// Enforce featured offer limit only when newly featuring
+		if *req.IsFeatured && !o.IsFeatured {
+			if err := s.checkFeaturedLimit(ctx, agentID); err != nil {
+				return nil, err
+			}
+		}
 		o.IsFeatured = *req.IsFeatured
 	}
 	if req.IsRecurring != nil {
@@ -254,6 +438,19 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 	if req.MaxPurchasesPerCustomer != nil {
 		o.MaxPurchasesPerCustomer = sql.NullInt32{Int32: *req.MaxPurchasesPerCustomer, Valid: true}
 	}
+	if req.PurchaseLimitPeriod != nil {
+		o.PurchaseLimitPeriod = *req.PurchaseLimitPeriod
+	}
+	if req.PurchaseCooldownSeconds != nil {
+		o.PurchaseCooldownSeconds = sql.NullInt32{Int32: *req.PurchaseCooldownSeconds, Valid: *req.PurchaseCooldownSeconds > 0}
+	}
+	if req.RequireConfirmation != nil {
+		o.RequireConfirmation = *req.RequireConfirmation
+	}
+	if req.RequiredDeviceID != nil {
+		deviceID := strings.TrimSpace(*req.RequiredDeviceID)
+		o.RequiredDeviceID = sql.NullString{String: deviceID, Valid: deviceID != ""}
+	}
 	if req.AvailableFrom != nil {
 		o.AvailableFrom = sql.NullTime{Time: *req.AvailableFrom, Valid: true}
 	}
@@ -272,6 +469,24 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 		return nil, err
 	}
 
+	// Validate amount against the per-type minimum
+	if err := s.validateOfferAmount(o.Type, o.Units, o.Amount); err != nil {
+		return nil, err
+	}
+
+	// Validate discounted price against floor
+	warnings, err := s.validateDiscountFloor(o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flag likely typos when the price or what it buys changed, unless the agent confirmed it
+	if !req.Confirm && (req.Price != nil || req.Type != nil || req.Amount != nil) {
+		if warning := s.priceAnomalyWarning(ctx, o); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
 	// Update in database
 	if err := s.offerRepo.Update(ctx, offerID, o); err != nil {
 		s.logger.Error("failed to update offer", zap.Error(err))
@@ -283,8 +498,29 @@ func (s *OfferService) UpdateOffer(ctx context.Context, agentID, offerID int64,
 		zap.Int64("agent_id", agentID),
 	)
 
+	if o.Price != oldPrice {
+		change := &offer.OfferPriceChange{
+			OfferID:         offerID,
+			AgentIdentityID: agentID,
+			OldPrice:        oldPrice,
+			NewPrice:        o.Price,
+			Source:          offer.PriceChangeManual,
+		}
+		if err := s.offerRepo.CreatePriceHistory(ctx, change); err != nil {
+			s.logger.Warn("failed to record price history", zap.Int64("offer_id", offerID), zap.Error(err))
+		}
+	}
+
+	s.InvalidateOfferCache(ctx, agentID)
+
 	// Return updated offer
-	return s.offerRepo.FindByID(ctx, offerID)
+	updated, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	updated.Warnings = warnings
+
+	return updated, nil
 }
 
 // ActivateOffer activates an offer
@@ -298,10 +534,16 @@ func (s *OfferService) ActivateOffer(ctx context.Context, agentID, offerID int64
 		return xerrors.ErrUnauthorized
 	}
 
+	if err := s.ensureActivatable(ctx, offerID); err != nil {
+		return err
+	}
+
 	if err := s.offerRepo.UpdateStatus(ctx, offerID, offer.OfferStatusActive); err != nil {
 		return fmt.Errorf("failed to activate offer: %w", err)
 	}
 
+	s.InvalidateOfferCache(ctx, agentID)
+
 	s.logger.Info("offer activated",
 		zap.Int64("offer_id", offerID),
 		zap.Int64("agent_id", agentID),
@@ -325,6 +567,8 @@ func (s *OfferService) DeactivateOffer(ctx context.Context, agentID, offerID int
 		return fmt.Errorf("failed to deactivate offer: %w", err)
 	}
 
+	s.InvalidateOfferCache(ctx, agentID)
+
 	s.logger.Info("offer deactivated",
 		zap.Int64("offer_id", offerID),
 		zap.Int64("agent_id", agentID),
@@ -348,6 +592,8 @@ func (s *OfferService) PauseOffer(ctx context.Context, agentID, offerID int64) e
 		return fmt.Errorf("failed to pause offer: %w", err)
 	}
 
+	s.InvalidateOfferCache(ctx, agentID)
+
 	s.logger.Info("offer paused",
 		zap.Int64("offer_id", offerID),
 		zap.Int64("agent_id", agentID),
@@ -372,6 +618,8 @@ func (s *OfferService) DeleteOffer(ctx context.Context, agentID, offerID int64)
 		return fmt.Errorf("failed to delete offer: %w", err)
 	}
 
+	s.InvalidateOfferCache(ctx, agentID)
+
 	s.logger.Info("offer deleted",
 		zap.Int64("offer_id", offerID),
 		zap.Int64("agent_id", agentID),
@@ -380,9 +628,69 @@ func (s *OfferService) DeleteOffer(ctx context.Context, agentID, offerID int64)
 	return nil
 }
 
-// GetOfferStats retrieves statistics for an agent's offers
-func (s *OfferService) GetOfferStats(ctx context.Context, agentID int64) (*offer.OfferStats, error) {
-	stats, err := s.offerRepo.GetStats(ctx, agentID)
+// BulkDelete soft deletes the agent's offers among offerIDs, skipping foreign or already-deleted IDs
+func (s *OfferService) BulkDelete(ctx context.Context, agentID int64, offerIDs []int64) (*offer.BulkDeleteResult, error) {
+	unique := make([]int64, 0, len(offerIDs))
+	seen := make(map[int64]bool, len(offerIDs))
+	for _, id := range offerIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	deletedIDs, err := s.offerRepo.SoftDeleteByIDs(ctx, agentID, unique)
+	if err != nil {
+		s.logger.Error("failed to bulk delete offers", zap.Error(err))
+		return nil, fmt.Errorf("failed to bulk delete offers: %w", err)
+	}
+
+	deleted := make(map[int64]bool, len(deletedIDs))
+	for _, id := range deletedIDs {
+		deleted[id] = true
+	}
+
+	skippedIDs := make([]int64, 0)
+	for _, id := range unique {
+		if !deleted[id] {
+			skippedIDs = append(skippedIDs, id)
+		}
+	}
+
+	if len(deletedIDs) > 0 {
+		s.InvalidateOfferCache(ctx, agentID)
+	}
+
+	s.logger.Info("offers bulk deleted",
+		zap.Int64("agent_id", agentID),
+		zap.Int("deleted", len(deletedIDs)),
+		zap.Int("skipped", len(skippedIDs)),
+	)
+
+	return &offer.BulkDeleteResult{
+		Deleted:    len(deletedIDs),
+		DeletedIDs: deletedIDs,
+		SkippedIDs: skippedIDs,
+	}, nil
+}
+
+// GetSearchFacets returns offer counts per type, status and price bucket for the given filters
+func (s *OfferService) GetSearchFacets(ctx context.Context, agentID int64, filters *offer.OfferListFilters) (*offer.OfferSearchFacets, error) {
+	facets, err := s.offerRepo.GetSearchFacets(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search facets: %w", err)
+	}
+
+	return facets, nil
+}
+
+// GetOfferStats retrieves statistics for an agent's offers; nil filters cover all time
+func (s *OfferService) GetOfferStats(ctx context.Context, agentID int64, filters *offer.StatsFilters) (*offer.OfferStats, error) {
+	if filters != nil && filters.From != nil && filters.To != nil && filters.From.After(*filters.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", xerrors.ErrInvalidInput)
+	}
+
+	stats, err := s.offerRepo.GetStats(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get offer stats: %w", err)
 	}
@@ -390,6 +698,73 @@ func (s *OfferService) GetOfferStats(ctx context.Context, agentID int64) (*offer
 	return stats, nil
 }
 
+// maxComparisonDays caps the length of a stats comparison period
+const maxComparisonDays = 366
+
+// GetStatsComparison compares the agent's sales over the latest period (e.g. "30d" or "4w") with the period before it
+func (s *OfferService) GetStatsComparison(ctx context.Context, agentID int64, period string) (*offer.OfferStatsComparison, error) {
+	length, err := parseStatsPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	current, err := s.offerRepo.GetPeriodStats(ctx, agentID, now.Add(-length), now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.offerRepo.GetPeriodStats(ctx, agentID, now.Add(-2*length), now.Add(-length))
+	if err != nil {
+		return nil, err
+	}
+
+	return &offer.OfferStatsComparison{
+		Period:   period,
+		Current:  *current,
+		Previous: *previous,
+		Change: offer.StatsChange{
+			Revenue:      percentChange(previous.Revenue, current.Revenue),
+			Redemptions:  percentChange(float64(previous.Redemptions), float64(current.Redemptions)),
+			ActiveOffers: percentChange(float64(previous.ActiveOffers), float64(current.ActiveOffers)),
+		},
+	}, nil
+}
+
+// parseStatsPeriod parses a period of days or weeks such as "30d" or "4w"
+func parseStatsPeriod(period string) (time.Duration, error) {
+	if len(period) < 2 {
+		return 0, fmt.Errorf("%w: period must look like 30d or 4w", xerrors.ErrInvalidInput)
+	}
+
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: period must look like 30d or 4w", xerrors.ErrInvalidInput)
+	}
+
+	days := n
+	switch period[len(period)-1] {
+	case 'd':
+	case 'w':
+		days = n * 7
+	default:
+		return 0, fmt.Errorf("%w: period unit must be d or w", xerrors.ErrInvalidInput)
+	}
+	if days > maxComparisonDays {
+		return 0, fmt.Errorf("%w: period cannot exceed %d days", xerrors.ErrInvalidInput, maxComparisonDays)
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// percentChange returns the change from previous to current in percent, rounded to two decimals
+func percentChange(previous, current float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*10000) / 100
+	return &change
+}
+
 // CloneOffer creates a copy of an existing offer
 func (s *OfferService) CloneOffer(ctx context.Context, agentID, offerID int64, newName string) (*offer.AgentOffer, error) {
 	// Get original offer
@@ -421,6 +796,9 @@ func (s *OfferService) CloneOffer(ctx context.Context, agentID, offerID int64, n
 		USSDErrorPattern:        original.USSDErrorPattern.String,
 		IsFeatured:              false, // Clones are not featured by default
 		IsRecurring:             original.IsRecurring,
+		PurchaseLimitPeriod:     original.PurchaseLimitPeriod,
+		RequiredDeviceID:        original.RequiredDeviceID.String,
+		RequireConfirmation:     original.RequireConfirmation,
 		Tags:                    original.Tags,
 		Metadata:                original.Metadata,
 	}
@@ -429,6 +807,14 @@ func (s *OfferService) CloneOffer(ctx context.Context, agentID, offerID int64, n
 		maxPurchases := original.MaxPurchasesPerCustomer.Int32
 		req.MaxPurchasesPerCustomer = &maxPurchases
 	}
+	if original.StockLimit.Valid {
+		stock := original.StockLimit.Int32
+		req.StockLimit = &stock
+	}
+	if original.PurchaseCooldownSeconds.Valid {
+		cooldown := original.PurchaseCooldownSeconds.Int32
+		req.PurchaseCooldownSeconds = &cooldown
+	}
 
 	return s.CreateOffer(ctx, agentID, req)
 }
@@ -449,6 +835,230 @@ func (s *OfferService) SearchOffers(ctx context.Context, agentID int64, query st
 	return result, nil
 }
 
+// RenameTag renames a tag across all of an agent's offers and customers in one transaction
+func (s *OfferService) RenameTag(ctx context.Context, agentID int64, oldTag, newTag string) (*offer.RenameTagResult, error) {
+	oldTag = strings.TrimSpace(oldTag)
+	newTag = strings.TrimSpace(newTag)
+
+	if oldTag == "" || newTag == "" {
+		return nil, fmt.Errorf("tags cannot be empty")
+	}
+	if oldTag == newTag {
+		return nil, fmt.Errorf("new tag must differ from old tag")
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	offersUpdated, err := s.offerRepo.RenameTagWithTx(ctx, tx, agentID, oldTag, newTag)
+	if err != nil {
+		return nil, err
+	}
+
+	customersUpdated, err := s.customerRepo.RenameTagWithTx(ctx, tx, agentID, oldTag, newTag)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if offersUpdated > 0 {
+		s.InvalidateOfferCache(ctx, agentID)
+	}
+
+	s.logger.Info("tag renamed",
+		zap.Int64("agent_id", agentID),
+		zap.String("old_tag", oldTag),
+		zap.String("new_tag", newTag),
+		zap.Int64("offers_updated", offersUpdated),
+		zap.Int64("customers_updated", customersUpdated),
+	)
+
+	return &offer.RenameTagResult{
+		OldTag:           oldTag,
+		NewTag:           newTag,
+		OffersUpdated:    offersUpdated,
+		CustomersUpdated: customersUpdated,
+	}, nil
+}
+
+// BulkAddTag tags the agent's offers among offerIDs; re-tagging an offer is a no-op
+func (s *OfferService) BulkAddTag(ctx context.Context, agentID int64, offerIDs []int64, tag string) (*offer.BulkTagResult, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("tag cannot be empty")
+	}
+
+	updatedIDs, err := s.offerRepo.AddTagByIDs(ctx, agentID, offerIDs, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updatedIDs) > 0 {
+		s.InvalidateOfferCache(ctx, agentID)
+	}
+
+	s.logger.Info("offers bulk tagged",
+		zap.Int64("agent_id", agentID),
+		zap.String("tag", tag),
+		zap.Int("updated", len(updatedIDs)),
+	)
+
+	return &offer.BulkTagResult{Tag: tag, Updated: len(updatedIDs), UpdatedIDs: updatedIDs}, nil
+}
+
+// BulkRemoveTag removes a tag from the agent's offers among offerIDs
+func (s *OfferService) BulkRemoveTag(ctx context.Context, agentID int64, offerIDs []int64, tag string) (*offer.BulkTagResult, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("tag cannot be empty")
+	}
+
+	updatedIDs, err := s.offerRepo.RemoveTagByIDs(ctx, agentID, offerIDs, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updatedIDs) > 0 {
+		s.InvalidateOfferCache(ctx, agentID)
+	}
+
+	s.logger.Info("offers bulk untagged",
+		zap.Int64("agent_id", agentID),
+		zap.String("tag", tag),
+		zap.Int("updated", len(updatedIDs)),
+	)
+
+	return &offer.BulkTagResult{Tag: tag, Updated: len(updatedIDs), UpdatedIDs: updatedIDs}, nil
+}
+
+// GenerateQRBatch renders a QR code PNG for each offer; every ID must belong to the agent
+func (s *OfferService) GenerateQRBatch(ctx context.Context, agentID int64, offerIDs []int64) ([]offer.QRCodeImage, error) {
+	offers, err := s.offerRepo.FindByIDs(ctx, offerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+
+	owned := make(map[int64]*offer.AgentOffer, len(offers))
+	for i := range offers {
+		if offers[i].AgentIdentityID == agentID {
+			owned[offers[i].ID] = &offers[i]
+		}
+	}
+
+	images := make([]offer.QRCodeImage, 0, len(offerIDs))
+	seen := make(map[int64]bool, len(offerIDs))
+	for _, id := range offerIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		o, ok := owned[id]
+		if !ok {
+			return nil, fmt.Errorf("offer %d not found", id)
+		}
+
+		png, err := qrcode.Encode(o.OfferCode, qrcode.Medium, qrCodeSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate QR code for offer %s: %w", o.OfferCode, err)
+		}
+
+		images = append(images, offer.QRCodeImage{
+			OfferID:   o.ID,
+			OfferCode: o.OfferCode,
+			Filename:  o.OfferCode + ".png",
+			PNG:       png,
+		})
+	}
+
+	return images, nil
+}
+
+// WriteQRZip streams QR images to w as a ZIP archive
+func WriteQRZip(w io.Writer, images []offer.QRCodeImage) error {
+	zw := zip.NewWriter(w)
+
+	for _, img := range images {
+		f, err := zw.Create(img.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", img.Filename, err)
+		}
+		if _, err := f.Write(img.PNG); err != nil {
+			return fmt.Errorf("failed to write %s: %w", img.Filename, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return nil
+}
+
+// ========== Admin Operations ==========
+
+// AdminTransferOffer moves an offer and its USSD codes to another agent (admin only)
+func (s *OfferService) AdminTransferOffer(ctx context.Context, offerID, toAgentID int64) (*offer.AgentOffer, error) {
+	o, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w %d", ErrOfferNotFound, offerID)
+		}
+		return nil, err
+	}
+
+	if o.AgentIdentityID == toAgentID {
+		return nil, fmt.Errorf("%w: offer already belongs to agent %d", xerrors.ErrInvalidInput, toAgentID)
+	}
+
+	// The target must be an existing agent account
+	if _, err := s.authRepo.FindIdentityByID(ctx, toAgentID); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w %d", ErrAgentNotFound, toAgentID)
+		}
+		return nil, err
+	}
+	isAgent, err := s.authRepo.IsAgent(ctx, toAgentID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAgent {
+		return nil, fmt.Errorf("%w: identity %d is not an agent", xerrors.ErrInvalidInput, toAgentID)
+	}
+
+	// Regenerate the offer code so it reflects the new agent ID
+	offerCode, err := s.generateOfferCode(ctx, toAgentID, &offer.CreateOfferRequest{
+		Type:         o.Type,
+		Amount:       o.Amount,
+		Units:        o.Units,
+		ValidityDays: o.ValidityDays,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate offer code: %w", err)
+	}
+
+	fromAgentID := o.AgentIdentityID
+	if err := s.offerRepo.TransferOwnership(ctx, offerID, toAgentID, offerCode); err != nil {
+		return nil, err
+	}
+	s.InvalidateOfferCache(ctx, fromAgentID)
+	s.InvalidateOfferCache(ctx, toAgentID)
+
+	s.logger.Info("offer transferred",
+		zap.Int64("offer_id", offerID),
+		zap.Int64("from_agent_id", fromAgentID),
+		zap.Int64("to_agent_id", toAgentID),
+		zap.String("offer_code", offerCode),
+	)
+
+	return s.offerRepo.FindByID(ctx, offerID)
+}
+
 // ========== USSD Code Management ==========
 
 // AddUSSDCode adds a new USSD code to an offer with automatic priority management
@@ -906,6 +1516,94 @@ func (s *OfferService) ToggleUSSDCodeStatus(ctx context.Context, agentID, offerI
 	return nil
 }
 
+// BulkToggleUSSDStatus activates or deactivates several USSD codes at once. Deactivation is
+// all-or-nothing: it is rejected if any offer would be left without an active code.
+func (s *OfferService) BulkToggleUSSDStatus(ctx context.Context, agentID int64, ussdCodeIDs []int64, isActive bool) (*offer.BulkToggleUSSDStatusResponse, error) {
+	// Resolve each code's offer and check the agent owns it
+	codesByOffer := make(map[int64]map[int64]bool)
+	ids := make([]int64, 0, len(ussdCodeIDs))
+	for _, id := range ussdCodeIDs {
+		code, err := s.ussdCodeRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("USSD code %d: %w", id, err)
+		}
+
+		if _, ok := codesByOffer[code.OfferID]; !ok {
+			existingOffer, err := s.offerRepo.FindByID(ctx, code.OfferID)
+			if err != nil {
+				return nil, err
+			}
+			if existingOffer.AgentIdentityID != agentID {
+				return nil, xerrors.ErrUnauthorized
+			}
+			codesByOffer[code.OfferID] = make(map[int64]bool)
+		}
+
+		if !codesByOffer[code.OfferID][id] {
+			codesByOffer[code.OfferID][id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	offerIDs := make([]int64, 0, len(codesByOffer))
+	for offerID := range codesByOffer {
+		offerIDs = append(offerIDs, offerID)
+	}
+	// Lock offers in a fixed order so concurrent bulk toggles can't deadlock
+	sort.Slice(offerIDs, func(i, j int) bool { return offerIDs[i] < offerIDs[j] })
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if !isActive {
+		stranded := []string{}
+		for _, offerID := range offerIDs {
+			activeIDs, err := s.ussdCodeRepo.LockActiveIDsWithTx(ctx, tx, offerID)
+			if err != nil {
+				return nil, err
+			}
+
+			remaining := 0
+			for _, activeID := range activeIDs {
+				if !codesByOffer[offerID][activeID] {
+					remaining++
+				}
+			}
+			if remaining == 0 {
+				stranded = append(stranded, strconv.FormatInt(offerID, 10))
+			}
+		}
+		if len(stranded) > 0 {
+			return nil, fmt.Errorf("%w: deactivating these codes would leave offers %s without an active USSD code", xerrors.ErrConflict, strings.Join(stranded, ", "))
+		}
+	}
+
+	updated, err := s.ussdCodeRepo.SetActiveWithTx(ctx, tx, ids, isActive)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("USSD code status bulk toggled",
+		zap.Int64("agent_id", agentID),
+		zap.Int("codes", len(ids)),
+		zap.Int("offers", len(offerIDs)),
+		zap.Bool("is_active", isActive),
+	)
+
+	return &offer.BulkToggleUSSDStatusResponse{
+		Updated:  int(updated),
+		IsActive: isActive,
+		OfferIDs: offerIDs,
+	}, nil
+}
+
 // RecordUSSDResult records the result of a USSD execution
 func (s *OfferService) RecordUSSDResult(ctx context.Context, agentID, offerID int64, req *offer.RecordUSSDResultRequest) error {
 	// Verify offer ownership
@@ -948,6 +1646,84 @@ func (s *OfferService) RecordUSSDResult(ctx context.Context, agentID, offerID in
 	return nil
 }
 
+// TestUSSDCode classifies a raw test response against the code's patterns and stores it as a test run
+// (test runs are not counted in the code's success/failure stats)
+func (s *OfferService) TestUSSDCode(ctx context.Context, agentID, offerID, ussdCodeID int64, testPhone, rawResponse string) (*offer.USSDCodeTestRun, error) {
+	// Verify offer ownership
+	existingOffer, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingOffer.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+
+	// Get USSD code
+	ussdCode, err := s.ussdCodeRepo.FindByID(ctx, ussdCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify USSD code belongs to this offer
+	if ussdCode.OfferID != offerID {
+		return nil, xerrors.ErrUnauthorized
+	}
+
+	// Code-level patterns take precedence over the offer's
+	expected := ussdCode.ExpectedResponse.String
+	if expected == "" {
+		expected = existingOffer.USSDExpectedResponse.String
+	}
+	errorPattern := ussdCode.ErrorPattern.String
+	if errorPattern == "" {
+		errorPattern = existingOffer.USSDErrorPattern.String
+	}
+
+	result, matched := classifyUSSDResponse(rawResponse, expected, errorPattern)
+
+	run := &offer.USSDCodeTestRun{
+		USSDCodeID:      ussdCodeID,
+		OfferID:         offerID,
+		AgentIdentityID: agentID,
+		TestPhone:       testPhone,
+		RawResponse:     rawResponse,
+		Result:          result,
+		MatchedPattern:  sql.NullString{String: matched, Valid: matched != ""},
+	}
+
+	if err := s.ussdCodeRepo.CreateTestRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("USSD code tested",
+		zap.Int64("ussd_code_id", ussdCodeID),
+		zap.String("result", string(result)),
+	)
+
+	return run, nil
+}
+
+// classifyUSSDResponse checks the error pattern first, then the expected response, returning the pattern that matched
+func classifyUSSDResponse(response, expected, errorPattern string) (offer.USSDTestResult, string) {
+	if errorPattern != "" && matchesUSSDPattern(response, errorPattern) {
+		return offer.USSDTestResultFailed, errorPattern
+	}
+	if expected != "" && matchesUSSDPattern(response, expected) {
+		return offer.USSDTestResultSuccess, expected
+	}
+	return offer.USSDTestResultUnknown, ""
+}
+
+// matchesUSSDPattern matches a pattern as a case-insensitive regex, falling back to a substring match if it doesn't compile
+func matchesUSSDPattern(response, pattern string) bool {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return strings.Contains(strings.ToLower(response), strings.ToLower(pattern))
+	}
+	return re.MatchString(response)
+}
+
 // GetUSSDCodeStats gets statistics for USSD codes
 func (s *OfferService) GetUSSDCodeStats(ctx context.Context, agentID, offerID int64) (*offer.USSDCodeStats, error) {
 	// Verify offer ownership
@@ -1023,6 +1799,66 @@ func (s *OfferService) validateOfferTypeAndUnits(offerType offer.OfferType, unit
 	return fmt.Errorf("invalid units '%s' for offer type '%s'", units, offerType)
 }
 
+// ensureActivatable rejects going live when none of the offer's USSD codes are active
+func (s *OfferService) ensureActivatable(ctx context.Context, offerID int64) error {
+	codes, err := s.ussdCodeRepo.GetActiveCodesByPriority(ctx, offerID)
+	if err != nil {
+		return fmt.Errorf("failed to get active USSD codes: %w", err)
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("offer has no active USSD code: %w", xerrors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// validateOfferAmount rejects amounts below the configured minimum for the offer type
+func (s *OfferService) validateOfferAmount(offerType offer.OfferType, units offer.OfferUnits, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("offer amount must be greater than zero")
+	}
+
+	var minimum, compared float64
+	var minimumUnits string
+
+	switch offerType {
+	case offer.OfferTypeData:
+		minimum, minimumUnits = s.minAmounts.DataMB, string(offer.UnitsMB)
+		switch units {
+		case offer.UnitsGB:
+			compared = amount * 1024
+		case offer.UnitsKB:
+			compared = amount / 1024
+		default:
+			compared = amount
+		}
+	case offer.OfferTypeSMS:
+		minimum, minimumUnits, compared = s.minAmounts.SMS, string(offer.UnitsSMS), amount
+	case offer.OfferTypeVoice:
+		minimum, minimumUnits, compared = s.minAmounts.VoiceMinutes, string(offer.UnitsMinutes), amount
+	case offer.OfferTypeCombo:
+		minimum, minimumUnits, compared = s.minAmounts.ComboUnits, string(offer.UnitsUnits), amount
+	default:
+		return nil
+	}
+
+	if compared < minimum {
+		return fmt.Errorf("%s offer amount must be at least %g %s", offerType, minimum, minimumUnits)
+	}
+
+	return nil
+}
+
+// validateValidityDays rejects validity periods outside 1..maxValidityDays
+func (s *OfferService) validateValidityDays(days int) error {
+	if days < 1 {
+		return fmt.Errorf("validity days must be at least 1: %w", xerrors.ErrInvalidInput)
+	}
+	if days > s.maxValidityDays {
+		return fmt.Errorf("validity days must be at most %d: %w", s.maxValidityDays, xerrors.ErrInvalidInput)
+	}
+	return nil
+}
+
 // validateUSSDCodeTemplate validates USSD code template format
 func (s *OfferService) validateUSSDCodeTemplate(template string) error {
 	if !strings.HasPrefix(template, "*") {
@@ -1087,77 +1923,270 @@ func (s *OfferService) generateOfferCode(ctx context.Context, agentID int64, req
 	return "", fmt.Errorf("failed to generate unique offer code after %d attempts", maxAttempts)
 }
 
-// generateValidityLabel generates a human-readable validity label
-func (s *OfferService) generateValidityLabel(days int) string {
-	if days == 1 {
-		return "1 day"
+// checkFeaturedLimit rejects featuring another offer once the agent's limit is reached.
+// A limit of 0 means the agent has turned featuring off.
+func (s *OfferService) checkFeaturedLimit(ctx context.Context, agentID int64) error {
+	businessConfig, err := s.configService.GetBusinessConfig(ctx, agentID)
+	if err != nil {
+		return fmt.Errorf("failed to get business config: %w", err)
 	}
-	if days == 7 {
-		return "1 week"
+
+	count, err := s.offerRepo.CountFeatured(ctx, agentID)
+	if err != nil {
+		return err
 	}
-	if days == 30 {
-		return "1 month"
+
+	limit := businessConfig.FeaturedLimit()
+	if limit == 0 {
+		return fmt.Errorf("%w: featured offers are turned off", xerrors.ErrInvalidInput)
 	}
-	if days == 90 {
-		return "3 months"
+	if count >= limit {
+		return fmt.Errorf("featured offer limit reached: %d of %d featured offers in use", count, limit)
 	}
-	if days == 365 {
-		return "1 year"
+
+	return nil
+}
+
+// checkOfferLimit rejects new offers once the agent reaches the max_offers of their active plan.
+// Plans without max_offers, and agents without an active subscription, are not capped.
+func (s *OfferService) checkOfferLimit(ctx context.Context, agentID int64) error {
+	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get active subscription: %w", err)
 	}
-	if days%30 == 0 {
-		return fmt.Sprintf("%d months", days/30)
+
+	plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription plan: %w", err)
 	}
-	if days%7 == 0 {
-		return fmt.Sprintf("%d weeks", days/7)
+	if !plan.MaxOffers.Valid {
+		return nil
 	}
-	return fmt.Sprintf("%d days", days)
-}
 
+	count, err := s.offerRepo.CountByAgent(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	if count >= int(plan.MaxOffers.Int32) {
+		return fmt.Errorf("offer limit reached: %d of %d offers allowed on the %s plan: %w", count, plan.MaxOffers.Int32, plan.Name, xerrors.ErrForbidden)
+	}
+
+	return nil
+}
 
 // CalculateDiscountedPrice calculates price after discount
 func (s *OfferService) CalculateDiscountedPrice(o *offer.AgentOffer) float64 {
-	if o.DiscountPercentage <= 0 {
-		return o.Price
+	price, _ := s.QuoteDiscountedPrice(o)
+	return price
+}
+
+// validateDiscountFloor rejects discounts that push the effective price below the offer's floor
+// (the higher of min_price and cost_price metadata) and warns on unusually large discounts
+func (s *OfferService) validateDiscountFloor(o *offer.AgentOffer) ([]string, error) {
+	warnings := []string{}
+	effectivePrice := s.CalculateDiscountedPrice(o)
+
+	if o.Price > 0 && effectivePrice <= 0 {
+		return nil, fmt.Errorf("discount of %.2f%% reduces price to zero", o.DiscountPercentage)
+	}
+
+	floor := 0.0
+	for _, key := range []string{metadataKeyMinPrice, metadataKeyCostPrice} {
+		if value, ok := metadataFloat(o.Metadata, key); ok && value > floor {
+			floor = value
+		}
 	}
 
-	discount := o.Price * (o.DiscountPercentage / 100)
-	return o.Price - discount
+	if floor > 0 && effectivePrice < floor {
+		return nil, fmt.Errorf("discounted price %.2f is below the minimum allowed price %.2f", effectivePrice, floor)
+	}
+
+	if o.DiscountPercentage > discountWarningThreshold {
+		warnings = append(warnings, fmt.Sprintf(
+			"discount of %.2f%% exceeds %.0f%%; effective price is %.2f %s",
+			o.DiscountPercentage, discountWarningThreshold, effectivePrice, o.Currency,
+		))
+		s.logger.Warn("offer discount exceeds warning threshold",
+			zap.Int64("offer_id", o.ID),
+			zap.Float64("discount_percentage", o.DiscountPercentage),
+			zap.Float64("effective_price", effectivePrice),
+		)
+	}
+
+	return warnings, nil
 }
 
-// IsOfferAvailable checks if offer is currently available
-func (s *OfferService) IsOfferAvailable(o *offer.AgentOffer) bool {
+// metadataFloat reads a numeric metadata value, accepting JSON numbers and numeric strings
+func metadataFloat(metadata map[string]interface{}, key string) (float64, bool) {
+	if metadata == nil {
+		return 0, false
+	}
+
+	switch v := metadata[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false
+		}
+		return value, true
+	}
+
+	return 0, false
+}
+
+// IsOfferAvailable checks if offer is currently available, including whether its required device is online
+func (s *OfferService) IsOfferAvailable(ctx context.Context, o *offer.AgentOffer) bool {
+	return unavailableReason(o, time.Now()) == "" && s.requiredDeviceOnline(ctx, o)
+}
+
+// requiredDeviceOnline reports whether the offer's required device is online; offers without one always pass.
+// Presence lookup failures are logged and treated as online so a Redis outage doesn't block sales.
+func (s *OfferService) requiredDeviceOnline(ctx context.Context, o *offer.AgentOffer) bool {
+	if !o.RequiredDeviceID.Valid {
+		return true
+	}
+
+	online, err := s.configService.IsDeviceOnline(ctx, o.AgentIdentityID, o.RequiredDeviceID.String)
+	if err != nil {
+		s.logger.Warn("failed to check device presence",
+			zap.Int64("offer_id", o.ID),
+			zap.String("device_id", o.RequiredDeviceID.String),
+			zap.Error(err),
+		)
+		return true
+	}
+	return online
+}
+
+// CheckAvailabilityBatch checks availability for many offers in one query.
+// IDs that don't exist or belong to another agent are reported as not_found.
+func (s *OfferService) CheckAvailabilityBatch(ctx context.Context, agentID int64, offerIDs []int64) (map[int64]offer.AvailabilityResult, error) {
+	offers, err := s.offerRepo.FindByIDs(ctx, offerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offers: %w", err)
+	}
+
+	owned := make(map[int64]*offer.AgentOffer, len(offers))
+	for i := range offers {
+		if offers[i].AgentIdentityID == agentID {
+			owned[offers[i].ID] = &offers[i]
+		}
+	}
+
 	now := time.Now()
+	results := make(map[int64]offer.AvailabilityResult, len(offerIDs))
+	for _, id := range offerIDs {
+		o, ok := owned[id]
+		if !ok {
+			results[id] = offer.AvailabilityResult{OfferID: id, Reason: "not_found"}
+			continue
+		}
+
+		reason := unavailableReason(o, now)
+		if reason == "" && !s.requiredDeviceOnline(ctx, o) {
+			reason = "device_offline"
+		}
+		results[id] = offer.AvailabilityResult{
+			OfferID:        o.ID,
+			OfferCode:      o.OfferCode,
+			IsAvailable:    reason == "",
+			Reason:         reason,
+			Status:         o.Status,
+			AvailableFrom:  o.AvailableFrom,
+			AvailableUntil: o.AvailableUntil,
+		}
+	}
+
+	return results, nil
+}
 
+// unavailableReason returns why an offer can't be purchased at the given time, or "" if it can
+func unavailableReason(o *offer.AgentOffer, now time.Time) string {
 	// Check status
 	if o.Status != offer.OfferStatusActive {
-		return false
+		return "inactive"
 	}
 
 	// Check availability window
 	if o.AvailableFrom.Valid && now.Before(o.AvailableFrom.Time) {
-		return false
+		return "not_started"
 	}
 	if o.AvailableUntil.Valid && now.After(o.AvailableUntil.Time) {
-		return false
+		return "expired"
 	}
 
-	return true
+	return ""
+}
+
+// checkCustomerVerified rejects unverified customers when the agent's business config requires verification
+func (s *OfferService) checkCustomerVerified(ctx context.Context, agentID, customerID int64) error {
+	businessConfig, err := s.configService.GetBusinessConfig(ctx, agentID)
+	if err != nil {
+		return fmt.Errorf("failed to load business config: %w", err)
+	}
+	if !businessConfig.RequireCustomerVerification {
+		return nil
+	}
+
+	c, err := s.customerRepo.FindByID(ctx, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to load customer: %w", err)
+	}
+	if !c.IsVerified {
+		return fmt.Errorf("customer must be verified before purchasing: %w", xerrors.ErrForbidden)
+	}
+
+	return nil
 }
 
 // ValidateOfferPurchase validates if a customer can purchase an offer
 func (s *OfferService) ValidateOfferPurchase(ctx context.Context, o *offer.AgentOffer, customerID int64) error {
 	// Check if offer is available
-	if !s.IsOfferAvailable(o) {
+	if unavailableReason(o, time.Now()) != "" {
 		return fmt.Errorf("offer is not currently available")
 	}
+	if !s.requiredDeviceOnline(ctx, o) {
+		return fmt.Errorf("offer is not currently available: its device is offline")
+	}
+
+	// Agents can require customers to confirm their phone number before buying
+	if err := s.checkCustomerVerified(ctx, o.AgentIdentityID, customerID); err != nil {
+		return err
+	}
+
+	// Check max purchases per customer within the limit period
+	if o.MaxPurchasesPerCustomer.Valid {
+		since := o.PurchaseLimitPeriod.WindowStart(time.Now())
+		count, err := s.offerRepo.CountCustomerPurchases(ctx, o.ID, customerID, since)
+		if err != nil {
+			return fmt.Errorf("failed to check purchase limit: %w", err)
+		}
+		if count >= int(o.MaxPurchasesPerCustomer.Int32) {
+			return fmt.Errorf("maximum purchase limit reached: %w", xerrors.ErrRateLimited)
+		}
+	}
 
-	// TODO: Check max purchases per customer when offer_redemptions table is implemented
-	// if o.MaxPurchasesPerCustomer.Valid {
-	//     count := s.getPurchaseCount(ctx, o.ID, customerID)
-	//     if count >= int(o.MaxPurchasesPerCustomer.Int32) {
-	//         return fmt.Errorf("maximum purchase limit reached")
-	//     }
-	// }
+	// Check cooldown since the customer's last successful purchase
+	if o.PurchaseCooldownSeconds.Valid && o.PurchaseCooldownSeconds.Int32 > 0 {
+		last, err := s.offerRepo.LastCustomerPurchaseAt(ctx, o.ID, customerID)
+		if err != nil {
+			return fmt.Errorf("failed to check purchase cooldown: %w", err)
+		}
+		if last != nil {
+			cooldown := time.Duration(o.PurchaseCooldownSeconds.Int32) * time.Second
+			if remaining := time.Until(last.Add(cooldown)); remaining > 0 {
+				return fmt.Errorf("offer can be purchased again in %s: %w", remaining.Round(time.Second), xerrors.ErrRateLimited)
+			}
+		}
+	}
 
 	return nil
 }
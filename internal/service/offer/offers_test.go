@@ -0,0 +1,1129 @@
+// internal/service/offer/offers_test.go
+package offer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
+	customersvc "bingwa-service/internal/service/customer"
+	"bingwa-service/internal/testutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newTestOfferService wires an OfferService against a test database and an in-memory Redis
+func newTestOfferService(t *testing.T) (*OfferService, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ussdCodeRepo := postgres.NewOfferUSSDCodeRepository(pool)
+	svc := NewOfferService(OfferRepositories{
+		Offers:        postgres.NewAgentOfferRepository(pool, ussdCodeRepo, db),
+		USSDCodes:     ussdCodeRepo,
+		Templates:     postgres.NewOfferTemplateRepository(pool),
+		Customers:     postgres.NewAgentCustomerRepository(pool),
+		Auth:          postgres.NewAuthRepository(pool),
+		Subscriptions: postgres.NewAgentSubscriptionRepository(pool),
+		Plans:         postgres.NewSubscriptionPlanRepository(pool),
+	},
+		configsvc.NewConfigService(postgres.NewAgentConfigRepository(pool), cache.NewDevicePresence(client), db, zap.NewNop()),
+		db,
+		cache.NewOfferCache(client),
+		zap.NewNop(),
+	)
+	return svc, pool
+}
+
+// testOfferRequest describes a valid KES data offer of amount GB
+func testOfferRequest(amount float64) *offer.CreateOfferRequest {
+	return &offer.CreateOfferRequest{
+		Name:               fmt.Sprintf("%.0fGB Monthly", amount),
+		Type:               offer.OfferTypeData,
+		Amount:             amount,
+		Units:              offer.UnitsGB,
+		Price:              100 * amount,
+		Currency:           "KES",
+		ValidityDays:       30,
+		USSDCodeTemplate:   "*180*{phone}#",
+		USSDProcessingType: offer.USSDProcessingExpress,
+	}
+}
+
+// seedCustomer inserts one of the agent's customers and returns its ID
+func seedCustomer(t *testing.T, pool *pgxpool.Pool, agentID int64, phone string) int64 {
+	t.Helper()
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO agent_customers (agent_identity_id, customer_reference, phone_number)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, agentID, "CUST-"+phone, phone).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+	return id
+}
+
+// seedPurchase inserts a successful request and redemption of the offer by the customer at the given time
+func seedPurchase(t *testing.T, pool *pgxpool.Pool, agentID, offerID, customerID int64, reference string, at time.Time) {
+	t.Helper()
+
+	_, err := pool.Exec(context.Background(), `
+		WITH request AS (
+			INSERT INTO offer_requests (
+				request_reference, offer_id, agent_identity_id, customer_id, customer_phone, payment_method, amount_paid, status
+			) VALUES ($1, $2, $3, $4, '254712345678', 'mpesa', 50, 'success')
+			RETURNING id
+		)
+		INSERT INTO offer_redemptions (
+			redemption_reference, offer_id, offer_request_id, agent_identity_id, customer_id, customer_phone,
+			amount, ussd_code_used, status, redemption_time
+		) SELECT $1, $2, id, $3, $4, '254712345678', 50, '*180*254712345678#', 'success', $5 FROM request
+	`, reference, offerID, agentID, customerID, at)
+	if err != nil {
+		t.Fatalf("failed to seed purchase: %v", err)
+	}
+}
+
+func TestFeaturedLimitRejectsExtraFeaturedOffers(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "featured@example.com")
+
+	limit := 1
+	if err := svc.configService.SetBusinessConfig(ctx, agentID, &config.BusinessConfig{MaxFeaturedOffers: &limit}); err != nil {
+		t.Fatalf("SetBusinessConfig: %v", err)
+	}
+
+	first := testOfferRequest(1)
+	first.IsFeatured = true
+	if _, err := svc.CreateOffer(ctx, agentID, first); err != nil {
+		t.Fatalf("CreateOffer within the limit: %v", err)
+	}
+
+	second := testOfferRequest(2)
+	second.IsFeatured = true
+	_, err := svc.CreateOffer(ctx, agentID, second)
+	if err == nil || !strings.Contains(err.Error(), "1 of 1") {
+		t.Fatalf("CreateOffer past the limit error = %v, want it to report 1 of 1 featured offers in use", err)
+	}
+
+	// Featuring an existing offer is held to the same limit
+	plain, err := svc.CreateOffer(ctx, agentID, testOfferRequest(3))
+	if err != nil {
+		t.Fatalf("CreateOffer unfeatured: %v", err)
+	}
+	featured := true
+	if _, err := svc.UpdateOffer(ctx, agentID, plain.ID, &offer.UpdateOfferRequest{IsFeatured: &featured}); err == nil {
+		t.Error("UpdateOffer featured an offer past the limit")
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM agent_offers WHERE agent_identity_id = $1 AND is_featured`, agentID).Scan(&count); err != nil {
+		t.Fatalf("failed to count featured offers: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d offers featured, want 1", count)
+	}
+}
+
+func TestAdminTransferOfferHandsOverManagement(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	fromID := testutil.Identity(t, pool, "from@example.com")
+	toID := testutil.Identity(t, pool, "to@example.com")
+	testutil.Role(t, pool, fromID, "user")
+	testutil.Role(t, pool, toID, "user")
+	offerID := testutil.Offer(t, pool, fromID, "DATA-1GB", 50)
+
+	transferred, err := svc.AdminTransferOffer(ctx, offerID, toID)
+	if err != nil {
+		t.Fatalf("AdminTransferOffer: %v", err)
+	}
+	if transferred.AgentIdentityID != toID {
+		t.Fatalf("offer owner = %d, want %d", transferred.AgentIdentityID, toID)
+	}
+
+	// The new owner can read and edit the offer; the previous owner no longer can
+	if _, err := svc.GetOffer(ctx, toID, offerID); err != nil {
+		t.Errorf("GetOffer as new owner: %v", err)
+	}
+	name := "Transferred 1GB"
+	updated, err := svc.UpdateOffer(ctx, toID, offerID, &offer.UpdateOfferRequest{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateOffer as new owner: %v", err)
+	}
+	if updated.Name != name {
+		t.Errorf("name = %q, want %q", updated.Name, name)
+	}
+	if _, err := svc.GetOffer(ctx, fromID, offerID); !errors.Is(err, xerrors.ErrUnauthorized) {
+		t.Errorf("GetOffer as previous owner error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestAdminTransferOfferNotFound(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "agent@example.com")
+	testutil.Role(t, pool, agentID, "user")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	_, err := svc.AdminTransferOffer(ctx, offerID+1000, agentID)
+	if !errors.Is(err, ErrOfferNotFound) || errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("missing offer error = %v, want ErrOfferNotFound", err)
+	}
+
+	_, err = svc.AdminTransferOffer(ctx, offerID, agentID+1000)
+	if !errors.Is(err, ErrAgentNotFound) || errors.Is(err, ErrOfferNotFound) {
+		t.Errorf("missing agent error = %v, want ErrAgentNotFound", err)
+	}
+	if !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("missing agent error = %v, want it to wrap ErrNotFound", err)
+	}
+}
+
+func TestValidateDiscountFloor(t *testing.T) {
+	svc := &OfferService{logger: zap.NewNop()}
+
+	tests := []struct {
+		name         string
+		discount     float64
+		metadata     map[string]interface{}
+		wantErr      bool
+		wantWarnings int
+	}{
+		{"no floor", 20, nil, false, 0},
+		{"above min_price", 20, map[string]interface{}{"min_price": 70.0}, false, 0},
+		{"at min_price", 30, map[string]interface{}{"min_price": 70.0}, false, 0},
+		{"below min_price", 40, map[string]interface{}{"min_price": 70.0}, true, 0},
+		{"below cost_price given as a string", 40, map[string]interface{}{"cost_price": "65"}, true, 0},
+		{"higher of the two floors applies", 25, map[string]interface{}{"min_price": 50.0, "cost_price": 80.0}, true, 0},
+		{"full discount", 100, nil, true, 0},
+		{"large discount warns", 60, nil, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &offer.AgentOffer{Price: 100, Currency: "KES", DiscountPercentage: tt.discount, Metadata: tt.metadata}
+			warnings, err := svc.validateDiscountFloor(o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateDiscountFloor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestCreateOfferRejectsDiscountBelowFloor(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "floor@example.com")
+
+	req := testOfferRequest(1)
+	req.DiscountPercentage = 50
+	req.Metadata = map[string]interface{}{"min_price": 60}
+	if _, err := svc.CreateOffer(ctx, agentID, req); err == nil || !strings.Contains(err.Error(), "below the minimum allowed price") {
+		t.Fatalf("CreateOffer below the floor error = %v, want the floor to be enforced", err)
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM agent_offers WHERE agent_identity_id = $1`, agentID).Scan(&count); err != nil {
+		t.Fatalf("failed to count offers: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d offers stored, want 0", count)
+	}
+}
+
+func TestCheckAvailabilityBatchReportsEachOffer(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "availability@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	available := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	expired := testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+	disabled := testutil.Offer(t, pool, agentID, "DATA-5GB", 200)
+	foreign := testutil.Offer(t, pool, otherID, "OTHER-1GB", 50)
+
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET available_until = NOW() - INTERVAL '1 day' WHERE id = $1`, expired); err != nil {
+		t.Fatalf("failed to expire offer: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET status = 'inactive' WHERE id = $1`, disabled); err != nil {
+		t.Fatalf("failed to disable offer: %v", err)
+	}
+
+	results, err := svc.CheckAvailabilityBatch(ctx, agentID, []int64{available, expired, disabled, foreign})
+	if err != nil {
+		t.Fatalf("CheckAvailabilityBatch: %v", err)
+	}
+
+	want := map[int64]struct {
+		available bool
+		reason    string
+	}{
+		available: {true, ""},
+		expired:   {false, "expired"},
+		disabled:  {false, "inactive"},
+		foreign:   {false, "not_found"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for id, w := range want {
+		got := results[id]
+		if got.OfferID != id || got.IsAvailable != w.available || got.Reason != w.reason {
+			t.Errorf("offer %d = available %v (%q), want %v (%q)", id, got.IsAvailable, got.Reason, w.available, w.reason)
+		}
+	}
+	// Another agent's offer reveals nothing about it
+	if results[foreign].OfferCode != "" {
+		t.Errorf("foreign offer leaked code %q", results[foreign].OfferCode)
+	}
+}
+
+func TestRenameTagAcrossOffers(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "tags@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	tagged := func(agentID int64, code string, tags []string) int64 {
+		t.Helper()
+		id := testutil.Offer(t, pool, agentID, code, 50)
+		if _, err := pool.Exec(ctx, `UPDATE agent_offers SET tags = $2 WHERE id = $1`, id, tags); err != nil {
+			t.Fatalf("failed to tag offer: %v", err)
+		}
+		return id
+	}
+	first := tagged(agentID, "DATA-1GB", []string{"promo", "weekend"})
+	second := tagged(agentID, "DATA-2GB", []string{"promo"})
+	both := tagged(agentID, "DATA-5GB", []string{"promo", "sale"})
+	untouched := tagged(agentID, "DATA-10GB", []string{"weekend"})
+	foreign := tagged(otherID, "OTHER-1GB", []string{"promo"})
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO agent_customers (agent_identity_id, customer_reference, phone_number, tags)
+		VALUES ($1, 'CUST-1', '254700000001', ARRAY['promo'])
+	`, agentID); err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+
+	result, err := svc.RenameTag(ctx, agentID, " promo ", "sale")
+	if err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	if result.OffersUpdated != 3 || result.CustomersUpdated != 1 {
+		t.Errorf("updated %d offers and %d customers, want 3 and 1", result.OffersUpdated, result.CustomersUpdated)
+	}
+
+	want := map[int64][]string{
+		first:     {"sale", "weekend"},
+		second:    {"sale"},
+		both:      {"sale"}, // already had the new tag, so it isn't doubled
+		untouched: {"weekend"},
+		foreign:   {"promo"},
+	}
+	for id, tags := range want {
+		var got []string
+		if err := pool.QueryRow(ctx, `SELECT tags FROM agent_offers WHERE id = $1`, id).Scan(&got); err != nil {
+			t.Fatalf("failed to read tags: %v", err)
+		}
+		if !reflect.DeepEqual(got, tags) {
+			t.Errorf("offer %d tags = %v, want %v", id, got, tags)
+		}
+	}
+
+	if _, err := svc.RenameTag(ctx, agentID, "sale", "sale"); err == nil {
+		t.Error("RenameTag accepted identical tags")
+	}
+}
+
+func TestQRBatchZipHasOneEntryPerOffer(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "qr@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	ids := []int64{
+		testutil.Offer(t, pool, agentID, "DATA-1GB", 50),
+		testutil.Offer(t, pool, agentID, "DATA-2GB", 90),
+		testutil.Offer(t, pool, agentID, "DATA-5GB", 200),
+	}
+
+	// A repeated ID is rendered once
+	images, err := svc.GenerateQRBatch(ctx, agentID, append(ids, ids[0]))
+	if err != nil {
+		t.Fatalf("GenerateQRBatch: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteQRZip(&buf, images); err != nil {
+		t.Fatalf("WriteQRZip: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+
+	names := []string{}
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		if !bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")) {
+			t.Errorf("%s is not a PNG", f.Name)
+		}
+	}
+	if want := []string{"DATA-1GB.png", "DATA-2GB.png", "DATA-5GB.png"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("archive entries = %v, want %v", names, want)
+	}
+
+	// Another agent's offer fails the whole batch
+	foreign := testutil.Offer(t, pool, otherID, "OTHER-1GB", 50)
+	if _, err := svc.GenerateQRBatch(ctx, agentID, []int64{ids[0], foreign}); err == nil {
+		t.Error("GenerateQRBatch rendered another agent's offer")
+	}
+}
+
+func TestValidateOfferAmount(t *testing.T) {
+	svc := &OfferService{minAmounts: offer.MinimumAmounts{DataMB: 100, SMS: 10, VoiceMinutes: 5, ComboUnits: 1}}
+
+	tests := []struct {
+		name      string
+		offerType offer.OfferType
+		units     offer.OfferUnits
+		amount    float64
+		wantErr   bool
+	}{
+		{"zero", offer.OfferTypeData, offer.UnitsGB, 0, true},
+		{"negative", offer.OfferTypeSMS, offer.UnitsSMS, -5, true},
+		{"data in GB is converted", offer.OfferTypeData, offer.UnitsGB, 0.5, false},
+		{"data in MB below minimum", offer.OfferTypeData, offer.UnitsMB, 50, true},
+		{"data in KB below minimum", offer.OfferTypeData, offer.UnitsKB, 1024, true},
+		{"sms at minimum", offer.OfferTypeSMS, offer.UnitsSMS, 10, false},
+		{"voice below minimum", offer.OfferTypeVoice, offer.UnitsMinutes, 3, true},
+		{"combo", offer.OfferTypeCombo, offer.UnitsUnits, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.validateOfferAmount(tt.offerType, tt.units, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOfferAmount(%s, %g %s) error = %v, wantErr %v", tt.offerType, tt.amount, tt.units, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateOfferRejectsZeroAmount(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "amounts@example.com")
+
+	zero := testOfferRequest(0)
+	zero.Price = 100
+	if _, err := svc.CreateOffer(ctx, agentID, zero); err == nil || !strings.Contains(err.Error(), "amount must be greater than zero") {
+		t.Errorf("CreateOffer with a zero amount error = %v, want it rejected", err)
+	}
+
+	created, err := svc.CreateOffer(ctx, agentID, testOfferRequest(1))
+	if err != nil {
+		t.Fatalf("CreateOffer with a valid amount: %v", err)
+	}
+	if created.Amount != 1 || created.Units != offer.UnitsGB {
+		t.Errorf("created %g %s, want 1 GB", created.Amount, created.Units)
+	}
+}
+
+func TestCreateOfferDefaultsBlankCurrencyToAgentDisplayCurrency(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "currency@example.com")
+	unsetID := testutil.Identity(t, pool, "unset@example.com")
+
+	if err := svc.configService.SetDisplayConfig(ctx, agentID, &config.DisplayConfig{
+		Theme: "light", Language: "en", Timezone: "Africa/Kampala", DateFormat: "DD/MM/YYYY", Currency: "UGX",
+	}); err != nil {
+		t.Fatalf("SetDisplayConfig: %v", err)
+	}
+
+	blank := testOfferRequest(1)
+	blank.Currency = ""
+	created, err := svc.CreateOffer(ctx, agentID, blank)
+	if err != nil {
+		t.Fatalf("CreateOffer: %v", err)
+	}
+	if created.Currency != "UGX" {
+		t.Errorf("blank currency stored as %q, want the agent's UGX", created.Currency)
+	}
+
+	// A supplied currency wins over the display currency
+	created, err = svc.CreateOffer(ctx, agentID, testOfferRequest(2))
+	if err != nil {
+		t.Fatalf("CreateOffer: %v", err)
+	}
+	if created.Currency != "KES" {
+		t.Errorf("supplied currency stored as %q, want KES", created.Currency)
+	}
+
+	// An agent without a display config gets KES
+	blank = testOfferRequest(1)
+	blank.Currency = " "
+	created, err = svc.CreateOffer(ctx, unsetID, blank)
+	if err != nil {
+		t.Fatalf("CreateOffer: %v", err)
+	}
+	if created.Currency != "KES" {
+		t.Errorf("blank currency without a display config stored as %q, want KES", created.Currency)
+	}
+}
+
+func TestValidateValidityDays(t *testing.T) {
+	svc := &OfferService{maxValidityDays: offer.DefaultMaxValidityDays}
+
+	tests := []struct {
+		name    string
+		days    int
+		wantErr bool
+	}{
+		{"negative", -7, true},
+		{"zero", 0, true},
+		{"one day", 1, false},
+		{"at the maximum", offer.DefaultMaxValidityDays, false},
+		{"past the maximum", offer.DefaultMaxValidityDays + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.validateValidityDays(tt.days)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateValidityDays(%d) error = %v, wantErr %v", tt.days, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, xerrors.ErrInvalidInput) {
+				t.Errorf("validateValidityDays(%d) error = %v, want ErrInvalidInput", tt.days, err)
+			}
+		})
+	}
+
+	// A configured maximum replaces the default; nonsense values keep it
+	svc.SetMaxValidityDays(30)
+	if err := svc.validateValidityDays(31); err == nil {
+		t.Error("validateValidityDays(31) with a 30-day maximum succeeded, want it rejected")
+	}
+	svc.SetMaxValidityDays(0)
+	if svc.maxValidityDays != offer.DefaultMaxValidityDays {
+		t.Errorf("maximum after SetMaxValidityDays(0) = %d, want the default %d", svc.maxValidityDays, offer.DefaultMaxValidityDays)
+	}
+}
+
+func TestActivateOfferRequiresActiveUSSDCode(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "activate@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET status = 'inactive' WHERE id = $1`, offerID); err != nil {
+		t.Fatalf("failed to deactivate offer: %v", err)
+	}
+	addCode := func(code string, active bool) {
+		t.Helper()
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO offer_ussd_codes (offer_id, ussd_code, is_active) VALUES ($1, $2, $3)
+		`, offerID, code, active); err != nil {
+			t.Fatalf("failed to add USSD code: %v", err)
+		}
+	}
+	status := func() string {
+		t.Helper()
+		var s string
+		if err := pool.QueryRow(ctx, `SELECT status::text FROM agent_offers WHERE id = $1`, offerID).Scan(&s); err != nil {
+			t.Fatalf("failed to read status: %v", err)
+		}
+		return s
+	}
+
+	// Only a disabled code on file
+	addCode("*180*1*{phone}#", false)
+	if err := svc.ActivateOffer(ctx, agentID, offerID); !errors.Is(err, xerrors.ErrInvalidInput) {
+		t.Fatalf("ActivateOffer without an active code error = %v, want ErrInvalidInput", err)
+	}
+	if s := status(); s != "inactive" {
+		t.Errorf("status after blocked activation = %s, want inactive", s)
+	}
+
+	addCode("*180*2*{phone}#", true)
+	if err := svc.ActivateOffer(ctx, agentID, offerID); err != nil {
+		t.Fatalf("ActivateOffer with an active code: %v", err)
+	}
+	if s := status(); s != "active" {
+		t.Errorf("status = %s, want active", s)
+	}
+}
+
+func TestDailyPurchaseLimitResetsNextDay(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "daily@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	if _, err := pool.Exec(ctx, `
+		UPDATE agent_offers SET max_purchases_per_customer = 1, purchase_limit_period = 'daily' WHERE id = $1
+	`, offerID); err != nil {
+		t.Fatalf("failed to set purchase limit: %v", err)
+	}
+	customerID := seedCustomer(t, pool, agentID, "254712345678")
+	purchase := func(reference string, at time.Time) {
+		t.Helper()
+		seedPurchase(t, pool, agentID, offerID, customerID, reference, at)
+	}
+
+	// Yesterday's purchase used up yesterday's allowance only
+	today := offer.PurchaseLimitDaily.WindowStart(time.Now())
+	purchase("REQ-DAILY-1", today.Add(-time.Hour))
+
+	o, err := svc.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); err != nil {
+		t.Fatalf("ValidateOfferPurchase the next day: %v", err)
+	}
+
+	purchase("REQ-DAILY-2", time.Now())
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); !errors.Is(err, xerrors.ErrRateLimited) {
+		t.Errorf("second purchase the same day error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestBulkDeleteSkipsForeignOffers(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "bulkdelete@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	first := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	second := testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+	foreign := testutil.Offer(t, pool, otherID, "OTHER-1GB", 50)
+
+	result, err := svc.BulkDelete(ctx, agentID, []int64{first, foreign, second})
+	if err != nil {
+		t.Fatalf("BulkDelete: %v", err)
+	}
+	sort.Slice(result.DeletedIDs, func(i, j int) bool { return result.DeletedIDs[i] < result.DeletedIDs[j] })
+	if result.Deleted != 2 || !reflect.DeepEqual(result.DeletedIDs, []int64{first, second}) {
+		t.Errorf("deleted %d: %v, want %v", result.Deleted, result.DeletedIDs, []int64{first, second})
+	}
+	if !reflect.DeepEqual(result.SkippedIDs, []int64{foreign}) {
+		t.Errorf("skipped %v, want the other agent's offer %d", result.SkippedIDs, foreign)
+	}
+
+	var remaining int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM agent_offers WHERE id = $1 AND deleted_at IS NULL`, foreign).Scan(&remaining); err != nil {
+		t.Fatalf("failed to read foreign offer: %v", err)
+	}
+	if remaining != 1 {
+		t.Error("the other agent's offer was deleted")
+	}
+}
+
+func TestUSSDCodeTestClassifiesResponse(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "ussdtest@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	var codeID int64
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO offer_ussd_codes (offer_id, ussd_code, expected_response, error_pattern)
+		VALUES ($1, '*544*1#', 'successfully purchased', 'insufficient')
+		RETURNING id
+	`, offerID).Scan(&codeID); err != nil {
+		t.Fatalf("failed to add USSD code: %v", err)
+	}
+
+	run, err := svc.TestUSSDCode(ctx, agentID, offerID, codeID, "254712345678", "You have Successfully purchased 1GB")
+	if err != nil {
+		t.Fatalf("TestUSSDCode: %v", err)
+	}
+	if run.Result != offer.USSDTestResultSuccess || run.MatchedPattern.String != "successfully purchased" {
+		t.Errorf("run = %s matching %q, want success matching the expected response", run.Result, run.MatchedPattern.String)
+	}
+	if run.ID == 0 {
+		t.Error("test run was not stored")
+	}
+
+	// An error match wins and the code's own stats are left alone
+	run, err = svc.TestUSSDCode(ctx, agentID, offerID, codeID, "254712345678", "Insufficient balance")
+	if err != nil {
+		t.Fatalf("TestUSSDCode: %v", err)
+	}
+	if run.Result != offer.USSDTestResultFailed {
+		t.Errorf("error response classified as %s, want failed", run.Result)
+	}
+	var successes, failures int
+	if err := pool.QueryRow(ctx, `
+		SELECT success_count, failure_count FROM offer_ussd_codes WHERE id = $1
+	`, codeID).Scan(&successes, &failures); err != nil {
+		t.Fatalf("failed to read code stats: %v", err)
+	}
+	if successes != 0 || failures != 0 {
+		t.Errorf("code stats = %d successes, %d failures after test runs; want 0 and 0", successes, failures)
+	}
+}
+
+func TestGetSearchFacetsCountsTypes(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "facets@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	seed := func(agentID int64, code string, offerType offer.OfferType, price float64) {
+		t.Helper()
+		id := testutil.Offer(t, pool, agentID, code, price)
+		if _, err := pool.Exec(ctx, `UPDATE agent_offers SET type = $2 WHERE id = $1`, id, string(offerType)); err != nil {
+			t.Fatalf("failed to set offer type: %v", err)
+		}
+	}
+	seed(agentID, "DATA-1GB", offer.OfferTypeData, 50)
+	seed(agentID, "DATA-2GB", offer.OfferTypeData, 90)
+	seed(agentID, "DATA-10GB", offer.OfferTypeData, 1000)
+	seed(agentID, "SMS-100", offer.OfferTypeSMS, 10)
+	seed(agentID, "VOICE-50", offer.OfferTypeVoice, 30)
+	seed(otherID, "OTHER-SMS", offer.OfferTypeSMS, 10)
+
+	facets, err := svc.GetSearchFacets(ctx, agentID, &offer.OfferListFilters{})
+	if err != nil {
+		t.Fatalf("GetSearchFacets: %v", err)
+	}
+	if facets.Total != 5 {
+		t.Errorf("total = %d, want 5", facets.Total)
+	}
+	wantTypes := map[offer.OfferType]int64{offer.OfferTypeData: 3, offer.OfferTypeSMS: 1, offer.OfferTypeVoice: 1}
+	if !reflect.DeepEqual(facets.Types, wantTypes) {
+		t.Errorf("types = %v, want %v", facets.Types, wantTypes)
+	}
+	if facets.Statuses[offer.OfferStatusActive] != 5 {
+		t.Errorf("statuses = %v, want 5 active", facets.Statuses)
+	}
+	var buckets []int64
+	for _, b := range facets.PriceBuckets {
+		buckets = append(buckets, b.Count)
+	}
+	if want := []int64{2, 2, 0, 0, 0, 1}; !reflect.DeepEqual(buckets, want) {
+		t.Errorf("price buckets = %v, want %v", buckets, want)
+	}
+
+	// Filters narrow every facet
+	dataType := offer.OfferTypeData
+	facets, err = svc.GetSearchFacets(ctx, agentID, &offer.OfferListFilters{Type: &dataType})
+	if err != nil {
+		t.Fatalf("GetSearchFacets: %v", err)
+	}
+	if facets.Total != 3 || len(facets.Types) != 1 || facets.Types[offer.OfferTypeData] != 3 {
+		t.Errorf("data-only facets = %d total, types %v; want 3 data offers", facets.Total, facets.Types)
+	}
+}
+
+func TestBulkAdjustPriceRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "bulkprice@example.com")
+	smallID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	largeID := testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+	smsID := testutil.Offer(t, pool, agentID, "SMS-100", 10)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET type = 'sms' WHERE id = $1`, smsID); err != nil {
+		t.Fatalf("failed to set offer type: %v", err)
+	}
+
+	dataType := offer.OfferTypeData
+	result, err := svc.BulkAdjustPrice(ctx, agentID, &offer.BulkPriceFilter{Type: &dataType}, offer.PriceAdjustment{
+		Type:  offer.PriceAdjustmentPercentage,
+		Value: 10,
+	})
+	if err != nil {
+		t.Fatalf("BulkAdjustPrice: %v", err)
+	}
+	if result.Adjusted != 2 {
+		t.Errorf("adjusted %d offers, want 2", result.Adjusted)
+	}
+
+	for id, want := range map[int64]float64{smallID: 55, largeID: 99} {
+		history, err := svc.GetPriceHistory(ctx, agentID, id)
+		if err != nil {
+			t.Fatalf("GetPriceHistory: %v", err)
+		}
+		if len(history) != 1 {
+			t.Fatalf("offer %d has %d price changes, want 1", id, len(history))
+		}
+		if h := history[0]; h.NewPrice != want || h.Source != offer.PriceChangeBulkAdjust {
+			t.Errorf("offer %d change = %.2f -> %.2f (%s), want -> %.2f from a bulk adjustment", id, h.OldPrice, h.NewPrice, h.Source, want)
+		}
+	}
+
+	// The SMS offer did not match the filter
+	history, err := svc.GetPriceHistory(ctx, agentID, smsID)
+	if err != nil {
+		t.Fatalf("GetPriceHistory: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("unmatched offer has %d price changes, want none", len(history))
+	}
+}
+
+func TestPurchaseCooldownRejectsThenAllows(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "cooldown@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET purchase_cooldown_seconds = 600 WHERE id = $1`, offerID); err != nil {
+		t.Fatalf("failed to set cooldown: %v", err)
+	}
+	customerID := seedCustomer(t, pool, agentID, "254712345678")
+	o, err := svc.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); err != nil {
+		t.Fatalf("first purchase: %v", err)
+	}
+
+	seedPurchase(t, pool, agentID, offerID, customerID, "REQ-COOLDOWN-1", time.Now().Add(-5*time.Minute))
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); !errors.Is(err, xerrors.ErrRateLimited) {
+		t.Errorf("purchase within the cooldown error = %v, want ErrRateLimited", err)
+	}
+
+	// Once the cooldown has passed the customer can buy again
+	if _, err := pool.Exec(ctx, `
+		UPDATE offer_redemptions SET redemption_time = $2 WHERE redemption_reference = $1
+	`, "REQ-COOLDOWN-1", time.Now().Add(-11*time.Minute)); err != nil {
+		t.Fatalf("failed to age purchase: %v", err)
+	}
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); err != nil {
+		t.Errorf("purchase after the cooldown: %v", err)
+	}
+}
+
+func TestBulkAddTagIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "bulktag@example.com")
+	ids := []int64{
+		testutil.Offer(t, pool, agentID, "DATA-1GB", 50),
+		testutil.Offer(t, pool, agentID, "DATA-2GB", 90),
+		testutil.Offer(t, pool, agentID, "DATA-5GB", 200),
+	}
+
+	result, err := svc.BulkAddTag(ctx, agentID, ids, " weekend ")
+	if err != nil {
+		t.Fatalf("BulkAddTag: %v", err)
+	}
+	sort.Slice(result.UpdatedIDs, func(i, j int) bool { return result.UpdatedIDs[i] < result.UpdatedIDs[j] })
+	if result.Tag != "weekend" || !reflect.DeepEqual(result.UpdatedIDs, ids) {
+		t.Errorf("result = %q on %v, want weekend on %v", result.Tag, result.UpdatedIDs, ids)
+	}
+
+	// Tagging again changes nothing and doesn't duplicate the tag
+	result, err = svc.BulkAddTag(ctx, agentID, ids, "weekend")
+	if err != nil {
+		t.Fatalf("BulkAddTag again: %v", err)
+	}
+	if result.Updated != 0 {
+		t.Errorf("re-tagging updated %d offers, want 0", result.Updated)
+	}
+	for _, id := range ids {
+		var tags []string
+		if err := pool.QueryRow(ctx, `SELECT tags FROM agent_offers WHERE id = $1`, id).Scan(&tags); err != nil {
+			t.Fatalf("failed to read tags: %v", err)
+		}
+		if !reflect.DeepEqual(tags, []string{"weekend"}) {
+			t.Errorf("offer %d tags = %v, want [weekend]", id, tags)
+		}
+	}
+}
+
+func TestCreateOfferBlockedPastPlanCap(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "capped@example.com")
+
+	// No subscription, no cap
+	if _, err := svc.CreateOffer(ctx, agentID, testOfferRequest(1)); err != nil {
+		t.Fatalf("CreateOffer without a subscription: %v", err)
+	}
+
+	now := time.Now()
+	if _, err := pool.Exec(ctx, `
+		WITH plan AS (
+			INSERT INTO subscription_plans (plan_code, name, price, currency, billing_usage, billing_cycle, max_offers)
+			VALUES ('starter', 'Starter', 500, 'KES', 100, 'monthly', 2)
+			RETURNING id, price, currency
+		)
+		INSERT INTO agent_subscriptions (
+			subscription_reference, agent_identity_id, subscription_plan_id,
+			start_date, current_period_start, current_period_end, next_billing_date,
+			requests_limit, plan_price, amount_paid, currency
+		)
+		SELECT 'SUB-CAPPED', $1, id, $2, $2, $3, $3, 100, price, price, currency FROM plan
+	`, agentID, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0)); err != nil {
+		t.Fatalf("failed to seed subscription: %v", err)
+	}
+
+	if _, err := svc.CreateOffer(ctx, agentID, testOfferRequest(2)); err != nil {
+		t.Fatalf("CreateOffer within the cap: %v", err)
+	}
+	_, err := svc.CreateOffer(ctx, agentID, testOfferRequest(3))
+	if !errors.Is(err, xerrors.ErrForbidden) || !strings.Contains(err.Error(), "2 of 2") {
+		t.Errorf("CreateOffer past the cap error = %v, want ErrForbidden reporting 2 of 2", err)
+	}
+}
+
+func TestOfferUnavailableWhileRequiredDeviceOffline(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "device@example.com")
+
+	req := testOfferRequest(50)
+	req.RequiredDeviceID = "pixel-7"
+	o, err := svc.CreateOffer(ctx, agentID, req)
+	if err != nil {
+		t.Fatalf("CreateOffer: %v", err)
+	}
+
+	if svc.IsOfferAvailable(ctx, o) {
+		t.Error("offer available before its device sent a heartbeat, want unavailable")
+	}
+
+	if err := svc.configService.RecordDeviceHeartbeat(ctx, agentID, "pixel-7"); err != nil {
+		t.Fatalf("RecordDeviceHeartbeat: %v", err)
+	}
+	if !svc.IsOfferAvailable(ctx, o) {
+		t.Error("offer unavailable after its device sent a heartbeat, want available")
+	}
+
+	// Another agent's device of the same name does not count
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	other := *o
+	other.AgentIdentityID = otherID
+	if svc.IsOfferAvailable(ctx, &other) {
+		t.Error("offer available on another agent's heartbeat, want unavailable")
+	}
+}
+
+func TestOfferStartingNextWeekIsAvailableThenButNotNow(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "preview@example.com")
+
+	now := time.Now()
+	alwaysID := testutil.Offer(t, pool, agentID, "DATA-ALWAYS", 50)
+	laterID := testutil.Offer(t, pool, agentID, "DATA-NEXT-WEEK", 60)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET available_from = $2 WHERE id = $1`, laterID, now.AddDate(0, 0, 7)); err != nil {
+		t.Fatalf("failed to set availability window: %v", err)
+	}
+
+	ids := func(at time.Time) []int64 {
+		t.Helper()
+		offers, err := svc.GetAvailableAt(ctx, agentID, at)
+		if err != nil {
+			t.Fatalf("GetAvailableAt: %v", err)
+		}
+		ids := make([]int64, len(offers))
+		for i := range offers {
+			ids[i] = offers[i].ID
+		}
+		return ids
+	}
+
+	if got := ids(now); !reflect.DeepEqual(got, []int64{alwaysID}) {
+		t.Errorf("offers available now = %v, want only %d", got, alwaysID)
+	}
+	if got := ids(now.AddDate(0, 0, 8)); !reflect.DeepEqual(got, []int64{alwaysID, laterID}) {
+		t.Errorf("offers available next week = %v, want %d and %d", got, alwaysID, laterID)
+	}
+}
+
+func TestVerifiedCustomerCanPurchaseWhenVerificationRequired(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	customers := customersvc.NewCustomerService(
+		postgres.NewAgentCustomerRepository(pool), nil, postgres.NewSMSOutboxRepository(pool), nil, zap.NewNop(),
+	)
+
+	agentID := testutil.Identity(t, pool, "verify@example.com")
+	customerID := seedCustomer(t, pool, agentID, "254712345678")
+	o, err := svc.GetOffer(ctx, agentID, testutil.Offer(t, pool, agentID, "DATA-1GB", 50))
+	if err != nil {
+		t.Fatalf("GetOffer: %v", err)
+	}
+
+	// Purchases go through until the agent requires verification
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); err != nil {
+		t.Fatalf("ValidateOfferPurchase without the requirement: %v", err)
+	}
+	if err := svc.configService.SetBusinessConfig(ctx, agentID, &config.BusinessConfig{RequireCustomerVerification: true}); err != nil {
+		t.Fatalf("SetBusinessConfig: %v", err)
+	}
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); !errors.Is(err, xerrors.ErrForbidden) {
+		t.Fatalf("unverified ValidateOfferPurchase error = %v, want ErrForbidden", err)
+	}
+
+	if _, err := customers.SendVerificationCode(ctx, agentID, customerID); err != nil {
+		t.Fatalf("SendVerificationCode: %v", err)
+	}
+	var body string
+	if err := pool.QueryRow(ctx, `
+		SELECT body FROM sms_outbox WHERE agent_identity_id = $1 AND phone_number = '254712345678'
+	`, agentID).Scan(&body); err != nil {
+		t.Fatalf("failed to read verification SMS: %v", err)
+	}
+	code := regexp.MustCompile(`\d{6}`).FindString(body)
+	if code == "" {
+		t.Fatalf("verification SMS %q carries no code", body)
+	}
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if err := customers.VerifyCustomerCode(ctx, "254712345678", wrong); !errors.Is(err, xerrors.ErrInvalidInput) {
+		t.Errorf("wrong code error = %v, want ErrInvalidInput", err)
+	}
+	if err := customers.VerifyCustomerCode(ctx, "0712345678", code); err != nil {
+		t.Fatalf("VerifyCustomerCode: %v", err)
+	}
+
+	if err := svc.ValidateOfferPurchase(ctx, o, customerID); err != nil {
+		t.Errorf("verified ValidateOfferPurchase: %v", err)
+	}
+}
+
+func TestOfferSummaryProjectionOmitsUSSD(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "summary@example.com")
+
+	created, err := svc.CreateOffer(ctx, agentID, testOfferRequest(1))
+	if err != nil {
+		t.Fatalf("CreateOffer: %v", err)
+	}
+
+	full, err := svc.ListOffers(ctx, agentID, &offer.OfferListFilters{})
+	if err != nil {
+		t.Fatalf("ListOffers: %v", err)
+	}
+	if len(full.Offers) != 1 || full.Offers[0].PrimaryUSSDCode == nil {
+		t.Fatalf("full list = %+v, want the offer with its primary USSD code", full.Offers)
+	}
+
+	summaries, err := svc.ListOfferSummaries(ctx, agentID, &offer.OfferListFilters{})
+	if err != nil {
+		t.Fatalf("ListOfferSummaries: %v", err)
+	}
+	if summaries.Total != 1 || len(summaries.Offers) != 1 {
+		t.Fatalf("summary list = %d of %d, want 1 of 1", len(summaries.Offers), summaries.Total)
+	}
+	if s := summaries.Offers[0]; s.ID != created.ID || s.OfferCode != created.OfferCode || s.Price != created.Price {
+		t.Errorf("summary = %+v, want offer %d (%s) at %.2f", s, created.ID, created.OfferCode, created.Price)
+	}
+
+	body, err := json.Marshal(summaries.Offers[0])
+	if err != nil {
+		t.Fatalf("failed to encode summary: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	for name := range fields {
+		if strings.Contains(name, "ussd") || name == "metadata" {
+			t.Errorf("summary includes %q, want it left out", name)
+		}
+	}
+}
+
+func TestBulkDeactivateRejectsLeavingOfferWithoutActiveCode(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+
+	agentID := testutil.Identity(t, pool, "bulkussd@example.com")
+	twoCodesID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	oneCodeID := testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+	addCode := func(offerID int64, code string) int64 {
+		t.Helper()
+		var id int64
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO offer_ussd_codes (offer_id, ussd_code, is_active) VALUES ($1, $2, TRUE) RETURNING id
+		`, offerID, code).Scan(&id); err != nil {
+			t.Fatalf("failed to add USSD code: %v", err)
+		}
+		return id
+	}
+	active := func(id int64) bool {
+		t.Helper()
+		var isActive bool
+		if err := pool.QueryRow(ctx, `SELECT is_active FROM offer_ussd_codes WHERE id = $1`, id).Scan(&isActive); err != nil {
+			t.Fatalf("failed to read USSD code: %v", err)
+		}
+		return isActive
+	}
+
+	first := addCode(twoCodesID, "*180*1*{phone}#")
+	addCode(twoCodesID, "*180*2*{phone}#")
+	only := addCode(oneCodeID, "*180*3*{phone}#")
+
+	// The whole batch fails when one offer would be left with no active code
+	if _, err := svc.BulkToggleUSSDStatus(ctx, agentID, []int64{first, only}, false); !errors.Is(err, xerrors.ErrConflict) {
+		t.Fatalf("BulkToggleUSSDStatus leaving no active code error = %v, want ErrConflict", err)
+	}
+	if !active(first) || !active(only) {
+		t.Error("a rejected batch changed codes, want it rolled back")
+	}
+
+	resp, err := svc.BulkToggleUSSDStatus(ctx, agentID, []int64{first}, false)
+	if err != nil {
+		t.Fatalf("BulkToggleUSSDStatus: %v", err)
+	}
+	if resp.Updated != 1 || active(first) {
+		t.Errorf("deactivated %d codes (first still active: %v), want 1", resp.Updated, active(first))
+	}
+}
@@ -0,0 +1,242 @@
+// internal/service/offer/pricing.go
+package offer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// metadataKeyComponentPrices holds standalone prices of a combo's components,
+// either as a list of prices or a map of component name to price
+const metadataKeyComponentPrices = "component_prices"
+
+// priceAnomalyFactor is how far an offer's price may stray from the median of the agent's
+// comparable offers, in either direction, before create/update responses carry a warning
+const priceAnomalyFactor = 3.0
+
+// QuoteDiscountedPrice returns the discounted price and the strategy that produced it.
+// Combos with component prices get whichever of the flat and component-wise discount is the better deal.
+func (s *OfferService) QuoteDiscountedPrice(o *offer.AgentOffer) (float64, offer.PricingStrategy) {
+	flatPrice := applyDiscount(o.Price, o.DiscountPercentage)
+	if o.Type != offer.OfferTypeCombo {
+		return flatPrice, offer.PricingStrategyFlat
+	}
+
+	componentTotal, ok := componentPriceTotal(o.Metadata)
+	if !ok {
+		return flatPrice, offer.PricingStrategyFlat
+	}
+
+	componentPrice := applyDiscount(componentTotal, o.DiscountPercentage)
+	if componentPrice < flatPrice {
+		return componentPrice, offer.PricingStrategyComponent
+	}
+
+	return flatPrice, offer.PricingStrategyFlat
+}
+
+// priceAnomalyWarning compares an offer's price with the median price of the agent's other offers
+// of the same type and amount, returning a warning if it is off by more than priceAnomalyFactor.
+// Lookup failures are logged and produce no warning.
+func (s *OfferService) priceAnomalyWarning(ctx context.Context, o *offer.AgentOffer) string {
+	comparable, err := s.offerRepo.FindByTypeAndAmount(ctx, o.AgentIdentityID, o.Type, o.Amount)
+	if err != nil {
+		s.logger.Warn("failed to load comparable offers", zap.Int64("offer_id", o.ID), zap.Error(err))
+		return ""
+	}
+
+	prices := make([]float64, 0, len(comparable))
+	for _, c := range comparable {
+		if c.ID != o.ID && c.Price > 0 {
+			prices = append(prices, c.Price)
+		}
+	}
+	if len(prices) == 0 || o.Price <= 0 {
+		return ""
+	}
+
+	sort.Float64s(prices)
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + prices[len(prices)/2]) / 2
+	}
+
+	if o.Price <= median*priceAnomalyFactor && o.Price*priceAnomalyFactor >= median {
+		return ""
+	}
+
+	s.logger.Warn("offer price deviates from comparable offers",
+		zap.Int64("agent_id", o.AgentIdentityID),
+		zap.Int64("offer_id", o.ID),
+		zap.Float64("price", o.Price),
+		zap.Float64("median_price", median),
+	)
+
+	return fmt.Sprintf(
+		"price %.2f %s is %.1fx the typical %.2f for your %s offers of this amount; set confirm to acknowledge",
+		o.Price, o.Currency, o.Price/median, median, o.Type,
+	)
+}
+
+// applyDiscount takes a percentage off a price
+func applyDiscount(price, discountPercentage float64) float64 {
+	if discountPercentage <= 0 {
+		return price
+	}
+	return price - price*(discountPercentage/100)
+}
+
+// componentPriceTotal sums the component prices in metadata, reporting false if none are usable
+func componentPriceTotal(metadata map[string]interface{}) (float64, bool) {
+	if metadata == nil {
+		return 0, false
+	}
+
+	var values []interface{}
+	switch v := metadata[metadataKeyComponentPrices].(type) {
+	case []interface{}:
+		values = v
+	case map[string]interface{}:
+		for _, price := range v {
+			values = append(values, price)
+		}
+	default:
+		return 0, false
+	}
+
+	total := 0.0
+	for _, value := range values {
+		price, ok := componentPrice(value)
+		if !ok || price < 0 {
+			return 0, false
+		}
+		total += price
+	}
+
+	return total, total > 0
+}
+
+// componentPrice converts a single JSON component price to float64
+func componentPrice(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		price, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false
+		}
+		return price, true
+	}
+	return 0, false
+}
+
+// priceHistoryLimit caps the price changes returned for an offer
+const priceHistoryLimit = 100
+
+// BulkAdjustPrice applies a percentage or fixed price change to every offer matching the filter, recording
+// each change in price history. It is all-or-nothing: if any resulting price is not positive or falls below
+// the offer's price floor, nothing is changed.
+func (s *OfferService) BulkAdjustPrice(ctx context.Context, agentID int64, filter *offer.BulkPriceFilter, adjustment offer.PriceAdjustment) (*offer.BulkAdjustPriceResult, error) {
+	if adjustment.Type == offer.PriceAdjustmentPercentage && adjustment.Value <= -100 {
+		return nil, fmt.Errorf("percentage adjustment must be greater than -100: %w", xerrors.ErrInvalidInput)
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	offers, err := s.offerRepo.FindMatchingForUpdateWithTx(ctx, tx, agentID, filter.ListFilters())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]offer.OfferPriceChange, 0, len(offers))
+	for i := range offers {
+		o := &offers[i]
+		oldPrice := o.Price
+		o.Price = adjustPrice(oldPrice, adjustment)
+
+		if o.Price <= 0 {
+			return nil, fmt.Errorf("offer %s: adjusted price %.2f must be greater than zero: %w", o.OfferCode, o.Price, xerrors.ErrInvalidInput)
+		}
+		if _, err := s.validateDiscountFloor(o); err != nil {
+			return nil, fmt.Errorf("offer %s: %v: %w", o.OfferCode, err, xerrors.ErrInvalidInput)
+		}
+		if o.Price == oldPrice {
+			continue
+		}
+
+		if err := s.offerRepo.UpdatePriceWithTx(ctx, tx, o.ID, o.Price); err != nil {
+			return nil, err
+		}
+
+		change := offer.OfferPriceChange{
+			OfferID:         o.ID,
+			AgentIdentityID: agentID,
+			OldPrice:        oldPrice,
+			NewPrice:        o.Price,
+			Source:          offer.PriceChangeBulkAdjust,
+		}
+		if err := s.offerRepo.CreatePriceHistoryWithTx(ctx, tx, &change); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(changes) > 0 {
+		s.InvalidateOfferCache(ctx, agentID)
+	}
+
+	s.logger.Info("offer prices bulk adjusted",
+		zap.Int64("agent_id", agentID),
+		zap.String("adjustment_type", string(adjustment.Type)),
+		zap.Float64("adjustment_value", adjustment.Value),
+		zap.Int("matched", len(offers)),
+		zap.Int("adjusted", len(changes)),
+	)
+
+	return &offer.BulkAdjustPriceResult{
+		Adjusted: len(changes),
+		Changes:  changes,
+	}, nil
+}
+
+// GetPriceHistory retrieves an offer's recorded price changes, newest first
+func (s *OfferService) GetPriceHistory(ctx context.Context, agentID, offerID int64) ([]offer.OfferPriceChange, error) {
+	o, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if o.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+
+	return s.offerRepo.ListPriceHistory(ctx, offerID, priceHistoryLimit)
+}
+
+// adjustPrice applies an adjustment to a price, rounded to two decimals
+func adjustPrice(price float64, adjustment offer.PriceAdjustment) float64 {
+	adjusted := price + adjustment.Value
+	if adjustment.Type == offer.PriceAdjustmentPercentage {
+		adjusted = price * (1 + adjustment.Value/100)
+	}
+	return math.Round(adjusted*100) / 100
+}
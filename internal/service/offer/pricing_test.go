@@ -0,0 +1,115 @@
+// internal/service/offer/pricing_test.go
+package offer
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/testutil"
+)
+
+func TestQuoteDiscountedPrice(t *testing.T) {
+	svc := &OfferService{}
+
+	tests := []struct {
+		name         string
+		offerType    offer.OfferType
+		price        float64
+		discount     float64
+		metadata     map[string]interface{}
+		wantPrice    float64
+		wantStrategy offer.PricingStrategy
+	}{
+		{"flat discount on a data offer", offer.OfferTypeData, 100, 10, nil, 90, offer.PricingStrategyFlat},
+		{
+			"component pricing beats the flat discount",
+			offer.OfferTypeCombo, 100, 20,
+			map[string]interface{}{"component_prices": []interface{}{40.0, 30.0, "20"}},
+			72, offer.PricingStrategyComponent,
+		},
+		{
+			"components named in a map",
+			offer.OfferTypeCombo, 100, 10,
+			map[string]interface{}{"component_prices": map[string]interface{}{"data": 50.0, "sms": 25.0}},
+			67.5, offer.PricingStrategyComponent,
+		},
+		{
+			"flat wins when components cost more",
+			offer.OfferTypeCombo, 100, 10,
+			map[string]interface{}{"component_prices": []interface{}{80.0, 40.0}},
+			90, offer.PricingStrategyFlat,
+		},
+		{
+			"unusable component prices fall back to flat",
+			offer.OfferTypeCombo, 100, 10,
+			map[string]interface{}{"component_prices": []interface{}{10.0, "free"}},
+			90, offer.PricingStrategyFlat,
+		},
+		{
+			"components ignored for non-combo offers",
+			offer.OfferTypeData, 100, 10,
+			map[string]interface{}{"component_prices": []interface{}{10.0}},
+			90, offer.PricingStrategyFlat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &offer.AgentOffer{Type: tt.offerType, Price: tt.price, DiscountPercentage: tt.discount, Metadata: tt.metadata}
+			price, strategy := svc.QuoteDiscountedPrice(o)
+			if math.Abs(price-tt.wantPrice) > 1e-9 || strategy != tt.wantStrategy {
+				t.Errorf("QuoteDiscountedPrice = %.2f (%s), want %.2f (%s)", price, strategy, tt.wantPrice, tt.wantStrategy)
+			}
+			if got := svc.CalculateDiscountedPrice(o); math.Abs(got-price) > 1e-9 {
+				t.Errorf("CalculateDiscountedPrice = %.2f, want the quoted %.2f", got, price)
+			}
+		})
+	}
+}
+
+func TestCreateOfferFlagsTenTimesTypicalPrice(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "anomaly@example.com")
+
+	// Three 1 GB data offers around 100 set the typical price
+	for _, price := range []float64{90, 100, 110} {
+		req := testOfferRequest(1)
+		req.Price = price
+		if _, err := svc.CreateOffer(ctx, agentID, req); err != nil {
+			t.Fatalf("CreateOffer: %v", err)
+		}
+	}
+
+	typo := testOfferRequest(1)
+	typo.Price = 1000
+	created, err := svc.CreateOffer(ctx, agentID, typo)
+	if err != nil {
+		t.Fatalf("CreateOffer at 10x: %v", err)
+	}
+	if !containsWarning(created.Warnings, "10.0x the typical 100.00") {
+		t.Errorf("warnings = %q, want the 10x price flagged", created.Warnings)
+	}
+
+	// Confirming the price suppresses the warning
+	typo.Confirm = true
+	created, err = svc.CreateOffer(ctx, agentID, typo)
+	if err != nil {
+		t.Fatalf("confirmed CreateOffer: %v", err)
+	}
+	if containsWarning(created.Warnings, "typical") {
+		t.Errorf("confirmed warnings = %q, want no price warning", created.Warnings)
+	}
+}
+
+func containsWarning(warnings []string, substr string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, substr) {
+			return true
+		}
+	}
+	return false
+}
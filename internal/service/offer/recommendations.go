@@ -0,0 +1,143 @@
+// internal/service/offer/recommendations.go
+package offer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"bingwa-service/internal/domain/offer"
+
+	"go.uber.org/zap"
+)
+
+// GetTopOffers returns the agent's most redeemed active offers (cached)
+func (s *OfferService) GetTopOffers(ctx context.Context, agentID int64, limit int) ([]offer.AgentOffer, error) {
+	limit = clampOfferLimit(limit)
+
+	var cached []offer.AgentOffer
+	entry, err := s.offerCache.TopOffers(ctx, agentID, limit)
+	if err != nil {
+		s.logger.Warn("failed to resolve top offers cache entry", zap.Error(err))
+	} else if found, err := entry.Get(ctx, &cached); err != nil {
+		s.logger.Warn("failed to read top offers from cache", zap.Error(err))
+	} else if found {
+		return cached, nil
+	}
+
+	offers, err := s.rankedOffers(ctx, agentID, nil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top offers: %w", err)
+	}
+
+	if entry != nil {
+		if err := entry.Set(ctx, offers); err != nil {
+			s.logger.Warn("failed to cache top offers", zap.Error(err))
+		}
+	}
+
+	return offers, nil
+}
+
+// RecommendForCustomer returns the customer's most purchased offers, topped up with the agent's top offers (cached)
+func (s *OfferService) RecommendForCustomer(ctx context.Context, agentID int64, phone string, limit int) ([]offer.AgentOffer, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return nil, fmt.Errorf("phone is required")
+	}
+	limit = clampOfferLimit(limit)
+
+	var cached []offer.AgentOffer
+	entry, err := s.offerCache.Recommendations(ctx, agentID, phone, limit)
+	if err != nil {
+		s.logger.Warn("failed to resolve recommendations cache entry", zap.Error(err))
+	} else if found, err := entry.Get(ctx, &cached); err != nil {
+		s.logger.Warn("failed to read recommendations from cache", zap.Error(err))
+	} else if found {
+		return cached, nil
+	}
+
+	recommendations := []offer.AgentOffer{}
+	if c, err := s.customerRepo.FindByAgentAndPhone(ctx, agentID, phone); err == nil && c != nil {
+		recommendations, err = s.rankedOffers(ctx, agentID, &c.ID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customer offers: %w", err)
+		}
+	}
+
+	if len(recommendations) < limit {
+		top, err := s.rankedOffers(ctx, agentID, nil, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get top offers: %w", err)
+		}
+
+		seen := make(map[int64]bool, len(recommendations))
+		for _, o := range recommendations {
+			seen[o.ID] = true
+		}
+		for _, o := range top {
+			if len(recommendations) >= limit {
+				break
+			}
+			if !seen[o.ID] {
+				recommendations = append(recommendations, o)
+			}
+		}
+	}
+
+	if entry != nil {
+		if err := entry.Set(ctx, recommendations); err != nil {
+			s.logger.Warn("failed to cache recommendations", zap.Error(err))
+		}
+	}
+
+	return recommendations, nil
+}
+
+// InvalidateOfferCache drops an agent's cached top offers and recommendations (run on new redemptions and offer edits)
+func (s *OfferService) InvalidateOfferCache(ctx context.Context, agentID int64) {
+	if err := s.offerCache.Invalidate(ctx, agentID); err != nil {
+		s.logger.Warn("failed to invalidate offer cache", zap.Int64("agent_id", agentID), zap.Error(err))
+	}
+}
+
+// rankedOffers loads offers ordered by redemption count, optionally for a single customer
+func (s *OfferService) rankedOffers(ctx context.Context, agentID int64, customerID *int64, limit int) ([]offer.AgentOffer, error) {
+	ids, err := s.offerRepo.TopOfferIDs(ctx, agentID, customerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []offer.AgentOffer{}, nil
+	}
+
+	offers, err := s.offerRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]offer.AgentOffer, len(offers))
+	for _, o := range offers {
+		byID[o.ID] = o
+	}
+
+	ranked := make([]offer.AgentOffer, 0, len(ids))
+	for _, id := range ids {
+		if o, ok := byID[id]; ok {
+			ranked = append(ranked, o)
+		}
+	}
+
+	return ranked, nil
+}
+
+// clampOfferLimit keeps list limits within 1..50, defaulting to 10
+func clampOfferLimit(limit int) int {
+	if limit < 1 {
+		return 10
+	}
+	if limit > 50 {
+		return 50
+	}
+	return limit
+}
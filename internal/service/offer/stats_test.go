@@ -0,0 +1,83 @@
+// internal/service/offer/stats_test.go
+package offer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	xerrors "bingwa-service/internal/pkg/errors"
+)
+
+func TestParseStatsPeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		period  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"days", "30d", 30 * 24 * time.Hour, false},
+		{"weeks", "4w", 28 * 24 * time.Hour, false},
+		{"single day", "1d", 24 * time.Hour, false},
+		{"longest allowed", "366d", 366 * 24 * time.Hour, false},
+		{"too long", "367d", 0, true},
+		{"too many weeks", "53w", 0, true},
+		{"zero", "0d", 0, true},
+		{"negative", "-7d", 0, true},
+		{"unknown unit", "3m", 0, true},
+		{"missing number", "d", 0, true},
+		{"empty", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatsPeriod(tt.period)
+			if tt.wantErr {
+				if !errors.Is(err, xerrors.ErrInvalidInput) {
+					t.Fatalf("parseStatsPeriod(%q) error = %v, want ErrInvalidInput", tt.period, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStatsPeriod(%q) unexpected error: %v", tt.period, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseStatsPeriod(%q) = %v, want %v", tt.period, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous float64
+		current  float64
+		want     *float64
+	}{
+		{"increase", 100, 150, ptr(50)},
+		{"decrease", 200, 150, ptr(-25)},
+		{"no change", 80, 80, ptr(0)},
+		{"rounded to two decimals", 3, 4, ptr(33.33)},
+		{"drop to zero", 50, 0, ptr(-100)},
+		{"no previous value", 0, 100, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentChange(tt.previous, tt.current)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("percentChange(%v, %v) = %v, want %v", tt.previous, tt.current, deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func deref(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,76 @@
+// internal/service/offer/stock.go
+package offer
+
+import (
+	"context"
+	"fmt"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// ReplenishStock adds units to a stock-limited offer. When the offer was sold out, the agent
+// selling it is notified so the customers waiting on it can be told it is back.
+func (s *OfferService) ReplenishStock(ctx context.Context, agentID, offerID int64, amount int32) (*offer.StockReplenished, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", xerrors.ErrInvalidInput)
+	}
+
+	o, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if o.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+	if !o.StockLimit.Valid {
+		return nil, fmt.Errorf("%w: offer has unlimited stock", xerrors.ErrConflict)
+	}
+
+	previous, current, err := s.offerRepo.AddStock(ctx, offerID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.InvalidateOfferCache(ctx, agentID)
+
+	s.logger.Info("offer stock replenished",
+		zap.Int64("offer_id", offerID),
+		zap.Int64("agent_id", agentID),
+		zap.Int32("amount", amount),
+		zap.Int32("previous_stock", previous),
+		zap.Int32("stock_limit", current),
+	)
+
+	restocked := backInStock(previous, current)
+	if restocked && s.notifService != nil {
+		title, message := restockNotice(o, current)
+		if err := s.notifService.SendInfoNotification(ctx, agentID, title, message, map[string]interface{}{
+			"offer_id":    offerID,
+			"stock_limit": current,
+		}); err != nil {
+			// The stock is already topped up; a missed notice should not fail the request
+			s.logger.Warn("failed to send back-in-stock notification", zap.Int64("offer_id", offerID), zap.Error(err))
+		}
+	}
+
+	return &offer.StockReplenished{
+		OfferID:       offerID,
+		PreviousStock: previous,
+		StockLimit:    current,
+		BackInStock:   restocked,
+	}, nil
+}
+
+// backInStock reports whether a stock change took a sold-out offer back to having units
+func backInStock(previous, current int32) bool {
+	return previous <= 0 && current > 0
+}
+
+// restockNotice builds the notification sent when a sold-out offer is replenished
+func restockNotice(o *offer.AgentOffer, stock int32) (string, string) {
+	return "Offer back in stock",
+		fmt.Sprintf("%s is back in stock with %d units. Let customers waiting for it know.", o.Name, stock)
+}
@@ -0,0 +1,45 @@
+// internal/service/offer/stock_test.go
+package offer
+
+import (
+	"strings"
+	"testing"
+
+	"bingwa-service/internal/domain/offer"
+)
+
+func TestReplenishNotifiesWatchers(t *testing.T) {
+	o := &offer.AgentOffer{ID: 7, AgentIdentityID: 3, Name: "Daily 1GB"}
+
+	tests := []struct {
+		name     string
+		previous int32
+		amount   int32
+		want     bool
+	}{
+		{"sold out offer replenished", 0, 10, true},
+		{"sold out offer replenished by one", 0, 1, true},
+		{"offer still in stock", 4, 10, false},
+		{"offer with one unit left", 1, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := tt.previous + tt.amount
+			if got := backInStock(tt.previous, current); got != tt.want {
+				t.Fatalf("backInStock(%d, %d) = %v, want %v", tt.previous, current, got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+
+			title, message := restockNotice(o, current)
+			if title == "" {
+				t.Error("notice has no title")
+			}
+			if !strings.Contains(message, o.Name) {
+				t.Errorf("notice %q does not name the offer", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// internal/service/offer/templates.go
+package offer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// defaultTemplateCurrency applies to templates created without a currency
+const defaultTemplateCurrency = "KES"
+
+// ListTemplates retrieves the offer template library; agents only see active templates
+func (s *OfferService) ListTemplates(ctx context.Context, activeOnly bool) ([]offer.OfferTemplate, error) {
+	return s.templateRepo.List(ctx, activeOnly)
+}
+
+// CreateTemplate adds a template to the library
+func (s *OfferService) CreateTemplate(ctx context.Context, req *offer.CreateOfferTemplateRequest) (*offer.OfferTemplate, error) {
+	if err := s.validateOfferTypeAndUnits(req.Type, req.Units); err != nil {
+		return nil, err
+	}
+	if err := s.validateOfferAmount(req.Type, req.Units, req.Amount); err != nil {
+		return nil, err
+	}
+	if err := s.validateValidityDays(req.ValidityDays); err != nil {
+		return nil, err
+	}
+	if err := s.validateUSSDCodeTemplate(req.USSDCodeTemplate); err != nil {
+		return nil, err
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = defaultTemplateCurrency
+	}
+
+	t := &offer.OfferTemplate{
+		Name:                 req.Name,
+		Description:          sql.NullString{String: req.Description, Valid: req.Description != ""},
+		Type:                 req.Type,
+		Amount:               req.Amount,
+		Units:                req.Units,
+		Price:                req.Price,
+		Currency:             currency,
+		ValidityDays:         req.ValidityDays,
+		ValidityLabel:        sql.NullString{String: req.ValidityLabel, Valid: req.ValidityLabel != ""},
+		USSDCodeTemplate:     req.USSDCodeTemplate,
+		USSDProcessingType:   req.USSDProcessingType,
+		USSDExpectedResponse: sql.NullString{String: req.USSDExpectedResponse, Valid: req.USSDExpectedResponse != ""},
+		USSDErrorPattern:     sql.NullString{String: req.USSDErrorPattern, Valid: req.USSDErrorPattern != ""},
+		Tags:                 req.Tags,
+		IsActive:             true,
+	}
+
+	if err := s.templateRepo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("offer template created", zap.Int64("template_id", t.ID), zap.String("name", t.Name))
+
+	return t, nil
+}
+
+// UpdateTemplate changes a library template
+func (s *OfferService) UpdateTemplate(ctx context.Context, templateID int64, req *offer.UpdateOfferTemplateRequest) (*offer.OfferTemplate, error) {
+	t, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		t.Name = *req.Name
+	}
+	if req.Description != nil {
+		t.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+	}
+	if req.Amount != nil {
+		t.Amount = *req.Amount
+	}
+	if req.Units != nil {
+		t.Units = *req.Units
+	}
+	if req.Price != nil {
+		t.Price = *req.Price
+	}
+	if req.ValidityDays != nil {
+		if err := s.validateValidityDays(*req.ValidityDays); err != nil {
+			return nil, err
+		}
+		t.ValidityDays = *req.ValidityDays
+	}
+	if req.ValidityLabel != nil {
+		t.ValidityLabel = sql.NullString{String: *req.ValidityLabel, Valid: *req.ValidityLabel != ""}
+	}
+	if req.USSDCodeTemplate != nil {
+		t.USSDCodeTemplate = *req.USSDCodeTemplate
+	}
+	if req.USSDProcessingType != nil {
+		t.USSDProcessingType = *req.USSDProcessingType
+	}
+	if req.USSDExpectedResponse != nil {
+		t.USSDExpectedResponse = sql.NullString{String: *req.USSDExpectedResponse, Valid: *req.USSDExpectedResponse != ""}
+	}
+	if req.USSDErrorPattern != nil {
+		t.USSDErrorPattern = sql.NullString{String: *req.USSDErrorPattern, Valid: *req.USSDErrorPattern != ""}
+	}
+	if req.Tags != nil {
+		t.Tags = req.Tags
+	}
+	if req.IsActive != nil {
+		t.IsActive = *req.IsActive
+	}
+
+	if err := s.validateOfferTypeAndUnits(t.Type, t.Units); err != nil {
+		return nil, err
+	}
+	if err := s.validateOfferAmount(t.Type, t.Units, t.Amount); err != nil {
+		return nil, err
+	}
+	if err := s.validateUSSDCodeTemplate(t.USSDCodeTemplate); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Update(ctx, t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// DeleteTemplate removes a template from the library
+func (s *OfferService) DeleteTemplate(ctx context.Context, templateID int64) error {
+	return s.templateRepo.Delete(ctx, templateID)
+}
+
+// CreateFromTemplate creates an offer for an agent from a library template, applying any overrides.
+// The offer goes through the same validation and limits as CreateOffer.
+func (s *OfferService) CreateFromTemplate(ctx context.Context, agentID, templateID int64, overrides *offer.OfferTemplateOverrides) (*offer.AgentOffer, error) {
+	t, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsActive {
+		return nil, fmt.Errorf("%w: offer template is not available", xerrors.ErrNotFound)
+	}
+
+	return s.CreateOffer(ctx, agentID, templateOfferRequest(t, overrides))
+}
+
+// templateOfferRequest fills a create request from a template's defaults and applies overrides
+func templateOfferRequest(t *offer.OfferTemplate, overrides *offer.OfferTemplateOverrides) *offer.CreateOfferRequest {
+	req := &offer.CreateOfferRequest{
+		Name:                 t.Name,
+		Description:          t.Description.String,
+		Type:                 t.Type,
+		Amount:               t.Amount,
+		Units:                t.Units,
+		Price:                t.Price,
+		Currency:             t.Currency,
+		ValidityDays:         t.ValidityDays,
+		ValidityLabel:        t.ValidityLabel.String,
+		USSDCodeTemplate:     t.USSDCodeTemplate,
+		USSDProcessingType:   t.USSDProcessingType,
+		USSDExpectedResponse: t.USSDExpectedResponse.String,
+		USSDErrorPattern:     t.USSDErrorPattern.String,
+		Tags:                 t.Tags,
+		Metadata:             map[string]interface{}{"template_id": t.ID},
+	}
+
+	if overrides == nil {
+		return req
+	}
+
+	if overrides.Name != nil {
+		req.Name = *overrides.Name
+	}
+	if overrides.Description != nil {
+		req.Description = *overrides.Description
+	}
+	if overrides.Price != nil {
+		req.Price = *overrides.Price
+	}
+	if overrides.Currency != nil {
+		req.Currency = *overrides.Currency
+	}
+	if overrides.DiscountPercentage != nil {
+		req.DiscountPercentage = *overrides.DiscountPercentage
+	}
+	if overrides.ValidityDays != nil {
+		req.ValidityDays = *overrides.ValidityDays
+		// A label written for the template's validity no longer fits
+		if overrides.ValidityLabel == nil {
+			req.ValidityLabel = ""
+		}
+	}
+	if overrides.ValidityLabel != nil {
+		req.ValidityLabel = *overrides.ValidityLabel
+	}
+	if overrides.USSDCodeTemplate != nil {
+		req.USSDCodeTemplate = *overrides.USSDCodeTemplate
+	}
+	if overrides.IsFeatured != nil {
+		req.IsFeatured = *overrides.IsFeatured
+	}
+	if overrides.Tags != nil {
+		req.Tags = overrides.Tags
+	}
+	req.Confirm = overrides.Confirm
+
+	return req
+}
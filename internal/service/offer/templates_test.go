@@ -0,0 +1,62 @@
+// internal/service/offer/templates_test.go
+package offer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bingwa-service/internal/domain/offer"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/testutil"
+)
+
+func TestCreateFromDataOfferTemplate(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestOfferService(t)
+	agentID := testutil.Identity(t, pool, "template@example.com")
+
+	tmpl, err := svc.CreateTemplate(ctx, &offer.CreateOfferTemplateRequest{
+		Name:               "1GB Weekly",
+		Type:               offer.OfferTypeData,
+		Amount:             1,
+		Units:              offer.UnitsGB,
+		Price:              99,
+		ValidityDays:       7,
+		USSDCodeTemplate:   "*180*{phone}#",
+		USSDProcessingType: offer.USSDProcessingExpress,
+		Tags:               []string{"weekly"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	if tmpl.Currency != "KES" {
+		t.Errorf("template currency = %q, want the KES default", tmpl.Currency)
+	}
+
+	price := 120.0
+	o, err := svc.CreateFromTemplate(ctx, agentID, tmpl.ID, &offer.OfferTemplateOverrides{Price: &price})
+	if err != nil {
+		t.Fatalf("CreateFromTemplate: %v", err)
+	}
+	if o.AgentIdentityID != agentID || o.Name != "1GB Weekly" || o.Type != offer.OfferTypeData ||
+		o.Amount != 1 || o.Units != offer.UnitsGB || o.ValidityDays != 7 {
+		t.Errorf("offer = %+v, want the template's 1 GB weekly data defaults for the agent", o)
+	}
+	if o.Price != 120 {
+		t.Errorf("offer price = %.2f, want the 120 override", o.Price)
+	}
+	// Metadata round-trips through JSON, so the ID comes back as a float64
+	if id, _ := o.Metadata["template_id"].(float64); int64(id) != tmpl.ID {
+		t.Errorf("offer metadata = %v, want template_id %d", o.Metadata, tmpl.ID)
+	}
+
+	// Retired templates can't be instantiated
+	inactive := false
+	if _, err := svc.UpdateTemplate(ctx, tmpl.ID, &offer.UpdateOfferTemplateRequest{IsActive: &inactive}); err != nil {
+		t.Fatalf("UpdateTemplate: %v", err)
+	}
+	if _, err := svc.CreateFromTemplate(ctx, agentID, tmpl.ID, nil); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("CreateFromTemplate on a retired template error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,96 @@
+// internal/service/offer/validity_label.go
+package offer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Supported validity label languages
+const (
+	LanguageEnglish = "en"
+	LanguageSwahili = "sw"
+)
+
+// validityUnits holds the unit words and number placement for one language
+type validityUnits struct {
+	day, days     string
+	week, weeks   string
+	month, months string
+	year          string
+	numberFirst   bool // "30 days" vs "siku 30"
+}
+
+var validityLabelUnits = map[string]validityUnits{
+	LanguageEnglish: {
+		day: "day", days: "days",
+		week: "week", weeks: "weeks",
+		month: "month", months: "months",
+		year:        "year",
+		numberFirst: true,
+	},
+	LanguageSwahili: {
+		day: "siku", days: "siku",
+		week: "wiki", weeks: "wiki",
+		month: "mwezi", months: "miezi",
+		year:        "mwaka",
+		numberFirst: false,
+	},
+}
+
+// format renders a count and its unit in the language's word order
+func (u validityUnits) format(n int, singular, plural string) string {
+	unit := plural
+	if n == 1 {
+		unit = singular
+	}
+	if u.numberFirst {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%s %d", unit, n)
+}
+
+// generateValidityLabel generates a human-readable validity label in the given language (falls back to English);
+// days below 1 are clamped so unvalidated values never render as "0 days"
+func (s *OfferService) generateValidityLabel(days int, language string) string {
+	u, ok := validityLabelUnits[language]
+	if !ok {
+		u = validityLabelUnits[LanguageEnglish]
+	}
+
+	if days < 1 {
+		days = 1
+	}
+
+	switch {
+	case days == 365:
+		return u.format(1, u.year, u.year)
+	case days%30 == 0:
+		return u.format(days/30, u.month, u.months)
+	case days%7 == 0:
+		return u.format(days/7, u.week, u.weeks)
+	default:
+		return u.format(days, u.day, u.days)
+	}
+}
+
+// labelLanguage resolves the language for validity labels from the agent's display config
+func (s *OfferService) labelLanguage(ctx context.Context, agentID int64) string {
+	displayConfig, err := s.configService.GetDisplayConfig(ctx, agentID)
+	if err != nil {
+		s.logger.Warn("failed to get display config, using default label language",
+			zap.Int64("agent_id", agentID),
+			zap.Error(err),
+		)
+		return LanguageEnglish
+	}
+
+	language := strings.ToLower(strings.TrimSpace(displayConfig.Language))
+	if _, ok := validityLabelUnits[language]; !ok {
+		return LanguageEnglish
+	}
+	return language
+}
@@ -0,0 +1,82 @@
+// internal/service/schedule/quiet_hours.go
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/schedule"
+	configsvc "bingwa-service/internal/service/config"
+
+	"go.uber.org/zap"
+)
+
+// SetConfigService wires per-agent business settings (optional; quiet hours are not enforced without it)
+func (s *ScheduleService) SetConfigService(configService *configsvc.ConfigService) {
+	s.configService = configService
+}
+
+// deferQuietSchedules moves due schedules out of the agent's quiet hours to the end of the
+// window and returns the ones that may run now. Config lookups that fail leave the schedules due.
+func (s *ScheduleService) deferQuietSchedules(ctx context.Context, agentID int64, due []schedule.ScheduledOffer) []schedule.ScheduledOffer {
+	if s.configService == nil || len(due) == 0 {
+		return due
+	}
+
+	businessConfig, err := s.configService.GetBusinessConfig(ctx, agentID)
+	if err != nil {
+		s.logger.Warn("failed to load quiet hours", zap.Int64("agent_id", agentID), zap.Error(err))
+		return due
+	}
+	if !businessConfig.QuietHoursEnabled() {
+		return due
+	}
+
+	until, quiet := businessConfig.QuietUntil(time.Now(), s.agentLocation(ctx, agentID))
+	if !quiet {
+		return due
+	}
+
+	if err := s.deferSchedules(ctx, due, until); err != nil {
+		s.logger.Error("failed to defer schedules for quiet hours", zap.Int64("agent_id", agentID), zap.Error(err))
+		return nil
+	}
+
+	s.logger.Info("due schedules deferred for quiet hours",
+		zap.Int64("agent_id", agentID),
+		zap.Int("count", len(due)),
+		zap.Time("deferred_until", until),
+	)
+
+	return nil
+}
+
+// deferSchedules moves each schedule's next renewal to until in one transaction
+func (s *ScheduleService) deferSchedules(ctx context.Context, due []schedule.ScheduledOffer, until time.Time) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, sched := range due {
+		if err := s.scheduleRepo.UpdateNextRenewalWithTx(ctx, tx, sched.ID, until); err != nil {
+			return fmt.Errorf("failed to defer schedule %d: %w", sched.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// agentLocation resolves the agent's display timezone, which quiet hours and recurrence patterns are written in
+func (s *ScheduleService) agentLocation(ctx context.Context, agentID int64) *time.Location {
+	if s.configService == nil {
+		return time.FixedZone("EAT", 3*60*60)
+	}
+	return s.configService.AgentLocation(ctx, agentID)
+}
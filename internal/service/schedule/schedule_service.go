@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 
 	//"strings"
 	"time"
@@ -12,14 +13,25 @@ import (
 	"bingwa-service/internal/domain/schedule"
 	"bingwa-service/internal/domain/transaction"
 	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/pkg/pagination"
 	"bingwa-service/internal/service/offer"
 	customer "bingwa-service/internal/service/customer"
 	domainoffer "bingwa-service/internal/domain/offer"
 	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
 
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
+// scheduleClaimLease bounds how long a batch processor holds a schedule before others may retry it
+const scheduleClaimLease = 5 * time.Minute
+
+// scheduleConflictWindow is how close two active schedules for the same offer and customer may run
+// before creating the second one needs force
+const scheduleConflictWindow = time.Hour
+
 type ScheduleService struct {
 	scheduleRepo       *postgres.ScheduledOfferRepository
 	historyRepo        *postgres.ScheduledOfferHistoryRepository
@@ -30,6 +42,7 @@ type ScheduleService struct {
 	db                 *postgres.DB
 
 	offerSvc 		   *offer.OfferService
+	configService      *configsvc.ConfigService
 	logger             *zap.Logger
 }
 
@@ -80,6 +93,24 @@ func (s *ScheduleService) CreateScheduledOffer(ctx context.Context, agentID int6
 		return nil, fmt.Errorf("scheduled time must be in the future")
 	}
 
+	// Validate recurrence pattern
+	if req.RecurrencePattern != "" {
+		if _, err := schedule.ParseRecurrence(req.RecurrencePattern); err != nil {
+			return nil, err
+		}
+	}
+
+	// Reject overlapping schedules that would top up the customer twice
+	if !req.Force {
+		conflicts, err := s.scheduleRepo.FindConflictingIDs(ctx, agentID, req.OfferID, req.CustomerPhone, req.ScheduledTime, scheduleConflictWindow)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			return nil, fmt.Errorf("active schedule(s) %v already run this offer for the customer within %s of the scheduled time; set force to create anyway: %w", conflicts, scheduleConflictWindow, xerrors.ErrConflict)
+		}
+	}
+
 	// Get or find customer
 	customerID, err := s.customerSvc.GetOrCreateCustomer(ctx, agentID, req.CustomerPhone)
 	if err != nil {
@@ -91,8 +122,8 @@ func (s *ScheduleService) CreateScheduledOffer(ctx context.Context, agentID int6
 
 	// Calculate next renewal date
 	var nextRenewal sql.NullTime
-	if req.AutoRenew && req.RenewalPeriod != "" {
-		nextDate := s.calculateNextRenewal(req.ScheduledTime, req.RenewalPeriod)
+	if req.AutoRenew && (req.RenewalPeriod != "" || req.RecurrencePattern != "") {
+		nextDate := s.calculateNextRenewal(ctx, agentID, req.ScheduledTime, req.RenewalPeriod, req.RecurrencePattern)
 		nextRenewal = sql.NullTime{Time: nextDate, Valid: true}
 	}
 
@@ -118,6 +149,10 @@ func (s *ScheduleService) CreateScheduledOffer(ctx context.Context, agentID int6
 		scheduledOffer.RenewalPeriod = sql.NullString{String: string(req.RenewalPeriod), Valid: true}
 	}
 
+	if req.RecurrencePattern != "" {
+		scheduledOffer.RecurrencePattern = sql.NullString{String: req.RecurrencePattern, Valid: true}
+	}
+
 	if req.RenewalLimit != nil {
 		scheduledOffer.RenewalLimit = sql.NullInt32{Int32: *req.RenewalLimit, Valid: true}
 	}
@@ -146,14 +181,85 @@ func (s *ScheduleService) CreateScheduledOffer(ctx context.Context, agentID int6
 	s.logger.Info("scheduled offer created",
 		zap.Int64("schedule_id", scheduledOffer.ID),
 		zap.String("schedule_reference", scheduledOffer.ScheduleReference),
+		zap.String("customer_phone", mask.Phone(scheduledOffer.CustomerPhone)),
+		zap.Int64("agent_id", agentID),
+	)
+
+	return scheduledOffer, nil
+}
+
+// CreateRenewalScheduleWithTx schedules the next period of a recurring offer purchase inside the caller's transaction
+func (s *ScheduleService) CreateRenewalScheduleWithTx(ctx context.Context, tx pgx.Tx, agentID int64, o *domainoffer.AgentOffer, customerPhone string, customerID sql.NullInt64, sourceReference string) (*schedule.ScheduledOffer, error) {
+	if !o.IsRecurring {
+		return nil, fmt.Errorf("offer is not recurring")
+	}
+
+	// Next purchase is due when the current one lapses
+	scheduledTime := time.Now().AddDate(0, 0, o.ValidityDays)
+
+	scheduledOffer := &schedule.ScheduledOffer{
+		ScheduleReference: s.generateScheduleReference(),
+		OfferID:           o.ID,
+		AgentIdentityID:   agentID,
+		CustomerID:        customerID,
+		CustomerPhone:     customerPhone,
+		ScheduledTime:     scheduledTime,
+		Status:            schedule.ScheduleStatusActive,
+		RenewalCount:      0,
+		Metadata: map[string]interface{}{
+			"created_from":      "auto_schedule_renewal",
+			"request_reference": sourceReference,
+		},
+	}
+
+	// Keep renewing when the validity maps onto a standard period
+	if period, ok := renewalPeriodForValidity(o.ValidityDays); ok {
+		scheduledOffer.AutoRenew = true
+		scheduledOffer.RenewalPeriod = sql.NullString{String: string(period), Valid: true}
+		scheduledOffer.NextRenewalDate = sql.NullTime{Time: s.calculateNextRenewal(ctx, agentID, scheduledTime, period, ""), Valid: true}
+	}
+
+	if err := s.scheduleRepo.CreateWithTx(ctx, tx, scheduledOffer); err != nil {
+		return nil, fmt.Errorf("failed to create renewal schedule: %w", err)
+	}
+
+	s.logger.Info("renewal schedule created",
+		zap.Int64("schedule_id", scheduledOffer.ID),
+		zap.Int64("offer_id", o.ID),
 		zap.Int64("agent_id", agentID),
+		zap.Time("scheduled_time", scheduledTime),
 	)
 
 	return scheduledOffer, nil
 }
 
-// ExecuteScheduledOffer executes a scheduled offer and creates redemption + history
+// ExecuteScheduledOffer executes a due scheduled offer and creates redemption + history. It takes the
+// same claim lease as ProcessDueSchedules, so a schedule that is not due or is being executed elsewhere
+// is rejected with ErrConflict instead of running twice.
 func (s *ScheduleService) ExecuteScheduledOffer(ctx context.Context, agentID, scheduleID int64, input *schedule.ExecuteScheduledOfferInput) (*transaction.OfferRedemption, *schedule.ScheduledOfferHistory, error) {
+	scheduledOffer, err := s.scheduleRepo.FindByID(ctx, scheduleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scheduledOffer.AgentIdentityID != agentID {
+		return nil, nil, fmt.Errorf("unauthorized: scheduled offer does not belong to agent")
+	}
+
+	claimToken := newClaimToken()
+	claimed, err := s.scheduleRepo.ClaimDueSchedule(ctx, scheduleID, claimToken, scheduleClaimLease)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claimed {
+		return nil, nil, fmt.Errorf("%w: scheduled offer is not due or is already being executed", xerrors.ErrConflict)
+	}
+	defer s.releaseClaim(ctx, scheduleID, claimToken)
+
+	return s.executeClaimedSchedule(ctx, agentID, scheduleID, input)
+}
+
+// executeClaimedSchedule executes a scheduled offer the caller holds the claim lease on
+func (s *ScheduleService) executeClaimedSchedule(ctx context.Context, agentID, scheduleID int64, input *schedule.ExecuteScheduledOfferInput) (*transaction.OfferRedemption, *schedule.ScheduledOfferHistory, error) {
 	// Get scheduled offer
 	scheduledOffer, err := s.scheduleRepo.FindByID(ctx, scheduleID)
 	if err != nil {
@@ -178,8 +284,11 @@ func (s *ScheduleService) ExecuteScheduledOffer(ctx context.Context, agentID, sc
 
 	// Determine execution status
 	executionStatus := transaction.TransactionStatusSuccess
-	if input.Status == "failed" {
+	switch input.Status {
+	case "failed":
 		executionStatus = transaction.TransactionStatusFailed
+	case "pending":
+		executionStatus = transaction.TransactionStatusPending
 	}
 
 	// Generate redemption reference
@@ -192,8 +301,10 @@ func (s *ScheduleService) ExecuteScheduledOffer(ctx context.Context, agentID, sc
 		//return nil, nil, fmt.Errorf("failed to generate USSD code: %w", err)
 	}
 
-
-	ussdCode := ussdCodeInfo.USSDCode
+	ussdCode := ""
+	if ussdCodeInfo != nil {
+		ussdCode = ussdCodeInfo.USSDCode
+	}
 	if ussdCode == "" {
 		ussdCode = offer.USSDCodeTemplate
 	}
@@ -285,9 +396,9 @@ func (s *ScheduleService) ExecuteScheduledOffer(ctx context.Context, agentID, sc
 	// Calculate next renewal if auto-renew is enabled
 	shouldContinue := s.shouldContinueRenewal(scheduledOffer, newRenewalCount)
 	
-	if scheduledOffer.AutoRenew && shouldContinue && executionStatus == transaction.TransactionStatusSuccess {
-		if scheduledOffer.RenewalPeriod.Valid {
-			nextRenewal = s.calculateNextRenewal(lastRenewal, schedule.RenewalPeriod(scheduledOffer.RenewalPeriod.String))
+	if scheduledOffer.AutoRenew && shouldContinue && executionStatus != transaction.TransactionStatusFailed {
+		if scheduledOffer.RenewalPeriod.Valid || scheduledOffer.RecurrencePattern.Valid {
+			nextRenewal = s.calculateNextRenewal(ctx, agentID, lastRenewal, schedule.RenewalPeriod(scheduledOffer.RenewalPeriod.String), scheduledOffer.RecurrencePattern.String)
 		}
 		
 		// Update renewal info
@@ -305,6 +416,7 @@ func (s *ScheduleService) ExecuteScheduledOffer(ctx context.Context, agentID, sc
 	if err := tx.Commit(ctx); err != nil {
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.offerSvc.InvalidateOfferCache(ctx, redemption.AgentIdentityID)
 
 	s.logger.Info("scheduled offer executed",
 		zap.Int64("schedule_id", scheduleID),
@@ -334,36 +446,26 @@ func (s *ScheduleService) GetScheduledOffer(ctx context.Context, agentID, schedu
 // ListScheduledOffers retrieves scheduled offers with filters
 func (s *ScheduleService) ListScheduledOffers(ctx context.Context, agentID int64, filters *schedule.ScheduledOfferListFilters) (*schedule.ScheduledOfferListResponse, error) {
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
-	}
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	schedules, total, err := s.scheduleRepo.List(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list scheduled offers: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &schedule.ScheduledOfferListResponse{
 		Schedules:  schedules,
-		Total:      total,
-		Page:       filters.Page,
-		PageSize:   filters.PageSize,
-		TotalPages: totalPages,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
 	}, nil
 }
 
-// GetDueSchedules retrieves schedules due for execution
+// GetDueSchedules retrieves schedules due for execution. Schedules due during the agent's
+// quiet hours are deferred to the end of the window instead of being returned.
 func (s *ScheduleService) GetDueSchedules(ctx context.Context, agentID int64) ([]schedule.ScheduledOffer, error) {
 	// Get all due schedules
 	allDue, err := s.scheduleRepo.GetDueSchedules(ctx)
@@ -379,7 +481,65 @@ func (s *ScheduleService) GetDueSchedules(ctx context.Context, agentID int64) ([
 		}
 	}
 
-	return agentSchedules, nil
+	return s.deferQuietSchedules(ctx, agentID, agentSchedules), nil
+}
+
+// ProcessDueSchedules claims and executes an agent's due schedules with at most maxConcurrency workers.
+// Executions are queued as pending redemptions for device dispatch.
+func (s *ScheduleService) ProcessDueSchedules(ctx context.Context, agentID int64, maxConcurrency int) (*schedule.ProcessDueSchedulesResult, error) {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	dueSchedules, err := s.GetDueSchedules(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	claimToken := newClaimToken()
+	results := make([]schedule.ScheduleExecutionResult, len(dueSchedules))
+
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+
+	for i, sched := range dueSchedules {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, scheduleID int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = s.processDueSchedule(ctx, agentID, scheduleID, claimToken)
+		}(i, sched.ID)
+	}
+
+	wg.Wait()
+
+	result := &schedule.ProcessDueSchedulesResult{
+		Total:   len(results),
+		Results: results,
+	}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			result.SkippedCount++
+		case r.Success:
+			result.SuccessCount++
+		default:
+			result.FailureCount++
+		}
+	}
+
+	s.logger.Info("due schedules processed",
+		zap.Int64("agent_id", agentID),
+		zap.Int("total", result.Total),
+		zap.Int("success_count", result.SuccessCount),
+		zap.Int("failure_count", result.FailureCount),
+		zap.Int("skipped_count", result.SkippedCount),
+	)
+
+	return result, nil
 }
 
 // UpdateScheduledOffer updates a scheduled offer
@@ -405,6 +565,14 @@ func (s *ScheduleService) UpdateScheduledOffer(ctx context.Context, agentID, sch
 	if req.RenewalPeriod != nil {
 		scheduledOffer.RenewalPeriod = sql.NullString{String: string(*req.RenewalPeriod), Valid: true}
 	}
+	if req.RecurrencePattern != nil {
+		if *req.RecurrencePattern != "" {
+			if _, err := schedule.ParseRecurrence(*req.RecurrencePattern); err != nil {
+				return nil, err
+			}
+		}
+		scheduledOffer.RecurrencePattern = sql.NullString{String: *req.RecurrencePattern, Valid: *req.RecurrencePattern != ""}
+	}
 	if req.RenewalLimit != nil {
 		scheduledOffer.RenewalLimit = sql.NullInt32{Int32: *req.RenewalLimit, Valid: true}
 	}
@@ -416,8 +584,9 @@ func (s *ScheduleService) UpdateScheduledOffer(ctx context.Context, agentID, sch
 	}
 
 	// Recalculate next renewal if renewal settings changed
-	if (req.AutoRenew != nil || req.RenewalPeriod != nil) && scheduledOffer.AutoRenew && scheduledOffer.RenewalPeriod.Valid {
-		nextDate := s.calculateNextRenewal(scheduledOffer.ScheduledTime, schedule.RenewalPeriod(scheduledOffer.RenewalPeriod.String))
+	if (req.AutoRenew != nil || req.RenewalPeriod != nil || req.RecurrencePattern != nil) && scheduledOffer.AutoRenew &&
+		(scheduledOffer.RenewalPeriod.Valid || scheduledOffer.RecurrencePattern.Valid) {
+		nextDate := s.calculateNextRenewal(ctx, agentID, scheduledOffer.ScheduledTime, schedule.RenewalPeriod(scheduledOffer.RenewalPeriod.String), scheduledOffer.RecurrencePattern.String)
 		scheduledOffer.NextRenewalDate = sql.NullTime{Time: nextDate, Valid: true}
 	}
 
@@ -489,6 +658,71 @@ func (s *ScheduleService) CancelScheduledOffer(ctx context.Context, agentID, sch
 	return nil
 }
 
+// SkipNextRenewal advances the next renewal date by one period without executing or counting a renewal,
+// recording the skipped date in the schedule history
+func (s *ScheduleService) SkipNextRenewal(ctx context.Context, agentID, scheduleID int64) (*schedule.ScheduledOffer, error) {
+	scheduledOffer, err := s.scheduleRepo.FindByID(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if scheduledOffer.AgentIdentityID != agentID {
+		return nil, xerrors.ErrUnauthorized
+	}
+
+	if scheduledOffer.Status != schedule.ScheduleStatusActive && scheduledOffer.Status != schedule.ScheduleStatusPaused {
+		return nil, fmt.Errorf("cannot skip a renewal on a %s schedule: %w", scheduledOffer.Status, xerrors.ErrInvalidInput)
+	}
+	if !scheduledOffer.AutoRenew || !scheduledOffer.NextRenewalDate.Valid {
+		return nil, fmt.Errorf("schedule has no upcoming renewal: %w", xerrors.ErrInvalidInput)
+	}
+
+	skipped := scheduledOffer.NextRenewalDate.Time
+	nextRenewal := s.calculateNextRenewal(ctx, agentID, skipped, schedule.RenewalPeriod(scheduledOffer.RenewalPeriod.String), scheduledOffer.RecurrencePattern.String)
+	if scheduledOffer.RenewUntil.Valid && nextRenewal.After(scheduledOffer.RenewUntil.Time) {
+		return nil, fmt.Errorf("skipping would move the next renewal past renew_until; cancel the schedule instead: %w", xerrors.ErrInvalidInput)
+	}
+
+	history := &schedule.ScheduledOfferHistory{
+		ScheduledOfferID: scheduleID,
+		CustomerID:       scheduledOffer.CustomerID,
+		CustomerPhone:    scheduledOffer.CustomerPhone,
+		RenewalTime:      skipped,
+		RenewalNumber:    scheduledOffer.RenewalCount,
+		Status:           string(transaction.TransactionStatusCancelled),
+		FailureReason:    sql.NullString{String: "renewal skipped by agent", Valid: true},
+		Metadata: map[string]interface{}{
+			schedule.HistoryMetadataSkipped: true,
+			"next_renewal_date":             nextRenewal.Format(time.RFC3339),
+		},
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.scheduleRepo.UpdateNextRenewalWithTx(ctx, tx, scheduleID, nextRenewal); err != nil {
+		return nil, err
+	}
+
+	if err := s.historyRepo.CreateWithTx(ctx, tx, history); err != nil {
+		return nil, fmt.Errorf("failed to create history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("scheduled renewal skipped",
+		zap.Int64("schedule_id", scheduleID),
+		zap.Time("skipped_date", skipped),
+		zap.Time("next_renewal_date", nextRenewal),
+	)
+
+	return s.scheduleRepo.FindByID(ctx, scheduleID)
+}
+
 // GetScheduleHistory retrieves history for a scheduled offer
 func (s *ScheduleService) GetScheduleHistory(ctx context.Context, agentID, scheduleID int64, filters *schedule.ScheduleHistoryListFilters) (*schedule.ScheduleHistoryListResponse, error) {
 	// Verify ownership
@@ -554,6 +788,48 @@ func (s *ScheduleService) GetScheduleStats(ctx context.Context, agentID int64) (
 
 // ========== Helper Methods ==========
 
+// processDueSchedule executes a single schedule under a claim lease
+func (s *ScheduleService) processDueSchedule(ctx context.Context, agentID, scheduleID int64, claimToken string) schedule.ScheduleExecutionResult {
+	result := schedule.ScheduleExecutionResult{ScheduleID: scheduleID}
+
+	claimed, err := s.scheduleRepo.ClaimDueSchedule(ctx, scheduleID, claimToken, scheduleClaimLease)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !claimed {
+		result.Skipped = true
+		return result
+	}
+	defer s.releaseClaim(ctx, scheduleID, claimToken)
+
+	input := &schedule.ExecuteScheduledOfferInput{Status: string(transaction.TransactionStatusPending)}
+	redemption, history, err := s.executeClaimedSchedule(ctx, agentID, scheduleID, input)
+	if err != nil {
+		s.logger.Error("failed to execute due schedule", zap.Int64("schedule_id", scheduleID), zap.Error(err))
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.RedemptionID = &redemption.ID
+	result.HistoryID = &history.ID
+	return result
+}
+
+
+// releaseClaim gives up a claim lease once its execution has finished
+func (s *ScheduleService) releaseClaim(ctx context.Context, scheduleID int64, claimToken string) {
+	if err := s.scheduleRepo.ReleaseClaim(ctx, scheduleID, claimToken); err != nil {
+		s.logger.Warn("failed to release schedule claim", zap.Int64("schedule_id", scheduleID), zap.Error(err))
+	}
+}
+
+// newClaimToken identifies one claimant of schedule leases
+func newClaimToken() string {
+	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), generateRandomString(8))
+}
+
 // generateScheduleReference generates unique schedule reference
 func (s *ScheduleService) generateScheduleReference() string {
 	timestamp := time.Now().Format("20060102150405")
@@ -573,8 +849,16 @@ func (s *ScheduleService) generateUSSDCode(ctx context.Context, offer *domainoff
 	return s.offerSvc.GetUSSDCodeForExecution(ctx, offer.AgentIdentityID, offer.ID, phoneNumber)
 }
 
-// calculateNextRenewal calculates next renewal date based on period
-func (s *ScheduleService) calculateNextRenewal(from time.Time, period schedule.RenewalPeriod) time.Time {
+// calculateNextRenewal calculates next renewal date from the recurrence pattern if set, otherwise the period.
+// Recurrence patterns are evaluated in the agent's display timezone.
+func (s *ScheduleService) calculateNextRenewal(ctx context.Context, agentID int64, from time.Time, period schedule.RenewalPeriod, pattern string) time.Time {
+	if pattern != "" {
+		if recurrence, err := schedule.ParseRecurrence(pattern); err == nil {
+			return recurrence.Next(from, s.agentLocation(ctx, agentID))
+		}
+		s.logger.Warn("invalid recurrence pattern, falling back to renewal period", zap.String("pattern", pattern))
+	}
+
 	switch period {
 	case schedule.RenewalDaily:
 		return from.AddDate(0, 0, 1)
@@ -591,6 +875,23 @@ func (s *ScheduleService) calculateNextRenewal(from time.Time, period schedule.R
 	}
 }
 
+// renewalPeriodForValidity maps an offer validity onto a renewal period
+func renewalPeriodForValidity(days int) (schedule.RenewalPeriod, bool) {
+	switch days {
+	case 1:
+		return schedule.RenewalDaily, true
+	case 7:
+		return schedule.RenewalWeekly, true
+	case 30:
+		return schedule.RenewalMonthly, true
+	case 90:
+		return schedule.RenewalQuarterly, true
+	case 365:
+		return schedule.RenewalYearly, true
+	}
+	return "", false
+}
+
 // shouldContinueRenewal checks if renewal should continue
 func (s *ScheduleService) shouldContinueRenewal(scheduledOffer *schedule.ScheduledOffer, newCount int) bool {
 	// Check renewal limit
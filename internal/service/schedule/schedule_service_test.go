@@ -0,0 +1,219 @@
+// internal/service/schedule/schedule_service_test.go
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/schedule"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	customersvc "bingwa-service/internal/service/customer"
+	"bingwa-service/internal/service/offer"
+	"bingwa-service/internal/testutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newTestScheduleService wires a ScheduleService against a test database
+func newTestScheduleService(t *testing.T) (*ScheduleService, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ussdCodeRepo := postgres.NewOfferUSSDCodeRepository(pool)
+	offerRepo := postgres.NewAgentOfferRepository(pool, ussdCodeRepo, db)
+	customerRepo := postgres.NewAgentCustomerRepository(pool)
+	offerSvc := offer.NewOfferService(offer.OfferRepositories{
+		Offers:        offerRepo,
+		USSDCodes:     ussdCodeRepo,
+		Templates:     postgres.NewOfferTemplateRepository(pool),
+		Customers:     customerRepo,
+		Auth:          postgres.NewAuthRepository(pool),
+		Subscriptions: postgres.NewAgentSubscriptionRepository(pool),
+		Plans:         postgres.NewSubscriptionPlanRepository(pool),
+	}, nil, db, cache.NewOfferCache(client), zap.NewNop())
+
+	svc := NewScheduleService(
+		postgres.NewScheduledOfferRepository(pool),
+		postgres.NewScheduledOfferHistoryRepository(pool),
+		postgres.NewOfferRedemptionRepository(pool),
+		offerRepo,
+		customerRepo,
+		customersvc.NewCustomerService(customerRepo, nil, nil, nil, zap.NewNop()),
+		db,
+		offerSvc,
+		zap.NewNop(),
+	)
+	return svc, pool
+}
+
+// seedDueSchedule inserts a monthly auto-renewing schedule that fell due an hour ago
+func seedDueSchedule(t *testing.T, pool *pgxpool.Pool, agentID, offerID int64, reference string) int64 {
+	t.Helper()
+
+	due := time.Now().Add(-time.Hour)
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO scheduled_offers (
+			schedule_reference, offer_id, agent_identity_id, customer_phone,
+			scheduled_time, next_renewal_date, auto_renew, renewal_period
+		) VALUES ($1, $2, $3, '0712345678', $4, $4, TRUE, 'monthly')
+		RETURNING id
+	`, reference, offerID, agentID, due).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed schedule %s: %v", reference, err)
+	}
+	return id
+}
+
+func TestProcessDueSchedulesExecutesEachScheduleOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestScheduleService(t)
+
+	agentID := testutil.Identity(t, pool, "schedules@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	ids := make([]int64, 10)
+	for i := range ids {
+		ids[i] = seedDueSchedule(t, pool, agentID, offerID, fmt.Sprintf("SCH-TEST-%d", i))
+	}
+
+	// Two batch processors and a device executing directly all race for the same schedules
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.ProcessDueSchedules(ctx, agentID, 3); err != nil {
+				t.Errorf("ProcessDueSchedules: %v", err)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, id := range ids {
+			input := &schedule.ExecuteScheduledOfferInput{Status: "pending"}
+			if _, _, err := svc.ExecuteScheduledOffer(ctx, agentID, id, input); err != nil && !errors.Is(err, xerrors.ErrConflict) {
+				t.Errorf("ExecuteScheduledOffer(%d): %v", id, err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	for _, id := range ids {
+		var runs int
+		if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM scheduled_offer_history WHERE scheduled_offer_id = $1`, id).Scan(&runs); err != nil {
+			t.Fatalf("failed to count history: %v", err)
+		}
+		if runs != 1 {
+			t.Errorf("schedule %d executed %d times, want 1", id, runs)
+		}
+	}
+
+	// Executed schedules moved to their next renewal, so executing again is refused
+	input := &schedule.ExecuteScheduledOfferInput{Status: "pending"}
+	if _, _, err := svc.ExecuteScheduledOffer(ctx, agentID, ids[0], input); !errors.Is(err, xerrors.ErrConflict) {
+		t.Errorf("ExecuteScheduledOffer after execution error = %v, want ErrConflict", err)
+	}
+}
+
+func TestSkipNextRenewalAdvancesDateAndRecordsSkip(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestScheduleService(t)
+
+	agentID := testutil.Identity(t, pool, "skip@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	id := seedDueSchedule(t, pool, agentID, offerID, "SCH-SKIP")
+
+	before, err := svc.scheduleRepo.FindByID(ctx, id)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	due := before.NextRenewalDate.Time
+
+	if _, err := svc.SkipNextRenewal(ctx, otherID, id); !errors.Is(err, xerrors.ErrUnauthorized) {
+		t.Errorf("SkipNextRenewal by another agent = %v, want ErrUnauthorized", err)
+	}
+
+	after, err := svc.SkipNextRenewal(ctx, agentID, id)
+	if err != nil {
+		t.Fatalf("SkipNextRenewal: %v", err)
+	}
+	if want := due.AddDate(0, 1, 0); !after.NextRenewalDate.Time.Equal(want) {
+		t.Errorf("next renewal = %v, want %v", after.NextRenewalDate.Time, want)
+	}
+	if after.RenewalCount != before.RenewalCount {
+		t.Errorf("renewal count = %d, want it unchanged at %d", after.RenewalCount, before.RenewalCount)
+	}
+
+	history, err := svc.GetScheduleHistory(ctx, agentID, id, &schedule.ScheduleHistoryListFilters{})
+	if err != nil {
+		t.Fatalf("GetScheduleHistory: %v", err)
+	}
+	if len(history.History) != 1 {
+		t.Fatalf("history has %d entries, want the one skip", len(history.History))
+	}
+	entry := history.History[0]
+	if skipped, _ := entry.Metadata[schedule.HistoryMetadataSkipped].(bool); !skipped || entry.Status != "cancelled" {
+		t.Errorf("history entry = %s with metadata %v, want a cancelled skip", entry.Status, entry.Metadata)
+	}
+	if !entry.RenewalTime.Equal(due) {
+		t.Errorf("skipped renewal time = %v, want %v", entry.RenewalTime, due)
+	}
+}
+
+func TestCreateScheduledOfferDetectsConflict(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestScheduleService(t)
+
+	agentID := testutil.Identity(t, pool, "conflict@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	at := time.Now().Add(24 * time.Hour)
+	request := func(scheduledTime time.Time, force bool) *schedule.CreateScheduledOfferRequest {
+		return &schedule.CreateScheduledOfferRequest{
+			OfferID:       offerID,
+			CustomerPhone: "254712345678",
+			ScheduledTime: scheduledTime,
+			Force:         force,
+		}
+	}
+
+	if _, err := svc.CreateScheduledOffer(ctx, agentID, request(at, false)); err != nil {
+		t.Fatalf("CreateScheduledOffer: %v", err)
+	}
+
+	// Half an hour later is within the conflict window
+	if _, err := svc.CreateScheduledOffer(ctx, agentID, request(at.Add(30*time.Minute), false)); !errors.Is(err, xerrors.ErrConflict) {
+		t.Errorf("overlapping schedule error = %v, want ErrConflict", err)
+	}
+
+	// Force creates it anyway, and a time outside the window needs no force
+	if _, err := svc.CreateScheduledOffer(ctx, agentID, request(at.Add(30*time.Minute), true)); err != nil {
+		t.Errorf("forced overlapping schedule: %v", err)
+	}
+	if _, err := svc.CreateScheduledOffer(ctx, agentID, request(at.Add(3*time.Hour), false)); err != nil {
+		t.Errorf("schedule outside the window: %v", err)
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM scheduled_offers WHERE agent_identity_id = $1`, agentID).Scan(&count); err != nil {
+		t.Fatalf("failed to count schedules: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("%d schedules created, want 3", count)
+	}
+}
@@ -0,0 +1,117 @@
+// internal/service/sms/relay.go
+package sms
+
+import (
+	"context"
+	"time"
+
+	"bingwa-service/internal/repository/postgres"
+
+	"go.uber.org/zap"
+)
+
+const (
+	relayBatchSize = 50
+	// relayLease covers a single send; the relay makes one attempt per message per run
+	relayLease       = time.Minute
+	maxSendAttempts  = 5
+	retryBaseBackoff = 30 * time.Second
+	maxRetryBackoff  = 30 * time.Minute
+	maxErrorLength   = 500
+)
+
+// Relay sends queued SMS messages to customers. Each claimed message gets exactly one
+// send attempt per run; failures are retried with backoff until maxSendAttempts.
+type Relay struct {
+	outboxRepo *postgres.SMSOutboxRepository
+	sender     *Sender
+	interval   time.Duration
+	logger     *zap.Logger
+}
+
+func NewRelay(outboxRepo *postgres.SMSOutboxRepository, sender *Sender, interval time.Duration, logger *zap.Logger) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		sender:     sender,
+		interval:   interval,
+		logger:     logger,
+	}
+}
+
+// Start runs the relay immediately and then on every interval until ctx is done
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RunOnce(ctx); err != nil {
+			r.logger.Error("sms relay run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends a batch of due messages and returns how many were delivered
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	messages, err := r.outboxRepo.ClaimDue(ctx, relayBatchSize, relayLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range messages {
+		m := &messages[i]
+
+		if err := r.sender.Send(ctx, m.PhoneNumber, m.Body); err != nil {
+			errMsg := err.Error()
+			if len(errMsg) > maxErrorLength {
+				errMsg = errMsg[:maxErrorLength]
+			}
+			attempts := m.Attempts + 1
+			final := attempts >= maxSendAttempts
+			if err := r.outboxRepo.RecordFailure(ctx, m.ID, errMsg, time.Now().Add(retryBackoff(attempts)), final); err != nil {
+				r.logger.Error("failed to record sms failure", zap.Int64("sms_id", m.ID), zap.Error(err))
+			}
+			r.logger.Warn("failed to send sms",
+				zap.Int64("sms_id", m.ID),
+				zap.String("purpose", string(m.Purpose)),
+				zap.Int("attempts", attempts),
+				zap.Bool("gave_up", final),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := r.outboxRepo.MarkSent(ctx, m.ID); err != nil {
+			r.logger.Error("failed to mark sms sent", zap.Int64("sms_id", m.ID), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		r.logger.Info("sms messages sent", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// retryBackoff doubles the wait after each failed attempt, capped at maxRetryBackoff
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := retryBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryBackoff {
+			return maxRetryBackoff
+		}
+	}
+	return delay
+}
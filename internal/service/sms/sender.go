@@ -0,0 +1,72 @@
+// internal/service/sms/sender.go
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrNotConfigured is returned when no SMS gateway has been set up
+var ErrNotConfigured = errors.New("sms gateway not configured")
+
+// Sender posts SMS messages to an HTTP gateway
+type Sender struct {
+	apiURL     string
+	apiKey     string
+	senderID   string
+	httpClient *http.Client
+}
+
+// NewSender creates a gateway sender; with an empty apiURL every send fails with ErrNotConfigured
+func NewSender(apiURL, apiKey, senderID string) *Sender {
+	return &Sender{
+		apiURL:     apiURL,
+		apiKey:     apiKey,
+		senderID:   senderID,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Send delivers one message; any non-2xx response is an error
+func (s *Sender) Send(ctx context.Context, to, message string) error {
+	if s.apiURL == "" {
+		return ErrNotConfigured
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"to":        to,
+		"message":   message,
+		"sender_id": s.senderID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal sms: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build sms request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
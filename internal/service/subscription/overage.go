@@ -0,0 +1,119 @@
+// internal/service/subscription/overage.go
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"bingwa-service/internal/domain/subscription"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// BillEndedPeriodOverages bills overage for active subscriptions whose period has ended
+// and resets their usage. It returns how many subscriptions were settled.
+func (s *SubscriptionService) BillEndedPeriodOverages(ctx context.Context) (int, error) {
+	subscriptions, err := s.subscriptionRepo.GetEndedPeriodSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if err := s.settlePeriod(ctx, sub); err != nil {
+			s.logger.Warn("failed to bill period overage",
+				zap.Int64("subscription_id", sub.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		settled++
+	}
+
+	return settled, nil
+}
+
+// settlePeriod bills a subscription's overage for its ended period and resets usage
+func (s *SubscriptionService) settlePeriod(ctx context.Context, sub *subscription.AgentSubscription) error {
+	plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
+	if err != nil {
+		return fmt.Errorf("subscription plan not found: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A renewal that lands first settles the period itself
+	renewalCount, err := s.subscriptionRepo.LockForRenewalWithTx(ctx, tx, sub.ID)
+	if err != nil {
+		return err
+	}
+	if renewalCount != sub.RenewalCount {
+		return nil
+	}
+
+	if err := s.billOverageWithTx(ctx, tx, sub, plan); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// billOverageWithTx records the requests used past the limit in the subscription's current period
+// as an overage charge, then resets usage. Only plans billed for overage produce a charge.
+// The caller must hold the renewal lock; a period that was already settled is left alone.
+func (s *SubscriptionService) billOverageWithTx(ctx context.Context, tx pgx.Tx, sub *subscription.AgentSubscription, plan *subscription.SubscriptionPlan) error {
+	// Usage keeps moving until the lock is held, so read it again
+	requestsUsed, settled, err := s.subscriptionRepo.GetPeriodUsageWithTx(ctx, tx, sub.ID)
+	if err != nil {
+		return err
+	}
+	if settled {
+		return nil
+	}
+
+	limit := effectiveRequestsLimit(sub)
+	overageCount := 0
+	if limit.Valid {
+		overageCount = requestsUsed - int(limit.Int32)
+	}
+
+	if overageCount > 0 && effectiveLimitBehavior(sub, plan) == subscription.LimitBehaviorOverage && plan.OverageCharge.Valid {
+		rec := &subscription.BillingRecord{
+			SubscriptionID:     sub.ID,
+			AgentIdentityID:    sub.AgentIdentityID,
+			SubscriptionPlanID: plan.ID,
+			PeriodStart:        sub.CurrentPeriodStart,
+			PeriodEnd:          sub.CurrentPeriodEnd,
+			Currency:           sub.Currency,
+			OverageRequests:    overageCount,
+			OverageRate:        sql.NullFloat64{Float64: plan.OverageCharge.Float64, Valid: true},
+			AmountDue:          float64(overageCount) * plan.OverageCharge.Float64,
+		}
+
+		created, err := s.subscriptionRepo.CreateOverageRecordWithTx(ctx, tx, rec)
+		if err != nil {
+			return err
+		}
+		if created {
+			s.logger.Info("subscription overage billed",
+				zap.Int64("subscription_id", sub.ID),
+				zap.Int("overage_requests", overageCount),
+				zap.Float64("amount_due", rec.AmountDue),
+			)
+		}
+	}
+
+	// Reset request usage counter for the next billing cycle
+	if err := s.subscriptionRepo.ResetRequestUsageWithTx(ctx, tx, sub.ID); err != nil {
+		return fmt.Errorf("failed to reset request usage: %w", err)
+	}
+
+	return nil
+}
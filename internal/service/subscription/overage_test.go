@@ -0,0 +1,119 @@
+// internal/service/subscription/overage_test.go
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/subscription"
+	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
+	notificationsvc "bingwa-service/internal/service/notification"
+	"bingwa-service/internal/testutil"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// newTestSubscriptionService wires a SubscriptionService against a test database
+func newTestSubscriptionService(t *testing.T) (*SubscriptionService, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	svc := NewSubscriptionService(
+		postgres.NewAgentSubscriptionRepository(pool),
+		postgres.NewSubscriptionPlanRepository(pool),
+		postgres.NewPromotionalCampaignRepository(pool),
+		postgres.NewOfferRequestRepository(pool),
+		configsvc.NewConfigService(postgres.NewAgentConfigRepository(pool), nil, db, zap.NewNop()),
+		notificationsvc.NewNotificationService(postgres.NewNotificationRepository(pool), nil),
+		postgres.NewAuthRepository(pool),
+		db,
+		zap.NewNop(),
+	)
+	return svc, pool
+}
+
+// seedPlan inserts a monthly KES plan and returns its ID
+func seedPlan(t *testing.T, pool *pgxpool.Pool, code string, price float64, usage int, overageCharge *float64) int64 {
+	t.Helper()
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO subscription_plans (plan_code, name, price, currency, billing_usage, billing_cycle, overage_charge)
+		VALUES ($1, $1, $2, 'KES', $3, 'monthly', $4)
+		RETURNING id
+	`, code, price, usage, overageCharge).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed plan %s: %v", code, err)
+	}
+	return id
+}
+
+// seedSubscription inserts an active subscription for the current period [start, end)
+func seedSubscription(t *testing.T, pool *pgxpool.Pool, agentID, planID int64, start, end time.Time, requestsUsed, requestsLimit int) int64 {
+	t.Helper()
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO agent_subscriptions (
+			subscription_reference, agent_identity_id, subscription_plan_id,
+			start_date, current_period_start, current_period_end, next_billing_date,
+			requests_used, requests_limit, plan_price, amount_paid, currency
+		)
+		SELECT $7, $1, $2, $3, $3, $4, $4, $5, $6, price, price, currency
+		FROM subscription_plans WHERE id = $2
+		RETURNING id
+	`, agentID, planID, start, end, requestsUsed, requestsLimit, fmt.Sprintf("SUB-TEST-%d", agentID)).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed subscription: %v", err)
+	}
+	return id
+}
+
+func TestBillEndedPeriodOveragesInvoicesOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	charge := 2.5
+	planID := seedPlan(t, pool, "overage-plan", 1000, 100, &charge)
+	agentID := testutil.Identity(t, pool, "overage@example.com")
+	end := time.Now().Add(-time.Hour)
+	subID := seedSubscription(t, pool, agentID, planID, end.AddDate(0, -1, 0), end, 112, 100)
+
+	settled, err := svc.BillEndedPeriodOverages(ctx)
+	if err != nil || settled != 1 {
+		t.Fatalf("BillEndedPeriodOverages = %d, %v; want 1 settled", settled, err)
+	}
+
+	records, err := svc.subscriptionRepo.ListBillingRecords(ctx, subID)
+	if err != nil {
+		t.Fatalf("ListBillingRecords: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d billing records, want 1 overage invoice", len(records))
+	}
+	if rec := records[0]; rec.Event != subscription.BillingEventOverage || rec.OverageRequests != 12 || rec.AmountDue != 30 {
+		t.Errorf("invoice = %s for %d requests, %.2f due; want overage for 12 requests, 30.00 due",
+			rec.Event, rec.OverageRequests, rec.AmountDue)
+	}
+
+	// Requests made after the settlement stay on the counter until the subscription renews
+	if _, err := pool.Exec(ctx, `UPDATE agent_subscriptions SET requests_used = 3 WHERE id = $1`, subID); err != nil {
+		t.Fatalf("failed to record usage: %v", err)
+	}
+	if settled, err := svc.BillEndedPeriodOverages(ctx); err != nil || settled != 0 {
+		t.Fatalf("second BillEndedPeriodOverages = %d, %v; want nothing left to settle", settled, err)
+	}
+
+	var requestsUsed int
+	if err := pool.QueryRow(ctx, `SELECT requests_used FROM agent_subscriptions WHERE id = $1`, subID).Scan(&requestsUsed); err != nil {
+		t.Fatalf("failed to read usage: %v", err)
+	}
+	if requestsUsed != 3 {
+		t.Errorf("requests_used = %d after a second run, want 3 (not reset again)", requestsUsed)
+	}
+}
@@ -0,0 +1,77 @@
+// internal/service/subscription/reference_test.go
+package subscription
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/subscription"
+	"bingwa-service/internal/testutil"
+)
+
+func TestGenerateSubscriptionReference(t *testing.T) {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		reference := generateSubscriptionReference()
+		parts := strings.Split(reference, "-")
+		if len(parts) != 3 || parts[0] != "SUB" || len(parts[1]) != 14 || len(parts[2]) != 6 {
+			t.Fatalf("reference %q does not match SUB-{TIMESTAMP}-{RANDOM}", reference)
+		}
+		if strings.Trim(parts[2], charset) != "" {
+			t.Fatalf("reference %q has characters outside %s", reference, charset)
+		}
+		seen[parts[2]] = true
+	}
+
+	// Back-to-back references in the same second still differ
+	if len(seen) < 990 {
+		t.Errorf("1000 references had only %d distinct suffixes", len(seen))
+	}
+}
+
+func TestCreateSubscriptionRetriesTakenReference(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "references", 1000, 100, nil)
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	seedSubscription(t, pool, otherID, planID, time.Now(), time.Now().AddDate(0, 1, 0), 0, 100)
+
+	var taken string
+	if err := pool.QueryRow(ctx, `SELECT subscription_reference FROM agent_subscriptions`).Scan(&taken); err != nil {
+		t.Fatalf("failed to read seeded reference: %v", err)
+	}
+
+	// The first candidate collides with the seeded subscription's reference
+	candidates := []string{taken, "SUB-20260101000000-FRESH1"}
+	svc.newReference = func() string {
+		reference := candidates[0]
+		candidates = candidates[1:]
+		return reference
+	}
+
+	agentID := testutil.Identity(t, pool, "references@example.com")
+	sub, err := svc.CreateSubscription(ctx, agentID, &subscription.CreateSubscriptionRequest{
+		SubscriptionPlanID: planID,
+		AmountPaid:         1000,
+		Currency:           "KES",
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if sub.SubscriptionReference != "SUB-20260101000000-FRESH1" {
+		t.Errorf("reference = %s, want the retried SUB-20260101000000-FRESH1", sub.SubscriptionReference)
+	}
+
+	records, err := svc.subscriptionRepo.ListBillingRecords(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("ListBillingRecords: %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("got %d billing records, want 1 from the successful attempt", len(records))
+	}
+}
@@ -0,0 +1,162 @@
+// internal/service/subscription/renewal_reminder.go
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/emaillog"
+	"bingwa-service/internal/domain/subscription"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/service/email"
+
+	"go.uber.org/zap"
+)
+
+// RenewalReminderWorker emails agents whose subscriptions are about to expire
+// and bills overage for subscriptions whose period has ended
+type RenewalReminderWorker struct {
+	subscriptionSvc  *SubscriptionService
+	subscriptionRepo *postgres.AgentSubscriptionRepository
+	planRepo         *postgres.SubscriptionPlanRepository
+	authRepo         *postgres.AuthRepository
+	sender           *email.EmailSender
+	days             int
+	interval         time.Duration
+	logger           *zap.Logger
+}
+
+func NewRenewalReminderWorker(
+	subscriptionSvc *SubscriptionService,
+	subscriptionRepo *postgres.AgentSubscriptionRepository,
+	planRepo *postgres.SubscriptionPlanRepository,
+	authRepo *postgres.AuthRepository,
+	sender *email.EmailSender,
+	days int,
+	interval time.Duration,
+	logger *zap.Logger,
+) *RenewalReminderWorker {
+	return &RenewalReminderWorker{
+		subscriptionSvc:  subscriptionSvc,
+		subscriptionRepo: subscriptionRepo,
+		planRepo:         planRepo,
+		authRepo:         authRepo,
+		sender:           sender,
+		days:             days,
+		interval:         interval,
+		logger:           logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *RenewalReminderWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("renewal reminder run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends reminders for subscriptions expiring within the configured window and returns how many were sent
+func (w *RenewalReminderWorker) RunOnce(ctx context.Context) (int, error) {
+	billed, err := w.subscriptionSvc.BillEndedPeriodOverages(ctx)
+	if err != nil {
+		w.logger.Error("period overage billing failed", zap.Error(err))
+	} else if billed > 0 {
+		w.logger.Info("ended subscription periods settled", zap.Int("count", billed))
+	}
+
+	subscriptions, err := w.subscriptionRepo.GetExpiringSubscriptions(ctx, w.days)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expiring subscriptions: %w", err)
+	}
+
+	sent := 0
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+
+		reminded, err := w.remind(ctx, sub)
+		if err != nil {
+			w.logger.Warn("failed to send renewal reminder",
+				zap.Int64("subscription_id", sub.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if reminded {
+			sent++
+		}
+	}
+
+	if sent > 0 {
+		w.logger.Info("renewal reminders sent", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// remind claims the current period's reminder and emails the agent. It reports false without
+// sending when another run already claimed the period; a failed send gives the claim back.
+func (w *RenewalReminderWorker) remind(ctx context.Context, sub *subscription.AgentSubscription) (bool, error) {
+	identity, err := w.authRepo.FindIdentityByID(ctx, sub.AgentIdentityID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find agent: %w", err)
+	}
+	if !identity.Email.Valid || identity.Email.String == "" {
+		return false, fmt.Errorf("agent has no email address")
+	}
+
+	key := reminderKey(sub)
+	claimed, err := w.subscriptionRepo.ClaimRenewalReminder(ctx, sub.ID, key)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	fullName := "there"
+	if profile, err := w.authRepo.GetUserProfile(ctx, sub.AgentIdentityID); err == nil && profile.FullName.Valid {
+		fullName = profile.FullName.String
+	}
+
+	planName := "your plan"
+	if plan, err := w.planRepo.FindByID(ctx, sub.SubscriptionPlanID); err == nil {
+		planName = plan.Name
+	}
+
+	subject, body := renewalReminderEmail(fullName, planName, sub)
+	if err := w.sender.SendAs(emaillog.TypeRenewalReminder, identity.Email.String, subject, body); err != nil {
+		if releaseErr := w.subscriptionRepo.ReleaseRenewalReminder(ctx, sub.ID, key); releaseErr != nil {
+			w.logger.Warn("failed to release renewal reminder", zap.Int64("subscription_id", sub.ID), zap.Error(releaseErr))
+		}
+		return false, fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return true, nil
+}
+
+// reminderKey identifies the billing period a reminder belongs to
+func reminderKey(sub *subscription.AgentSubscription) string {
+	return sub.CurrentPeriodEnd.UTC().Format(time.RFC3339)
+}
+
+// renewalReminderEmail builds the renewal reminder email
+func renewalReminderEmail(fullName, planName string, sub *subscription.AgentSubscription) (string, string) {
+	subject := "Your subscription is expiring soon"
+	body := fmt.Sprintf(`
+		<h2>Subscription Renewal Reminder</h2>
+		<p>Hello %s,</p>
+		<p>Your <strong>%s</strong> subscription (%s) expires on <strong>%s</strong>.</p>
+		<p>Renew before then to keep your offers and schedules running without interruption.</p>
+	`, fullName, planName, sub.SubscriptionReference, sub.CurrentPeriodEnd.Format("02 Jan 2006 15:04"))
+
+	return subject, body
+}
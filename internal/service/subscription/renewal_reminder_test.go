@@ -0,0 +1,52 @@
+// internal/service/subscription/renewal_reminder_test.go
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/service/email"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestRenewalReminderSentOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+	smtp := testutil.SMTP(t)
+
+	planID := seedPlan(t, pool, "reminders", 1000, 100, nil)
+	agentID := testutil.Identity(t, pool, "reminders@example.com")
+	end := time.Now().Add(48 * time.Hour)
+	seedSubscription(t, pool, agentID, planID, end.AddDate(0, -1, 0), end, 0, 100)
+
+	worker := NewRenewalReminderWorker(
+		svc, svc.subscriptionRepo, svc.planRepo, svc.authRepo,
+		email.NewEmailSender(smtp.Host, smtp.Port, "noreply@example.com", "secret", "Bingwa", false),
+		3, time.Hour, zap.NewNop(),
+	)
+
+	// Two workers running at once claim the period's reminder only once
+	sent := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			n, err := worker.RunOnce(ctx)
+			if err != nil {
+				t.Errorf("RunOnce: %v", err)
+			}
+			sent <- n
+		}()
+	}
+	if total := <-sent + <-sent; total != 1 {
+		t.Errorf("concurrent runs sent %d reminders, want 1", total)
+	}
+
+	if n, err := worker.RunOnce(ctx); err != nil || n != 0 {
+		t.Errorf("next run = %d, %v; want no reminder", n, err)
+	}
+	if got := smtp.Delivered(); len(got) != 1 || got[0] != "reminders@example.com" {
+		t.Errorf("delivered = %v, want one reminder to reminders@example.com", got)
+	}
+}
@@ -0,0 +1,173 @@
+// internal/service/subscription/sub_agents.go
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bingwa-service/internal/domain/subscription"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// AddSubAgent invites a child agent to have its usage count against the parent agent's subscription.
+// The link stays pending, and costs the parent nothing, until the child accepts it.
+// Sub-agents are one level deep; the database rejects links that would nest them.
+func (s *SubscriptionService) AddSubAgent(ctx context.Context, parentID, childID int64) (*subscription.SubAgent, error) {
+	if err := s.checkSubAgentLink(ctx, parentID, childID); err != nil {
+		return nil, err
+	}
+
+	subAgent, err := s.subscriptionRepo.AddSubAgent(ctx, parentID, childID, subscription.SubAgentStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.notifService.SendInfoNotification(ctx, childID, "Subscription sharing invitation",
+		"Another agent has invited you to use their subscription. Accept it to have your requests count against their plan.",
+		map[string]interface{}{
+			"parent_agent_id": parentID,
+			"action":          "accept_sub_agent_invite",
+		}); err != nil {
+		s.logger.Warn("failed to notify invited sub-agent", zap.Int64("child_agent_id", childID), zap.Error(err))
+	}
+
+	s.logger.Info("sub-agent invited",
+		zap.Int64("parent_agent_id", parentID),
+		zap.Int64("child_agent_id", childID),
+	)
+
+	return subAgent, nil
+}
+
+// AdminLinkSubAgent links a child agent to a parent straight away, without the child's acceptance
+func (s *SubscriptionService) AdminLinkSubAgent(ctx context.Context, parentID, childID, adminID int64) (*subscription.SubAgent, error) {
+	if err := s.checkSubAgentLink(ctx, parentID, childID); err != nil {
+		return nil, err
+	}
+
+	subAgent, err := s.subscriptionRepo.AddSubAgent(ctx, parentID, childID, subscription.SubAgentStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("sub-agent linked by admin",
+		zap.Int64("parent_agent_id", parentID),
+		zap.Int64("child_agent_id", childID),
+		zap.Int64("admin_id", adminID),
+	)
+
+	return subAgent, nil
+}
+
+// AcceptSubAgentInvite activates the caller's pending link, so its usage counts against the parent's subscription
+func (s *SubscriptionService) AcceptSubAgentInvite(ctx context.Context, childID int64) (*subscription.SubAgent, error) {
+	if err := s.checkNoOwnSubscription(ctx, childID); err != nil {
+		return nil, err
+	}
+
+	subAgent, err := s.subscriptionRepo.AcceptSubAgent(ctx, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("sub-agent invite accepted",
+		zap.Int64("parent_agent_id", subAgent.ParentAgentID),
+		zap.Int64("child_agent_id", childID),
+	)
+
+	return subAgent, nil
+}
+
+// GetParentLink retrieves the caller's link to a parent agent, pending or active
+func (s *SubscriptionService) GetParentLink(ctx context.Context, childID int64) (*subscription.SubAgent, error) {
+	return s.subscriptionRepo.FindParentLink(ctx, childID)
+}
+
+// LeaveParent lets a child agent decline an invite or stop sharing its parent's subscription
+func (s *SubscriptionService) LeaveParent(ctx context.Context, childID int64) error {
+	if err := s.subscriptionRepo.RemoveParentLink(ctx, childID); err != nil {
+		return err
+	}
+
+	s.logger.Info("sub-agent left parent", zap.Int64("child_agent_id", childID))
+
+	return nil
+}
+
+// RemoveSubAgent stops a child agent's usage counting against the parent's subscription
+func (s *SubscriptionService) RemoveSubAgent(ctx context.Context, parentID, childID int64) error {
+	if err := s.subscriptionRepo.RemoveSubAgent(ctx, parentID, childID); err != nil {
+		return err
+	}
+
+	s.logger.Info("sub-agent removed",
+		zap.Int64("parent_agent_id", parentID),
+		zap.Int64("child_agent_id", childID),
+	)
+
+	return nil
+}
+
+// ListSubAgents retrieves the agents sharing, or invited to share, the parent's subscription
+func (s *SubscriptionService) ListSubAgents(ctx context.Context, parentID int64) ([]subscription.SubAgent, error) {
+	return s.subscriptionRepo.ListSubAgents(ctx, parentID)
+}
+
+// checkSubAgentLink validates a proposed parent-child link: both must be agents, the parent must have
+// a subscription to share and the child must not have its own
+func (s *SubscriptionService) checkSubAgentLink(ctx context.Context, parentID, childID int64) error {
+	if parentID == childID {
+		return fmt.Errorf("an agent can't be its own sub-agent: %w", xerrors.ErrInvalidInput)
+	}
+
+	for _, id := range []int64{parentID, childID} {
+		isAgent, err := s.authRepo.IsAgent(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !isAgent {
+			return fmt.Errorf("agent %d not found: %w", id, xerrors.ErrNotFound)
+		}
+	}
+
+	if _, err := s.subscriptionRepo.FindActiveByAgent(ctx, parentID); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return fmt.Errorf("no active subscription to share: %w", xerrors.ErrInvalidInput)
+		}
+		return err
+	}
+
+	return s.checkNoOwnSubscription(ctx, childID)
+}
+
+// checkNoOwnSubscription refuses to put an agent with its own active subscription under a parent
+func (s *SubscriptionService) checkNoOwnSubscription(ctx context.Context, childID int64) error {
+	_, err := s.subscriptionRepo.FindActiveByAgent(ctx, childID)
+	switch {
+	case err == nil:
+		return fmt.Errorf("agent has its own active subscription: %w", xerrors.ErrConflict)
+	case errors.Is(err, xerrors.ErrNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// usageSubscription returns the active subscription an agent's requests count against: the agent's
+// own when it has one, otherwise its parent's through an accepted sub-agent link
+func (s *SubscriptionService) usageSubscription(ctx context.Context, agentID int64) (*subscription.AgentSubscription, error) {
+	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	if err == nil || !errors.Is(err, xerrors.ErrNotFound) {
+		return sub, err
+	}
+
+	parentID, err := s.subscriptionRepo.FindParentAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.subscriptionRepo.FindActiveByAgent(ctx, parentID)
+}
@@ -3,15 +3,24 @@ package subscription
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strings"
 	"time"
 
+	domaincampaign "bingwa-service/internal/domain/campaign"
 	"bingwa-service/internal/domain/subscription"
 	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/pagination"
 	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
+	notificationsvc "bingwa-service/internal/service/notification"
 
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -19,14 +28,29 @@ type SubscriptionService struct {
 	subscriptionRepo *postgres.AgentSubscriptionRepository
 	planRepo         *postgres.SubscriptionPlanRepository
 	campaignRepo     *postgres.PromotionalCampaignRepository
+	requestRepo      *postgres.OfferRequestRepository
+	configService    *configsvc.ConfigService
+	notifService     *notificationsvc.NotificationService
+	authRepo         *postgres.AuthRepository
 	db               *postgres.DB
 	logger           *zap.Logger
+
+	renewalPricing subscription.RenewalPricing
+	usagePolicy    subscription.UsagePolicy
+	newReference   func() string
 }
 
+// maxReferenceAttempts bounds the retries when a new subscription's reference is already taken
+const maxReferenceAttempts = 5
+
 func NewSubscriptionService(
 	subscriptionRepo *postgres.AgentSubscriptionRepository,
 	planRepo *postgres.SubscriptionPlanRepository,
 	campaignRepo *postgres.PromotionalCampaignRepository,
+	requestRepo *postgres.OfferRequestRepository,
+	configService *configsvc.ConfigService,
+	notifService *notificationsvc.NotificationService,
+	authRepo *postgres.AuthRepository,
 	db *postgres.DB,
 	logger *zap.Logger,
 ) *SubscriptionService {
@@ -34,8 +58,37 @@ func NewSubscriptionService(
 		subscriptionRepo: subscriptionRepo,
 		planRepo:         planRepo,
 		campaignRepo:     campaignRepo,
+		requestRepo:      requestRepo,
+		configService:    configService,
+		notifService:     notifService,
+		authRepo:         authRepo,
 		db:               db,
 		logger:           logger,
+		renewalPricing:   subscription.DefaultRenewalPricing,
+		usagePolicy:      subscription.DefaultUsagePolicy,
+		newReference:     generateSubscriptionReference,
+	}
+}
+
+// SetRenewalPricing configures whether renewals keep a subscriber's grandfathered price
+// or move them to the plan's current price; unknown values keep the default
+func (s *SubscriptionService) SetRenewalPricing(pricing subscription.RenewalPricing) {
+	switch pricing {
+	case subscription.RenewalPricingGrandfathered, subscription.RenewalPricingCurrent:
+		s.renewalPricing = pricing
+	default:
+		s.renewalPricing = subscription.DefaultRenewalPricing
+	}
+}
+
+// SetUsagePolicy configures whether a mid-cycle plan change keeps or resets request usage;
+// unknown values keep the default
+func (s *SubscriptionService) SetUsagePolicy(policy subscription.UsagePolicy) {
+	switch policy {
+	case subscription.UsagePolicyKeep, subscription.UsagePolicyReset:
+		s.usagePolicy = policy
+	default:
+		s.usagePolicy = subscription.DefaultUsagePolicy
 	}
 }
 
@@ -56,6 +109,11 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, agentID in
 		return nil, fmt.Errorf("subscription plan is not available for subscription")
 	}
 
+	req.Currency, err = resolvePlanCurrency(plan, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if agent already has active subscription
 	existingSub, _ := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
 	if existingSub != nil {
@@ -71,7 +129,7 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, agentID in
 
 	// Apply promotional code if provided
 	if req.PromotionalCode != "" {
-		discount, campID, err := s.applyPromotionalCode(ctx, req.PromotionalCode, plan.ID, planPrice)
+		discount, campID, err := s.applyPromotionalCode(ctx, agentID, req.PromotionalCode, plan.ID, planPrice)
 		if err != nil {
 			s.logger.Warn("failed to apply promotional code", zap.Error(err))
 		} else {
@@ -97,12 +155,8 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, agentID in
 		nextBilling = sql.NullTime{Time: periodEnd, Valid: true}
 	}
 
-	// Generate unique reference
-	subRef := s.generateSubscriptionReference()
-
 	// Create subscription entity
 	sub := &subscription.AgentSubscription{
-		SubscriptionReference: subRef,
 		AgentIdentityID:       agentID,
 		SubscriptionPlanID:    req.SubscriptionPlanID,
 		StartDate:             startDate,
@@ -113,6 +167,7 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, agentID in
 		NextBillingDate:       nextBilling,
 		RequestsUsed:          0,
 		PlanPrice:             planPrice,
+		PlanVersion:           plan.Version,
 		DiscountApplied:       discountAmount,
 		AmountPaid:            req.AmountPaid,
 		Currency:              strings.ToUpper(req.Currency),
@@ -138,40 +193,73 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, agentID in
 		sub.Metadata["setup_fee"] = setupFee
 	}
 
-	// Execute in transaction
+	// A reference taken by another subscription fails the unique constraint; retry with a fresh one
+	for attempt := 1; ; attempt++ {
+		sub.SubscriptionReference = s.newReference()
+		err = s.insertSubscription(ctx, sub, plan, setupFee, req.PaymentReference, campaignID)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, xerrors.ErrDuplicateEntry) || attempt == maxReferenceAttempts {
+			return nil, err
+		}
+		s.logger.Warn("subscription reference collision, retrying", zap.String("subscription_reference", sub.SubscriptionReference))
+	}
+
+	s.logger.Info("subscription created",
+		zap.Int64("subscription_id", sub.ID),
+		zap.String("subscription_reference", sub.SubscriptionReference),
+		zap.Int64("agent_id", agentID),
+		zap.Int64("plan_id", req.SubscriptionPlanID),
+		zap.String("billing_cycle", string(plan.BillingCycle)),
+		zap.Int("usage_limit", plan.BillingUsage),
+	)
+
+	return sub, nil
+}
+
+// insertSubscription stores a new subscription with its billing record and campaign use in one transaction
+func (s *SubscriptionService) insertSubscription(ctx context.Context, sub *subscription.AgentSubscription, plan *subscription.SubscriptionPlan, setupFee float64, paymentReference string, campaignID *int64) error {
 	tx, err := s.db.BeginTx(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Create subscription
 	if err := s.subscriptionRepo.CreateWithTx(ctx, tx, sub); err != nil {
-		return nil, fmt.Errorf("failed to create subscription: %w", err)
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	if err := s.subscriptionRepo.CreateBillingRecordWithTx(ctx, tx, &subscription.BillingRecord{
+		SubscriptionID:        sub.ID,
+		AgentIdentityID:       sub.AgentIdentityID,
+		Event:                 subscription.BillingEventSubscribed,
+		SubscriptionPlanID:    plan.ID,
+		PeriodStart:           sub.CurrentPeriodStart,
+		PeriodEnd:             sub.CurrentPeriodEnd,
+		PlanPrice:             sub.PlanPrice,
+		SetupFee:              setupFee,
+		DiscountApplied:       sub.DiscountApplied,
+		AmountPaid:            sub.AmountPaid,
+		Currency:              sub.Currency,
+		PromotionalCampaignID: sub.PromotionalCampaignID,
+		PaymentReference:      sql.NullString{String: paymentReference, Valid: paymentReference != ""},
+	}); err != nil {
+		return err
 	}
 
-	// Increment campaign usage if applicable
+	// Count the campaign use and its discount against the budget
 	if campaignID != nil {
-		if err := s.campaignRepo.IncrementUses(ctx, *campaignID); err != nil {
-			s.logger.Warn("failed to increment campaign uses", zap.Error(err))
+		if err := s.recordCampaignUseWithTx(ctx, tx, sub.AgentIdentityID, *campaignID, sub.DiscountApplied); err != nil {
+			return err
 		}
 	}
 
-	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
-	s.logger.Info("subscription created",
-		zap.Int64("subscription_id", sub.ID),
-		zap.String("subscription_reference", sub.SubscriptionReference),
-		zap.Int64("agent_id", agentID),
-		zap.Int64("plan_id", req.SubscriptionPlanID),
-		zap.String("billing_cycle", string(plan.BillingCycle)),
-		zap.Int("usage_limit", plan.BillingUsage),
-	)
-
-	return sub, nil
+	return nil
 }
 
 // RenewSubscription renews an existing subscription (from mobile USSD payment)
@@ -188,14 +276,19 @@ func (s *SubscriptionService) RenewSubscription(ctx context.Context, agentID int
 		return nil, fmt.Errorf("subscription plan not found: %w", err)
 	}
 
+	req.Currency, err = resolvePlanCurrency(plan, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate pricing (no setup fee for renewals)
-	planPrice := plan.Price
+	planPrice, planVersion := s.renewalPrice(currentSub, plan)
 	discountAmount := 0.0
 	var campaignID *int64
 
 	// Apply promotional code if provided
 	if req.PromotionalCode != "" {
-		discount, campID, err := s.applyPromotionalCode(ctx, req.PromotionalCode, plan.ID, planPrice)
+		discount, campID, err := s.applyPromotionalCode(ctx, agentID, req.PromotionalCode, plan.ID, planPrice)
 		if err != nil {
 			s.logger.Warn("failed to apply promotional code", zap.Error(err))
 		} else {
@@ -229,20 +322,56 @@ func (s *SubscriptionService) RenewSubscription(ctx context.Context, agentID int
 	}
 	defer tx.Rollback(ctx)
 
+	// Serialize concurrent renewals; a renewal that lands first makes this one stale
+	lockedRenewalCount, err := s.subscriptionRepo.LockForRenewalWithTx(ctx, tx, currentSub.ID)
+	if err != nil {
+		return nil, err
+	}
+	if lockedRenewalCount != currentSub.RenewalCount {
+		return nil, fmt.Errorf("subscription already renewed: %w", xerrors.ErrConflict)
+	}
+
+	// Bill the ending period's overage and reset usage before the period moves on
+	if err := s.billOverageWithTx(ctx, tx, currentSub, plan); err != nil {
+		return nil, err
+	}
+
 	// Update renewal info
 	if err := s.subscriptionRepo.UpdateRenewalInfoWithTx(ctx, tx, currentSub.ID, newPeriodStart, newPeriodEnd, nextBilling, newRenewalCount); err != nil {
 		return nil, fmt.Errorf("failed to update renewal info: %w", err)
 	}
 
-	// Reset request usage counter for new billing cycle
-	if err := s.subscriptionRepo.ResetRequestUsage(ctx, currentSub.ID); err != nil {
-		s.logger.Warn("failed to reset request usage", zap.Error(err))
+	// Move the subscription onto the plan's current price
+	if planVersion != currentSub.PlanVersion {
+		if err := s.subscriptionRepo.UpdatePlanPricingWithTx(ctx, tx, currentSub.ID, planPrice, planVersion); err != nil {
+			return nil, err
+		}
 	}
 
-	// Increment campaign usage if applicable
+	renewal := &subscription.BillingRecord{
+		SubscriptionID:     currentSub.ID,
+		AgentIdentityID:    agentID,
+		Event:              subscription.BillingEventRenewed,
+		SubscriptionPlanID: plan.ID,
+		PeriodStart:        newPeriodStart,
+		PeriodEnd:          newPeriodEnd,
+		PlanPrice:          planPrice,
+		DiscountApplied:    discountAmount,
+		AmountPaid:         req.AmountPaid,
+		Currency:           strings.ToUpper(req.Currency),
+		PaymentReference:   sql.NullString{String: req.PaymentReference, Valid: req.PaymentReference != ""},
+	}
 	if campaignID != nil {
-		if err := s.campaignRepo.IncrementUses(ctx, *campaignID); err != nil {
-			s.logger.Warn("failed to increment campaign uses", zap.Error(err))
+		renewal.PromotionalCampaignID = sql.NullInt64{Int64: *campaignID, Valid: true}
+	}
+	if err := s.subscriptionRepo.CreateBillingRecordWithTx(ctx, tx, renewal); err != nil {
+		return nil, err
+	}
+
+	// Count the campaign use and its discount against the budget
+	if campaignID != nil {
+		if err := s.recordCampaignUseWithTx(ctx, tx, agentID, *campaignID, discountAmount); err != nil {
+			return nil, err
 		}
 	}
 
@@ -261,6 +390,151 @@ func (s *SubscriptionService) RenewSubscription(ctx context.Context, agentID int
 	return s.subscriptionRepo.FindByID(ctx, currentSub.ID)
 }
 
+// renewalPrice returns the price and plan version a renewal charges. Subscribers on an older
+// plan version keep their price unless renewal pricing is set to current.
+func (s *SubscriptionService) renewalPrice(sub *subscription.AgentSubscription, plan *subscription.SubscriptionPlan) (float64, int) {
+	if s.renewalPricing == subscription.RenewalPricingGrandfathered && sub.PlanVersion < plan.Version {
+		return sub.PlanPrice, sub.PlanVersion
+	}
+	return plan.Price, plan.Version
+}
+
+// ChangePlan moves the agent's active subscription to another plan mid-cycle.
+// The requests limit always follows the new plan; usage is kept or reset per the configured usage policy.
+func (s *SubscriptionService) ChangePlan(ctx context.Context, agentID int64, req *subscription.ChangePlanRequest) (*subscription.AgentSubscription, error) {
+	currentSub, plan, err := s.loadPlanChange(ctx, agentID, req.NewPlanID)
+	if err != nil {
+		return nil, err
+	}
+	quote := quotePlanChange(currentSub, plan, time.Now())
+
+	resetUsage := s.usagePolicy == subscription.UsagePolicyReset
+
+	// Execute in transaction
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.subscriptionRepo.ChangePlanWithTx(ctx, tx, currentSub.ID, plan.ID, plan.Price, plan.Version, plan.BillingUsage, resetUsage); err != nil {
+		return nil, fmt.Errorf("failed to change plan: %w", err)
+	}
+
+	if err := s.subscriptionRepo.CreateBillingRecordWithTx(ctx, tx, &subscription.BillingRecord{
+		SubscriptionID:     currentSub.ID,
+		AgentIdentityID:    agentID,
+		Event:              subscription.BillingEventPlanChanged,
+		SubscriptionPlanID: plan.ID,
+		PeriodStart:        time.Now(),
+		PeriodEnd:          quote.NewPeriodEnd,
+		PlanPrice:          plan.Price,
+		AmountDue:          quote.NetCharge, // Invoiced; nothing has been collected for the change yet
+		Currency:           quote.Currency,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("subscription plan changed",
+		zap.Int64("subscription_id", currentSub.ID),
+		zap.Int64("agent_id", agentID),
+		zap.Int64("from_plan_id", currentSub.SubscriptionPlanID),
+		zap.Int64("to_plan_id", plan.ID),
+		zap.Int("usage_limit", plan.BillingUsage),
+		zap.String("usage_policy", string(s.usagePolicy)),
+		zap.Float64("proration_credit", quote.ProrationCredit),
+		zap.Float64("net_charge", quote.NetCharge),
+	)
+
+	if err := s.subscriptionRepo.MergeMetadata(ctx, currentSub.ID, map[string]interface{}{
+		metadataKeyLastPlanChange: quote,
+	}); err != nil {
+		s.logger.Warn("failed to record plan change quote", zap.Int64("subscription_id", currentSub.ID), zap.Error(err))
+	}
+
+	return s.subscriptionRepo.FindByID(ctx, currentSub.ID)
+}
+
+// PreviewPlanChange prices a plan change exactly as ChangePlan would, without persisting anything
+func (s *SubscriptionService) PreviewPlanChange(ctx context.Context, agentID, newPlanID int64) (*subscription.PlanChangePreview, error) {
+	currentSub, plan, err := s.loadPlanChange(ctx, agentID, newPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return quotePlanChange(currentSub, plan, time.Now()), nil
+}
+
+// loadPlanChange loads the agent's active subscription and validates the target plan
+func (s *SubscriptionService) loadPlanChange(ctx context.Context, agentID, newPlanID int64) (*subscription.AgentSubscription, *subscription.SubscriptionPlan, error) {
+	currentSub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no active subscription found: %w", err)
+	}
+
+	if currentSub.SubscriptionPlanID == newPlanID {
+		return nil, nil, fmt.Errorf("subscription is already on this plan")
+	}
+
+	plan, err := s.planRepo.FindByID(ctx, newPlanID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("subscription plan not found: %w", err)
+	}
+
+	currentPlan, err := s.planRepo.FindByID(ctx, currentSub.SubscriptionPlanID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("current subscription plan not found: %w", err)
+	}
+	if !currentPlan.AllowsTransitionTo(plan.ID) {
+		return nil, nil, fmt.Errorf("changing from plan %s to %s is not allowed: %w", currentPlan.PlanCode, plan.PlanCode, xerrors.ErrForbidden)
+	}
+
+	if plan.Status != subscription.StatusActive {
+		return nil, nil, fmt.Errorf("subscription plan is not active")
+	}
+
+	if !plan.IsPublic {
+		return nil, nil, fmt.Errorf("subscription plan is not available for subscription")
+	}
+
+	return currentSub, plan, nil
+}
+
+// quotePlanChange credits the unused share of the current period at the price it is billed at,
+// and charges the new plan for the same share
+func quotePlanChange(currentSub *subscription.AgentSubscription, plan *subscription.SubscriptionPlan, now time.Time) *subscription.PlanChangePreview {
+	periodLength := currentSub.CurrentPeriodEnd.Sub(currentSub.CurrentPeriodStart)
+	remaining := currentSub.CurrentPeriodEnd.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	fraction := 0.0
+	if periodLength > 0 {
+		fraction = math.Min(1, remaining.Seconds()/periodLength.Seconds())
+	}
+
+	credit := math.Round(currentSub.PlanPrice*fraction*100) / 100
+	charge := math.Round(plan.Price*fraction*100) / 100
+
+	return &subscription.PlanChangePreview{
+		CurrentPlanID:   currentSub.SubscriptionPlanID,
+		NewPlanID:       plan.ID,
+		NewPlanPrice:    plan.Price,
+		RemainingDays:   int(math.Ceil(remaining.Hours() / 24)),
+		ProrationCredit: credit,
+		ProratedCharge:  charge,
+		NetCharge:       math.Max(0, charge-credit),
+		Currency:        currentSub.Currency,
+		NewPeriodEnd:    currentSub.CurrentPeriodEnd,
+	}
+}
+
 // GetSubscription retrieves a subscription by ID
 func (s *SubscriptionService) GetSubscription(ctx context.Context, agentID, subscriptionID int64, isAdmin bool) (*subscription.AgentSubscription, error) {
 	sub, err := s.subscriptionRepo.FindByID(ctx, subscriptionID)
@@ -276,6 +550,32 @@ func (s *SubscriptionService) GetSubscription(ctx context.Context, agentID, subs
 	return sub, nil
 }
 
+// GetBillingHistory lists a subscription's charges, renewals and discounts in chronological order
+func (s *SubscriptionService) GetBillingHistory(ctx context.Context, agentID, subscriptionID int64) (*subscription.BillingHistoryResponse, error) {
+	sub, err := s.GetSubscription(ctx, agentID, subscriptionID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.subscriptionRepo.ListBillingRecords(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &subscription.BillingHistoryResponse{
+		SubscriptionID: sub.ID,
+		Entries:        records,
+		Currency:       sub.Currency,
+	}
+	for _, rec := range records {
+		history.TotalPaid += rec.AmountPaid
+		history.TotalDiscount += rec.DiscountApplied
+		history.TotalDue += rec.AmountDue
+	}
+
+	return history, nil
+}
+
 // GetActiveSubscription retrieves active subscription for an agent
 func (s *SubscriptionService) GetActiveSubscription(ctx context.Context, agentID int64) (*subscription.AgentSubscription, error) {
 	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
@@ -289,32 +589,21 @@ func (s *SubscriptionService) GetActiveSubscription(ctx context.Context, agentID
 // ListSubscriptions retrieves subscriptions with filters
 func (s *SubscriptionService) ListSubscriptions(ctx context.Context, agentID int64, filters *subscription.SubscriptionListFilters, isAdmin bool) (*subscription.SubscriptionListResponse, error) {
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
-	}
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	subscriptions, total, err := s.subscriptionRepo.List(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &subscription.SubscriptionListResponse{
 		Subscriptions: subscriptions,
-		Total:         total,
-		Page:          filters.Page,
-		PageSize:      filters.PageSize,
-		TotalPages:    totalPages,
+		Total:         meta.Total,
+		Page:          meta.Page,
+		PageSize:      meta.PageSize,
+		TotalPages:    meta.TotalPages,
 	}, nil
 }
 
@@ -336,6 +625,15 @@ func (s *SubscriptionService) UpdateSubscription(ctx context.Context, agentID, s
 		sub.AutoRenew = *req.AutoRenew
 	}
 	if req.Metadata != nil {
+		if !isAdmin {
+			// Custom limits are admin-granted; agents cannot set or drop them
+			for _, key := range []string{metadataKeyCustomRequestsLimit, metadataKeyCustomLimitSetBy, metadataKeyCustomLimitSetAt, metadataKeyLimitBehavior} {
+				delete(req.Metadata, key)
+				if v, ok := sub.Metadata[key]; ok {
+					req.Metadata[key] = v
+				}
+			}
+		}
 		sub.Metadata = req.Metadata
 	}
 
@@ -368,12 +666,29 @@ func (s *SubscriptionService) CancelSubscription(ctx context.Context, agentID, s
 		return fmt.Errorf("subscription is already cancelled")
 	}
 
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	// Cancel subscription
-	if err := s.subscriptionRepo.CancelSubscription(ctx, subscriptionID, req.Reason, req.CancelImmediately); err != nil {
+	if err := s.subscriptionRepo.CancelSubscriptionWithTx(ctx, tx, subscriptionID, req.Reason, req.CancelImmediately); err != nil {
 		s.logger.Error("failed to cancel subscription", zap.Error(err))
 		return fmt.Errorf("failed to cancel subscription: %w", err)
 	}
 
+	// An immediately cancelled subscription didn't stick, so give its promo use back
+	if req.CancelImmediately && sub.PromotionalCampaignID.Valid {
+		if err := s.campaignRepo.DecrementUsesWithTx(ctx, tx, sub.PromotionalCampaignID.Int64, sub.DiscountApplied); err != nil {
+			return fmt.Errorf("failed to restore campaign use: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	cancelType := "at period end"
 	if req.CancelImmediately {
 		cancelType = "immediately"
@@ -388,12 +703,13 @@ func (s *SubscriptionService) CancelSubscription(ctx context.Context, agentID, s
 	return nil
 }
 
-// GetSubscriptionUsage retrieves usage information for active subscription
+// GetSubscriptionUsage retrieves usage information for active subscription (the parent's for a sub-agent)
 func (s *SubscriptionService) GetSubscriptionUsage(ctx context.Context, agentID int64) (*subscription.SubscriptionUsageInfo, error) {
-	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	sub, err := s.usageSubscription(ctx, agentID)
 	if err != nil {
 		return nil, fmt.Errorf("no active subscription found: %w", err)
 	}
+	sub.RequestsLimit = effectiveRequestsLimit(sub)
 
 	// Get plan to check for overage charges
 	plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
@@ -401,9 +717,11 @@ func (s *SubscriptionService) GetSubscriptionUsage(ctx context.Context, agentID
 		return nil, fmt.Errorf("plan not found: %w", err)
 	}
 
+	behavior := effectiveLimitBehavior(sub, plan)
 	usage := &subscription.SubscriptionUsageInfo{
 		SubscriptionID: sub.ID,
 		RequestsUsed:   sub.RequestsUsed,
+		LimitBehavior:  behavior,
 	}
 
 	// Calculate remaining requests
@@ -412,9 +730,9 @@ func (s *SubscriptionService) GetSubscriptionUsage(ctx context.Context, agentID
 		usage.RequestsRemaining = usage.RequestsLimit - usage.RequestsUsed
 		
 		if usage.RequestsRemaining < 0 {
-			// Over limit - check if overage is allowed
+			// Over limit - only the overage behavior is billed
 			usage.RequestsRemaining = 0
-			if plan.OverageCharge.Valid {
+			if behavior == subscription.LimitBehaviorOverage && plan.OverageCharge.Valid {
 				// Calculate overage charges
 				overageCount := usage.RequestsUsed - usage.RequestsLimit
 				overageAmount := float64(overageCount) * plan.OverageCharge.Float64
@@ -451,21 +769,13 @@ func (s *SubscriptionService) GetSubscriptionUsage(ctx context.Context, agentID
 	}
 
 	// Check if can make requests
-	// Allow if within limit OR if overage is allowed
+	// Allow if within limit OR if the limit behavior lets requests past it
 	canMakeRequests := sub.Status == subscription.SubscriptionStatusActive &&
 		sub.CurrentPeriodEnd.After(now)
 	
-	if sub.RequestsLimit.Valid {
-		if usage.RequestsUsed < int(sub.RequestsLimit.Int32) {
-			// Within limit
-			canMakeRequests = canMakeRequests && true
-		} else if plan.OverageCharge.Valid {
-			// Over limit but overage allowed
-			canMakeRequests = canMakeRequests && true
-		} else {
-			// Over limit and no overage allowed
-			canMakeRequests = false
-		}
+	if sub.RequestsLimit.Valid && usage.RequestsUsed >= int(sub.RequestsLimit.Int32) &&
+		behavior == subscription.LimitBehaviorBlock {
+		canMakeRequests = false
 	}
 	
 	usage.CanMakeRequests = canMakeRequests
@@ -473,41 +783,52 @@ func (s *SubscriptionService) GetSubscriptionUsage(ctx context.Context, agentID
 	return usage, nil
 }
 
-// IncrementRequestUsage increments request usage counter
+// IncrementRequestUsage increments request usage counter; a sub-agent's usage counts against its parent's subscription
 func (s *SubscriptionService) IncrementRequestUsage(ctx context.Context, agentID int64) error {
-	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	sub, err := s.usageSubscription(ctx, agentID)
 	if err != nil {
 		return fmt.Errorf("no active subscription found: %w", err)
 	}
+	sub.RequestsLimit = effectiveRequestsLimit(sub)
 
-	// Get plan to check overage
+	// Get plan to resolve the limit behavior
 	plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
 	if err != nil {
 		return fmt.Errorf("plan not found: %w", err)
 	}
 
-	// Check if limit reached and overage not allowed
+	// Check if limit reached and the behavior blocks further requests
 	if sub.RequestsLimit.Valid && sub.RequestsUsed >= int(sub.RequestsLimit.Int32) {
-		if !plan.OverageCharge.Valid {
-			return fmt.Errorf("request limit reached and overage not allowed")
+		behavior := effectiveLimitBehavior(sub, plan)
+		if behavior == subscription.LimitBehaviorBlock {
+			return fmt.Errorf("request limit reached: %w", xerrors.ErrRateLimited)
 		}
-		// Overage allowed - log warning
+		// Warn or overage - allow and log
 		s.logger.Warn("request usage over limit",
 			zap.Int64("subscription_id", sub.ID),
+			zap.String("limit_behavior", string(behavior)),
 			zap.Int("requests_used", sub.RequestsUsed),
 			zap.Int("requests_limit", int(sub.RequestsLimit.Int32)),
 		)
 	}
 
-	return s.subscriptionRepo.IncrementRequestUsage(ctx, sub.ID)
+	used, err := s.subscriptionRepo.IncrementRequestUsage(ctx, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	s.sendUsageAlert(ctx, sub, used)
+
+	return nil
 }
 
-// CheckSubscriptionAccess checks if agent has active subscription access
+// CheckSubscriptionAccess checks if agent has active subscription access, directly or through a parent agent
 func (s *SubscriptionService) CheckSubscriptionAccess(ctx context.Context, agentID int64) (bool, error) {
-	sub, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID)
+	sub, err := s.usageSubscription(ctx, agentID)
 	if err != nil {
 		return false, nil // No active subscription
 	}
+	sub.RequestsLimit = effectiveRequestsLimit(sub)
 
 	// Check if active and not expired
 	if sub.Status != subscription.SubscriptionStatusActive {
@@ -518,19 +839,17 @@ func (s *SubscriptionService) CheckSubscriptionAccess(ctx context.Context, agent
 		return false, nil
 	}
 
-	// Get plan to check overage
+	// Get plan to resolve the limit behavior
 	plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
 	if err != nil {
 		return false, nil
 	}
 
-	// Check request limit
+	// Check request limit; only the block behavior denies access at the limit
 	if sub.RequestsLimit.Valid && sub.RequestsUsed >= int(sub.RequestsLimit.Int32) {
-		// Check if overage is allowed
-		if !plan.OverageCharge.Valid {
+		if effectiveLimitBehavior(sub, plan) == subscription.LimitBehaviorBlock {
 			return false, nil
 		}
-		// Overage allowed
 	}
 
 	return true, nil
@@ -546,6 +865,32 @@ func (s *SubscriptionService) GetSubscriptionStats(ctx context.Context, agentID
 	return stats, nil
 }
 
+// GetCancellationReasons retrieves cancellation reason frequencies for an agent
+func (s *SubscriptionService) GetCancellationReasons(ctx context.Context, agentID int64, filters *subscription.CancellationReasonFilters) (*subscription.CancellationReasonsResponse, error) {
+	reasons, err := s.subscriptionRepo.GetCancellationReasons(ctx, agentID, filters.DateFrom, filters.DateTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cancellation reasons: %w", err)
+	}
+
+	var total int64
+	for _, reason := range reasons {
+		total += reason.Count
+	}
+
+	for i := range reasons {
+		if total > 0 {
+			reasons[i].Percentage = float64(reasons[i].Count) / float64(total) * 100
+		}
+	}
+
+	return &subscription.CancellationReasonsResponse{
+		Reasons:            reasons,
+		TotalCancellations: total,
+		DateFrom:           filters.DateFrom,
+		DateTo:             filters.DateTo,
+	}, nil
+}
+
 // GetExpiringSubscriptions retrieves subscriptions expiring soon
 func (s *SubscriptionService) GetExpiringSubscriptions(ctx context.Context, days int) ([]subscription.AgentSubscription, error) {
 	if days < 1 {
@@ -563,6 +908,113 @@ func (s *SubscriptionService) GetExpiringSubscriptions(ctx context.Context, days
 	return subscriptions, nil
 }
 
+// recommendationWindowDays is how far back request volume is analyzed for plan recommendations
+const recommendationWindowDays = 90
+
+const (
+	// metadataKeyCustomRequestsLimit holds an admin-granted request limit that overrides the plan's
+	metadataKeyCustomRequestsLimit = "custom_requests_limit"
+	metadataKeyCustomLimitSetBy    = "custom_requests_limit_set_by"
+	metadataKeyCustomLimitSetAt    = "custom_requests_limit_set_at"
+
+	// metadataKeyLimitBehavior holds an admin-set limit behavior that overrides the plan's
+	metadataKeyLimitBehavior = "limit_behavior"
+
+	// metadataKeyLastPlanChange holds the proration quote applied by the latest plan change
+	metadataKeyLastPlanChange = "last_plan_change"
+)
+
+// RecommendPlan recommends the cheapest public plan for the agent's projected usage, including overage costs.
+// Agents with no request history are recommended the entry (cheapest) plan.
+func (s *SubscriptionService) RecommendPlan(ctx context.Context, agentID int64) (*subscription.PlanRecommendation, error) {
+	isPublic := true
+	status := subscription.StatusActive
+	plans, _, err := s.planRepo.List(ctx, &subscription.PlanListFilters{
+		Status:    &status,
+		IsPublic:  &isPublic,
+		Page:      1,
+		PageSize:  pagination.MaxPageSize,
+		SortBy:    "price",
+		SortOrder: "asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plans: %w", err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("no plans available")
+	}
+
+	now := time.Now()
+	count, first, err := s.requestRepo.GetRequestVolume(ctx, agentID, now.AddDate(0, 0, -recommendationWindowDays))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &subscription.PlanRecommendation{
+		Candidates: []subscription.PlanCostEstimate{},
+	}
+	if current, err := s.subscriptionRepo.FindActiveByAgent(ctx, agentID); err == nil && current != nil {
+		rec.CurrentPlanID = &current.SubscriptionPlanID
+	}
+
+	if count == 0 {
+		entry := &plans[0]
+		rec.RecommendedPlan = entry
+		rec.Reason = "no recent request history; recommending the entry plan"
+		rec.EstimatedMonthlyCost = entry.Price * 30 / float64(billingCycleDays(entry.BillingCycle))
+		rec.IsChange = rec.CurrentPlanID == nil || *rec.CurrentPlanID != entry.ID
+		return rec, nil
+	}
+
+	// Average over the days the agent has actually been active in the window
+	historyDays := int(now.Sub(first.Time).Hours()/24) + 1
+	if historyDays > recommendationWindowDays {
+		historyDays = recommendationWindowDays
+	}
+	rec.HistoryDays = historyDays
+	rec.AverageDailyRequests = float64(count) / float64(historyDays)
+
+	var best *subscription.PlanCostEstimate
+	var bestPlan *subscription.SubscriptionPlan
+	for i := range plans {
+		estimate, viable := estimatePlanCost(&plans[i], rec.AverageDailyRequests)
+		rec.Candidates = append(rec.Candidates, estimate)
+		if !viable {
+			continue
+		}
+
+		if best == nil || estimate.EstimatedMonthlyCost < best.EstimatedMonthlyCost ||
+			(estimate.EstimatedMonthlyCost == best.EstimatedMonthlyCost && estimate.BillingUsage > best.BillingUsage) {
+			e := estimate
+			best = &e
+			bestPlan = &plans[i]
+		}
+	}
+
+	if bestPlan == nil {
+		// Nothing covers the usage and no plan allows overage; fall back to the largest allowance
+		bestPlan = &plans[0]
+		for i := range plans {
+			if plans[i].BillingUsage > bestPlan.BillingUsage {
+				bestPlan = &plans[i]
+			}
+		}
+		estimate, _ := estimatePlanCost(bestPlan, rec.AverageDailyRequests)
+		best = &estimate
+		rec.Reason = "projected usage exceeds every plan; recommending the plan with the highest allowance"
+	} else if best.CoversUsage {
+		rec.Reason = "cheapest plan that covers projected usage"
+	} else {
+		rec.Reason = "cheapest plan for projected usage including overage charges"
+	}
+
+	rec.RecommendedPlan = bestPlan
+	rec.EstimatedMonthlyCost = best.EstimatedMonthlyCost
+	rec.IsChange = rec.CurrentPlanID == nil || *rec.CurrentPlanID != bestPlan.ID
+
+	return rec, nil
+}
+
 // ========== Admin Operations ==========
 
 // DeactivateSubscription deactivates a subscription (admin only)
@@ -613,33 +1065,182 @@ func (s *SubscriptionService) ReactivateSubscription(ctx context.Context, subscr
 	}
 	defer tx.Rollback(ctx)
 
+	// Hold the renewal lock so a renewal cannot move the period while it is being reset
+	if _, err := s.subscriptionRepo.LockForRenewalWithTx(ctx, tx, subscriptionID); err != nil {
+		return err
+	}
+
+	// Read after locking so the period reflects any renewal that committed first
+	sub, err := s.subscriptionRepo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	// A lapsed period would grant access that has already run out, so start a fresh one
+	now := time.Now()
+	periodLapsed := sub.CurrentPeriodEnd.Before(now)
+
 	if err := s.subscriptionRepo.UpdateStatusWithTx(ctx, tx, subscriptionID, subscription.SubscriptionStatusActive); err != nil {
 		return fmt.Errorf("failed to reactivate subscription: %w", err)
 	}
 
+	if periodLapsed {
+		plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
+		if err != nil {
+			return fmt.Errorf("plan not found: %w", err)
+		}
+
+		// Usage belongs to the lapsed period, so clear it before the period moves on
+		if err := s.subscriptionRepo.ResetRequestUsageWithTx(ctx, tx, subscriptionID); err != nil {
+			return fmt.Errorf("failed to reset request usage: %w", err)
+		}
+
+		periodEnd := s.calculatePeriodEnd(now, plan.BillingCycle)
+		if err := s.subscriptionRepo.UpdateRenewalInfoWithTx(ctx, tx, subscriptionID, now, periodEnd, periodEnd, sub.RenewalCount); err != nil {
+			return fmt.Errorf("failed to reset subscription period: %w", err)
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	s.logger.Info("subscription reactivated by admin", zap.Int64("subscription_id", subscriptionID))
+	s.logger.Info("subscription reactivated by admin",
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Bool("period_reset", periodLapsed),
+	)
 	return nil
 }
 
+// SetCustomLimit overrides the plan's request limit for a subscription; a nil limit clears the override (admin only)
+func (s *SubscriptionService) SetCustomLimit(ctx context.Context, adminID, subscriptionID int64, limit *int) (*subscription.AgentSubscription, error) {
+	if limit != nil && *limit < 0 {
+		return nil, fmt.Errorf("custom limit cannot be negative")
+	}
+
+	values := map[string]interface{}{
+		metadataKeyCustomRequestsLimit: nil,
+		metadataKeyCustomLimitSetBy:    adminID,
+		metadataKeyCustomLimitSetAt:    time.Now().Format(time.RFC3339),
+	}
+	if limit != nil {
+		values[metadataKeyCustomRequestsLimit] = *limit
+	}
+
+	if err := s.subscriptionRepo.MergeMetadata(ctx, subscriptionID, values); err != nil {
+		return nil, fmt.Errorf("failed to set custom limit: %w", err)
+	}
+
+	s.logger.Info("subscription custom limit set by admin",
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Int64("admin_id", adminID),
+		zap.Any("custom_limit", limit),
+	)
+
+	return s.subscriptionRepo.FindByID(ctx, subscriptionID)
+}
+
+// SetLimitBehavior overrides the plan's limit behavior for a subscription; a nil behavior clears the override (admin only)
+func (s *SubscriptionService) SetLimitBehavior(ctx context.Context, adminID, subscriptionID int64, behavior *subscription.LimitBehavior) (*subscription.AgentSubscription, error) {
+	sub, err := s.subscriptionRepo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if behavior != nil {
+		if !behavior.IsValid() {
+			return nil, fmt.Errorf("invalid limit behavior %q: %w", *behavior, xerrors.ErrInvalidInput)
+		}
+		if *behavior == subscription.LimitBehaviorOverage {
+			plan, err := s.planRepo.FindByID(ctx, sub.SubscriptionPlanID)
+			if err != nil {
+				return nil, fmt.Errorf("plan not found: %w", err)
+			}
+			if !plan.OverageCharge.Valid {
+				return nil, fmt.Errorf("plan has no overage charge: %w", xerrors.ErrInvalidInput)
+			}
+		}
+		value = string(*behavior)
+	}
+
+	if err := s.subscriptionRepo.MergeMetadata(ctx, subscriptionID, map[string]interface{}{metadataKeyLimitBehavior: value}); err != nil {
+		return nil, fmt.Errorf("failed to set limit behavior: %w", err)
+	}
+
+	s.logger.Info("subscription limit behavior set by admin",
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Int64("admin_id", adminID),
+		zap.Any("limit_behavior", behavior),
+	)
+
+	return s.subscriptionRepo.FindByID(ctx, subscriptionID)
+}
+
+// AdminGetCancellationReasons retrieves cancellation reason frequencies across all agents (admin only)
+func (s *SubscriptionService) AdminGetCancellationReasons(ctx context.Context, filters *subscription.CancellationReasonFilters) (*subscription.CancellationReasonsResponse, error) {
+	return s.GetCancellationReasons(ctx, 0, filters)
+}
+
 // ========== Helper Methods ==========
 
-// generateSubscriptionReference generates unique subscription reference
-func (s *SubscriptionService) generateSubscriptionReference() string {
+// effectiveRequestsLimit returns the admin-granted custom limit from metadata, falling back to the plan-derived limit
+func effectiveRequestsLimit(sub *subscription.AgentSubscription) sql.NullInt32 {
+	switch v := sub.Metadata[metadataKeyCustomRequestsLimit].(type) {
+	case float64:
+		return sql.NullInt32{Int32: int32(v), Valid: true}
+	case int:
+		return sql.NullInt32{Int32: int32(v), Valid: true}
+	}
+	return sub.RequestsLimit
+}
+
+// effectiveLimitBehavior returns the admin-set limit behavior from metadata, falling back to the plan's
+func effectiveLimitBehavior(sub *subscription.AgentSubscription, plan *subscription.SubscriptionPlan) subscription.LimitBehavior {
+	if v, ok := sub.Metadata[metadataKeyLimitBehavior].(string); ok {
+		if behavior := subscription.LimitBehavior(v); behavior.IsValid() {
+			return behavior
+		}
+	}
+	return plan.EffectiveLimitBehavior()
+}
+
+// generateSubscriptionReference generates a subscription reference. Uniqueness is enforced on insert.
+func generateSubscriptionReference() string {
+	// Format: SUB-{TIMESTAMP}-{RANDOM}
+	// Example: SUB-20240115103000-A3B2C1
 	timestamp := time.Now().Format("20060102150405")
 	random := generateRandomString(6)
 	return fmt.Sprintf("SUB-%s-%s", timestamp, random)
 }
 
-// applyPromotionalCode applies promotional code and returns discount amount
-func (s *SubscriptionService) applyPromotionalCode(ctx context.Context, code string, planID int64, basePrice float64) (float64, *int64, error) {
+// resolvePlanCurrency returns the currency a plan payment is made in. A blank currency
+// defaults to the plan's; any other currency than the plan's is rejected.
+func resolvePlanCurrency(plan *subscription.SubscriptionPlan, currency string) (string, error) {
+	currency = strings.TrimSpace(currency)
+	if currency == "" {
+		return plan.Currency, nil
+	}
+	if !strings.EqualFold(currency, plan.Currency) {
+		return "", fmt.Errorf("currency %s does not match plan currency %s: %w", strings.ToUpper(currency), plan.Currency, xerrors.ErrInvalidInput)
+	}
+	return strings.ToUpper(currency), nil
+}
+
+// applyPromotionalCode applies promotional code and returns discount amount. An A/B tested
+// campaign gives the agent their variant's discount value.
+func (s *SubscriptionService) applyPromotionalCode(ctx context.Context, agentID int64, code string, planID int64, basePrice float64) (float64, *int64, error) {
 	campaign, err := s.campaignRepo.FindByPromotionalCode(ctx, code)
 	if err != nil {
 		return 0, nil, fmt.Errorf("promotional code not found: %w", err)
 	}
+	variant, err := s.assignedVariant(ctx, campaign, agentID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if variant != nil {
+		campaign.DiscountValue = variant.DiscountValue
+	}
 
 	// Check if campaign is active
 	now := time.Now()
@@ -683,9 +1284,68 @@ func (s *SubscriptionService) applyPromotionalCode(ctx context.Context, code str
 		discount = basePrice // Full discount
 	}
 
+	// Check budget; a discount larger than what is left is rejected, but the campaign stays
+	// active for smaller discounts until the budget is actually spent
+	if !campaign.BudgetAllows(discount) {
+		if campaign.DeactivateOnBudgetSpent && campaign.BudgetSpent() {
+			if err := s.campaignRepo.UpdateStatus(ctx, campaign.ID, domaincampaign.CampaignStatusInactive); err != nil {
+				s.logger.Warn("failed to deactivate campaign with spent budget", zap.Int64("campaign_id", campaign.ID), zap.Error(err))
+			}
+		}
+		return 0, nil, fmt.Errorf("promotional code budget is insufficient for this discount")
+	}
+
 	return discount, &campaign.ID, nil
 }
 
+// recordCampaignUseWithTx counts a campaign use and its discount, logging when the campaign's budget runs out.
+// Uses of an A/B tested campaign also count as a conversion for the agent's variant.
+func (s *SubscriptionService) recordCampaignUseWithTx(ctx context.Context, tx pgx.Tx, agentID, campaignID int64, discount float64) error {
+	status, err := s.campaignRepo.RecordUseWithTx(ctx, tx, campaignID, discount)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.campaignRepo.FindByID(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	variant, err := s.assignedVariant(ctx, c, agentID)
+	if err != nil {
+		return err
+	}
+	if variant != nil {
+		if err := s.campaignRepo.RecordVariantConversionWithTx(ctx, tx, campaignID, agentID, variant.Name, discount); err != nil {
+			return err
+		}
+	}
+
+	if status == domaincampaign.CampaignStatusInactive {
+		s.logger.Info("campaign deactivated after spending its budget", zap.Int64("campaign_id", campaignID))
+	}
+
+	return nil
+}
+
+// assignedVariant returns the agent's stored variant of an A/B tested campaign, assigning one on first use,
+// so the discount and the conversion follow the variant the agent was shown. Nil without variants.
+func (s *SubscriptionService) assignedVariant(ctx context.Context, c *domaincampaign.PromotionalCampaign, agentID int64) (*domaincampaign.CampaignVariant, error) {
+	picked := c.VariantFor(agentID)
+	if picked == nil {
+		return nil, nil
+	}
+
+	stored, err := s.campaignRepo.AssignVariant(ctx, c.ID, agentID, picked.Name)
+	if err != nil {
+		return nil, err
+	}
+	if v := c.VariantNamed(stored); v != nil {
+		return v, nil
+	}
+	// The stored variant has since been removed from the campaign
+	return picked, nil
+}
+
 // calculatePeriodEnd calculates period end date based on billing cycle
 func (s *SubscriptionService) calculatePeriodEnd(start time.Time, cycle subscription.RenewalPeriod) time.Time {
 	switch cycle {
@@ -704,12 +1364,65 @@ func (s *SubscriptionService) calculatePeriodEnd(start time.Time, cycle subscrip
 	}
 }
 
+// billingCycleDays approximates the length of a billing cycle in days
+func billingCycleDays(cycle subscription.RenewalPeriod) int {
+	switch cycle {
+	case subscription.RenewalDaily:
+		return 1
+	case subscription.RenewalWeekly:
+		return 7
+	case subscription.RenewalQuarterly:
+		return 90
+	case subscription.RenewalYearly:
+		return 365
+	default:
+		return 30
+	}
+}
+
+// estimatePlanCost projects a plan's cost at the given daily request rate.
+// A plan is not viable if usage exceeds its allowance and it has no overage charge.
+func estimatePlanCost(plan *subscription.SubscriptionPlan, dailyRequests float64) (subscription.PlanCostEstimate, bool) {
+	cycleDays := billingCycleDays(plan.BillingCycle)
+	projected := int(math.Ceil(dailyRequests * float64(cycleDays)))
+
+	estimate := subscription.PlanCostEstimate{
+		PlanID:            plan.ID,
+		PlanName:          plan.Name,
+		BillingUsage:      plan.BillingUsage,
+		ProjectedRequests: projected,
+		CoversUsage:       projected <= plan.BillingUsage,
+	}
+
+	cycleCost := plan.Price
+	if !estimate.CoversUsage {
+		estimate.OverageRequests = projected - plan.BillingUsage
+		switch plan.EffectiveLimitBehavior() {
+		case subscription.LimitBehaviorBlock:
+			return estimate, false
+		case subscription.LimitBehaviorOverage:
+			if plan.OverageCharge.Valid {
+				cycleCost += float64(estimate.OverageRequests) * plan.OverageCharge.Float64
+			}
+		}
+	}
+
+	estimate.EstimatedCycleCost = cycleCost
+	estimate.EstimatedMonthlyCost = cycleCost * 30 / float64(cycleDays)
+	return estimate, true
+}
+
+// generateRandomString returns length characters drawn uniformly from crypto/rand
 func generateRandomString(length int) string {
 	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
+	size := big.NewInt(int64(len(charset)))
 	for i := range result {
-		result[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-		time.Sleep(time.Nanosecond)
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic(fmt.Sprintf("crypto/rand failed: %v", err))
+		}
+		result[i] = charset[n.Int64()]
 	}
 	return string(result)
 }
\ No newline at end of file
@@ -0,0 +1,802 @@
+// internal/service/subscription/subscription_service_test.go
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/campaign"
+	"bingwa-service/internal/domain/subscription"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/testutil"
+)
+
+func TestQuotePlanChange(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+
+	tests := []struct {
+		name          string
+		currentPrice  float64
+		newPrice      float64
+		now           time.Time
+		wantDays      int
+		wantCredit    float64
+		wantCharge    float64
+		wantNetCharge float64
+	}{
+		{"upgrade half way", 1000, 2000, start.AddDate(0, 0, 15), 15, 500, 1000, 500},
+		{"downgrade half way", 2000, 1000, start.AddDate(0, 0, 15), 15, 1000, 500, 0},
+		{"upgrade at period start", 1000, 2000, start, 30, 1000, 2000, 1000},
+		{"upgrade after period end", 1000, 2000, end.Add(time.Hour), 0, 0, 0, 0},
+		{"upgrade from a free plan", 0, 1500, start.AddDate(0, 0, 20), 10, 0, 500, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currentSub := &subscription.AgentSubscription{
+				SubscriptionPlanID: 1,
+				CurrentPeriodStart: start,
+				CurrentPeriodEnd:   end,
+				PlanPrice:          tt.currentPrice,
+				Currency:           "KES",
+			}
+			plan := &subscription.SubscriptionPlan{ID: 2, Price: tt.newPrice}
+
+			got := quotePlanChange(currentSub, plan, tt.now)
+			if got.RemainingDays != tt.wantDays {
+				t.Errorf("RemainingDays = %d, want %d", got.RemainingDays, tt.wantDays)
+			}
+			if got.ProrationCredit != tt.wantCredit {
+				t.Errorf("ProrationCredit = %v, want %v", got.ProrationCredit, tt.wantCredit)
+			}
+			if got.ProratedCharge != tt.wantCharge {
+				t.Errorf("ProratedCharge = %v, want %v", got.ProratedCharge, tt.wantCharge)
+			}
+			if got.NetCharge != tt.wantNetCharge {
+				t.Errorf("NetCharge = %v, want %v", got.NetCharge, tt.wantNetCharge)
+			}
+			if got.CurrentPlanID != 1 || got.NewPlanID != 2 || !got.NewPeriodEnd.Equal(end) {
+				t.Errorf("quote = %+v, want plan 1 -> 2 ending %v", got, end)
+			}
+		})
+	}
+}
+
+func TestSetUsagePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy subscription.UsagePolicy
+		want   subscription.UsagePolicy
+	}{
+		{"keep", subscription.UsagePolicyKeep, subscription.UsagePolicyKeep},
+		{"reset", subscription.UsagePolicyReset, subscription.UsagePolicyReset},
+		{"unknown value", "refill", subscription.DefaultUsagePolicy},
+		{"unset", "", subscription.DefaultUsagePolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SubscriptionService{}
+			s.SetUsagePolicy(tt.policy)
+			if s.usagePolicy != tt.want {
+				t.Errorf("SetUsagePolicy(%q) = %s, want %s", tt.policy, s.usagePolicy, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangePlanAppliesLimitAndUsagePolicy(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	basicID := seedPlan(t, pool, "basic", 1000, 100, nil)
+	proID := seedPlan(t, pool, "pro", 2000, 500, nil)
+	start := time.Now().AddDate(0, 0, -10)
+
+	for _, tt := range []struct {
+		policy   subscription.UsagePolicy
+		wantUsed int
+	}{
+		{subscription.UsagePolicyKeep, 80},
+		{subscription.UsagePolicyReset, 0},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			agentID := testutil.Identity(t, pool, string(tt.policy)+"@example.com")
+			seedSubscription(t, pool, agentID, basicID, start, start.AddDate(0, 1, 0), 80, 100)
+			svc.SetUsagePolicy(tt.policy)
+
+			sub, err := svc.ChangePlan(ctx, agentID, &subscription.ChangePlanRequest{NewPlanID: proID})
+			if err != nil {
+				t.Fatalf("ChangePlan: %v", err)
+			}
+			if !sub.RequestsLimit.Valid || sub.RequestsLimit.Int32 != 500 {
+				t.Errorf("requests_limit = %v, want the new plan's 500 mid-cycle", sub.RequestsLimit)
+			}
+			if sub.RequestsUsed != tt.wantUsed {
+				t.Errorf("requests_used = %d, want %d", sub.RequestsUsed, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestConcurrentRenewalsAdvancePeriodOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "renewals", 1000, 100, nil)
+	agentID := testutil.Identity(t, pool, "renewals@example.com")
+	start := time.Now().AddDate(0, -1, 0)
+	subID := seedSubscription(t, pool, agentID, planID, start, start.AddDate(0, 1, 0), 0, 100)
+
+	// Hold the renewal lock until every renewal has read the subscription and is queued behind it
+	holder, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	defer holder.Rollback(ctx)
+	if _, err := svc.subscriptionRepo.LockForRenewalWithTx(ctx, holder, subID); err != nil {
+		t.Fatalf("LockForRenewalWithTx: %v", err)
+	}
+
+	const attempts = 5
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.RenewSubscription(ctx, agentID, &subscription.RenewSubscriptionRequest{AmountPaid: 1000, Currency: "KES"})
+			errs <- err
+		}()
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var waiting int
+		err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM pg_locks
+			WHERE locktype = 'advisory' AND objsubid = 2 AND objid = $1::bigint::oid AND NOT granted
+		`, subID).Scan(&waiting)
+		if err != nil {
+			t.Fatalf("failed to read pg_locks: %v", err)
+		}
+		if waiting == attempts {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d renewals waiting on the lock, want %d", waiting, attempts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	holder.Rollback(ctx)
+
+	wg.Wait()
+	close(errs)
+
+	renewed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			renewed++
+		case !errors.Is(err, xerrors.ErrConflict):
+			t.Errorf("RenewSubscription error = %v, want nil or ErrConflict", err)
+		}
+	}
+	if renewed != 1 {
+		t.Errorf("%d renewals succeeded, want 1", renewed)
+	}
+
+	sub, err := svc.subscriptionRepo.FindByID(ctx, subID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if sub.RenewalCount != 1 {
+		t.Errorf("renewal_count = %d, want 1", sub.RenewalCount)
+	}
+}
+
+func TestResolvePlanCurrency(t *testing.T) {
+	plan := &subscription.SubscriptionPlan{Currency: "KES"}
+
+	tests := []struct {
+		currency string
+		want     string
+		wantErr  bool
+	}{
+		{"KES", "KES", false},
+		{"kes", "KES", false},
+		{"", "KES", false},
+		{"  ", "KES", false},
+		{"USD", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolvePlanCurrency(plan, tt.currency)
+		if tt.wantErr {
+			if !errors.Is(err, xerrors.ErrInvalidInput) {
+				t.Errorf("resolvePlanCurrency(%q) error = %v, want ErrInvalidInput", tt.currency, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolvePlanCurrency(%q) = %q, %v; want %q", tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestCreateSubscriptionRejectsCurrencyOtherThanPlans(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "kes-plan", 1000, 100, nil)
+	agentID := testutil.Identity(t, pool, "currency@example.com")
+
+	_, err := svc.CreateSubscription(ctx, agentID, &subscription.CreateSubscriptionRequest{
+		SubscriptionPlanID: planID,
+		AmountPaid:         10,
+		Currency:           "USD",
+	})
+	if !errors.Is(err, xerrors.ErrInvalidInput) {
+		t.Fatalf("USD payment for a KES plan error = %v, want ErrInvalidInput", err)
+	}
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM agent_subscriptions WHERE agent_identity_id = $1`, agentID).Scan(&count); err != nil {
+		t.Fatalf("failed to count subscriptions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d subscriptions created for a rejected payment, want 0", count)
+	}
+
+	// Leaving the currency out pays in the plan's
+	sub, err := svc.CreateSubscription(ctx, agentID, &subscription.CreateSubscriptionRequest{
+		SubscriptionPlanID: planID,
+		AmountPaid:         1000,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscription without a currency: %v", err)
+	}
+	if sub.Currency != "KES" {
+		t.Errorf("currency = %q, want the plan's KES", sub.Currency)
+	}
+}
+
+func TestAdminGetCancellationReasonsAggregates(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "cancellations", 1000, 100, nil)
+	now := time.Now()
+	for i, reason := range []string{"Too expensive", " too expensive", "Switching provider"} {
+		agentID := testutil.Identity(t, pool, fmt.Sprintf("cancel%d@example.com", i))
+		subID := seedSubscription(t, pool, agentID, planID, now, now.AddDate(0, 1, 0), 0, 100)
+		if err := svc.CancelSubscription(ctx, agentID, subID, &subscription.CancelSubscriptionRequest{Reason: reason}, false); err != nil {
+			t.Fatalf("CancelSubscription: %v", err)
+		}
+	}
+
+	result, err := svc.AdminGetCancellationReasons(ctx, &subscription.CancellationReasonFilters{})
+	if err != nil {
+		t.Fatalf("AdminGetCancellationReasons: %v", err)
+	}
+	if result.TotalCancellations != 3 {
+		t.Errorf("total cancellations = %d, want 3", result.TotalCancellations)
+	}
+
+	// Reasons differing only in case and spacing count together, most frequent first
+	want := []subscription.CancellationReasonStat{
+		{Reason: "too expensive", Count: 2},
+		{Reason: "switching provider", Count: 1},
+	}
+	if len(result.Reasons) != len(want) {
+		t.Fatalf("reasons = %+v, want %+v", result.Reasons, want)
+	}
+	for i, w := range want {
+		got := result.Reasons[i]
+		if got.Reason != w.Reason || got.Count != w.Count {
+			t.Errorf("reason %d = %s x%d, want %s x%d", i, got.Reason, got.Count, w.Reason, w.Count)
+		}
+	}
+	if p := result.Reasons[0].Percentage; p < 66.6 || p > 66.7 {
+		t.Errorf("top reason percentage = %v, want 2 of 3", p)
+	}
+}
+
+func TestRecommendPlanUpgradesAgentConsistentlyOverLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	basicID := seedPlan(t, pool, "basic", 500, 100, nil)
+	proID := seedPlan(t, pool, "pro", 1500, 1000, nil)
+	seedPlan(t, pool, "enterprise", 5000, 10000, nil)
+
+	agentID := testutil.Identity(t, pool, "busy@example.com")
+	now := time.Now()
+	seedSubscription(t, pool, agentID, basicID, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20), 100, 100)
+
+	// Ten requests a day for the last 30 days is three times the basic allowance
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO offer_requests (
+			request_reference, offer_id, agent_identity_id, customer_phone, payment_method, amount_paid, status, request_time
+		)
+		SELECT 'REQ-VOLUME-' || n, $1, $2, '254712345678', 'mpesa', 50, 'success',
+		       NOW() - ((n % 30) * INTERVAL '1 day')
+		FROM generate_series(1, 300) AS n
+	`, offerID, agentID); err != nil {
+		t.Fatalf("failed to seed request history: %v", err)
+	}
+
+	rec, err := svc.RecommendPlan(ctx, agentID)
+	if err != nil {
+		t.Fatalf("RecommendPlan: %v", err)
+	}
+	if rec.RecommendedPlan == nil || rec.RecommendedPlan.ID != proID {
+		t.Fatalf("recommended %+v, want the pro plan", rec.RecommendedPlan)
+	}
+	if !rec.IsChange || rec.CurrentPlanID == nil || *rec.CurrentPlanID != basicID {
+		t.Errorf("is_change = %v from %v, want a change from the basic plan", rec.IsChange, rec.CurrentPlanID)
+	}
+	if rec.HistoryDays != 30 || rec.AverageDailyRequests != 10 {
+		t.Errorf("history = %d days at %.2f a day, want 30 at 10", rec.HistoryDays, rec.AverageDailyRequests)
+	}
+	if rec.EstimatedMonthlyCost != 1500 {
+		t.Errorf("estimated monthly cost = %.2f, want 1500", rec.EstimatedMonthlyCost)
+	}
+	for _, c := range rec.Candidates {
+		if c.PlanID == basicID && c.CoversUsage {
+			t.Errorf("basic plan reported as covering %d requests", c.ProjectedRequests)
+		}
+	}
+}
+
+func TestCustomLimitOverridesPlanLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "capped", 500, 100, nil)
+	agentID := testutil.Identity(t, pool, "custom@example.com")
+	adminID := testutil.Identity(t, pool, "admin@example.com")
+	now := time.Now()
+	subID := seedSubscription(t, pool, agentID, planID, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20), 100, 100)
+
+	if ok, _ := svc.CheckSubscriptionAccess(ctx, agentID); ok {
+		t.Fatal("access allowed at the plan limit, want it blocked")
+	}
+
+	limit := 250
+	if _, err := svc.SetCustomLimit(ctx, adminID, subID, &limit); err != nil {
+		t.Fatalf("SetCustomLimit: %v", err)
+	}
+	if ok, _ := svc.CheckSubscriptionAccess(ctx, agentID); !ok {
+		t.Error("access blocked under the custom limit")
+	}
+	usage, err := svc.GetSubscriptionUsage(ctx, agentID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionUsage: %v", err)
+	}
+	if usage.RequestsLimit != 250 || usage.RequestsRemaining != 150 {
+		t.Errorf("usage = %d limit, %d remaining; want 250 and 150", usage.RequestsLimit, usage.RequestsRemaining)
+	}
+
+	// Clearing the override falls back to the plan's limit
+	if _, err := svc.SetCustomLimit(ctx, adminID, subID, nil); err != nil {
+		t.Fatalf("SetCustomLimit clear: %v", err)
+	}
+	if ok, _ := svc.CheckSubscriptionAccess(ctx, agentID); ok {
+		t.Error("access allowed after the custom limit was cleared")
+	}
+
+	negative := -1
+	if _, err := svc.SetCustomLimit(ctx, adminID, subID, &negative); err == nil {
+		t.Error("SetCustomLimit accepted a negative limit")
+	}
+}
+
+func TestImmediateCancelRestoresCampaignUse(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "promo-plan", 1000, 100, nil)
+	agentID := testutil.Identity(t, pool, "promo@example.com")
+	now := time.Now()
+
+	var campaignID int64
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO promotional_campaigns (
+			campaign_code, name, promotional_code, discount_type, discount_value,
+			start_date, end_date, current_uses, discount_given
+		)
+		VALUES ('CAMP-LAUNCH', 'Launch', 'LAUNCH10', 'fixed_amount', 100, $1, $2, 1, 100)
+		RETURNING id
+	`, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0)).Scan(&campaignID); err != nil {
+		t.Fatalf("failed to seed campaign: %v", err)
+	}
+
+	promoSubscription := func(agentID int64) int64 {
+		t.Helper()
+		id := seedSubscription(t, pool, agentID, planID, now, now.AddDate(0, 1, 0), 0, 100)
+		if _, err := pool.Exec(ctx, `
+			UPDATE agent_subscriptions SET promotional_campaign_id = $2, discount_applied = 100 WHERE id = $1
+		`, id, campaignID); err != nil {
+			t.Fatalf("failed to attach campaign: %v", err)
+		}
+		return id
+	}
+
+	readCampaign := func() (uses int, given float64) {
+		t.Helper()
+		if err := pool.QueryRow(ctx, `
+			SELECT current_uses, discount_given FROM promotional_campaigns WHERE id = $1
+		`, campaignID).Scan(&uses, &given); err != nil {
+			t.Fatalf("failed to read campaign: %v", err)
+		}
+		return uses, given
+	}
+
+	// Cancelling at period end keeps the use, the subscription still runs its course
+	laterID := testutil.Identity(t, pool, "later@example.com")
+	err := svc.CancelSubscription(ctx, laterID, promoSubscription(laterID), &subscription.CancelSubscriptionRequest{
+		Reason: "moving on",
+	}, false)
+	if err != nil {
+		t.Fatalf("CancelSubscription at period end: %v", err)
+	}
+	if uses, given := readCampaign(); uses != 1 || given != 100 {
+		t.Errorf("campaign after period-end cancel = %d uses, %.2f given; want 1 and 100", uses, given)
+	}
+
+	err = svc.CancelSubscription(ctx, agentID, promoSubscription(agentID), &subscription.CancelSubscriptionRequest{
+		Reason:            "changed my mind",
+		CancelImmediately: true,
+	}, false)
+	if err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+	if uses, given := readCampaign(); uses != 0 || given != 0 {
+		t.Errorf("campaign after immediate cancel = %d uses, %.2f given; want 0 and 0", uses, given)
+	}
+}
+
+func TestReactivateLapsedSubscriptionStartsFreshPeriod(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "monthly-plan", 1000, 100, nil)
+	now := time.Now()
+
+	lapsedAgent := testutil.Identity(t, pool, "lapsed@example.com")
+	lapsedID := seedSubscription(t, pool, lapsedAgent, planID, now.AddDate(0, -2, 0), now.AddDate(0, -1, 0), 80, 100)
+	currentAgent := testutil.Identity(t, pool, "current@example.com")
+	oldStart, oldEnd := now.AddDate(0, 0, -10), now.AddDate(0, 0, 20)
+	currentID := seedSubscription(t, pool, currentAgent, planID, oldStart, oldEnd, 40, 100)
+	if _, err := pool.Exec(ctx, `
+		UPDATE agent_subscriptions SET status = 'suspended' WHERE id = ANY($1)
+	`, []int64{lapsedID, currentID}); err != nil {
+		t.Fatalf("failed to suspend subscriptions: %v", err)
+	}
+
+	for _, id := range []int64{lapsedID, currentID} {
+		if err := svc.ReactivateSubscription(ctx, id); err != nil {
+			t.Fatalf("ReactivateSubscription(%d): %v", id, err)
+		}
+	}
+
+	sub, err := svc.subscriptionRepo.FindByID(ctx, lapsedID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if sub.Status != subscription.SubscriptionStatusActive {
+		t.Errorf("lapsed subscription status = %s, want active", sub.Status)
+	}
+	if sub.CurrentPeriodStart.Before(now.Add(-time.Minute)) || !sub.CurrentPeriodEnd.After(now.AddDate(0, 0, 27)) {
+		t.Errorf("lapsed subscription period = %v to %v, want a month starting now", sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+	}
+	if sub.RequestsUsed != 0 {
+		t.Errorf("lapsed subscription requests used = %d, want 0", sub.RequestsUsed)
+	}
+
+	// A period that is still running is kept as it was
+	sub, err = svc.subscriptionRepo.FindByID(ctx, currentID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if sub.Status != subscription.SubscriptionStatusActive {
+		t.Errorf("current subscription status = %s, want active", sub.Status)
+	}
+	if sub.CurrentPeriodEnd.Sub(oldEnd).Abs() > time.Second || sub.RequestsUsed != 40 {
+		t.Errorf("current subscription = period end %v with %d used, want %v with 40", sub.CurrentPeriodEnd, sub.RequestsUsed, oldEnd)
+	}
+}
+
+func TestLimitBehaviorAtTheLimit(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	charge := 2.0
+	now := time.Now()
+	tests := []struct {
+		behavior      subscription.LimitBehavior
+		allowedAtCap  bool
+		overageBilled bool
+	}{
+		{subscription.LimitBehaviorBlock, false, false},
+		{subscription.LimitBehaviorWarn, true, false},
+		{subscription.LimitBehaviorOverage, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.behavior), func(t *testing.T) {
+			planID := seedPlan(t, pool, "plan-"+string(tt.behavior), 500, 100, &charge)
+			if _, err := pool.Exec(ctx, `UPDATE subscription_plans SET limit_behavior = $2 WHERE id = $1`, planID, string(tt.behavior)); err != nil {
+				t.Fatalf("failed to set limit behavior: %v", err)
+			}
+			agentID := testutil.Identity(t, pool, string(tt.behavior)+"@example.com")
+			subID := seedSubscription(t, pool, agentID, planID, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0), 99, 100)
+			setUsed := func(used int) {
+				t.Helper()
+				if _, err := pool.Exec(ctx, `UPDATE agent_subscriptions SET requests_used = $2 WHERE id = $1`, subID, used); err != nil {
+					t.Fatalf("failed to set usage: %v", err)
+				}
+			}
+
+			if ok, _ := svc.CheckSubscriptionAccess(ctx, agentID); !ok {
+				t.Error("access blocked one request below the limit")
+			}
+
+			setUsed(100)
+			if ok, _ := svc.CheckSubscriptionAccess(ctx, agentID); ok != tt.allowedAtCap {
+				t.Errorf("access at the limit = %v, want %v", ok, tt.allowedAtCap)
+			}
+
+			setUsed(101)
+			usage, err := svc.GetSubscriptionUsage(ctx, agentID)
+			if err != nil {
+				t.Fatalf("GetSubscriptionUsage: %v", err)
+			}
+			if usage.LimitBehavior != tt.behavior {
+				t.Errorf("usage limit behavior = %s, want %s", usage.LimitBehavior, tt.behavior)
+			}
+			if _, billed := usage.Metadata["overage_count"]; billed != tt.overageBilled {
+				t.Errorf("overage billed past the limit = %v (%v), want %v", billed, usage.Metadata, tt.overageBilled)
+			}
+		})
+	}
+}
+
+func TestChangePlanRejectsDisallowedTransition(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	basicID := seedPlan(t, pool, "basic", 1000, 100, nil)
+	proID := seedPlan(t, pool, "pro", 2000, 500, nil)
+	enterpriseID := seedPlan(t, pool, "enterprise", 5000, 2000, nil)
+	if _, err := pool.Exec(ctx, `UPDATE subscription_plans SET allowed_transitions = $2 WHERE id = $1`, basicID, []int64{proID}); err != nil {
+		t.Fatalf("failed to restrict transitions: %v", err)
+	}
+
+	agentID := testutil.Identity(t, pool, "transition@example.com")
+	start := time.Now().AddDate(0, 0, -10)
+	seedSubscription(t, pool, agentID, basicID, start, start.AddDate(0, 1, 0), 10, 100)
+
+	if _, err := svc.PreviewPlanChange(ctx, agentID, enterpriseID); !errors.Is(err, xerrors.ErrForbidden) {
+		t.Errorf("PreviewPlanChange to a disallowed plan = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.ChangePlan(ctx, agentID, &subscription.ChangePlanRequest{NewPlanID: enterpriseID}); !errors.Is(err, xerrors.ErrForbidden) {
+		t.Errorf("ChangePlan to a disallowed plan = %v, want ErrForbidden", err)
+	}
+
+	sub, err := svc.ChangePlan(ctx, agentID, &subscription.ChangePlanRequest{NewPlanID: proID})
+	if err != nil {
+		t.Fatalf("ChangePlan to an allowed plan: %v", err)
+	}
+	if sub.SubscriptionPlanID != proID {
+		t.Errorf("subscription plan = %d, want the pro plan %d", sub.SubscriptionPlanID, proID)
+	}
+}
+
+func TestRecordUseDeactivatesCampaignOnceBudgetIsSpent(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	now := time.Now()
+	var campaignID int64
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO promotional_campaigns (
+			campaign_code, name, promotional_code, discount_type, discount_value,
+			start_date, end_date, total_budget, deactivate_on_budget_spent
+		)
+		VALUES ('CAMP-BUDGET', 'Budget', 'BUDGET100', 'fixed_amount', 100, $1, $2, 250, TRUE)
+		RETURNING id
+	`, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0)).Scan(&campaignID); err != nil {
+		t.Fatalf("failed to seed campaign: %v", err)
+	}
+
+	recordUse := func(discount float64) (campaign.CampaignStatus, error) {
+		t.Helper()
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback(ctx)
+		status, err := svc.campaignRepo.RecordUseWithTx(ctx, tx, campaignID, discount)
+		if err == nil {
+			if err := tx.Commit(ctx); err != nil {
+				t.Fatalf("failed to commit: %v", err)
+			}
+		}
+		return status, err
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, err := recordUse(100); err != nil || status != campaign.CampaignStatusActive {
+			t.Fatalf("use %d = %s, %v; want the campaign still active", i+1, status, err)
+		}
+	}
+
+	// A discount that would overrun the budget is refused and changes nothing
+	if _, err := recordUse(100); err == nil {
+		t.Error("RecordUseWithTx overran the budget")
+	}
+
+	// The last 50 spends the budget exactly, switching the campaign off
+	if status, err := recordUse(50); err != nil || status != campaign.CampaignStatusInactive {
+		t.Errorf("budget-spending use = %s, %v; want the campaign inactive", status, err)
+	}
+
+	var uses int
+	var given float64
+	var status string
+	if err := pool.QueryRow(ctx, `
+		SELECT current_uses, discount_given, status::text FROM promotional_campaigns WHERE id = $1
+	`, campaignID).Scan(&uses, &given, &status); err != nil {
+		t.Fatalf("failed to read campaign: %v", err)
+	}
+	if uses != 3 || given != 250 || status != "inactive" {
+		t.Errorf("campaign = %d uses, %.2f given, %s; want 3, 250, inactive", uses, given, status)
+	}
+}
+
+func TestTwoRenewalsGiveTwoBillingEntries(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "billing", 1000, 100, nil)
+	agentID := testutil.Identity(t, pool, "billing@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	now := time.Now()
+	subID := seedSubscription(t, pool, agentID, planID, now.AddDate(0, -1, 0), now.Add(time.Hour), 0, 100)
+
+	for _, reference := range []string{"MPESA-1", "MPESA-2"} {
+		if _, err := svc.RenewSubscription(ctx, agentID, &subscription.RenewSubscriptionRequest{
+			AmountPaid:       1000,
+			Currency:         "KES",
+			PaymentReference: reference,
+		}); err != nil {
+			t.Fatalf("RenewSubscription(%s): %v", reference, err)
+		}
+	}
+
+	history, err := svc.GetBillingHistory(ctx, agentID, subID)
+	if err != nil {
+		t.Fatalf("GetBillingHistory: %v", err)
+	}
+	if len(history.Entries) != 2 {
+		t.Fatalf("billing history has %d entries, want 2", len(history.Entries))
+	}
+	for i, entry := range history.Entries {
+		if entry.Event != subscription.BillingEventRenewed || entry.PaymentReference.String != fmt.Sprintf("MPESA-%d", i+1) {
+			t.Errorf("entry %d = %s paid by %q, want renewal MPESA-%d", i, entry.Event, entry.PaymentReference.String, i+1)
+		}
+	}
+	// The second renewal picks up where the first left off
+	if first, second := history.Entries[0], history.Entries[1]; !second.PeriodStart.Equal(first.PeriodEnd) {
+		t.Errorf("second period starts %v, want the first's end %v", second.PeriodStart, first.PeriodEnd)
+	}
+	if history.TotalPaid != 2000 || history.Currency != "KES" {
+		t.Errorf("total paid = %.2f %s, want 2000 KES", history.TotalPaid, history.Currency)
+	}
+
+	if _, err := svc.GetBillingHistory(ctx, otherID, subID); err == nil {
+		t.Error("another agent read the billing history")
+	}
+}
+
+func TestSubAgentRequestCountsAgainstParentQuota(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	now := time.Now()
+	planID := seedPlan(t, pool, "shared", 500, 100, nil)
+	parentID := testutil.Identity(t, pool, "parent@example.com")
+	childID := testutil.Identity(t, pool, "child@example.com")
+	testutil.Role(t, pool, parentID, "user")
+	testutil.Role(t, pool, childID, "user")
+	subID := seedSubscription(t, pool, parentID, planID, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0), 10, 100)
+
+	// Before the link the child has no subscription of its own to draw on
+	if err := svc.IncrementRequestUsage(ctx, childID); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Fatalf("unlinked child IncrementRequestUsage error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := svc.AdminLinkSubAgent(ctx, parentID, childID, parentID); err != nil {
+		t.Fatalf("AdminLinkSubAgent: %v", err)
+	}
+	if err := svc.IncrementRequestUsage(ctx, childID); err != nil {
+		t.Fatalf("child IncrementRequestUsage: %v", err)
+	}
+
+	var used int
+	if err := pool.QueryRow(ctx, `SELECT requests_used FROM agent_subscriptions WHERE id = $1`, subID).Scan(&used); err != nil {
+		t.Fatalf("failed to read usage: %v", err)
+	}
+	if used != 11 {
+		t.Errorf("parent requests used = %d, want 11", used)
+	}
+
+	usage, err := svc.GetSubscriptionUsage(ctx, childID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionUsage: %v", err)
+	}
+	if usage.RequestsUsed != 11 {
+		t.Errorf("child sees %d requests used, want the parent's 11", usage.RequestsUsed)
+	}
+}
+
+func TestRenewalKeepsGrandfatheredPrice(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	planID := seedPlan(t, pool, "versioned", 1000, 100, nil)
+	now := time.Now()
+	keptID := testutil.Identity(t, pool, "kept@example.com")
+	movedID := testutil.Identity(t, pool, "moved@example.com")
+	keptSub := seedSubscription(t, pool, keptID, planID, now.AddDate(0, -1, 0), now.Add(time.Hour), 0, 100)
+	movedSub := seedSubscription(t, pool, movedID, planID, now.AddDate(0, -1, 0), now.Add(time.Hour), 0, 100)
+
+	// The plan's price goes up after both agents subscribed
+	if _, err := pool.Exec(ctx, `UPDATE subscription_plans SET price = 1500, version = 2 WHERE id = $1`, planID); err != nil {
+		t.Fatalf("failed to raise plan price: %v", err)
+	}
+	pricing := func(subID int64) (float64, int) {
+		t.Helper()
+		var price float64
+		var version int
+		if err := pool.QueryRow(ctx, `SELECT plan_price, plan_version FROM agent_subscriptions WHERE id = $1`, subID).Scan(&price, &version); err != nil {
+			t.Fatalf("failed to read subscription pricing: %v", err)
+		}
+		return price, version
+	}
+	renew := func(agentID int64, amount float64, reference string) error {
+		_, err := svc.RenewSubscription(ctx, agentID, &subscription.RenewSubscriptionRequest{
+			AmountPaid:       amount,
+			Currency:         "KES",
+			PaymentReference: reference,
+		})
+		return err
+	}
+
+	// By default the old price carries over
+	if err := renew(keptID, 1000, "MPESA-KEPT"); err != nil {
+		t.Fatalf("grandfathered RenewSubscription: %v", err)
+	}
+	if price, version := pricing(keptSub); price != 1000 || version != 1 {
+		t.Errorf("grandfathered renewal = %.2f at version %d, want 1000 at version 1", price, version)
+	}
+
+	// With current pricing the renewal moves to the new price
+	svc.SetRenewalPricing(subscription.RenewalPricingCurrent)
+	if err := renew(movedID, 1000, "MPESA-SHORT"); err == nil {
+		t.Error("renewal paying the old price succeeded under current pricing, want it rejected")
+	}
+	if err := renew(movedID, 1500, "MPESA-MOVED"); err != nil {
+		t.Fatalf("current-price RenewSubscription: %v", err)
+	}
+	if price, version := pricing(movedSub); price != 1500 || version != 2 {
+		t.Errorf("current-price renewal = %.2f at version %d, want 1500 at version 2", price, version)
+	}
+}
@@ -0,0 +1,76 @@
+// internal/service/subscription/usage_alerts.go
+package subscription
+
+import (
+	"context"
+	"fmt"
+
+	"bingwa-service/internal/domain/subscription"
+
+	"go.uber.org/zap"
+)
+
+// sendUsageAlert notifies the subscription owner when usage crosses one of their alert thresholds.
+// Each threshold fires at most once per billing period; failures are logged, not returned.
+func (s *SubscriptionService) sendUsageAlert(ctx context.Context, sub *subscription.AgentSubscription, used int) {
+	if !sub.RequestsLimit.Valid || sub.RequestsLimit.Int32 <= 0 {
+		return
+	}
+	limit := int(sub.RequestsLimit.Int32)
+
+	notifConfig, err := s.configService.GetNotificationConfig(ctx, sub.AgentIdentityID)
+	if err != nil {
+		s.logger.Warn("failed to load notification config for usage alert",
+			zap.Int64("agent_id", sub.AgentIdentityID),
+			zap.Error(err),
+		)
+		return
+	}
+	if !notifConfig.Enabled {
+		return
+	}
+
+	threshold := crossedThreshold(notifConfig.AlertThresholds(), used, limit)
+	if threshold == 0 {
+		return
+	}
+
+	claimed, err := s.subscriptionRepo.ClaimUsageAlert(ctx, sub.ID, threshold)
+	if err != nil {
+		s.logger.Warn("failed to record usage alert", zap.Int64("subscription_id", sub.ID), zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	title := fmt.Sprintf("%d%% of subscription requests used", threshold)
+	message := fmt.Sprintf("You have used %d of %d requests in the current billing period.", used, limit)
+	if err := s.notifService.SendAlertNotification(ctx, sub.AgentIdentityID, title, message, map[string]interface{}{
+		"subscription_id": sub.ID,
+		"threshold":       threshold,
+		"requests_used":   used,
+		"requests_limit":  limit,
+	}); err != nil {
+		s.logger.Warn("failed to send usage alert", zap.Int64("subscription_id", sub.ID), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("subscription usage alert sent",
+		zap.Int64("subscription_id", sub.ID),
+		zap.Int("threshold", threshold),
+		zap.Int("requests_used", used),
+	)
+}
+
+// crossedThreshold returns the highest threshold (percent) that used has reached, or 0 for none.
+// thresholds must be sorted ascending.
+func crossedThreshold(thresholds []int, used, limit int) int {
+	crossed := 0
+	for _, t := range thresholds {
+		if used*100 >= t*limit {
+			crossed = t
+		}
+	}
+	return crossed
+}
@@ -0,0 +1,65 @@
+// internal/service/subscription/usage_alerts_test.go
+package subscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/testutil"
+)
+
+func TestCustomUsageAlertThresholdsFireAtTheirPercentages(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestSubscriptionService(t)
+
+	now := time.Now()
+	agentID := testutil.Identity(t, pool, "alerts@example.com")
+	planID := seedPlan(t, pool, "alerts", 500, 10, nil)
+	subID := seedSubscription(t, pool, agentID, planID, now.AddDate(0, 0, -1), now.AddDate(0, 1, 0), 3, 10)
+	if err := svc.configService.SetNotificationConfig(ctx, agentID, &config.NotificationConfig{
+		Enabled:              true,
+		UsageAlertThresholds: []int{90, 50},
+	}); err != nil {
+		t.Fatalf("SetNotificationConfig: %v", err)
+	}
+
+	// Usage after each request, and the alert level it should leave behind
+	steps := []struct {
+		used  int
+		level int
+	}{
+		{4, 0},
+		{5, 50},
+		{6, 50},
+		{8, 50}, // Two requests; 80 would be a default threshold but isn't configured
+		{9, 90},
+		{10, 90},
+	}
+	used := 3
+	for _, step := range steps {
+		for ; used < step.used; used++ {
+			if err := svc.IncrementRequestUsage(ctx, agentID); err != nil {
+				t.Fatalf("IncrementRequestUsage: %v", err)
+			}
+		}
+		var level int
+		if err := pool.QueryRow(ctx, `SELECT usage_alert_level FROM agent_subscriptions WHERE id = $1`, subID).Scan(&level); err != nil {
+			t.Fatalf("failed to read alert level: %v", err)
+		}
+		if level != step.level {
+			t.Errorf("alert level at %d of 10 = %d, want %d", step.used, level, step.level)
+		}
+	}
+
+	var alerts int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE identity_id = $1 AND type = 'alert'
+	`, agentID).Scan(&alerts); err != nil {
+		t.Fatalf("failed to count alerts: %v", err)
+	}
+	if alerts != 2 {
+		t.Errorf("sent %d usage alerts, want one each at 50%% and 90%%", alerts)
+	}
+}
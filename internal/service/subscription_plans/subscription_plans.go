@@ -11,20 +11,28 @@ import (
 	"bingwa-service/internal/domain/subscription"
 	//xerrors "bingwa-service/internal/pkg/errors"
 	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
 
 	"go.uber.org/zap"
 )
 
 type PlanService struct {
-	planRepo *postgres.SubscriptionPlanRepository
-	logger   *zap.Logger
+	planRepo  *postgres.SubscriptionPlanRepository
+	planCache *cache.PlanCache
+	logger    *zap.Logger
 }
 
-func NewPlanService(planRepo *postgres.SubscriptionPlanRepository, logger *zap.Logger) *PlanService {
-	return &PlanService{
-		planRepo: planRepo,
-		logger:   logger,
+func NewPlanService(planRepo *postgres.SubscriptionPlanRepository, planCache *cache.PlanCache, logger *zap.Logger) *PlanService {
+	s := &PlanService{
+		planRepo:  planRepo,
+		planCache: planCache,
+		logger:    logger,
 	}
+
+	// Bust cached public listings/comparisons whenever a plan changes
+	planRepo.OnChange(s.invalidateCache)
+
+	return s
 }
 
 // CreatePlan creates a new subscription plan
@@ -50,24 +58,29 @@ func (s *PlanService) CreatePlan(ctx context.Context, req *subscription.CreatePl
 
 	// Create plan entity
 	plan := &subscription.SubscriptionPlan{
-		PlanCode:     req.PlanCode,
-		Name:         req.Name,
-		Description:  sql.NullString{String: req.Description, Valid: req.Description != ""},
-		Price:        req.Price,
-		Currency:     strings.ToUpper(req.Currency),
-		SetupFee:     req.SetupFee,
-		BillingUsage: req.BillingUsage,
-		BillingCycle: req.BillingCycle,
-		Features:     req.Features,
-		Status:       subscription.StatusActive,
-		IsPublic:     req.IsPublic,
-		Metadata:     req.Metadata,
-	}
+		PlanCode:      req.PlanCode,
+		Name:          req.Name,
+		Description:   sql.NullString{String: req.Description, Valid: req.Description != ""},
+		Price:         req.Price,
+		Currency:      strings.ToUpper(req.Currency),
+		SetupFee:      req.SetupFee,
+		BillingUsage:  req.BillingUsage,
+		BillingCycle:  req.BillingCycle,
+		Features:      req.Features,
+		Status:        subscription.StatusActive,
+		IsPublic:      req.IsPublic,
+		Metadata:      req.Metadata,
+		LimitBehavior: req.LimitBehavior,
+	}
+	plan.AllowedTransitions = req.AllowedTransitions
 
 	// Set optional fields
 	if req.OverageCharge != nil {
 		plan.OverageCharge = sql.NullFloat64{Float64: *req.OverageCharge, Valid: true}
 	}
+	if err := s.validateLimitBehavior(plan); err != nil {
+		return nil, err
+	}
 	if req.MaxOffers != nil {
 		plan.MaxOffers = sql.NullInt32{Int32: *req.MaxOffers, Valid: true}
 	}
@@ -141,8 +154,18 @@ func (s *PlanService) ListPlans(ctx context.Context, filters *subscription.PlanL
 	}, nil
 }
 
-// ListPublicPlans retrieves only public (subscribable) plans
+// ListPublicPlans retrieves only public (subscribable) plans (cached)
 func (s *PlanService) ListPublicPlans(ctx context.Context, page, pageSize int) (*subscription.PlanListResponse, error) {
+	var cached subscription.PlanListResponse
+	entry, err := s.planCache.PublicPlans(ctx, page, pageSize)
+	if err != nil {
+		s.logger.Warn("failed to resolve public plans cache entry", zap.Error(err))
+	} else if found, err := entry.Get(ctx, &cached); err != nil {
+		s.logger.Warn("failed to read public plans from cache", zap.Error(err))
+	} else if found {
+		return &cached, nil
+	}
+
 	isPublic := true
 	status := subscription.StatusActive
 
@@ -155,7 +178,18 @@ func (s *PlanService) ListPublicPlans(ctx context.Context, page, pageSize int) (
 		SortOrder: "asc",
 	}
 
-	return s.ListPlans(ctx, filters)
+	result, err := s.ListPlans(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil {
+		if err := entry.Set(ctx, result); err != nil {
+			s.logger.Warn("failed to cache public plans", zap.Error(err))
+		}
+	}
+
+	return result, nil
 }
 
 // UpdatePlan updates a subscription plan
@@ -206,6 +240,17 @@ func (s *PlanService) UpdatePlan(ctx context.Context, id int64, req *subscriptio
 	if req.Metadata != nil {
 		plan.Metadata = req.Metadata
 	}
+	if req.LimitBehavior != nil {
+		plan.LimitBehavior = *req.LimitBehavior
+	}
+	if req.AllowAllTransitions {
+		plan.AllowedTransitions = nil
+	} else if req.AllowedTransitions != nil {
+		plan.AllowedTransitions = req.AllowedTransitions
+	}
+	if err := s.validateLimitBehavior(plan); err != nil {
+		return nil, err
+	}
 
 	// Update in database
 	if err := s.planRepo.Update(ctx, id, plan); err != nil {
@@ -312,6 +357,20 @@ func (s *PlanService) isValidBillingCycle(cycle subscription.RenewalPeriod) bool
 	return false
 }
 
+// validateLimitBehavior checks the plan's limit behavior; overage billing needs an overage charge
+func (s *PlanService) validateLimitBehavior(plan *subscription.SubscriptionPlan) error {
+	if plan.LimitBehavior == "" {
+		return nil
+	}
+	if !plan.LimitBehavior.IsValid() {
+		return fmt.Errorf("invalid limit behavior: %s", plan.LimitBehavior)
+	}
+	if plan.LimitBehavior == subscription.LimitBehaviorOverage && !plan.OverageCharge.Valid {
+		return fmt.Errorf("limit behavior overage requires an overage charge")
+	}
+	return nil
+}
+
 // hasActiveSubscriptions checks if a plan has active subscriptions
 func (s *PlanService) hasActiveSubscriptions(ctx context.Context, planID int64) (bool, error) {
 	// This would query the agent_subscriptions table
@@ -335,7 +394,7 @@ func (s *PlanService) CalculateOverageCost(plan *subscription.SubscriptionPlan,
 		return 0
 	}
 
-	if !plan.OverageCharge.Valid {
+	if plan.EffectiveLimitBehavior() != subscription.LimitBehaviorOverage || !plan.OverageCharge.Valid {
 		return 0
 	}
 
@@ -361,8 +420,18 @@ func (s *PlanService) GetNextBillingDate(startDate time.Time, cycle subscription
 	}
 }
 
-// ComparePlans compares two plans and returns differences
+// ComparePlans compares two plans and returns differences (cached)
 func (s *PlanService) ComparePlans(ctx context.Context, planID1, planID2 int64) (map[string]interface{}, error) {
+	var cached map[string]interface{}
+	entry, err := s.planCache.Comparison(ctx, planID1, planID2)
+	if err != nil {
+		s.logger.Warn("failed to resolve plan comparison cache entry", zap.Error(err))
+	} else if found, err := entry.Get(ctx, &cached); err != nil {
+		s.logger.Warn("failed to read plan comparison from cache", zap.Error(err))
+	} else if found {
+		return cached, nil
+	}
+
 	plan1, err := s.planRepo.FindByID(ctx, planID1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get plan 1: %w", err)
@@ -394,9 +463,22 @@ func (s *PlanService) ComparePlans(ctx context.Context, planID1, planID2 int64)
 		"better_value":     s.determineBetterValue(plan1, plan2),
 	}
 
+	if entry != nil {
+		if err := entry.Set(ctx, comparison); err != nil {
+			s.logger.Warn("failed to cache plan comparison", zap.Error(err))
+		}
+	}
+
 	return comparison, nil
 }
 
+// invalidateCache drops cached public plan data after a plan change
+func (s *PlanService) invalidateCache(ctx context.Context) {
+	if err := s.planCache.Invalidate(ctx); err != nil {
+		s.logger.Warn("failed to invalidate plan cache", zap.Error(err))
+	}
+}
+
 // determineBetterValue determines which plan offers better value
 func (s *PlanService) determineBetterValue(plan1, plan2 *subscription.SubscriptionPlan) string {
 	// Simple comparison based on price per billing usage
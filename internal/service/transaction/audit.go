@@ -0,0 +1,123 @@
+// internal/service/transaction/audit.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/pkg/mask"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// redactedValue replaces free-text fields that may carry PII in audit payloads
+const redactedValue = "[redacted]"
+
+// GetTransactionAudit retrieves the captured input and status history of an agent's request
+func (s *TransactionService) GetTransactionAudit(ctx context.Context, agentID, requestID int64) (*transaction.TransactionAudit, error) {
+	request, err := s.requestRepo.FindByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.AgentIdentityID != agentID {
+		return nil, fmt.Errorf("unauthorized: request does not belong to agent")
+	}
+
+	entries, err := s.auditRepo.ListByRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction.TransactionAudit{
+		RequestID:        request.ID,
+		RequestReference: request.RequestReference,
+		Status:           request.Status,
+		Entries:          entries,
+	}, nil
+}
+
+// auditRequestCreatedWithTx records the PII-masked inbound request
+func (s *TransactionService) auditRequestCreatedWithTx(ctx context.Context, tx pgx.Tx, request *transaction.OfferRequest, input *transaction.CreateOfferRequestInput) error {
+	return s.auditRepo.CreateWithTx(ctx, tx, &transaction.AuditEntry{
+		OfferRequestID:  request.ID,
+		AgentIdentityID: request.AgentIdentityID,
+		Event:           transaction.AuditEventRequestCreated,
+		Status:          request.Status,
+		Payload:         maskedRequestInput(input, request.FailureReason.String),
+	})
+}
+
+// auditStatusUpdatedWithTx records a status change along with any USSD response
+func (s *TransactionService) auditStatusUpdatedWithTx(ctx context.Context, tx pgx.Tx, request *transaction.OfferRequest, status transaction.TransactionStatus, failureCode transaction.FailureCode, ussdResponse *transaction.UpdateUSSDResponseInput) error {
+	payload := map[string]interface{}{
+		"previous_status": request.Status,
+	}
+	if failureCode != "" {
+		payload["failure_code"] = failureCode
+	}
+	if ussdResponse != nil {
+		payload["ussd_response"] = ussdResponse.USSDResponse
+		payload["ussd_session_id"] = ussdResponse.USSDSessionID
+		payload["ussd_processing_time"] = ussdResponse.USSDProcessingTime
+		if ussdResponse.FailureReason != "" {
+			payload["failure_reason"] = ussdResponse.FailureReason
+		}
+	}
+
+	return s.auditRepo.CreateWithTx(ctx, tx, &transaction.AuditEntry{
+		OfferRequestID:  request.ID,
+		AgentIdentityID: request.AgentIdentityID,
+		Event:           transaction.AuditEventStatusUpdated,
+		Status:          status,
+		Payload:         payload,
+	})
+}
+
+// maskedRequestInput copies the request input for the audit trail with customer identifiers masked
+func maskedRequestInput(input *transaction.CreateOfferRequestInput, failureReason string) map[string]interface{} {
+	payload := map[string]interface{}{
+		"offer_id":              input.OfferID,
+		"customer_phone":        mask.Phone(input.CustomerPhone),
+		"payment_method":        input.PaymentMethod,
+		"amount_paid":           input.AmountPaid,
+		"currency":              input.Currency,
+		"source":                input.Source,
+		"auto_schedule_renewal": input.AutoScheduleRenewal,
+	}
+
+	if input.CustomerName != "" {
+		payload["customer_name"] = mask.Name(input.CustomerName)
+	}
+	if input.MpesaTransactionID != "" {
+		payload["mpesa_transaction_id"] = input.MpesaTransactionID
+	}
+	if input.MpesaReceiptNumber != "" {
+		payload["mpesa_receipt_number"] = mask.Receipt(input.MpesaReceiptNumber)
+	}
+	if !input.MpesaTransactionDate.IsZero() {
+		payload["mpesa_transaction_date"] = input.MpesaTransactionDate
+	}
+	if input.MpesaPhoneNumber != "" {
+		payload["mpesa_phone_number"] = mask.Phone(input.MpesaPhoneNumber)
+	}
+	if input.MpesaMessage != "" {
+		payload["mpesa_message"] = redactedValue
+	}
+	if input.Latitude != nil && input.Longitude != nil {
+		payload["latitude"] = *input.Latitude
+		payload["longitude"] = *input.Longitude
+	}
+	if input.DeviceInfo != nil {
+		payload["device_info"] = input.DeviceInfo
+	}
+	if input.Metadata != nil {
+		payload["metadata"] = input.Metadata
+	}
+	if failureReason != "" {
+		payload["failure_reason"] = failureReason
+	}
+
+	return payload
+}
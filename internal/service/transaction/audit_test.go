@@ -0,0 +1,74 @@
+// internal/service/transaction/audit_test.go
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/testutil"
+)
+
+func TestTransactionAuditCapturesRequestAndFinalStatus(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "audit@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	request, _, err := svc.CreateOfferRequest(ctx, agentID, &transaction.CreateOfferRequestInput{
+		OfferID:       offerID,
+		CustomerPhone: "254712345678",
+		CustomerName:  "Jane Wanjiku",
+		PaymentMethod: transaction.PaymentMethodMpesa,
+		AmountPaid:    50,
+		MpesaMessage:  "QWE123 Confirmed. Ksh50 received from JANE WANJIKU 0712345678",
+	})
+	if err != nil {
+		t.Fatalf("CreateOfferRequest: %v", err)
+	}
+	response := &transaction.UpdateUSSDResponseInput{
+		Status:        transaction.TransactionStatusSuccess,
+		USSDResponse:  "You have bought 1GB",
+		USSDSessionID: "session-1",
+	}
+	if err := svc.UpdateOfferRequestStatus(ctx, agentID, request.ID, transaction.TransactionStatusSuccess, response); err != nil {
+		t.Fatalf("UpdateOfferRequestStatus: %v", err)
+	}
+
+	audit, err := svc.GetTransactionAudit(ctx, agentID, request.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionAudit: %v", err)
+	}
+	if audit.Status != transaction.TransactionStatusSuccess || len(audit.Entries) != 2 {
+		t.Fatalf("audit = %s with %d entries, want success with 2", audit.Status, len(audit.Entries))
+	}
+
+	created := audit.Entries[0]
+	if created.Event != transaction.AuditEventRequestCreated {
+		t.Errorf("first entry = %s, want %s", created.Event, transaction.AuditEventRequestCreated)
+	}
+	if got := created.Payload["customer_phone"]; got != mask.Phone("254712345678") {
+		t.Errorf("audited phone = %v, want it masked", got)
+	}
+	if got := created.Payload["customer_name"]; got != mask.Name("Jane Wanjiku") {
+		t.Errorf("audited name = %v, want it masked", got)
+	}
+	if got := created.Payload["mpesa_message"]; got != redactedValue {
+		t.Errorf("audited M-Pesa message = %v, want it redacted", got)
+	}
+
+	final := audit.Entries[1]
+	if final.Event != transaction.AuditEventStatusUpdated || final.Status != transaction.TransactionStatusSuccess {
+		t.Errorf("last entry = %s to %s, want a status update to success", final.Event, final.Status)
+	}
+	if final.Payload["ussd_response"] != "You have bought 1GB" {
+		t.Errorf("last entry payload = %v, want the USSD response", final.Payload)
+	}
+
+	if _, err := svc.GetTransactionAudit(ctx, otherID, request.ID); err == nil {
+		t.Error("another agent read the audit trail")
+	}
+}
@@ -0,0 +1,103 @@
+// internal/service/transaction/batch_create.go
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// BatchCreateRequests creates each request and its redemption independently, continuing past failures.
+// Inputs carrying an M-Pesa receipt that is already recorded (or repeated earlier in the batch)
+// are reported as duplicates instead of being created twice.
+func (s *TransactionService) BatchCreateRequests(ctx context.Context, agentID int64, inputs []transaction.CreateOfferRequestInput) *transaction.BatchCreateResponse {
+	resp := &transaction.BatchCreateResponse{
+		Results: make([]transaction.BatchCreateResult, 0, len(inputs)),
+		Total:   len(inputs),
+	}
+
+	seenReceipts := make(map[string]transaction.BatchCreateResult, len(inputs))
+
+	for i := range inputs {
+		input := &inputs[i]
+		result := transaction.BatchCreateResult{Index: i}
+
+		receipt := strings.ToUpper(strings.TrimSpace(input.MpesaReceiptNumber))
+		if receipt != "" {
+			if earlier, ok := seenReceipts[receipt]; ok && earlier.RequestID != 0 {
+				result.Status = transaction.BatchItemDuplicate
+				result.RequestID = earlier.RequestID
+				result.RequestReference = earlier.RequestReference
+				result.RequestStatus = earlier.RequestStatus
+				s.appendBatchResult(resp, result)
+				continue
+			}
+
+			existing, err := s.requestRepo.FindByMpesaReceipt(ctx, agentID, receipt)
+			if err == nil {
+				result.Status = transaction.BatchItemDuplicate
+				result.RequestID = existing.ID
+				result.RequestReference = existing.RequestReference
+				result.RequestStatus = existing.Status
+				s.appendBatchResult(resp, result)
+				continue
+			}
+			if !errors.Is(err, xerrors.ErrNotFound) {
+				result.Status = transaction.BatchItemFailed
+				result.Error = fmt.Sprintf("failed to check receipt: %v", err)
+				s.appendBatchResult(resp, result)
+				continue
+			}
+		}
+
+		request, redemption, err := s.CreateOfferRequest(ctx, agentID, input)
+		if request != nil {
+			result.RequestID = request.ID
+			result.RequestReference = request.RequestReference
+			result.RequestStatus = request.Status
+		}
+		if redemption != nil {
+			result.RedemptionReference = redemption.RedemptionReference
+		}
+
+		if err != nil {
+			result.Status = transaction.BatchItemFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = transaction.BatchItemCreated
+		}
+
+		s.appendBatchResult(resp, result)
+		if receipt != "" {
+			seenReceipts[receipt] = result
+		}
+	}
+
+	s.logger.Info("batch offer requests processed",
+		zap.Int64("agent_id", agentID),
+		zap.Int("total", resp.Total),
+		zap.Int("created", resp.CreatedCount),
+		zap.Int("duplicates", resp.DuplicateCount),
+		zap.Int("failed", resp.FailedCount),
+	)
+
+	return resp
+}
+
+func (s *TransactionService) appendBatchResult(resp *transaction.BatchCreateResponse, result transaction.BatchCreateResult) {
+	switch result.Status {
+	case transaction.BatchItemCreated:
+		resp.CreatedCount++
+	case transaction.BatchItemDuplicate:
+		resp.DuplicateCount++
+	default:
+		resp.FailedCount++
+	}
+	resp.Results = append(resp.Results, result)
+}
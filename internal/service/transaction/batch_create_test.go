@@ -0,0 +1,61 @@
+// internal/service/transaction/batch_create_test.go
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/testutil"
+)
+
+func TestBatchCreateContinuesPastAnInvalidOffer(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "batch@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	input := func(offerID int64, phone, receipt string) transaction.CreateOfferRequestInput {
+		return transaction.CreateOfferRequestInput{
+			OfferID:            offerID,
+			CustomerPhone:      phone,
+			PaymentMethod:      transaction.PaymentMethodMpesa,
+			AmountPaid:         50,
+			MpesaTransactionID: receipt,
+			MpesaReceiptNumber: receipt,
+		}
+	}
+
+	resp := svc.BatchCreateRequests(ctx, agentID, []transaction.CreateOfferRequestInput{
+		input(offerID, "254700000001", "RCP0001"),
+		input(offerID+1000, "254700000002", "RCP0002"), // No such offer
+		input(offerID, "254700000003", "RCP0003"),
+	})
+
+	if resp.Total != 3 || resp.CreatedCount != 2 || resp.FailedCount != 1 || resp.DuplicateCount != 0 {
+		t.Fatalf("batch = %d created, %d failed, %d duplicates of %d; want 2, 1, 0 of 3",
+			resp.CreatedCount, resp.FailedCount, resp.DuplicateCount, resp.Total)
+	}
+	want := []transaction.BatchItemStatus{transaction.BatchItemCreated, transaction.BatchItemFailed, transaction.BatchItemCreated}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != want[i] {
+			t.Errorf("result %d = index %d, %s; want index %d, %s", i, result.Index, result.Status, i, want[i])
+		}
+	}
+	if failed := resp.Results[1]; failed.Error == "" || failed.RequestID != 0 {
+		t.Errorf("failed item = %+v, want an error and no request", failed)
+	}
+	for _, i := range []int{0, 2} {
+		if r := resp.Results[i]; r.RequestID == 0 || r.RequestReference == "" {
+			t.Errorf("created item %d = %+v, want a request", i, r)
+		}
+	}
+
+	var requests int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM offer_requests WHERE agent_identity_id = $1`, agentID).Scan(&requests); err != nil {
+		t.Fatalf("failed to count requests: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests stored, want 2", requests)
+	}
+}
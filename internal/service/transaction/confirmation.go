@@ -0,0 +1,88 @@
+// internal/service/transaction/confirmation.go
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
+
+	"go.uber.org/zap"
+)
+
+// defaultConfirmationWindow is how long a request for a confirmation-required offer waits before it expires
+const defaultConfirmationWindow = 15 * time.Minute
+
+// SetConfirmationWindow configures how long requests may await confirmation; non-positive values keep the default
+func (s *TransactionService) SetConfirmationWindow(window time.Duration) {
+	if window <= 0 {
+		window = defaultConfirmationWindow
+	}
+	s.confirmationWindow = window
+}
+
+// ConfirmOfferRequest releases a request awaiting confirmation for dispatch
+func (s *TransactionService) ConfirmOfferRequest(ctx context.Context, agentID, requestID int64) (*transaction.OfferRequest, error) {
+	request, err := s.requestRepo.FindByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.AgentIdentityID != agentID {
+		return nil, xerrors.ErrNotFound
+	}
+	if request.Status != transaction.TransactionStatusPendingConfirmation {
+		return nil, fmt.Errorf("%w: request is not awaiting confirmation", xerrors.ErrConflict)
+	}
+	if time.Since(request.CreatedAt) > s.confirmationWindow {
+		return nil, fmt.Errorf("%w: confirmation window has expired", xerrors.ErrConflict)
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The guarded update loses cleanly to a concurrent confirm or expiry
+	if err := s.requestRepo.ConfirmWithTx(ctx, tx, requestID); err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: request is not awaiting confirmation", xerrors.ErrConflict)
+		}
+		return nil, err
+	}
+
+	if err := s.redemptionRepo.ConfirmByRequestIDWithTx(ctx, tx, requestID); err != nil {
+		return nil, err
+	}
+
+	if err := s.auditRepo.CreateWithTx(ctx, tx, &transaction.AuditEntry{
+		OfferRequestID:  request.ID,
+		AgentIdentityID: request.AgentIdentityID,
+		Event:           transaction.AuditEventConfirmed,
+		Status:          transaction.TransactionStatusPending,
+		Payload: map[string]interface{}{
+			"previous_status": request.Status,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.redemptionRepo.EnqueueStatusEventsWithTx(ctx, tx, []int64{requestID}, request.Status); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("offer request confirmed",
+		zap.Int64("request_id", requestID),
+		zap.String("customer_phone", mask.Phone(request.CustomerPhone)),
+	)
+
+	return s.requestRepo.FindByID(ctx, requestID)
+}
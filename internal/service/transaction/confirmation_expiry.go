@@ -0,0 +1,112 @@
+// internal/service/transaction/confirmation_expiry.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+
+	"go.uber.org/zap"
+)
+
+// confirmationExpiredReason is recorded on requests and redemptions cancelled by the expiry worker
+const confirmationExpiredReason = "confirmation_expired"
+
+// ConfirmationExpiryWorker cancels requests for confirmation-required offers that were never
+// confirmed, along with their redemptions, so they don't wait forever.
+type ConfirmationExpiryWorker struct {
+	requestRepo    *postgres.OfferRequestRepository
+	redemptionRepo *postgres.OfferRedemptionRepository
+	auditRepo      *postgres.TransactionAuditRepository
+	db             *postgres.DB
+	window         time.Duration
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+func NewConfirmationExpiryWorker(
+	requestRepo *postgres.OfferRequestRepository,
+	redemptionRepo *postgres.OfferRedemptionRepository,
+	auditRepo *postgres.TransactionAuditRepository,
+	db *postgres.DB,
+	window time.Duration,
+	interval time.Duration,
+	logger *zap.Logger,
+) *ConfirmationExpiryWorker {
+	return &ConfirmationExpiryWorker{
+		requestRepo:    requestRepo,
+		redemptionRepo: redemptionRepo,
+		auditRepo:      auditRepo,
+		db:             db,
+		window:         window,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *ConfirmationExpiryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("confirmation expiry run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce cancels requests left unconfirmed longer than the window and returns how many were cancelled
+func (w *ConfirmationExpiryWorker) RunOnce(ctx context.Context) (int, error) {
+	tx, err := w.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cutoff := time.Now().Add(-w.window)
+	requestIDs, err := w.requestRepo.CancelUnconfirmedWithTx(ctx, tx, cutoff, confirmationExpiredReason)
+	if err != nil {
+		return 0, err
+	}
+	if len(requestIDs) == 0 {
+		return 0, nil
+	}
+
+	redemptions, err := w.redemptionRepo.CancelUnconfirmedByRequestIDsWithTx(ctx, tx, requestIDs, confirmationExpiredReason)
+	if err != nil {
+		return 0, err
+	}
+
+	auditPayload := map[string]interface{}{
+		"previous_status": transaction.TransactionStatusPendingConfirmation,
+		"failure_reason":  confirmationExpiredReason,
+	}
+	if err := w.auditRepo.CreateForRequestsWithTx(ctx, tx, requestIDs, transaction.AuditEventStatusUpdated, auditPayload); err != nil {
+		return 0, err
+	}
+
+	if err := w.redemptionRepo.EnqueueStatusEventsWithTx(ctx, tx, requestIDs, transaction.TransactionStatusPendingConfirmation); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	w.logger.Info("unconfirmed offer requests expired",
+		zap.Int("requests", len(requestIDs)),
+		zap.Int64("redemptions", redemptions),
+	)
+
+	return len(requestIDs), nil
+}
@@ -0,0 +1,102 @@
+// internal/service/transaction/confirmation_test.go
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestConfirmationRequiredRequestIsConfirmedOrExpires(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "confirm@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-10GB", 500)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET require_confirmation = TRUE WHERE id = $1`, offerID); err != nil {
+		t.Fatalf("failed to require confirmation: %v", err)
+	}
+
+	create := func(phone string) *transaction.OfferRequest {
+		t.Helper()
+		request, _, err := svc.CreateOfferRequest(ctx, agentID, &transaction.CreateOfferRequestInput{
+			OfferID:       offerID,
+			CustomerPhone: phone,
+			PaymentMethod: transaction.PaymentMethodMpesa,
+			AmountPaid:    500,
+		})
+		if err != nil {
+			t.Fatalf("CreateOfferRequest: %v", err)
+		}
+		if request.Status != transaction.TransactionStatusPendingConfirmation {
+			t.Fatalf("new request status = %s, want %s", request.Status, transaction.TransactionStatusPendingConfirmation)
+		}
+		return request
+	}
+	status := func(requestID int64) (request, redemption string, reason sql.NullString) {
+		t.Helper()
+		if err := pool.QueryRow(ctx, `
+			SELECT r.status::text, d.status::text, r.failure_reason
+			FROM offer_requests r JOIN offer_redemptions d ON d.offer_request_id = r.id
+			WHERE r.id = $1
+		`, requestID).Scan(&request, &redemption, &reason); err != nil {
+			t.Fatalf("failed to read request %d: %v", requestID, err)
+		}
+		return request, redemption, reason
+	}
+
+	confirmed := create("254712345678")
+	if _, err := svc.ConfirmOfferRequest(ctx, otherID, confirmed.ID); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("confirm by another agent = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.ConfirmOfferRequest(ctx, agentID, confirmed.ID); err != nil {
+		t.Fatalf("ConfirmOfferRequest: %v", err)
+	}
+	if request, redemption, _ := status(confirmed.ID); request != "pending" || redemption != "pending" {
+		t.Errorf("confirmed request = %s with redemption %s, want both pending", request, redemption)
+	}
+	if _, err := svc.ConfirmOfferRequest(ctx, agentID, confirmed.ID); !errors.Is(err, xerrors.ErrConflict) {
+		t.Errorf("second confirm = %v, want ErrConflict", err)
+	}
+
+	// A request left past the window can no longer be confirmed, and the worker cancels it
+	expired := create("254700000001")
+	if _, err := pool.Exec(ctx, `UPDATE offer_requests SET created_at = $2 WHERE id = $1`, expired.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to backdate request: %v", err)
+	}
+	if _, err := svc.ConfirmOfferRequest(ctx, agentID, expired.ID); !errors.Is(err, xerrors.ErrConflict) {
+		t.Errorf("confirm after the window = %v, want ErrConflict", err)
+	}
+	waiting := create("254700000002")
+
+	worker := NewConfirmationExpiryWorker(
+		postgres.NewOfferRequestRepository(pool),
+		postgres.NewOfferRedemptionRepository(pool),
+		postgres.NewTransactionAuditRepository(pool),
+		postgres.NewDB(pool),
+		defaultConfirmationWindow, time.Minute, zap.NewNop(),
+	)
+	if n, err := worker.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1 request cancelled", n, err)
+	}
+
+	if request, redemption, reason := status(expired.ID); request != "cancelled" || redemption != "cancelled" || reason.String != confirmationExpiredReason {
+		t.Errorf("expired request = %s (%s) with redemption %s, want both cancelled for %s", request, reason.String, redemption, confirmationExpiredReason)
+	}
+	if request, redemption, _ := status(waiting.ID); request != "pending_confirmation" || redemption != "pending_confirmation" {
+		t.Errorf("waiting request = %s with redemption %s, want both still awaiting confirmation", request, redemption)
+	}
+	if request, _, _ := status(confirmed.ID); request != "pending" {
+		t.Errorf("confirmed request = %s after expiry, want it left pending", request)
+	}
+}
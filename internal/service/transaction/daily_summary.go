@@ -0,0 +1,196 @@
+// internal/service/transaction/daily_summary.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/emaillog"
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
+	"bingwa-service/internal/service/email"
+	notificationsvc "bingwa-service/internal/service/notification"
+
+	"go.uber.org/zap"
+)
+
+// dailySummaryMarkTTL outlives the day a summary covers so it's sent once per agent per day
+const dailySummaryMarkTTL = 48 * time.Hour
+
+// DailySummaryWorker sends agents who opted in an end-of-day summary of their requests,
+// redemptions and revenue, as an in-app notification and, with email alerts on, an email
+type DailySummaryWorker struct {
+	transactionSvc *TransactionService
+	configSvc      *configsvc.ConfigService
+	notifService   *notificationsvc.NotificationService
+	authRepo       *postgres.AuthRepository
+	sender         *email.EmailSender
+	marker         *cache.OnceMarker
+	hour           int
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+func NewDailySummaryWorker(
+	transactionSvc *TransactionService,
+	configSvc *configsvc.ConfigService,
+	notifService *notificationsvc.NotificationService,
+	authRepo *postgres.AuthRepository,
+	sender *email.EmailSender,
+	marker *cache.OnceMarker,
+	hour int,
+	interval time.Duration,
+	logger *zap.Logger,
+) *DailySummaryWorker {
+	return &DailySummaryWorker{
+		transactionSvc: transactionSvc,
+		configSvc:      configSvc,
+		notifService:   notifService,
+		authRepo:       authRepo,
+		sender:         sender,
+		marker:         marker,
+		hour:           hour,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *DailySummaryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("daily summary run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends today's summary to opted-in agents that haven't had it yet, once their local day is past
+// the configured hour, and returns how many were sent. Days follow each agent's display timezone.
+func (w *DailySummaryWorker) RunOnce(ctx context.Context) (int, error) {
+	agentIDs, err := w.configSvc.ListDailySummaryAgents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list daily summary agents: %w", err)
+	}
+
+	now := time.Now()
+	sent := 0
+	for _, agentID := range agentIDs {
+		dayStart, due := summaryDay(now, w.configSvc.AgentLocation(ctx, agentID), w.hour)
+		if !due {
+			continue
+		}
+		filters := &transaction.StatsFilters{From: &dayStart, To: &now}
+
+		key := fmt.Sprintf("%d:%s", agentID, dayStart.Format("2006-01-02"))
+		claimed, err := w.marker.Mark(ctx, key, dailySummaryMarkTTL)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := w.send(ctx, agentID, dayStart, filters); err != nil {
+			w.logger.Warn("failed to send daily summary", zap.Int64("agent_id", agentID), zap.Error(err))
+			if err := w.marker.Unmark(ctx, key); err != nil {
+				w.logger.Warn("failed to clear daily summary marker", zap.Int64("agent_id", agentID), zap.Error(err))
+			}
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		w.logger.Info("daily summaries sent", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// summaryDay returns the start of the agent's current day in loc, and whether that day is past hour
+func summaryDay(now time.Time, loc *time.Location, hour int) (time.Time, bool) {
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return dayStart, local.Hour() >= hour
+}
+
+// send notifies the agent of the day's stats and emails them if email alerts are on
+func (w *DailySummaryWorker) send(ctx context.Context, agentID int64, day time.Time, filters *transaction.StatsFilters) error {
+	stats, err := w.transactionSvc.GetTransactionStats(ctx, agentID, filters)
+	if err != nil {
+		return err
+	}
+
+	message := dailySummaryMessage(stats)
+	if err := w.notifService.SendInfoNotification(ctx, agentID, "Today's summary", message, map[string]interface{}{
+		"date":                   day.Format("2006-01-02"),
+		"total_requests":         stats.TotalRequests,
+		"successful_requests":    stats.SuccessfulRequests,
+		"failed_requests":        stats.FailedRequests,
+		"total_redemptions":      stats.TotalRedemptions,
+		"successful_redemptions": stats.SuccessfulRedemptions,
+		"total_revenue":          stats.TotalRevenue,
+	}); err != nil {
+		return fmt.Errorf("failed to notify agent: %w", err)
+	}
+
+	notifCfg, err := w.configSvc.GetNotificationConfig(ctx, agentID)
+	if err != nil || !notifCfg.EmailAlerts {
+		return nil
+	}
+
+	identity, err := w.authRepo.FindIdentityByID(ctx, agentID)
+	if err != nil || !identity.Email.Valid || identity.Email.String == "" {
+		return nil
+	}
+
+	subject, body := dailySummaryEmail(day, stats)
+	if err := w.sender.SendAs(emaillog.TypeDailySummary, identity.Email.String, subject, body); err != nil {
+		// The agent has been notified in-app; don't resend just because the email failed
+		w.logger.Warn("failed to email daily summary", zap.Int64("agent_id", agentID), zap.Error(err))
+	}
+
+	return nil
+}
+
+// dailySummaryMessage builds the one-line in-app summary
+func dailySummaryMessage(stats *transaction.TransactionStats) string {
+	return fmt.Sprintf("%d requests (%d successful, %d failed), %d redemptions, revenue %.2f",
+		stats.TotalRequests, stats.SuccessfulRequests, stats.FailedRequests,
+		stats.TotalRedemptions, stats.TotalRevenue,
+	)
+}
+
+// dailySummaryEmail builds the daily summary email
+func dailySummaryEmail(day time.Time, stats *transaction.TransactionStats) (string, string) {
+	subject := fmt.Sprintf("Your summary for %s", day.Format("02 Jan 2006"))
+	body := fmt.Sprintf(`
+		<h2>Daily Summary</h2>
+		<p>Here is how %s went:</p>
+		<ul>
+			<li>Requests: <strong>%d</strong> (%d successful, %d pending, %d failed)</li>
+			<li>Success rate: <strong>%.1f%%</strong></li>
+			<li>Redemptions: <strong>%d</strong> (%d successful)</li>
+			<li>Revenue: <strong>%.2f</strong></li>
+		</ul>
+	`, day.Format("02 Jan 2006"),
+		stats.TotalRequests, stats.SuccessfulRequests, stats.PendingRequests, stats.FailedRequests,
+		stats.SuccessRate,
+		stats.TotalRedemptions, stats.SuccessfulRedemptions,
+		stats.TotalRevenue,
+	)
+
+	return subject, body
+}
@@ -0,0 +1,34 @@
+// internal/service/transaction/daily_summary_test.go
+package transaction
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummaryDay(t *testing.T) {
+	eat := time.FixedZone("EAT", 3*60*60)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		hour    int
+		wantDay time.Time
+		wantDue bool
+	}{
+		{"before the summary hour", time.Date(2026, 10, 16, 19, 59, 0, 0, eat), 20, time.Date(2026, 10, 16, 0, 0, 0, 0, eat), false},
+		{"at the summary hour", time.Date(2026, 10, 16, 20, 0, 0, 0, eat), 20, time.Date(2026, 10, 16, 0, 0, 0, 0, eat), true},
+		{"late in the day", time.Date(2026, 10, 16, 23, 30, 0, 0, eat), 20, time.Date(2026, 10, 16, 0, 0, 0, 0, eat), true},
+		{"utc evening is the next local day", time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC), 20, time.Date(2026, 10, 17, 0, 0, 0, 0, eat), false},
+		{"utc afternoon is the local evening", time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC), 20, time.Date(2026, 10, 16, 0, 0, 0, 0, eat), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, due := summaryDay(tt.now, eat, tt.hour)
+			if !day.Equal(tt.wantDay) || due != tt.wantDue {
+				t.Errorf("summaryDay(%v) = %v, %v, want %v, %v", tt.now, day, due, tt.wantDay, tt.wantDue)
+			}
+		})
+	}
+}
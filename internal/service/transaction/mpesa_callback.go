@@ -0,0 +1,385 @@
+// internal/service/transaction/mpesa_callback.go
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/pkg/webhook"
+
+	"go.uber.org/zap"
+)
+
+// mpesaMatchWindow bounds how old a pending request may be to match a payment on amount and phone
+const mpesaMatchWindow = 24 * time.Hour
+
+// mpesaTimeLayout is the timestamp format M-Pesa uses for TransTime and TransactionDate
+const mpesaTimeLayout = "20060102150405"
+
+// mpesaLocation is the timezone M-Pesa timestamps are written in
+var mpesaLocation = time.FixedZone("EAT", 3*60*60)
+
+// mpesaPayment is a confirmed payment read from either callback shape
+type mpesaPayment struct {
+	TransactionID string
+	Receipt       string
+	Amount        float64
+	Phone         string
+	PaidAt        time.Time
+	Reference     string
+	PayerName     string
+}
+
+// IngestMpesaCallback records an M-Pesa STK or C2B callback against the agent's pending requests.
+// The payload must carry an X-Bingwa-Signature made with the agent's M-Pesa callback secret, and C2B
+// confirmations must be for a shortcode an agent has registered.
+func (s *TransactionService) IngestMpesaCallback(ctx context.Context, payload []byte, signature string) (*transaction.MpesaCallbackResult, error) {
+	var callback transaction.MpesaCallback
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return nil, fmt.Errorf("%w: malformed mpesa callback", xerrors.ErrInvalidInput)
+	}
+
+	if callback.Body != nil && callback.Body.StkCallback != nil {
+		return s.ingestSTKCallback(ctx, callback.Body.StkCallback, payload, signature)
+	}
+	return s.ingestC2BConfirmation(ctx, &callback, payload, signature)
+}
+
+// ingestSTKCallback settles the pending request an STK push was started for
+func (s *TransactionService) ingestSTKCallback(ctx context.Context, callback *transaction.MpesaSTKCallback, payload []byte, signature string) (*transaction.MpesaCallbackResult, error) {
+	if callback.CheckoutRequestID == "" {
+		return nil, fmt.Errorf("%w: stk callback has no CheckoutRequestID", xerrors.ErrInvalidInput)
+	}
+
+	request, err := s.requestRepo.FindByMpesaTransactionID(ctx, callback.CheckoutRequestID)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			s.logger.Warn("mpesa stk callback matched no request", zap.String("checkout_request_id", callback.CheckoutRequestID))
+			return &transaction.MpesaCallbackResult{Status: transaction.MpesaCallbackUnmatched}, nil
+		}
+		return nil, err
+	}
+
+	if err := s.verifyMpesaSignature(ctx, request.AgentIdentityID, payload, signature); err != nil {
+		return nil, err
+	}
+
+	if callback.ResultCode != 0 {
+		s.logger.Info("mpesa stk push not paid",
+			zap.Int64("request_id", request.ID),
+			zap.Int("result_code", callback.ResultCode),
+			zap.String("result_desc", callback.ResultDesc),
+		)
+		return &transaction.MpesaCallbackResult{Status: transaction.MpesaCallbackFailed, RequestID: request.ID}, nil
+	}
+
+	payment, err := stkPayment(callback)
+	if err != nil {
+		return nil, err
+	}
+
+	// M-Pesa retries callbacks; a receipt already on file has been handled
+	if result, err := s.mpesaDuplicate(ctx, request.AgentIdentityID, payment.Receipt); result != nil || err != nil {
+		return result, err
+	}
+
+	if request.Status != transaction.TransactionStatusPending || request.MpesaReceiptNumber.Valid {
+		s.logger.Warn("mpesa stk callback for a request no longer awaiting payment",
+			zap.Int64("request_id", request.ID),
+			zap.String("status", string(request.Status)),
+		)
+		return &transaction.MpesaCallbackResult{Status: transaction.MpesaCallbackUnmatched, ReceiptNumber: payment.Receipt}, nil
+	}
+
+	return s.recordMpesaPayment(ctx, request, payment, "checkout_request")
+}
+
+// ingestC2BConfirmation records a till/paybill payment for the agent owning the shortcode. It settles a
+// pending request matched by reference, then by amount and phone; failing that, an account reference that
+// names one of the agent's offer codes creates a new request for that offer.
+func (s *TransactionService) ingestC2BConfirmation(ctx context.Context, callback *transaction.MpesaCallback, payload []byte, signature string) (*transaction.MpesaCallbackResult, error) {
+	if callback.BusinessShortCode == "" || callback.TransID == "" {
+		return nil, fmt.Errorf("%w: c2b callback needs BusinessShortCode and TransID", xerrors.ErrInvalidInput)
+	}
+
+	agentID, err := s.configSvc.FindAgentByMpesaShortCode(ctx, callback.BusinessShortCode)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: unknown shortcode %s", xerrors.ErrUnauthorized, callback.BusinessShortCode)
+		}
+		return nil, err
+	}
+
+	if err := s.verifyMpesaSignature(ctx, agentID, payload, signature); err != nil {
+		return nil, err
+	}
+
+	payment, err := c2bPayment(callback)
+	if err != nil {
+		return nil, err
+	}
+
+	// M-Pesa retries callbacks; a receipt already on file has been handled
+	if result, err := s.mpesaDuplicate(ctx, agentID, payment.Receipt); result != nil || err != nil {
+		return result, err
+	}
+
+	if payment.Reference != "" {
+		request, err := s.requestRepo.FindPendingByReference(ctx, agentID, payment.Reference)
+		if err == nil {
+			return s.recordMpesaPayment(ctx, request, payment, "reference")
+		}
+		if !errors.Is(err, xerrors.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	request, err := s.requestRepo.FindPendingByAmountAndPhone(ctx, agentID, payment.Amount, payment.Phone, time.Now().Add(-mpesaMatchWindow))
+	if err == nil {
+		return s.recordMpesaPayment(ctx, request, payment, "amount_phone")
+	}
+	if !errors.Is(err, xerrors.ErrNotFound) {
+		return nil, err
+	}
+
+	if payment.Reference != "" {
+		if result, err := s.createMpesaRequest(ctx, agentID, payment); result != nil || err != nil {
+			return result, err
+		}
+	}
+
+	s.logger.Warn("mpesa payment matched no request",
+		zap.Int64("agent_id", agentID),
+		zap.String("receipt", mask.Receipt(payment.Receipt)),
+		zap.Float64("amount", payment.Amount),
+	)
+
+	return &transaction.MpesaCallbackResult{
+		Status:        transaction.MpesaCallbackUnmatched,
+		ReceiptNumber: payment.Receipt,
+	}, nil
+}
+
+// recordMpesaPayment stores the payment on a pending request and audits it. A payment whose amount
+// is off the request's by more than the tolerance is still recorded, but the request is held for review.
+func (s *TransactionService) recordMpesaPayment(ctx context.Context, request *transaction.OfferRequest, payment *mpesaPayment, matchedBy string) (*transaction.MpesaCallbackResult, error) {
+	mismatched := math.Abs(payment.Amount-request.AmountPaid) > s.amountTolerance
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.requestRepo.RecordMpesaPaymentWithTx(ctx, tx, request.ID, payment.TransactionID, payment.Receipt, payment.Phone, payment.PaidAt, mismatched); err != nil {
+		// A retry delivered concurrently recorded the receipt first
+		if errors.Is(err, xerrors.ErrDuplicateEntry) {
+			return s.mpesaDuplicate(ctx, request.AgentIdentityID, payment.Receipt)
+		}
+		return nil, err
+	}
+
+	if err := s.auditRepo.CreateWithTx(ctx, tx, &transaction.AuditEntry{
+		OfferRequestID:  request.ID,
+		AgentIdentityID: request.AgentIdentityID,
+		Event:           transaction.AuditEventMpesaPayment,
+		Status:          request.Status,
+		Payload: map[string]interface{}{
+			"matched_by":     matchedBy,
+			"receipt_number": mask.Receipt(payment.Receipt),
+			"amount":         payment.Amount,
+			"phone":          mask.Phone(payment.Phone),
+			"held":           mismatched,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	status := transaction.MpesaCallbackMatched
+	if mismatched {
+		status = transaction.MpesaCallbackHeld
+		s.logger.Warn("mpesa payment amount differs from request, holding for review",
+			zap.Int64("request_id", request.ID),
+			zap.Float64("expected", request.AmountPaid),
+			zap.Float64("paid", payment.Amount),
+		)
+	}
+
+	result := &transaction.MpesaCallbackResult{
+		Status:        status,
+		MatchedBy:     matchedBy,
+		ReceiptNumber: payment.Receipt,
+		RequestID:     request.ID,
+	}
+	if redemption, err := s.redemptionRepo.FindByOfferRequestID(ctx, request.ID); err == nil {
+		result.RedemptionID = redemption.ID
+	}
+
+	s.logger.Info("mpesa payment recorded",
+		zap.Int64("request_id", request.ID),
+		zap.Int64("agent_id", request.AgentIdentityID),
+		zap.String("matched_by", matchedBy),
+	)
+
+	return result, nil
+}
+
+// createMpesaRequest creates a pending request and redemption for a payment whose account reference
+// is one of the agent's offer codes; it returns nil when the reference names no such offer
+func (s *TransactionService) createMpesaRequest(ctx context.Context, agentID int64, payment *mpesaPayment) (*transaction.MpesaCallbackResult, error) {
+	offer, err := s.offerRepo.FindByOfferCode(ctx, strings.ToUpper(payment.Reference))
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if offer.AgentIdentityID != agentID {
+		return nil, nil
+	}
+
+	// Only the receipt is set so the request stays pending until the device dials the offer
+	request, redemption, err := s.CreateOfferRequest(ctx, agentID, &transaction.CreateOfferRequestInput{
+		OfferID:              offer.ID,
+		CustomerPhone:        payment.Phone,
+		CustomerName:         payment.PayerName,
+		PaymentMethod:        transaction.PaymentMethodMpesa,
+		AmountPaid:           payment.Amount,
+		Currency:             offer.Currency,
+		MpesaReceiptNumber:   payment.Receipt,
+		MpesaTransactionDate: payment.PaidAt,
+		MpesaPhoneNumber:     payment.Phone,
+		Metadata:             map[string]interface{}{"mpesa_callback": true},
+	})
+	if errors.Is(err, xerrors.ErrDuplicateEntry) {
+		return s.mpesaDuplicate(ctx, agentID, payment.Receipt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction.MpesaCallbackResult{
+		Status:        transaction.MpesaCallbackCreated,
+		MatchedBy:     "offer_code",
+		ReceiptNumber: payment.Receipt,
+		RequestID:     request.ID,
+		RedemptionID:  redemption.ID,
+	}, nil
+}
+
+// mpesaDuplicate reports the request already holding an agent's receipt, or nil when there is none
+func (s *TransactionService) mpesaDuplicate(ctx context.Context, agentID int64, receipt string) (*transaction.MpesaCallbackResult, error) {
+	existing, err := s.requestRepo.FindByMpesaReceipt(ctx, agentID, receipt)
+	if err != nil {
+		if errors.Is(err, xerrors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &transaction.MpesaCallbackResult{
+		Status:        transaction.MpesaCallbackDuplicate,
+		ReceiptNumber: receipt,
+		RequestID:     existing.ID,
+	}, nil
+}
+
+// verifyMpesaSignature checks the callback signature against the agent's M-Pesa callback secret
+func (s *TransactionService) verifyMpesaSignature(ctx context.Context, agentID int64, payload []byte, signature string) error {
+	mpesaConfig, err := s.configSvc.GetMpesaConfig(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	if !webhook.VerifySignature(payload, signature, mpesaConfig.CallbackSecret) {
+		return fmt.Errorf("%w: invalid mpesa callback signature", xerrors.ErrUnauthorized)
+	}
+
+	return nil
+}
+
+// stkPayment reads the payment from a successful STK callback's metadata items
+func stkPayment(callback *transaction.MpesaSTKCallback) (*mpesaPayment, error) {
+	payment := &mpesaPayment{TransactionID: callback.CheckoutRequestID}
+
+	for _, item := range callback.CallbackMetadata.Item {
+		switch item.Name {
+		case "Amount":
+			payment.Amount, _ = mpesaNumber(item.Value)
+		case "MpesaReceiptNumber":
+			payment.Receipt = mpesaString(item.Value)
+		case "PhoneNumber":
+			payment.Phone = mpesaString(item.Value)
+		case "TransactionDate":
+			payment.PaidAt = parseMpesaTime(mpesaString(item.Value))
+		}
+	}
+
+	if payment.Receipt == "" || payment.Amount <= 0 {
+		return nil, fmt.Errorf("%w: stk callback is missing the receipt or amount", xerrors.ErrInvalidInput)
+	}
+
+	return payment, nil
+}
+
+// c2bPayment reads the payment from a C2B confirmation
+func c2bPayment(callback *transaction.MpesaCallback) (*mpesaPayment, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(callback.TransAmount), 64)
+	if err != nil || amount <= 0 {
+		return nil, fmt.Errorf("%w: invalid TransAmount %q", xerrors.ErrInvalidInput, callback.TransAmount)
+	}
+
+	return &mpesaPayment{
+		Receipt:   callback.TransID,
+		Amount:    amount,
+		Phone:     callback.MSISDN,
+		PaidAt:    parseMpesaTime(callback.TransTime),
+		Reference: strings.TrimSpace(callback.BillRefNumber),
+		PayerName: strings.TrimSpace(callback.FirstName + " " + callback.LastName),
+	}, nil
+}
+
+// mpesaNumber reads a metadata value M-Pesa may send as a number or a string
+func mpesaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// mpesaString formats a metadata value as text; phone numbers and dates arrive as JSON numbers
+func mpesaString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// parseMpesaTime parses an M-Pesa timestamp, falling back to now when it is missing or malformed
+func parseMpesaTime(value string) time.Time {
+	t, err := time.ParseInLocation(mpesaTimeLayout, value, mpesaLocation)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
@@ -0,0 +1,195 @@
+// internal/service/transaction/mpesa_callback_test.go
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/webhook"
+	"bingwa-service/internal/testutil"
+)
+
+func TestSTKPayment(t *testing.T) {
+	paidAt := time.Date(2026, 10, 16, 14, 30, 5, 0, mpesaLocation)
+
+	tests := []struct {
+		name    string
+		items   []transaction.MpesaCallbackItem
+		want    *mpesaPayment
+		wantErr bool
+	}{
+		{
+			"numeric values",
+			[]transaction.MpesaCallbackItem{
+				{Name: "Amount", Value: float64(50)},
+				{Name: "MpesaReceiptNumber", Value: "QJK7ABC123"},
+				{Name: "TransactionDate", Value: float64(20261016143005)},
+				{Name: "PhoneNumber", Value: float64(254712345678)},
+			},
+			&mpesaPayment{TransactionID: "ws_CO_1", Receipt: "QJK7ABC123", Amount: 50, Phone: "254712345678", PaidAt: paidAt},
+			false,
+		},
+		{
+			"string values",
+			[]transaction.MpesaCallbackItem{
+				{Name: "Amount", Value: "99.5"},
+				{Name: "MpesaReceiptNumber", Value: "QJK7ABC124"},
+				{Name: "TransactionDate", Value: "20261016143005"},
+				{Name: "PhoneNumber", Value: "254712345678"},
+			},
+			&mpesaPayment{TransactionID: "ws_CO_1", Receipt: "QJK7ABC124", Amount: 99.5, Phone: "254712345678", PaidAt: paidAt},
+			false,
+		},
+		{
+			"missing receipt",
+			[]transaction.MpesaCallbackItem{{Name: "Amount", Value: float64(50)}},
+			nil,
+			true,
+		},
+		{
+			"zero amount",
+			[]transaction.MpesaCallbackItem{
+				{Name: "Amount", Value: float64(0)},
+				{Name: "MpesaReceiptNumber", Value: "QJK7ABC125"},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback := &transaction.MpesaSTKCallback{CheckoutRequestID: "ws_CO_1"}
+			callback.CallbackMetadata.Item = tt.items
+
+			got, err := stkPayment(callback)
+			if tt.wantErr {
+				if !errors.Is(err, xerrors.ErrInvalidInput) {
+					t.Fatalf("stkPayment() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("stkPayment() unexpected error: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("stkPayment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestC2BPayment(t *testing.T) {
+	paidAt := time.Date(2026, 10, 16, 9, 0, 0, 0, mpesaLocation)
+
+	tests := []struct {
+		name     string
+		callback transaction.MpesaCallback
+		want     *mpesaPayment
+		wantErr  bool
+	}{
+		{
+			"confirmation",
+			transaction.MpesaCallback{
+				TransID: "QJK7ABC123", TransAmount: "100.00", MSISDN: "254712345678", TransTime: "20261016090000",
+				BillRefNumber: " DATA-1GB-1D-7 ", FirstName: "Jane", LastName: "Doe",
+			},
+			&mpesaPayment{
+				Receipt: "QJK7ABC123", Amount: 100, Phone: "254712345678", PaidAt: paidAt,
+				Reference: "DATA-1GB-1D-7", PayerName: "Jane Doe",
+			},
+			false,
+		},
+		{
+			"first name only",
+			transaction.MpesaCallback{TransID: "QJK7ABC124", TransAmount: " 20 ", TransTime: "20261016090000", FirstName: "Jane"},
+			&mpesaPayment{Receipt: "QJK7ABC124", Amount: 20, PaidAt: paidAt, PayerName: "Jane"},
+			false,
+		},
+		{"non-numeric amount", transaction.MpesaCallback{TransID: "QJK7ABC125", TransAmount: "ten"}, nil, true},
+		{"zero amount", transaction.MpesaCallback{TransID: "QJK7ABC126", TransAmount: "0"}, nil, true},
+		{"missing amount", transaction.MpesaCallback{TransID: "QJK7ABC127"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c2bPayment(&tt.callback)
+			if tt.wantErr {
+				if !errors.Is(err, xerrors.ErrInvalidInput) {
+					t.Fatalf("c2bPayment() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("c2bPayment() unexpected error: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("c2bPayment() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngestMpesaCallbackCompletesMatchingRequest(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "mpesa@example.com")
+	if err := svc.configSvc.SetMpesaConfig(ctx, agentID, &config.MpesaConfig{ShortCode: "600100", CallbackSecret: "s3cret"}); err != nil {
+		t.Fatalf("SetMpesaConfig: %v", err)
+	}
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	requestID, reference := seedRequest(t, pool, agentID, offerID, "0712345678", 50)
+
+	payload, err := json.Marshal(transaction.MpesaCallback{
+		TransID:           "QJK7XYZ001",
+		TransTime:         "20261016090000",
+		TransAmount:       "50",
+		BusinessShortCode: "600100",
+		BillRefNumber:     reference,
+		MSISDN:            "254712345678",
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal callback: %v", err)
+	}
+	signature := webhook.Sign(payload, "s3cret")
+
+	result, err := svc.IngestMpesaCallback(ctx, payload, signature)
+	if err != nil {
+		t.Fatalf("IngestMpesaCallback: %v", err)
+	}
+	if result.Status != transaction.MpesaCallbackMatched || result.RequestID != requestID || result.MatchedBy != "reference" {
+		t.Fatalf("result = %+v, want request %d matched by reference", result, requestID)
+	}
+
+	request, err := svc.requestRepo.FindByID(ctx, requestID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if request.MpesaReceiptNumber.String != "QJK7XYZ001" {
+		t.Errorf("receipt = %q, want QJK7XYZ001", request.MpesaReceiptNumber.String)
+	}
+
+	// M-Pesa retrying the same confirmation is reported as a duplicate
+	result, err = svc.IngestMpesaCallback(ctx, payload, signature)
+	if err != nil || result.Status != transaction.MpesaCallbackDuplicate || result.RequestID != requestID {
+		t.Fatalf("retry = %+v, %v; want a duplicate of request %d", result, err, requestID)
+	}
+
+	// A retry that got past the lookup loses to the unique receipt index
+	otherID, _ := seedRequest(t, pool, agentID, offerID, "0712345678", 50)
+	other, err := svc.requestRepo.FindByID(ctx, otherID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	result, err = svc.recordMpesaPayment(ctx, other, &mpesaPayment{Receipt: "QJK7XYZ001", Amount: 50, PaidAt: time.Now()}, "reference")
+	if err != nil || result.Status != transaction.MpesaCallbackDuplicate || result.RequestID != requestID {
+		t.Fatalf("racing record = %+v, %v; want a duplicate of request %d", result, err, requestID)
+	}
+}
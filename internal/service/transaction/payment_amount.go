@@ -0,0 +1,57 @@
+// internal/service/transaction/payment_amount.go
+package transaction
+
+import (
+	"math"
+	"strings"
+
+	domainoffer "bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/transaction"
+)
+
+// metadataKeyPaymentDiscrepancy flags requests whose amount paid differs from the offer price
+const metadataKeyPaymentDiscrepancy = "payment_discrepancy"
+
+// defaultAmountTolerance absorbs rounding in the M-Pesa amount
+const defaultAmountTolerance = 1.0
+
+type paymentDiscrepancyKind string
+
+const (
+	paymentUnderpaid paymentDiscrepancyKind = "underpayment"
+	paymentOverpaid  paymentDiscrepancyKind = "overpayment"
+)
+
+// paymentDiscrepancy is stored in request metadata when the amount paid is off by more than the tolerance
+type paymentDiscrepancy struct {
+	Kind       paymentDiscrepancyKind `json:"kind"`
+	Expected   float64                `json:"expected"`
+	Paid       float64                `json:"paid"`
+	Difference float64                `json:"difference"`
+}
+
+// checkPaymentAmount compares the amount paid with the offer's discounted price.
+// Amounts in another currency are not compared.
+func (s *TransactionService) checkPaymentAmount(o *domainoffer.AgentOffer, input *transaction.CreateOfferRequestInput) *paymentDiscrepancy {
+	if !strings.EqualFold(input.Currency, o.Currency) {
+		return nil
+	}
+
+	expected, _ := s.offerSvc.QuoteDiscountedPrice(o)
+	difference := math.Round((input.AmountPaid-expected)*100) / 100
+	if math.Abs(difference) <= s.amountTolerance {
+		return nil
+	}
+
+	kind := paymentOverpaid
+	if difference < 0 {
+		kind = paymentUnderpaid
+	}
+
+	return &paymentDiscrepancy{
+		Kind:       kind,
+		Expected:   expected,
+		Paid:       input.AmountPaid,
+		Difference: difference,
+	}
+}
@@ -0,0 +1,43 @@
+// internal/service/transaction/payment_amount_test.go
+package transaction
+
+import (
+	"testing"
+
+	domainoffer "bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/transaction"
+	offersvc "bingwa-service/internal/service/offer"
+)
+
+func TestCheckPaymentAmount(t *testing.T) {
+	s := &TransactionService{offerSvc: &offersvc.OfferService{}, amountTolerance: defaultAmountTolerance}
+	o := &domainoffer.AgentOffer{Price: 100, Currency: "KES"}
+	discounted := &domainoffer.AgentOffer{Price: 100, Currency: "KES", DiscountPercentage: 20}
+
+	tests := []struct {
+		name     string
+		offer    *domainoffer.AgentOffer
+		paid     float64
+		currency string
+		want     *paymentDiscrepancy
+	}{
+		{"exact amount", o, 100, "KES", nil},
+		{"within tolerance below", o, 99, "KES", nil},
+		{"within tolerance above", o, 101, "kes", nil},
+		{"underpaid", o, 90, "KES", &paymentDiscrepancy{Kind: paymentUnderpaid, Expected: 100, Paid: 90, Difference: -10}},
+		{"overpaid", o, 150, "KES", &paymentDiscrepancy{Kind: paymentOverpaid, Expected: 100, Paid: 150, Difference: 50}},
+		{"discounted price paid", discounted, 80, "KES", nil},
+		{"full price paid for a discounted offer", discounted, 100, "KES", &paymentDiscrepancy{Kind: paymentOverpaid, Expected: 80, Paid: 100, Difference: 20}},
+		{"other currency not compared", o, 1, "USD", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &transaction.CreateOfferRequestInput{AmountPaid: tt.paid, Currency: tt.currency}
+			got := s.checkPaymentAmount(tt.offer, input)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("checkPaymentAmount() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
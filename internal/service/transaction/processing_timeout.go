@@ -0,0 +1,124 @@
+// internal/service/transaction/processing_timeout.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+
+	"go.uber.org/zap"
+)
+
+// processingTimeoutReason is recorded on requests and redemptions failed by the timeout worker
+const processingTimeoutReason = "processing_timeout"
+
+// ProcessingTimeoutWorker fails requests stuck in processing (e.g. the device died mid-USSD)
+// along with their redemptions, so they don't stay unresolved forever.
+type ProcessingTimeoutWorker struct {
+	requestRepo    *postgres.OfferRequestRepository
+	redemptionRepo *postgres.OfferRedemptionRepository
+	auditRepo      *postgres.TransactionAuditRepository
+	deviceSlots    *cache.DeviceSlots
+	db             *postgres.DB
+	timeout        time.Duration
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+func NewProcessingTimeoutWorker(
+	requestRepo *postgres.OfferRequestRepository,
+	redemptionRepo *postgres.OfferRedemptionRepository,
+	auditRepo *postgres.TransactionAuditRepository,
+	deviceSlots *cache.DeviceSlots,
+	db *postgres.DB,
+	timeout time.Duration,
+	interval time.Duration,
+	logger *zap.Logger,
+) *ProcessingTimeoutWorker {
+	return &ProcessingTimeoutWorker{
+		requestRepo:    requestRepo,
+		redemptionRepo: redemptionRepo,
+		auditRepo:      auditRepo,
+		deviceSlots:    deviceSlots,
+		db:             db,
+		timeout:        timeout,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *ProcessingTimeoutWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("processing timeout run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce fails requests that have been processing longer than the timeout and returns how many were failed.
+// Like UpdateOfferRequestStatus it audits the change, queues the webhooks and frees the device slots.
+func (w *ProcessingTimeoutWorker) RunOnce(ctx context.Context) (int, error) {
+	tx, err := w.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cutoff := time.Now().Add(-w.timeout)
+	requestIDs, err := w.requestRepo.FailStaleProcessingWithTx(ctx, tx, cutoff, processingTimeoutReason, transaction.FailureCodeUSSDTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if len(requestIDs) == 0 {
+		return 0, nil
+	}
+
+	redemptions, err := w.redemptionRepo.FailByRequestIDsWithTx(ctx, tx, requestIDs, processingTimeoutReason)
+	if err != nil {
+		return 0, err
+	}
+
+	auditPayload := map[string]interface{}{
+		"previous_status": transaction.TransactionStatusProcessing,
+		"failure_reason":  processingTimeoutReason,
+		"failure_code":    transaction.FailureCodeUSSDTimeout,
+	}
+	if err := w.auditRepo.CreateForRequestsWithTx(ctx, tx, requestIDs, transaction.AuditEventStatusUpdated, auditPayload); err != nil {
+		return 0, err
+	}
+
+	if err := w.redemptionRepo.EnqueueStatusEventsWithTx(ctx, tx, requestIDs, transaction.TransactionStatusProcessing); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, id := range requestIDs {
+		if err := w.deviceSlots.Release(ctx, id); err != nil {
+			w.logger.Warn("failed to release device slot", zap.Int64("request_id", id), zap.Error(err))
+		}
+	}
+
+	w.logger.Info("timed out processing requests failed",
+		zap.Int("requests", len(requestIDs)),
+		zap.Int64("redemptions", redemptions),
+	)
+
+	return len(requestIDs), nil
+}
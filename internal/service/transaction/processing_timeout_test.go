@@ -0,0 +1,99 @@
+// internal/service/transaction/processing_timeout_test.go
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	"bingwa-service/internal/testutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestProcessingTimeoutWorkerFailsStaleRequests(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	deviceSlots := cache.NewDeviceSlots(client, time.Hour)
+
+	agentID := testutil.Identity(t, pool, "timeout@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	// processing seeds a request and its redemption that started processing at the given time
+	processing := func(startedAt time.Time) int64 {
+		t.Helper()
+		redemptionID := seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusProcessing, 50, startedAt)
+		var requestID int64
+		if err := pool.QueryRow(ctx, `
+			UPDATE offer_requests SET status = 'processing', processing_started_at = $2
+			WHERE id = (SELECT offer_request_id FROM offer_redemptions WHERE id = $1)
+			RETURNING id
+		`, redemptionID, startedAt).Scan(&requestID); err != nil {
+			t.Fatalf("failed to mark request processing: %v", err)
+		}
+		return requestID
+	}
+	staleID := processing(time.Now().Add(-time.Hour))
+	freshID := processing(time.Now())
+	if _, err := deviceSlots.TryAcquire(ctx, agentID, "device-1", 5, []int64{staleID, freshID}); err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+
+	worker := NewProcessingTimeoutWorker(
+		postgres.NewOfferRequestRepository(pool),
+		postgres.NewOfferRedemptionRepository(pool),
+		postgres.NewTransactionAuditRepository(pool),
+		deviceSlots,
+		postgres.NewDB(pool),
+		10*time.Minute, time.Minute, zap.NewNop(),
+	)
+
+	if n, err := worker.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1 request failed", n, err)
+	}
+
+	status := func(requestID int64) (request, redemption string, reason sql.NullString) {
+		t.Helper()
+		if err := pool.QueryRow(ctx, `
+			SELECT r.status::text, d.status::text, r.failure_reason
+			FROM offer_requests r JOIN offer_redemptions d ON d.offer_request_id = r.id
+			WHERE r.id = $1
+		`, requestID).Scan(&request, &redemption, &reason); err != nil {
+			t.Fatalf("failed to read request %d: %v", requestID, err)
+		}
+		return request, redemption, reason
+	}
+	if request, redemption, reason := status(staleID); request != "failed" || redemption != "failed" || reason.String != processingTimeoutReason {
+		t.Errorf("stale request = %s (%s) with redemption %s, want both failed for %s", request, reason.String, redemption, processingTimeoutReason)
+	}
+	if request, redemption, _ := status(freshID); request != "processing" || redemption != "processing" {
+		t.Errorf("fresh request = %s with redemption %s, want both still processing", request, redemption)
+	}
+
+	var audits int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM transaction_audit WHERE offer_request_id = $1 AND status = 'failed'
+	`, staleID).Scan(&audits); err != nil {
+		t.Fatalf("failed to count audit entries: %v", err)
+	}
+	if audits != 1 {
+		t.Errorf("stale request has %d failure audit entries, want 1", audits)
+	}
+
+	// Only the stale request's device slot is freed
+	if n, err := deviceSlots.InFlight(ctx, agentID, "device-1"); err != nil || n != 1 {
+		t.Errorf("in-flight on device = %d, %v; want 1", n, err)
+	}
+
+	if n, err := worker.RunOnce(ctx); err != nil || n != 0 {
+		t.Errorf("second run = %d, %v; want nothing left to fail", n, err)
+	}
+}
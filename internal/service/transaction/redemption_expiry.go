@@ -0,0 +1,173 @@
+// internal/service/transaction/redemption_expiry.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/emaillog"
+	"bingwa-service/internal/domain/sms"
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+	"bingwa-service/internal/service/email"
+	notificationsvc "bingwa-service/internal/service/notification"
+
+	"go.uber.org/zap"
+)
+
+// RedemptionExpiryWorker warns about redemptions whose validity is about to end.
+// The customer is texted (and emailed when an address is on file) and the agent is notified.
+type RedemptionExpiryWorker struct {
+	redemptionRepo *postgres.OfferRedemptionRepository
+	offerRepo      *postgres.AgentOfferRepository
+	customerRepo   *postgres.AgentCustomerRepository
+	smsRepo        *postgres.SMSOutboxRepository
+	notifService   *notificationsvc.NotificationService
+	sender         *email.EmailSender
+	notice         time.Duration
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+func NewRedemptionExpiryWorker(
+	redemptionRepo *postgres.OfferRedemptionRepository,
+	offerRepo *postgres.AgentOfferRepository,
+	customerRepo *postgres.AgentCustomerRepository,
+	smsRepo *postgres.SMSOutboxRepository,
+	notifService *notificationsvc.NotificationService,
+	sender *email.EmailSender,
+	notice time.Duration,
+	interval time.Duration,
+	logger *zap.Logger,
+) *RedemptionExpiryWorker {
+	return &RedemptionExpiryWorker{
+		redemptionRepo: redemptionRepo,
+		offerRepo:      offerRepo,
+		customerRepo:   customerRepo,
+		smsRepo:        smsRepo,
+		notifService:   notifService,
+		sender:         sender,
+		notice:         notice,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *RedemptionExpiryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("redemption expiry run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce notifies about redemptions expiring within the notice window and returns how many were notified
+func (w *RedemptionExpiryWorker) RunOnce(ctx context.Context) (int, error) {
+	redemptions, err := w.redemptionRepo.ClaimExpiringRedemptions(ctx, w.notice)
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for i := range redemptions {
+		redemption := &redemptions[i]
+
+		if err := w.notify(ctx, redemption); err != nil {
+			w.logger.Warn("failed to send redemption expiry notice",
+				zap.Int64("redemption_id", redemption.ID),
+				zap.Error(err),
+			)
+			if err := w.redemptionRepo.ReleaseExpiryNotice(ctx, redemption.ID); err != nil {
+				w.logger.Error("failed to release expiry notice", zap.Int64("redemption_id", redemption.ID), zap.Error(err))
+			}
+			continue
+		}
+		notified++
+	}
+
+	if notified > 0 {
+		w.logger.Info("redemption expiry notices sent", zap.Int("count", notified))
+	}
+
+	return notified, nil
+}
+
+// notify texts the customer, alerts the agent and emails the customer if possible
+func (w *RedemptionExpiryWorker) notify(ctx context.Context, redemption *transaction.OfferRedemption) error {
+	offerName := "Your offer"
+	if o, err := w.offerRepo.FindByID(ctx, redemption.OfferID); err == nil {
+		offerName = o.Name
+	}
+
+	validUntil := redemption.ValidUntil.Time
+
+	if err := w.smsRepo.Enqueue(ctx, &sms.Message{
+		AgentIdentityID: redemption.AgentIdentityID,
+		PhoneNumber:     redemption.CustomerPhone,
+		Body:            redemptionExpirySMS(offerName, validUntil),
+		Purpose:         sms.PurposeRedemptionExpiry,
+	}); err != nil {
+		return fmt.Errorf("failed to text customer: %w", err)
+	}
+
+	// The customer has been texted; an agent or email failure below must not resend it
+	message := fmt.Sprintf("%s for %s expires on %s", offerName, redemption.CustomerPhone, validUntil.Format("02 Jan 2006 15:04"))
+	if err := w.notifService.SendInfoNotification(ctx, redemption.AgentIdentityID, "Customer offer expiring soon", message, map[string]interface{}{
+		"redemption_id":  redemption.ID,
+		"customer_phone": redemption.CustomerPhone,
+		"offer_id":       redemption.OfferID,
+		"valid_until":    validUntil,
+	}); err != nil {
+		w.logger.Warn("failed to notify agent about expiring redemption",
+			zap.Int64("redemption_id", redemption.ID),
+			zap.Error(err),
+		)
+	}
+
+	if redemption.CustomerID.Valid {
+		if c, err := w.customerRepo.FindByID(ctx, redemption.CustomerID.Int64); err == nil && c.Email.Valid && c.Email.String != "" {
+			subject, body := redemptionExpiryEmail(c.FullName.String, offerName, validUntil)
+			if err := w.sender.SendAs(emaillog.TypeRedemptionExpiry, c.Email.String, subject, body); err != nil {
+				w.logger.Warn("failed to email customer about expiring redemption",
+					zap.Int64("redemption_id", redemption.ID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// redemptionExpirySMS builds the customer expiry text
+func redemptionExpirySMS(offerName string, validUntil time.Time) string {
+	return fmt.Sprintf("%s expires on %s. Contact your agent to renew it before then.", offerName, validUntil.Format("02 Jan 2006 15:04"))
+}
+
+// redemptionExpiryEmail builds the customer expiry email
+func redemptionExpiryEmail(fullName, offerName string, validUntil time.Time) (string, string) {
+	if fullName == "" {
+		fullName = "there"
+	}
+
+	subject := "Your offer is expiring soon"
+	body := fmt.Sprintf(`
+		<h2>Offer Expiring Soon</h2>
+		<p>Hello %s,</p>
+		<p>Your <strong>%s</strong> expires on <strong>%s</strong>.</p>
+		<p>Contact your agent to renew it before then.</p>
+	`, fullName, offerName, validUntil.Format("02 Jan 2006 15:04"))
+
+	return subject, body
+}
@@ -0,0 +1,64 @@
+// internal/service/transaction/redemption_expiry_test.go
+package transaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/sms"
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/repository/postgres"
+	notificationsvc "bingwa-service/internal/service/notification"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestRedemptionExpiryWorkerNotifiesOnce(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.Postgres(t)
+
+	agentID := testutil.Identity(t, pool, "expiry@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	expiresIn := func(d time.Duration) {
+		t.Helper()
+		id := seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusSuccess, 50, time.Now().Add(-time.Hour))
+		if _, err := pool.Exec(ctx, `UPDATE offer_redemptions SET valid_until = $2 WHERE id = $1`, id, time.Now().Add(d)); err != nil {
+			t.Fatalf("failed to set validity: %v", err)
+		}
+	}
+	expiresIn(2 * time.Hour)
+	// Outside the notice window, or already over
+	expiresIn(72 * time.Hour)
+	expiresIn(-time.Hour)
+
+	worker := NewRedemptionExpiryWorker(
+		postgres.NewOfferRedemptionRepository(pool),
+		postgres.NewAgentOfferRepository(pool, postgres.NewOfferUSSDCodeRepository(pool), postgres.NewDB(pool)),
+		postgres.NewAgentCustomerRepository(pool),
+		postgres.NewSMSOutboxRepository(pool),
+		notificationsvc.NewNotificationService(postgres.NewNotificationRepository(pool), nil),
+		nil, // the redemptions have no customer record, so nothing is emailed
+		24*time.Hour, time.Hour, zap.NewNop(),
+	)
+
+	if n, err := worker.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("first run = %d, %v; want 1 notice", n, err)
+	}
+	if n, err := worker.RunOnce(ctx); err != nil || n != 0 {
+		t.Errorf("second run = %d, %v; want no repeat notice", n, err)
+	}
+
+	var texts, notifications int
+	if err := pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM sms_outbox WHERE phone_number = '254712345678' AND purpose = $2),
+		       (SELECT COUNT(*) FROM notifications WHERE identity_id = $1)
+	`, agentID, string(sms.PurposeRedemptionExpiry)).Scan(&texts, &notifications); err != nil {
+		t.Fatalf("failed to count notices: %v", err)
+	}
+	if texts != 1 || notifications != 1 {
+		t.Errorf("%d texts and %d agent notifications, want 1 each", texts, notifications)
+	}
+}
@@ -0,0 +1,152 @@
+// internal/service/transaction/risk_score.go
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domainoffer "bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
+	"bingwa-service/internal/pkg/pagination"
+
+	"go.uber.org/zap"
+)
+
+// metadataKeyRiskReasons lists why a request scored as risky
+const metadataKeyRiskReasons = "risk_reasons"
+
+// defaultRiskHoldThreshold is the score at which pending requests are held for review
+const defaultRiskHoldThreshold = 70
+
+// riskVelocityWindow is how far back repeat requests from the same phone count towards velocity
+const riskVelocityWindow = 10 * time.Minute
+
+// Score weights; the total is capped at 100
+const (
+	riskPerRecentRequest   = 20
+	riskMaxVelocity        = 60
+	riskUnderpayment       = 30
+	riskOverpayment        = 10
+	riskPayerPhoneMismatch = 15
+	riskMaxScore           = 100
+)
+
+// SetRiskHoldThreshold configures the risk score at which pending requests are held for review; 0 disables holds
+func (s *TransactionService) SetRiskHoldThreshold(threshold int) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	s.riskHoldThreshold = threshold
+}
+
+// ScoreRequest rates how suspicious an incoming request looks from the phone's recent request
+// velocity and from amount and payer anomalies
+func (s *TransactionService) ScoreRequest(ctx context.Context, agentID int64, o *domainoffer.AgentOffer, input *transaction.CreateOfferRequestInput) (*transaction.RiskAssessment, error) {
+	assessment := &transaction.RiskAssessment{}
+
+	recent, err := s.requestRepo.CountRecentByPhone(ctx, agentID, input.CustomerPhone, time.Now().Add(-riskVelocityWindow))
+	if err != nil {
+		return nil, err
+	}
+	if recent > 0 {
+		velocity := recent * riskPerRecentRequest
+		if velocity > riskMaxVelocity {
+			velocity = riskMaxVelocity
+		}
+		assessment.Score += velocity
+		assessment.Reasons = append(assessment.Reasons, fmt.Sprintf("%d requests from this phone in the last %s", recent, riskVelocityWindow))
+	}
+
+	if discrepancy := s.checkPaymentAmount(o, input); discrepancy != nil {
+		if discrepancy.Kind == paymentUnderpaid {
+			assessment.Score += riskUnderpayment
+		} else {
+			assessment.Score += riskOverpayment
+		}
+		assessment.Reasons = append(assessment.Reasons, fmt.Sprintf("%s: expected %.2f, received %.2f", discrepancy.Kind, discrepancy.Expected, discrepancy.Paid))
+	}
+
+	if input.MpesaPhoneNumber != "" && input.MpesaPhoneNumber != input.CustomerPhone {
+		assessment.Score += riskPayerPhoneMismatch
+		assessment.Reasons = append(assessment.Reasons, "paying phone differs from customer phone")
+	}
+
+	if assessment.Score > riskMaxScore {
+		assessment.Score = riskMaxScore
+	}
+
+	return assessment, nil
+}
+
+// shouldHold reports whether a pending request's risk is high enough to wait for review
+func (s *TransactionService) shouldHold(risk *transaction.RiskAssessment) bool {
+	return risk != nil && s.riskHoldThreshold > 0 && risk.Score >= s.riskHoldThreshold
+}
+
+// GetHeldRequests lists pending requests held for review, riskiest first
+func (s *TransactionService) GetHeldRequests(ctx context.Context, agentID int64, page, pageSize int) (*transaction.OfferRequestListResponse, error) {
+	page, pageSize = pagination.Clamp(page, pageSize)
+
+	status := transaction.TransactionStatusPending
+	held := true
+	filters := &transaction.OfferRequestListFilters{
+		Status:        &status,
+		HeldForReview: &held,
+		Page:          page,
+		PageSize:      pageSize,
+		SortBy:        "risk_score",
+		SortOrder:     "desc",
+	}
+
+	requests, total, err := s.requestRepo.List(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get held requests: %w", err)
+	}
+
+	meta := pagination.Compute(total, page, pageSize)
+
+	return &transaction.OfferRequestListResponse{
+		Requests:   requests,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
+	}, nil
+}
+
+// ReviewHeldRequest releases a held request for dispatch, or cancels it when rejected
+func (s *TransactionService) ReviewHeldRequest(ctx context.Context, agentID, requestID int64, approve bool) error {
+	request, err := s.requestRepo.FindByID(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.AgentIdentityID != agentID {
+		return xerrors.ErrNotFound
+	}
+	if !request.HeldForReview || request.Status != transaction.TransactionStatusPending {
+		return fmt.Errorf("%w: request is not held for review", xerrors.ErrConflict)
+	}
+
+	// Cancel before releasing so a rejected request is never dispatchable
+	if !approve {
+		if err := s.UpdateOfferRequestStatus(ctx, agentID, requestID, transaction.TransactionStatusCancelled, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := s.requestRepo.ClearHold(ctx, requestID); err != nil {
+		return err
+	}
+
+	s.logger.Info("held offer request reviewed",
+		zap.Int64("request_id", requestID),
+		zap.Bool("approved", approve),
+		zap.Int("risk_score", request.RiskScore),
+		zap.String("customer_phone", mask.Phone(request.CustomerPhone)),
+	)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// internal/service/transaction/risk_score_test.go
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	domainoffer "bingwa-service/internal/domain/offer"
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/testutil"
+)
+
+func TestScoreRequestRapidRepeatScoresHigher(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "risk@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	o := &domainoffer.AgentOffer{ID: offerID, Price: 50, Currency: "KES"}
+	input := &transaction.CreateOfferRequestInput{
+		OfferID:       offerID,
+		CustomerPhone: "254712345678",
+		PaymentMethod: transaction.PaymentMethodMpesa,
+		AmountPaid:    50,
+		Currency:      "KES",
+	}
+
+	first, err := svc.ScoreRequest(ctx, agentID, o, input)
+	if err != nil {
+		t.Fatalf("ScoreRequest: %v", err)
+	}
+	if first.Score != 0 {
+		t.Errorf("first request score = %d (%v), want 0", first.Score, first.Reasons)
+	}
+
+	// Two requests from the same phone moments ago make the next one look like rapid-fire
+	seedRequest(t, pool, agentID, offerID, input.CustomerPhone, 50)
+	seedRequest(t, pool, agentID, offerID, input.CustomerPhone, 50)
+
+	repeat, err := svc.ScoreRequest(ctx, agentID, o, input)
+	if err != nil {
+		t.Fatalf("ScoreRequest: %v", err)
+	}
+	if repeat.Score != 2*riskPerRecentRequest {
+		t.Errorf("repeat request score = %d, want %d", repeat.Score, 2*riskPerRecentRequest)
+	}
+	if len(repeat.Reasons) != 1 {
+		t.Errorf("repeat request reasons = %v, want one velocity reason", repeat.Reasons)
+	}
+
+	// Another phone is not affected
+	other := *input
+	other.CustomerPhone = "254700000001"
+	if score, err := svc.ScoreRequest(ctx, agentID, o, &other); err != nil || score.Score != 0 {
+		t.Errorf("other phone score = %+v, %v; want 0", score, err)
+	}
+
+	// Velocity is capped, and an underpayment adds on top up to the maximum score
+	for i := 0; i < 3; i++ {
+		seedRequest(t, pool, agentID, offerID, input.CustomerPhone, 50)
+	}
+	underpaid := *input
+	underpaid.AmountPaid = 10
+	capped, err := svc.ScoreRequest(ctx, agentID, o, &underpaid)
+	if err != nil {
+		t.Fatalf("ScoreRequest: %v", err)
+	}
+	if capped.Score != riskMaxVelocity+riskUnderpayment {
+		t.Errorf("underpaid rapid-fire score = %d, want %d", capped.Score, riskMaxVelocity+riskUnderpayment)
+	}
+}
+
+func TestGetHeldRequestsPagesRiskiestFirst(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "held@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	for _, score := range []int{75, 95, 85} {
+		id, _ := seedRequest(t, pool, agentID, offerID, "254712345678", 50)
+		if _, err := pool.Exec(ctx, `UPDATE offer_requests SET risk_score = $2, held_for_review = TRUE WHERE id = $1`, id, score); err != nil {
+			t.Fatalf("failed to hold request: %v", err)
+		}
+	}
+	// A request that is not held is left out
+	seedRequest(t, pool, agentID, offerID, "254700000001", 50)
+
+	page, err := svc.GetHeldRequests(ctx, agentID, 2, 2)
+	if err != nil {
+		t.Fatalf("GetHeldRequests: %v", err)
+	}
+	if page.Total != 3 || page.TotalPages != 2 || len(page.Requests) != 1 {
+		t.Fatalf("page 2 = %d of %d total over %d pages, want 1 of 3 over 2", len(page.Requests), page.Total, page.TotalPages)
+	}
+	if page.Requests[0].RiskScore != 75 {
+		t.Errorf("last held request risk = %d, want 75", page.Requests[0].RiskScore)
+	}
+
+	// Out-of-range paging falls back to the defaults
+	page, err = svc.GetHeldRequests(ctx, agentID, 0, 1000)
+	if err != nil {
+		t.Fatalf("GetHeldRequests: %v", err)
+	}
+	if page.Page != 1 || page.PageSize != 100 || len(page.Requests) != 3 || page.Requests[0].RiskScore != 95 {
+		t.Errorf("clamped page = %+v, want page 1 of size 100 starting at risk 95", page)
+	}
+}
@@ -0,0 +1,290 @@
+// internal/service/transaction/stats_test.go
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/testutil"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// seedStatsRequest inserts a request through seedRequest and sets the columns the stats group by
+func seedStatsRequest(t *testing.T, pool *pgxpool.Pool, agentID, offerID int64, source transaction.RequestSource, status transaction.TransactionStatus, amount float64) int64 {
+	t.Helper()
+
+	id, _ := seedRequest(t, pool, agentID, offerID, "254712345678", amount)
+	if _, err := pool.Exec(context.Background(), `
+		UPDATE offer_requests SET source = $2, status = $3 WHERE id = $1
+	`, id, source, status); err != nil {
+		t.Fatalf("failed to set request source and status: %v", err)
+	}
+	return id
+}
+
+func TestGetTransactionStatsSplitsBySource(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "sources@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	otherOfferID := testutil.Offer(t, pool, otherID, "DATA-1GB", 50)
+
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceUSSD, transaction.TransactionStatusSuccess, 50)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceUSSD, transaction.TransactionStatusSuccess, 50)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceUSSD, transaction.TransactionStatusFailed, 50)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, transaction.TransactionStatusSuccess, 80)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, transaction.TransactionStatusPending, 80)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceWeb, transaction.TransactionStatusFailed, 30)
+	seedStatsRequest(t, pool, otherID, otherOfferID, transaction.RequestSourceWeb, transaction.TransactionStatusSuccess, 999)
+
+	stats, err := svc.GetTransactionStats(ctx, agentID, nil)
+	if err != nil {
+		t.Fatalf("GetTransactionStats: %v", err)
+	}
+	if stats.TotalRequests != 6 || stats.SuccessfulRequests != 3 || stats.TotalRevenue != 180 {
+		t.Errorf("totals = %d requests, %d successful, %.2f revenue; want 6, 3, 180",
+			stats.TotalRequests, stats.SuccessfulRequests, stats.TotalRevenue)
+	}
+
+	want := map[transaction.RequestSource]transaction.SourceStats{
+		transaction.RequestSourceUSSD: {Source: transaction.RequestSourceUSSD, TotalRequests: 3, SuccessfulRequests: 2, TotalRevenue: 100},
+		transaction.RequestSourceApp:  {Source: transaction.RequestSourceApp, TotalRequests: 2, SuccessfulRequests: 1, TotalRevenue: 80},
+		transaction.RequestSourceWeb:  {Source: transaction.RequestSourceWeb, TotalRequests: 1, SuccessfulRequests: 0, TotalRevenue: 0},
+	}
+	if len(stats.BySource) != len(want) {
+		t.Fatalf("by source = %+v, want %d sources", stats.BySource, len(want))
+	}
+	for _, got := range stats.BySource {
+		if got != want[got.Source] {
+			t.Errorf("source %s = %+v, want %+v", got.Source, got, want[got.Source])
+		}
+	}
+	// Busiest channel first
+	if stats.BySource[0].Source != transaction.RequestSourceUSSD {
+		t.Errorf("first source = %s, want ussd", stats.BySource[0].Source)
+	}
+}
+
+func TestGetTransactionStatsDateRangeExcludesOutOfRangeRecords(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "range@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	now := time.Now()
+
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, transaction.TransactionStatusSuccess, 50)
+	old := seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, transaction.TransactionStatusSuccess, 80)
+	if _, err := pool.Exec(ctx, `UPDATE offer_requests SET request_time = $2 WHERE id = $1`, old, now.AddDate(0, 0, -40)); err != nil {
+		t.Fatalf("failed to backdate request: %v", err)
+	}
+	// Each redemption brings its own pending request, made now
+	seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusSuccess, 30, now.AddDate(0, 0, -2))
+	seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusSuccess, 30, now.AddDate(0, 0, -40))
+
+	from, to := now.AddDate(0, 0, -15), now.Add(time.Minute)
+	stats, err := svc.GetTransactionStats(ctx, agentID, &transaction.StatsFilters{From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("GetTransactionStats: %v", err)
+	}
+	if stats.TotalRequests != 3 || stats.SuccessfulRequests != 1 || stats.TotalRevenue != 50 || stats.TotalRedemptions != 1 {
+		t.Errorf("ranged stats = %d requests, %d successful, %.2f revenue, %d redemptions; want 3, 1, 50, 1",
+			stats.TotalRequests, stats.SuccessfulRequests, stats.TotalRevenue, stats.TotalRedemptions)
+	}
+
+	lifetime, err := svc.GetTransactionStats(ctx, agentID, nil)
+	if err != nil {
+		t.Fatalf("GetTransactionStats: %v", err)
+	}
+	if lifetime.TotalRequests != 4 || lifetime.TotalRevenue != 130 || lifetime.TotalRedemptions != 2 {
+		t.Errorf("lifetime stats = %d requests, %.2f revenue, %d redemptions; want 4, 130, 2",
+			lifetime.TotalRequests, lifetime.TotalRevenue, lifetime.TotalRedemptions)
+	}
+
+	if _, err := svc.GetTransactionStats(ctx, agentID, &transaction.StatsFilters{From: &to, To: &from}); !errors.Is(err, xerrors.ErrInvalidInput) {
+		t.Errorf("reversed range error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestGetSalesHeatmapBinsGeotaggedSales(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "heatmap@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	geotag := func(id int64, lat, lng float64) {
+		t.Helper()
+		if _, err := pool.Exec(ctx, `UPDATE offer_requests SET latitude = $2, longitude = $3 WHERE id = $1`, id, lat, lng); err != nil {
+			t.Fatalf("failed to geotag request: %v", err)
+		}
+	}
+	success, pending := transaction.TransactionStatusSuccess, transaction.TransactionStatusPending
+
+	// Two Nairobi sales share a cell, one Mombasa sale has its own
+	geotag(seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, success, 50), -1.28, 36.82)
+	geotag(seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, success, 80), -1.29, 36.81)
+	geotag(seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, success, 30), -4.04, 39.66)
+	// Unfinished and untagged requests are left out
+	geotag(seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceApp, pending, 50), -1.28, 36.82)
+	seedStatsRequest(t, pool, agentID, offerID, transaction.RequestSourceUSSD, success, 50)
+
+	heatmap, err := svc.GetSalesHeatmap(ctx, agentID, &transaction.SalesHeatmapBounds{CellSize: 0.1})
+	if err != nil {
+		t.Fatalf("GetSalesHeatmap: %v", err)
+	}
+	if heatmap.TotalSales != 3 || len(heatmap.Cells) != 2 {
+		t.Fatalf("heatmap = %d sales in %d cells, want 3 in 2", heatmap.TotalSales, len(heatmap.Cells))
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	nairobi, mombasa := heatmap.Cells[0], heatmap.Cells[1]
+	if nairobi.Count != 2 || nairobi.Revenue != 130 || !near(nairobi.Latitude, -1.25) || !near(nairobi.Longitude, 36.85) {
+		t.Errorf("busiest cell = %+v, want 2 sales worth 130 centred on -1.25, 36.85", nairobi)
+	}
+	if mombasa.Count != 1 || mombasa.Revenue != 30 || !near(mombasa.Latitude, -4.05) || !near(mombasa.Longitude, 39.65) {
+		t.Errorf("second cell = %+v, want 1 sale worth 30 centred on -4.05, 39.65", mombasa)
+	}
+
+	// Bounds keep only the sales inside them
+	minLat := -2.0
+	heatmap, err = svc.GetSalesHeatmap(ctx, agentID, &transaction.SalesHeatmapBounds{CellSize: 0.1, MinLatitude: &minLat})
+	if err != nil {
+		t.Fatalf("GetSalesHeatmap within bounds: %v", err)
+	}
+	if heatmap.TotalSales != 2 || len(heatmap.Cells) != 1 {
+		t.Errorf("bounded heatmap = %d sales in %d cells, want 2 in 1", heatmap.TotalSales, len(heatmap.Cells))
+	}
+
+	maxLat := -3.0
+	if _, err := svc.GetSalesHeatmap(ctx, agentID, &transaction.SalesHeatmapBounds{MinLatitude: &minLat, MaxLatitude: &maxLat}); err == nil {
+		t.Error("GetSalesHeatmap accepted min_lat above max_lat")
+	}
+}
+
+func TestGetTransactionStatsGroupsFailuresByCode(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "failures@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	outcomes := []struct {
+		status transaction.TransactionStatus
+		code   transaction.FailureCode
+	}{
+		{transaction.TransactionStatusFailed, transaction.FailureCodeUSSDTimeout},
+		{transaction.TransactionStatusFailed, transaction.FailureCodeUSSDTimeout},
+		{transaction.TransactionStatusFailed, transaction.FailureCodeInsufficientBalance},
+		{transaction.TransactionStatusFailed, ""}, // no code given
+		{transaction.TransactionStatusSuccess, transaction.FailureCodeUSSDTimeout},
+	}
+	for i, outcome := range outcomes {
+		request, _, err := svc.CreateOfferRequest(ctx, agentID, &transaction.CreateOfferRequestInput{
+			OfferID:       offerID,
+			CustomerPhone: fmt.Sprintf("25470000000%d", i),
+			PaymentMethod: transaction.PaymentMethodMpesa,
+			AmountPaid:    50,
+		})
+		if err != nil {
+			t.Fatalf("CreateOfferRequest: %v", err)
+		}
+		response := &transaction.UpdateUSSDResponseInput{Status: outcome.status, FailureReason: "USSD said no", FailureCode: outcome.code}
+		if err := svc.UpdateOfferRequestStatus(ctx, agentID, request.ID, outcome.status, response); err != nil {
+			t.Fatalf("UpdateOfferRequestStatus(%s, %q): %v", outcome.status, outcome.code, err)
+		}
+	}
+
+	// A code outside the taxonomy is refused
+	request, _, err := svc.CreateOfferRequest(ctx, agentID, &transaction.CreateOfferRequestInput{
+		OfferID:       offerID,
+		CustomerPhone: "254700000009",
+		PaymentMethod: transaction.PaymentMethodMpesa,
+		AmountPaid:    50,
+	})
+	if err != nil {
+		t.Fatalf("CreateOfferRequest: %v", err)
+	}
+	bogus := &transaction.UpdateUSSDResponseInput{Status: transaction.TransactionStatusFailed, FailureCode: "out_of_airtime"}
+	if err := svc.UpdateOfferRequestStatus(ctx, agentID, request.ID, transaction.TransactionStatusFailed, bogus); err == nil {
+		t.Error("UpdateOfferRequestStatus accepted an unknown failure code")
+	}
+
+	stats, err := svc.GetTransactionStats(ctx, agentID, nil)
+	if err != nil {
+		t.Fatalf("GetTransactionStats: %v", err)
+	}
+	want := map[transaction.FailureCode]int64{
+		transaction.FailureCodeUSSDTimeout:         2,
+		transaction.FailureCodeInsufficientBalance: 1,
+		transaction.FailureCodeUnknown:             1,
+	}
+	if len(stats.FailureBreakdown) != len(want) {
+		t.Fatalf("failure breakdown = %+v, want %v", stats.FailureBreakdown, want)
+	}
+	for _, got := range stats.FailureBreakdown {
+		if got.Count != want[got.FailureCode] {
+			t.Errorf("%s failures = %d, want %d", got.FailureCode, got.Count, want[got.FailureCode])
+		}
+	}
+	if stats.FailureBreakdown[0].FailureCode != transaction.FailureCodeUSSDTimeout {
+		t.Errorf("most common failure = %s, want ussd_timeout", stats.FailureBreakdown[0].FailureCode)
+	}
+}
+
+func TestGetOfferSalesTimeSeriesBucketsByDay(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "series@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	otherOfferID := testutil.Offer(t, pool, agentID, "DATA-2GB", 90)
+
+	now := time.Now().UTC()
+	day0 := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -3)
+	day1, day2 := day0.AddDate(0, 0, 1), day0.AddDate(0, 0, 2)
+	success, failed := transaction.TransactionStatusSuccess, transaction.TransactionStatusFailed
+
+	seedRedemption(t, pool, agentID, offerID, success, 50, day0.Add(10*time.Hour))
+	seedRedemption(t, pool, agentID, offerID, success, 80, day0.Add(15*time.Hour))
+	seedRedemption(t, pool, agentID, offerID, success, 30, day2.Add(12*time.Hour))
+	// Failed sales, other offers and sales outside the range are left out
+	seedRedemption(t, pool, agentID, offerID, failed, 50, day2.Add(13*time.Hour))
+	seedRedemption(t, pool, agentID, otherOfferID, success, 90, day1.Add(12*time.Hour))
+	seedRedemption(t, pool, agentID, offerID, success, 50, day0.Add(-time.Hour))
+
+	series, err := svc.GetOfferSalesTimeSeries(ctx, agentID, offerID, day0, day0.AddDate(0, 0, 3), transaction.SalesSeriesDaily)
+	if err != nil {
+		t.Fatalf("GetOfferSalesTimeSeries: %v", err)
+	}
+
+	want := []transaction.SalesSeriesPoint{
+		{BucketStart: day0, Count: 2, Revenue: 130},
+		{BucketStart: day1}, // no sales, still reported
+		{BucketStart: day2, Count: 1, Revenue: 30},
+	}
+	if len(series.Points) != len(want) {
+		t.Fatalf("points = %+v, want %d days", series.Points, len(want))
+	}
+	for i, p := range series.Points {
+		if !p.BucketStart.Equal(want[i].BucketStart) || p.Count != want[i].Count || p.Revenue != want[i].Revenue {
+			t.Errorf("point %d = %+v, want %+v", i, p, want[i])
+		}
+	}
+	if series.TotalCount != 3 || series.TotalRevenue != 160 {
+		t.Errorf("totals = %d sales worth %.2f, want 3 worth 160", series.TotalCount, series.TotalRevenue)
+	}
+
+	if _, err := svc.GetOfferSalesTimeSeries(ctx, agentID, offerID, day0, day2, "hourly"); err == nil {
+		t.Error("GetOfferSalesTimeSeries accepted an hourly granularity")
+	}
+}
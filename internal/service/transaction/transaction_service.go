@@ -4,34 +4,54 @@ package transaction
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/pkg/pagination"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/pkg/mask"
 	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
 	offersvc "bingwa-service/internal/service/offer"
+	domainconfig "bingwa-service/internal/domain/config"
 	domainoffer "bingwa-service/internal/domain/offer"
 	customer "bingwa-service/internal/service/customer"
 	subsvc "bingwa-service/internal/service/subscription"
+	schedulesvc "bingwa-service/internal/service/schedule"
 
 	//"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
+// metadataKeyAutoScheduleRenewal marks pending requests that should schedule a renewal on success
+const metadataKeyAutoScheduleRenewal = "auto_schedule_renewal"
+
 type TransactionService struct {
 	requestRepo    *postgres.OfferRequestRepository
 	redemptionRepo *postgres.OfferRedemptionRepository
 	offerRepo      *postgres.AgentOfferRepository
 	customerRepo   *postgres.AgentCustomerRepository
+	auditRepo      *postgres.TransactionAuditRepository
 	offerSvc 		   *offersvc.OfferService
 	customerSvc        *customer.CustomerService
 	subService             *subsvc.SubscriptionService
+	scheduleSvc    *schedulesvc.ScheduleService
+	configSvc      *configsvc.ConfigService
+	deviceSlots    *cache.DeviceSlots
 	db             *postgres.DB // For transaction management
 	logger         *zap.Logger
 	
 	// Configuration
 	requireSubscription bool // Toggle subscription check
+	amountTolerance     float64
+	rejectUnderpayments bool
+	riskHoldThreshold   int
+	confirmationWindow  time.Duration
+	batchLease          time.Duration
 }
 
 func NewTransactionService(
@@ -39,9 +59,13 @@ func NewTransactionService(
 	redemptionRepo *postgres.OfferRedemptionRepository,
 	offerRepo *postgres.AgentOfferRepository,
 	customerRepo *postgres.AgentCustomerRepository,
+	auditRepo *postgres.TransactionAuditRepository,
 	offerSvc 		   *offersvc.OfferService,
 	customerSvc        *customer.CustomerService,
 	subService         *subsvc.SubscriptionService,
+	scheduleSvc        *schedulesvc.ScheduleService,
+	configSvc *configsvc.ConfigService,
+	deviceSlots *cache.DeviceSlots,
 	db *postgres.DB,
 	logger *zap.Logger,
 ) *TransactionService {
@@ -50,12 +74,20 @@ func NewTransactionService(
 		redemptionRepo:      redemptionRepo,
 		offerRepo:           offerRepo,
 		customerRepo:        customerRepo,
+		auditRepo:           auditRepo,
 		offerSvc:            offerSvc,
 		customerSvc:         customerSvc,
 		subService:          subService,
+		scheduleSvc:         scheduleSvc,
+		configSvc:           configSvc,
+		deviceSlots:         deviceSlots,
 		db:                  db,
 		logger:              logger,
 		requireSubscription: false, // Default: don't require subscription (can be configured)
+		amountTolerance:     defaultAmountTolerance,
+		riskHoldThreshold:   defaultRiskHoldThreshold,
+		confirmationWindow:  defaultConfirmationWindow,
+		batchLease:          defaultBatchLease,
 	}
 }
 
@@ -64,6 +96,16 @@ func (s *TransactionService) SetRequireSubscription(require bool) {
 	s.requireSubscription = require
 }
 
+// SetPaymentAmountPolicy configures how far AmountPaid may drift from the offer price
+// and whether pending requests that underpay are rejected rather than just flagged
+func (s *TransactionService) SetPaymentAmountPolicy(tolerance float64, rejectUnderpayments bool) {
+	if tolerance < 0 {
+		tolerance = 0
+	}
+	s.amountTolerance = tolerance
+	s.rejectUnderpayments = rejectUnderpayments
+}
+
 // CreateOfferRequest creates an offer request and redemption in a transaction
 func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int64, input *transaction.CreateOfferRequestInput) (*transaction.OfferRequest, *transaction.OfferRedemption, error) {
 	// Get offer details
@@ -89,17 +131,37 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 		// Continue without customer_id
 	}
 
+	// Fall back to the agent's display currency
+	if strings.TrimSpace(input.Currency) == "" {
+		input.Currency = s.configSvc.DefaultCurrency(ctx, agentID)
+	}
+
 	// Default to M-Pesa if not provided
 	paymentMethod := input.PaymentMethod
 	if paymentMethod == "" {
 		paymentMethod = transaction.PaymentMethodMpesa
 	}
 
+	// Default to unknown source if not provided
+	source, err := s.resolveSource(input.Source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Validate optional location
+	latitude, longitude, err := resolveLocation(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Determine initial status based on input completeness
 	isCompleted := s.isRequestCompleted(input)
 	initialStatus := transaction.TransactionStatusPending
 	if isCompleted {
 		initialStatus = transaction.TransactionStatusSuccess
+	} else if offer.RequireConfirmation {
+		// High-value offers wait for the agent to confirm before dispatch
+		initialStatus = transaction.TransactionStatusPendingConfirmation
 	}
 
 	// Check subscription if required (only for non-completed requests)
@@ -114,6 +176,25 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 		}
 	}
 
+	// Enforce per-customer purchase limits (only for non-completed requests)
+	if !isCompleted && customerID != nil {
+		if err := s.offerSvc.ValidateOfferPurchase(ctx, offer, *customerID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Compare the amount paid with what the offer costs
+	discrepancy := s.checkPaymentAmount(offer, input)
+	if discrepancy != nil && discrepancy.Kind == paymentUnderpaid && !isCompleted && s.rejectUnderpayments {
+		return nil, nil, fmt.Errorf("underpayment: expected %.2f, received %.2f: %w", discrepancy.Expected, discrepancy.Paid, xerrors.ErrInvalidInput)
+	}
+
+	// Score the request for fraud; a scoring failure shouldn't block the sale
+	risk, err := s.ScoreRequest(ctx, agentID, offer, input)
+	if err != nil {
+		s.logger.Warn("failed to score offer request", zap.Error(err))
+	}
+
 	// Generate references
 	requestRef := s.generateRequestReference()
 	redemptionRef := s.generateRedemptionReference()
@@ -130,6 +211,9 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 		RequestTime:       time.Now(),
 		Status:            initialStatus,
 		RetryCount:        0,
+		Source:            source,
+		Latitude:          latitude,
+		Longitude:         longitude,
 		DeviceInfo:        input.DeviceInfo,
 		Metadata:          input.Metadata,
 	}
@@ -162,6 +246,47 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 		offerRequest.ProcessedAt = sql.NullTime{Time: time.Now(), Valid: true}
 	}
 
+	if discrepancy != nil {
+		if offerRequest.Metadata == nil {
+			offerRequest.Metadata = make(map[string]interface{})
+		}
+		offerRequest.Metadata[metadataKeyPaymentDiscrepancy] = discrepancy
+		s.logger.Warn("offer request amount does not match offer price",
+			zap.Int64("offer_id", offer.ID),
+			zap.String("kind", string(discrepancy.Kind)),
+			zap.Float64("expected", discrepancy.Expected),
+			zap.Float64("paid", discrepancy.Paid),
+		)
+	}
+
+	if risk != nil {
+		offerRequest.RiskScore = risk.Score
+		if len(risk.Reasons) > 0 {
+			if offerRequest.Metadata == nil {
+				offerRequest.Metadata = make(map[string]interface{})
+			}
+			offerRequest.Metadata[metadataKeyRiskReasons] = risk.Reasons
+		}
+		// Only requests still waiting for dispatch can be held
+		if !isCompleted && s.shouldHold(risk) {
+			offerRequest.HeldForReview = true
+			s.logger.Warn("offer request held for review",
+				zap.Int64("offer_id", offer.ID),
+				zap.Int("risk_score", risk.Score),
+				zap.Strings("reasons", risk.Reasons),
+				zap.String("customer_phone", mask.Phone(input.CustomerPhone)),
+			)
+		}
+	}
+
+	// Remember the renewal request so it can be honoured when a pending request succeeds
+	if input.AutoScheduleRenewal && offer.IsRecurring && !isCompleted {
+		if offerRequest.Metadata == nil {
+			offerRequest.Metadata = make(map[string]interface{})
+		}
+		offerRequest.Metadata[metadataKeyAutoScheduleRenewal] = true
+	}
+
 	// Generate USSD code
 	ussdCodeInfo, err := s.generateUSSDCode(ctx, offer, input.CustomerPhone)
 	if err != nil {
@@ -214,6 +339,13 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 	}
 	defer tx.Rollback(ctx)
 
+	// Take a unit from a stock-limited offer; the row lock serialises concurrent sales
+	if offer.StockLimit.Valid {
+		if err := s.offerRepo.TakeStockWithTx(ctx, tx, offer.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Create offer request
 	if err := s.requestRepo.CreateWithTx(ctx, tx, offerRequest); err != nil {
 		return nil, nil, fmt.Errorf("failed to create offer request: %w", err)
@@ -227,15 +359,36 @@ func (s *TransactionService) CreateOfferRequest(ctx context.Context, agentID int
 		return nil, nil, fmt.Errorf("failed to create redemption: %w", err)
 	}
 
+	// Record customer activity
+	if customerID != nil {
+		if err := s.customerRepo.TouchLastActivityWithTx(ctx, tx, *customerID, offerRequest.RequestTime); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Schedule the next period of a successful recurring purchase
+	if input.AutoScheduleRenewal && offer.IsRecurring && isCompleted {
+		if _, err := s.scheduleSvc.CreateRenewalScheduleWithTx(ctx, tx, agentID, offer, input.CustomerPhone, offerRequest.CustomerID, offerRequest.RequestReference); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Capture the inbound request for disputes
+	if err := s.auditRequestCreatedWithTx(ctx, tx, offerRequest, input); err != nil {
+		return nil, nil, err
+	}
+
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.offerSvc.InvalidateOfferCache(ctx, agentID)
 
 	s.logger.Info("offer request created",
 		zap.Int64("request_id", offerRequest.ID),
 		zap.Int64("redemption_id", redemption.ID),
 		zap.String("status", string(offerRequest.Status)),
+		zap.String("customer_phone", mask.Phone(offerRequest.CustomerPhone)),
 		zap.Int64("agent_id", agentID),
 	)
 
@@ -254,6 +407,16 @@ func (s *TransactionService) UpdateOfferRequestStatus(ctx context.Context, agent
 		return fmt.Errorf("unauthorized: request does not belong to agent")
 	}
 
+	// Unconfirmed requests may only be cancelled or failed until confirmed
+	if request.Status == transaction.TransactionStatusPendingConfirmation && status != transaction.TransactionStatusCancelled && status != transaction.TransactionStatusFailed {
+		return fmt.Errorf("%w: request is awaiting confirmation", xerrors.ErrConflict)
+	}
+
+	// Held requests may only be cancelled or failed until reviewed
+	if request.HeldForReview && (status == transaction.TransactionStatusProcessing || status == transaction.TransactionStatusSuccess) {
+		return fmt.Errorf("%w: request is held for review", xerrors.ErrConflict)
+	}
+
 	// Execute in transaction
 	tx, err := s.db.BeginTx(ctx)
 	if err != nil {
@@ -267,10 +430,30 @@ func (s *TransactionService) UpdateOfferRequestStatus(ctx context.Context, agent
 		failureReason = ussdResponse.FailureReason
 	}
 
-	if err := s.requestRepo.UpdateStatusWithTx(ctx, tx, requestID, status, failureReason); err != nil {
+	failureCode, err := resolveFailureCode(status, ussdResponse)
+	if err != nil {
+		return err
+	}
+
+	if err := s.requestRepo.UpdateStatusWithTx(ctx, tx, requestID, status, failureReason, failureCode); err != nil {
 		return fmt.Errorf("failed to update request status: %w", err)
 	}
 
+	// Honour a deferred renewal schedule once the request succeeds
+	if status == transaction.TransactionStatusSuccess && request.Status != transaction.TransactionStatusSuccess {
+		if autoSchedule, _ := request.Metadata[metadataKeyAutoScheduleRenewal].(bool); autoSchedule {
+			offer, err := s.offerRepo.FindByID(ctx, request.OfferID)
+			if err != nil {
+				return fmt.Errorf("offer not found: %w", err)
+			}
+			if offer.IsRecurring {
+				if _, err := s.scheduleSvc.CreateRenewalScheduleWithTx(ctx, tx, agentID, offer, request.CustomerPhone, request.CustomerID, request.RequestReference); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// Find and update redemption
 	// Note: We need to get redemption by request_id
 	// For now, update via direct query (or add method to repo)
@@ -282,7 +465,7 @@ func (s *TransactionService) UpdateOfferRequestStatus(ctx context.Context, agent
 
 	// Update redemption status
 	if ussdResponse != nil {
-		if err := s.redemptionRepo.UpdateUSSDResponse(ctx, redemptionID, ussdResponse); err != nil {
+		if err := s.redemptionRepo.UpdateUSSDResponseWithTx(ctx, tx, redemptionID, ussdResponse); err != nil {
 			return fmt.Errorf("failed to update redemption: %w", err)
 		}
 	} else {
@@ -291,11 +474,30 @@ func (s *TransactionService) UpdateOfferRequestStatus(ctx context.Context, agent
 		}
 	}
 
+	// Capture the status change and USSD response for disputes
+	if err := s.auditStatusUpdatedWithTx(ctx, tx, request, status, failureCode, ussdResponse); err != nil {
+		return err
+	}
+
+	// Queue the redemption webhook with the change; the outbox relay delivers it after commit
+	if status != request.Status {
+		if err := s.redemptionRepo.EnqueueStatusEventsWithTx(ctx, tx, []int64{requestID}, request.Status); err != nil {
+			return err
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Free the device's concurrency slot once the request leaves the pending/processing states
+	if status != transaction.TransactionStatusPending && status != transaction.TransactionStatusProcessing {
+		if err := s.deviceSlots.Release(ctx, requestID); err != nil {
+			s.logger.Warn("failed to release device slot", zap.Int64("request_id", requestID), zap.Error(err))
+		}
+	}
+
 	s.logger.Info("offer request status updated",
 		zap.Int64("request_id", requestID),
 		zap.String("status", string(status)),
@@ -313,13 +515,16 @@ func (s *TransactionService) GetPendingRequests(ctx context.Context, agentID int
 		limit = 100
 	}
 
+	// Held requests wait for review instead of going to devices
 	status := transaction.TransactionStatusPending
+	notHeld := false
 	filters := &transaction.OfferRequestListFilters{
-		Status:   &status,
-		Page:     1,
-		PageSize: limit,
-		SortBy:   "created_at",
-		SortOrder: "asc",
+		Status:        &status,
+		HeldForReview: &notHeld,
+		Page:          1,
+		PageSize:      limit,
+		SortBy:        "created_at",
+		SortOrder:     "asc",
 	}
 
 	requests, _, err := s.requestRepo.List(ctx, agentID, filters)
@@ -330,6 +535,81 @@ func (s *TransactionService) GetPendingRequests(ctx context.Context, agentID int
 	return requests, nil
 }
 
+// defaultBatchLease is how long a batch-fetched request stays hidden from other fetches
+const defaultBatchLease = 10 * time.Minute
+
+// SetBatchLease configures how long batch-fetched requests are leased; non-positive values keep the default
+func (s *TransactionService) SetBatchLease(lease time.Duration) {
+	if lease > 0 {
+		s.batchLease = lease
+	}
+}
+
+// GetBatchPendingForDevice leases pending requests to a caller so no two fetches receive the same request.
+// With a device ID the batch is also capped to the device's free concurrency slots; without one
+// the requests are only leased.
+func (s *TransactionService) GetBatchPendingForDevice(ctx context.Context, agentID int64, deviceID string, limit int) ([]transaction.OfferRequest, error) {
+	if deviceID == "" {
+		return s.requestRepo.ClaimPendingForDevice(ctx, agentID, deviceID, limit, s.batchLease)
+	}
+
+	deviceConfig, err := s.configSvc.GetAndroidDeviceConfig(ctx, agentID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device config: %w", err)
+	}
+
+	// Only sizes the claim; the slots themselves are reserved atomically below
+	inFlight, err := s.deviceSlots.InFlight(ctx, agentID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	available := deviceConfig.MaxConcurrent - inFlight
+	if available <= 0 {
+		return []transaction.OfferRequest{}, nil
+	}
+	if limit > available {
+		limit = available
+	}
+
+	claimed, err := s.requestRepo.ClaimPendingForDevice(ctx, agentID, deviceID, limit, s.batchLease)
+	if err != nil {
+		return nil, err
+	}
+
+	requestIDs := make([]int64, len(claimed))
+	for i, req := range claimed {
+		requestIDs[i] = req.ID
+	}
+	acquired, err := s.deviceSlots.TryAcquire(ctx, agentID, deviceID, deviceConfig.MaxConcurrent, requestIDs)
+	if err != nil {
+		if releaseErr := s.requestRepo.ReleaseLeases(ctx, requestIDs); releaseErr != nil {
+			s.logger.Error("failed to release request leases", zap.Int64("agent_id", agentID), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	// A concurrent fetch for the same device may have taken the remaining slots
+	taken := make(map[int64]bool, len(acquired))
+	for _, id := range acquired {
+		taken[id] = true
+	}
+	requests := make([]transaction.OfferRequest, 0, len(acquired))
+	unslotted := []int64{}
+	for _, req := range claimed {
+		if taken[req.ID] {
+			requests = append(requests, req)
+		} else {
+			unslotted = append(unslotted, req.ID)
+		}
+	}
+	if err := s.requestRepo.ReleaseLeases(ctx, unslotted); err != nil {
+		s.logger.Error("failed to release request leases", zap.Int64("agent_id", agentID), zap.Error(err))
+	}
+
+	return requests, nil
+}
+
 // GetFailedRequests retrieves failed offer requests for retry
 func (s *TransactionService) GetFailedRequests(ctx context.Context, agentID int64, limit int) ([]transaction.OfferRequest, error) {
 	if limit < 1 {
@@ -398,9 +678,18 @@ func (s *TransactionService) RetryFailedRequest(ctx context.Context, agentID, re
 		return fmt.Errorf("can only retry failed requests")
 	}
 
-	// Increment retry count
-	if err := s.requestRepo.IncrementRetryCount(ctx, requestID); err != nil {
-		return fmt.Errorf("failed to increment retry count: %w", err)
+	// Count the retry, enforcing the agent's cooldown between retries
+	cooldown := s.retryCooldown(ctx, agentID)
+	claimed, err := s.requestRepo.ClaimRetry(ctx, requestID, cooldown)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		wait := cooldown
+		if request.LastRetryAt.Valid {
+			wait = time.Until(request.LastRetryAt.Time.Add(cooldown))
+		}
+		return fmt.Errorf("request was retried recently, try again in %s: %w", wait.Round(time.Second), xerrors.ErrRateLimited)
 	}
 
 	// Update status to pending
@@ -416,6 +705,98 @@ func (s *TransactionService) RetryFailedRequest(ctx context.Context, agentID, re
 	return nil
 }
 
+// retryCooldown returns the agent's minimum interval between retries of a request
+func (s *TransactionService) retryCooldown(ctx context.Context, agentID int64) time.Duration {
+	seconds := domainconfig.DefaultRetryCooldownSeconds
+	businessConfig, err := s.configSvc.GetBusinessConfig(ctx, agentID)
+	if err != nil {
+		s.logger.Warn("failed to get business config, using default retry cooldown",
+			zap.Int64("agent_id", agentID),
+			zap.Error(err),
+		)
+	} else {
+		seconds = businessConfig.RetryCooldownSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// AdminRetryRedemption re-queues a stuck or failed redemption on an agent's behalf, skipping the
+// ownership check. The redemption's retry budget is restored and the action is audited.
+func (s *TransactionService) AdminRetryRedemption(ctx context.Context, adminID, redemptionID int64) (*transaction.OfferRedemption, error) {
+	redemption, err := s.redemptionRepo.FindByID(ctx, redemptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch redemption.Status {
+	case transaction.TransactionStatusPending, transaction.TransactionStatusProcessing, transaction.TransactionStatusFailed:
+	default:
+		return nil, fmt.Errorf("%w: cannot retry a %s redemption", xerrors.ErrConflict, redemption.Status)
+	}
+
+	if redemption.OfferRequestID == nil {
+		return nil, fmt.Errorf("%w: redemption has no offer request", xerrors.ErrConflict)
+	}
+
+	request, err := s.requestRepo.FindByID(ctx, *redemption.OfferRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.redemptionRepo.ResetForRetryWithTx(ctx, tx, redemption.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.requestRepo.RequeueWithTx(ctx, tx, request.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.auditRepo.CreateWithTx(ctx, tx, &transaction.AuditEntry{
+		OfferRequestID:  request.ID,
+		AgentIdentityID: request.AgentIdentityID,
+		Event:           transaction.AuditEventAdminRetry,
+		Status:          transaction.TransactionStatusPending,
+		Payload: map[string]interface{}{
+			"admin_id":             adminID,
+			"redemption_id":        redemption.ID,
+			"previous_status":      request.Status,
+			"previous_retry_count": redemption.RetryCount,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	if request.Status != transaction.TransactionStatusPending {
+		if err := s.redemptionRepo.EnqueueStatusEventsWithTx(ctx, tx, []int64{request.ID}, request.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// A request stuck in processing may still hold a device slot
+	if err := s.deviceSlots.Release(ctx, request.ID); err != nil {
+		s.logger.Warn("failed to release device slot", zap.Int64("request_id", request.ID), zap.Error(err))
+	}
+
+	s.logger.Info("redemption retry forced by admin",
+		zap.Int64("admin_id", adminID),
+		zap.Int64("redemption_id", redemption.ID),
+		zap.Int64("request_id", request.ID),
+		zap.String("previous_status", string(request.Status)),
+	)
+
+	return s.redemptionRepo.FindByID(ctx, redemption.ID)
+}
+
 // GetOfferRequest retrieves an offer request by ID
 func (s *TransactionService) GetOfferRequest(ctx context.Context, agentID, requestID int64) (*transaction.OfferRequest, error) {
 	request, err := s.requestRepo.FindByID(ctx, requestID)
@@ -430,35 +811,48 @@ func (s *TransactionService) GetOfferRequest(ctx context.Context, agentID, reque
 	return request, nil
 }
 
-// ListOfferRequests retrieves offer requests with filters
-func (s *TransactionService) ListOfferRequests(ctx context.Context, agentID int64, filters *transaction.OfferRequestListFilters) (*transaction.OfferRequestListResponse, error) {
-	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
+// FindByMpesaReceipt looks up an agent's request and its redemption by M-Pesa receipt number
+func (s *TransactionService) FindByMpesaReceipt(ctx context.Context, agentID int64, receipt string) (*transaction.RequestWithRedemption, error) {
+	receipt = strings.ToUpper(strings.TrimSpace(receipt))
+	if receipt == "" {
+		return nil, fmt.Errorf("receipt is required")
 	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
+
+	// Scoped to the agent, so another agent's receipt is reported as not found
+	request, err := s.requestRepo.FindByMpesaReceipt(ctx, agentID, receipt)
+	if err != nil {
+		return nil, err
 	}
-	if filters.PageSize > 100 {
-		filters.PageSize = 100
+
+	result := &transaction.RequestWithRedemption{Request: request}
+
+	redemption, err := s.redemptionRepo.FindByOfferRequestID(ctx, request.ID)
+	if err != nil && !errors.Is(err, xerrors.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get redemption: %w", err)
 	}
+	result.Redemption = redemption
+
+	return result, nil
+}
+
+// ListOfferRequests retrieves offer requests with filters
+func (s *TransactionService) ListOfferRequests(ctx context.Context, agentID int64, filters *transaction.OfferRequestListFilters) (*transaction.OfferRequestListResponse, error) {
+	// Set defaults
+	filters.Page, filters.PageSize = pagination.Clamp(filters.Page, filters.PageSize)
 
 	requests, total, err := s.requestRepo.List(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list requests: %w", err)
 	}
 
-	totalPages := int(total) / filters.PageSize
-	if int(total)%filters.PageSize > 0 {
-		totalPages++
-	}
+	meta := pagination.Compute(total, filters.Page, filters.PageSize)
 
 	return &transaction.OfferRequestListResponse{
 		Requests:   requests,
-		Total:      total,
-		Page:       filters.Page,
-		PageSize:   filters.PageSize,
-		TotalPages: totalPages,
+		Total:      meta.Total,
+		Page:       meta.Page,
+		PageSize:   meta.PageSize,
+		TotalPages: meta.TotalPages,
 	}, nil
 }
 
@@ -508,24 +902,214 @@ func (s *TransactionService) ListOfferRedemptions(ctx context.Context, agentID i
 	}, nil
 }
 
-// GetTransactionStats retrieves transaction statistics
-func (s *TransactionService) GetTransactionStats(ctx context.Context, agentID int64) (*transaction.TransactionStats, error) {
-	stats, err := s.requestRepo.GetStats(ctx, agentID)
+// GetTransactionStats retrieves transaction statistics; nil filters cover all time
+func (s *TransactionService) GetTransactionStats(ctx context.Context, agentID int64, filters *transaction.StatsFilters) (*transaction.TransactionStats, error) {
+	if filters != nil && filters.From != nil && filters.To != nil && filters.From.After(*filters.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", xerrors.ErrInvalidInput)
+	}
+
+	stats, err := s.requestRepo.GetStats(ctx, agentID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
 
+	stats.TotalRedemptions, stats.SuccessfulRedemptions, err = s.redemptionRepo.CountByStatus(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemption counts: %w", err)
+	}
+
+	stats.BySource, err = s.requestRepo.GetStatsBySource(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats by source: %w", err)
+	}
+
+	stats.FailureBreakdown, err = s.requestRepo.GetFailureBreakdown(ctx, agentID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure breakdown: %w", err)
+	}
+
 	return stats, nil
 }
 
+// defaultHeatmapCellSize is roughly 1km at the equator
+const defaultHeatmapCellSize = 0.01
+
+// GetSalesHeatmap bins geotagged sales into lat/lng cells for a map view
+func (s *TransactionService) GetSalesHeatmap(ctx context.Context, agentID int64, bounds *transaction.SalesHeatmapBounds) (*transaction.SalesHeatmapResponse, error) {
+	if bounds.MinLatitude != nil && bounds.MaxLatitude != nil && *bounds.MinLatitude > *bounds.MaxLatitude {
+		return nil, fmt.Errorf("min_lat must not exceed max_lat")
+	}
+	if bounds.MinLongitude != nil && bounds.MaxLongitude != nil && *bounds.MinLongitude > *bounds.MaxLongitude {
+		return nil, fmt.Errorf("min_lng must not exceed max_lng")
+	}
+
+	cellSize := bounds.CellSize
+	if cellSize <= 0 {
+		cellSize = defaultHeatmapCellSize
+	}
+
+	cells, err := s.requestRepo.GetSalesHeatmap(ctx, agentID, bounds, cellSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales heatmap: %w", err)
+	}
+
+	var totalSales int64
+	for _, cell := range cells {
+		totalSales += cell.Count
+	}
+
+	return &transaction.SalesHeatmapResponse{
+		CellSize:   cellSize,
+		Cells:      cells,
+		TotalSales: totalSales,
+	}, nil
+}
+
+// Sales series range limits
+const (
+	defaultSalesSeriesDays = 30
+	maxSalesSeriesDays     = 366
+)
+
+// GetOfferSalesTimeSeries returns bucketed successful sales for one offer, with empty buckets filled in
+func (s *TransactionService) GetOfferSalesTimeSeries(ctx context.Context, agentID, offerID int64, from, to time.Time, granularity transaction.SalesSeriesGranularity) (*transaction.SalesSeriesResponse, error) {
+	if granularity == "" {
+		granularity = transaction.SalesSeriesDaily
+	}
+	unit, ok := granularity.TruncUnit()
+	if !ok {
+		return nil, fmt.Errorf("invalid granularity: %s (must be daily or weekly)", granularity)
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultSalesSeriesDays)
+	}
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > maxSalesSeriesDays*24*time.Hour {
+		return nil, fmt.Errorf("range cannot exceed %d days", maxSalesSeriesDays)
+	}
+
+	o, err := s.offerRepo.FindByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if o.AgentIdentityID != agentID {
+		return nil, xerrors.ErrNotFound
+	}
+
+	points, err := s.redemptionRepo.GetOfferSalesSeries(ctx, agentID, offerID, from, to, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &transaction.SalesSeriesResponse{
+		OfferID:     offerID,
+		Granularity: granularity,
+		From:        from,
+		To:          to,
+		Points:      fillSalesSeries(points, from, to, granularity),
+	}
+	for _, p := range result.Points {
+		result.TotalCount += p.Count
+		result.TotalRevenue += p.Revenue
+	}
+
+	return result, nil
+}
+
 // ========== Helper Methods ==========
 
+// bucketStart truncates t to the start of its bucket, matching Postgres date_trunc (weeks start on Monday)
+func bucketStart(t time.Time, granularity transaction.SalesSeriesGranularity) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if granularity == transaction.SalesSeriesWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// fillSalesSeries returns one point per bucket between from and to, using zeroes where there were no sales
+func fillSalesSeries(points []transaction.SalesSeriesPoint, from, to time.Time, granularity transaction.SalesSeriesGranularity) []transaction.SalesSeriesPoint {
+	byBucket := make(map[time.Time]transaction.SalesSeriesPoint, len(points))
+	for _, p := range points {
+		byBucket[p.BucketStart] = p
+	}
+
+	step := 1
+	if granularity == transaction.SalesSeriesWeekly {
+		step = 7
+	}
+
+	filled := []transaction.SalesSeriesPoint{}
+	for b := bucketStart(from, granularity); b.Before(to); b = b.AddDate(0, 0, step) {
+		if p, ok := byBucket[b]; ok {
+			filled = append(filled, p)
+			continue
+		}
+		filled = append(filled, transaction.SalesSeriesPoint{BucketStart: b})
+	}
+
+	return filled
+}
+
 // isRequestCompleted checks if request is already completed (has USSD response)
 func (s *TransactionService) isRequestCompleted(input *transaction.CreateOfferRequestInput) bool {
 	// Consider completed if has M-Pesa transaction details
 	return input.MpesaTransactionID != "" && input.MpesaReceiptNumber != ""
 }
 
+// resolveSource validates the request source, defaulting to unknown when empty
+func (s *TransactionService) resolveSource(source transaction.RequestSource) (transaction.RequestSource, error) {
+	if source == "" {
+		return transaction.RequestSourceUnknown, nil
+	}
+	if !source.IsValid() {
+		return "", fmt.Errorf("invalid request source: %s", source)
+	}
+	return source, nil
+}
+
+// resolveFailureCode validates the failure code; failed requests without one are recorded as unknown
+func resolveFailureCode(status transaction.TransactionStatus, ussdResponse *transaction.UpdateUSSDResponseInput) (transaction.FailureCode, error) {
+	if status != transaction.TransactionStatusFailed {
+		return "", nil
+	}
+	if ussdResponse == nil || ussdResponse.FailureCode == "" {
+		return transaction.FailureCodeUnknown, nil
+	}
+	if !ussdResponse.FailureCode.IsValid() {
+		return "", fmt.Errorf("invalid failure code: %s", ussdResponse.FailureCode)
+	}
+	return ussdResponse.FailureCode, nil
+}
+
+// resolveLocation validates optional coordinates; both must be given together
+func resolveLocation(input *transaction.CreateOfferRequestInput) (sql.NullFloat64, sql.NullFloat64, error) {
+	if input.Latitude == nil && input.Longitude == nil {
+		return sql.NullFloat64{}, sql.NullFloat64{}, nil
+	}
+	if input.Latitude == nil || input.Longitude == nil {
+		return sql.NullFloat64{}, sql.NullFloat64{}, fmt.Errorf("latitude and longitude must be provided together")
+	}
+
+	lat, lng := *input.Latitude, *input.Longitude
+	if lat < -90 || lat > 90 {
+		return sql.NullFloat64{}, sql.NullFloat64{}, fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if lng < -180 || lng > 180 {
+		return sql.NullFloat64{}, sql.NullFloat64{}, fmt.Errorf("longitude must be between -180 and 180")
+	}
+
+	return sql.NullFloat64{Float64: lat, Valid: true}, sql.NullFloat64{Float64: lng, Valid: true}, nil
+}
+
 // generateRequestReference generates unique request reference
 func (s *TransactionService) generateRequestReference() string {
 	timestamp := time.Now().Format("20060102150405")
@@ -564,7 +1148,13 @@ func (s *TransactionService) createFailedRequest(ctx context.Context, agentID in
 		Status:           transaction.TransactionStatusFailed,
 		FailureReason:    sql.NullString{String: reason, Valid: true},
 		RetryCount:       0,
+		Source:           input.Source,
+	}
+
+	if !offerRequest.Source.IsValid() {
+		offerRequest.Source = transaction.RequestSourceUnknown
 	}
+	offerRequest.Latitude, offerRequest.Longitude, _ = resolveLocation(input)
 
 	if customerID != nil {
 		offerRequest.CustomerID = sql.NullInt64{Int64: *customerID, Valid: true}
@@ -602,9 +1192,14 @@ func (s *TransactionService) createFailedRequest(ctx context.Context, agentID in
 		return nil, nil, err
 	}
 
+	if err := s.auditRequestCreatedWithTx(ctx, tx, offerRequest, input); err != nil {
+		return nil, nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, nil, err
 	}
+	s.offerSvc.InvalidateOfferCache(ctx, agentID)
 
 	return offerRequest, redemption, fmt.Errorf("%s", reason)
 }
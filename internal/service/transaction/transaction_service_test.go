@@ -0,0 +1,389 @@
+// internal/service/transaction/transaction_service_test.go
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/transaction"
+	xerrors "bingwa-service/internal/pkg/errors"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
+	customersvc "bingwa-service/internal/service/customer"
+	offersvc "bingwa-service/internal/service/offer"
+	schedulesvc "bingwa-service/internal/service/schedule"
+	subsvc "bingwa-service/internal/service/subscription"
+	"bingwa-service/internal/testutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newTestTransactionService wires a TransactionService and the services it purchases through
+// against a test database and an in-memory Redis. Notifications are left nil.
+func newTestTransactionService(t *testing.T) (*TransactionService, *pgxpool.Pool) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	configSvc := configsvc.NewConfigService(postgres.NewAgentConfigRepository(pool), nil, db, zap.NewNop())
+
+	ussdCodeRepo := postgres.NewOfferUSSDCodeRepository(pool)
+	offerRepo := postgres.NewAgentOfferRepository(pool, ussdCodeRepo, db)
+	customerRepo := postgres.NewAgentCustomerRepository(pool)
+	requestRepo := postgres.NewOfferRequestRepository(pool)
+	redemptionRepo := postgres.NewOfferRedemptionRepository(pool)
+	authRepo := postgres.NewAuthRepository(pool)
+	subscriptionRepo := postgres.NewAgentSubscriptionRepository(pool)
+	planRepo := postgres.NewSubscriptionPlanRepository(pool)
+
+	offerSvc := offersvc.NewOfferService(offersvc.OfferRepositories{
+		Offers:        offerRepo,
+		USSDCodes:     ussdCodeRepo,
+		Templates:     postgres.NewOfferTemplateRepository(pool),
+		Customers:     customerRepo,
+		Auth:          authRepo,
+		Subscriptions: subscriptionRepo,
+		Plans:         planRepo,
+	}, configSvc, db, cache.NewOfferCache(client), zap.NewNop())
+	customerSvc := customersvc.NewCustomerService(customerRepo, nil, nil, nil, zap.NewNop())
+	subService := subsvc.NewSubscriptionService(
+		subscriptionRepo, planRepo, postgres.NewPromotionalCampaignRepository(pool), requestRepo,
+		configSvc, nil, authRepo, db, zap.NewNop(),
+	)
+	scheduleSvc := schedulesvc.NewScheduleService(
+		postgres.NewScheduledOfferRepository(pool),
+		postgres.NewScheduledOfferHistoryRepository(pool),
+		redemptionRepo, offerRepo, customerRepo, customerSvc, db, offerSvc, zap.NewNop(),
+	)
+
+	svc := NewTransactionService(
+		requestRepo,
+		redemptionRepo,
+		offerRepo,
+		customerRepo,
+		postgres.NewTransactionAuditRepository(pool),
+		offerSvc, customerSvc, subService, scheduleSvc,
+		configSvc,
+		cache.NewDeviceSlots(client, time.Minute),
+		db,
+		zap.NewNop(),
+	)
+	return svc, pool
+}
+
+// seedRequest inserts an unpaid M-Pesa request for an offer and returns its ID and reference
+func seedRequest(t *testing.T, pool *pgxpool.Pool, agentID, offerID int64, phone string, amount float64) (int64, string) {
+	t.Helper()
+
+	var n int
+	if err := pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM offer_requests`).Scan(&n); err != nil {
+		t.Fatalf("failed to count requests: %v", err)
+	}
+	reference := fmt.Sprintf("REQ-TEST-%d", n+1)
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO offer_requests (
+			request_reference, offer_id, agent_identity_id, customer_phone, payment_method, amount_paid, status
+		) VALUES ($1, $2, $3, $4, 'mpesa', $5, 'pending')
+		RETURNING id
+	`, reference, offerID, agentID, phone, amount).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed request: %v", err)
+	}
+	return id, reference
+}
+
+// seedRedemption inserts a request and a redemption for it with the given status and time, returning the redemption ID
+func seedRedemption(t *testing.T, pool *pgxpool.Pool, agentID, offerID int64, status transaction.TransactionStatus, amount float64, at time.Time) int64 {
+	t.Helper()
+
+	requestID, reference := seedRequest(t, pool, agentID, offerID, "254712345678", amount)
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO offer_redemptions (
+			redemption_reference, offer_id, offer_request_id, agent_identity_id, customer_phone,
+			amount, ussd_code_used, status, redemption_time
+		) VALUES ($1, $2, $3, $4, '254712345678', $5, '*180*254712345678#', $6, $7)
+		RETURNING id
+	`, "RED"+strings.TrimPrefix(reference, "REQ"), offerID, requestID, agentID, amount, status, at).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to seed redemption: %v", err)
+	}
+	return id
+}
+
+func TestCreateOfferRequestSchedulesRecurringRenewal(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "recurring@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-MONTHLY", 50)
+	if _, err := pool.Exec(ctx, `UPDATE agent_offers SET is_recurring = TRUE, validity_days = 30 WHERE id = $1`, offerID); err != nil {
+		t.Fatalf("failed to make offer recurring: %v", err)
+	}
+
+	schedules := func(phone string) (int, time.Time, bool, string) {
+		t.Helper()
+		var count int
+		var scheduledTime time.Time
+		var autoRenew bool
+		var period string
+		err := pool.QueryRow(ctx, `
+			SELECT COUNT(*) OVER (), scheduled_time, auto_renew, COALESCE(renewal_period::text, '')
+			FROM scheduled_offers WHERE offer_id = $1 AND customer_phone = $2
+		`, offerID, phone).Scan(&count, &scheduledTime, &autoRenew, &period)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, time.Time{}, false, ""
+		}
+		if err != nil {
+			t.Fatalf("failed to read schedules: %v", err)
+		}
+		return count, scheduledTime, autoRenew, period
+	}
+	purchase := func(phone, receipt string, autoSchedule bool) *transaction.OfferRequest {
+		t.Helper()
+		input := &transaction.CreateOfferRequestInput{
+			OfferID:             offerID,
+			CustomerPhone:       phone,
+			PaymentMethod:       transaction.PaymentMethodMpesa,
+			AmountPaid:          50,
+			AutoScheduleRenewal: autoSchedule,
+		}
+		if receipt != "" {
+			input.MpesaTransactionID = receipt
+			input.MpesaReceiptNumber = receipt
+		}
+		request, _, err := svc.CreateOfferRequest(ctx, agentID, input)
+		if err != nil {
+			t.Fatalf("CreateOfferRequest: %v", err)
+		}
+		return request
+	}
+
+	// A completed purchase with the flag schedules the next period straight away
+	purchase("254700000001", "RCP0001", true)
+	count, scheduledTime, autoRenew, period := schedules("254700000001")
+	if count != 1 {
+		t.Fatalf("got %d schedules, want 1", count)
+	}
+	if due := time.Now().AddDate(0, 0, 30); scheduledTime.Before(due.Add(-time.Minute)) || scheduledTime.After(due.Add(time.Minute)) {
+		t.Errorf("renewal scheduled for %v, want about %v", scheduledTime, due)
+	}
+	if !autoRenew || period != "monthly" {
+		t.Errorf("auto_renew = %v, period = %q; want a monthly auto-renewal", autoRenew, period)
+	}
+
+	// Without the flag nothing is scheduled
+	purchase("254700000002", "RCP0002", false)
+	if count, _, _, _ := schedules("254700000002"); count != 0 {
+		t.Errorf("got %d schedules without the flag, want 0", count)
+	}
+
+	// A pending purchase schedules once it succeeds
+	pending := purchase("254700000003", "", true)
+	if count, _, _, _ := schedules("254700000003"); count != 0 {
+		t.Fatalf("got %d schedules before the purchase succeeded, want 0", count)
+	}
+	if err := svc.UpdateOfferRequestStatus(ctx, agentID, pending.ID, transaction.TransactionStatusSuccess, nil); err != nil {
+		t.Fatalf("UpdateOfferRequestStatus: %v", err)
+	}
+	if count, _, _, _ := schedules("254700000003"); count != 1 {
+		t.Errorf("got %d schedules after the purchase succeeded, want 1", count)
+	}
+}
+
+func TestFindByMpesaReceiptIsScopedToAgent(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "receipts@example.com")
+	otherID := testutil.Identity(t, pool, "other@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	redemptionID := seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusSuccess, 50, time.Now())
+	if _, err := pool.Exec(ctx, `
+		UPDATE offer_requests SET mpesa_receipt_number = 'QKX1ABC2DE'
+		WHERE id = (SELECT offer_request_id FROM offer_redemptions WHERE id = $1)
+	`, redemptionID); err != nil {
+		t.Fatalf("failed to set receipt: %v", err)
+	}
+
+	// Receipts are matched regardless of surrounding space and case
+	found, err := svc.FindByMpesaReceipt(ctx, agentID, " qkx1abc2de ")
+	if err != nil {
+		t.Fatalf("FindByMpesaReceipt: %v", err)
+	}
+	if found.Request.MpesaReceiptNumber.String != "QKX1ABC2DE" {
+		t.Errorf("found request with receipt %q, want QKX1ABC2DE", found.Request.MpesaReceiptNumber.String)
+	}
+	if found.Redemption == nil || found.Redemption.ID != redemptionID {
+		t.Errorf("found redemption %+v, want %d", found.Redemption, redemptionID)
+	}
+
+	if _, err := svc.FindByMpesaReceipt(ctx, otherID, "QKX1ABC2DE"); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("another agent's lookup error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeviceBatchIsCappedToFreeSlots(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "slots@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	if err := svc.configSvc.SetAndroidDeviceConfig(ctx, agentID, "device-1", &config.AndroidDeviceConfig{
+		Enabled:       true,
+		MaxConcurrent: 3,
+	}); err != nil {
+		t.Fatalf("SetAndroidDeviceConfig: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		seedRequest(t, pool, agentID, offerID, fmt.Sprintf("25470000000%d", i), 50)
+	}
+
+	batch, err := svc.GetBatchPendingForDevice(ctx, agentID, "device-1", 10)
+	if err != nil {
+		t.Fatalf("GetBatchPendingForDevice: %v", err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("first batch has %d requests, want the device's 3 slots", len(batch))
+	}
+
+	// Every slot is taken, so the device gets nothing more
+	if more, err := svc.GetBatchPendingForDevice(ctx, agentID, "device-1", 10); err != nil || len(more) != 0 {
+		t.Errorf("batch with no free slots = %d requests, %v; want none", len(more), err)
+	}
+
+	// Finishing a request frees its slot for one more
+	if err := svc.UpdateOfferRequestStatus(ctx, agentID, batch[0].ID, transaction.TransactionStatusSuccess, nil); err != nil {
+		t.Fatalf("UpdateOfferRequestStatus: %v", err)
+	}
+	more, err := svc.GetBatchPendingForDevice(ctx, agentID, "device-1", 10)
+	if err != nil {
+		t.Fatalf("GetBatchPendingForDevice: %v", err)
+	}
+	if len(more) != 1 {
+		t.Errorf("batch after one request finished = %d requests, want 1", len(more))
+	}
+}
+
+func TestAdminRetriesAnyAgentsRedemption(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "stuck@example.com")
+	adminID := testutil.Identity(t, pool, "support@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+
+	failedID := seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusFailed, 50, time.Now())
+	if _, err := pool.Exec(ctx, `
+		UPDATE offer_redemptions SET retry_count = 3 WHERE id = $1
+	`, failedID); err != nil {
+		t.Fatalf("failed to set retry count: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `
+		UPDATE offer_requests SET status = 'failed'
+		WHERE id = (SELECT offer_request_id FROM offer_redemptions WHERE id = $1)
+	`, failedID); err != nil {
+		t.Fatalf("failed to fail request: %v", err)
+	}
+
+	// The admin owns neither the offer nor the redemption
+	retried, err := svc.AdminRetryRedemption(ctx, adminID, failedID)
+	if err != nil {
+		t.Fatalf("AdminRetryRedemption: %v", err)
+	}
+	if retried.Status != transaction.TransactionStatusPending || retried.RetryCount != 0 {
+		t.Errorf("retried redemption = %s with %d retries, want pending with 0", retried.Status, retried.RetryCount)
+	}
+
+	audit, err := svc.GetTransactionAudit(ctx, agentID, *retried.OfferRequestID)
+	if err != nil {
+		t.Fatalf("GetTransactionAudit: %v", err)
+	}
+	if audit.Status != transaction.TransactionStatusPending {
+		t.Errorf("request status = %s, want pending", audit.Status)
+	}
+	var logged bool
+	for _, entry := range audit.Entries {
+		if entry.Event == transaction.AuditEventAdminRetry && entry.Payload["admin_id"] == float64(adminID) {
+			logged = true
+		}
+	}
+	if !logged {
+		t.Errorf("audit entries = %+v, want an admin_retry by %d", audit.Entries, adminID)
+	}
+
+	// Finished redemptions stay finished, and unknown ones are not found
+	doneID := seedRedemption(t, pool, agentID, offerID, transaction.TransactionStatusSuccess, 50, time.Now())
+	if _, err := svc.AdminRetryRedemption(ctx, adminID, doneID); !errors.Is(err, xerrors.ErrConflict) {
+		t.Errorf("retry of a successful redemption error = %v, want ErrConflict", err)
+	}
+	if _, err := svc.AdminRetryRedemption(ctx, adminID, doneID+1000); !errors.Is(err, xerrors.ErrNotFound) {
+		t.Errorf("retry of a missing redemption error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetryFailedRequestCooldown(t *testing.T) {
+	ctx := context.Background()
+	svc, pool := newTestTransactionService(t)
+
+	agentID := testutil.Identity(t, pool, "retry@example.com")
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	requestID, _ := seedRequest(t, pool, agentID, offerID, "254712345678", 50)
+
+	fail := func() {
+		t.Helper()
+		if _, err := pool.Exec(ctx, `UPDATE offer_requests SET status = 'failed' WHERE id = $1`, requestID); err != nil {
+			t.Fatalf("failed to fail request: %v", err)
+		}
+	}
+	retries := func() int {
+		t.Helper()
+		var n int
+		if err := pool.QueryRow(ctx, `SELECT retry_count FROM offer_requests WHERE id = $1`, requestID).Scan(&n); err != nil {
+			t.Fatalf("failed to read retry count: %v", err)
+		}
+		return n
+	}
+
+	fail()
+	if err := svc.RetryFailedRequest(ctx, agentID, requestID); err != nil {
+		t.Fatalf("first retry: %v", err)
+	}
+
+	// Failing again straight away doesn't reopen the cooldown
+	fail()
+	if err := svc.RetryFailedRequest(ctx, agentID, requestID); !errors.Is(err, xerrors.ErrRateLimited) {
+		t.Fatalf("retry within the cooldown error = %v, want ErrRateLimited", err)
+	}
+	if n := retries(); n != 1 {
+		t.Errorf("retry count after a rejected retry = %d, want 1", n)
+	}
+
+	// Once the cooldown has passed the retry goes through
+	if _, err := pool.Exec(ctx, `UPDATE offer_requests SET last_retry_at = $2 WHERE id = $1`,
+		requestID, time.Now().Add(-time.Duration(config.DefaultRetryCooldownSeconds+1)*time.Second)); err != nil {
+		t.Fatalf("failed to backdate last retry: %v", err)
+	}
+	if err := svc.RetryFailedRequest(ctx, agentID, requestID); err != nil {
+		t.Fatalf("retry after the cooldown: %v", err)
+	}
+	if n := retries(); n != 2 {
+		t.Errorf("retry count = %d, want 2", n)
+	}
+}
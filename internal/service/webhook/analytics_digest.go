@@ -0,0 +1,201 @@
+// internal/service/webhook/analytics_digest.go
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/domain/webhook"
+	"bingwa-service/internal/repository/postgres"
+	cache "bingwa-service/internal/repository/redis"
+	configsvc "bingwa-service/internal/service/config"
+
+	"go.uber.org/zap"
+)
+
+// analyticsDigestMarkTTL outlives the longest digest period so each period is sent once per agent
+const analyticsDigestMarkTTL = 8 * 24 * time.Hour
+
+// AnalyticsDigestWorker queues each opted-in agent a per-offer performance digest for the
+// previous day or ISO week, once the agent's local day is past the configured hour.
+// Digests go through the outbox, so delivery and its retries are left to the outbox relay.
+type AnalyticsDigestWorker struct {
+	requestRepo *postgres.OfferRequestRepository
+	configSvc   *configsvc.ConfigService
+	outboxRepo  *postgres.OutboxRepository
+	marker      *cache.OnceMarker
+	hour        int
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+func NewAnalyticsDigestWorker(
+	requestRepo *postgres.OfferRequestRepository,
+	configSvc *configsvc.ConfigService,
+	outboxRepo *postgres.OutboxRepository,
+	marker *cache.OnceMarker,
+	hour int,
+	interval time.Duration,
+	logger *zap.Logger,
+) *AnalyticsDigestWorker {
+	return &AnalyticsDigestWorker{
+		requestRepo: requestRepo,
+		configSvc:   configSvc,
+		outboxRepo:  outboxRepo,
+		marker:      marker,
+		hour:        hour,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+// Start runs the worker immediately and then on every interval until ctx is done
+func (w *AnalyticsDigestWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			w.logger.Error("analytics digest run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce queues the daily digests, and the weekly ones for the last ISO week, that haven't gone
+// out yet and returns how many were queued
+func (w *AnalyticsDigestWorker) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	sent := 0
+	for _, frequency := range []string{config.AnalyticsDigestDaily, config.AnalyticsDigestWeekly} {
+		queued, err := w.runFrequency(ctx, frequency, now)
+		sent += queued
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	if sent > 0 {
+		w.logger.Info("analytics digests queued", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// runFrequency queues the latest finished period's digest for every agent subscribed at the given frequency
+func (w *AnalyticsDigestWorker) runFrequency(ctx context.Context, frequency string, now time.Time) (int, error) {
+	agentIDs, err := w.configSvc.ListAnalyticsDigestAgents(ctx, frequency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s digest agents: %w", frequency, err)
+	}
+
+	sent := 0
+	for _, agentID := range agentIDs {
+		from, to, period, due := digestPeriod(frequency, now, w.configSvc.AgentLocation(ctx, agentID), w.hour)
+		if !due {
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%s:%s", agentID, frequency, period)
+		claimed, err := w.marker.Mark(ctx, key, analyticsDigestMarkTTL)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := w.enqueue(ctx, agentID, frequency, from, to); err != nil {
+			w.logger.Warn("failed to queue analytics digest",
+				zap.Int64("agent_id", agentID),
+				zap.String("frequency", frequency),
+				zap.Error(err),
+			)
+			if err := w.marker.Unmark(ctx, key); err != nil {
+				w.logger.Warn("failed to clear analytics digest marker", zap.Int64("agent_id", agentID), zap.Error(err))
+			}
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// enqueue composes the agent's digest and writes it to the outbox
+func (w *AnalyticsDigestWorker) enqueue(ctx context.Context, agentID int64, frequency string, from, to time.Time) error {
+	performance, err := w.requestRepo.GetOfferPerformance(ctx, agentID, from, to)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.outboxRepo.Enqueue(ctx, agentID, webhook.EventOfferAnalyticsDigest, analyticsDigestPayload(frequency, from, to, performance))
+	return err
+}
+
+// digestPeriod returns the latest finished [from, to) period for a digest frequency in the agent's
+// timezone, a label naming it (the date, or the ISO week for weekly digests) and whether the
+// agent's local day is past hour so the digest is due
+func digestPeriod(frequency string, now time.Time, loc *time.Location, hour int) (time.Time, time.Time, string, bool) {
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	due := local.Hour() >= hour
+
+	if frequency == config.AnalyticsDigestWeekly {
+		// ISO weeks start on Monday
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		to := today.AddDate(0, 0, -sinceMonday)
+		from := to.AddDate(0, 0, -7)
+		year, week := from.ISOWeek()
+		return from, to, fmt.Sprintf("%04d-W%02d", year, week), due
+	}
+
+	from := today.AddDate(0, 0, -1)
+	return from, today, from.Format("2006-01-02"), due
+}
+
+// analyticsDigestPayload builds the digest event body: the period, per-offer rows and agent-wide totals
+func analyticsDigestPayload(frequency string, from, to time.Time, performance []transaction.OfferPerformance) map[string]interface{} {
+	var requests, sales, ussdAttempts, ussdSuccesses int64
+	var revenue float64
+	for _, p := range performance {
+		requests += p.Requests
+		sales += p.Sales
+		revenue += p.Revenue
+		ussdAttempts += p.USSDAttempts
+		ussdSuccesses += p.USSDSuccesses
+	}
+
+	totals := map[string]interface{}{
+		"requests":          requests,
+		"sales":             sales,
+		"revenue":           revenue,
+		"conversion_rate":   0.0,
+		"ussd_attempts":     ussdAttempts,
+		"ussd_successes":    ussdSuccesses,
+		"ussd_success_rate": 0.0,
+	}
+	if requests > 0 {
+		totals["conversion_rate"] = float64(sales) / float64(requests) * 100
+	}
+	if ussdAttempts > 0 {
+		totals["ussd_success_rate"] = float64(ussdSuccesses) / float64(ussdAttempts) * 100
+	}
+
+	return map[string]interface{}{
+		"frequency":    frequency,
+		"period_start": from.Format(time.RFC3339),
+		"period_end":   to.Format(time.RFC3339),
+		"offers":       performance,
+		"totals":       totals,
+	}
+}
@@ -0,0 +1,128 @@
+// internal/service/webhook/analytics_digest_test.go
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/transaction"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	eat := time.FixedZone("EAT", 3*60*60)
+
+	tests := []struct {
+		name      string
+		frequency string
+		now       time.Time
+		wantFrom  time.Time
+		wantTo    time.Time
+		wantLabel string
+		wantDue   bool
+	}{
+		{
+			"daily covers yesterday", config.AnalyticsDigestDaily,
+			time.Date(2026, 10, 16, 9, 0, 0, 0, eat),
+			time.Date(2026, 10, 15, 0, 0, 0, 0, eat), time.Date(2026, 10, 16, 0, 0, 0, 0, eat), "2026-10-15", true,
+		},
+		{
+			"daily before the send hour", config.AnalyticsDigestDaily,
+			time.Date(2026, 10, 16, 7, 59, 0, 0, eat),
+			time.Date(2026, 10, 15, 0, 0, 0, 0, eat), time.Date(2026, 10, 16, 0, 0, 0, 0, eat), "2026-10-15", false,
+		},
+		{
+			"daily uses the agent's day", config.AnalyticsDigestDaily,
+			time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 16, 0, 0, 0, 0, eat), time.Date(2026, 10, 17, 0, 0, 0, 0, eat), "2026-10-16", false,
+		},
+		{
+			"weekly mid-week covers last iso week", config.AnalyticsDigestWeekly,
+			time.Date(2026, 10, 16, 9, 0, 0, 0, eat),
+			time.Date(2026, 10, 5, 0, 0, 0, 0, eat), time.Date(2026, 10, 12, 0, 0, 0, 0, eat), "2026-W41", true,
+		},
+		{
+			"weekly on monday", config.AnalyticsDigestWeekly,
+			time.Date(2026, 10, 12, 8, 0, 0, 0, eat),
+			time.Date(2026, 10, 5, 0, 0, 0, 0, eat), time.Date(2026, 10, 12, 0, 0, 0, 0, eat), "2026-W41", true,
+		},
+		{
+			"weekly across the year end", config.AnalyticsDigestWeekly,
+			time.Date(2027, 1, 5, 9, 0, 0, 0, eat),
+			time.Date(2026, 12, 28, 0, 0, 0, 0, eat), time.Date(2027, 1, 4, 0, 0, 0, 0, eat), "2026-W53", true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, label, due := digestPeriod(tt.frequency, tt.now, eat, 8)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("period = [%v, %v), want [%v, %v)", from, to, tt.wantFrom, tt.wantTo)
+			}
+			if label != tt.wantLabel {
+				t.Errorf("label = %q, want %q", label, tt.wantLabel)
+			}
+			if due != tt.wantDue {
+				t.Errorf("due = %v, want %v", due, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestAnalyticsDigestPayload(t *testing.T) {
+	from := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name            string
+		performance     []transaction.OfferPerformance
+		wantRequests    int64
+		wantSales       int64
+		wantRevenue     float64
+		wantConversion  float64
+		wantUSSDSuccess float64
+	}{
+		{"no activity", nil, 0, 0, 0, 0, 0},
+		{
+			"single offer",
+			[]transaction.OfferPerformance{{OfferID: 1, Requests: 4, Sales: 3, Revenue: 150, USSDAttempts: 3, USSDSuccesses: 3}},
+			4, 3, 150, 75, 100,
+		},
+		{
+			"totals across offers",
+			[]transaction.OfferPerformance{
+				{OfferID: 1, Requests: 6, Sales: 3, Revenue: 150, USSDAttempts: 4, USSDSuccesses: 3},
+				{OfferID: 2, Requests: 4, Sales: 2, Revenue: 40.5, USSDAttempts: 4, USSDSuccesses: 3},
+			},
+			10, 5, 190.5, 50, 75,
+		},
+		{
+			"requests without ussd attempts",
+			[]transaction.OfferPerformance{{OfferID: 1, Requests: 2}},
+			2, 0, 0, 0, 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := analyticsDigestPayload(config.AnalyticsDigestDaily, from, to, tt.performance)
+
+			if payload["frequency"] != config.AnalyticsDigestDaily ||
+				payload["period_start"] != "2026-10-15T00:00:00Z" ||
+				payload["period_end"] != "2026-10-16T00:00:00Z" {
+				t.Errorf("period fields = %v %v %v", payload["frequency"], payload["period_start"], payload["period_end"])
+			}
+
+			totals := payload["totals"].(map[string]interface{})
+			if totals["requests"] != tt.wantRequests || totals["sales"] != tt.wantSales || totals["revenue"] != tt.wantRevenue {
+				t.Errorf("totals = %v, want %d requests, %d sales, %v revenue", totals, tt.wantRequests, tt.wantSales, tt.wantRevenue)
+			}
+			if totals["conversion_rate"] != tt.wantConversion {
+				t.Errorf("conversion_rate = %v, want %v", totals["conversion_rate"], tt.wantConversion)
+			}
+			if totals["ussd_success_rate"] != tt.wantUSSDSuccess {
+				t.Errorf("ussd_success_rate = %v, want %v", totals["ussd_success_rate"], tt.wantUSSDSuccess)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// internal/service/webhook/outbox_relay.go
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bingwa-service/internal/repository/postgres"
+
+	"go.uber.org/zap"
+)
+
+const (
+	outboxBatchSize = 50
+	// outboxLease covers one delivery attempt; events are claimed one at a time so it never has to span a batch
+	outboxLease      = 2 * time.Minute
+	maxRelayAttempts = 10
+	relayBaseBackoff = 30 * time.Second
+	maxRelayBackoff  = 30 * time.Minute
+)
+
+// OutboxRelay hands events committed to the outbox to webhook delivery.
+// Each claimed event gets exactly one delivery attempt per run; failures are retried with
+// backoff until the delivery gives up or maxRelayAttempts is reached. Events are marked sent
+// only after the attempt has been stored, so a crash in between redelivers rather than loses
+// an event; receivers can dedupe on event_id.
+type OutboxRelay struct {
+	outboxRepo *postgres.OutboxRepository
+	webhookSvc *WebhookService
+	interval   time.Duration
+	logger     *zap.Logger
+}
+
+func NewOutboxRelay(outboxRepo *postgres.OutboxRepository, webhookSvc *WebhookService, interval time.Duration, logger *zap.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		webhookSvc: webhookSvc,
+		interval:   interval,
+		logger:     logger,
+	}
+}
+
+// Start runs the relay immediately and then on every interval until ctx is done
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RunOnce(ctx); err != nil {
+			r.logger.Error("outbox relay run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce relays up to a batch of due events and returns how many were handed off
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	sent := 0
+	for i := 0; i < outboxBatchSize && ctx.Err() == nil; i++ {
+		events, err := r.outboxRepo.ClaimPending(ctx, 1, outboxLease)
+		if err != nil {
+			return sent, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		e := &events[0]
+		if e.Payload == nil {
+			e.Payload = map[string]interface{}{}
+		}
+		e.Payload["event_id"] = e.ID
+
+		if _, err := r.webhookSvc.DeliverOutboxEvent(ctx, e); err != nil {
+			errMsg := err.Error()
+			if len(errMsg) > maxErrorLength {
+				errMsg = errMsg[:maxErrorLength]
+			}
+			attempts := e.Attempts + 1
+			final := attempts >= maxRelayAttempts
+			if err := r.outboxRepo.RecordFailure(ctx, e.ID, errMsg, time.Now().Add(relayBackoff(attempts)), final); err != nil {
+				r.logger.Error("failed to record outbox failure", zap.Int64("event_id", e.ID), zap.Error(err))
+			}
+			r.logger.Warn("failed to relay outbox event",
+				zap.Int64("event_id", e.ID),
+				zap.String("event", e.EventType),
+				zap.Int("attempts", attempts),
+				zap.Bool("gave_up", final),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := r.outboxRepo.MarkSent(ctx, e.ID); err != nil {
+			return sent, fmt.Errorf("failed to mark outbox event %d sent: %w", e.ID, err)
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		r.logger.Info("outbox events relayed", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// relayBackoff doubles the wait after each failed attempt, capped at maxRelayBackoff
+func relayBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := relayBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRelayBackoff {
+			return maxRelayBackoff
+		}
+	}
+	return delay
+}
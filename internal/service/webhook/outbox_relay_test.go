@@ -0,0 +1,148 @@
+// internal/service/webhook/outbox_relay_test.go
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/transaction"
+	"bingwa-service/internal/domain/webhook"
+	"bingwa-service/internal/repository/postgres"
+	transactionsvc "bingwa-service/internal/service/transaction"
+	"bingwa-service/internal/testutil"
+
+	"go.uber.org/zap"
+)
+
+func TestRelayBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{"no attempts yet", 0, 30 * time.Second},
+		{"first failure", 1, 30 * time.Second},
+		{"second failure", 2, time.Minute},
+		{"fifth failure", 5, 8 * time.Minute},
+		{"seventh failure capped", 7, maxRelayBackoff},
+		{"last attempt capped", maxRelayAttempts, maxRelayBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relayBackoff(tt.attempts); got != tt.want {
+				t.Errorf("relayBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedemptionStatusEventCommitsWithChangeAndIsRelayed(t *testing.T) {
+	ctx := context.Background()
+	webhookSvc, pool, endpoint, agentID := newTestWebhookService(t)
+	db := postgres.NewDB(pool)
+	ussdCodeRepo := postgres.NewOfferUSSDCodeRepository(pool)
+	txSvc := transactionsvc.NewTransactionService(
+		postgres.NewOfferRequestRepository(pool),
+		postgres.NewOfferRedemptionRepository(pool),
+		postgres.NewAgentOfferRepository(pool, ussdCodeRepo, db),
+		postgres.NewAgentCustomerRepository(pool),
+		postgres.NewTransactionAuditRepository(pool),
+		nil, nil, nil, nil, nil, nil,
+		db,
+		zap.NewNop(),
+	)
+	outboxRepo := postgres.NewOutboxRepository(pool)
+	relay := NewOutboxRelay(outboxRepo, webhookSvc, time.Minute, zap.NewNop())
+
+	offerID := testutil.Offer(t, pool, agentID, "DATA-1GB", 50)
+	var requestID int64
+	err := pool.QueryRow(ctx, `
+		WITH request AS (
+			INSERT INTO offer_requests (
+				request_reference, offer_id, agent_identity_id, customer_phone, payment_method, amount_paid, status
+			) VALUES ('REQ-OUTBOX', $1, $2, '0712345678', 'mpesa', 50, 'pending')
+			RETURNING id
+		)
+		INSERT INTO offer_redemptions (
+			redemption_reference, offer_id, offer_request_id, agent_identity_id, customer_phone, amount, ussd_code_used
+		) SELECT 'RED-OUTBOX', $1, id, $2, '0712345678', 50, '*180*0712345678#' FROM request
+		RETURNING offer_request_id
+	`, offerID, agentID).Scan(&requestID)
+	if err != nil {
+		t.Fatalf("failed to seed request: %v", err)
+	}
+
+	requestStatus := func() string {
+		var status string
+		if err := pool.QueryRow(ctx, `SELECT status FROM offer_requests WHERE id = $1`, requestID).Scan(&status); err != nil {
+			t.Fatalf("failed to read request status: %v", err)
+		}
+		return status
+	}
+	outboxCount := func() int {
+		var n int
+		if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM outbox_events`).Scan(&n); err != nil {
+			t.Fatalf("failed to count outbox events: %v", err)
+		}
+		return n
+	}
+
+	// When the outbox write fails, the status change rolls back with it
+	if _, err := pool.Exec(ctx, `
+		CREATE FUNCTION reject_outbox() RETURNS trigger AS $$
+		BEGIN RAISE EXCEPTION 'outbox unavailable'; END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER reject_outbox BEFORE INSERT ON outbox_events FOR EACH ROW EXECUTE FUNCTION reject_outbox();
+	`); err != nil {
+		t.Fatalf("failed to install outbox trigger: %v", err)
+	}
+	if err := txSvc.UpdateOfferRequestStatus(ctx, agentID, requestID, transaction.TransactionStatusProcessing, nil); err == nil {
+		t.Fatal("UpdateOfferRequestStatus succeeded while the outbox rejected writes")
+	}
+	if status := requestStatus(); status != "pending" {
+		t.Errorf("request status = %s after a failed outbox write, want pending", status)
+	}
+	if _, err := pool.Exec(ctx, `DROP TRIGGER reject_outbox ON outbox_events`); err != nil {
+		t.Fatalf("failed to drop outbox trigger: %v", err)
+	}
+
+	// A successful change commits exactly one event alongside it
+	if err := txSvc.UpdateOfferRequestStatus(ctx, agentID, requestID, transaction.TransactionStatusProcessing, nil); err != nil {
+		t.Fatalf("UpdateOfferRequestStatus: %v", err)
+	}
+	if status := requestStatus(); status != "processing" {
+		t.Errorf("request status = %s, want processing", status)
+	}
+	if n := outboxCount(); n != 1 {
+		t.Fatalf("got %d outbox events, want 1", n)
+	}
+	var status, previous string
+	if err := pool.QueryRow(ctx, `
+		SELECT payload->>'status', payload->>'previous_status' FROM outbox_events
+	`).Scan(&status, &previous); err != nil {
+		t.Fatalf("failed to read outbox event: %v", err)
+	}
+	if status != "processing" || previous != "pending" {
+		t.Errorf("event moved %s -> %s, want pending -> processing", previous, status)
+	}
+
+	// The relay delivers the committed event once and marks it sent
+	if sent, err := relay.RunOnce(ctx); err != nil || sent != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1 sent", sent, err)
+	}
+	if sent, err := relay.RunOnce(ctx); err != nil || sent != 0 {
+		t.Errorf("second RunOnce = %d, %v; want nothing left", sent, err)
+	}
+	if events := endpoint.Events(); len(events) != 1 || events[0] != webhook.EventRedemptionStatusChanged {
+		t.Errorf("endpoint received %v, want one %s", events, webhook.EventRedemptionStatusChanged)
+	}
+	var outboxStatus string
+	if err := pool.QueryRow(ctx, `SELECT status FROM outbox_events`).Scan(&outboxStatus); err != nil {
+		t.Fatalf("failed to read outbox status: %v", err)
+	}
+	if outboxStatus != "sent" {
+		t.Errorf("outbox event status = %s, want sent", outboxStatus)
+	}
+}
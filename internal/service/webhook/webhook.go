@@ -0,0 +1,274 @@
+// internal/service/webhook/webhook.go
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/webhook"
+	xerrors "bingwa-service/internal/pkg/errors"
+	signing "bingwa-service/internal/pkg/webhook"
+	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// EventHeader and DeliveryHeader identify the event on outgoing webhooks
+	EventHeader    = "X-Bingwa-Event"
+	DeliveryHeader = "X-Bingwa-Delivery"
+
+	maxReplayBatch   = 100
+	retryBaseBackoff = 2 * time.Second
+	maxErrorLength   = 500
+)
+
+type WebhookService struct {
+	deliveryRepo  *postgres.WebhookDeliveryRepository
+	configService *configsvc.ConfigService
+	httpClient    *http.Client
+	logger        *zap.Logger
+}
+
+func NewWebhookService(deliveryRepo *postgres.WebhookDeliveryRepository, configService *configsvc.ConfigService, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		deliveryRepo:  deliveryRepo,
+		configService: configService,
+		// Deliveries never reach loopback, link-local or private addresses, even after a DNS change
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:       http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: signing.DialControl}).DialContext,
+			},
+		},
+		logger: logger,
+	}
+}
+
+// Dispatch stores an event for the agent and delivers it, retrying with backoff up to the configured attempts.
+// It blocks while retrying, so callers on a request path should run it in a goroutine.
+// Returns nil without storing anything when the agent has no enabled webhook.
+func (s *WebhookService) Dispatch(ctx context.Context, agentID int64, eventType string, payload map[string]interface{}) (*webhook.Delivery, error) {
+	cfg, err := s.configService.GetWebhookConfig(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook config: %w", err)
+	}
+	if !cfg.Enabled || cfg.URL == "" {
+		return nil, nil
+	}
+
+	delivery := &webhook.Delivery{
+		AgentIdentityID: agentID,
+		EventType:       eventType,
+		Payload:         payload,
+		Status:          webhook.DeliveryStatusPending,
+	}
+	if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return delivery, ctx.Err()
+			case <-time.After(retryBaseBackoff * time.Duration(1<<(attempt-2))):
+			}
+		}
+
+		if s.attempt(ctx, cfg, delivery, attempt == maxAttempts) {
+			break
+		}
+	}
+
+	return delivery, nil
+}
+
+// DeliverOutboxEvent makes a single delivery attempt for an outbox event. All attempts for the event share
+// one delivery, which is marked failed once the configured attempts are used up. It returns an error while
+// the event should be retried, and nil once it is delivered, has failed for good or the agent has no webhook.
+func (s *WebhookService) DeliverOutboxEvent(ctx context.Context, e *webhook.OutboxEvent) (*webhook.Delivery, error) {
+	cfg, err := s.configService.GetWebhookConfig(ctx, e.AgentIdentityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook config: %w", err)
+	}
+	if !cfg.Enabled || cfg.URL == "" {
+		return nil, nil
+	}
+
+	delivery := &webhook.Delivery{
+		AgentIdentityID: e.AgentIdentityID,
+		EventType:       e.EventType,
+		Payload:         e.Payload,
+		Status:          webhook.DeliveryStatusPending,
+	}
+	if err := s.deliveryRepo.CreateForOutboxEvent(ctx, delivery, e.ID); err != nil {
+		return nil, err
+	}
+	if delivery.Status != webhook.DeliveryStatusPending {
+		return delivery, nil
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	if s.attempt(ctx, cfg, delivery, delivery.Attempts+1 >= maxAttempts) || delivery.Status == webhook.DeliveryStatusFailed {
+		return delivery, nil
+	}
+	return delivery, fmt.Errorf("webhook delivery %d attempt %d of %d failed", delivery.ID, delivery.Attempts, maxAttempts)
+}
+
+// ReplayFailed re-delivers the agent's events created since the given time that exhausted their retries.
+// Each event gets one attempt; events that fail again stay failed and can be replayed later. Every event
+// is claimed before it is sent, so overlapping replays never deliver the same event twice.
+func (s *WebhookService) ReplayFailed(ctx context.Context, agentID int64, since time.Time) (*webhook.ReplayResult, error) {
+	cfg, err := s.configService.GetWebhookConfig(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook config: %w", err)
+	}
+	if !cfg.Enabled || cfg.URL == "" {
+		return nil, fmt.Errorf("webhooks are not enabled for this agent: %w", xerrors.ErrInvalidInput)
+	}
+
+	deliveries, err := s.deliveryRepo.ListFailedSince(ctx, agentID, since, maxReplayBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &webhook.ReplayResult{
+		DeliveredIDs: []int64{},
+		FailedIDs:    []int64{},
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		d := &deliveries[i]
+		claimed, err := s.deliveryRepo.ClaimFailed(ctx, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			continue
+		}
+
+		result.Replayed++
+		if s.attempt(ctx, cfg, d, true) {
+			result.Delivered++
+			result.DeliveredIDs = append(result.DeliveredIDs, d.ID)
+		} else {
+			result.Failed++
+			result.FailedIDs = append(result.FailedIDs, d.ID)
+		}
+	}
+
+	s.logger.Info("webhook replay completed",
+		zap.Int64("agent_id", agentID),
+		zap.Time("since", since),
+		zap.Int("replayed", result.Replayed),
+		zap.Int("delivered", result.Delivered),
+	)
+
+	return result, nil
+}
+
+// attempt posts a delivery once and records the outcome; final marks the delivery failed instead of pending on error
+func (s *WebhookService) attempt(ctx context.Context, cfg *config.WebhookConfig, d *webhook.Delivery, final bool) bool {
+	statusCode, sendErr := s.send(ctx, cfg, d)
+
+	status := webhook.DeliveryStatusDelivered
+	errMsg := ""
+	if sendErr != nil {
+		status = webhook.DeliveryStatusPending
+		if final {
+			status = webhook.DeliveryStatusFailed
+		}
+		errMsg = sendErr.Error()
+		if len(errMsg) > maxErrorLength {
+			errMsg = errMsg[:maxErrorLength]
+		}
+	}
+
+	if err := s.deliveryRepo.RecordAttempt(ctx, d.ID, status, statusCode, errMsg); err != nil {
+		s.logger.Error("failed to record webhook attempt", zap.Int64("delivery_id", d.ID), zap.Error(err))
+	}
+
+	d.Status = status
+	d.Attempts++
+	if sendErr != nil {
+		s.logger.Warn("webhook delivery attempt failed",
+			zap.Int64("delivery_id", d.ID),
+			zap.String("event", d.EventType),
+			zap.Int("status_code", statusCode),
+			zap.Error(sendErr),
+		)
+		return false
+	}
+
+	return true
+}
+
+// send posts the signed event envelope to the agent's endpoint; any non-2xx response is an error
+func (s *WebhookService) send(ctx context.Context, cfg *config.WebhookConfig, d *webhook.Delivery) (int, error) {
+	body, err := json.Marshal(webhook.Envelope{
+		ID:        d.ID,
+		Event:     d.EventType,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if limit := config.MaxWebhookTimeoutSeconds * time.Second; timeout > limit {
+		timeout = limit
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	if cfg.Secret != "" {
+		req.Header.Set(signing.SignatureHeader, signing.Sign(body, cfg.Secret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
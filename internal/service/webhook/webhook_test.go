@@ -0,0 +1,149 @@
+// internal/service/webhook/webhook_test.go
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"bingwa-service/internal/domain/config"
+	"bingwa-service/internal/domain/webhook"
+	"bingwa-service/internal/repository/postgres"
+	configsvc "bingwa-service/internal/service/config"
+	"bingwa-service/internal/testutil"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// testEndpoint is a healthy webhook receiver that counts requests per delivery ID
+type testEndpoint struct {
+	URL string
+
+	mu       sync.Mutex
+	received map[string]int
+	events   []string
+}
+
+// Received returns how many requests each delivery ID got
+func (e *testEndpoint) Received() map[string]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	received := make(map[string]int, len(e.received))
+	for id, n := range e.received {
+		received[id] = n
+	}
+	return received
+}
+
+// Events returns the event type of every request received, in order
+func (e *testEndpoint) Events() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+// newTestWebhookService wires a WebhookService against a test database, with a healthy endpoint
+// configured as agentID's webhook
+func newTestWebhookService(t *testing.T) (*WebhookService, *pgxpool.Pool, *testEndpoint, int64) {
+	t.Helper()
+
+	pool := testutil.Postgres(t)
+	db := postgres.NewDB(pool)
+	svc := NewWebhookService(
+		postgres.NewWebhookDeliveryRepository(pool),
+		configsvc.NewConfigService(postgres.NewAgentConfigRepository(pool), nil, db, zap.NewNop()),
+		zap.NewNop(),
+	)
+
+	endpoint := &testEndpoint{received: map[string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint.mu.Lock()
+		endpoint.received[r.Header.Get(DeliveryHeader)]++
+		endpoint.events = append(endpoint.events, r.Header.Get(EventHeader))
+		endpoint.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	endpoint.URL = server.URL
+	// The production client refuses loopback addresses
+	svc.httpClient = server.Client()
+
+	agentID := testutil.Identity(t, pool, "webhooks@example.com")
+	value, _ := json.Marshal(config.WebhookConfig{Enabled: true, URL: endpoint.URL, MaxAttempts: 3, TimeoutSeconds: 5})
+	if _, err := pool.Exec(context.Background(), `
+		INSERT INTO agent_configs (agent_identity_id, config_key, config_value) VALUES ($1, $2, $3)
+	`, agentID, config.ConfigKeyWebhook, value); err != nil {
+		t.Fatalf("failed to seed webhook config: %v", err)
+	}
+
+	return svc, pool, endpoint, agentID
+}
+
+func TestReplayFailedDeliversEachEventOnce(t *testing.T) {
+	ctx := context.Background()
+	svc, _, endpoint, agentID := newTestWebhookService(t)
+	deliveryRepo := svc.deliveryRepo
+
+	var failed []int64
+	for _, status := range []webhook.DeliveryStatus{webhook.DeliveryStatusFailed, webhook.DeliveryStatusFailed, webhook.DeliveryStatusDelivered} {
+		d := &webhook.Delivery{
+			AgentIdentityID: agentID,
+			EventType:       "request.completed",
+			Payload:         map[string]interface{}{"status": "completed"},
+			Status:          status,
+		}
+		if err := deliveryRepo.Create(ctx, d); err != nil {
+			t.Fatalf("failed to seed delivery: %v", err)
+		}
+		if status == webhook.DeliveryStatusFailed {
+			failed = append(failed, d.ID)
+		}
+	}
+
+	// Two overlapping replays share the failed events between them
+	since := time.Now().Add(-time.Hour)
+	results := make(chan *webhook.ReplayResult, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			result, err := svc.ReplayFailed(ctx, agentID, since)
+			if err != nil {
+				t.Errorf("ReplayFailed: %v", err)
+			}
+			results <- result
+		}()
+	}
+	delivered := 0
+	for i := 0; i < 2; i++ {
+		if result := <-results; result != nil {
+			delivered += result.Delivered
+			if result.Failed != 0 {
+				t.Errorf("replay failed %d events, want 0", result.Failed)
+			}
+		}
+	}
+	if delivered != len(failed) {
+		t.Errorf("replays delivered %d events, want %d", delivered, len(failed))
+	}
+
+	received := endpoint.Received()
+	for _, id := range failed {
+		if n := received[strconv.FormatInt(id, 10)]; n != 1 {
+			t.Errorf("delivery %d received %d times, want 1", id, n)
+		}
+	}
+	if len(received) != len(failed) {
+		t.Errorf("endpoint received %d events, want %d", len(received), len(failed))
+	}
+
+	// Nothing is left to replay
+	result, err := svc.ReplayFailed(ctx, agentID, since)
+	if err != nil || result.Replayed != 0 {
+		t.Errorf("second replay = %+v, %v; want nothing replayed", result, err)
+	}
+}
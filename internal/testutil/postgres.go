@@ -0,0 +1,145 @@
+// internal/testutil/postgres.go
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrations are applied in order to every test schema
+var migrations = []string{"auth_init.sql", "svc_init.sql"}
+
+// Postgres returns a pool on a fresh schema loaded from the service migrations. The schema is
+// dropped when the test ends. Tests are skipped unless TEST_DATABASE_URL points at a database.
+func Postgres(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("failed to create test schema: %v", err)
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("failed to parse TEST_DATABASE_URL: %v", err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to open test pool: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+		if _, err := admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Logf("failed to drop test schema %s: %v", schema, err)
+		}
+		admin.Close(context.Background())
+	})
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("failed to acquire test connection: %v", err)
+	}
+	defer conn.Release()
+
+	for _, name := range migrations {
+		script, err := loadMigration(name)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", name, err)
+		}
+		// The simple protocol runs the whole multi-statement script in one round trip
+		if _, err := conn.Conn().PgConn().Exec(ctx, script).ReadAll(); err != nil {
+			t.Fatalf("failed to apply %s: %v", name, err)
+		}
+	}
+
+	return pool
+}
+
+// Identity inserts an active auth identity and returns its ID
+func Identity(t *testing.T, pool *pgxpool.Pool, email string) int64 {
+	t.Helper()
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO auth_identities (email, email_verified, status)
+		VALUES ($1, TRUE, 'active')
+		RETURNING id
+	`, email).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to create identity %s: %v", email, err)
+	}
+	return id
+}
+
+// Offer inserts an active 1 GB data offer for the agent and returns its ID
+func Offer(t *testing.T, pool *pgxpool.Pool, agentID int64, code string, price float64) int64 {
+	t.Helper()
+
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO agent_offers (
+			agent_identity_id, offer_code, name, type, amount, units, price, validity_days, ussd_code_template
+		) VALUES ($1, $2, $2, 'data', 1, 'GB', $3, 1, '*180*{phone}#')
+		RETURNING id
+	`, agentID, code, price).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to create offer %s: %v", code, err)
+	}
+	return id
+}
+
+// Role grants an identity one of the seeded roles, such as "user" for agents or "admin"
+func Role(t *testing.T, pool *pgxpool.Pool, identityID int64, name string) {
+	t.Helper()
+
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO auth_identity_roles (identity_id, role_id)
+		SELECT $1, id FROM auth_roles WHERE name = $2
+	`, identityID, name)
+	if err != nil {
+		t.Fatalf("failed to grant role %s: %v", name, err)
+	}
+}
+
+// loadMigration reads a migration script, dropping psql meta-commands such as \c
+func loadMigration(name string) (string, error) {
+	_, file, _, _ := runtime.Caller(0)
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "db", "migrations", name))
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), `\`) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), nil
+}
@@ -0,0 +1,101 @@
+// internal/testutil/smtp.go
+package testutil
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SMTPServer is a local SMTP server that accepts every message and counts deliveries
+type SMTPServer struct {
+	Host string
+	Port string
+
+	mu        sync.Mutex
+	delivered []string
+}
+
+// Delivered returns the recipients of the messages accepted so far
+func (s *SMTPServer) Delivered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.delivered...)
+}
+
+// SMTP starts an SMTP server on 127.0.0.1 that is shut down when the test ends. It speaks just
+// enough of the protocol for net/smtp: plain auth without TLS, which net/smtp allows on localhost.
+func SMTP(t *testing.T) *SMTPServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	server := &SMTPServer{Host: host, Port: port}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *SMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	var recipient string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 authenticated")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			recipient = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<> ")
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 send the message")
+			for {
+				body, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if body == ".\r\n" {
+					break
+				}
+			}
+			s.mu.Lock()
+			s.delivered = append(s.delivered, recipient)
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -66,6 +66,11 @@ func (h *Hub) AuthenticateClient(ctx context.Context, token string) (*ClientAuth
 		return nil, ErrTokenBlacklisted
 	}
 
+	return h.ClientAuthFromClaims(ctx, claims)
+}
+
+// ClientAuthFromClaims builds client auth for already-validated token claims
+func (h *Hub) ClientAuthFromClaims(ctx context.Context, claims *jwt.Claims) (*ClientAuth, error) {
 	// Verify session exists
 	sessionData, err := h.sessionManager.GetSession(ctx, claims.IdentityID, claims.ID)
 	if err != nil {